@@ -3,8 +3,11 @@ package alpine
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"regexp"
 	"runtime/trace"
+	"strconv"
+	"strings"
 
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
@@ -21,7 +24,7 @@ import (
 
 const (
 	scannerName    = "alpine"
-	scannerVersion = "v0.0.1"
+	scannerVersion = "v0.0.2"
 	scannerKind    = "distribution"
 )
 
@@ -34,7 +37,15 @@ type alpineRegex struct {
 // ex: "Alpine Linux v3.3"
 // and the issue string in the issue file
 // ex: "Welcome to Alpine Linux 3.3"
+//
+// Edge reports itself as "Alpine Linux edge" in os-release and with a
+// prerelease version of the next release, like "3.17_alpha20220809", in the
+// issue file, so it's checked first.
 var alpineRegexes = []alpineRegex{
+	{
+		dist:   alpineEdgeDist,
+		regexp: regexp.MustCompile(`Alpine Linux (edge|v?\d+\.\d+(\.\d+)?_alpha)`),
+	},
 	{
 		dist:   alpine3_15Dist,
 		regexp: regexp.MustCompile(`Alpine Linux v?3\.15`),
@@ -92,8 +103,22 @@ var alpineRegexes = []alpineRegex{
 const osReleasePath = `etc/os-release`
 const issuePath = `etc/issue`
 
+// AlpineReleasePath is the file containing the release's version, such as
+// "3.14.2", installed by the "alpine-release" package or, in older releases,
+// "alpine-baselayout".
+const alpineReleasePath = `etc/alpine-release`
+
+// ReleaseVersion matches the major and minor version at the start of a
+// release version string.
+var releaseVersion = regexp.MustCompile(`^v?(\d+)\.(\d+)`)
+
+// VersionID matches the VERSION_ID line of an os-release file.
+var versionID = regexp.MustCompile(`(?m)^VERSION_ID=["']?([^"'\n]+)`)
+
 var _ indexer.DistributionScanner = (*DistributionScanner)(nil)
 var _ indexer.VersionedScanner = (*DistributionScanner)(nil)
+var _ indexer.FileLimiter = (*DistributionScanner)(nil)
+var _ indexer.PathDeclarer = (*DistributionScanner)(nil)
 
 // DistributionScanner attempts to discover if a layer
 // displays characteristics of a alpine distribution
@@ -108,10 +133,24 @@ func (*DistributionScanner) Version() string { return scannerVersion }
 // Kind implements scanner.VersionedScanner.
 func (*DistributionScanner) Kind() string { return scannerKind }
 
+// Paths implements indexer.PathDeclarer.
+func (*DistributionScanner) Paths() []string {
+	return []string{osReleasePath, issuePath, alpineReleasePath, installedFile}
+}
+
+// FileLimit implements indexer.FileLimiter.
+//
+// The limit is sized for the apk database, the largest file consulted.
+func (*DistributionScanner) FileLimit() (int64, bool) { return 64 << 20, false }
+
 // Scan will inspect the layer for an os-release or lsb-release file
 // and perform a regex match for keywords indicating the associated alpine release
 //
-// If neither file is found a (nil,nil) is returned.
+// Images lacking an os-release file, such as very minimal or hand-rolled
+// ones, are examined for an alpine-release file and then the apk installed
+// database.
+//
+// If no file is found a (nil,nil) is returned.
 // If the files are found but all regexp fail to match an empty slice is returned.
 func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Distribution, error) {
 	defer trace.StartRegion(ctx, "Scanner.Scan").End()
@@ -121,14 +160,23 @@ func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]
 		label.String("layer", l.Hash.String()))
 	zlog.Debug(ctx).Msg("start")
 	defer zlog.Debug(ctx).Msg("done")
-	files, err := l.Files(osReleasePath, issuePath)
+	files, err := l.Files(osReleasePath, issuePath, alpineReleasePath, installedFile)
 	if err != nil {
 		zlog.Debug(ctx).Msg("didn't find an os-release or issue file")
 		return nil, nil
 	}
+	if len(files) == 0 {
+		return nil, nil
+	}
 	// Always check the os-release file first.
 	if b, ok := files[osReleasePath]; ok {
 		dist := ds.parse(b)
+		if dist == nil && bytes.Contains(b.Bytes(), []byte("ID=alpine")) {
+			// A release newer than the known ones: use the version.
+			if m := versionID.FindSubmatch(b.Bytes()); m != nil {
+				dist = parseRelease(string(m[1]))
+			}
+		}
 		if dist != nil {
 			return []*claircore.Distribution{dist}, nil
 		}
@@ -139,9 +187,76 @@ func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]
 			return []*claircore.Distribution{dist}, nil
 		}
 	}
+	if _, ok := files[osReleasePath]; ok {
+		return []*claircore.Distribution{}, nil
+	}
+	if b, ok := files[alpineReleasePath]; ok {
+		if dist := parseRelease(b.String()); dist != nil {
+			zlog.Debug(ctx).Msg("found release via alpine-release file")
+			return []*claircore.Distribution{dist}, nil
+		}
+	}
+	if b, ok := files[installedFile]; ok {
+		if dist := parseInstalled(b.Bytes()); dist != nil {
+			zlog.Debug(ctx).Msg("found release via apk installed database")
+			return []*claircore.Distribution{dist}, nil
+		}
+	}
+	if _, ok := files[issuePath]; !ok {
+		// Only the fallback files were found, and they weren't useful.
+		return nil, nil
+	}
 	return []*claircore.Distribution{}, nil
 }
 
+// ParseRelease returns the distribution for a release version string, such as
+// the contents of the alpine-release file.
+//
+// Edge uses a prerelease version of the next release, like
+// "3.17_alpha20220809", and is reported as edge rather than that release.
+func parseRelease(v string) *claircore.Distribution {
+	v = strings.TrimSpace(v)
+	if v == "edge" || strings.Contains(v, "_alpha") {
+		return alpineEdgeDist
+	}
+	m := releaseVersion.FindStringSubmatch(v)
+	if m == nil {
+		return nil
+	}
+	maj, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil
+	}
+	min, err := strconv.Atoi(m[2])
+	if err != nil {
+		return nil
+	}
+	if d := releaseToDist(Release(fmt.Sprintf("v%d.%d", maj, min))); d.DID != "" {
+		return d
+	}
+	return mkdist(maj, min)
+}
+
+// ParseInstalled looks in the apk installed database for the package
+// providing the alpine-release file and returns the distribution for its
+// version.
+func parseInstalled(b []byte) *claircore.Distribution {
+	var pkg string
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		switch {
+		case len(line) == 0:
+			pkg = ""
+		case bytes.HasPrefix(line, []byte("P:")):
+			pkg = string(line[2:])
+		case bytes.HasPrefix(line, []byte("V:")):
+			if pkg == "alpine-release" || pkg == "alpine-base" {
+				return parseRelease(string(line[2:]))
+			}
+		}
+	}
+	return nil
+}
+
 // parse attempts to match all Alpine release regexp and returns the associated
 // distribution if it exists.
 //
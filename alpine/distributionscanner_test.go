@@ -2,9 +2,14 @@ package alpine
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
 )
 
 type distTestcase struct {
@@ -96,6 +101,96 @@ func TestDistributionScanner(t *testing.T) {
 	}
 }
 
+func TestDistributionScannerFallback(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tt := []struct {
+		Name  string
+		Files map[string]string
+		Want  []*claircore.Distribution
+	}{
+		{
+			Name:  "AlpineRelease",
+			Files: map[string]string{"etc/alpine-release": "3.14.2\n"},
+			Want:  []*claircore.Distribution{alpine3_14Dist},
+		},
+		{
+			Name:  "UnknownRelease",
+			Files: map[string]string{"etc/alpine-release": "3.99.0\n"},
+			Want:  []*claircore.Distribution{mkdist(3, 99)},
+		},
+		{
+			Name: "Installed",
+			Files: map[string]string{"lib/apk/db/installed": "P:musl\nV:1.2.2-r3\n\n" +
+				"P:alpine-release\nV:3.13.5-r0\nA:x86_64\n\n"},
+			Want: []*claircore.Distribution{alpine3_13Dist},
+		},
+		{
+			Name: "OSReleasePreferred",
+			Files: map[string]string{
+				"etc/os-release":     v3_12_OSRelease,
+				"etc/alpine-release": "3.14.2\n",
+			},
+			Want: []*claircore.Distribution{alpine3_12Dist},
+		},
+		{
+			Name:  "EdgeOSRelease",
+			Files: map[string]string{"etc/os-release": edgeOSRelease},
+			Want:  []*claircore.Distribution{alpineEdgeDist},
+		},
+		{
+			Name:  "EdgeIssue",
+			Files: map[string]string{"etc/issue": edgeIssue},
+			Want:  []*claircore.Distribution{alpineEdgeDist},
+		},
+		{
+			Name:  "EdgeAlpineRelease",
+			Files: map[string]string{"etc/alpine-release": "3.17_alpha20220809\n"},
+			Want:  []*claircore.Distribution{alpineEdgeDist},
+		},
+		{
+			Name:  "NewerOSRelease",
+			Files: map[string]string{"etc/os-release": v3_16_OSRelease},
+			Want:  []*claircore.Distribution{mkdist(3, 16)},
+		},
+		{
+			Name:  "Nothing",
+			Files: map[string]string{"lib/apk/db/installed": "P:musl\nV:1.2.2-r3\n"},
+			Want:  nil,
+		},
+	}
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			l := fixture.Files(tc.Files).Local(t)
+			got, err := (&DistributionScanner{}).Scan(ctx, l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}
+
+const (
+	edgeOSRelease = `NAME="Alpine Linux"
+ID=alpine
+VERSION_ID=3.17_alpha20220809
+PRETTY_NAME="Alpine Linux edge"
+HOME_URL="https://alpinelinux.org/"
+BUG_REPORT_URL="https://gitlab.alpinelinux.org/alpine/aports/-/issues"`
+	edgeIssue = `Welcome to Alpine Linux 3.17_alpha20220809
+Kernel \r on an \m (\l)`
+	v3_16_OSRelease = `NAME="Alpine Linux"
+ID=alpine
+VERSION_ID=3.16.2
+PRETTY_NAME="Alpine Linux v3.16"
+HOME_URL="https://alpinelinux.org/"
+BUG_REPORT_URL="https://gitlab.alpinelinux.org/alpine/aports/-/issues"`
+)
+
 // These are a mess of constants copied out of alpine containers.
 //
 // Might make sense to move these into testdata files at some point.
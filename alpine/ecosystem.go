@@ -5,16 +5,20 @@ import (
 
 	"github.com/quay/claircore/internal/indexer"
 	"github.com/quay/claircore/internal/indexer/linux"
+	"github.com/quay/claircore/wolfi"
 )
 
 // NewEcosystem provides the set of scanners and coalescers for the alpine ecosystem
+//
+// Wolfi and Chainguard images use apk as well, so their distribution scanner
+// is included here.
 func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
 	return &indexer.Ecosystem{
 		PackageScanners: func(ctx context.Context) ([]indexer.PackageScanner, error) {
 			return []indexer.PackageScanner{&Scanner{}}, nil
 		},
 		DistributionScanners: func(ctx context.Context) ([]indexer.DistributionScanner, error) {
-			return []indexer.DistributionScanner{&DistributionScanner{}}, nil
+			return []indexer.DistributionScanner{&DistributionScanner{}, &wolfi.DistributionScanner{}}, nil
 		},
 		RepositoryScanners: func(ctx context.Context) ([]indexer.RepositoryScanner, error) {
 			return []indexer.RepositoryScanner{}, nil
@@ -15,13 +15,15 @@ import (
 
 const (
 	pkgName    = `apk`
-	pkgVersion = `v0.0.1`
+	pkgVersion = `v0.0.2`
 	pkgKind    = `package`
 )
 
 var (
-	_ indexer.VersionedScanner = (*Scanner)(nil)
-	_ indexer.PackageScanner   = (*Scanner)(nil)
+	_ indexer.VersionedScanner   = (*Scanner)(nil)
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
+	_ indexer.PathDeclarer       = (*Scanner)(nil)
 )
 
 // Scanner scans for packages in an apk database.
@@ -38,6 +40,14 @@ func (*Scanner) Version() string { return pkgVersion }
 // Kind implements indexer.VersionedScanner.
 func (*Scanner) Kind() string { return pkgKind }
 
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceAuthoritative }
+
+// Paths implements indexer.PathDeclarer.
+func (*Scanner) Paths() []string {
+	return []string{installedFile}
+}
+
 const installedFile = "lib/apk/db/installed"
 
 // Scan examines a layer for an apk installation database, and extracts
@@ -81,6 +91,7 @@ func (*Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.
 	var delim = []byte("\n\n")
 	entries := bytes.Split(b.Bytes(), delim)
 	for _, entry := range entries {
+		entry = bytes.TrimSpace(entry)
 		if len(entry) == 0 {
 			continue
 		}
@@ -88,8 +99,12 @@ func (*Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.
 			Kind:      claircore.BINARY,
 			PackageDB: installedFile,
 		}
-		r := bytes.NewBuffer(entry)
-		for line, err := r.ReadBytes('\n'); err == nil; line, err = r.ReadBytes('\n') {
+		// The last line of an entry has no trailing newline, so split rather
+		// than reading lines.
+		for _, line := range bytes.Split(entry, []byte("\n")) {
+			if len(line) < 2 || line[1] != ':' {
+				continue
+			}
 			l := string(bytes.TrimSpace(line[2:]))
 			switch line[0] {
 			case 'P':
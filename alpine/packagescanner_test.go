@@ -11,6 +11,7 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/test/fetch"
+	"github.com/quay/claircore/test/fixture"
 )
 
 func TestScan(t *testing.T) {
@@ -174,3 +175,37 @@ func TestScan(t *testing.T) {
 		t.Fatal(cmp.Diff(want, got))
 	}
 }
+
+func TestScanTruncated(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	// Extra blank lines between entries and no trailing newline.
+	const db = "P:musl\nV:1.2.2-r3\nA:x86_64\no:musl\n\n\n" +
+		"P:libcrypto1.1\nV:1.1.1l-r0\nA:x86_64\no:openssl\nc:a8d6b4c4c5ac6b31ea0ab5e1b5e3fae3d28c9f3e"
+	l := fixture.Files(map[string]string{installedFile: db}).Local(t)
+	got, err := (&Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Package{
+		{
+			Name:      "musl",
+			Version:   "1.2.2-r3",
+			Kind:      claircore.BINARY,
+			Arch:      "x86_64",
+			Source:    &claircore.Package{Name: "musl", Version: "1.2.2-r3", Kind: claircore.SOURCE},
+			PackageDB: installedFile,
+		},
+		{
+			Name:           "libcrypto1.1",
+			Version:        "1.1.1l-r0",
+			Kind:           claircore.BINARY,
+			Arch:           "x86_64",
+			Source:         &claircore.Package{Name: "openssl", Version: "1.1.1l-r0", Kind: claircore.SOURCE},
+			PackageDB:      installedFile,
+			RepositoryHint: "a8d6b4c4c5ac6b31ea0ab5e1b5e3fae3d28c9f3e",
+		},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -2,6 +2,7 @@ package alpine
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/quay/claircore"
 )
@@ -29,6 +30,8 @@ const (
 	V3_5  Release = "v3.5"
 	V3_4  Release = "v3.4"
 	V3_3  Release = "v3.3"
+	// Edge is the rolling development branch.
+	Edge Release = "edge"
 )
 
 // Common os-release fields applicable for *claircore.Distribution usage.
@@ -60,6 +63,12 @@ var (
 	alpine3_13Dist = mkdist(3, 13)
 	alpine3_14Dist = mkdist(3, 14)
 	alpine3_15Dist = mkdist(3, 15)
+	alpineEdgeDist = &claircore.Distribution{
+		Name:       Name,
+		DID:        ID,
+		VersionID:  "edge",
+		PrettyName: "Alpine Linux edge",
+	}
 )
 
 func releaseToDist(r Release) *claircore.Distribution {
@@ -90,8 +99,19 @@ func releaseToDist(r Release) *claircore.Distribution {
 		return alpine3_14Dist
 	case V3_15:
 		return alpine3_15Dist
+	case Edge:
+		return alpineEdgeDist
 	default:
 		// return empty dist
 		return &claircore.Distribution{}
 	}
 }
+
+// DistributionFor returns the Distribution for the release with the provided
+// version, such as "3.14" or "edge", or nil if it can't be parsed.
+//
+// This is the Distribution the DistributionScanner reports for the release,
+// for updaters outside this package importing Alpine vulnerabilities.
+func DistributionFor(version string) *claircore.Distribution {
+	return parseRelease(strings.TrimPrefix(version, "v"))
+}
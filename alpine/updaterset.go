@@ -8,8 +8,8 @@ import (
 )
 
 var alpineMatrix = map[Repo][]Release{
-	Main:      []Release{V3_15, V3_14, V3_13, V3_12, V3_11, V3_10, V3_9, V3_8, V3_7, V3_6, V3_5, V3_4, V3_3},
-	Community: []Release{V3_15, V3_14, V3_13, V3_12, V3_11, V3_10, V3_9, V3_8, V3_7, V3_6, V3_5, V3_4, V3_3},
+	Main:      []Release{Edge, V3_15, V3_14, V3_13, V3_12, V3_11, V3_10, V3_9, V3_8, V3_7, V3_6, V3_5, V3_4, V3_3},
+	Community: []Release{Edge, V3_15, V3_14, V3_13, V3_12, V3_11, V3_10, V3_9, V3_8, V3_7, V3_6, V3_5, V3_4, V3_3},
 }
 
 func UpdaterSet(_ context.Context) (driver.UpdaterSet, error) {
@@ -0,0 +1,99 @@
+package claircore
+
+// Package managers and image platforms name the same architectures
+// differently: an RPM built for "aarch64" runs on an "arm64" image, and a dpkg
+// package for "ppc64el" on a "ppc64le" image.
+//
+// ArchAliases maps each architecture, by its name in image platforms, to the
+// names package managers use for it.
+var archAliases = map[string][]string{
+	"amd64":    {"amd64", "x86_64", "x86-64"},
+	"arm64":    {"arm64", "aarch64"},
+	"arm":      {"arm", "armhf", "armhfp", "armv7hl", "armv7l", "armel", "armv6l"},
+	"386":      {"386", "i386", "i486", "i586", "i686"},
+	"ppc64le":  {"ppc64le", "ppc64el"},
+	"ppc64":    {"ppc64"},
+	"s390x":    {"s390x"},
+	"mips64le": {"mips64le", "mips64el"},
+	"riscv64":  {"riscv64"},
+}
+
+// ArchNames maps every known architecture name to its name in image
+// platforms.
+var archNames = func() map[string]string {
+	m := make(map[string]string)
+	for p, as := range archAliases {
+		for _, a := range as {
+			m[a] = p
+		}
+	}
+	return m
+}()
+
+// NormalizeArch returns the name image platforms use for the architecture a
+// package manager calls "a", such as "arm64" for "aarch64". Unknown
+// architectures are returned unchanged.
+func NormalizeArch(a string) string {
+	if n, ok := archNames[a]; ok {
+		return n
+	}
+	return a
+}
+
+// ArchIndependent reports whether the package architecture names no
+// particular architecture.
+func archIndependent(a string) bool {
+	switch a {
+	case "", "noarch", "all", "any":
+		return true
+	}
+	return false
+}
+
+// CmpRecord is like Cmp, but compares the architecture of the record's
+// package against "b" with the architecture's aliases taken into account, so
+// an "aarch64" constraint applies to an "arm64" package.
+//
+// If the package doesn't name a particular architecture, as with "noarch"
+// packages, and the constraint doesn't explicitly allow that, the record's
+// Platform is consulted: the constraint is compared against the architecture
+// of the image the package was found in. Without a Platform, the result is the
+// same as Cmp.
+func (o ArchOp) CmpRecord(r *IndexRecord, b string) bool {
+	if b == "" {
+		return true
+	}
+	var a string
+	if r.Package != nil {
+		a = r.Package.Arch
+	}
+	if archIndependent(a) {
+		if a != "" && o.Cmp(a, b) {
+			return true
+		}
+		if r.Platform == nil || r.Platform.Architecture == "" {
+			return o.Cmp(a, b)
+		}
+		a = r.Platform.Architecture
+	}
+	names := []string{a}
+	for _, n := range archAliases[NormalizeArch(a)] {
+		if n != a {
+			names = append(names, n)
+		}
+	}
+	if o == OpNotEquals {
+		for _, n := range names {
+			if n == b {
+				return false
+			}
+		}
+		return true
+	}
+	for _, n := range names {
+		if o.Cmp(n, b) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package claircore
+
+import "testing"
+
+func TestCmpRecord(t *testing.T) {
+	arm64 := &Platform{OS: "linux", Architecture: "arm64"}
+	amd64 := &Platform{OS: "linux", Architecture: "amd64"}
+	tt := []struct {
+		Name     string
+		Op       ArchOp
+		Arch     string
+		Platform *Platform
+		Want     string
+		Match    bool
+	}{
+		{"NoConstraint", OpEquals, "aarch64", nil, "", true},
+		{"Equal", OpEquals, "x86_64", nil, "x86_64", true},
+		{"Alias", OpEquals, "arm64", nil, "aarch64", true},
+		{"OtherArch", OpEquals, "aarch64", arm64, "x86_64", false},
+		{"Pattern", OpPatternMatch, "ppc64el", nil, `^(ppc64le|s390x)$`, true},
+		{"PatternMiss", OpPatternMatch, "s390x", nil, `^(aarch64|x86_64)$`, false},
+		{"NotEquals", OpNotEquals, "arm64", nil, "aarch64", false},
+		{"NotEqualsOther", OpNotEquals, "arm64", nil, "x86_64", true},
+		{"NoarchAllowed", OpPatternMatch, "noarch", arm64, `^(noarch|x86_64)$`, true},
+		{"NoarchOnPlatform", OpEquals, "noarch", amd64, "x86_64", true},
+		{"NoarchOtherPlatform", OpEquals, "noarch", arm64, "x86_64", false},
+		{"NoarchUnknownPlatform", OpEquals, "noarch", nil, "x86_64", false},
+		{"EmptyOnPlatform", OpPatternMatch, "", arm64, `^(aarch64|s390x)$`, true},
+		{"EmptyUnknownPlatform", OpPatternMatch, "", nil, `^(aarch64|s390x)$`, false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := &IndexRecord{
+				Package:  &Package{Name: "pkg", Arch: tc.Arch},
+				Platform: tc.Platform,
+			}
+			if got := tc.Op.CmpRecord(r, tc.Want); got != tc.Match {
+				t.Errorf("%v.CmpRecord(%q on %v, %q): got: %v, want: %v", tc.Op, tc.Arch, tc.Platform, tc.Want, got, tc.Match)
+			}
+		})
+	}
+}
+
+func TestIndexRecordsPlatform(t *testing.T) {
+	pkg := &Package{ID: "1", Name: "pkg"}
+	ir := &IndexReport{
+		Packages: map[string]*Package{"1": pkg},
+		Environments: map[string][]*Environment{
+			"1": {{}, {Platform: "linux/s390x"}},
+		},
+		Platform: &Platform{OS: "linux", Architecture: "arm64"},
+	}
+	rs := ir.IndexRecords()
+	if got, want := len(rs), 2; got != want {
+		t.Fatalf("got: %d records, want: %d", got, want)
+	}
+	if got, want := rs[0].Platform.Architecture, "arm64"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := rs[1].Platform.Architecture, "s390x"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestNormalizeArch(t *testing.T) {
+	for in, want := range map[string]string{
+		"x86_64":  "amd64",
+		"aarch64": "arm64",
+		"ppc64el": "ppc64le",
+		"i686":    "386",
+		"s390x":   "s390x",
+		"sparc64": "sparc64",
+	} {
+		if got := NormalizeArch(in); got != want {
+			t.Errorf("%q: got: %q, want: %q", in, got, want)
+		}
+	}
+}
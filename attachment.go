@@ -0,0 +1,47 @@
+package claircore
+
+// Kinds of Attachment.
+const (
+	AttachmentSignature   = "signature"
+	AttachmentAttestation = "attestation"
+	AttachmentSBOM        = "sbom"
+)
+
+// Attachment is an artifact stored in the registry alongside an image and
+// referring to it, such as a cosign signature, an in-toto attestation, or an
+// SBOM.
+//
+// Attachments are only recorded as present: signatures and attestations are
+// not verified.
+type Attachment struct {
+	// Kind is one of the Attachment* constants.
+	Kind string `json:"kind"`
+	// Digest is the digest of the attached artifact's manifest.
+	Digest Digest `json:"digest"`
+	// ArtifactType is the artifact's declared type, if known. Attachments
+	// found by tag don't have one.
+	ArtifactType string `json:"artifact_type,omitempty"`
+	// Source is how the attachment was discovered: "referrers" for the OCI
+	// referrers API, or "tag" for cosign's tag naming convention.
+	Source string `json:"source"`
+	// Provenance is the build provenance recorded in an attestation, if
+	// reading it was enabled and the attestation contained any.
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Provenance describes how an image was built, as recorded in a SLSA
+// provenance attestation.
+type Provenance struct {
+	// PredicateType is the SLSA provenance predicate type the information
+	// was read from, such as "https://slsa.dev/provenance/v1".
+	PredicateType string `json:"predicate_type"`
+	// BuilderID identifies the build platform that produced the image.
+	BuilderID string `json:"builder_id,omitempty"`
+	// BuildType identifies the template the build followed.
+	BuildType string `json:"build_type,omitempty"`
+	// SourceURI is the location of the source the image was built from, such
+	// as a git repository.
+	SourceURI string `json:"source_uri,omitempty"`
+	// SourceRevision is the revision of the source, such as a git commit.
+	SourceRevision string `json:"source_revision,omitempty"`
+}
@@ -16,11 +16,12 @@ import (
 
 // AWS Linux keeps a consistent os-release file between
 // major releases.
-// All tested images on docker hub contained os-release file
+// All tested images on docker hub contained os-release file, but the
+// system-release file is also checked in case it's been removed.
 
 const (
 	scannerName    = "aws"
-	scannerVersion = "v0.0.1"
+	scannerVersion = "v0.0.2"
 	scannerKind    = "distribution"
 )
 
@@ -29,21 +30,30 @@ type awsRegex struct {
 	regexp  *regexp.Regexp
 }
 
+// AwsRegexes match both the PRETTY_NAME in the os-release file, like
+// "Amazon Linux 2", and the system-release file, like "Amazon Linux release 2
+// (Karoo)". They're checked in order, so 2023 must come before 2.
 var awsRegexes = []awsRegex{
 	{
 		release: Linux1,
-		regexp:  regexp.MustCompile(`Amazon Linux AMI 2018.03`),
+		regexp:  regexp.MustCompile(`Amazon Linux AMI (release )?2018.03`),
+	},
+	{
+		release: Linux2023,
+		regexp:  regexp.MustCompile(`Amazon Linux (release )?2023`),
 	},
 	{
 		release: Linux2,
-		regexp:  regexp.MustCompile(`Amazon Linux 2`),
+		regexp:  regexp.MustCompile(`Amazon Linux (release )?2`),
 	},
 }
 
 const osReleasePath = `etc/os-release`
+const systemReleasePath = `etc/system-release`
 
 var _ indexer.DistributionScanner = (*DistributionScanner)(nil)
 var _ indexer.VersionedScanner = (*DistributionScanner)(nil)
+var _ indexer.PathDeclarer = (*DistributionScanner)(nil)
 
 // DistributionScanner attempts to discover if a layer
 // displays characteristics of a AWS distribution
@@ -58,7 +68,12 @@ func (*DistributionScanner) Version() string { return scannerVersion }
 // Kind implements scanner.VersionedScanner.
 func (*DistributionScanner) Kind() string { return scannerKind }
 
-// Scan will inspect the layer for an os-release or lsb-release file
+// Paths implements indexer.PathDeclarer.
+func (*DistributionScanner) Paths() []string {
+	return []string{osReleasePath, systemReleasePath}
+}
+
+// Scan will inspect the layer for an os-release or system-release file
 // and perform a regex match for keywords indicating the associated AWS release
 //
 // If neither file is found a (nil,nil) is returned.
@@ -73,14 +88,17 @@ func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]
 		label.String("layer", l.Hash.String()))
 	zlog.Debug(ctx).Msg("start")
 	defer zlog.Debug(ctx).Msg("done")
-	files, err := l.Files(osReleasePath)
+	files, err := l.Files(osReleasePath, systemReleasePath)
 	if err != nil {
-		zlog.Debug(ctx).Msg("didn't find an os-release")
+		zlog.Debug(ctx).Msg("didn't find an os-release or system-release")
 		return nil, nil
 	}
-	for _, buff := range files {
-		dist := ds.parse(buff)
-		if dist != nil {
+	for _, p := range []string{osReleasePath, systemReleasePath} {
+		buff, ok := files[p]
+		if !ok {
+			continue
+		}
+		if dist := ds.parse(buff); dist != nil {
 			return []*claircore.Distribution{dist}, nil
 		}
 	}
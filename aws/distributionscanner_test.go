@@ -27,6 +27,23 @@ ANSI_COLOR="0;33"
 CPE_NAME="cpe:2.3:o:amazon:amazon_linux:2"
 HOME_URL="https://amazonlinux.com/"`)
 
+var linux2023OSRelease []byte = []byte(`NAME="Amazon Linux"
+VERSION="2023"
+ID="amzn"
+ID_LIKE="fedora"
+VERSION_ID="2023"
+PLATFORM_ID="platform:al2023"
+PRETTY_NAME="Amazon Linux 2023"
+ANSI_COLOR="0;33"
+CPE_NAME="cpe:2.3:o:amazon:amazon_linux:2023"
+HOME_URL="https://aws.amazon.com/linux/"
+BUG_REPORT_URL="https://github.com/amazonlinux/amazon-linux-2023"
+SUPPORT_END="2028-03-15"`)
+
+var linux1SystemRelease []byte = []byte(`Amazon Linux AMI release 2018.03`)
+var linux2SystemRelease []byte = []byte(`Amazon Linux release 2 (Karoo)`)
+var linux2023SystemRelease []byte = []byte(`Amazon Linux release 2023.0.20230315 (Amazon Linux)`)
+
 func TestDistributionScanner(t *testing.T) {
 	table := []struct {
 		name      string
@@ -43,6 +60,26 @@ func TestDistributionScanner(t *testing.T) {
 			release:   Linux2,
 			osRelease: linux2OSRelease,
 		},
+		{
+			name:      "linux2023",
+			release:   Linux2023,
+			osRelease: linux2023OSRelease,
+		},
+		{
+			name:      "linux1 system-release",
+			release:   Linux1,
+			osRelease: linux1SystemRelease,
+		},
+		{
+			name:      "linux2 system-release",
+			release:   Linux2,
+			osRelease: linux2SystemRelease,
+		},
+		{
+			name:      "linux2023 system-release",
+			release:   Linux2023,
+			osRelease: linux2023SystemRelease,
+		},
 	}
 	for _, tt := range table {
 		t.Run(tt.name, func(t *testing.T) {
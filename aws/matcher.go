@@ -3,12 +3,14 @@ package aws
 import (
 	"context"
 
-	version "github.com/knqyf263/go-rpm-version"
-
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
 )
 
+// Matcher matches packages against ALAS advisories.
+//
+// Versions are compared with Amazon Linux's dist tags in mind; see
+// compareRelease.
 type Matcher struct{}
 
 var _ driver.Matcher = (*Matcher)(nil)
@@ -40,20 +42,10 @@ func (*Matcher) Query() []driver.MatchConstraint {
 }
 
 func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
-	v1 := version.NewVersion(record.Package.Version)
-	v2 := version.NewVersion(vuln.FixedInVersion)
-
-	if vuln.FixedInVersion == "" {
-		return true, nil
-	}
-
-	if v2.String() == "0" {
+	if vuln.FixedInVersion == "" || vuln.FixedInVersion == "0" {
 		return true, nil
 	}
-
-	if v1.LessThan(v2) {
-		return true, nil
-	}
-
-	return false, nil
+	v := parseEVR(record.Package.Version)
+	fixed := parseEVR(vuln.FixedInVersion)
+	return v.Compare(fixed) < 0, nil
 }
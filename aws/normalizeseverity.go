@@ -1,6 +1,10 @@
 package aws
 
-import "github.com/quay/claircore"
+import (
+	"strings"
+
+	"github.com/quay/claircore"
+)
 
 const (
 	Low       = "low"
@@ -11,8 +15,10 @@ const (
 
 // NormalizeSeverity takes a aws.Severity and normalizes it to
 // a claircore.Severity.
+//
+// The comparison ignores case and surrounding space.
 func NormalizeSeverity(severity string) claircore.Severity {
-	switch severity {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
 	case Low:
 		return claircore.Low
 	case Medium:
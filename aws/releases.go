@@ -12,6 +12,9 @@ type Release string
 const (
 	Linux1 Release = "linux1"
 	Linux2 Release = "linux2"
+	// Linux2023 is the first of the Amazon Linux releases named for their
+	// year, which are released every two years.
+	Linux2023 Release = "linux2023"
 	// os-release name ID field consistently available on official amazon linux images
 	ID = "amzn"
 )
@@ -22,6 +25,8 @@ func (r Release) mirrorlist() string {
 		return "http://repo.us-west-2.amazonaws.com/2018.03/updates/x86_64/mirror.list"
 	case Linux2:
 		return "https://cdn.amazonlinux.com/2/core/latest/x86_64/mirror.list"
+	case Linux2023:
+		return "https://cdn.amazonlinux.com/al2023/core/mirrors/latest/x86_64/mirror.list"
 	}
 	panic(fmt.Sprintf("unknown release %q", r))
 }
@@ -44,12 +49,23 @@ var linux2Dist = &claircore.Distribution{
 	CPE:        cpe.MustUnbind("cpe:2.3:o:amazon:amazon_linux:2"),
 }
 
+var linux2023Dist = &claircore.Distribution{
+	Name:       "Amazon Linux",
+	DID:        ID,
+	Version:    "2023",
+	VersionID:  "2023",
+	PrettyName: "Amazon Linux 2023",
+	CPE:        cpe.MustUnbind("cpe:2.3:o:amazon:amazon_linux:2023"),
+}
+
 func releaseToDist(release Release) *claircore.Distribution {
 	switch release {
 	case Linux1:
 		return linux1Dist
 	case Linux2:
 		return linux2Dist
+	case Linux2023:
+		return linux2023Dist
 	default:
 		// return empty dist
 		return &claircore.Distribution{}
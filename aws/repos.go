@@ -3,11 +3,13 @@ package aws
 type Repo string
 
 const (
-	amzn1 Repo = "amzn1"
-	amzn2 Repo = "amzn2"
+	amzn1  Repo = "amzn1"
+	amzn2  Repo = "amzn2"
+	al2023 Repo = "al2023"
 )
 
 var ReleaseToRepo = map[Release]Repo{
-	Linux1: amzn1,
-	Linux2: amzn2,
+	Linux1:    amzn1,
+	Linux2:    amzn2,
+	Linux2023: al2023,
 }
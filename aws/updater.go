@@ -96,11 +96,15 @@ func (u *Updater) Parse(ctx context.Context, contents io.ReadCloser) ([]*clairco
 		if err != nil {
 			return vulns, err
 		}
+		// The updated date is informational, so it's not an error if it's
+		// missing or malformed.
+		modified, _ := time.Parse("2006-01-02 15:04", update.Updated.Date)
 		partial := &claircore.Vulnerability{
 			Updater:            u.Name(),
 			Name:               update.ID,
 			Description:        update.Description,
 			Issued:             issued,
+			Modified:           modified,
 			Links:              refsToLinks(update),
 			Severity:           update.Severity,
 			NormalizedSeverity: NormalizeSeverity(update.Severity),
@@ -125,6 +129,11 @@ func (u *Updater) unpack(partial *claircore.Vulnerability, packages []alas.Packa
 			Kind: claircore.BINARY,
 		}
 		v.FixedInVersion = fmt.Sprintf("%s-%s", alasPKG.Version, alasPKG.Release)
+		// Packages with an epoch must keep it, or every installed version
+		// compares as newer than the fix.
+		if e := alasPKG.Epoch; e != "" && e != "0" {
+			v.FixedInVersion = e + ":" + v.FixedInVersion
+		}
 
 		out = append(out, &v)
 	}
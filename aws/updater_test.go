@@ -0,0 +1,60 @@
+package aws
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+)
+
+func TestParse(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const doc = `<?xml version="1.0" ?>
+<updates>
+  <update author="linux-security@amazon.com" from="linux-security@amazon.com" status="final" type="security" version="1.4">
+    <id>ALAS2-2021-1600</id>
+    <title>Amazon Linux 2 2021-1600: curl</title>
+    <issued date="2021-02-04 19:52" />
+    <updated date="2021-02-10 22:18" />
+    <severity>medium</severity>
+    <description>Package updates are available for Amazon Linux 2 that fix the following vulnerabilities.</description>
+    <pkglist>
+      <collection short="amazon-linux-2">
+        <name>Amazon Linux 2</name>
+        <package arch="x86_64" epoch="0" name="curl" release="1.amzn2.0.1" version="7.61.1">
+          <filename>Packages/curl-7.61.1-1.amzn2.0.1.x86_64.rpm</filename>
+        </package>
+        <package arch="x86_64" epoch="1" name="openssl" release="22.amzn2.0.1" version="1.0.2k">
+          <filename>Packages/openssl-1.0.2k-22.amzn2.0.1.x86_64.rpm</filename>
+        </package>
+      </collection>
+    </pkglist>
+  </update>
+</updates>`
+	u, err := NewUpdater(Linux2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := u.Parse(ctx, io.NopCloser(strings.NewReader(doc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) != 2 {
+		t.Fatalf("got: %d vulnerabilities, want: 2", len(vs))
+	}
+	v := vs[0]
+	if got, want := v.Issued, time.Date(2021, 2, 4, 19, 52, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("issued: got: %v, want: %v", got, want)
+	}
+	if got, want := v.Modified, time.Date(2021, 2, 10, 22, 18, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("modified: got: %v, want: %v", got, want)
+	}
+	for i, want := range []string{"7.61.1-1.amzn2.0.1", "1:1.0.2k-22.amzn2.0.1"} {
+		if got := vs[i].FixedInVersion; got != want {
+			t.Errorf("fixed in: got: %q, want: %q", got, want)
+		}
+	}
+}
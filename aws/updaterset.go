@@ -10,6 +10,7 @@ import (
 var amazonReleases = []Release{
 	Linux1,
 	Linux2,
+	Linux2023,
 }
 
 func UpdaterSet(_ context.Context) (driver.UpdaterSet, error) {
@@ -0,0 +1,171 @@
+package aws
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Evr is an rpm "epoch:version-release" triple, as found in ALAS advisories
+// and in the rpm databases of Amazon Linux images.
+type evr struct {
+	epoch   int
+	version string
+	release string
+}
+
+// ParseEVR parses an "[epoch:]version[-release]" string. A missing or
+// malformed epoch is treated as 0.
+func parseEVR(s string) evr {
+	var v evr
+	if i := strings.IndexByte(s, ':'); i != -1 {
+		v.epoch, _ = strconv.Atoi(strings.TrimSpace(s[:i]))
+		s = s[i+1:]
+	}
+	// The version can't contain a "-", but the release can.
+	if i := strings.IndexByte(s, '-'); i != -1 {
+		v.version, v.release = s[:i], s[i+1:]
+	} else {
+		v.version = s
+	}
+	return v
+}
+
+// Compare returns an integer comparing two EVRs: 0 if a == b, -1 if a < b,
+// and +1 if a > b.
+func (a evr) Compare(b evr) int {
+	switch {
+	case a.epoch < b.epoch:
+		return -1
+	case a.epoch > b.epoch:
+		return 1
+	}
+	if c := rpmvercmp(a.version, b.version); c != 0 {
+		return c
+	}
+	return compareRelease(a.release, b.release)
+}
+
+// DistTag matches the Amazon Linux dist tag in a release, like the
+// ".amzn2023" in "1.amzn2023.0.2".
+var distTag = regexp.MustCompile(`\.amzn\d*`)
+
+// CompareRelease compares two releases, accounting for the Amazon Linux dist
+// tag.
+//
+// The tag names the release a package was built for rather than ordering
+// builds, but ordinary comparison takes its number as a version segment: a
+// package carried from Amazon Linux 2 into a later release would sort below
+// a rebuild for Amazon Linux 2023 purely because 2 < 2023. When both releases
+// have a tag, the parts before it are compared, then the parts after it (the
+// ".0.2" in "1.amzn2023.0.2", counting Amazon's rebuilds), and the tags
+// themselves are ignored.
+func compareRelease(a, b string) int {
+	ai, bi := distTag.FindStringIndex(a), distTag.FindStringIndex(b)
+	if ai == nil || bi == nil {
+		return rpmvercmp(a, b)
+	}
+	if c := rpmvercmp(a[:ai[0]], b[:bi[0]]); c != 0 {
+		return c
+	}
+	return rpmvercmp(a[ai[1]:], b[bi[1]:])
+}
+
+// Rpmvercmp compares version or release strings the way rpm does, including
+// the handling of "~" (sorts before anything, even the end of the string) and
+// "^" (sorts after the end of the string, but before anything else).
+//
+// See rpmvercmp in rpm's lib/rpmvercmp.c.
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	isAlnum := func(c byte) bool { return isDigit(c) || isAlpha(c) }
+	for len(a) != 0 || len(b) != 0 {
+		for len(a) != 0 && !isAlnum(a[0]) && a[0] != '~' && a[0] != '^' {
+			a = a[1:]
+		}
+		for len(b) != 0 && !isAlnum(b[0]) && b[0] != '~' && b[0] != '^' {
+			b = b[1:]
+		}
+
+		// Tilde sorts before everything else.
+		if (len(a) != 0 && a[0] == '~') || (len(b) != 0 && b[0] == '~') {
+			if len(a) == 0 || a[0] != '~' {
+				return 1
+			}
+			if len(b) == 0 || b[0] != '~' {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		// Caret sorts after the end of the string, but before anything
+		// else.
+		if (len(a) != 0 && a[0] == '^') || (len(b) != 0 && b[0] == '^') {
+			if len(a) == 0 {
+				return -1
+			}
+			if len(b) == 0 {
+				return 1
+			}
+			if a[0] != '^' {
+				return 1
+			}
+			if b[0] != '^' {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if len(a) == 0 || len(b) == 0 {
+			break
+		}
+
+		// Grab the next segment of the same type from each.
+		num := isDigit(a[0])
+		class := isAlpha
+		if num {
+			class = isDigit
+		}
+		var i, j int
+		for i < len(a) && class(a[i]) {
+			i++
+		}
+		for j < len(b) && class(b[j]) {
+			j++
+		}
+		sa, sb := a[:i], b[:j]
+		a, b = a[i:], b[j:]
+		// Segments of different types: numeric is newer.
+		if len(sb) == 0 {
+			if num {
+				return 1
+			}
+			return -1
+		}
+		if num {
+			sa = strings.TrimLeft(sa, "0")
+			sb = strings.TrimLeft(sb, "0")
+			switch {
+			case len(sa) > len(sb):
+				return 1
+			case len(sa) < len(sb):
+				return -1
+			}
+		}
+		if c := strings.Compare(sa, sb); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return -1
+	}
+	return 1
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+func isAlpha(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
@@ -0,0 +1,119 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestRpmvercmp(t *testing.T) {
+	// Cases from rpm's tests/rpmvercmp.at.
+	tt := []struct {
+		A, B string
+		Want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "2.0", -1},
+		{"2.0.1", "2.0", 1},
+		{"5.5p1", "5.5p2", -1},
+		{"5.5p10", "5.5p1", 1},
+		{"10xyz", "10.1xyz", -1},
+		{"xyz10", "xyz10.1", -1},
+		{"1.0aa", "1.0a", 1},
+		{"10.0001", "10.1", 0},
+		{"10.0001", "10.0039", -1},
+		{"4.999.9", "5.0", -1},
+		{"20101121", "20101122", -1},
+		{"1.0", "1_0", 0},
+		{"1b.fc17", "1.fc17", -1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~rc1~git123", "1.0~rc1", -1},
+		{"1.0^", "1.0", 1},
+		{"1.0^git1", "1.0", 1},
+		{"1.0^git1", "1.01", -1},
+		{"1.0^20160101", "1.0.1", -1},
+		{"1.0^git1", "1.0^git2", -1},
+		{"1.0^git1~pre", "1.0^git1", -1},
+		{"1.0~rc1", "1.0^git1", -1},
+	}
+	for _, tc := range tt {
+		if got := rpmvercmp(tc.A, tc.B); got != tc.Want {
+			t.Errorf("rpmvercmp(%q, %q): got: %d, want: %d", tc.A, tc.B, got, tc.Want)
+		}
+		if got := rpmvercmp(tc.B, tc.A); got != -tc.Want {
+			t.Errorf("rpmvercmp(%q, %q): got: %d, want: %d", tc.B, tc.A, got, -tc.Want)
+		}
+	}
+}
+
+func TestCompareEVR(t *testing.T) {
+	tt := []struct {
+		A, B string
+		Want int
+	}{
+		{"7.61.1-1.amzn2.0.1", "7.61.1-1.amzn2.0.1", 0},
+		{"7.61.1-1.amzn2.0.1", "7.61.1-1.amzn2.0.2", -1},
+		{"7.61.1-1.amzn2", "7.61.1-1.amzn2.0.1", -1},
+		{"7.61.1-12.amzn2.0.1", "7.61.1-9.amzn2.0.2", 1},
+		// The dist tag's number isn't a version.
+		{"2.9.1-1.amzn2023.0.1", "2.9.1-1.amzn2.0.2", -1},
+		{"2.9.1-2.amzn2", "2.9.1-1.amzn2023.0.4", 1},
+		{"2.9.1-1.amzn2023", "2.9.1-1.amzn2", 0},
+		// Epochs win.
+		{"1:1.0-1.amzn2023", "2.0-1.amzn2023", 1},
+		{"1.0-1.amzn2023", "1:1.0-1.amzn2023", -1},
+		// Carets, as used for snapshots in Amazon Linux 2023.
+		{"1.4.0^20230213git1-1.amzn2023", "1.4.0-1.amzn2023", 1},
+		{"1.4.0^20230213git1-1.amzn2023", "1.4.1-1.amzn2023", -1},
+	}
+	for _, tc := range tt {
+		a, b := parseEVR(tc.A), parseEVR(tc.B)
+		if got := a.Compare(b); got != tc.Want {
+			t.Errorf("%q <=> %q: got: %d, want: %d", tc.A, tc.B, got, tc.Want)
+		}
+	}
+}
+
+func TestVulnerable(t *testing.T) {
+	ctx := context.Background()
+	var m Matcher
+	tt := []struct {
+		Installed, Fixed string
+		Want             bool
+	}{
+		{"7.61.1-1.amzn2.0.1", "7.61.1-1.amzn2.0.2", true},
+		{"7.61.1-1.amzn2.0.2", "7.61.1-1.amzn2.0.2", false},
+		{"1:3.0.8-1.amzn2023.0.1", "1:3.0.8-1.amzn2023.0.2", true},
+		{"1:3.0.8-1.amzn2023.0.1", "3.0.9-1.amzn2023", false},
+		{"3.0.8-1.amzn2023.0.1", "", true},
+		{"3.0.8-1.amzn2023.0.1", "0", true},
+	}
+	for _, tc := range tt {
+		r := &claircore.IndexRecord{Package: &claircore.Package{Version: tc.Installed}}
+		v := &claircore.Vulnerability{FixedInVersion: tc.Fixed}
+		got, err := m.Vulnerable(ctx, r, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.Want {
+			t.Errorf("%q fixed in %q: got: %v, want: %v", tc.Installed, tc.Fixed, got, tc.Want)
+		}
+	}
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	for in, want := range map[string]claircore.Severity{
+		"low":        claircore.Low,
+		"Medium":     claircore.Medium,
+		"important":  claircore.High,
+		"Important ": claircore.High,
+		"CRITICAL":   claircore.Critical,
+		"bogus":      claircore.Unknown,
+	} {
+		if got := NormalizeSeverity(in); got != want {
+			t.Errorf("%q: got: %v, want: %v", in, got, want)
+		}
+	}
+}
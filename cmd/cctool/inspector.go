@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
 	"path"
@@ -9,8 +10,10 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 
 	"github.com/quay/claircore"
 )
@@ -19,7 +22,85 @@ import (
 //
 // The command (skopeo or docker) needs to be configured with any needed
 // permissions.
-func Inspect(ctx context.Context, r string) (*claircore.Manifest, error) {
+//
+// If the reference is to a manifest list, the platform ("os/arch[/variant]")
+// selects which image to inspect. Inspecting a manifest list without a
+// platform is an error.
+func Inspect(ctx context.Context, r, platform string) (*claircore.Manifest, error) {
+	ins, err := newInspector(r)
+	if err != nil {
+		return nil, err
+	}
+	if !isIndex(ins.desc.MediaType) {
+		img, err := ins.desc.Image()
+		if err != nil {
+			return nil, err
+		}
+		return ins.manifest(ctx, img, nil)
+	}
+
+	ml, err := ins.list(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if platform == "" {
+		return nil, fmt.Errorf("%s is a manifest list; choose one of %v", r, ml.Platforms())
+	}
+	p, err := claircore.ParsePlatform(platform)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := ml.Select(p)
+	if !ok {
+		return nil, fmt.Errorf("%s has no image for %v; choose one of %v", r, p, ml.Platforms())
+	}
+	idx, err := ins.desc.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	h, err := v1.NewHash(m.Hash.String())
+	if err != nil {
+		return nil, err
+	}
+	img, err := idx.Image(h)
+	if err != nil {
+		return nil, err
+	}
+	return ins.manifest(ctx, img, m.Platform)
+}
+
+// InspectList is like Inspect, but inspects every image in a manifest list.
+//
+// A reference to a single image is reported as a list of one, with no
+// platform.
+func InspectList(ctx context.Context, r string) (*claircore.ManifestList, error) {
+	ins, err := newInspector(r)
+	if err != nil {
+		return nil, err
+	}
+	if !isIndex(ins.desc.MediaType) {
+		img, err := ins.desc.Image()
+		if err != nil {
+			return nil, err
+		}
+		m, err := ins.manifest(ctx, img, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &claircore.ManifestList{Hash: m.Hash, Manifests: []*claircore.Manifest{m}}, nil
+	}
+	return ins.list(ctx, true)
+}
+
+// Inspector holds what's needed to talk to the registry an image reference
+// points into.
+type inspector struct {
+	repo name.Repository
+	rt   http.RoundTripper
+	desc *remote.Descriptor
+}
+
+func newInspector(r string) (*inspector, error) {
 	ref, err := name.ParseReference(r)
 	if err != nil {
 		return nil, err
@@ -38,11 +119,70 @@ func Inspect(ctx context.Context, r string) (*claircore.Manifest, error) {
 	if err != nil {
 		return nil, err
 	}
-	img, err := desc.Image()
+	return &inspector{repo: repo, rt: rt, desc: desc}, nil
+}
+
+func isIndex(mt types.MediaType) bool {
+	return mt == types.OCIImageIndex || mt == types.DockerManifestList
+}
+
+// List reports the images in the manifest list. If "full" is set, each
+// image's layers are inspected; otherwise only the manifest digests and
+// platforms are filled in.
+func (ins *inspector) list(ctx context.Context, full bool) (*claircore.ManifestList, error) {
+	idx, err := ins.desc.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	im, err := idx.IndexManifest()
 	if err != nil {
 		return nil, err
 	}
+	h, err := idx.Digest()
+	if err != nil {
+		return nil, err
+	}
+	ccd, err := claircore.ParseDigest(h.String())
+	if err != nil {
+		return nil, err
+	}
+	out := claircore.ManifestList{Hash: ccd}
+	for _, d := range im.Manifests {
+		// Skip anything that's not an image, such as nested indexes or
+		// attestation manifests without a platform.
+		if d.Platform == nil || isIndex(d.MediaType) || d.Platform.OS == "unknown" {
+			continue
+		}
+		p := &claircore.Platform{
+			OS:           d.Platform.OS,
+			Architecture: d.Platform.Architecture,
+			Variant:      d.Platform.Variant,
+		}
+		if !full {
+			ccd, err := claircore.ParseDigest(d.Digest.String())
+			if err != nil {
+				return nil, err
+			}
+			out.Manifests = append(out.Manifests, &claircore.Manifest{Hash: ccd, Platform: p})
+			continue
+		}
+		img, err := idx.Image(d.Digest)
+		if err != nil {
+			return nil, err
+		}
+		m, err := ins.manifest(ctx, img, p)
+		if err != nil {
+			return nil, err
+		}
+		out.Manifests = append(out.Manifests, m)
+	}
+	return &out, nil
+}
 
+// Manifest builds a claircore.Manifest for the image, with layer URIs
+// pointing at the registry's blob endpoint.
+func (ins *inspector) manifest(ctx context.Context, img v1.Image, p *claircore.Platform) (*claircore.Manifest, error) {
+	repo := ins.repo
 	h, err := img.Digest()
 	if err != nil {
 		return nil, err
@@ -52,20 +192,43 @@ func Inspect(ctx context.Context, r string) (*claircore.Manifest, error) {
 		return nil, err
 	}
 	out := claircore.Manifest{
-		Hash: ccd,
+		Hash:     ccd,
+		Platform: p,
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	out.Labels = cfg.Config.Labels
+	for _, h := range cfg.History {
+		out.History = append(out.History, claircore.History{
+			CreatedBy:  h.CreatedBy,
+			EmptyLayer: h.EmptyLayer,
+		})
 	}
 
 	ls, err := img.Layers()
 	if err != nil {
 		return nil, err
 	}
+	// Foreign layers declare the URLs they can be fetched from.
+	mf, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	urls := make(map[string][]string)
+	for _, d := range mf.Layers {
+		if len(d.URLs) != 0 {
+			urls[d.Digest.String()] = d.URLs
+		}
+	}
 
 	rURL := url.URL{
 		Scheme: repo.Scheme(),
 		Host:   repo.RegistryStr(),
 	}
 	c := http.Client{
-		Transport: rt,
+		Transport: ins.rt,
 	}
 
 	for _, l := range ls {
@@ -96,6 +259,7 @@ func Inspect(ctx context.Context, r string) (*claircore.Manifest, error) {
 			Hash:    ccd,
 			URI:     res.Request.URL.String(),
 			Headers: res.Request.Header,
+			URLs:    urls[d.String()],
 		})
 	}
 
@@ -56,6 +56,8 @@ func main() {
 		fmt.Fprintln(out, "\tgenerate manifests for containers provided as arguments or on stdin")
 		fmt.Fprintln(out, "unpack")
 		fmt.Fprintln(out, "\textracts each container's layers content for inspection")
+		fmt.Fprintln(out, "paths")
+		fmt.Fprintln(out, "\texplain which scanners look at which files in the provided layers")
 		fmt.Fprintln(out, "run-updaters")
 		fmt.Fprintln(out, "\trun default updaters and produce an artifact for later importing")
 		fmt.Fprintln(out, "load-updates")
@@ -75,6 +77,8 @@ func main() {
 		cmd = Manifest
 	case "unpack":
 		cmd = Unpack
+	case "paths":
+		cmd = Paths
 	case "run-updaters":
 		cmd = RunUpdaters
 	case "load-updates":
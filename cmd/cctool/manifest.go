@@ -15,8 +15,10 @@ import (
 )
 
 type manifestConfig struct {
-	timeout time.Duration
-	pretty  bool
+	timeout  time.Duration
+	pretty   bool
+	platform string
+	all      bool
 }
 
 // Manifest is the subcommand for generating container manifests.
@@ -24,6 +26,8 @@ func Manifest(cmd context.Context, cfg *commonConfig, args []string) error {
 	cmdcfg := manifestConfig{}
 	fs := flag.NewFlagSet("cctool manifest", flag.ExitOnError)
 	fs.DurationVar(&cmdcfg.timeout, "timeout", 5*time.Minute, "timeout for successful http responses")
+	fs.StringVar(&cmdcfg.platform, "platform", "", "platform (os/arch[/variant]) to generate a manifest for, for manifest lists")
+	fs.BoolVar(&cmdcfg.all, "all", false, "generate a manifest list with a manifest for every platform")
 	fs.Parse(args)
 
 	images := fs.Args()
@@ -50,7 +54,13 @@ func Manifest(cmd context.Context, cfg *commonConfig, args []string) error {
 		i := i
 		go func() {
 			defer wg.Done()
-			m, err := Inspect(ctx, img)
+			var m interface{}
+			var err error
+			if cmdcfg.all {
+				m, err = InspectList(ctx, img)
+			} else {
+				m, err = Inspect(ctx, img, cmdcfg.platform)
+			}
 			if err != nil {
 				eo.Do(func() { errd = true })
 				errs[i] = err
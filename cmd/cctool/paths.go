@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/libindex"
+)
+
+type pathsConfig struct {
+	all bool
+}
+
+// Paths is the subcommand for explaining which scanners look at which files
+// in a layer.
+func Paths(cmd context.Context, cfg *commonConfig, args []string) error {
+	cmdcfg := pathsConfig{}
+	fs := flag.NewFlagSet("cctool paths", flag.ExitOnError)
+	fs.BoolVar(&cmdcfg.all, "a", false, "also list scanners that would skip the layer")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage:\n")
+		fmt.Fprintf(out, "\tcctool paths [-a] <layer.tar>...\n")
+		fmt.Fprintf(out, "Arguments:\n")
+		fmt.Fprintf(out, "\tlayer.tar: an uncompressed layer, as written by `unpack`\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return nil
+	}
+
+	// Use the same scanners a default Libindex would.
+	opts := libindex.Opts{Ephemeral: true}
+	if err := opts.Parse(cmd); err != nil {
+		return err
+	}
+	ps, ds, rs, err := indexer.EcosystemsToScanners(cmd, opts.Ecosystems, false)
+	if err != nil {
+		return err
+	}
+	var vs indexer.VersionedScanners
+	for _, s := range ps {
+		vs = append(vs, s)
+	}
+	for _, s := range ds {
+		vs = append(vs, s)
+	}
+	for _, s := range rs {
+		vs = append(vs, s)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	defer w.Flush()
+	for _, name := range fs.Args() {
+		if _, err := os.Stat(name); err != nil {
+			return err
+		}
+		var l claircore.Layer
+		if err := l.SetLocal(name); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, name)
+		for _, s := range vs {
+			pd, ok := s.(indexer.PathDeclarer)
+			if !ok {
+				fmt.Fprintf(w, "\t%s\t%s\t(any file)\n", s.Name(), s.Kind())
+				continue
+			}
+			var found []string
+			for _, p := range pd.Paths() {
+				ns, err := l.Glob(p)
+				if err != nil {
+					return fmt.Errorf("%s: bad declared path %q: %w", s.Name(), p, err)
+				}
+				found = append(found, ns...)
+			}
+			switch {
+			case len(found) != 0:
+				fmt.Fprintf(w, "\t%s\t%s\t%s\n", s.Name(), s.Kind(), strings.Join(found, " "))
+			case cmdcfg.all:
+				fmt.Fprintf(w, "\t%s\t%s\t(skipped: none of %s)\n", s.Name(), s.Kind(), strings.Join(pd.Paths(), " "))
+			}
+		}
+	}
+	return nil
+}
@@ -94,6 +94,7 @@ var (
 
 type reportConfig struct {
 	jqFilter          string
+	platform          string
 	timeout           time.Duration
 	libindex, libvuln *url.URL
 	dump              bool
@@ -108,6 +109,7 @@ func Report(cmd context.Context, cfg *commonConfig, args []string) error {
 	fs := flag.NewFlagSet("cctool report", flag.ExitOnError)
 	fs.StringVar(&cmdcfg.jqFilter, "jq", "", "run a jq filter on the manifest index before sending for matching")
 	fs.DurationVar(&cmdcfg.timeout, "timeout", 5*time.Minute, "timeout for successful http responses")
+	fs.StringVar(&cmdcfg.platform, "platform", "", "platform (os/arch[/variant]) to report on for manifest lists")
 	fs.BoolVar(&cmdcfg.dump, "dump", false, "dump indexreports to file described by dump-fmt")
 	useJunitReport := fs.Bool("junit", false, "produce jUnit compatible report (instead of tabwriter)")
 	libindexRoot := fs.String("libindex", "http://localhost:8080/", "address for a libindex api server")
@@ -236,7 +238,7 @@ func Report(cmd context.Context, cfg *commonConfig, args []string) error {
 }
 
 func runManifest(ctx context.Context, img string, cfg *commonConfig, cmdcfg *reportConfig) (*claircore.VulnerabilityReport, error) {
-	m, err := Inspect(ctx, img)
+	m, err := Inspect(ctx, img, cmdcfg.platform)
 	if err != nil {
 		return nil, err
 	}
@@ -19,7 +19,8 @@ import (
 )
 
 type unpackConfig struct {
-	timeout time.Duration
+	timeout  time.Duration
+	platform string
 }
 
 // Unpack will decompress and untar each layer of the provided image ref
@@ -27,6 +28,7 @@ func Unpack(cmd context.Context, cfg *commonConfig, args []string) error {
 	cmdcfg := unpackConfig{}
 	fs := flag.NewFlagSet("cctool unpack", flag.ExitOnError)
 	fs.DurationVar(&cmdcfg.timeout, "timeout", 5*time.Minute, "timeout for successful http responses")
+	fs.StringVar(&cmdcfg.platform, "platform", "", "platform (os/arch[/variant]) to unpack for manifest lists")
 	fs.Usage = func() {
 		out := fs.Output()
 		fmt.Fprintf(out, "Usage:\n")
@@ -47,7 +49,7 @@ func Unpack(cmd context.Context, cfg *commonConfig, args []string) error {
 	defer done()
 
 	// inspect image reference and get manifest
-	m, err := Inspect(ctx, image)
+	m, err := Inspect(ctx, image, cmdcfg.platform)
 	if err != nil {
 		return err
 	}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/quay/zlog"
@@ -15,6 +16,7 @@ import (
 	"github.com/quay/claircore/libvuln"
 	"github.com/quay/claircore/libvuln/jsonblob"
 	"github.com/quay/claircore/libvuln/updates"
+	"github.com/quay/claircore/pkg/clock"
 	_ "github.com/quay/claircore/updater/defaults"
 )
 
@@ -25,8 +27,12 @@ func RunUpdaters(cmd context.Context, cfg *commonConfig, args []string) error {
 	// Strict controls whether the command should exit non-zero if any
 	// updater fails.
 	var strict bool
+	// Now, if set, is used as the time for the run, so that the same feeds
+	// produce the same output.
+	var now string
 	fs := flag.NewFlagSet("cctool run-updaters", flag.ExitOnError)
 	fs.BoolVar(&strict, "strict", false, "exit non-zero is any updater fails")
+	fs.StringVar(&now, "now", "", "RFC 3339 time to record for updates, for reproducible output")
 	fs.Usage = func() {
 		out := fs.Output()
 		fmt.Fprintf(out, "Usage:\n")
@@ -35,6 +41,14 @@ func RunUpdaters(cmd context.Context, cfg *commonConfig, args []string) error {
 		fmt.Fprintf(out, "\toutfile: a filename to write results to. (default: stdout)\n\n")
 	}
 	fs.Parse(args)
+	var opts []updates.ManagerOption
+	if now != "" {
+		t, err := time.Parse(time.RFC3339, now)
+		if err != nil {
+			return fmt.Errorf("bad -now flag: %w", err)
+		}
+		opts = append(opts, updates.WithClock(clock.Fixed(t)))
+	}
 
 	var out io.Writer
 	switch len(fs.Args()) {
@@ -65,7 +79,7 @@ func RunUpdaters(cmd context.Context, cfg *commonConfig, args []string) error {
 			zlog.Warn(ctx).Err(err).Send()
 		}
 	}()
-	mgr, err := updates.NewManager(ctx, store, updates.NewLocalLockSource(), http.DefaultClient)
+	mgr, err := updates.NewManager(ctx, store, updates.NewLocalLockSource(), http.DefaultClient, opts...)
 	if err != nil {
 		return err
 	}
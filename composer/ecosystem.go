@@ -0,0 +1,21 @@
+package composer
+
+import (
+	"context"
+
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// NewEcosystem provides the set of scanners and coalescers for PHP
+// packages installed by Composer.
+func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
+	return &indexer.Ecosystem{
+		Name: "composer",
+		PackageScanners: func(_ context.Context) ([]indexer.PackageScanner, error) {
+			return []indexer.PackageScanner{&Scanner{}}, nil
+		},
+		DistributionScanners: func(_ context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+		RepositoryScanners:   func(_ context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		Coalescer:            NewCoalescer,
+	}
+}
@@ -0,0 +1,237 @@
+package composer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Composer 2 also writes "installed.php", a PHP file returning an array
+// literal:
+//
+//	<?php return array(
+//	    'root' => array(
+//	        'name' => 'acme/app',
+//	        'pretty_version' => 'dev-main',
+//	        ...
+//	    ),
+//	    'versions' => array(
+//	        'monolog/monolog' => array(
+//	            'pretty_version' => '2.3.5',
+//	            'version' => '2.3.5.0',
+//	            'reference' => 'fd4380d6fc37626e2f799f29d91195040137eba9',
+//	            'install_path' => __DIR__ . '/../monolog/monolog',
+//	            'dev_requirement' => false,
+//	        ),
+//	        ...
+//
+// Rather than evaluate PHP, the literal is parsed by a small parser that
+// understands arrays, strings, and bare words. Anything else is an error.
+
+// ParsePHP parses an installed.php file.
+func parsePHP(b []byte) ([]installed, error) {
+	s := string(b)
+	i := strings.Index(s, "return")
+	if i == -1 {
+		return nil, errors.New("composer: no return statement")
+	}
+	p := phpParser{s: s[i+len("return"):]}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	top, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("composer: unexpected installed.php structure")
+	}
+	var root string
+	if r, ok := top["root"].(map[string]interface{}); ok {
+		root, _ = r["name"].(string)
+	}
+	vs, ok := top["versions"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("composer: missing versions")
+	}
+	out := make([]installed, 0, len(vs))
+	for name, v := range vs {
+		m, ok := v.(map[string]interface{})
+		if !ok || name == root {
+			continue
+		}
+		// Virtual packages that are only provided or replaced by others
+		// have no version of their own.
+		ver, _ := m["pretty_version"].(string)
+		ref, _ := m["reference"].(string)
+		out = append(out, installed{Name: name, Version: ver, Reference: ref})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// PhpParser is a recursive-descent parser for PHP array literals.
+//
+// Arrays are returned as map[string]interface{}, with integer keys formatted
+// as strings. The literals true, false, and null are returned as bools and
+// nil; strings and other bare words (numbers, __DIR__) are returned as strings.
+// Concatenations are joined.
+type phpParser struct {
+	s string
+}
+
+func (p *phpParser) skipSpace() {
+	for {
+		p.s = strings.TrimLeft(p.s, " \t\r\n")
+		switch {
+		case strings.HasPrefix(p.s, "//"), strings.HasPrefix(p.s, "#"):
+			if i := strings.IndexByte(p.s, '\n'); i != -1 {
+				p.s = p.s[i:]
+				continue
+			}
+			p.s = ""
+		case strings.HasPrefix(p.s, "/*"):
+			if i := strings.Index(p.s, "*/"); i != -1 {
+				p.s = p.s[i+2:]
+				continue
+			}
+			p.s = ""
+		}
+		return
+	}
+}
+
+// Consume reports whether the input begins with "tok", consuming it if so.
+func (p *phpParser) consume(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.s, tok) {
+		p.s = p.s[len(tok):]
+		return true
+	}
+	return false
+}
+
+func (p *phpParser) value() (interface{}, error) {
+	v, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	for p.consume(".") {
+		t, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		ts, ok := t.(string)
+		if !ok {
+			return nil, errors.New("composer: concatenation of non-string")
+		}
+		s += ts
+	}
+	return s, nil
+}
+
+func (p *phpParser) term() (interface{}, error) {
+	p.skipSpace()
+	switch {
+	case p.s == "":
+		return nil, errors.New("composer: unexpected end of input")
+	case p.consume("array("):
+		return p.array(")")
+	case p.consume("["):
+		return p.array("]")
+	case p.s[0] == '\'' || p.s[0] == '"':
+		return p.str()
+	}
+	// Bare word or number.
+	i := strings.IndexFunc(p.s, func(r rune) bool {
+		return !(r == '_' || r == '-' ||
+			r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	if i == 0 {
+		return nil, fmt.Errorf("composer: unexpected character %q", p.s[0])
+	}
+	if i == -1 {
+		i = len(p.s)
+	}
+	w := p.s[:i]
+	p.s = p.s[i:]
+	switch strings.ToLower(w) {
+	case "null":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return w, nil
+}
+
+func (p *phpParser) array(end string) (interface{}, error) {
+	out := make(map[string]interface{})
+	for n := 0; ; n++ {
+		if p.consume(end) {
+			return out, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if p.consume("=>") {
+			k, ok := v.(string)
+			if !ok {
+				return nil, errors.New("composer: array key is not a scalar")
+			}
+			if v, err = p.value(); err != nil {
+				return nil, err
+			}
+			out[k] = v
+		} else {
+			out[fmt.Sprint(n)] = v
+		}
+		if !p.consume(",") {
+			if !p.consume(end) {
+				return nil, errors.New("composer: malformed array")
+			}
+			return out, nil
+		}
+	}
+}
+
+// Str parses a quoted string, handling the escapes PHP processes.
+func (p *phpParser) str() (string, error) {
+	q := p.s[0]
+	var b strings.Builder
+	for i := 1; i < len(p.s); i++ {
+		c := p.s[i]
+		switch {
+		case c == q:
+			p.s = p.s[i+1:]
+			return b.String(), nil
+		case c == '\\' && i+1 < len(p.s):
+			n := p.s[i+1]
+			switch {
+			case n == q || n == '\\':
+				b.WriteByte(n)
+				i++
+				continue
+			case q == '"' && n == 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case q == '"' && n == 't':
+				b.WriteByte('\t')
+				i++
+				continue
+			case q == '"' && n == '$':
+				b.WriteByte('$')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return "", errors.New("composer: unterminated string")
+}
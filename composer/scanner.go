@@ -0,0 +1,250 @@
+// Package composer contains components for finding PHP packages installed by
+// Composer.
+package composer
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"path/filepath"
+	"runtime/trace"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	_ indexer.VersionedScanner   = (*Scanner)(nil)
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
+)
+
+// RepositoryHint is used for packages that don't record where they were
+// installed from.
+const RepositoryHint = "https://packagist.org"
+
+// MaxInstalled is the largest installed.json or installed.php that will be
+// read.
+const maxInstalled = 32 << 20
+
+// Scanner implements the indexer.PackageScanner interface.
+//
+// It looks for the "installed.json" file Composer writes into the "composer"
+// directory of a vendor directory, falling back to "installed.php" if that's
+// missing. Every vendor directory is reported as a separate PackageDB, so
+// applications in the same image are kept distinct.
+//
+// The RepositoryHint of a package is the URL of the package's source
+// repository, with the source reference (usually a commit) as the fragment,
+// if recorded.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements indexer.VersionedScanner.
+func (*Scanner) Name() string { return "composer" }
+
+// Version implements indexer.VersionedScanner.
+func (*Scanner) Version() string { return "1" }
+
+// Kind implements indexer.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceDeclared }
+
+// Scan attempts to find Composer-installed PHP packages.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "composer/Scanner.Scan"),
+		label.String("version", s.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// Both maps are keyed by vendor directory.
+	fromJSON := make(map[string][]installed)
+	fromPHP := make(map[string][]installed)
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+		if err != nil {
+			return nil, err
+		}
+		n = filepath.ToSlash(n)
+		dir, base := path.Split(n)
+		if path.Base(dir) != "composer" {
+			continue
+		}
+		var parse func([]byte) ([]installed, error)
+		var found map[string][]installed
+		switch base {
+		case "installed.json":
+			parse, found = parseJSON, fromJSON
+		case "installed.php":
+			parse, found = parsePHP, fromPHP
+		default:
+			continue
+		}
+		if h.Size > maxInstalled {
+			zlog.Info(ctx).
+				Str("file", n).
+				Int64("size", h.Size).
+				Msg("skipping oversized file")
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		ps, err := parse(b)
+		if err != nil {
+			zlog.Info(ctx).
+				Str("file", n).
+				Err(err).
+				Msg("unable to parse file, skipping")
+			continue
+		}
+		vendor := path.Dir(path.Dir(n))
+		found[vendor] = ps
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for vendor, ps := range fromPHP {
+		if _, ok := fromJSON[vendor]; !ok {
+			fromJSON[vendor] = ps
+		}
+	}
+
+	var ret []*claircore.Package
+	for vendor, ps := range fromJSON {
+		for _, p := range ps {
+			if p.Name == "" || p.Version == "" {
+				continue
+			}
+			ret = append(ret, &claircore.Package{
+				Name:           p.Name,
+				Version:        normVersion(p.Version),
+				Kind:           claircore.BINARY,
+				PackageDB:      "composer:" + vendor,
+				RepositoryHint: p.hint(),
+			})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].PackageDB != ret[j].PackageDB {
+			return ret[i].PackageDB < ret[j].PackageDB
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+// Installed is an installed package.
+type installed struct {
+	Name    string
+	Version string
+	// URL and Reference describe where the package's source came from.
+	URL       string
+	Reference string
+}
+
+// Hint returns the RepositoryHint for the package.
+func (p *installed) hint() string {
+	if p.URL == "" {
+		return RepositoryHint
+	}
+	if p.Reference == "" {
+		return p.URL
+	}
+	return p.URL + "#" + p.Reference
+}
+
+// NormVersion removes the "v" prefix commonly used in tags, which Composer
+// ignores when comparing versions.
+func normVersion(v string) string {
+	if len(v) > 1 && (v[0] == 'v' || v[0] == 'V') && v[1] >= '0' && v[1] <= '9' {
+		return v[1:]
+	}
+	return v
+}
+
+// JSONPackage is the subset of a package entry in installed.json used.
+type jsonPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  struct {
+		URL       string `json:"url"`
+		Reference string `json:"reference"`
+	} `json:"source"`
+	Dist struct {
+		URL       string `json:"url"`
+		Reference string `json:"reference"`
+	} `json:"dist"`
+}
+
+// ParseJSON parses an installed.json file.
+//
+// Composer 1 writes an array of packages, while Composer 2 writes an object
+// with the array in the "packages" member.
+func parseJSON(b []byte) ([]installed, error) {
+	var ps []jsonPackage
+	b = []byte(strings.TrimSpace(string(b)))
+	switch {
+	case len(b) == 0:
+		return nil, errors.New("composer: empty file")
+	case b[0] == '[':
+		if err := json.Unmarshal(b, &ps); err != nil {
+			return nil, err
+		}
+	default:
+		var v2 struct {
+			Packages []jsonPackage `json:"packages"`
+		}
+		if err := json.Unmarshal(b, &v2); err != nil {
+			return nil, err
+		}
+		ps = v2.Packages
+	}
+	out := make([]installed, 0, len(ps))
+	for _, p := range ps {
+		i := installed{
+			Name:      p.Name,
+			Version:   p.Version,
+			URL:       p.Source.URL,
+			Reference: p.Source.Reference,
+		}
+		if i.URL == "" {
+			i.URL, i.Reference = p.Dist.URL, p.Dist.Reference
+		}
+		out = append(out, i)
+	}
+	return out, nil
+}
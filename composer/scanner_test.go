@@ -0,0 +1,196 @@
+package composer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
+)
+
+// Written by Composer 2.
+const installedV2 = `{
+    "packages": [
+        {
+            "name": "monolog/monolog",
+            "version": "2.3.5",
+            "version_normalized": "2.3.5.0",
+            "source": {
+                "type": "git",
+                "url": "https://github.com/Seldaek/monolog.git",
+                "reference": "fd4380d6fc37626e2f799f29d91195040137eba9"
+            },
+            "dist": {
+                "type": "zip",
+                "url": "https://api.github.com/repos/Seldaek/monolog/zipball/fd4380d6fc37626e2f799f29d91195040137eba9",
+                "reference": "fd4380d6fc37626e2f799f29d91195040137eba9"
+            }
+        },
+        {
+            "name": "psr/log",
+            "version": "v1.1.4",
+            "dist": {
+                "type": "zip",
+                "url": "https://api.github.com/repos/php-fig/log/zipball/d49695b909c3b7628b6289db5479a1c204601f11",
+                "reference": "d49695b909c3b7628b6289db5479a1c204601f11"
+            }
+        }
+    ],
+    "dev": true,
+    "dev-package-names": []
+}`
+
+// Written by Composer 1.
+const installedV1 = `[
+    {
+        "name": "symfony/polyfill-ctype",
+        "version": "v1.23.0",
+        "source": {
+            "type": "git",
+            "url": "https://github.com/symfony/polyfill-ctype.git",
+            "reference": "46cd95797e9df938fdd2b03693b5fca5e64b01ce"
+        }
+    }
+]`
+
+const installedPHP = `<?php return array(
+    'root' => array(
+        'name' => 'acme/app',
+        'pretty_version' => 'dev-main',
+        'version' => 'dev-main',
+        'reference' => null,
+        'type' => 'project',
+        'install_path' => __DIR__ . '/../../',
+        'aliases' => array(),
+        'dev' => true,
+    ),
+    'versions' => array(
+        'acme/app' => array(
+            'pretty_version' => 'dev-main',
+            'version' => 'dev-main',
+            'reference' => null,
+            'type' => 'project',
+            'install_path' => __DIR__ . '/../../',
+            'aliases' => array(),
+            'dev_requirement' => false,
+        ),
+        'guzzlehttp/guzzle' => array(
+            'pretty_version' => '7.4.1',
+            'version' => '7.4.1.0',
+            'reference' => 'ee0a041b1760e6a53d2a39c8c34115adc2af2c79',
+            'type' => 'library',
+            'install_path' => __DIR__ . '/../guzzlehttp/guzzle',
+            'aliases' => array(),
+            'dev_requirement' => false,
+        ),
+        // Provided by guzzle, so there's no version.
+        'psr/http-client-implementation' => array(
+            'dev_requirement' => false,
+            'provided' => array(
+                0 => '1.0',
+            ),
+        ),
+    ),
+);
+`
+
+func TestScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := fixture.Layer{
+		{Name: "srv/app/vendor/composer/installed.json", Body: installedV2},
+		// Ignored in favor of the installed.json.
+		{Name: "srv/app/vendor/composer/installed.php", Body: installedPHP},
+		{Name: "srv/legacy/vendor/composer/installed.json", Body: installedV1},
+		{Name: "opt/tool/vendor/composer/installed.php", Body: installedPHP},
+		// Not in a composer directory.
+		{Name: "srv/other/installed.json", Body: installedV1},
+		// Not parseable.
+		{Name: "srv/broken/vendor/composer/installed.json", Body: "{"},
+	}.Local(t)
+
+	got, err := (&Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Package{
+		{
+			Name:           "guzzlehttp/guzzle",
+			Version:        "7.4.1",
+			Kind:           claircore.BINARY,
+			PackageDB:      "composer:opt/tool/vendor",
+			RepositoryHint: RepositoryHint,
+		},
+		{
+			Name:           "monolog/monolog",
+			Version:        "2.3.5",
+			Kind:           claircore.BINARY,
+			PackageDB:      "composer:srv/app/vendor",
+			RepositoryHint: "https://github.com/Seldaek/monolog.git#fd4380d6fc37626e2f799f29d91195040137eba9",
+		},
+		{
+			Name:           "psr/log",
+			Version:        "1.1.4",
+			Kind:           claircore.BINARY,
+			PackageDB:      "composer:srv/app/vendor",
+			RepositoryHint: "https://api.github.com/repos/php-fig/log/zipball/d49695b909c3b7628b6289db5479a1c204601f11#d49695b909c3b7628b6289db5479a1c204601f11",
+		},
+		{
+			Name:           "symfony/polyfill-ctype",
+			Version:        "1.23.0",
+			Kind:           claircore.BINARY,
+			PackageDB:      "composer:srv/legacy/vendor",
+			RepositoryHint: "https://github.com/symfony/polyfill-ctype.git#46cd95797e9df938fdd2b03693b5fca5e64b01ce",
+		},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestParsePHP(t *testing.T) {
+	tt := []struct {
+		Name string
+		In   string
+		Want []installed
+		Err  bool
+	}{
+		{
+			Name: "ShortArrays",
+			In: `<?php return [
+    'root' => ['name' => "acme/app"],
+    "versions" => [
+        'doctrine/lexer' => ['pretty_version' => "1.2.1", 'reference' => 'e864bbf5904cb8f5bb334f99209b48018522f042'],
+        'it\'s/odd' => ['pretty_version' => '0.1'],
+    ],
+];`,
+			Want: []installed{
+				{Name: "doctrine/lexer", Version: "1.2.1", Reference: "e864bbf5904cb8f5bb334f99209b48018522f042"},
+				{Name: "it's/odd", Version: "0.1"},
+			},
+		},
+		{
+			Name: "NoReturn",
+			In:   `<?php echo "hi";`,
+			Err:  true,
+		},
+		{
+			Name: "Unterminated",
+			In:   `<?php return array('versions' => array('a/b' => array('pretty_version' => '1.0`,
+			Err:  true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := parsePHP([]byte(tc.In))
+			if (err != nil) != tc.Err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package dbimport
+
+import (
+	"strings"
+
+	pep440 "github.com/aquasecurity/go-pep440-version"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/debian"
+	"github.com/quay/claircore/python"
+	"github.com/quay/claircore/ubuntu"
+)
+
+// Target describes how advisories from a source in a database are turned into
+// vulnerabilities the matchers can use.
+type target struct {
+	dist *claircore.Distribution
+	repo *claircore.Repository
+	// Kind is the kind of package advisories name.
+	kind string
+	// Python reports whether the advisories are for Python packages, which
+	// are matched by version specifier instead of fixed version.
+	python bool
+}
+
+// PythonRepo is the repository Python vulnerabilities are associated with.
+var pythonRepo = python.Repository
+
+// DistTarget returns the target for a distribution's advisories, or nil if
+// it's not one that's imported.
+//
+// Debian and Ubuntu advisories name source packages.
+func distTarget(id, version string) *target {
+	var d *claircore.Distribution
+	kind := claircore.SOURCE
+	switch strings.ToLower(id) {
+	case "debian":
+		d = debian.DistributionFor(version)
+	case "ubuntu":
+		d = ubuntu.DistributionFor(version)
+	case "alpine":
+		d = alpine.DistributionFor(version)
+		kind = claircore.BINARY
+	}
+	if d == nil {
+		return nil
+	}
+	return &target{dist: d, kind: kind}
+}
+
+// LanguageTarget returns the target for a language ecosystem's advisories, or
+// nil if it's not one that's imported.
+func languageTarget(lang string) *target {
+	switch strings.ToLower(lang) {
+	case "python", "pip", "pypi":
+		return &target{repo: &pythonRepo, kind: claircore.BINARY, python: true}
+	}
+	return nil
+}
+
+// Package returns the package for an advisory about the named package.
+func (t *target) Package(name string) *claircore.Package {
+	if t.python {
+		name = python.NormalizeName(name)
+	}
+	return &claircore.Package{Name: name, Kind: t.kind}
+}
+
+// Specifier normalizes a version constraint into a PEP 440 specifier, reporting
+// false if it's not valid.
+//
+// Constraints are written with spaces between operators and versions in some
+// databases, which the specifier parser doesn't allow.
+func specifier(c string) (string, bool) {
+	s := strings.Join(strings.Fields(c), "")
+	if s == "" {
+		return "", false
+	}
+	if _, err := pep440.NewSpecifiers(s); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// Severity maps a severity name, as both databases use, to a claircore
+// Severity.
+func severity(s string) claircore.Severity {
+	switch strings.ToLower(s) {
+	case "negligible":
+		return claircore.Negligible
+	case "low":
+		return claircore.Low
+	case "medium", "moderate":
+		return claircore.Medium
+	case "high", "important":
+		return claircore.High
+	case "critical":
+		return claircore.Critical
+	}
+	return claircore.Unknown
+}
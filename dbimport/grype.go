@@ -0,0 +1,150 @@
+package dbimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/sqlite"
+)
+
+// The Grype database is a SQLite database with a "vulnerability" table
+// holding a row per affected package, and a "vulnerability_metadata" table
+// holding the details of each vulnerability. Both are keyed by the
+// vulnerability ID and the namespace of its source, such as
+// "debian:distro:debian:11" or "github:language:python". Older schemas use
+// shorter namespaces, like "debian:11" and "github:python".
+
+// GrypeTarget returns the target for the namespace, or nil if it's not
+// imported.
+func grypeTarget(ns string) *target {
+	f := strings.Split(ns, ":")
+	switch {
+	case len(f) == 4 && f[1] == "distro":
+		return distTarget(f[2], f[3])
+	case len(f) == 3 && f[1] == "language":
+		return languageTarget(f[2])
+	case len(f) == 2 && f[0] == "github":
+		return languageTarget(f[1])
+	case len(f) == 2:
+		return distTarget(f[0], f[1])
+	}
+	return nil
+}
+
+// GrypeMetadata is the part of a "vulnerability_metadata" row used.
+type grypeMetadata struct {
+	severity    string
+	links       string
+	description string
+}
+
+// ParseGrype reads the Grype database in "r".
+func parseGrype(ctx context.Context, updater string, r io.ReaderAt) ([]*claircore.Vulnerability, error) {
+	db, err := sqlite.Open(r)
+	if err != nil {
+		return nil, fmt.Errorf("dbimport: unable to open grype database: %w", err)
+	}
+	if !db.HasTable("vulnerability") || !db.HasTable("vulnerability_metadata") {
+		return nil, fmt.Errorf("dbimport: not a grype database")
+	}
+
+	meta := make(map[[2]string]grypeMetadata)
+	err = db.Scan("vulnerability_metadata", func(row sqlite.Row) error {
+		m := grypeMetadata{
+			severity:    row.String("severity"),
+			description: row.String("description"),
+		}
+		if u := row.String("urls"); u != "" {
+			var urls []string
+			if err := json.Unmarshal([]byte(u), &urls); err != nil {
+				return fmt.Errorf("dbimport: metadata for %q: %w", row.String("id"), err)
+			}
+			m.links = strings.Join(urls, " ")
+		}
+		meta[[2]string{row.String("id"), row.String("namespace")}] = m
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*claircore.Vulnerability
+	var skipped, bad int
+	err = db.Scan("vulnerability", func(row sqlite.Row) error {
+		id, ns, name := row.String("id"), row.String("namespace"), row.String("package_name")
+		t := grypeTarget(ns)
+		if t == nil {
+			skipped++
+			return nil
+		}
+		m := meta[[2]string{id, ns}]
+		proto := claircore.Vulnerability{
+			Updater:            updater,
+			Name:               id,
+			Description:        m.description,
+			Links:              m.links,
+			Severity:           m.severity,
+			NormalizedSeverity: severity(m.severity),
+			Dist:               t.dist,
+			Repo:               t.repo,
+			Package:            t.Package(name),
+		}
+		if proto.Severity == "" {
+			proto.Severity = "Unknown"
+		}
+		if row.String("fix_state") == "wont-fix" {
+			proto.FixState = "wont-fix"
+		}
+		c := row.String("version_constraint")
+
+		if !t.python {
+			v := proto
+			var fixed []string
+			if f := row.String("fixed_in_versions"); f != "" {
+				if err := json.Unmarshal([]byte(f), &fixed); err != nil {
+					return fmt.Errorf("dbimport: %s: %s: %q: %w", ns, name, id, err)
+				}
+			}
+			switch {
+			case len(fixed) != 0:
+				v.FixedInVersion = fixed[0]
+			case strings.HasPrefix(c, "<") && !strings.HasPrefix(c, "<="):
+				v.FixedInVersion = strings.TrimSpace(c[1:])
+			}
+			out = append(out, &v)
+			return nil
+		}
+		// Alternatives are separated by "||", and each is a separate
+		// specifier.
+		for _, r := range strings.Split(c, "||") {
+			s, ok := specifier(r)
+			if !ok {
+				bad++
+				continue
+			}
+			v := proto
+			v.Package = t.Package(name)
+			v.Package.Version = s
+			out = append(out, &v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	zlog.Debug(ctx).
+		Int("skipped", skipped).
+		Msg("skipped unsupported namespaces")
+	if bad > 0 {
+		zlog.Debug(ctx).
+			Int("count", bad).
+			Msg("skipped malformed version constraints")
+	}
+	return out, nil
+}
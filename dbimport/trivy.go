@@ -0,0 +1,200 @@
+package dbimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quay/zlog"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/quay/claircore"
+)
+
+// The Trivy database is a bolt database with a bucket per advisory source,
+// like "debian 11" or "pip::GitHub Security Advisory pip", holding a bucket
+// per package, holding advisories keyed by vulnerability ID. The details of
+// each vulnerability are in the "vulnerability" bucket.
+
+// TrivyAdvisory is an advisory in a package's bucket.
+type trivyAdvisory struct {
+	FixedVersion       string   `json:"FixedVersion"`
+	VulnerableVersions []string `json:"VulnerableVersions"`
+	Status             int      `json:"Status"`
+	Severity           int      `json:"Severity"`
+}
+
+// TrivyVulnerability is the entry for a vulnerability in the "vulnerability"
+// bucket.
+type trivyVulnerability struct {
+	Title          string         `json:"Title"`
+	Description    string         `json:"Description"`
+	Severity       string         `json:"Severity"`
+	VendorSeverity map[string]int `json:"VendorSeverity"`
+	References     []string       `json:"References"`
+	PublishedDate  time.Time      `json:"PublishedDate"`
+}
+
+// Trivy advisory statuses.
+const (
+	trivyNotAffected = 1
+	trivyWillNotFix  = 5
+	trivyFixDeferred = 6
+	trivyEndOfLife   = 7
+)
+
+// TrivySeverities are the names of Trivy's numeric severities.
+var trivySeverities = []string{"UNKNOWN", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// TrivyTarget returns the target for the named source bucket, along with the
+// key of its vendor severity, or nil if it's not imported.
+func trivyTarget(name string) (*target, string) {
+	if i := strings.Index(name, "::"); i != -1 {
+		vendor := "osv"
+		if strings.Contains(name[i:], "GitHub") {
+			vendor = "ghsa"
+		}
+		return languageTarget(name[:i]), vendor
+	}
+	f := strings.Fields(name)
+	if len(f) != 2 {
+		return nil, ""
+	}
+	return distTarget(f[0], f[1]), f[0]
+}
+
+// ParseTrivy reads the Trivy database at the path.
+func parseTrivy(ctx context.Context, updater, path string) ([]*claircore.Vulnerability, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("dbimport: unable to open trivy database: %w", err)
+	}
+	defer db.Close()
+
+	var out []*claircore.Vulnerability
+	var skipped, bad int
+	err = db.View(func(tx *bolt.Tx) error {
+		vb := tx.Bucket([]byte("vulnerability"))
+		if vb == nil {
+			return fmt.Errorf("dbimport: not a trivy database")
+		}
+		details := make(map[string]*trivyVulnerability)
+		lookup := func(id string) (*trivyVulnerability, error) {
+			if v, ok := details[id]; ok {
+				return v, nil
+			}
+			var v trivyVulnerability
+			if b := vb.Get([]byte(id)); b != nil {
+				if err := json.Unmarshal(b, &v); err != nil {
+					return nil, fmt.Errorf("dbimport: vulnerability %q: %w", id, err)
+				}
+			}
+			details[id] = &v
+			return &v, nil
+		}
+
+		return tx.ForEach(func(src []byte, sb *bolt.Bucket) error {
+			t, vendor := trivyTarget(string(src))
+			if t == nil {
+				skipped++
+				return nil
+			}
+			return sb.ForEach(func(pkg, v []byte) error {
+				pb := sb.Bucket(pkg)
+				if v != nil || pb == nil {
+					return nil
+				}
+				return pb.ForEach(func(id, b []byte) error {
+					var a trivyAdvisory
+					if err := json.Unmarshal(b, &a); err != nil {
+						return fmt.Errorf("dbimport: %s: %s: %q: %w", src, pkg, id, err)
+					}
+					if a.Status == trivyNotAffected {
+						return nil
+					}
+					d, err := lookup(string(id))
+					if err != nil {
+						return err
+					}
+					proto := claircore.Vulnerability{
+						Updater:     updater,
+						Name:        string(id),
+						Description: d.Description,
+						Issued:      d.PublishedDate,
+						Links:       strings.Join(d.References, " "),
+						Dist:        t.dist,
+						Repo:        t.repo,
+						Package:     t.Package(string(pkg)),
+					}
+					if proto.Description == "" {
+						proto.Description = d.Title
+					}
+					proto.Severity = trivySeverity(d, vendor, a.Severity)
+					proto.NormalizedSeverity = severity(proto.Severity)
+					switch a.Status {
+					case trivyWillNotFix:
+						proto.FixState = "will_not_fix"
+					case trivyFixDeferred:
+						proto.FixState = "fix_deferred"
+					case trivyEndOfLife:
+						proto.FixState = "end_of_life"
+					}
+
+					if !t.python {
+						v := proto
+						v.FixedInVersion = a.FixedVersion
+						out = append(out, &v)
+						return nil
+					}
+					// Each of the vulnerable ranges is a separate specifier.
+					for _, r := range a.VulnerableVersions {
+						s, ok := specifier(r)
+						if !ok {
+							bad++
+							continue
+						}
+						v := proto
+						v.Package = t.Package(string(pkg))
+						v.Package.Version = s
+						out = append(out, &v)
+					}
+					return nil
+				})
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	zlog.Debug(ctx).
+		Int("skipped", skipped).
+		Msg("skipped unsupported sources")
+	if bad > 0 {
+		zlog.Debug(ctx).
+			Int("count", bad).
+			Msg("skipped malformed version ranges")
+	}
+	return out, nil
+}
+
+// TrivySeverity returns the name of the severity for an advisory, preferring
+// the severity its source assigned.
+func trivySeverity(d *trivyVulnerability, vendor string, advisory int) string {
+	n := -1
+	if s, ok := d.VendorSeverity[vendor]; ok {
+		n = s
+	} else if advisory != 0 {
+		n = advisory
+	} else if s, ok := d.VendorSeverity["nvd"]; ok {
+		n = s
+	}
+	if n >= 0 && n < len(trivySeverities) {
+		return trivySeverities[n]
+	}
+	if d.Severity != "" {
+		return d.Severity
+	}
+	return trivySeverities[0]
+}
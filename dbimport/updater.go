@@ -0,0 +1,329 @@
+// Package dbimport provides an updater importing vulnerabilities from the
+// databases distributed for the Trivy and Grype scanners.
+//
+// This allows a mirror of one of those databases to be used as a
+// vulnerability source. Only the advisories claircore can match are imported:
+// those for Debian, Ubuntu, and Alpine packages, and for Python packages.
+package dbimport
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/tmp"
+)
+
+var (
+	_ driver.Updater      = (*Updater)(nil)
+	_ driver.Configurable = (*Updater)(nil)
+)
+
+// Updater imports a Trivy or Grype database.
+//
+// The database is fetched from a URL, which may use the "file" scheme to read
+// a local copy. It may be the bare database file, or a tarball containing it
+// named "trivy.db" or "vulnerability.db", either of which may be gzip
+// compressed. The format is detected from the file's contents.
+//
+// The zero value is not safe to use.
+type Updater struct {
+	url    *url.URL
+	client *http.Client
+	name   string
+}
+
+// NewUpdater returns a configured Updater or reports an error.
+//
+// The URL must be provided with WithURL or in the Updater's configuration
+// before it's used.
+func NewUpdater(opt ...Option) (*Updater, error) {
+	u := Updater{
+		name: "dbimport",
+	}
+	for _, f := range opt {
+		if err := f(&u); err != nil {
+			return nil, err
+		}
+	}
+	if u.client == nil {
+		u.client = http.DefaultClient // TODO(hank) Remove DefaultClient
+	}
+	return &u, nil
+}
+
+// Option controls the configuration of an Updater.
+type Option func(*Updater) error
+
+// WithClient sets the http.Client that the updater should use for requests.
+//
+// If not passed to NewUpdater, http.DefaultClient will be used.
+func WithClient(c *http.Client) Option {
+	return func(u *Updater) error {
+		u.client = c
+		return nil
+	}
+}
+
+// WithURL sets the URL the updater should fetch the database from.
+func WithURL(uri string) Option {
+	u, err := url.Parse(uri)
+	return func(up *Updater) error {
+		if err != nil {
+			return err
+		}
+		up.url = u
+		return nil
+	}
+}
+
+// WithName sets the name the updater reports, which is recorded in the
+// vulnerabilities it imports.
+//
+// This allows more than one database to be imported. If not passed to
+// NewUpdater, the name is "dbimport".
+func WithName(n string) Option {
+	return func(u *Updater) error {
+		if n == "" {
+			return errors.New("dbimport: empty name")
+		}
+		u.name = n
+		return nil
+	}
+}
+
+// Config is the configuration for the updater.
+//
+// By convention, this is in a map called "dbimport", or the name provided to
+// WithName.
+type Config struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+// Configure implements driver.Configurable.
+func (u *Updater) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "dbimport/Updater.Configure"))
+	var cfg Config
+	if err := f(&cfg); err != nil {
+		return err
+	}
+
+	if cfg.URL != "" {
+		uri, err := url.Parse(cfg.URL)
+		if err != nil {
+			return err
+		}
+		u.url = uri
+		zlog.Info(ctx).
+			Msg("configured URL")
+	}
+	u.client = c
+	zlog.Info(ctx).
+		Msg("configured HTTP client")
+	return nil
+}
+
+// Name implements driver.Updater.
+func (u *Updater) Name() string { return u.name }
+
+// Fetch implements driver.Updater.
+//
+// The returned fingerprint is the ETag of an HTTP response, or the size and
+// modification time of a local file.
+func (u *Updater) Fetch(ctx context.Context, hint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "dbimport/Updater.Fetch"))
+	if u.url == nil {
+		return nil, hint, errors.New("dbimport: no URL configured")
+	}
+	zlog.Info(ctx).Str("database", u.url.String()).Msg("starting fetch")
+
+	var rd io.Reader
+	var fp driver.Fingerprint
+	switch u.url.Scheme {
+	case "file":
+		f, err := os.Open(u.url.Path)
+		if err != nil {
+			return nil, hint, err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, hint, err
+		}
+		fp = driver.Fingerprint(fmt.Sprintf("%d-%d", fi.Size(), fi.ModTime().UnixNano()))
+		if fp == hint {
+			return nil, hint, driver.Unchanged
+		}
+		rd = f
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.url.String(), nil)
+		if err != nil {
+			return nil, hint, err
+		}
+		req.Header.Set("User-Agent", "claircore/dbimport/Updater")
+		if hint != "" {
+			zlog.Debug(ctx).
+				Str("hint", string(hint)).
+				Msg("using hint")
+			req.Header.Set("if-none-match", string(hint))
+		}
+		res, err := u.client.Do(req)
+		if res != nil {
+			defer res.Body.Close()
+		}
+		if err != nil {
+			return nil, hint, err
+		}
+		switch res.StatusCode {
+		case http.StatusNotModified:
+			return nil, hint, driver.Unchanged
+		case http.StatusOK:
+			// break
+		default:
+			return nil, hint, fmt.Errorf("dbimport: fetcher got unexpected HTTP response: %d (%s)", res.StatusCode, res.Status)
+		}
+		fp = driver.Fingerprint(res.Header.Get("etag"))
+		rd = res.Body
+	default:
+		return nil, hint, fmt.Errorf("dbimport: unsupported URL scheme %q", u.url.Scheme)
+	}
+
+	tf, err := tmp.NewFile("", "dbimport.")
+	if err != nil {
+		return nil, hint, err
+	}
+	zlog.Debug(ctx).
+		Str("path", tf.Name()).
+		Msg("using tempfile")
+	success := false
+	defer func() {
+		if !success {
+			zlog.Debug(ctx).Msg("unsuccessful, cleaning up tempfile")
+			if err := tf.Close(); err != nil {
+				zlog.Warn(ctx).Err(err).Msg("failed to close tempfile")
+			}
+		}
+	}()
+	if err := unpack(tf, rd); err != nil {
+		return nil, hint, err
+	}
+	if _, err := tf.Seek(0, io.SeekStart); err != nil {
+		return nil, hint, err
+	}
+	zlog.Debug(ctx).Msg("unpacked and buffered database")
+
+	if fp != "" {
+		zlog.Debug(ctx).
+			Str("hint", string(fp)).
+			Msg("using new hint")
+		hint = fp
+	}
+	success = true
+	return tf, hint, nil
+}
+
+// DBNames are the names the databases have in the archives they're
+// distributed in.
+var dbNames = map[string]bool{
+	"trivy.db":         true,
+	"vulnerability.db": true,
+}
+
+// Unpack copies the database in "r" to "w", decompressing it and extracting it
+// from a tarball as needed.
+func unpack(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(2); err == nil && b[0] == 0x1f && b[1] == 0x8b {
+		z, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer z.Close()
+		br = bufio.NewReader(z)
+	}
+	// Tar headers have a magic string at a fixed offset.
+	if b, err := br.Peek(262); err == nil && bytes.HasPrefix(b[257:], []byte("ustar")) {
+		tr := tar.NewReader(br)
+		for {
+			h, err := tr.Next()
+			switch {
+			case errors.Is(err, nil):
+			case errors.Is(err, io.EOF):
+				return errors.New("dbimport: no database found in archive")
+			default:
+				return err
+			}
+			if h.Typeflag != tar.TypeReg || !dbNames[path.Base(h.Name)] {
+				continue
+			}
+			_, err = io.Copy(w, tr)
+			return err
+		}
+	}
+	_, err := io.Copy(w, br)
+	return err
+}
+
+// Parse implements driver.Updater.
+func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "dbimport/Updater.Parse"))
+	zlog.Info(ctx).Msg("parse start")
+	defer r.Close()
+	defer zlog.Info(ctx).Msg("parse done")
+
+	// Both formats need random access, and bolt needs a file it can map.
+	f, ok := r.(*tmp.File)
+	if !ok {
+		var err error
+		f, err = tmp.NewFile("", "dbimport.")
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if _, err := io.Copy(f, r); err != nil {
+			return nil, err
+		}
+	}
+
+	var magic [16]byte
+	if _, err := f.ReadAt(magic[:], 0); err != nil {
+		return nil, fmt.Errorf("dbimport: unable to read database: %w", err)
+	}
+	var vs []*claircore.Vulnerability
+	var err error
+	if string(magic[:]) == sqliteMagic {
+		zlog.Debug(ctx).Msg("found grype database")
+		vs, err = parseGrype(ctx, u.name, f)
+	} else {
+		zlog.Debug(ctx).Msg("assuming trivy database")
+		vs, err = parseTrivy(ctx, u.name, f.Name())
+	}
+	if err != nil {
+		return nil, err
+	}
+	zlog.Debug(ctx).
+		Int("count", len(vs)).
+		Msg("found vulnerabilities")
+	return vs, nil
+}
+
+// SqliteMagic is the header string SQLite databases start with.
+const sqliteMagic = "SQLite format 3\x00"
@@ -0,0 +1,229 @@
+package dbimport
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Summarize flattens the vulnerabilities into sorted strings, for comparison.
+func summarize(vs []*claircore.Vulnerability) []string {
+	out := make([]string, len(vs))
+	for i, v := range vs {
+		where := ""
+		switch {
+		case v.Dist != nil:
+			where = v.Dist.PrettyName
+		case v.Repo != nil:
+			where = v.Repo.Name
+		}
+		out[i] = fmt.Sprintf("%s %s(%s) %q fixed:%q %s %v %q %q",
+			v.Name, v.Package.Name, v.Package.Kind, v.Package.Version,
+			v.FixedInVersion, where, v.NormalizedSeverity, v.FixState, v.Links)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func fetchParse(ctx context.Context, t *testing.T, u *Updater) ([]*claircore.Vulnerability, driver.Fingerprint) {
+	t.Helper()
+	rc, fp, err := u.Fetch(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := u.Fetch(ctx, fp); !errors.Is(err, driver.Unchanged) {
+		t.Errorf("refetch: got: %v, want: %v", err, driver.Unchanged)
+	}
+	vs, err := u.Parse(ctx, rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vs, fp
+}
+
+// The Grype database is served from a gzipped tarball on disk.
+func TestGrype(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	db, err := os.ReadFile("testdata/vulnerability.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "vulnerability-db.tar.gz")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := gzip.NewWriter(f)
+	tw := tar.NewWriter(z)
+	for _, e := range []struct {
+		name string
+		b    []byte
+	}{
+		{"metadata.json", []byte(`{"version":5}`)},
+		{"vulnerability.db", db},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.b)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(e.b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []io.Closer{tw, z, f} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, err := NewUpdater(WithURL("file://"+p), WithName("grype"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, _ := fetchParse(ctx, t, u)
+	want := []string{
+		`CVE-2021-3711 openssl(source) "" fixed:"1.1.1k-1+deb11u1" Debian GNU/Linux 11 (bullseye) High "" "https://security-tracker.debian.org/tracker/CVE-2021-3711"`,
+		`CVE-2021-3712 openssl(source) "" fixed:"1.1.1f-1ubuntu2.8" Ubuntu 20.04 LTS Medium "" "https://ubuntu.com/security/CVE-2021-3712"`,
+		`CVE-2022-0001 busybox(binary) "" fixed:"1.33.1-r7" Alpine Linux v3.14 Unknown "" ""`,
+		`CVE-2022-0002 zlib(source) "" fixed:"" Debian GNU/Linux 11 (bullseye) Unknown "wont-fix" ""`,
+		`GHSA-xxxx-yyyy-zzzz django(binary) ">=3.0,<3.1.13" fixed:"" pypi Critical "" "https://github.com/advisories/GHSA-xxxx-yyyy-zzzz"`,
+		`GHSA-xxxx-yyyy-zzzz django(binary) ">=3.2,<3.2.5" fixed:"" pypi Critical "" "https://github.com/advisories/GHSA-xxxx-yyyy-zzzz"`,
+	}
+	if got := summarize(vs); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	for _, v := range vs {
+		if v.Updater != "grype" {
+			t.Errorf("%s: unexpected updater %q", v.Name, v.Updater)
+		}
+	}
+}
+
+// The Trivy database is built by the test and served gzipped over HTTP.
+func TestTrivy(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	p := filepath.Join(t.TempDir(), "trivy.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(tx *bolt.Tx, path []string, v interface{}) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(path[0]))
+		if err != nil {
+			return err
+		}
+		for _, n := range path[1 : len(path)-1] {
+			if b, err = b.CreateBucketIfNotExists([]byte(n)); err != nil {
+				return err
+			}
+		}
+		j, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(path[len(path)-1]), j)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, e := range []struct {
+			path []string
+			v    interface{}
+		}{
+			{[]string{"vulnerability", "CVE-2021-3711"}, map[string]interface{}{
+				"Title":          "SM2 decryption buffer overflow",
+				"VendorSeverity": map[string]int{"nvd": 4, "debian": 3},
+				"References":     []string{"https://www.openssl.org/news/secadv/20210824.txt"},
+			}},
+			{[]string{"vulnerability", "CVE-2019-19844"}, map[string]interface{}{
+				"Description":    "Django account hijack",
+				"Severity":       "CRITICAL",
+				"VendorSeverity": map[string]int{"nvd": 4},
+			}},
+			{[]string{"debian 11", "openssl", "CVE-2021-3711"}, map[string]interface{}{"FixedVersion": "1.1.1k-1+deb11u1"}},
+			{[]string{"debian 11", "libxml2", "CVE-2022-1000"}, map[string]interface{}{"Status": trivyWillNotFix}},
+			{[]string{"ubuntu 22.04", "openssl", "CVE-2021-3711"}, map[string]interface{}{"Status": trivyNotAffected}},
+			{[]string{"alpine 3.14", "busybox", "CVE-2022-0001"}, map[string]interface{}{"FixedVersion": "1.33.1-r7", "Severity": 2}},
+			{[]string{"pip::GitHub Security Advisory pip", "Django", "CVE-2019-19844"}, map[string]interface{}{
+				"VulnerableVersions": []string{">= 1.11, < 1.11.27", ">=2.2,<2.2.9", "not a specifier"},
+				"PatchedVersions":    []string{"1.11.27", "2.2.9"},
+			}},
+			{[]string{"npm::GitHub Security Advisory npm", "lodash", "CVE-2021-23337"}, map[string]interface{}{
+				"VulnerableVersions": []string{"<4.17.21"},
+			}},
+			{[]string{"data-source", "debian 11"}, map[string]interface{}{"ID": "debian"}},
+		} {
+			if err := put(tx, e.path, e.v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const etag = `"trivy-1"`
+		if r.Header.Get("if-none-match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("etag", etag)
+		z := gzip.NewWriter(w)
+		z.Write(b)
+		z.Close()
+	}))
+	defer srv.Close()
+
+	u, err := NewUpdater(WithURL(srv.URL+"/trivy.db.gz"), WithClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, fp := fetchParse(ctx, t, u)
+	if got, want := fp, driver.Fingerprint(`"trivy-1"`); got != want {
+		t.Errorf("fingerprint: got: %q, want: %q", got, want)
+	}
+	want := []string{
+		`CVE-2019-19844 django(binary) ">=1.11,<1.11.27" fixed:"" pypi Critical "" ""`,
+		`CVE-2019-19844 django(binary) ">=2.2,<2.2.9" fixed:"" pypi Critical "" ""`,
+		`CVE-2021-3711 openssl(source) "" fixed:"1.1.1k-1+deb11u1" Debian GNU/Linux 11 (bullseye) High "" "https://www.openssl.org/news/secadv/20210824.txt"`,
+		`CVE-2022-0001 busybox(binary) "" fixed:"1.33.1-r7" Alpine Linux v3.14 Medium "" ""`,
+		`CVE-2022-1000 libxml2(source) "" fixed:"" Debian GNU/Linux 11 (bullseye) Unknown "will_not_fix" ""`,
+	}
+	if got := summarize(vs); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestNoURL(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	u, err := NewUpdater()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := u.Fetch(ctx, ""); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"regexp"
 	"runtime/trace"
+	"strconv"
+	"strings"
 
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
@@ -16,7 +18,7 @@ import (
 
 const (
 	scannerName    = "debian"
-	scannerVersion = "v0.0.2"
+	scannerVersion = "v0.0.3"
 	scannerKind    = "distribution"
 )
 
@@ -25,7 +27,17 @@ type debianRegex struct {
 	regexp  *regexp.Regexp
 }
 
+// DebianRegexes is checked in order. Sid is first because its os-release and
+// issue files name the upcoming release, as in "Debian GNU/Linux bookworm/sid".
 var debianRegexes = []debianRegex{
+	{
+		release: Sid,
+		regexp:  regexp.MustCompile(`(?is)debian gnu/linux \w+/sid`),
+	},
+	{
+		release: Bookworm,
+		regexp:  regexp.MustCompile(`(?is)debian gnu/linux 12`),
+	},
 	{
 		release: Bullseye,
 		regexp:  regexp.MustCompile(`(?is)debian gnu/linux 11`),
@@ -50,9 +62,11 @@ var debianRegexes = []debianRegex{
 
 const osReleasePath = `etc/os-release`
 const issuePath = `etc/issue`
+const debianVersionPath = `etc/debian_version`
 
 var _ indexer.DistributionScanner = (*DistributionScanner)(nil)
 var _ indexer.VersionedScanner = (*DistributionScanner)(nil)
+var _ indexer.PathDeclarer = (*DistributionScanner)(nil)
 
 // DistributionScanner attempts to discover if a layer
 // displays characteristics of a Debian distribution
@@ -67,10 +81,19 @@ func (*DistributionScanner) Version() string { return scannerVersion }
 // Kind implements scanner.VersionedScanner.
 func (*DistributionScanner) Kind() string { return scannerKind }
 
-// Scan will inspect the layer for an os-release or lsb-release file
-// and perform a regex match for keywords indicating the associated Debian release
+// Paths implements indexer.PathDeclarer.
+func (*DistributionScanner) Paths() []string {
+	return []string{osReleasePath, issuePath, debianVersionPath}
+}
+
+// Scan will inspect the layer for an os-release or issue file and perform a
+// regex match for keywords indicating the associated Debian release. If there's
+// no os-release file, the debian_version file is consulted.
+//
+// Point releases are reported as their major release, as security data is only
+// published per major release.
 //
-// If neither file is found a (nil,nil) is returned.
+// If no files are found a (nil,nil) is returned.
 // If the files are found but all regexp fail to match an empty slice is returned.
 func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Distribution, error) {
 	defer trace.StartRegion(ctx, "Scanner.Scan").End()
@@ -80,17 +103,27 @@ func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]
 		label.String("layer", l.Hash.String()))
 	zlog.Debug(ctx).Msg("start")
 	defer zlog.Debug(ctx).Msg("done")
-	files, err := l.Files(osReleasePath, issuePath)
+	files, err := l.Files(osReleasePath, issuePath, debianVersionPath)
 	if err != nil {
-		zlog.Debug(ctx).Msg("didn't find an os-release or issue file")
+		zlog.Debug(ctx).Msg("didn't find an os-release, issue, or debian_version file")
 		return nil, nil
 	}
-	for _, buff := range files {
-		dist := ds.parse(buff)
-		if dist != nil {
+	for _, p := range []string{osReleasePath, issuePath} {
+		buff, ok := files[p]
+		if !ok {
+			continue
+		}
+		if dist := ds.parse(buff); dist != nil {
 			return []*claircore.Distribution{dist}, nil
 		}
 	}
+	if _, ok := files[osReleasePath]; !ok {
+		if buff, ok := files[debianVersionPath]; ok {
+			if dist := parseDebianVersion(buff); dist != nil {
+				return []*claircore.Distribution{dist}, nil
+			}
+		}
+	}
 	return []*claircore.Distribution{}, nil
 }
 
@@ -106,3 +139,25 @@ func (ds *DistributionScanner) parse(buff *bytes.Buffer) *claircore.Distribution
 	}
 	return nil
 }
+
+// ParseDebianVersion maps the point release in a debian_version file, such as
+// "11.5", to its major release.
+//
+// Only numeric versions are considered: testing and unstable write a codename
+// like "bookworm/sid" here, but so do distributions derived from them, like
+// Ubuntu.
+func parseDebianVersion(buff *bytes.Buffer) *claircore.Distribution {
+	v := strings.TrimSpace(buff.String())
+	if i := strings.IndexByte(v, '.'); i != -1 {
+		v = v[:i]
+	}
+	if _, err := strconv.Atoi(v); err != nil {
+		return nil
+	}
+	for r, id := range ReleaseToVersionID {
+		if id == v {
+			return releaseToDist(r)
+		}
+	}
+	return nil
+}
@@ -2,11 +2,37 @@ package debian
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
 )
 
+var bookwormOSRelease []byte = []byte(`PRETTY_NAME="Debian GNU/Linux 12 (bookworm)"
+NAME="Debian GNU/Linux"
+VERSION_ID="12"
+VERSION="12 (bookworm)"
+VERSION_CODENAME=bookworm
+ID=debian
+HOME_URL="https://www.debian.org/"
+SUPPORT_URL="https://www.debian.org/support"
+BUG_REPORT_URL="https://bugs.debian.org/"`)
+
+var bookwormIssue []byte = []byte(`Debian GNU/Linux 12 \n \l`)
+
+var sidOSRelease []byte = []byte(`PRETTY_NAME="Debian GNU/Linux bookworm/sid"
+NAME="Debian GNU/Linux"
+ID=debian
+HOME_URL="https://www.debian.org/"
+SUPPORT_URL="https://www.debian.org/support"
+BUG_REPORT_URL="https://bugs.debian.org/"`)
+
+var sidIssue []byte = []byte(`Debian GNU/Linux bookworm/sid \n \l`)
+
 var bullseyeOSRelease []byte = []byte(`PRETTY_NAME="Debian GNU/Linux 11 (bullseye)"
 NAME="Debian GNU/Linux"
 VERSION_ID="11"
@@ -73,6 +99,18 @@ func TestDistributionScanner(t *testing.T) {
 		osRelease []byte
 		issue     []byte
 	}{
+		{
+			name:      "bookworm",
+			release:   Bookworm,
+			osRelease: bookwormOSRelease,
+			issue:     bookwormIssue,
+		},
+		{
+			name:      "sid",
+			release:   Sid,
+			osRelease: sidOSRelease,
+			issue:     sidIssue,
+		},
 		{
 			name:      "bullseye",
 			release:   Bullseye,
@@ -118,3 +156,65 @@ func TestDistributionScanner(t *testing.T) {
 		})
 	}
 }
+
+func TestDistributionScannerFiles(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	table := []struct {
+		name  string
+		files map[string]string
+		want  []*claircore.Distribution
+	}{
+		{
+			name: "PointRelease",
+			files: map[string]string{
+				"etc/debian_version": "11.5\n",
+			},
+			want: []*claircore.Distribution{bullseyeDist},
+		},
+		{
+			name: "OSReleasePreferred",
+			files: map[string]string{
+				"etc/os-release":     string(bookwormOSRelease),
+				"etc/debian_version": "11.5\n",
+			},
+			want: []*claircore.Distribution{bookwormDist},
+		},
+		{
+			// Ubuntu records the Debian release it's based on, which must not
+			// be mistaken for Debian.
+			name: "Derivative",
+			files: map[string]string{
+				"etc/os-release":     "NAME=\"Ubuntu\"\nID=ubuntu\n",
+				"etc/debian_version": "bullseye/sid\n",
+			},
+			want: []*claircore.Distribution{},
+		},
+		{
+			name: "CodenameOnly",
+			files: map[string]string{
+				"etc/debian_version": "bookworm/sid\n",
+			},
+			want: []*claircore.Distribution{},
+		},
+		{
+			name: "None",
+			files: map[string]string{
+				"etc/hostname": "localhost\n",
+			},
+			want: nil,
+		},
+	}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			var s DistributionScanner
+			got, err := s.Scan(ctx, fixture.Files(tc.files).Local(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+}
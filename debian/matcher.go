@@ -9,7 +9,13 @@ import (
 	"github.com/quay/claircore/libvuln/driver"
 )
 
-type Matcher struct{}
+// Matcher implements driver.Matcher for Debian.
+type Matcher struct {
+	// ReportUnfixed causes open issues that the security tracker says won't
+	// get an advisory (see NoDSA, Postponed, and Ignored) to be reported. By
+	// default they're not, as there's nothing to upgrade to.
+	ReportUnfixed bool
+}
 
 var _ driver.Matcher = (*Matcher)(nil)
 
@@ -40,7 +46,10 @@ func (*Matcher) Query() []driver.MatchConstraint {
 	}
 }
 
-func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
+func (m *Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
+	if vuln.FixState != "" {
+		return m.ReportUnfixed, nil
+	}
 	v1, err := version.NewVersion(record.Package.Version)
 	if err != nil {
 		return false, nil
@@ -35,6 +35,7 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 			Name:               def.Title,
 			Description:        def.Description,
 			Issued:             def.Advisory.Issued.Date,
+			Modified:           def.Advisory.Updated.Date,
 			Links:              ovalutil.Links(def),
 			NormalizedSeverity: claircore.Unknown,
 			Dist:               releaseToDist(u.release),
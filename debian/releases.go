@@ -5,14 +5,20 @@ import "github.com/quay/claircore"
 type Release string
 
 const (
+	Bookworm Release = "bookworm"
 	Bullseye Release = "bullseye"
 	Buster   Release = "buster"
 	Jessie   Release = "jessie"
 	Stretch  Release = "stretch"
 	Wheezy   Release = "wheezy"
+	// Sid is the unstable distribution. It's never released, so it has no
+	// version number and no security data of its own, and isn't in
+	// AllReleases.
+	Sid Release = "sid"
 )
 
 var AllReleases = map[Release]struct{}{
+	Bookworm: struct{}{},
 	Bullseye: struct{}{},
 	Buster:   struct{}{},
 	Jessie:   struct{}{},
@@ -21,6 +27,7 @@ var AllReleases = map[Release]struct{}{
 }
 
 var ReleaseToVersionID = map[Release]string{
+	Bookworm: "12",
 	Bullseye: "11",
 	Buster:   "10",
 	Jessie:   "8",
@@ -28,6 +35,15 @@ var ReleaseToVersionID = map[Release]string{
 	Wheezy:   "7",
 }
 
+var bookwormDist = &claircore.Distribution{
+	PrettyName:      "Debian GNU/Linux 12 (bookworm)",
+	Name:            "Debian GNU/Linux",
+	VersionID:       "12",
+	Version:         "12 (bookworm)",
+	VersionCodeName: "bookworm",
+	DID:             "debian",
+}
+
 var bullseyeDist = &claircore.Distribution{
 	PrettyName:      "Debian GNU/Linux 11 (bullseye)",
 	Name:            "Debian GNU/Linux",
@@ -71,8 +87,18 @@ var wheezyDist = &claircore.Distribution{
 	DID:        "debian",
 }
 
+var sidDist = &claircore.Distribution{
+	PrettyName:      "Debian GNU/Linux sid",
+	Name:            "Debian GNU/Linux",
+	Version:         "sid",
+	VersionCodeName: "sid",
+	DID:             "debian",
+}
+
 func releaseToDist(r Release) *claircore.Distribution {
 	switch r {
+	case Bookworm:
+		return bookwormDist
 	case Bullseye:
 		return bullseyeDist
 	case Buster:
@@ -83,7 +109,23 @@ func releaseToDist(r Release) *claircore.Distribution {
 		return stretchDist
 	case Wheezy:
 		return wheezyDist
+	case Sid:
+		return sidDist
 	default:
 		return &claircore.Distribution{}
 	}
 }
+
+// DistributionFor returns the Distribution for the release with the provided
+// version ID, such as "11", or nil if it's not a known release.
+//
+// This is the Distribution the DistributionScanner reports for the release,
+// for updaters outside this package importing Debian vulnerabilities.
+func DistributionFor(versionID string) *claircore.Distribution {
+	for r, id := range ReleaseToVersionID {
+		if id == versionID {
+			return releaseToDist(r)
+		}
+	}
+	return nil
+}
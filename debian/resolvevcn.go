@@ -5,6 +5,7 @@ import (
 )
 
 func init() {
+	bookwormRegex := regexp.MustCompile("bookworm")
 	bullseyeRegex := regexp.MustCompile("bullseye")
 	busterRegex := regexp.MustCompile("buster")
 	jessieRegex := regexp.MustCompile("jessie")
@@ -12,6 +13,7 @@ func init() {
 	wheezyRegex := regexp.MustCompile("wheezy")
 
 	resolvers = []vcnRegexp{
+		vcnRegexp{Bookworm, bookwormRegex},
 		vcnRegexp{Bullseye, bullseyeRegex},
 		vcnRegexp{Buster, busterRegex},
 		vcnRegexp{Jessie, jessieRegex},
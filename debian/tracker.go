@@ -0,0 +1,201 @@
+package debian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// TrackerURL is the Debian security tracker's JSON export.
+const TrackerURL = "https://security-tracker.debian.org/tracker/data/json"
+
+// These are the fix states the tracker reports for open issues that won't be
+// fixed in a security advisory.
+const (
+	// NoDSA is an issue that's minor enough not to warrant an advisory. It
+	// may be fixed in a point release.
+	NoDSA = "no-dsa"
+	// Postponed is an issue that will be fixed in a later update.
+	Postponed = "postponed"
+	// Ignored is an issue that won't be fixed in the release.
+	Ignored = "ignored"
+)
+
+var (
+	_ driver.Updater      = (*TrackerUpdater)(nil)
+	_ driver.Configurable = (*TrackerUpdater)(nil)
+)
+
+// TrackerUpdater reports the open issues the Debian security tracker has
+// marked as not getting an advisory, which don't appear in the OVAL
+// databases.
+//
+// The reported vulnerabilities have no fixed version and a FixState of NoDSA,
+// Postponed, or Ignored. The Matcher only reports them if configured to.
+type TrackerUpdater struct {
+	url string
+	c   *http.Client
+}
+
+// TrackerConfig is the configuration for the TrackerUpdater.
+//
+// By convention, this is in a map called "debian-tracker-updater".
+type TrackerConfig struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+// NewTrackerUpdater returns a TrackerUpdater using the default URL.
+func NewTrackerUpdater() *TrackerUpdater {
+	return &TrackerUpdater{
+		url: TrackerURL,
+		c:   http.DefaultClient, // TODO(hank) Remove DefaultClient
+	}
+}
+
+// Name implements driver.Updater.
+func (*TrackerUpdater) Name() string { return "debian-tracker-updater" }
+
+// Configure implements driver.Configurable.
+func (u *TrackerUpdater) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "debian/TrackerUpdater.Configure"))
+	var cfg TrackerConfig
+	if err := f(&cfg); err != nil {
+		return err
+	}
+	if cfg.URL != "" {
+		u.url = cfg.URL
+		zlog.Info(ctx).
+			Msg("configured database URL")
+	}
+	u.c = c
+	return nil
+}
+
+// Fetch implements driver.Fetcher.
+func (u *TrackerUpdater) Fetch(ctx context.Context, fingerprint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "debian/TrackerUpdater.Fetch"),
+		label.String("database", u.url))
+	return fetch(ctx, u.c, u.url, fingerprint)
+}
+
+// TrackerIssue is an issue in the tracker's JSON export.
+type trackerIssue struct {
+	Description string                    `json:"description"`
+	Releases    map[string]trackerRelease `json:"releases"`
+}
+
+// TrackerRelease is an issue's state in one release.
+type trackerRelease struct {
+	Status      string `json:"status"`
+	NoDSA       string `json:"nodsa"`
+	NoDSAReason string `json:"nodsa_reason"`
+}
+
+// FixState returns the state the tracker's UI shows for the release, or an
+// empty string if the issue is fixed or expected to get an advisory.
+func (r *trackerRelease) fixState() string {
+	if r.Status != "open" || r.NoDSA == "" {
+		return ""
+	}
+	switch r.NoDSAReason {
+	case Postponed:
+		return Postponed
+	case Ignored:
+		return Ignored
+	}
+	return NoDSA
+}
+
+// Parse implements driver.Parser.
+//
+// The export is a very large object keyed by source package, so it's
+// decoded one package at a time.
+func (u *TrackerUpdater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "debian/TrackerUpdater.Parse"))
+	zlog.Info(ctx).Msg("starting parse")
+	defer r.Close()
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("debian: unexpected start of tracker data: %v %v", tok, err)
+	}
+	var out []*claircore.Vulnerability
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("debian: unable to decode tracker data: %w", err)
+		}
+		name, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("debian: unexpected token in tracker data: %v", tok)
+		}
+		var issues map[string]trackerIssue
+		if err := dec.Decode(&issues); err != nil {
+			return nil, fmt.Errorf("debian: unable to decode tracker data for %q: %w", name, err)
+		}
+		out = append(out, u.vulns(name, issues)...)
+	}
+	zlog.Debug(ctx).
+		Int("count", len(out)).
+		Msg("found unfixed issues")
+	return out, nil
+}
+
+// Vulns returns a Vulnerability for every release of every issue that has a
+// fix state.
+func (u *TrackerUpdater) vulns(name string, issues map[string]trackerIssue) []*claircore.Vulnerability {
+	ids := make([]string, 0, len(issues))
+	for id := range issues {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var out []*claircore.Vulnerability
+	var pkg *claircore.Package
+	for _, id := range ids {
+		issue := issues[id]
+		rels := make([]string, 0, len(issue.Releases))
+		for rel := range issue.Releases {
+			rels = append(rels, rel)
+		}
+		sort.Strings(rels)
+		for _, rel := range rels {
+			if _, ok := AllReleases[Release(rel)]; !ok {
+				continue
+			}
+			r := issue.Releases[rel]
+			st := r.fixState()
+			if st == "" {
+				continue
+			}
+			if pkg == nil {
+				pkg = &claircore.Package{
+					Name: name,
+					Kind: claircore.SOURCE,
+				}
+			}
+			out = append(out, &claircore.Vulnerability{
+				Updater:            u.Name(),
+				Name:               id,
+				Description:        issue.Description,
+				Links:              "https://security-tracker.debian.org/tracker/" + id,
+				NormalizedSeverity: claircore.Unknown,
+				Package:            pkg,
+				Dist:               releaseToDist(Release(rel)),
+				FixState:           st,
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package debian
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+func TestTrackerParse(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, err := os.Open("testdata/tracker.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := NewTrackerUpdater()
+	vs, err := u.Parse(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		Name, Package, Release, State string
+	}
+	var got []result
+	for _, v := range vs {
+		if v.Package.Kind != claircore.SOURCE || v.FixedInVersion != "" {
+			t.Errorf("unexpected vulnerability: %+v", v)
+		}
+		got = append(got, result{v.Name, v.Package.Name, v.Dist.VersionCodeName, v.FixState})
+	}
+	want := []result{
+		{"CVE-2021-0001", "curl", "bullseye", Postponed},
+		{"CVE-2021-0001", "curl", "buster", NoDSA},
+		{"CVE-2021-0003", "zlib", "stretch", Ignored},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestMatcherUnfixed(t *testing.T) {
+	ctx := context.Background()
+	record := &claircore.IndexRecord{
+		Package:      &claircore.Package{Name: "curl", Version: "7.64.0-4+deb10u2"},
+		Distribution: busterDist,
+	}
+	vuln := &claircore.Vulnerability{
+		Name:     "CVE-2021-0001",
+		Package:  &claircore.Package{Name: "curl", Kind: claircore.SOURCE},
+		Dist:     busterDist,
+		FixState: NoDSA,
+	}
+	for _, report := range []bool{false, true} {
+		m := Matcher{ReportUnfixed: report}
+		got, err := m.Vulnerable(ctx, record, vuln)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != report {
+			t.Errorf("ReportUnfixed %v: got: %v", report, got)
+		}
+	}
+}
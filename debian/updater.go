@@ -94,8 +94,13 @@ func (u *Updater) Fetch(ctx context.Context, fingerprint driver.Fingerprint) (io
 		label.String("component", "debian/Updater.Fetch"),
 		label.String("release", string(u.release)),
 		label.String("database", u.url))
+	return fetch(ctx, u.c, u.url, fingerprint)
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.url, nil)
+// Fetch retrieves the database at the URL into a temporary file, using the
+// fingerprint as an etag.
+func fetch(ctx context.Context, c *http.Client, url string, fingerprint driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create request")
 	}
@@ -103,18 +108,17 @@ func (u *Updater) Fetch(ctx context.Context, fingerprint driver.Fingerprint) (io
 		req.Header.Set("if-none-match", string(fingerprint))
 	}
 
-	// fetch OVAL xml database
-	resp, err := u.c.Do(req)
+	resp, err := c.Do(req)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to retrieve OVAL database: %v", err)
+		return nil, "", fmt.Errorf("failed to retrieve database: %v", err)
 	}
 
 	switch resp.StatusCode {
 	case http.StatusOK:
-		zlog.Info(ctx).Msg("fetching latest oval database")
+		zlog.Info(ctx).Msg("fetching latest database")
 	case http.StatusNotModified:
 		return nil, fingerprint, driver.Unchanged
 	default:
@@ -135,6 +139,6 @@ func (u *Updater) Fetch(ctx context.Context, fingerprint driver.Fingerprint) (io
 		return nil, "", fmt.Errorf("failed to seek body: %v", err)
 	}
 
-	zlog.Info(ctx).Msg("fetched latest oval database successfully")
+	zlog.Info(ctx).Msg("fetched latest database successfully")
 	return f, driver.Fingerprint(fp), err
 }
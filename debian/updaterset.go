@@ -7,6 +7,7 @@ import (
 )
 
 var debianReleases = []Release{
+	Bookworm,
 	Bullseye,
 	Buster,
 	Jessie,
@@ -23,5 +24,8 @@ func UpdaterSet(_ context.Context) (driver.UpdaterSet, error) {
 			return us, err
 		}
 	}
+	if err := us.Add(NewTrackerUpdater()); err != nil {
+		return us, err
+	}
 	return us, nil
 }
@@ -0,0 +1,14 @@
+package claircore
+
+// Detection is a match of a content signature, such as a malware or
+// indicator-of-compromise signature, against a file in a layer.
+type Detection struct {
+	// Layer is the layer the file was found in.
+	Layer Digest `json:"layer"`
+	// Path is the file's path, relative to the layer's tar-root.
+	Path string `json:"path"`
+	// Signature names the matched signature, as reported by the engine.
+	Signature string `json:"signature"`
+	// Scanner is the name of the ContentScanner that made the detection.
+	Scanner string `json:"scanner"`
+}
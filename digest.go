@@ -2,6 +2,7 @@ package claircore
 
 import (
 	"bytes"
+	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
 	"database/sql/driver"
@@ -11,6 +12,7 @@ import (
 )
 
 const (
+	SHA1   = "sha1"
 	SHA256 = "sha256"
 	SHA512 = "sha512"
 )
@@ -35,6 +37,8 @@ func (d Digest) Algorithm() string { return d.algo }
 // Hash returns an instance of the hashing algorithm used for this Digest.
 func (d Digest) Hash() hash.Hash {
 	switch d.algo {
+	case "sha1":
+		return sha1.New()
 	case "sha256":
 		return sha256.New()
 	case "sha512":
@@ -90,9 +94,35 @@ func (e *DigestError) Unwrap() error {
 	return e.inner
 }
 
+// ChecksumError is returned when contents don't match the Digest they're
+// expected to have, such as a layer corrupted in transit or in storage.
+type ChecksumError struct {
+	// Want is the expected Digest.
+	Want Digest
+	// Got is the checksum of the contents, using Want's algorithm.
+	Got []byte
+}
+
+// Error implements error.
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("validation failed: got %q, expected %q",
+		hex.EncodeToString(e.Got),
+		hex.EncodeToString(e.Want.checksum))
+}
+
+// Verify returns a *ChecksumError if the checksum doesn't match the Digest.
+func (d Digest) Verify(sum []byte) error {
+	if bytes.Equal(sum, d.checksum) {
+		return nil
+	}
+	return &ChecksumError{Want: d, Got: sum}
+}
+
 func (d *Digest) setChecksum(b []byte) error {
 	var sz int
 	switch d.algo {
+	case "sha1":
+		sz = sha1.Size
 	case "sha256":
 		sz = sha256.Size
 	case "sha512":
@@ -0,0 +1,38 @@
+package dotnet
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var _ indexer.Coalescer = (*coalescer)(nil)
+
+// NewCoalescer is a constructor for a Coalescer.
+func NewCoalescer(_ context.Context) (indexer.Coalescer, error) {
+	return &coalescer{}, nil
+}
+
+type coalescer struct{}
+
+// Coalesce implements indexer.Coalescer.
+//
+// Neither deps.json files nor package folders are updated in place by later
+// layers, so every package found is reported in the layer it was found in.
+func (c *coalescer) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{},
+		Packages:     map[string]*claircore.Package{},
+	}
+	for _, l := range ls {
+		for _, pkg := range l.Pkgs {
+			ir.Packages[pkg.ID] = pkg
+			ir.Environments[pkg.ID] = append(ir.Environments[pkg.ID], &claircore.Environment{
+				PackageDB:    pkg.PackageDB,
+				IntroducedIn: l.Hash,
+			})
+		}
+	}
+	return ir, nil
+}
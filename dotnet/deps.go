@@ -0,0 +1,125 @@
+package dotnet
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// DepsFile is the subset of a deps.json file used.
+//
+// A deps.json lists every library the application uses in "libraries", and
+// the assets of each library for each target framework (and runtime
+// identifier, for runtime-specific applications) in "targets":
+//
+//	{
+//	  "runtimeTarget": {"name": ".NETCoreApp,Version=v6.0/linux-x64"},
+//	  "targets": {
+//	    ".NETCoreApp,Version=v6.0/linux-x64": {
+//	      "Newtonsoft.Json/13.0.1": {"runtime": {"lib/netstandard2.0/Newtonsoft.Json.dll": {}}}
+//	    }
+//	  },
+//	  "libraries": {
+//	    "Newtonsoft.Json/13.0.1": {"type": "package", "sha512": "sha512-..."}
+//	  }
+//	}
+type depsFile struct {
+	RuntimeTarget struct {
+		Name string `json:"name"`
+	} `json:"runtimeTarget"`
+	Targets   map[string]map[string]depsTarget `json:"targets"`
+	Libraries map[string]depsLibrary           `json:"libraries"`
+}
+
+// DepsTarget is a library's assets for a target.
+type depsTarget struct {
+	Native         map[string]json.RawMessage `json:"native"`
+	RuntimeTargets map[string]struct {
+		RID string `json:"rid"`
+	} `json:"runtimeTargets"`
+}
+
+// DepsLibrary is a library entry.
+type depsLibrary struct {
+	Type   string `json:"type"`
+	SHA512 string `json:"sha512"`
+}
+
+// Library types that name NuGet packages. Others are the application's own
+// projects and plain assembly references.
+const (
+	libPackage     = "package"
+	libRuntimePack = "runtimepack"
+)
+
+// ParseDeps parses the deps.json file "n".
+func parseDeps(b []byte, n string) ([]*claircore.Package, error) {
+	var f depsFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	if f.Libraries == nil {
+		return nil, errors.New("dotnet: no libraries")
+	}
+	// The runtime identifier is the part of the target name after the slash,
+	// if the application is runtime-specific.
+	var rid string
+	if i := strings.IndexByte(f.RuntimeTarget.Name, '/'); i != -1 {
+		rid = f.RuntimeTarget.Name[i+1:]
+	}
+	target := f.Targets[f.RuntimeTarget.Name]
+
+	var ret []*claircore.Package
+	for key, lib := range f.Libraries {
+		if lib.Type != libPackage && lib.Type != libRuntimePack {
+			continue
+		}
+		i := strings.LastIndexByte(key, '/')
+		if i <= 0 || i == len(key)-1 {
+			continue
+		}
+		name, version := key[:i], key[i+1:]
+		pkg := claircore.Package{
+			Name:           name,
+			Version:        version,
+			Kind:           claircore.BINARY,
+			PackageDB:      "dotnet:" + n,
+			RepositoryHint: RepositoryHint,
+		}
+		if lib.Type == libRuntimePack {
+			// Runtime packs are keyed with a prefix to keep them distinct
+			// from the reference packages of the same name.
+			pkg.Name = strings.TrimPrefix(name, "runtimepack.")
+		}
+		if t, ok := target[key]; ok && rid != "" &&
+			(lib.Type == libRuntimePack || len(t.Native) != 0 || len(t.RuntimeTargets) != 0) {
+			pkg.Arch = rid
+		}
+		if d, ok := decodeSHA512(lib.SHA512); ok {
+			pkg.Digests = []claircore.Digest{d}
+		}
+		ret = append(ret, &pkg)
+	}
+	return ret, nil
+}
+
+// DecodeSHA512 decodes the "sha512-<base64>" package hash recorded in a
+// deps.json, which is the hash of the nupkg file.
+func decodeSHA512(s string) (claircore.Digest, bool) {
+	const prefix = "sha512-"
+	if !strings.HasPrefix(s, prefix) {
+		return claircore.Digest{}, false
+	}
+	b, err := base64.StdEncoding.DecodeString(s[len(prefix):])
+	if err != nil {
+		return claircore.Digest{}, false
+	}
+	d, err := claircore.NewDigest(claircore.SHA512, b)
+	if err != nil {
+		return claircore.Digest{}, false
+	}
+	return d, true
+}
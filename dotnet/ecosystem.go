@@ -0,0 +1,21 @@
+package dotnet
+
+import (
+	"context"
+
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// NewEcosystem provides the set of scanners and coalescers for NuGet
+// packages used by .NET applications.
+func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
+	return &indexer.Ecosystem{
+		Name: "dotnet",
+		PackageScanners: func(_ context.Context) ([]indexer.PackageScanner, error) {
+			return []indexer.PackageScanner{&Scanner{}}, nil
+		},
+		DistributionScanners: func(_ context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+		RepositoryScanners:   func(_ context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		Coalescer:            NewCoalescer,
+	}
+}
@@ -0,0 +1,43 @@
+package dotnet
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+
+	"github.com/quay/claircore"
+)
+
+// Nuspec is the subset of a nuspec file used.
+//
+// The XML namespace changes with the schema version, so elements are matched
+// by local name only.
+type nuspec struct {
+	XMLName  xml.Name `xml:"package"`
+	Metadata struct {
+		ID      string `xml:"id"`
+		Version string `xml:"version"`
+	} `xml:"metadata"`
+}
+
+// ParseNuspec parses the nuspec file "n".
+func parseNuspec(b []byte, n string) ([]*claircore.Package, error) {
+	var s nuspec
+	// Some nuspecs are written with a byte order mark, which encoding/xml
+	// doesn't expect.
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	if err := xml.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	id, v := s.Metadata.ID, s.Metadata.Version
+	if id == "" || v == "" {
+		return nil, errors.New("dotnet: nuspec missing id or version")
+	}
+	return []*claircore.Package{{
+		Name:           id,
+		Version:        v,
+		Kind:           claircore.BINARY,
+		PackageDB:      nuspecDB(n, id, v),
+		RepositoryHint: RepositoryHint,
+	}}, nil
+}
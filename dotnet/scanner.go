@@ -0,0 +1,156 @@
+// Package dotnet contains components for finding NuGet packages used by .NET
+// applications.
+package dotnet
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"path/filepath"
+	"runtime/trace"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	_ indexer.VersionedScanner   = (*Scanner)(nil)
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
+)
+
+// RepositoryHint is used for all packages found, as NuGet doesn't record where
+// a package was installed from.
+const RepositoryHint = "https://api.nuget.org/v3/index.json"
+
+// MaxFile is the largest deps.json or nuspec file that will be read.
+const maxFile = 32 << 20
+
+// Scanner implements the indexer.PackageScanner interface.
+//
+// It looks for two kinds of files:
+//
+//   - "*.deps.json" files, written next to every published .NET application
+//     and listing the packages it was built with. Each file is reported as a
+//     separate PackageDB.
+//   - "*.nuspec" files, found in NuGet package folders. Packages in the
+//     "<root>/<id>/<version>/<id>.nuspec" layout of a global packages folder
+//     are reported with the folder as the PackageDB; others use the directory
+//     containing the nuspec.
+//
+// Packages with runtime-specific assets, such as the runtime pack of a
+// self-contained application or a package with native libraries, have their
+// Arch set to the runtime identifier (for example, "linux-x64") the
+// application was published for.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements indexer.VersionedScanner.
+func (*Scanner) Name() string { return "dotnet" }
+
+// Version implements indexer.VersionedScanner.
+func (*Scanner) Version() string { return "1" }
+
+// Kind implements indexer.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceDeclared }
+
+// Scan attempts to find NuGet packages.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "dotnet/Scanner.Scan"),
+		label.String("version", s.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var ret []*claircore.Package
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+		if err != nil {
+			return nil, err
+		}
+		n = filepath.ToSlash(n)
+		var parse func([]byte, string) ([]*claircore.Package, error)
+		switch {
+		case strings.HasSuffix(n, ".deps.json"):
+			parse = parseDeps
+		case strings.HasSuffix(n, ".nuspec"):
+			parse = parseNuspec
+		default:
+			continue
+		}
+		if h.Size > maxFile {
+			zlog.Info(ctx).
+				Str("file", n).
+				Int64("size", h.Size).
+				Msg("skipping oversized file")
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		ps, err := parse(b, n)
+		if err != nil {
+			zlog.Info(ctx).
+				Str("file", n).
+				Err(err).
+				Msg("unable to parse file, skipping")
+			continue
+		}
+		ret = append(ret, ps...)
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].PackageDB != ret[j].PackageDB {
+			return ret[i].PackageDB < ret[j].PackageDB
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+// NuspecDB returns the PackageDB for the nuspec file at "n", given the package
+// ID and version declared in it.
+func nuspecDB(n, id, version string) string {
+	dir := path.Dir(n)
+	// Global packages folders use lowercased IDs and versions for directory
+	// names.
+	if strings.EqualFold(path.Base(dir), version) &&
+		strings.EqualFold(path.Base(path.Dir(dir)), id) {
+		return "nuget:" + path.Dir(path.Dir(dir))
+	}
+	return "nuget:" + dir
+}
@@ -0,0 +1,171 @@
+package dotnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
+)
+
+// A self-contained application published for linux-x64.
+const appDeps = `{
+  "runtimeTarget": {
+    "name": ".NETCoreApp,Version=v6.0/linux-x64",
+    "signature": ""
+  },
+  "compilationOptions": {},
+  "targets": {
+    ".NETCoreApp,Version=v6.0": {},
+    ".NETCoreApp,Version=v6.0/linux-x64": {
+      "App/1.0.0": {
+        "dependencies": {
+          "Newtonsoft.Json": "13.0.1",
+          "SQLitePCLRaw.lib.e_sqlite3": "2.0.6"
+        },
+        "runtime": {
+          "App.dll": {}
+        }
+      },
+      "runtimepack.Microsoft.NETCore.App.Runtime.linux-x64/6.0.5": {
+        "runtime": {
+          "System.Private.CoreLib.dll": {}
+        },
+        "native": {
+          "libcoreclr.so": {}
+        }
+      },
+      "Newtonsoft.Json/13.0.1": {
+        "runtime": {
+          "lib/netstandard2.0/Newtonsoft.Json.dll": {
+            "assemblyVersion": "13.0.0.0",
+            "fileVersion": "13.0.1.25517"
+          }
+        }
+      },
+      "SQLitePCLRaw.lib.e_sqlite3/2.0.6": {
+        "runtimeTargets": {
+          "runtimes/linux-x64/native/libe_sqlite3.so": {
+            "rid": "linux-x64",
+            "assetType": "native"
+          }
+        }
+      }
+    }
+  },
+  "libraries": {
+    "App/1.0.0": {
+      "type": "project",
+      "serviceable": false,
+      "sha512": ""
+    },
+    "runtimepack.Microsoft.NETCore.App.Runtime.linux-x64/6.0.5": {
+      "type": "runtimepack",
+      "serviceable": false,
+      "sha512": ""
+    },
+    "Newtonsoft.Json/13.0.1": {
+      "type": "package",
+      "serviceable": true,
+      "sha512": "sha512-AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA==",
+      "path": "newtonsoft.json/13.0.1",
+      "hashPath": "newtonsoft.json.13.0.1.nupkg.sha512"
+    },
+    "SQLitePCLRaw.lib.e_sqlite3/2.0.6": {
+      "type": "package",
+      "serviceable": true,
+      "sha512": "not a hash"
+    },
+    "System.Runtime/4.3.0": {
+      "type": "reference",
+      "serviceable": false,
+      "sha512": ""
+    }
+  }
+}`
+
+const serilogNuspec = "\xef\xbb\xbf" + `<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://schemas.microsoft.com/packaging/2013/05/nuspec.xsd">
+  <metadata>
+    <id>Serilog</id>
+    <version>2.10.0</version>
+    <authors>Serilog Contributors</authors>
+  </metadata>
+</package>`
+
+const oldNuspec = `<?xml version="1.0"?>
+<package xmlns="http://schemas.microsoft.com/packaging/2010/07/nuspec.xsd">
+  <metadata>
+    <id>log4net</id>
+    <version>2.0.8</version>
+  </metadata>
+</package>`
+
+var digestCmp = cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })
+
+func TestScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := fixture.Layer{
+		{Name: "app/App.deps.json", Body: appDeps},
+		{Name: "root/.nuget/packages/serilog/2.10.0/serilog.nuspec", Body: serilogNuspec},
+		{Name: "opt/lib/log4net.nuspec", Body: oldNuspec},
+		// Not parseable.
+		{Name: "app/Broken.deps.json", Body: "{"},
+		{Name: "opt/lib/broken.nuspec", Body: "<package/>"},
+	}.Local(t)
+
+	got, err := (&Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nsDigest, err := claircore.NewDigest(claircore.SHA512, make([]byte, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Package{
+		{
+			Name:           "Microsoft.NETCore.App.Runtime.linux-x64",
+			Version:        "6.0.5",
+			Kind:           claircore.BINARY,
+			PackageDB:      "dotnet:app/App.deps.json",
+			RepositoryHint: RepositoryHint,
+			Arch:           "linux-x64",
+		},
+		{
+			Name:           "Newtonsoft.Json",
+			Version:        "13.0.1",
+			Kind:           claircore.BINARY,
+			PackageDB:      "dotnet:app/App.deps.json",
+			RepositoryHint: RepositoryHint,
+			Digests:        []claircore.Digest{nsDigest},
+		},
+		{
+			Name:           "SQLitePCLRaw.lib.e_sqlite3",
+			Version:        "2.0.6",
+			Kind:           claircore.BINARY,
+			PackageDB:      "dotnet:app/App.deps.json",
+			RepositoryHint: RepositoryHint,
+			Arch:           "linux-x64",
+		},
+		{
+			Name:           "log4net",
+			Version:        "2.0.8",
+			Kind:           claircore.BINARY,
+			PackageDB:      "nuget:opt/lib",
+			RepositoryHint: RepositoryHint,
+		},
+		{
+			Name:           "Serilog",
+			Version:        "2.10.0",
+			Kind:           claircore.BINARY,
+			PackageDB:      "nuget:root/.nuget/packages",
+			RepositoryHint: RepositoryHint,
+		},
+	}
+	if !cmp.Equal(got, want, digestCmp) {
+		t.Error(cmp.Diff(got, want, digestCmp))
+	}
+}
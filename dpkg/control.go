@@ -0,0 +1,134 @@
+package dpkg
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ControlReader reads paragraphs from a Debian control file, such as dpkg's
+// "status" file, one at a time.
+//
+// The format is described in deb-control(5) and deb822(5): paragraphs are
+// separated by blank lines, each line is a "Field: value" pair, and lines
+// beginning with a space or tab continue the previous field's value. Field
+// names are case-insensitive.
+type controlReader struct {
+	r    *bufio.Reader
+	line int
+	// Bad counts the lines skipped for being neither fields nor
+	// continuations.
+	bad int
+}
+
+func newControlReader(r io.Reader) *controlReader {
+	return &controlReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next paragraph, or io.EOF if there are none left.
+//
+// Malformed lines are skipped, so that one bad entry doesn't lose the rest of
+// the file; see the "bad" member.
+func (c *controlReader) Next() (paragraph, error) {
+	var p paragraph
+	var last string // lowercased name of the field being continued
+	for {
+		l, err := c.r.ReadString('\n')
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, io.EOF):
+			if l == "" {
+				if len(p) != 0 {
+					return p, nil
+				}
+				return nil, io.EOF
+			}
+		default:
+			return nil, fmt.Errorf("dpkg: reading control file: %w", err)
+		}
+		c.line++
+		l = strings.TrimRight(l, "\r\n")
+
+		switch {
+		case strings.TrimSpace(l) == "":
+			if len(p) != 0 {
+				return p, nil
+			}
+			// Extra blank lines between paragraphs are ignored.
+			continue
+		case l[0] == '#':
+			// Comments are only allowed in some control files, but are
+			// harmless to skip.
+			continue
+		case l[0] == ' ' || l[0] == '\t':
+			if last == "" {
+				c.bad++
+				continue
+			}
+			v := strings.TrimSpace(l)
+			if v == "." {
+				// A lone "." is an empty line in a multiline value.
+				v = ""
+			}
+			p[last] += "\n" + v
+			continue
+		}
+
+		i := strings.IndexByte(l, ':')
+		if i < 1 || strings.ContainsAny(l[:i], " \t") {
+			c.bad++
+			last = ""
+			continue
+		}
+		if p == nil {
+			p = make(paragraph)
+		}
+		last = strings.ToLower(l[:i])
+		p[last] = strings.TrimSpace(l[i+1:])
+	}
+}
+
+// Paragraph is a single paragraph from a control file, keyed by lowercased
+// field name.
+//
+// Multiline values have their lines joined with "\n", with the leading
+// whitespace of continuation lines removed.
+type paragraph map[string]string
+
+// Get returns the named field's value, or "" if it's not present.
+func (p paragraph) Get(name string) string {
+	return p[strings.ToLower(name)]
+}
+
+// Source returns the name and version of the source package the binary
+// package was built from.
+//
+// The "Source" field may carry a version in parentheses, as in
+// "Source: glibc (2.31-13)", when the source version differs from the binary
+// version. Otherwise the binary's version is used. If there's no "Source"
+// field, the returned name is empty.
+func (p paragraph) Source() (name, version string) {
+	name, version = p.Get("Source"), p.Get("Version")
+	if i := strings.IndexByte(name, '('); i != -1 {
+		if j := strings.IndexByte(name[i:], ')'); j != -1 {
+			if v := strings.TrimSpace(name[i+1 : i+j]); v != "" {
+				version = v
+			}
+		}
+		name = strings.TrimSpace(name[:i])
+	}
+	return name, version
+}
+
+// Maintainer returns the "Maintainer" field.
+func (p paragraph) Maintainer() string {
+	return p.Get("Maintainer")
+}
+
+// Essential reports whether the package is marked "Essential: yes", meaning
+// dpkg won't remove it without being forced.
+func (p paragraph) Essential() bool {
+	return strings.EqualFold(p.Get("Essential"), "yes")
+}
@@ -0,0 +1,60 @@
+//go:build go1.18
+// +build go1.18
+
+package dpkg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+)
+
+// FuzzControlReader checks that no input makes the control file parser
+// panic, loop, or produce nonsense fields, and that the scanner's use of it
+// copes with whatever comes out.
+func FuzzControlReader(f *testing.F) {
+	b, err := os.ReadFile("testdata/texlive.status")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(b[:4096])
+	f.Add([]byte("Package: a\nVersion: 1\n\nPackage: b\nSource: c (2)\nVersion: 1\n"))
+	f.Add([]byte("Package: a\n continuation\n .\n\n\n\n: no name\nbad line\n\tDescription: x\n"))
+	f.Add([]byte("\r\nPackage:\r\nVersion:1:2-3\r\nSource: (\r\n"))
+	f.Fuzz(func(t *testing.T, in []byte) {
+		cr := newControlReader(strings.NewReader(string(in)))
+		var n int
+		p, err := cr.Next()
+		for ; err == nil; p, err = cr.Next() {
+			n++
+			if n > len(in) {
+				t.Fatalf("more paragraphs (%d) than bytes of input (%d)", n, len(in))
+			}
+			if len(p) == 0 {
+				t.Fatal("empty paragraph returned")
+			}
+			for k := range p {
+				if k == "" || strings.ContainsAny(k, " \t\n:") || k != strings.ToLower(k) {
+					t.Fatalf("bad field name %q", k)
+				}
+			}
+			p.Source()
+		}
+		if !errors.Is(err, io.EOF) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ctx := zlog.Test(context.Background(), t)
+		var s Scanner
+		for _, pkg := range s.readStatus(ctx, strings.NewReader(string(in)), "var/lib/dpkg/status") {
+			if pkg.Name == "" {
+				t.Fatal("package without a name")
+			}
+		}
+	})
+}
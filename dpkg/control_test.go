@@ -0,0 +1,117 @@
+package dpkg
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestControlReader(t *testing.T) {
+	const in = `Package: libc6
+Status: install ok installed
+Priority: optional
+Section: libs
+Maintainer: GNU Libc Maintainers <debian-glibc@lists.debian.org>
+Architecture: amd64
+Multi-Arch: same
+Source: glibc
+Version: 2.31-13+deb11u5
+Description: GNU C Library: Shared libraries
+ Contains the standard libraries that are used by nearly all programs on
+ the system.
+ .
+ This package includes shared versions of the standard C library.
+Conffiles:
+ /etc/ld.so.conf.d/x86_64-linux-gnu.conf d4e7a7b88a71b5ffd9e2644e71a0cfab
+
+
+package: libgcc-s1
+STATUS: install ok installed
+Essential: yes
+Source: gcc-10 (10.2.1-6)
+Version: 10.2.1-6
+this line is bogus
+Architecture: amd64
+Package: openssl
+Version: 1.1.1n-0+deb11u4`
+	cr := newControlReader(strings.NewReader(in))
+	var ps []paragraph
+	p, err := cr.Next()
+	for ; err == nil; p, err = cr.Next() {
+		ps = append(ps, p)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Fatal(err)
+	}
+	if got, want := len(ps), 2; got != want {
+		t.Fatalf("got: %d paragraphs, want: %d", got, want)
+	}
+	if got, want := cr.bad, 1; got != want {
+		t.Errorf("got: %d bad lines, want: %d", got, want)
+	}
+
+	libc := ps[0]
+	if got, want := libc.Get("Package"), "libc6"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	wantDesc := "GNU C Library: Shared libraries\n" +
+		"Contains the standard libraries that are used by nearly all programs on\n" +
+		"the system.\n" +
+		"\n" +
+		"This package includes shared versions of the standard C library."
+	if got := libc.Get("description"); got != wantDesc {
+		t.Error(cmp.Diff(got, wantDesc))
+	}
+	if got, want := libc.Get("Conffiles"), "\n/etc/ld.so.conf.d/x86_64-linux-gnu.conf d4e7a7b88a71b5ffd9e2644e71a0cfab"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := libc.Maintainer(), "GNU Libc Maintainers <debian-glibc@lists.debian.org>"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if n, v := libc.Source(); n != "glibc" || v != "2.31-13+deb11u5" {
+		t.Errorf("got source: %q %q", n, v)
+	}
+	if libc.Essential() {
+		t.Error("libc6 isn't essential")
+	}
+
+	gcc := ps[1]
+	// Field names are case-insensitive, and a repeated field replaces the
+	// earlier one.
+	if got, want := gcc.Get("Status"), "install ok installed"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := gcc.Get("Package"), "openssl"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if !gcc.Essential() {
+		t.Error("libgcc-s1 is essential")
+	}
+	if n, v := gcc.Source(); n != "gcc-10" || v != "10.2.1-6" {
+		t.Errorf("got source: %q %q", n, v)
+	}
+}
+
+func TestSourceVersion(t *testing.T) {
+	tt := []struct {
+		Source, Version string
+		Name, Want      string
+	}{
+		{"", "1.0", "", "1.0"},
+		{"foo", "1.0", "foo", "1.0"},
+		{"foo (1.2)", "1.0+b1", "foo", "1.2"},
+		{"foo (1:1.2-3)", "1:1.2-3+b2", "foo", "1:1.2-3"},
+		{"foo ()", "1.0", "foo", "1.0"},
+		{"foo (1.2", "1.0", "foo", "1.0"},
+	}
+	for _, tc := range tt {
+		p := paragraph{"source": tc.Source, "version": tc.Version}
+		n, v := p.Source()
+		if n != tc.Name || v != tc.Want {
+			t.Errorf("%q: got: %q %q, want: %q %q", tc.Source, n, v, tc.Name, tc.Want)
+		}
+	}
+}
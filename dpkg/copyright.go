@@ -0,0 +1,74 @@
+package dpkg
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DocDir is where packages install their documentation, including the
+// "copyright" file policy requires every package to have.
+const docDir = "usr/share/doc"
+
+// ReadCopyright returns the license expression declared by a machine-readable
+// copyright file, or an empty string if the file isn't machine-readable.
+//
+// The format is described at
+// https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/: the
+// header paragraph has a "Format" field naming the specification, and each
+// "Files" paragraph has a "License" field whose first line is the license's
+// short name. Stand-alone "License" paragraphs only hold license texts, so
+// they're skipped.
+//
+// The short names are deduplicated and sorted, then joined with "AND".
+func readCopyright(r io.Reader) (string, error) {
+	cr := newControlReader(r)
+	hdr, err := cr.Next()
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, io.EOF):
+		return "", nil
+	default:
+		return "", err
+	}
+	if f := hdr.Get("Format"); !strings.Contains(f, "copyright-format") && !strings.Contains(f, "dep5") {
+		return "", nil
+	}
+	seen := make(map[string]struct{})
+	add := func(p paragraph) {
+		l := p.Get("License")
+		if i := strings.IndexByte(l, '\n'); i != -1 {
+			l = l[:i]
+		}
+		if l = strings.TrimSpace(l); l != "" {
+			seen[l] = struct{}{}
+		}
+	}
+	add(hdr)
+	for {
+		p, err := cr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if p.Get("Files") != "" {
+			add(p)
+		}
+	}
+	ls := make([]string, 0, len(seen))
+	for l := range seen {
+		ls = append(ls, l)
+	}
+	sort.Strings(ls)
+	if len(ls) > 1 {
+		for i, l := range ls {
+			if strings.ContainsAny(l, " \t") {
+				ls[i] = "(" + l + ")"
+			}
+		}
+	}
+	return strings.Join(ls, " AND "), nil
+}
@@ -0,0 +1,165 @@
+package dpkg
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+func TestReadCopyright(t *testing.T) {
+	tt := []struct {
+		Name string
+		In   string
+		Want string
+	}{
+		{
+			Name: "Single",
+			In: `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: hostname
+
+Files: *
+Copyright: 1997 Peter Tobias
+License: GPL-2+
+ This program is free software.
+`,
+			Want: "GPL-2+",
+		},
+		{
+			Name: "Several",
+			In: `Format: http://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+License: Zlib
+
+Files: *
+Copyright: 1995-2017 Jean-loup Gailly and Mark Adler
+License: Zlib
+
+Files: contrib/*
+Copyright: 2004 someone
+License: BSD-3-clause or GPL-2+
+
+License: Zlib
+ This software is provided 'as-is'.
+
+License: Artistic
+ Not used by any Files paragraph.
+`,
+			Want: "(BSD-3-clause or GPL-2+) AND Zlib",
+		},
+		{
+			Name: "DEP5",
+			In: `Format-Specification: http://svn.debian.org/wsvn/dep/web/deps/dep5.mdwn?op=file&rev=135
+Format: http://dep.debian.net/deps/dep5
+
+Files: *
+License: MIT
+`,
+			Want: "MIT",
+		},
+		{
+			Name: "FreeForm",
+			In: `This package was debianized by someone.
+
+It is licensed under the GPL.
+`,
+		},
+		{Name: "Empty"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := readCopyright(strings.NewReader(tc.In))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.Want {
+				t.Errorf("got: %q, want: %q", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestLicenses(t *testing.T) {
+	t.Parallel()
+	ctx := zlog.Test(context.Background(), t)
+	const status = `Package: hostname
+Status: install ok installed
+Version: 3.23
+Architecture: amd64
+
+Package: base-files
+Status: install ok installed
+Version: 11.1+deb11u1
+Architecture: amd64
+
+`
+	const copyright = `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+
+Files: *
+License: GPL-2+
+`
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{Name: "var/lib/dpkg/info/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []struct{ Name, Body string }{
+		{"var/lib/dpkg/status", status},
+		{"usr/share/doc/hostname/copyright", copyright},
+		{"usr/share/doc/base-files/copyright", "This is Debian GNU/Linux's prepackaged version of the FSF's GNU hello program.\n"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: e.Name, Typeflag: tar.TypeReg, Size: int64(len(e.Body)), Mode: 0644}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, e.Body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := claircore.Layer{}
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, include := range []bool{false, true} {
+		var s Scanner
+		if err := s.Configure(ctx, func(v interface{}) error {
+			v.(*ScannerConfig).IncludeLicenses = include
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		ps, err := s.Scan(ctx, &l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, p := range ps {
+			if l, ok := p.Metadata[claircore.MetadataLicense]; ok {
+				got[p.Name] = l
+			}
+		}
+		want := map[string]string{}
+		if include {
+			want["hostname"] = "GPL-2+"
+		}
+		if len(got) != len(want) || got["hostname"] != want["hostname"] {
+			t.Errorf("include %v: got: %v, want: %v", include, got, want)
+		}
+	}
+}
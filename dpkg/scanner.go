@@ -4,15 +4,17 @@ package dpkg
 import (
 	"archive/tar"
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"net/textproto"
+	"path"
 	"path/filepath"
 	"runtime/trace"
+	"sort"
 	"strings"
 
 	"github.com/quay/zlog"
@@ -26,21 +28,65 @@ import (
 const (
 	name    = "dpkg"
 	kind    = "package"
-	version = "4"
+	version = "8"
 )
 
 var (
-	_ indexer.VersionedScanner = (*Scanner)(nil)
-	_ indexer.PackageScanner   = (*Scanner)(nil)
+	_ indexer.VersionedScanner    = (*Scanner)(nil)
+	_ indexer.PackageScanner      = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter  = (*Scanner)(nil)
+	_ indexer.ConfigurableScanner = (*Scanner)(nil)
 )
 
+// ScannerConfig is the struct used to configure a Scanner.
+type ScannerConfig struct {
+	// IncludeConfigFiles reports packages that have been removed but whose
+	// configuration files remain, in the "config-files" state. By default,
+	// only packages with their files unpacked are reported.
+	IncludeConfigFiles bool `yaml:"include_config_files" json:"include_config_files"`
+	// IncludeLicenses reads each package's machine-readable copyright file
+	// from "usr/share/doc" in the same layer as the package database, and
+	// records the declared licenses in the package's Metadata under the
+	// claircore.MetadataLicense key.
+	IncludeLicenses bool `yaml:"include_licenses" json:"include_licenses"`
+	// DatabasePaths restricts the directories examined as dpkg databases to
+	// those matching one of the patterns, in the syntax of path.Match.
+	// Patterns are relative to the root of the layer, such as
+	// "var/lib/dpkg" or "srv/chroot/*/var/lib/dpkg".
+	//
+	// A directory matching a pattern is used as a database if it has a
+	// "status" file, even without the "info" directory the default heuristic
+	// requires.
+	//
+	// By default, any directory that looks like a dpkg database is examined.
+	DatabasePaths []string `yaml:"database_paths" json:"database_paths"`
+	// VerifyFiles hashes the files each package installed that are present
+	// in the same layer as the package database, and compares them against
+	// the package's ".md5sums" file. Packages with files that don't match
+	// have them listed in the package's Metadata under the
+	// claircore.MetadataModified key.
+	//
+	// This reads the layer twice.
+	VerifyFiles bool `yaml:"verify_files" json:"verify_files"`
+}
+
 // Scanner implements the scanner.PackageScanner interface.
 //
 // This looks for directories that look like dpkg databases and examines the
-// "status" file it finds there.
+// "status" file it finds there, along with each package's list of installed
+// files. The "status.d" directories used by distroless images are also
+// examined.
+//
+// Only packages whose "Status" field shows them to be installed are reported;
+// see ScannerConfig.
 //
 // The zero value is ready to use.
-type Scanner struct{}
+type Scanner struct {
+	dbPaths     []string
+	configFiles bool
+	licenses    bool
+	verify      bool
+}
 
 // Name implements scanner.VersionedScanner.
 func (ps *Scanner) Name() string { return name }
@@ -51,6 +97,38 @@ func (ps *Scanner) Version() string { return version }
 // Kind implements scanner.VersionedScanner.
 func (ps *Scanner) Kind() string { return kind }
 
+// Confidence implements indexer.ConfidenceReporter.
+func (ps *Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceAuthoritative }
+
+// Configure implements indexer.ConfigurableScanner.
+func (ps *Scanner) Configure(ctx context.Context, f indexer.ConfigDeserializer) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "dpkg/Scanner.Configure"),
+		label.String("version", ps.Version()))
+	var cfg ScannerConfig
+	if err := f(&cfg); err != nil {
+		return err
+	}
+	ps.dbPaths = nil
+	for _, p := range cfg.DatabasePaths {
+		p = strings.Trim(path.Clean("/"+p), "/")
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("dpkg: bad database path %q: %w", p, err)
+		}
+		ps.dbPaths = append(ps.dbPaths, p)
+	}
+	ps.configFiles = cfg.IncludeConfigFiles
+	ps.licenses = cfg.IncludeLicenses
+	ps.verify = cfg.VerifyFiles
+	zlog.Debug(ctx).
+		Strs("database_paths", ps.dbPaths).
+		Bool("include_config_files", ps.configFiles).
+		Bool("include_licenses", ps.licenses).
+		Bool("verify_files", ps.verify).
+		Msg("configured")
+	return nil
+}
+
 // Scan attempts to find a dpkg database within the layer and read all of the
 // installed packages it can find in the "status" file.
 //
@@ -68,164 +146,413 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 	zlog.Debug(ctx).Msg("start")
 	defer zlog.Debug(ctx).Msg("done")
 
-	// Grab a handle to the tarball, make sure we can seek.
-	// If we can't, we'd need another reader for every database found.
-	// It's cleaner to just demand that it's a seeker.
 	rd, err := layer.Reader()
 	if err != nil {
 		return nil, fmt.Errorf("opening layer failed: %w", err)
 	}
 	defer rd.Close()
-	r, ok := rd.(io.ReadSeeker)
-	if !ok {
-		err := errors.New("unable to coerce to io.Seeker")
-		return nil, fmt.Errorf("opening layer failed: %w", err)
-	}
 
-	tr := tar.NewReader(r)
-	// This is a map keyed by directory. A "score" of 2 means this is almost
-	// certainly a dpkg database.
-	loc := make(map[string]int)
-Find:
-	for {
-		h, err := tr.Next()
-		switch err {
-		case nil:
-		case io.EOF:
-			break Find
-		default:
-			return nil, fmt.Errorf("reading next header failed: %w", err)
-		}
-		switch filepath.Base(h.Name) {
-		case "status":
-			if h.Typeflag == tar.TypeReg {
-				loc[filepath.Dir(h.Name)]++
-			}
-		case "info":
-			if h.Typeflag == tar.TypeDir {
-				loc[filepath.Dir(filepath.Dir(h.Name))]++
-			}
+	// The layer is read once. Everything that could be part of a database
+	// is collected, keyed by the directory it would be a database in, and
+	// the databases are put together afterwards.
+	dbs := make(map[string]*database)
+	get := func(d string) *database {
+		db, ok := dbs[d]
+		if !ok {
+			db = newDatabase()
+			dbs[d] = db
 		}
+		return db
 	}
-	zlog.Debug(ctx).Msg("scanned for possible databases")
-
-	// If we didn't find anything, this loop is completely skipped.
-	var pkgs []*claircore.Package
-	for p, x := range loc {
-		if x != 2 { // If we didn't find both files, skip this directory.
-			continue
-		}
-		ctx = baggage.ContextWithValues(ctx, label.String("database", p))
-		zlog.Debug(ctx).Msg("examining package database")
-
-		// Reset the tar reader.
-		if n, err := r.Seek(0, io.SeekStart); n != 0 || err != nil {
-			return nil, fmt.Errorf("unable to seek reader: %w", err)
-		}
-		tr = tar.NewReader(r)
-
-		// We want the "status" file, so search the archive for it.
-		fn := filepath.Join(p, "status")
-		var db io.Reader
-		var h *tar.Header
-		for h, err = tr.Next(); err == nil; h, err = tr.Next() {
-			// The location from above is cleaned, so make sure to do that.
-			if c := filepath.Clean(h.Name); c == fn {
-				db = tr
-				break
-			}
-		}
-		// Check what happened in the above loop.
+	// Licenses holds the license expression from each package's copyright
+	// file, keyed by package name.
+	licenses := make(map[string]string)
+	tr := tar.NewReader(rd)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		name := filepath.Clean(h.Name)
+		dir, base := filepath.Dir(name), filepath.Base(name)
 		switch {
-		case errors.Is(err, io.EOF):
-			return nil, nil
-		case err != nil:
-			return nil, fmt.Errorf("reading status file from layer failed: %w", err)
-		case db == nil:
-			zlog.Error(ctx).
-				Str("filename", fn).
-				Msg("unable to get reader for file")
-			panic("file existed, but now doesn't")
-		}
-
-		// Take all the packages found in the database and attach to the slice
-		// defined outside the loop.
-		found := make(map[string]*claircore.Package)
-		// The database is actually an RFC822-like message with "\n\n"
-		// separators, so don't be alarmed by the usage of the "net/textproto"
-		// package here.
-		tp := textproto.NewReader(bufio.NewReader(db))
-	Restart:
-		hdr, err := tp.ReadMIMEHeader()
-		for ; err == nil && len(hdr) > 0; hdr, err = tp.ReadMIMEHeader() {
-			name := hdr.Get("Package")
-			v := hdr.Get("Version")
-			p := &claircore.Package{
-				Name:      name,
-				Version:   v,
-				Kind:      claircore.BINARY,
-				Arch:      hdr.Get("Architecture"),
-				PackageDB: fn,
+		case h.Typeflag == tar.TypeDir:
+			if base == "info" {
+				get(dir).info = true
 			}
-			if src := hdr.Get("Source"); src != "" {
-				p.Source = &claircore.Package{
-					Name: src,
-					Kind: claircore.SOURCE,
-					// Right now, this is an assumption that discovered source
-					// packages relate to their binary versions. We see this in
-					// Debian.
-					Version:   v,
-					PackageDB: fn,
+		case h.Typeflag != tar.TypeReg:
+		case ps.licenses && base == "copyright" && filepath.Dir(dir) == docDir:
+			n := filepath.Base(dir)
+			l, err := readCopyright(tr)
+			if err != nil {
+				zlog.Warn(ctx).
+					Err(err).
+					Str("package", n).
+					Msg("unable to read copyright file")
+				continue
+			}
+			if l != "" {
+				licenses[n] = l
+			}
+		case filepath.Base(dir) == "status.d":
+			// Distroless images use a "status.d" directory of status file
+			// fragments in place of the "status" file and "info" directory.
+			db := get(filepath.Dir(dir))
+			if n := strings.TrimSuffix(base, sumsSuffix); n != base {
+				if err := ps.readSums(db, n, tr); err != nil {
+					zlog.Warn(ctx).
+						Err(err).
+						Str("file", name).
+						Msg("unable to read package metadata")
 				}
+				continue
 			}
-
-			found[name] = p
-			pkgs = append(pkgs, p)
-		}
-		switch {
-		case errors.Is(err, io.EOF):
-		default:
-			zlog.Warn(ctx).Err(err).Msg("unable to read entry")
-			goto Restart
-		}
-
-		// Reset the tar reader, again.
-		if n, err := r.Seek(0, io.SeekStart); n != 0 || err != nil {
-			return nil, fmt.Errorf("resetting tar reader failed: %w", err)
-		}
-		tr = tar.NewReader(r)
-		prefix := filepath.Join(p, "info") + string(filepath.Separator)
-		const suffix = ".md5sums"
-		for h, err = tr.Next(); err == nil; h, err = tr.Next() {
-			if !strings.HasPrefix(h.Name, prefix) || !strings.HasSuffix(h.Name, suffix) {
+			db.fragments[base] = ps.readStatus(ctx, tr, dir)
+		case base == "status":
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("reading status file from layer failed: %w", err)
+			}
+			get(dir).status = b
+		case filepath.Base(dir) == "info":
+			n := base
+			var suffix string
+			switch {
+			case strings.HasSuffix(n, sumsSuffix):
+				suffix = sumsSuffix
+			case strings.HasSuffix(n, listSuffix):
+				suffix = listSuffix
+			default:
 				continue
 			}
-			n := filepath.Base(h.Name)
 			n = strings.TrimSuffix(n, suffix)
 			if i := strings.IndexRune(n, ':'); i != -1 {
 				n = n[:i]
 			}
-			p, ok := found[n]
-			if !ok {
-				zlog.Debug(ctx).
-					Str("package", n).
-					Msg("extra metadata found, ignoring")
+			db := get(filepath.Dir(dir))
+			if suffix == listSuffix {
+				fs, err := readList(tr)
+				if err != nil {
+					zlog.Warn(ctx).
+						Err(err).
+						Str("package", n).
+						Msg("unable to read package file list")
+					continue
+				}
+				db.files[n] = fs
 				continue
 			}
-			hash := md5.New()
-			if _, err := io.Copy(hash, tr); err != nil {
+			if err := ps.readSums(db, n, tr); err != nil {
 				zlog.Warn(ctx).
 					Err(err).
 					Str("package", n).
 					Msg("unable to read package metadata")
-				continue
 			}
-			p.RepositoryHint = hex.EncodeToString(hash.Sum(nil))
 		}
-		zlog.Debug(ctx).
-			Int("count", len(found)).
-			Msg("found packages")
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading next header failed: %w", err)
+	}
+	zlog.Debug(ctx).Msg("scanned for possible databases")
+
+	ds := make([]string, 0, len(dbs))
+	for d := range dbs {
+		ds = append(ds, d)
+	}
+	sort.Strings(ds)
+	var v *verifier
+	if ps.verify {
+		v = newVerifier()
+	}
+	var pkgs []*claircore.Package
+	for _, d := range ds {
+		db := dbs[d]
+		configured := len(ps.dbPaths) != 0
+		if configured && !ps.isDatabasePath(d) {
+			zlog.Debug(ctx).
+				Str("database", d).
+				Msg("skipping unconfigured database path")
+			continue
+		}
+		if db.status != nil && (db.info || configured) {
+			ctx := baggage.ContextWithValues(ctx, label.String("database", d))
+			zlog.Debug(ctx).Msg("examining package database")
+			found := db.packages(ctx, ps, d, v)
+			zlog.Debug(ctx).
+				Int("count", len(found)).
+				Msg("found packages")
+			pkgs = append(pkgs, found...)
+		}
+		if len(db.fragments) != 0 {
+			ctx := baggage.ContextWithValues(ctx, label.String("database", filepath.Join(d, "status.d")))
+			zlog.Debug(ctx).Msg("examining distroless package database")
+			found := db.distroless(d, v)
+			zlog.Debug(ctx).
+				Int("count", len(found)).
+				Msg("found packages")
+			pkgs = append(pkgs, found...)
+		}
+	}
+	for _, p := range pkgs {
+		if l, ok := licenses[p.Name]; ok {
+			setMetadata(p, claircore.MetadataLicense, l)
+		}
+	}
+	if v != nil {
+		if err := v.run(ctx, layer); err != nil {
+			return nil, fmt.Errorf("verifying installed files failed: %w", err)
+		}
 	}
 
 	return pkgs, nil
 }
+
+// IsDatabasePath reports whether the directory "d" matches one of the
+// configured database paths.
+func (ps *Scanner) isDatabasePath(d string) bool {
+	d = filepath.ToSlash(d)
+	for _, p := range ps.dbPaths {
+		if ok, _ := path.Match(p, d); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Suffixes of the per-package files in the "info" and "status.d"
+// directories.
+const (
+	sumsSuffix = ".md5sums"
+	listSuffix = ".list"
+)
+
+// Database collects the parts of a possible dpkg database found in a layer.
+//
+// A directory with a "status" file and an "info" directory is almost
+// certainly a dpkg database. One with a "status.d" directory is a distroless
+// database.
+type database struct {
+	status []byte
+	info   bool
+	// Sums holds the md5 of each package's ".md5sums" file and files holds
+	// its ".list" file, keyed by package name, or in "status.d", by the name
+	// of the fragment.
+	sums  map[string]string
+	files map[string][]string
+	// Manifests holds the contents of the ".md5sums" files, keyed the same
+	// way as sums. It's only populated when verifying files.
+	manifests map[string]map[string]string
+	// Fragments holds the packages from each file in "status.d".
+	fragments map[string][]*claircore.Package
+}
+
+func newDatabase() *database {
+	return &database{
+		sums:      make(map[string]string),
+		files:     make(map[string][]string),
+		manifests: make(map[string]map[string]string),
+		fragments: make(map[string][]*claircore.Package),
+	}
+}
+
+// Packages reads the packages from the "status" file in the directory "d",
+// and attaches their metadata from the "info" directory.
+//
+// Their manifests are added to the verifier, if it's not nil.
+func (db *database) packages(ctx context.Context, ps *Scanner, d string, v *verifier) []*claircore.Package {
+	pkgs := ps.readStatus(ctx, bytes.NewReader(db.status), filepath.Join(d, "status"))
+	found := make(map[string]*claircore.Package, len(pkgs))
+	for _, p := range pkgs {
+		found[p.Name] = p
+	}
+	for n, sum := range db.sums {
+		p, ok := found[n]
+		if !ok {
+			zlog.Debug(ctx).
+				Str("package", n).
+				Msg("extra metadata found, ignoring")
+			continue
+		}
+		p.RepositoryHint = sum
+		v.add(d, p, db.manifests[n])
+	}
+	for n, fs := range db.files {
+		if p, ok := found[n]; ok {
+			p.Files = fs
+		}
+	}
+	return pkgs
+}
+
+// Distroless returns the packages from the "status.d" fragments in the
+// directory "d". Any ".md5sums" file is named for the fragment it
+// accompanies.
+//
+// Their manifests are added to the verifier, if it's not nil.
+func (db *database) distroless(d string, v *verifier) []*claircore.Package {
+	ns := make([]string, 0, len(db.fragments))
+	for n := range db.fragments {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	var pkgs []*claircore.Package
+	for _, n := range ns {
+		for _, p := range db.fragments[n] {
+			if sum, ok := db.sums[n]; ok {
+				p.RepositoryHint = sum
+				v.add(d, p, db.manifests[n])
+			}
+			pkgs = append(pkgs, p)
+		}
+	}
+	return pkgs
+}
+
+// ReadSums records the md5 of a package's ".md5sums" file in the database,
+// keyed by "n", and if verifying files, its contents.
+func (ps *Scanner) readSums(db *database, n string, r io.Reader) error {
+	if !ps.verify {
+		sum, err := md5sum(r)
+		if err != nil {
+			return err
+		}
+		db.sums[n] = sum
+		return nil
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	sum, err := md5sum(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	m, err := parseSums(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	db.sums[n] = sum
+	db.manifests[n] = m
+	return nil
+}
+
+// Md5sum returns the hex-encoded md5 of the Reader's contents.
+func md5sum(r io.Reader) (string, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadStatus reads the package entries from a "status" file, or a fragment of
+// one, recording "fn" as the PackageDB.
+//
+// Entries for packages that aren't installed are skipped.
+func (ps *Scanner) readStatus(ctx context.Context, db io.Reader, fn string) []*claircore.Package {
+	var pkgs []*claircore.Package
+	cr := newControlReader(db)
+	for {
+		p, err := cr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to read entry")
+			break
+		}
+		name := p.Get("Package")
+		if name == "" {
+			continue
+		}
+		if st := p.Get("Status"); !ps.installed(st) {
+			zlog.Debug(ctx).
+				Str("package", name).
+				Str("status", st).
+				Msg("skipping package not installed")
+			continue
+		}
+		pkg := &claircore.Package{
+			Name:      name,
+			Version:   p.Get("Version"),
+			Kind:      claircore.BINARY,
+			Arch:      p.Get("Architecture"),
+			PackageDB: fn,
+		}
+		if src, v := p.Source(); src != "" {
+			pkg.Source = &claircore.Package{
+				Name:      src,
+				Kind:      claircore.SOURCE,
+				Version:   v,
+				PackageDB: fn,
+			}
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	if cr.bad != 0 {
+		zlog.Warn(ctx).
+			Int("count", cr.bad).
+			Msg("skipped malformed lines")
+	}
+	return pkgs
+}
+
+// ReadList reads a package's ".list" file, returning the paths of the files
+// the package installed, relative to the root and sorted.
+//
+// The list includes every directory the package created or shares, so any
+// entry that's the parent of another entry is left out.
+func readList(r io.Reader) ([]string, error) {
+	var ls []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := strings.TrimPrefix(path.Clean("/"+s.Text()), "/")
+		if l == "" {
+			continue
+		}
+		ls = append(ls, l)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(ls)
+	out := ls[:0]
+	for i, l := range ls {
+		if i+1 < len(ls) && isParent(l, ls[i+1:]) {
+			continue
+		}
+		if len(out) != 0 && out[len(out)-1] == l {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+// IsParent reports whether "dir" is the parent directory of any of the
+// sorted paths following it.
+func isParent(dir string, rest []string) bool {
+	p := dir + "/"
+	i := sort.SearchStrings(rest, p)
+	return i < len(rest) && strings.HasPrefix(rest[i], p)
+}
+
+// Installed reports whether a package with the provided "Status" field value
+// should be reported.
+//
+// The field is "want flag status"; the last word is the package's state. An
+// absent field, as in some distroless "status.d" fragments, is taken to mean
+// the package is installed.
+func (ps *Scanner) installed(status string) bool {
+	if status == "" {
+		return true
+	}
+	f := strings.Fields(status)
+	switch f[len(f)-1] {
+	case "not-installed":
+		return false
+	case "config-files":
+		return ps.configFiles
+	}
+	// The remaining states ("installed", "unpacked", "half-configured",
+	// "half-installed", "triggers-awaited", and "triggers-pending") all have
+	// some or all of the package's files on disk.
+	return true
+}
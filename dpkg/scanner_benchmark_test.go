@@ -0,0 +1,79 @@
+package dpkg
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// BenchmarkScan measures scanning a synthetic layer with a few hundred
+// packages in its dpkg database and many unrelated files, which is where
+// reading the layer more than once is costly.
+func BenchmarkScan(b *testing.B) {
+	const (
+		pkgs  = 500
+		other = 20000
+	)
+	name := filepath.Join(b.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	add := func(n, c string) {
+		if err := tw.WriteHeader(&tar.Header{Name: n, Typeflag: tar.TypeReg, Size: int64(len(c)), Mode: 0644}); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, c); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "var/lib/dpkg/info/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		b.Fatal(err)
+	}
+	var status strings.Builder
+	for i := 0; i < pkgs; i++ {
+		n := fmt.Sprintf("pkg%d", i)
+		fmt.Fprintf(&status, "Package: %s\nStatus: install ok installed\nVersion: 1.%d-1\nArchitecture: amd64\nSource: src%d\n\n", n, i, i)
+		add("var/lib/dpkg/info/"+n+".md5sums", fmt.Sprintf("d41d8cd98f00b204e9800998ecf8427e  usr/bin/%s\n", n))
+		add("var/lib/dpkg/info/"+n+".list", fmt.Sprintf("/.\n/usr\n/usr/bin\n/usr/bin/%s\n", n))
+	}
+	filler := strings.Repeat("x", 4096)
+	for i := 0; i < other; i++ {
+		add(fmt.Sprintf("usr/share/filler/%d/file", i), filler)
+	}
+	add("var/lib/dpkg/status", status.String())
+	if err := tw.Close(); err != nil {
+		b.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		b.Fatal(err)
+	}
+	var l claircore.Layer
+	if err := l.SetLocal(name); err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := zlog.Test(context.Background(), b)
+	var s Scanner
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps, err := s.Scan(ctx, &l)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(ps) != pkgs {
+			b.Fatalf("got: %d packages, want: %d", len(ps), pkgs)
+		}
+	}
+}
@@ -2,17 +2,18 @@ package dpkg
 
 import (
 	"archive/tar"
-	"bufio"
 	"context"
 	"errors"
 	"io"
 	"net/http"
-	"net/textproto"
 	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/quay/zlog"
 
 	"github.com/quay/claircore"
@@ -823,8 +824,10 @@ func TestScanner(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !cmp.Equal(got, want) {
-		t.Fatal(cmp.Diff(got, want))
+	// The file lists are long; they're checked in TestFileList.
+	opt := cmpopts.IgnoreFields(claircore.Package{}, "Files")
+	if !cmp.Equal(got, want, opt) {
+		t.Fatal(cmp.Diff(got, want, opt))
 	}
 }
 
@@ -981,9 +984,9 @@ func TestGiantStatus(t *testing.T) {
 	}
 
 	var found int
-	tp := textproto.NewReader(bufio.NewReader(db))
-	hdr, err := tp.ReadMIMEHeader()
-	for ; err == nil && len(hdr) > 0; hdr, err = tp.ReadMIMEHeader() {
+	cr := newControlReader(db)
+	_, err = cr.Next()
+	for ; err == nil; _, err = cr.Next() {
 		found++
 	}
 	t.Logf("found %d installed packages", found)
@@ -994,3 +997,328 @@ func TestGiantStatus(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestDistroless(t *testing.T) {
+	t.Parallel()
+	ctx := zlog.Test(context.Background(), t)
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	for _, e := range []struct {
+		Name, Content string
+	}{
+		{"var/lib/dpkg/status.d/base-files", "Package: base-files\nVersion: 11.1+deb11u5\nArchitecture: amd64\n"},
+		{"var/lib/dpkg/status.d/base-files.md5sums", "d41d8cd98f00b204e9800998ecf8427e  etc/debian_version\n"},
+		{"var/lib/dpkg/status.d/libssl1.1", "Package: libssl1.1\nSource: openssl\nVersion: 1.1.1n-0+deb11u3\nArchitecture: amd64\n"},
+		{"./var/lib/dpkg/status.d/tzdata", "Package: tzdata\nVersion: 2021a-1+deb11u8\nArchitecture: all\n"},
+	} {
+		h := tar.Header{Name: e.Name, Typeflag: tar.TypeReg, Size: int64(len(e.Content)), Mode: 0644}
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, e.Content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := claircore.Layer{}
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	const db = "var/lib/dpkg/status.d"
+	want := []*claircore.Package{
+		{
+			Name:           "base-files",
+			Version:        "11.1+deb11u5",
+			Kind:           claircore.BINARY,
+			Arch:           "amd64",
+			PackageDB:      db,
+			RepositoryHint: "37f11386a95a35d59ebd6b419aa59126",
+		},
+		{
+			Name:      "libssl1.1",
+			Version:   "1.1.1n-0+deb11u3",
+			Kind:      claircore.BINARY,
+			Arch:      "amd64",
+			PackageDB: db,
+			Source:    &claircore.Package{Name: "openssl", Version: "1.1.1n-0+deb11u3", Kind: claircore.SOURCE, PackageDB: db},
+		},
+		{
+			Name:      "tzdata",
+			Version:   "2021a-1+deb11u8",
+			Kind:      claircore.BINARY,
+			Arch:      "all",
+			PackageDB: db,
+		},
+	}
+	var s Scanner
+	got, err := s.Scan(ctx, &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestInstallState(t *testing.T) {
+	t.Parallel()
+	ctx := zlog.Test(context.Background(), t)
+	const status = `Package: bash
+Status: install ok installed
+Version: 5.1-2+deb11u1
+Architecture: amd64
+
+Package: vim-tiny
+Status: deinstall ok config-files
+Version: 2:8.2.2434-3+deb11u1
+Architecture: amd64
+
+Package: nano
+Status: purge ok not-installed
+Architecture: amd64
+
+Package: tzdata
+Status: install ok unpacked
+Version: 2021a-1+deb11u8
+Architecture: all
+
+`
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{Name: "var/lib/dpkg/info/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "var/lib/dpkg/status", Typeflag: tar.TypeReg, Size: int64(len(status)), Mode: 0644}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(tw, status); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := claircore.Layer{}
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		Name   string
+		Config ScannerConfig
+		Want   []string
+	}{
+		{Name: "Default", Want: []string{"bash", "tzdata"}},
+		{Name: "ConfigFiles", Config: ScannerConfig{IncludeConfigFiles: true}, Want: []string{"bash", "tzdata", "vim-tiny"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			var s Scanner
+			cfg := tc.Config
+			if err := s.Configure(ctx, func(v interface{}) error {
+				*(v.(*ScannerConfig)) = cfg
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			ps, err := s.Scan(ctx, &l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range ps {
+				got = append(got, p.Name)
+			}
+			sort.Strings(got)
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}
+
+func TestFileList(t *testing.T) {
+	t.Parallel()
+	ctx := zlog.Test(context.Background(), t)
+	const status = `Package: hostname
+Status: install ok installed
+Version: 3.23
+Architecture: amd64
+
+`
+	const list = `/.
+/bin
+/bin/hostname
+/usr
+/usr/share
+/usr/share/man
+/usr/share/man/man1
+/usr/share/man/man1/hostname.1.gz
+/usr/share/man/man1/dnsdomainname.1.gz
+/bin/dnsdomainname
+`
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{Name: "var/lib/dpkg/info/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []struct {
+		Name, Content string
+	}{
+		{"var/lib/dpkg/status", status},
+		{"var/lib/dpkg/info/hostname.list", list},
+		{"var/lib/dpkg/info/removed.list", "/.\n/usr/bin/removed\n"},
+	} {
+		h := tar.Header{Name: e.Name, Typeflag: tar.TypeReg, Size: int64(len(e.Content)), Mode: 0644}
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, e.Content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := claircore.Layer{}
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	var s Scanner
+	ps, err := s.Scan(ctx, &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(ps), 1; got != want {
+		t.Fatalf("got: %d packages, want: %d", got, want)
+	}
+	want := []string{
+		"bin/dnsdomainname",
+		"bin/hostname",
+		"usr/share/man/man1/dnsdomainname.1.gz",
+		"usr/share/man/man1/hostname.1.gz",
+	}
+	if got := ps[0].Files; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestDatabasePaths(t *testing.T) {
+	t.Parallel()
+	ctx := zlog.Test(context.Background(), t)
+	status := func(pkg string) string {
+		return "Package: " + pkg + "\nStatus: install ok installed\nVersion: 1.0-1\nArchitecture: amd64\n\n"
+	}
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	for _, e := range []struct {
+		Name, Content string
+	}{
+		{"var/lib/dpkg/info/", ""},
+		{"var/lib/dpkg/status", status("root")},
+		{"srv/chroot/buster/var/lib/dpkg/info/", ""},
+		{"srv/chroot/buster/var/lib/dpkg/status", status("chroot")},
+		// No "info" directory, so only found if configured.
+		{"opt/extra/dpkg/status", status("extra")},
+	} {
+		h := tar.Header{Name: e.Name, Typeflag: tar.TypeReg, Size: int64(len(e.Content)), Mode: 0644}
+		if e.Content == "" {
+			h.Typeflag, h.Mode = tar.TypeDir, 0755
+		}
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, e.Content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := claircore.Layer{}
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		Name  string
+		Paths []string
+		Want  []string
+	}{
+		{Name: "Default", Want: []string{"chroot", "root"}},
+		{Name: "Restrict", Paths: []string{"var/lib/dpkg"}, Want: []string{"root"}},
+		{Name: "Chroots", Paths: []string{"/srv/chroot/*/var/lib/dpkg"}, Want: []string{"chroot"}},
+		{Name: "Extend", Paths: []string{"/var/lib/dpkg/", "opt/extra/dpkg"}, Want: []string{"extra", "root"}},
+		{Name: "NoMatch", Paths: []string{"srv/chroot/var/lib/dpkg"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			var s Scanner
+			paths := tc.Paths
+			if err := s.Configure(ctx, func(v interface{}) error {
+				v.(*ScannerConfig).DatabasePaths = paths
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			ps, err := s.Scan(ctx, &l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range ps {
+				got = append(got, p.Name)
+			}
+			sort.Strings(got)
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+
+	t.Run("BadPattern", func(t *testing.T) {
+		ctx := zlog.Test(ctx, t)
+		var s Scanner
+		err := s.Configure(ctx, func(v interface{}) error {
+			v.(*ScannerConfig).DatabasePaths = []string{"var/lib/[dpkg"}
+			return nil
+		})
+		if err == nil {
+			t.Error("expected error for malformed pattern")
+		}
+	})
+}
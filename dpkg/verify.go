@@ -0,0 +1,143 @@
+package dpkg
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// ParseSums reads a package's ".md5sums" file, returning the md5 of each file
+// the package installed, keyed by its path relative to the root.
+//
+// Each line is a hex-encoded md5, two spaces, and a path. Malformed lines are
+// skipped.
+func parseSums(r io.Reader) (map[string]string, error) {
+	m := make(map[string]string)
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := s.Text()
+		i := strings.IndexByte(l, ' ')
+		if i != 32 {
+			continue
+		}
+		p := strings.TrimPrefix(path.Clean("/"+strings.TrimLeft(l[i:], " *")), "/")
+		if p == "" {
+			continue
+		}
+		m[p] = strings.ToLower(l[:i])
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Verifier checks installed files against the md5 their package recorded.
+//
+// Packages and their manifests are added as they're found, then the layer is
+// read again and every listed file present in it is hashed.
+type verifier struct {
+	want     map[string][]expect
+	modified map[*claircore.Package][]string
+}
+
+// Expect is a package's recorded md5 for a file.
+type expect struct {
+	pkg *claircore.Package
+	sum string
+}
+
+func newVerifier() *verifier {
+	return &verifier{
+		want:     make(map[string][]expect),
+		modified: make(map[*claircore.Package][]string),
+	}
+}
+
+// Add records the manifest for a package from the database in the directory
+// "db". The paths in a manifest are relative to the root the database
+// manages, which is the directory containing "var/lib/dpkg".
+//
+// It's safe to call Add on a nil verifier.
+func (v *verifier) add(db string, p *claircore.Package, m map[string]string) {
+	if v == nil || len(m) == 0 {
+		return
+	}
+	db = filepath.ToSlash(db)
+	root := ""
+	if r := strings.TrimSuffix(db, "var/lib/dpkg"); r != db {
+		root = r
+	}
+	for f, sum := range m {
+		n := path.Join(root, f)
+		v.want[n] = append(v.want[n], expect{pkg: p, sum: sum})
+	}
+}
+
+// Run reads the layer, hashing the files with recorded md5s, and records
+// every mismatch in the package's Metadata.
+//
+// Files absent from the layer aren't reported: they may be in another layer,
+// or have been deleted.
+func (v *verifier) run(ctx context.Context, layer *claircore.Layer) error {
+	if len(v.want) == 0 {
+		return nil
+	}
+	rd, err := layer.Reader()
+	if err != nil {
+		return fmt.Errorf("opening layer failed: %w", err)
+	}
+	defer rd.Close()
+	tr := tar.NewReader(rd)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(h.Name)), "/")
+		es, ok := v.want[name]
+		if !ok {
+			continue
+		}
+		sum, err := md5sum(tr)
+		if err != nil {
+			return fmt.Errorf("reading %q failed: %w", name, err)
+		}
+		for _, e := range es {
+			if e.sum != sum {
+				v.modified[e.pkg] = append(v.modified[e.pkg], name)
+			}
+		}
+	}
+	if !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading next header failed: %w", err)
+	}
+	for p, fs := range v.modified {
+		sort.Strings(fs)
+		zlog.Debug(ctx).
+			Str("package", p.Name).
+			Int("count", len(fs)).
+			Msg("found modified files")
+		setMetadata(p, claircore.MetadataModified, strings.Join(fs, "\n"))
+	}
+	return nil
+}
+
+// SetMetadata sets the key in the package's Metadata, creating it if needed.
+func setMetadata(p *claircore.Package, k, v string) {
+	if p.Metadata == nil {
+		p.Metadata = make(map[string]string)
+	}
+	p.Metadata[k] = v
+}
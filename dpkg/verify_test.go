@@ -0,0 +1,128 @@
+package dpkg
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+func TestParseSums(t *testing.T) {
+	const in = `d41d8cd98f00b204e9800998ecf8427e  usr/bin/hello
+0123456789ABCDEF0123456789abcdef  usr/share/doc/hello/file with spaces
+not a sums line
+0123456789abcdef0123456789abcdef */usr/lib/binary-mode
+`
+	got, err := parseSums(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"usr/bin/hello":                        "d41d8cd98f00b204e9800998ecf8427e",
+		"usr/share/doc/hello/file with spaces": "0123456789abcdef0123456789abcdef",
+		"usr/lib/binary-mode":                  "0123456789abcdef0123456789abcdef",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestVerifyFiles(t *testing.T) {
+	t.Parallel()
+	ctx := zlog.Test(context.Background(), t)
+	sum := func(s string) string {
+		b := md5.Sum([]byte(s))
+		return hex.EncodeToString(b[:])
+	}
+	const status = `Package: hello
+Status: install ok installed
+Version: 2.10-2
+Architecture: amd64
+
+Package: tzdata
+Status: install ok installed
+Version: 2021a-1
+Architecture: all
+
+`
+	sums := sum("hello\n") + "  usr/bin/hello\n" +
+		sum("readme\n") + "  usr/share/doc/hello/README\n" +
+		sum("elsewhere\n") + "  usr/share/hello/in-another-layer\n"
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	for _, root := range []string{"", "srv/chroot/sid/"} {
+		for _, e := range []struct {
+			Name, Content string
+		}{
+			{"var/lib/dpkg/info/", ""},
+			{"var/lib/dpkg/status", status},
+			{"var/lib/dpkg/info/hello.md5sums", sums},
+			{"var/lib/dpkg/info/tzdata.md5sums", sum("UTC\n") + "  usr/share/zoneinfo/UTC\n"},
+			{"usr/bin/hello", "patched\n"},
+			{"usr/share/doc/hello/README", "readme\n"},
+			{"usr/share/zoneinfo/UTC", "UTC\n"},
+		} {
+			h := tar.Header{Name: root + e.Name, Typeflag: tar.TypeReg, Size: int64(len(e.Content)), Mode: 0644}
+			if e.Content == "" {
+				h.Typeflag, h.Mode = tar.TypeDir, 0755
+			}
+			if err := tw.WriteHeader(&h); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.WriteString(tw, e.Content); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := claircore.Layer{}
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, verify := range []bool{false, true} {
+		var s Scanner
+		if err := s.Configure(ctx, func(v interface{}) error {
+			v.(*ScannerConfig).VerifyFiles = verify
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		ps, err := s.Scan(ctx, &l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(ps), 4; got != want {
+			t.Fatalf("got: %d packages, want: %d", got, want)
+		}
+		for _, p := range ps {
+			got := p.Metadata[claircore.MetadataModified]
+			want := ""
+			if verify && p.Name == "hello" {
+				want = filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(p.PackageDB)))), "usr/bin/hello")
+			}
+			if got != want {
+				t.Errorf("verify %v: %s in %s: got: %q, want: %q", verify, p.Name, p.PackageDB, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package electron
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// Asar is an opened ASAR archive, the format Electron uses to package an
+// application's sources.
+//
+// The format is a pickled header size, a pickled JSON header describing the
+// file tree, then the contents of every file, concatenated. Offsets in the
+// header are relative to the end of the header.
+type asar struct {
+	r     io.ReaderAt
+	base  int64
+	files map[string]asarEntry
+}
+
+type asarEntry struct {
+	offset, size int64
+	unpacked     bool
+}
+
+type asarNode struct {
+	Files    map[string]*asarNode `json:"files"`
+	Offset   string               `json:"offset"`
+	Size     int64                `json:"size"`
+	Unpacked bool                 `json:"unpacked"`
+	Link     string               `json:"link"`
+}
+
+// MaxAsarHeader bounds the size of the JSON header that will be read.
+const maxAsarHeader = 64 << 20
+
+var errNotAsar = errors.New("not an asar archive")
+
+func openAsar(r io.ReaderAt) (*asar, error) {
+	var b [16]byte
+	if _, err := r.ReadAt(b[:], 0); err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotAsar, err)
+	}
+	le := binary.LittleEndian
+	// The first pickle contains only the size of the second.
+	if le.Uint32(b[0:]) != 4 {
+		return nil, errNotAsar
+	}
+	hdrSize := int64(le.Uint32(b[4:]))
+	// The second pickle is the payload size, then the length-prefixed string.
+	strLen := int64(le.Uint32(b[12:]))
+	if strLen > maxAsarHeader || strLen+8 > hdrSize {
+		return nil, errNotAsar
+	}
+	hdr := make([]byte, strLen)
+	if _, err := r.ReadAt(hdr, 16); err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotAsar, err)
+	}
+	var root asarNode
+	if err := json.Unmarshal(hdr, &root); err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotAsar, err)
+	}
+	a := asar{
+		r:     r,
+		base:  8 + hdrSize,
+		files: make(map[string]asarEntry),
+	}
+	if err := a.walk("", &root); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (a *asar) walk(dir string, n *asarNode) error {
+	for name, c := range n.Files {
+		p := path.Join(dir, name)
+		switch {
+		case c.Files != nil:
+			if err := a.walk(p, c); err != nil {
+				return err
+			}
+		case c.Link != "":
+		default:
+			e := asarEntry{size: c.Size, unpacked: c.Unpacked}
+			if !c.Unpacked {
+				off, err := strconv.ParseInt(c.Offset, 10, 64)
+				if err != nil {
+					return fmt.Errorf("%w: bad offset for %q", errNotAsar, p)
+				}
+				e.offset = off
+			}
+			a.files[p] = e
+		}
+	}
+	return nil
+}
+
+// Names returns the names of all files in the archive, sorted.
+func (a *asar) Names() []string {
+	ns := make([]string, 0, len(a.files))
+	for n := range a.files {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return ns
+}
+
+// ReadFile returns the contents of the named file. Files stored outside the
+// archive, in the "app.asar.unpacked" directory, report an error.
+func (a *asar) ReadFile(name string) ([]byte, error) {
+	e, ok := a.files[name]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("asar: %q: file does not exist", name)
+	case e.unpacked:
+		return nil, fmt.Errorf("asar: %q: file is unpacked", name)
+	case e.size < 0 || e.size > maxManifest:
+		return nil, fmt.Errorf("asar: %q: file too large", name)
+	}
+	b := make([]byte, e.size)
+	if _, err := a.r.ReadAt(b, a.base+e.offset); err != nil {
+		return nil, fmt.Errorf("asar: %q: %w", name, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,209 @@
+package electron
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BinInfo is what can be learned about an executable by reading it once.
+type binInfo struct {
+	// Versions of the runtimes embedded in the binary, if found.
+	electron, chrome, node string
+	// Pkg holds the payload position, payload size, prelude position, and
+	// prelude size of a binary built with pkg.
+	pkg [4]int64
+	// Nexe holds the code size and resource bundle size of a binary built
+	// with nexe.
+	nexe [2]int64
+	size int64
+}
+
+// IsPkg reports whether the binary was built with pkg.
+func (i *binInfo) isPkg() bool { return i.pkg[1] != 0 && i.pkg[3] != 0 }
+
+// IsNexe reports whether the binary was built with nexe.
+func (i *binInfo) isNexe() bool { return i.nexe[0] != 0 }
+
+var (
+	electronVersion = regexp.MustCompile(`Electron/(\d+\.\d+\.\d+(?:-[0-9A-Za-z.]+)?)`)
+	chromeVersion   = regexp.MustCompile(`Chrome/(\d+\.\d+\.\d+\.\d+)`)
+	// Node binaries embed the URL of their headers, for building native
+	// addons.
+	nodeVersion = regexp.MustCompile(`nodejs\.org/download/release/v(\d+\.\d+\.\d+)/`)
+	// Pkg writes the location of the payload and prelude into placeholders
+	// in its patched Node bootstrap, padded with spaces.
+	pkgPlaceholder = regexp.MustCompile(`(PAYLOAD_POSITION|PAYLOAD_SIZE|PRELUDE_POSITION|PRELUDE_SIZE) = '(\d+) *' \| 0`)
+	pkgIndex       = map[string]int{
+		"PAYLOAD_POSITION": 0,
+		"PAYLOAD_SIZE":     1,
+		"PRELUDE_POSITION": 2,
+		"PRELUDE_SIZE":     3,
+	}
+)
+
+// NexeSentinel precedes the trailer nexe appends to binaries: two
+// little-endian float64s holding the code and resource bundle sizes.
+var nexeSentinel = []byte("<nexe~~sentinel>")
+
+const (
+	nexeTrailer = 16 + 16
+	chunkSize   = 256 << 10
+	// Overlap is the amount of each chunk carried into the next, so that
+	// matches spanning a boundary are found. It must be longer than any
+	// match.
+	overlap = 512
+)
+
+// ReadBinary streams an executable, looking for the markers described by
+// binInfo.
+func readBinary(r io.Reader) (*binInfo, error) {
+	var info binInfo
+	buf := make([]byte, overlap+chunkSize)
+	carry := 0
+	for {
+		n, err := io.ReadFull(r, buf[carry:])
+		info.size += int64(n)
+		b := buf[:carry+n]
+		info.search(b)
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			info.trailer(b)
+			return &info, nil
+		default:
+			return nil, err
+		}
+		carry = copy(buf, b[len(b)-overlap:])
+	}
+}
+
+func (i *binInfo) search(b []byte) {
+	if i.electron == "" {
+		if m := electronVersion.FindSubmatch(b); m != nil {
+			i.electron = string(m[1])
+		}
+	}
+	if i.chrome == "" {
+		if m := chromeVersion.FindSubmatch(b); m != nil {
+			i.chrome = string(m[1])
+		}
+	}
+	if i.node == "" {
+		if m := nodeVersion.FindSubmatch(b); m != nil {
+			i.node = string(m[1])
+		}
+	}
+	for _, m := range pkgPlaceholder.FindAllSubmatch(b, -1) {
+		v, err := strconv.ParseInt(string(m[2]), 10, 64)
+		if err != nil {
+			continue
+		}
+		i.pkg[pkgIndex[string(m[1])]] = v
+	}
+}
+
+// Trailer examines the end of the binary for a nexe trailer.
+func (i *binInfo) trailer(b []byte) {
+	if len(b) < nexeTrailer {
+		return
+	}
+	t := b[len(b)-nexeTrailer:]
+	if !bytes.Equal(t[:16], nexeSentinel) {
+		return
+	}
+	code := math.Float64frombits(binary.LittleEndian.Uint64(t[16:]))
+	bundle := math.Float64frombits(binary.LittleEndian.Uint64(t[24:]))
+	if code <= 0 || bundle < 0 || code+bundle > float64(i.size-nexeTrailer) {
+		return
+	}
+	i.nexe = [2]int64{int64(code), int64(bundle)}
+}
+
+// Span is a region of a file.
+type span struct {
+	off, size int64
+}
+
+// PkgFiles returns the location of the package.json files in a pkg binary's
+// virtual filesystem, keyed by path.
+//
+// The prelude contains the virtual filesystem as a JSON object mapping paths
+// under "/snapshot" to the stores for that path. Store "1" is the file's
+// content, relative to the payload.
+func pkgFiles(r io.ReaderAt, info *binInfo) (map[string]span, error) {
+	const maxPrelude = 64 << 20
+	if info.pkg[3] > maxPrelude {
+		return nil, errors.New("pkg: prelude too large")
+	}
+	prelude := make([]byte, info.pkg[3])
+	if _, err := r.ReadAt(prelude, info.pkg[2]); err != nil {
+		return nil, err
+	}
+	i := bytes.Index(prelude, []byte(`{"/snapshot`))
+	if i == -1 {
+		i = bytes.Index(prelude, []byte(`{"C:\\snapshot`))
+	}
+	if i == -1 {
+		return nil, errors.New("pkg: virtual filesystem not found")
+	}
+	var vfs map[string]map[string][2]int64
+	if err := json.NewDecoder(bytes.NewReader(prelude[i:])).Decode(&vfs); err != nil {
+		return nil, err
+	}
+	out := make(map[string]span)
+	for p, stores := range vfs {
+		p = strings.ReplaceAll(p, `\`, `/`)
+		if !isManifest(p) {
+			continue
+		}
+		c, ok := stores["1"]
+		if !ok || c[0] < 0 || c[1] < 0 || c[0]+c[1] > info.pkg[1] {
+			continue
+		}
+		out[p] = span{off: info.pkg[0] + c[0], size: c[1]}
+	}
+	return out, nil
+}
+
+// NexeFiles returns the location of the package.json files in a nexe binary's
+// resource bundle, keyed by path.
+//
+// The code begins by assigning the resource table, mapping paths to offsets
+// and sizes within the bundle, to "process.__nexe".
+func nexeFiles(r io.ReaderAt, info *binInfo) (map[string]span, error) {
+	const maxCode = 64 << 20
+	code, bundle := info.nexe[0], info.nexe[1]
+	bundleStart := info.size - nexeTrailer - bundle
+	codeStart := bundleStart - code
+	if code > maxCode {
+		code = maxCode
+	}
+	b := make([]byte, code)
+	if _, err := r.ReadAt(b, codeStart); err != nil {
+		return nil, err
+	}
+	i := bytes.Index(b, []byte(`"resources":`))
+	if i == -1 {
+		return nil, errors.New("nexe: resource table not found")
+	}
+	var res map[string][2]int64
+	if err := json.NewDecoder(bytes.NewReader(b[i+len(`"resources":`):])).Decode(&res); err != nil {
+		return nil, err
+	}
+	out := make(map[string]span)
+	for p, s := range res {
+		p = strings.TrimPrefix(strings.ReplaceAll(p, `\`, `/`), "./")
+		if !isManifest(p) || s[0] < 0 || s[1] < 0 || s[0]+s[1] > bundle {
+			continue
+		}
+		out[p] = span{off: bundleStart + s[0], size: s[1]}
+	}
+	return out, nil
+}
@@ -0,0 +1,79 @@
+package electron
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var _ indexer.Coalescer = (*coalescer)(nil)
+
+// NewCoalescer is a constructor for a Coalescer.
+func NewCoalescer(_ context.Context) (indexer.Coalescer, error) {
+	return &coalescer{}, nil
+}
+
+type coalescer struct{}
+
+// Coalesce implements indexer.Coalescer.
+//
+// Bundled applications don't have a package database, so every package found
+// is reported in the layer it was found in, unless a higher layer removed the
+// file or directory it was found in.
+func (c *coalescer) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{},
+		Packages:     map[string]*claircore.Package{},
+	}
+	// Removed holds, for each layer, the paths removed by the layers above it.
+	removed := make([][]string, len(ls))
+	for i := len(ls) - 1; i > 0; i-- {
+		removed[i-1] = removed[i]
+		for _, rm := range ls[i].Removed {
+			if rm := cleanPath(rm); rm != "" {
+				removed[i-1] = append(removed[i-1], rm)
+			}
+		}
+	}
+	for i, l := range ls {
+		for _, pkg := range l.Pkgs {
+			if removes(removed[i], pkg.PackageDB) {
+				continue
+			}
+			ir.Packages[pkg.ID] = pkg
+			ir.Environments[pkg.ID] = append(ir.Environments[pkg.ID], &claircore.Environment{
+				PackageDB:    pkg.PackageDB,
+				IntroducedIn: l.Hash,
+			})
+		}
+	}
+	return ir, nil
+}
+
+// Removes reports whether any of the removed paths is the file named by the
+// PackageDB "db" or one of its parent directories.
+//
+// The PackageDB is the file or directory a package was found in, prefixed
+// with the kind of application, e.g. "electron:opt/App/resources/app.asar".
+func removes(removed []string, db string) bool {
+	i := strings.IndexByte(db, ':')
+	if i == -1 {
+		return false
+	}
+	p := cleanPath(db[i+1:])
+	for _, rm := range removed {
+		if p == rm || strings.HasPrefix(p, rm+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CleanPath returns the path relative to the layer root, without a leading
+// "/" or "./".
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
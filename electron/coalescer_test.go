@@ -0,0 +1,73 @@
+package electron
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/test"
+)
+
+func TestCoalesceWhiteout(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	pkg := func(id, db string) *claircore.Package {
+		return &claircore.Package{ID: id, Name: "pkg-" + id, Version: "1", PackageDB: db}
+	}
+	layers := []*indexer.LayerArtifacts{
+		{
+			Hash: test.RandomSHA256Digest(t),
+			Pkgs: []*claircore.Package{
+				pkg("0", "electron:opt/Example/resources/app.asar"),
+				pkg("1", "electron:opt/Example"),
+				pkg("2", "electron:opt/Other/resources/app.asar"),
+				pkg("3", "pkg:usr/bin/pkgapp"),
+				pkg("4", "nexe:usr/bin/nexeapp"),
+			},
+		},
+		{
+			// Removes the first application's directory and the pkg binary.
+			// The whiteouts in the same layer as a package don't apply to it.
+			Hash: test.RandomSHA256Digest(t),
+			Pkgs: []*claircore.Package{
+				pkg("5", "electron:opt/Example/resources/app.asar"),
+			},
+			Removed: []string{"opt/Example", "usr/bin/pkgapp", "opt/Other/resources/app.asar.unpacked"},
+		},
+		{
+			// Replaces the nexe binary.
+			Hash:    test.RandomSHA256Digest(t),
+			Removed: []string{"usr/bin/nexeapp"},
+			Pkgs: []*claircore.Package{
+				pkg("6", "nexe:usr/bin/nexeapp"),
+			},
+		},
+	}
+	c, err := NewCoalescer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir, err := c.Coalesce(ctx, layers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]claircore.Digest{
+		"2": layers[0].Hash,
+		"5": layers[1].Hash,
+		"6": layers[2].Hash,
+	}
+	if got, want := len(ir.Packages), len(want); got != want {
+		t.Errorf("got: %d packages, want: %d", got, want)
+	}
+	for id, d := range want {
+		if _, ok := ir.Packages[id]; !ok {
+			t.Errorf("missing package %q", id)
+			continue
+		}
+		if got := ir.Environments[id][0].IntroducedIn; got.String() != d.String() {
+			t.Errorf("%s: got: %v, want: %v", id, got, d)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package electron
+
+import (
+	"context"
+
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// NewEcosystem provides the set of scanners and coalescers for bundled
+// Node.js applications.
+func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
+	return &indexer.Ecosystem{
+		Name: "electron",
+		PackageScanners: func(_ context.Context) ([]indexer.PackageScanner, error) {
+			return []indexer.PackageScanner{&Scanner{}}, nil
+		},
+		DistributionScanners: func(_ context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+		RepositoryScanners:   func(_ context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		Coalescer:            NewCoalescer,
+	}
+}
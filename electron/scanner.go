@@ -0,0 +1,514 @@
+// Package electron contains components for finding Node.js applications
+// packaged as single files: Electron applications and their ASAR archives,
+// and executables built with pkg or nexe.
+//
+// The bundled npm dependencies are reported as packages, along with the
+// versions of the embedded runtimes (Electron, Chromium, and Node.js) when
+// they can be determined. Packages installed into node_modules directories
+// outside of Electron applications are reported by the npm package instead.
+//
+// Finding these means reading every large executable in a layer, so libindex
+// doesn't use this package unless it's added to its Ecosystems.
+package electron
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime/trace"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	_ indexer.VersionedScanner   = (*Scanner)(nil)
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
+)
+
+// RepositoryHint is used for packages that came from the npm registry.
+const RepositoryHint = "https://registry.npmjs.org"
+
+const (
+	// MinBinary is the smallest executable examined. Anything embedding a
+	// JavaScript runtime is much larger than this.
+	minBinary = 8 << 20
+	// MaxFile is the largest archive or executable that will be read. Files
+	// are spooled to disk to be read, so this bounds the space used.
+	maxFile = 1 << 30
+	// MaxManifest is the largest package.json that will be read.
+	maxManifest = 1 << 20
+)
+
+// Scanner implements the indexer.PackageScanner interface.
+//
+// Electron applications are found by their "resources" directory, which
+// holds the application either as an "app.asar" archive or an "app"
+// directory. The Electron version is read from the "version" file the
+// Electron distribution ships alongside its binary, or the binary itself. The
+// Chromium version is read from the binary.
+//
+// Executables built with pkg or nexe are found by the markers those tools
+// leave in them, and their embedded filesystems are read for package.json
+// files.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements indexer.VersionedScanner.
+func (*Scanner) Name() string { return "electron" }
+
+// Version implements indexer.VersionedScanner.
+func (*Scanner) Version() string { return "1" }
+
+// Kind implements indexer.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceDeclared }
+
+// Scan attempts to find bundled Node.js applications and report the packages
+// in them.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "electron/Scanner.Scan"),
+		label.String("version", s.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	rd, ok := r.(io.ReadSeeker)
+	if !ok {
+		return nil, errors.New("electron: cannot seek on returned layer Reader")
+	}
+
+	// The layer is read up to three times: once to find candidate files by
+	// name, once to read them, and once more for any executables that turn
+	// out to need random access.
+	c, err := findCandidates(rd)
+	if err != nil {
+		return nil, err
+	}
+	if c.empty() {
+		return nil, nil
+	}
+	if _, err := rd.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var ret []*claircore.Package
+	versions := make(map[string]string)
+	bins := make(map[string]*binInfo)
+	bundles := make(map[string]*binInfo)
+	err = eachFile(rd, func(n string, r io.Reader) error {
+		switch {
+		case c.asars[n]:
+			ps, err := scanAsar(ctx, n, r)
+			if err != nil {
+				return err
+			}
+			ret = append(ret, ps...)
+		case c.loose[n] != "":
+			b, err := io.ReadAll(io.LimitReader(r, maxManifest))
+			if err != nil {
+				return err
+			}
+			if p := parseManifest(b); p != nil {
+				p.PackageDB = "electron:" + c.loose[n]
+				ret = append(ret, p)
+			}
+		case c.versions[n]:
+			b, err := io.ReadAll(io.LimitReader(r, 64))
+			if err != nil {
+				return err
+			}
+			versions[path.Dir(n)] = strings.TrimPrefix(strings.TrimSpace(string(b)), "v")
+		case c.exes[n]:
+			info, err := readBinary(r)
+			if err != nil {
+				return err
+			}
+			bins[n] = info
+			if info.isPkg() || info.isNexe() {
+				bundles[n] = info
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret = append(ret, runtimes(c, versions, bins)...)
+
+	if len(bundles) != 0 {
+		if _, err := rd.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		err = eachFile(rd, func(n string, r io.Reader) error {
+			info, ok := bundles[n]
+			if !ok {
+				return nil
+			}
+			ps, err := scanBundle(ctx, n, r, info)
+			if err != nil {
+				return err
+			}
+			ret = append(ret, ps...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// Candidates are the files of interest found by name.
+type candidates struct {
+	// Apps is the set of Electron application directories.
+	apps map[string]bool
+	// Asars is the set of application archives.
+	asars map[string]bool
+	// Loose maps package.json files of unpacked applications to the
+	// application's directory.
+	loose map[string]string
+	// Versions is the set of Electron version files.
+	versions map[string]bool
+	// Exes is the set of executables large enough to be interesting.
+	exes map[string]bool
+}
+
+func (c *candidates) empty() bool {
+	return len(c.apps) == 0 && len(c.exes) == 0
+}
+
+func findCandidates(r io.Reader) (*candidates, error) {
+	c := candidates{
+		apps:     make(map[string]bool),
+		asars:    make(map[string]bool),
+		loose:    make(map[string]string),
+		versions: make(map[string]bool),
+		exes:     make(map[string]bool),
+	}
+	var names []string
+	err := eachHeader(r, func(n string, h *tar.Header) {
+		names = append(names, n)
+		switch {
+		case h.Size > maxFile:
+		case path.Base(n) == "app.asar" && path.Base(path.Dir(n)) == "resources":
+			c.apps[path.Dir(path.Dir(n))] = true
+			c.asars[n] = true
+		case h.FileInfo().Mode()&0o111 != 0 && h.Size >= minBinary:
+			c.exes[n] = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Now that the application directories are known, find the files
+	// related to them.
+	for _, n := range names {
+		if !isManifest(n) {
+			continue
+		}
+		for _, dir := range []string{"/resources/app/", "/resources/app.asar.unpacked/"} {
+			i := strings.Index("/"+n, dir)
+			if i == -1 {
+				continue
+			}
+			root := strings.TrimPrefix(("/" + n)[:i+len(dir)-1], "/")
+			rel := strings.TrimPrefix(n, root+"/")
+			if rel == "package.json" || depManifest.MatchString(rel) {
+				c.apps[path.Dir(path.Dir(root))] = true
+				c.loose[n] = root
+			}
+		}
+	}
+	for _, n := range names {
+		if path.Base(n) == "version" && c.apps[path.Dir(n)] {
+			c.versions[n] = true
+		}
+	}
+	return &c, nil
+}
+
+// EachHeader calls "f" with the cleaned name and header of every regular file
+// in the tar stream.
+func eachHeader(r io.Reader, f func(string, *tar.Header)) error {
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+		if err != nil {
+			return err
+		}
+		f(filepath.ToSlash(n), h)
+	}
+	if err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// EachFile calls "f" with the cleaned name and contents of every regular file
+// in the tar stream.
+func eachFile(r io.Reader, f func(string, io.Reader) error) error {
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+		if err != nil {
+			return err
+		}
+		if err := f(filepath.ToSlash(n), tr); err != nil {
+			return err
+		}
+	}
+	if err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Runtimes reports the Electron and Chromium versions of every application.
+func runtimes(c *candidates, versions map[string]string, bins map[string]*binInfo) []*claircore.Package {
+	var ret []*claircore.Package
+	apps := make([]string, 0, len(c.apps))
+	for a := range c.apps {
+		apps = append(apps, a)
+	}
+	sort.Strings(apps)
+	for _, a := range apps {
+		// Look at every executable in the application directory, as the
+		// Electron binary is renamed to the application's name.
+		var exes []string
+		for n := range bins {
+			if path.Dir(n) == a {
+				exes = append(exes, n)
+			}
+		}
+		sort.Strings(exes)
+		ev, cv := versions[path.Join(a, "version")], ""
+		for _, n := range exes {
+			if ev == "" {
+				ev = bins[n].electron
+			}
+			if cv == "" {
+				cv = bins[n].chrome
+			}
+		}
+		if ev != "" {
+			ret = append(ret, &claircore.Package{
+				Name:       "electron",
+				Version:    ev,
+				Kind:       claircore.BINARY,
+				PackageDB:  "electron:" + a,
+				Confidence: claircore.ConfidenceHeuristic,
+			})
+		}
+		if cv != "" {
+			ret = append(ret, &claircore.Package{
+				Name:       "chromium",
+				Version:    cv,
+				Kind:       claircore.BINARY,
+				PackageDB:  "electron:" + a,
+				Confidence: claircore.ConfidenceHeuristic,
+			})
+		}
+	}
+	return ret
+}
+
+// Spool copies "r" into a temporary file, for random access. The caller must
+// call the returned function to remove it.
+func spool(r io.Reader) (*os.File, func(), error) {
+	f, err := os.CreateTemp("", "electron.")
+	if err != nil {
+		return nil, nil, err
+	}
+	done := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		done()
+		return nil, nil, err
+	}
+	return f, done, nil
+}
+
+// ScanAsar reports the application and its dependencies from the ASAR
+// archive read from "r".
+func scanAsar(ctx context.Context, n string, r io.Reader) ([]*claircore.Package, error) {
+	f, done, err := spool(r)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	a, err := openAsar(f)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, errNotAsar):
+		zlog.Info(ctx).
+			Str("file", n).
+			Err(err).
+			Msg("skipping archive")
+		return nil, nil
+	default:
+		return nil, err
+	}
+	var ret []*claircore.Package
+	for _, m := range bundled(a.Names()) {
+		b, err := a.ReadFile(m)
+		if err != nil {
+			zlog.Debug(ctx).
+				Str("file", n).
+				Err(err).
+				Msg("unable to read manifest")
+			continue
+		}
+		if p := parseManifest(b); p != nil {
+			p.PackageDB = "electron:" + n
+			ret = append(ret, p)
+		}
+	}
+	return ret, nil
+}
+
+// ScanBundle reports the Node.js version and bundled dependencies of a pkg or
+// nexe executable.
+func scanBundle(ctx context.Context, n string, r io.Reader, info *binInfo) ([]*claircore.Package, error) {
+	f, done, err := spool(r)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	kind := "pkg"
+	files, err := map[string]span(nil), error(nil)
+	if info.isPkg() {
+		files, err = pkgFiles(f, info)
+	} else {
+		kind = "nexe"
+		files, err = nexeFiles(f, info)
+	}
+	if err != nil {
+		zlog.Info(ctx).
+			Str("file", n).
+			Str("kind", kind).
+			Err(err).
+			Msg("unable to read embedded filesystem")
+	}
+	db := kind + ":" + n
+	var ret []*claircore.Package
+	if info.node != "" {
+		ret = append(ret, &claircore.Package{
+			Name:       "node",
+			Version:    info.node,
+			Kind:       claircore.BINARY,
+			PackageDB:  db,
+			Confidence: claircore.ConfidenceHeuristic,
+		})
+	}
+	names := make([]string, 0, len(files))
+	for p := range files {
+		names = append(names, p)
+	}
+	for _, m := range bundled(names) {
+		s := files[m]
+		if s.size > maxManifest {
+			continue
+		}
+		b := make([]byte, s.size)
+		if _, err := f.ReadAt(b, s.off); err != nil {
+			return nil, fmt.Errorf("electron: %s: %w", n, err)
+		}
+		// Pkg may have compressed the file, in which case this fails to
+		// parse and the file is skipped.
+		if p := parseManifest(b); p != nil {
+			p.PackageDB = db
+			ret = append(ret, p)
+		}
+	}
+	return ret, nil
+}
+
+// DepManifest matches the package.json of a dependency.
+var depManifest = regexp.MustCompile(`(^|/)node_modules/(@[^/]+/)?[^/@][^/]*/package\.json$`)
+
+func isManifest(p string) bool { return path.Base(p) == "package.json" }
+
+// Bundled selects the package.json files of interest from the names in an
+// embedded filesystem: the application's own, which is the shallowest one
+// outside of any node_modules directory, and those of its dependencies.
+func bundled(names []string) []string {
+	var out []string
+	app := ""
+	for _, n := range names {
+		switch {
+		case !isManifest(n):
+		case depManifest.MatchString(n):
+			out = append(out, n)
+		case strings.Contains(n, "node_modules/"):
+		case app == "" || strings.Count(n, "/") < strings.Count(app, "/"):
+			app = n
+		}
+	}
+	if app != "" {
+		out = append(out, app)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ParseManifest returns a Package for the package.json "b", or nil if it's
+// not usable.
+func parseManifest(b []byte) *claircore.Package {
+	var m struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	if m.Name == "" || m.Version == "" {
+		return nil
+	}
+	return &claircore.Package{
+		Name:           m.Name,
+		Version:        m.Version,
+		Kind:           claircore.BINARY,
+		RepositoryHint: RepositoryHint,
+	}
+}
@@ -0,0 +1,246 @@
+package electron
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
+)
+
+func TestScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	app := mkAsar(t, map[string][]byte{
+		"package.json":                         manifest("example-app", "1.0.0"),
+		"node_modules/left-pad/package.json":   manifest("left-pad", "1.3.0"),
+		"node_modules/@scope/pkg/package.json": manifest("@scope/pkg", "2.0.0"),
+		// Nested package.json files that aren't a package root should be
+		// ignored.
+		"node_modules/left-pad/lib/package.json": manifest("internal", "0.0.0"),
+		"index.js":                               []byte("require('left-pad')"),
+	})
+	electronBin := mkBinary([]byte("Mozilla/5.0 Chrome/91.0.4472.164 Electron/12.0.1 Safari/537.36"))
+	l := fixture.Layer{
+		{Name: "opt/Example/resources/app.asar", Body: string(app)},
+		{Name: "opt/Example/version", Body: "v12.0.1"},
+		{Name: "opt/Example/example", Body: string(electronBin), Exec: true},
+		{Name: "opt/Loose/resources/app/package.json", Body: string(manifest("loose-app", "0.1.0"))},
+		{Name: "opt/Loose/resources/app/node_modules/ms/package.json", Body: string(manifest("ms", "2.1.3"))},
+		{Name: "opt/Loose/loose", Body: string(mkBinary([]byte("Chrome/90.0.4430.212 Electron/11.4.7"))), Exec: true},
+		{Name: "usr/bin/pkgapp", Body: string(mkPkg(t)), Exec: true},
+		{Name: "usr/bin/nexeapp", Body: string(mkNexe(t)), Exec: true},
+		// A large executable that's none of the above.
+		{Name: "usr/bin/other", Body: string(mkBinary(nil)), Exec: true},
+	}.Local(t)
+
+	want := []*claircore.Package{
+		{Name: "electron", Version: "12.0.1", Kind: claircore.BINARY, PackageDB: "electron:opt/Example", Confidence: claircore.ConfidenceHeuristic},
+		{Name: "chromium", Version: "91.0.4472.164", Kind: claircore.BINARY, PackageDB: "electron:opt/Example", Confidence: claircore.ConfidenceHeuristic},
+		npm("example-app", "1.0.0", "electron:opt/Example/resources/app.asar"),
+		npm("left-pad", "1.3.0", "electron:opt/Example/resources/app.asar"),
+		npm("@scope/pkg", "2.0.0", "electron:opt/Example/resources/app.asar"),
+		{Name: "electron", Version: "11.4.7", Kind: claircore.BINARY, PackageDB: "electron:opt/Loose", Confidence: claircore.ConfidenceHeuristic},
+		{Name: "chromium", Version: "90.0.4430.212", Kind: claircore.BINARY, PackageDB: "electron:opt/Loose", Confidence: claircore.ConfidenceHeuristic},
+		npm("loose-app", "0.1.0", "electron:opt/Loose/resources/app"),
+		npm("ms", "2.1.3", "electron:opt/Loose/resources/app"),
+		{Name: "node", Version: "14.4.0", Kind: claircore.BINARY, PackageDB: "pkg:usr/bin/pkgapp", Confidence: claircore.ConfidenceHeuristic},
+		npm("pkg-app", "3.0.0", "pkg:usr/bin/pkgapp"),
+		npm("debug", "4.3.1", "pkg:usr/bin/pkgapp"),
+		{Name: "node", Version: "12.16.2", Kind: claircore.BINARY, PackageDB: "nexe:usr/bin/nexeapp", Confidence: claircore.ConfidenceHeuristic},
+		npm("nexe-app", "0.9.0", "nexe:usr/bin/nexeapp"),
+		npm("chalk", "4.1.0", "nexe:usr/bin/nexeapp"),
+	}
+
+	var s Scanner
+	got, err := s.Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortPkgs(want)
+	sortPkgs(got)
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestScanEmpty(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := fixture.Layer{
+		{Name: "etc/hostname", Body: "localhost"},
+		{Name: "app/package.json", Body: string(manifest("not-bundled", "1.0.0"))},
+	}.Local(t)
+	var s Scanner
+	got, err := s.Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("got: %v, want: nil", got)
+	}
+}
+
+func manifest(name, version string) []byte {
+	return []byte(fmt.Sprintf(`{"name":%q,"version":%q,"main":"index.js"}`, name, version))
+}
+
+func npm(name, version, db string) *claircore.Package {
+	return &claircore.Package{
+		Name:           name,
+		Version:        version,
+		Kind:           claircore.BINARY,
+		PackageDB:      db,
+		RepositoryHint: RepositoryHint,
+	}
+}
+
+func sortPkgs(ps []*claircore.Package) {
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].PackageDB != ps[j].PackageDB {
+			return ps[i].PackageDB < ps[j].PackageDB
+		}
+		return ps[i].Name < ps[j].Name
+	})
+}
+
+// MkBinary returns a binary large enough to be examined, containing "b".
+func mkBinary(b []byte) []byte {
+	out := make([]byte, minBinary)
+	copy(out[minBinary/2:], b)
+	return out
+}
+
+// MkAsar builds an ASAR archive containing the provided files.
+func mkAsar(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	type node map[string]interface{}
+	root := node{"files": node{}}
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var content bytes.Buffer
+	for _, n := range names {
+		dir := root["files"].(node)
+		els := strings.Split(n, "/")
+		for _, el := range els[:len(els)-1] {
+			d, ok := dir[el].(node)
+			if !ok {
+				d = node{"files": node{}}
+				dir[el] = d
+			}
+			dir = d["files"].(node)
+		}
+		dir[path.Base(n)] = node{
+			"size":   len(files[n]),
+			"offset": fmt.Sprint(content.Len()),
+		}
+		content.Write(files[n])
+	}
+	hdr, err := json.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pad := (4 - len(hdr)%4) % 4
+	hdrSize := 8 + len(hdr) + pad
+	var out bytes.Buffer
+	le := binary.LittleEndian
+	for _, v := range []uint32{4, uint32(hdrSize), uint32(hdrSize - 4), uint32(len(hdr))} {
+		binary.Write(&out, le, v)
+	}
+	out.Write(hdr)
+	out.Write(make([]byte, pad))
+	out.Write(content.Bytes())
+	return out.Bytes()
+}
+
+// MkPkg builds something that looks enough like a pkg binary: the patched
+// bootstrap with the placeholders filled in, then the payload, then the
+// prelude containing the virtual filesystem.
+func mkPkg(t *testing.T) []byte {
+	t.Helper()
+	files := []struct {
+		Name string
+		Data []byte
+	}{
+		{"/snapshot/app/package.json", manifest("pkg-app", "3.0.0")},
+		{"/snapshot/app/node_modules/debug/package.json", manifest("debug", "4.3.1")},
+		{"/snapshot/app/index.js", []byte("console.log('hi')")},
+	}
+	var payload bytes.Buffer
+	vfs := make(map[string]map[string][2]int)
+	for _, f := range files {
+		vfs[f.Name] = map[string][2]int{
+			"1": {payload.Len(), len(f.Data)},
+			"3": {0, 0},
+		}
+		payload.Write(f.Data)
+	}
+	fs, err := json.Marshal(vfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prelude := []byte("return (function (REQUIRE_COMMON, VIRTUAL_FILESYSTEM) {\n})\n, " +
+		string(fs) + `, "/snapshot/app/index.js"`)
+
+	const start = minBinary
+	bootstrap := func(pos, size, ppos, psize int) []byte {
+		return []byte(fmt.Sprintf(
+			"https://nodejs.org/download/release/v14.4.0/node-v14.4.0-headers.tar.gz\x00"+
+				"var PAYLOAD_POSITION = '%-20d' | 0;\nvar PAYLOAD_SIZE = '%-20d' | 0;\n"+
+				"var PRELUDE_POSITION = '%-20d' | 0;\nvar PRELUDE_SIZE = '%-20d' | 0;\n",
+			pos, size, ppos, psize))
+	}
+	out := make([]byte, start)
+	copy(out[start/2:], bootstrap(start, payload.Len(), start+payload.Len(), len(prelude)))
+	out = append(out, payload.Bytes()...)
+	out = append(out, prelude...)
+	return out
+}
+
+// MkNexe builds something that looks enough like a nexe binary: the node
+// binary, then the code that sets up the resources, then the resource bundle,
+// then the trailer.
+func mkNexe(t *testing.T) []byte {
+	t.Helper()
+	files := []struct {
+		Name string
+		Data []byte
+	}{
+		{"./package.json", manifest("nexe-app", "0.9.0")},
+		{"./node_modules/chalk/package.json", manifest("chalk", "4.1.0")},
+	}
+	var bundle bytes.Buffer
+	res := make(map[string][2]int)
+	for _, f := range files {
+		res[f.Name] = [2]int{bundle.Len(), len(f.Data)}
+		bundle.Write(f.Data)
+	}
+	rt, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := []byte(`!(function () {process.__nexe = {"resources":` + string(rt) + `};})();`)
+
+	out := mkBinary([]byte("https://nodejs.org/download/release/v12.16.2/node-v12.16.2-headers.tar.gz"))
+	out = append(out, code...)
+	out = append(out, bundle.Bytes()...)
+	out = append(out, nexeSentinel...)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(float64(len(code))))
+	out = append(out, b[:]...)
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(float64(bundle.Len())))
+	out = append(out, b[:]...)
+	return out
+}
@@ -11,8 +11,15 @@ type Environment struct {
 	PackageDB string `json:"package_db"`
 	// the layer in which the associated package was introduced
 	IntroducedIn Digest `json:"introduced_in"`
+	// the instruction that created the layer in which the associated package
+	// was introduced, such as a Dockerfile "RUN" line, if the image's history
+	// is known
+	IntroducedBy string `json:"introduced_by,omitempty"`
 	// the ID of the distribution the package was discovered on
 	DistributionID string `json:"distribution_id"`
 	// the ID of the repository where this package was downloaded from (currently not used)
 	RepositoryIDs []string `json:"repository_ids"`
+	// the platform the package was found on, set only in IndexReports merged
+	// from several platforms' reports
+	Platform string `json:"platform,omitempty"`
 }
@@ -0,0 +1,102 @@
+package gem
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Spec is the information about a gem needed to report it.
+type spec struct {
+	Name     string
+	Version  string
+	Platform string
+}
+
+// FullName returns the gem's full name, as used for its directory and
+// specification file names.
+func (s *spec) FullName() string {
+	if s.Platform == "" || s.Platform == "ruby" {
+		return s.Name + "-" + s.Version
+	}
+	return s.Name + "-" + s.Version + "-" + s.Platform
+}
+
+// Installed gemspecs are Ruby, written by Gem::Specification#to_ruby. Rather
+// than evaluate them, the stub line RubyGems writes at the top for its own
+// fast loading is used:
+//
+//	# stub: rack 2.2.3 ruby lib
+//
+// Specifications written before stub lines were introduced are handled by
+// looking for the relevant assignments:
+//
+//	s.name = "rack".freeze
+//	s.version = "2.2.3"
+//	s.platform = "x86_64-linux".freeze
+var (
+	stubLine = regexp.MustCompile(`^# stub: (\S+) (\S+) (\S+)`)
+	assign   = regexp.MustCompile(`^s\.(name|version|platform) = (?:Gem::Version\.new\()?"([^"]+)"`)
+)
+
+// ParseSpec extracts the name, version, and platform from an installed
+// gemspec.
+func parseSpec(b []byte) (*spec, error) {
+	var s spec
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if m := stubLine.FindStringSubmatch(line); m != nil {
+			s = spec{Name: m[1], Version: m[2], Platform: m[3]}
+			break
+		}
+		m := assign.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch m[1] {
+		case "name":
+			s.Name = m[2]
+		case "version":
+			s.Version = m[2]
+		case "platform":
+			s.Platform = m[2]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if s.Name == "" || s.Version == "" {
+		return nil, errors.New("gem: missing name or version")
+	}
+	return &s, nil
+}
+
+// VersionSegment matches a segment of a gem's full name that could start the
+// version.
+var versionSegment = regexp.MustCompile(`^[0-9]+(\.[0-9a-zA-Z]+)*$`)
+
+// ParseDirName splits the name of a directory in a gem home's "gems"
+// directory into the gem's name, version, and platform.
+//
+// Gem names may contain dashes, so the version is taken to be the first
+// dash-separated segment after the first that looks like a version.
+func parseDirName(n string) (*spec, bool) {
+	seg := strings.Split(n, "-")
+	for i := 1; i < len(seg); i++ {
+		if !versionSegment.MatchString(seg[i]) {
+			continue
+		}
+		s := spec{
+			Name:    strings.Join(seg[:i], "-"),
+			Version: seg[i],
+		}
+		if i+1 < len(seg) {
+			s.Platform = strings.Join(seg[i+1:], "-")
+		}
+		return &s, true
+	}
+	return nil, false
+}
@@ -0,0 +1,213 @@
+// Package gem contains components for finding Ruby gems installed into gem
+// homes, such as those managed by RubyGems, Bundler, or a distribution's
+// packaging.
+package gem
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"regexp"
+	"runtime/trace"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	_ indexer.VersionedScanner   = (*Scanner)(nil)
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
+)
+
+// RepositoryHint is used for every package found. Installed gems don't record
+// the source they were fetched from.
+const RepositoryHint = "https://rubygems.org"
+
+// MaxSpec is the largest gemspec that will be read.
+const maxSpec = 1 << 20
+
+// Scanner implements the indexer.PackageScanner interface.
+//
+// It looks in gem homes for the specifications RubyGems writes on install,
+// including the specifications of the default gems shipped with Ruby. Gems
+// installed into a gem home's "gems" directory without a specification are
+// reported using the name, version, and platform in the directory's name.
+//
+// Gems built for a specific platform have it reported as the package's Arch.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements indexer.VersionedScanner.
+func (*Scanner) Name() string { return "gem" }
+
+// Version implements indexer.VersionedScanner.
+func (*Scanner) Version() string { return "1" }
+
+// Kind implements indexer.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceDeclared }
+
+// Scan attempts to find installed Ruby gems.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "gem/Scanner.Scan"),
+		label.String("version", s.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// Specs and dirs are keyed by gem home, then by the gem's full name.
+	specs := make(map[string]map[string]*spec)
+	dirs := make(map[string]map[string]*spec)
+	add := func(m map[string]map[string]*spec, home string, s *spec) {
+		if m[home] == nil {
+			m[home] = make(map[string]*spec)
+		}
+		m[home][s.FullName()] = s
+	}
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+		if err != nil {
+			return nil, err
+		}
+		n = filepath.ToSlash(n)
+		home, rest, ok := splitHome(n)
+		if !ok {
+			continue
+		}
+		switch {
+		case specPath.MatchString(rest):
+			if h.Size > maxSpec {
+				zlog.Info(ctx).
+					Str("file", n).
+					Int64("size", h.Size).
+					Msg("skipping oversized gemspec")
+				continue
+			}
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			s, err := parseSpec(b)
+			if err != nil {
+				zlog.Debug(ctx).
+					Str("file", n).
+					Err(err).
+					Msg("unable to parse gemspec, skipping")
+				continue
+			}
+			add(specs, home, s)
+		case strings.HasPrefix(rest, "gems/"):
+			d := strings.TrimPrefix(rest, "gems/")
+			i := strings.IndexByte(d, '/')
+			if i == -1 {
+				continue
+			}
+			if s, ok := parseDirName(d[:i]); ok {
+				add(dirs, home, s)
+			}
+		}
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var ret []*claircore.Package
+	emit := func(home string, s *spec) {
+		p := &claircore.Package{
+			Name:           s.Name,
+			Version:        s.Version,
+			Kind:           claircore.BINARY,
+			PackageDB:      "gem:" + home,
+			RepositoryHint: RepositoryHint,
+		}
+		if s.Platform != "" && s.Platform != "ruby" {
+			p.Arch = s.Platform
+		}
+		ret = append(ret, p)
+	}
+	for home, m := range specs {
+		for _, s := range m {
+			emit(home, s)
+		}
+	}
+	for home, m := range dirs {
+		for n, s := range m {
+			if _, ok := specs[home][n]; ok {
+				continue
+			}
+			zlog.Debug(ctx).
+				Str("home", home).
+				Str("gem", n).
+				Msg("gem directory without specification")
+			emit(home, s)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].PackageDB != ret[j].PackageDB {
+			return ret[i].PackageDB < ret[j].PackageDB
+		}
+		if ret[i].Name != ret[j].Name {
+			return ret[i].Name < ret[j].Name
+		}
+		if ret[i].Version != ret[j].Version {
+			return ret[i].Version < ret[j].Version
+		}
+		return ret[i].Arch < ret[j].Arch
+	})
+	return ret, nil
+}
+
+// HomePath matches the common locations of gem homes:
+//
+//	usr/lib/ruby/gems/3.0.0          RubyGems, distribution packages
+//	usr/local/lib/ruby/gems/3.0.0    Ruby built from source, the official images
+//	var/lib/gems/2.7.0               Debian and Ubuntu "gem install"
+//	usr/share/gems                   Fedora and RHEL packages
+//	usr/local/bundle                 The official images' BUNDLE_PATH
+//	vendor/bundle/ruby/3.0.0         "bundle install --deployment"
+//	root/.gem/ruby/3.0.0             "gem install --user-install"
+var homePath = regexp.MustCompile(`^((?:.*/)?(?:lib(?:64)?/ruby/gems/[^/]+|lib/gems/[^/]+|share/gems|local/bundle|bundle/ruby/[^/]+|\.gem/ruby/[^/]+))/(.+)$`)
+
+// SpecPath matches a specification relative to a gem home.
+var specPath = regexp.MustCompile(`^specifications/(?:default/)?[^/]+\.gemspec$`)
+
+// SplitHome splits a path into a gem home and the path within it, reporting
+// false if the path isn't within a gem home.
+func splitHome(p string) (home, rest string, ok bool) {
+	m := homePath.FindStringSubmatch(p)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
@@ -0,0 +1,111 @@
+package gem
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
+)
+
+const rackSpec = `# -*- encoding: utf-8 -*-
+# stub: rack 2.2.3 ruby lib
+
+Gem::Specification.new do |s|
+  s.name = "rack".freeze
+  s.version = "2.2.3"
+end
+`
+
+const nokogiriSpec = `# -*- encoding: utf-8 -*-
+# stub: nokogiri 1.13.3 x86_64-linux lib
+
+Gem::Specification.new do |s|
+  s.name = "nokogiri".freeze
+  s.version = "1.13.3"
+  s.platform = "x86_64-linux".freeze
+end
+`
+
+// An old-style specification, without a stub line.
+const railsSpec = `# -*- encoding: utf-8 -*-
+
+Gem::Specification.new do |s|
+  s.name = %q{rails}
+  s.name = "rails"
+  s.version = "4.2.11"
+end
+`
+
+func pkg(name, version, home, arch string) *claircore.Package {
+	return &claircore.Package{
+		Name:           name,
+		Version:        version,
+		Kind:           claircore.BINARY,
+		PackageDB:      "gem:" + home,
+		RepositoryHint: RepositoryHint,
+		Arch:           arch,
+	}
+}
+
+func TestScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const (
+		bundle = "usr/local/bundle"
+		system = "usr/local/lib/ruby/gems/3.0.0"
+		vendor = "srv/app/vendor/bundle/ruby/2.7.0"
+	)
+	l := fixture.Layer{
+		{Name: bundle + "/specifications/rack-2.2.3.gemspec", Body: rackSpec},
+		{Name: bundle + "/gems/rack-2.2.3/lib/rack.rb", Body: ""},
+		{Name: bundle + "/specifications/nokogiri-1.13.3-x86_64-linux.gemspec", Body: nokogiriSpec},
+		{Name: bundle + "/gems/nokogiri-1.13.3-x86_64-linux/lib/nokogiri.rb", Body: ""},
+		// A default gem.
+		{Name: system + "/specifications/default/rexml-3.2.5.gemspec", Body: "# stub: rexml 3.2.5 ruby lib\n"},
+		// A gem without a specification.
+		{Name: vendor + "/gems/actionpack-page_caching-1.2.4/lib/page_caching.rb", Body: ""},
+		{Name: vendor + "/specifications/rails-4.2.11.gemspec", Body: railsSpec},
+		// Not a specification.
+		{Name: bundle + "/gems/rack-2.2.3/example.gemspec", Body: "# stub: example 0.0.1 ruby lib\n"},
+		// Not a gem home.
+		{Name: "srv/app/gems/thing-1.0.0/lib/thing.rb", Body: ""},
+		{Name: "srv/app/specifications/thing-1.0.0.gemspec", Body: "# stub: thing 1.0.0 ruby lib\n"},
+	}.Local(t)
+
+	got, err := (&Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Package{
+		pkg("actionpack-page_caching", "1.2.4", vendor, ""),
+		pkg("rails", "4.2.11", vendor, ""),
+		pkg("nokogiri", "1.13.3", bundle, "x86_64-linux"),
+		pkg("rack", "2.2.3", bundle, ""),
+		pkg("rexml", "3.2.5", system, ""),
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestParseDirName(t *testing.T) {
+	tt := []struct {
+		In   string
+		Want *spec
+	}{
+		{"rack-2.2.3", &spec{Name: "rack", Version: "2.2.3"}},
+		{"net-http-persistent-4.0.1", &spec{Name: "net-http-persistent", Version: "4.0.1"}},
+		{"nokogiri-1.13.3-x86_64-linux", &spec{Name: "nokogiri", Version: "1.13.3", Platform: "x86_64-linux"}},
+		{"rails-7.0.0.rc1", &spec{Name: "rails", Version: "7.0.0.rc1"}},
+		{"bundler", nil},
+	}
+	for _, tc := range tt {
+		got, _ := parseDirName(tc.In)
+		if !cmp.Equal(got, tc.Want) {
+			t.Errorf("%s: %s", tc.In, cmp.Diff(got, tc.Want))
+		}
+	}
+}
@@ -18,6 +18,7 @@ require (
 	github.com/knqyf263/go-apk-version v0.0.0-20200609155635-041fdbb8563f
 	github.com/knqyf263/go-deb-version v0.0.0-20190517075300-09fca494f03d
 	github.com/knqyf263/go-rpm-version v0.0.0-20170716094938-74609b86c936
+	github.com/mattn/go-sqlite3 v1.14.19
 	github.com/prometheus/client_golang v1.9.0
 	github.com/quay/alas v1.0.1
 	github.com/quay/goval-parser v0.8.6
@@ -25,6 +26,7 @@ require (
 	github.com/remind101/migrate v0.0.0-20170729031349-52c1edff7319
 	github.com/rs/zerolog v1.26.0
 	github.com/ulikunitz/xz v0.5.8
+	go.etcd.io/bbolt v1.3.6
 	go.opentelemetry.io/otel v0.15.0
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
 	golang.org/x/text v0.3.6
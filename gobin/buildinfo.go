@@ -0,0 +1,181 @@
+package gobin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// The Go linker writes a 32-byte header to the start of the build info
+// section:
+//
+//   - the 14-byte magic
+//   - the pointer size in bytes
+//   - flags: bit 0 is set for big-endian targets, bit 1 is set if the
+//     version strings follow the header inline (Go 1.18 and later)
+//   - padding, or two pointers to Go strings holding the toolchain version and
+//     the module information (before Go 1.18)
+//
+// See the runtime/debug and cmd/go/internal/version packages.
+const (
+	buildInfoMagic  = "\xff Go buildinf:"
+	buildInfoHeader = 32
+	// SearchSize is how far into the data section the header is looked for.
+	searchSize = 64 * 1024
+)
+
+// ErrNotGo is returned by readBuildInfo when the executable has no build
+// info.
+var errNotGo = errors.New("gobin: not a Go executable")
+
+// ReadBuildInfo returns the toolchain version and the raw module information
+// embedded in a Go executable.
+func readBuildInfo(x exe) (vers, mod string, err error) {
+	start := x.DataStart()
+	if start == 0 {
+		return "", "", errNotGo
+	}
+	data, err := x.ReadData(start, searchSize)
+	if err != nil {
+		return "", "", err
+	}
+	// The header is 16-byte aligned.
+	for {
+		i := bytes.Index(data, []byte(buildInfoMagic))
+		if i < 0 || len(data)-i < buildInfoHeader {
+			return "", "", errNotGo
+		}
+		if i%16 == 0 {
+			data = data[i:]
+			break
+		}
+		data = data[(i+15)&^15:]
+	}
+
+	ptrSize := int(data[14])
+	flags := data[15]
+	if flags&2 != 0 {
+		var rest []byte
+		vers, rest = decodeString(data[buildInfoHeader:])
+		mod, _ = decodeString(rest)
+	} else {
+		var bo binary.ByteOrder = binary.LittleEndian
+		if flags&1 != 0 {
+			bo = binary.BigEndian
+		}
+		var readPtr func([]byte) uint64
+		switch ptrSize {
+		case 4:
+			readPtr = func(b []byte) uint64 { return uint64(bo.Uint32(b)) }
+		case 8:
+			readPtr = bo.Uint64
+		default:
+			return "", "", errNotGo
+		}
+		vers = readString(x, ptrSize, readPtr, readPtr(data[16:]))
+		mod = readString(x, ptrSize, readPtr, readPtr(data[16+ptrSize:]))
+	}
+	if vers == "" {
+		return "", "", errNotGo
+	}
+	// The module information is wrapped in 16-byte sentinels, so that it can
+	// be found in the binary by other tools.
+	if len(mod) >= 33 && mod[len(mod)-17] == '\n' {
+		mod = mod[16 : len(mod)-16]
+	} else {
+		mod = ""
+	}
+	return vers, mod, nil
+}
+
+// DecodeString reads a uvarint-prefixed string from the front of "b".
+func decodeString(b []byte) (string, []byte) {
+	n, w := binary.Uvarint(b)
+	if w <= 0 || n > uint64(len(b)-w) {
+		return "", nil
+	}
+	return string(b[w : w+int(n)]), b[w+int(n):]
+}
+
+// ReadString reads the Go string header at "addr", then the string it
+// points to.
+func readString(x exe, ptrSize int, readPtr func([]byte) uint64, addr uint64) string {
+	hdr, err := x.ReadData(addr, uint64(2*ptrSize))
+	if err != nil || len(hdr) < 2*ptrSize {
+		return ""
+	}
+	data := readPtr(hdr)
+	n := readPtr(hdr[ptrSize:])
+	if n > searchSize*16 {
+		return ""
+	}
+	b, err := x.ReadData(data, n)
+	if err != nil || uint64(len(b)) < n {
+		return ""
+	}
+	return string(b)
+}
+
+// Module is a module recorded in the build info.
+type module struct {
+	Path    string
+	Version string
+	Sum     string
+	Replace *module
+}
+
+// BuildInfo is the parsed module information.
+type buildInfo struct {
+	// Path is the main package's import path.
+	Path string
+	Main module
+	Deps []*module
+}
+
+// ParseModInfo parses the module information written by the go command. The
+// format is a series of tab-separated lines:
+//
+//	path	example.com/cmd/tool
+//	mod	example.com	(devel)
+//	dep	golang.org/x/text	v0.3.7	h1:...
+//	=>	golang.org/x/text	v0.3.8	h1:...
+//	build	-compiler=gc
+//
+// where a "=>" line is a replacement of the module on the line before it.
+func parseModInfo(s string) *buildInfo {
+	var bi buildInfo
+	var last *module
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) < 2 {
+			continue
+		}
+		mod := func() *module {
+			m := module{Path: f[1]}
+			if len(f) > 2 {
+				m.Version = f[2]
+			}
+			if len(f) > 3 {
+				m.Sum = f[3]
+			}
+			return &m
+		}
+		switch f[0] {
+		case "path":
+			bi.Path = f[1]
+		case "mod":
+			bi.Main = *mod()
+			last = &bi.Main
+		case "dep":
+			last = mod()
+			bi.Deps = append(bi.Deps, last)
+		case "=>":
+			if last != nil {
+				last.Replace = mod()
+			}
+			last = nil
+		}
+	}
+	return &bi
+}
@@ -0,0 +1,177 @@
+package gobin
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Exe is the interface to an executable needed to find the build info.
+type exe interface {
+	// DataStart returns the address of the section the build info is in.
+	DataStart() uint64
+	// ReadData reads "size" bytes starting at virtual address "addr".
+	ReadData(addr, size uint64) ([]byte, error)
+}
+
+// IsExe reports whether the first bytes of a file look like an executable
+// format Go can produce.
+func isExe(b []byte) bool {
+	if len(b) < 4 {
+		return false
+	}
+	switch string(b[:4]) {
+	case "\x7fELF",
+		"\xfe\xed\xfa\xce", "\xfe\xed\xfa\xcf", // Mach-O, big-endian
+		"\xce\xfa\xed\xfe", "\xcf\xfa\xed\xfe": // Mach-O, little-endian
+		return true
+	}
+	return string(b[:2]) == "MZ"
+}
+
+// ErrNotExe is returned by openExe when the file isn't a supported executable.
+var errNotExe = errors.New("gobin: not a supported executable format")
+
+// OpenExe opens the executable in "r", using the magic in "b" to choose the
+// format.
+func openExe(r io.ReaderAt, b []byte) (exe, error) {
+	switch {
+	case len(b) >= 4 && string(b[:4]) == "\x7fELF":
+		f, err := elf.NewFile(r)
+		if err != nil {
+			return nil, fmt.Errorf("gobin: bad ELF: %w", err)
+		}
+		return &elfExe{f}, nil
+	case len(b) >= 2 && string(b[:2]) == "MZ":
+		f, err := pe.NewFile(r)
+		if err != nil {
+			return nil, fmt.Errorf("gobin: bad PE: %w", err)
+		}
+		return &peExe{f}, nil
+	case isExe(b):
+		f, err := macho.NewFile(r)
+		if err != nil {
+			return nil, fmt.Errorf("gobin: bad Mach-O: %w", err)
+		}
+		return &machoExe{f}, nil
+	}
+	return nil, errNotExe
+}
+
+type elfExe struct{ f *elf.File }
+
+func (x *elfExe) ReadData(addr, size uint64) ([]byte, error) {
+	for _, p := range x.f.Progs {
+		if p.Vaddr <= addr && addr < p.Vaddr+p.Filesz {
+			n := p.Vaddr + p.Filesz - addr
+			if n > size {
+				n = size
+			}
+			b := make([]byte, n)
+			if _, err := p.ReadAt(b, int64(addr-p.Vaddr)); err != nil {
+				return nil, err
+			}
+			return b, nil
+		}
+	}
+	return nil, errors.New("gobin: address not mapped")
+}
+
+func (x *elfExe) DataStart() uint64 {
+	// Go 1.13 and later put the build info in its own section.
+	if s := x.f.Section(".go.buildinfo"); s != nil {
+		return s.Addr
+	}
+	for _, p := range x.f.Progs {
+		if p.Type == elf.PT_LOAD && p.Flags&(elf.PF_X|elf.PF_W) == elf.PF_W {
+			return p.Vaddr
+		}
+	}
+	return 0
+}
+
+type peExe struct{ f *pe.File }
+
+func (x *peExe) imageBase() uint64 {
+	switch oh := x.f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		return uint64(oh.ImageBase)
+	case *pe.OptionalHeader64:
+		return oh.ImageBase
+	}
+	return 0
+}
+
+func (x *peExe) ReadData(addr, size uint64) ([]byte, error) {
+	addr -= x.imageBase()
+	for _, s := range x.f.Sections {
+		if uint64(s.VirtualAddress) <= addr && addr < uint64(s.VirtualAddress)+uint64(s.Size) {
+			n := uint64(s.VirtualAddress) + uint64(s.Size) - addr
+			if n > size {
+				n = size
+			}
+			b := make([]byte, n)
+			if _, err := s.ReadAt(b, int64(addr-uint64(s.VirtualAddress))); err != nil {
+				return nil, err
+			}
+			return b, nil
+		}
+	}
+	return nil, errors.New("gobin: address not mapped")
+}
+
+func (x *peExe) DataStart() uint64 {
+	// The Go linker writes its data section as initialized, readable, and
+	// writable data, possibly with an alignment flag.
+	const (
+		initializedData = 0x00000040
+		align32         = 0x00600000
+		memRead         = 0x40000000
+		memWrite        = 0x80000000
+	)
+	for _, s := range x.f.Sections {
+		if s.VirtualAddress != 0 && s.Size != 0 && s.Characteristics&^align32 == initializedData|memRead|memWrite {
+			return uint64(s.VirtualAddress) + x.imageBase()
+		}
+	}
+	return 0
+}
+
+type machoExe struct{ f *macho.File }
+
+func (x *machoExe) ReadData(addr, size uint64) ([]byte, error) {
+	for _, l := range x.f.Loads {
+		seg, ok := l.(*macho.Segment)
+		if !ok || seg.Name == "__PAGEZERO" {
+			continue
+		}
+		if seg.Addr <= addr && addr < seg.Addr+seg.Filesz {
+			n := seg.Addr + seg.Filesz - addr
+			if n > size {
+				n = size
+			}
+			b := make([]byte, n)
+			if _, err := seg.ReadAt(b, int64(addr-seg.Addr)); err != nil {
+				return nil, err
+			}
+			return b, nil
+		}
+	}
+	return nil, errors.New("gobin: address not mapped")
+}
+
+func (x *machoExe) DataStart() uint64 {
+	// Go 1.13 and later put the build info in its own section.
+	if s := x.f.Section("__go_buildinfo"); s != nil {
+		return s.Addr
+	}
+	for _, s := range x.f.Sections {
+		if s.Seg == "__DATA" && s.Name == "__data" {
+			return s.Addr
+		}
+	}
+	return 0
+}
@@ -0,0 +1,219 @@
+// Package gobin contains components for finding the Go modules compiled into
+// executables.
+//
+// The go command embeds the list of modules used to build an executable into
+// it. This package reads that list from ELF, PE, and Mach-O executables, so
+// that images without any package database, such as distroless images, can
+// have their Go dependencies matched against vulnerability data.
+package gobin
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/trace"
+	"sort"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/tmp"
+)
+
+var (
+	_ indexer.VersionedScanner   = (*Scanner)(nil)
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
+)
+
+// StdlibName is the package name used for the Go standard library, matching
+// the name used by the Go vulnerability database.
+const StdlibName = "stdlib"
+
+// Scanner implements the indexer.PackageScanner interface.
+//
+// It examines every executable file in a layer and reports a package for
+// each module recorded in a Go executable's build info: the main module, its
+// dependencies, and the standard library of the toolchain used. Versions are
+// reported as recorded, so pseudo-versions are preserved. Dependencies that
+// were replaced with another module are reported as the replacement.
+//
+// Every package has the SHA256 of the executable it was found in recorded as a
+// digest.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements indexer.VersionedScanner.
+func (*Scanner) Name() string { return "gobin" }
+
+// Version implements indexer.VersionedScanner.
+func (*Scanner) Version() string { return "2" }
+
+// Kind implements indexer.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceDeclared }
+
+// Scan attempts to find Go executables and report the modules they were built
+// from.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "gobin/Scanner.Scan"),
+		label.String("version", s.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// Executables are spooled into a scratch file, because the executable
+	// formats need random access.
+	spool, err := tmp.NewFile("", "gobin.")
+	if err != nil {
+		return nil, err
+	}
+	defer spool.Close()
+
+	var ret []*claircore.Package
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg || h.Mode&0o111 == 0 || h.Size < buildInfoHeader {
+			continue
+		}
+		br := bufio.NewReader(tr)
+		magic, err := br.Peek(4)
+		if err != nil || !isExe(magic) {
+			continue
+		}
+		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+		if err != nil {
+			return nil, err
+		}
+		n = filepath.ToSlash(n)
+		ps, err := scanExe(ctx, spool.File, n, br, magic)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, ps...)
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].PackageDB != ret[j].PackageDB {
+			return ret[i].PackageDB < ret[j].PackageDB
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+// ScanExe copies the executable in "r", named "n", to "spool" and reports the
+// modules in its build info. Files that aren't Go executables are ignored.
+//
+// The "magic" slice must not be used after "r" is read from.
+func scanExe(ctx context.Context, spool *os.File, n string, r io.Reader, magic []byte) ([]*claircore.Package, error) {
+	b := make([]byte, len(magic))
+	copy(b, magic)
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := spool.Truncate(0); err != nil {
+		return nil, err
+	}
+	h1, h256 := sha1.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(spool, h1, h256), r); err != nil {
+		return nil, fmt.Errorf("gobin: unable to spool %q: %w", n, err)
+	}
+
+	x, err := openExe(spool, b)
+	if err != nil {
+		zlog.Debug(ctx).
+			Str("file", n).
+			Err(err).
+			Msg("unable to open executable")
+		return nil, nil
+	}
+	vers, mod, err := readBuildInfo(x)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, errNotGo):
+		return nil, nil
+	default:
+		zlog.Debug(ctx).
+			Str("file", n).
+			Err(err).
+			Msg("unable to read build info")
+		return nil, nil
+	}
+	zlog.Debug(ctx).
+		Str("file", n).
+		Str("go", vers).
+		Msg("found Go executable")
+	// Both digests are recorded, as catalogs of binaries are keyed by
+	// either.
+	d1, err := claircore.NewDigest(claircore.SHA1, h1.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	d256, err := claircore.NewDigest(claircore.SHA256, h256.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	db := "go:" + n
+	pkg := func(name, version string) *claircore.Package {
+		return &claircore.Package{
+			Name:      name,
+			Version:   version,
+			Kind:      claircore.BINARY,
+			PackageDB: db,
+			Digests:   []claircore.Digest{d1, d256},
+		}
+	}
+
+	ret := []*claircore.Package{pkg(StdlibName, vers)}
+	if mod == "" {
+		// Built outside of module mode.
+		return ret, nil
+	}
+	bi := parseModInfo(mod)
+	if bi.Main.Path != "" {
+		ret = append(ret, pkg(bi.Main.Path, bi.Main.Version))
+	}
+	for _, d := range bi.Deps {
+		m := d
+		if d.Replace != nil {
+			// A replacement without a version is a local directory; the
+			// original module is the best name for its contents.
+			if d.Replace.Version != "" {
+				m = d.Replace
+			}
+		}
+		ret = append(ret, pkg(m.Path, m.Version))
+	}
+	return ret, nil
+}
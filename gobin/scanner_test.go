@@ -0,0 +1,147 @@
+package gobin
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// TestScan uses the test executable itself, which is a Go executable built in
+// module mode.
+func TestScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h1, h256 := sha1.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(h1, h256), in); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	wantDigests := []string{
+		"sha1:" + hex.EncodeToString(h1.Sum(nil)),
+		"sha256:" + hex.EncodeToString(h256.Sum(nil)),
+	}
+
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := tar.NewWriter(f)
+	if err := w.WriteHeader(&tar.Header{
+		Name:     "usr/bin/tool",
+		Typeflag: tar.TypeReg,
+		Size:     fi.Size(),
+		Mode:     0o755,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		t.Fatal(err)
+	}
+	// Not executable.
+	if err := w.WriteHeader(&tar.Header{
+		Name:     "usr/share/doc/tool/README",
+		Typeflag: tar.TypeReg,
+		Size:     64,
+		Mode:     0o644,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(make([]byte, 64)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var l claircore.Layer
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := (&Scanner{}).Scan(ctx, &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]*claircore.Package)
+	for _, p := range ps {
+		if p.PackageDB != "go:usr/bin/tool" {
+			t.Errorf("unexpected PackageDB: %q", p.PackageDB)
+		}
+		var ds []string
+		for _, d := range p.Digests {
+			ds = append(ds, d.String())
+		}
+		if !cmp.Equal(ds, wantDigests) {
+			t.Errorf("%s: %s", p.Name, cmp.Diff(wantDigests, ds))
+		}
+		got[p.Name] = p
+	}
+	if p, ok := got[StdlibName]; !ok || p.Version != runtime.Version() {
+		t.Errorf("stdlib: got: %+v, want: %q", p, runtime.Version())
+	}
+	for _, m := range []string{"github.com/quay/claircore", "github.com/quay/zlog", "github.com/google/go-cmp"} {
+		if _, ok := got[m]; !ok {
+			t.Errorf("missing module %q", m)
+		}
+	}
+}
+
+func TestParseModInfo(t *testing.T) {
+	const in = "path\texample.com/cmd/tool\n" +
+		"mod\texample.com\t(devel)\t\n" +
+		"dep\tgolang.org/x/text\tv0.3.7\th1:olpwvP2KacW1ZWvsR7uQhoyTYvKAupfQrRGBFM352Gk=\n" +
+		"dep\tgithub.com/example/old\tv1.0.0\th1:abc=\n" +
+		"=>\tgithub.com/example/new\tv0.0.0-20210101000000-0123456789ab\th1:def=\n" +
+		"build\t-compiler=gc\n"
+	got := parseModInfo(in)
+	want := &buildInfo{
+		Path: "example.com/cmd/tool",
+		Main: module{Path: "example.com", Version: "(devel)"},
+		Deps: []*module{
+			{Path: "golang.org/x/text", Version: "v0.3.7", Sum: "h1:olpwvP2KacW1ZWvsR7uQhoyTYvKAupfQrRGBFM352Gk="},
+			{
+				Path:    "github.com/example/old",
+				Version: "v1.0.0",
+				Sum:     "h1:abc=",
+				Replace: &module{
+					Path:    "github.com/example/new",
+					Version: "v0.0.0-20210101000000-0123456789ab",
+					Sum:     "h1:def=",
+				},
+			},
+		},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
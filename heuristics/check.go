@@ -0,0 +1,166 @@
+package heuristics
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"runtime/trace"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+)
+
+// Check reads the file lists of the layers, which must be in order and
+// fetched, and returns warnings about the image they make up.
+//
+// A return of (nil, nil) is expected if nothing is amiss.
+func Check(ctx context.Context, layers []*claircore.Layer) ([]claircore.IndexWarning, error) {
+	defer trace.StartRegion(ctx, "heuristics.Check").End()
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "heuristics/Check"))
+	fs := make([][]fact, len(layers))
+	for i, l := range layers {
+		f, err := layerFacts(l)
+		if err != nil {
+			return nil, fmt.Errorf("heuristics: layer %v: %w", l.Hash, err)
+		}
+		fs[i] = f
+	}
+	ws := evaluate(layers, fs)
+	zlog.Debug(ctx).
+		Int("layers", len(layers)).
+		Int("count", len(ws)).
+		Msg("checked layers")
+	return ws, nil
+}
+
+// LayerFacts records the package managers, package databases, and whiteouts
+// of either found in a layer.
+func layerFacts(l *claircore.Layer) ([]fact, error) {
+	rd, err := l.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+
+	var out []fact
+	tr := tar.NewReader(rd)
+	var h *tar.Header
+	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
+		if h.Typeflag == tar.TypeDir {
+			continue
+		}
+		n := clean(h.Name)
+		dir, base := path.Split(n)
+		if strings.HasPrefix(base, ".wh.") {
+			rm := path.Join(dir, strings.TrimPrefix(base, ".wh."))
+			if base == ".wh..wh..opq" {
+				rm = path.Clean(dir)
+			}
+			for p, f := range databases {
+				if covers(rm, p) {
+					out = append(out, fact{kind: factWhiteout, format: f, path: p})
+				}
+			}
+			for p, f := range managers {
+				if covers(rm, p) {
+					out = append(out, fact{kind: factWhiteout, format: f, path: p})
+				}
+			}
+			continue
+		}
+		if f, ok := managers[n]; ok {
+			out = append(out, fact{kind: factManager, format: f, path: n})
+		}
+		if f, ok := databases[n]; ok {
+			out = append(out, fact{kind: factDatabase, format: f, path: n})
+		}
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Evaluate applies the facts of each layer in order and reports warnings about
+// the result.
+func evaluate(layers []*claircore.Layer, fs [][]fact) []claircore.IndexWarning {
+	// Present tracks paths in the final filesystem, mapped to their format.
+	mgrs := make(map[string]string)
+	dbs := make(map[string]string)
+	// Removed tracks the last layer that removed a database of a format.
+	removed := make(map[string]claircore.Digest)
+	for i, l := range layers {
+		// Whiteouts apply to lower layers, so handle them before any
+		// additions in the same layer.
+		for _, f := range fs[i] {
+			if f.kind != factWhiteout {
+				continue
+			}
+			if _, ok := dbs[f.path]; ok {
+				delete(dbs, f.path)
+				removed[f.format] = l.Hash
+			}
+			delete(mgrs, f.path)
+		}
+		for _, f := range fs[i] {
+			switch f.kind {
+			case factManager:
+				mgrs[f.path] = f.format
+			case factDatabase:
+				dbs[f.path] = f.format
+			}
+		}
+	}
+
+	have := make(map[string]bool)
+	for _, f := range dbs {
+		have[f] = true
+	}
+	want := make(map[string][]string)
+	for p, f := range mgrs {
+		want[f] = append(want[f], "/"+p)
+	}
+
+	var ws []claircore.IndexWarning
+	formats := make([]string, 0, len(removed))
+	for f := range removed {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	for _, f := range formats {
+		if have[f] {
+			continue
+		}
+		d := removed[f]
+		ws = append(ws, claircore.IndexWarning{
+			Kind:    WarnDatabaseRemoved,
+			Message: fmt.Sprintf("%s package database removed by an upper layer; packages installed before its removal are not reported", f),
+			Layer:   &d,
+		})
+	}
+	formats = formats[:0]
+	for f := range want {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	for _, f := range formats {
+		if _, ok := removed[f]; have[f] || ok {
+			continue
+		}
+		bins := want[f]
+		sort.Strings(bins)
+		ws = append(ws, claircore.IndexWarning{
+			Kind:    WarnDatabaseMissing,
+			Message: fmt.Sprintf("package manager %v present but no %s package database found; binaries may have been copied in without a package manager", bins, f),
+		})
+	}
+	return ws
+}
@@ -0,0 +1,78 @@
+// Package heuristics implements image-level checks that explain why an
+// IndexReport may be missing packages.
+//
+// Check reads each layer's file list for facts (package manager binaries,
+// package databases, and whiteouts removing either) and evaluates them across
+// all layers, reporting claircore.IndexWarnings for situations like a package
+// manager present with no package database, or a package database deleted by
+// an upper layer. Nothing is stored: the checks are run on the fetched layers
+// each time a manifest is indexed.
+package heuristics
+
+import (
+	"path"
+	"strings"
+)
+
+// Warning kinds reported in claircore.IndexWarning.Kind.
+const (
+	// WarnDatabaseMissing is reported when a package manager is present in
+	// the final image but its package database is not.
+	WarnDatabaseMissing = "package-database-missing"
+	// WarnDatabaseRemoved is reported when a package database present in a
+	// lower layer is removed by an upper layer.
+	WarnDatabaseRemoved = "package-database-removed"
+)
+
+// Fact kinds.
+const (
+	factManager = iota
+	factDatabase
+	factWhiteout
+)
+
+// Fact is something found in a layer that's relevant to the checks.
+type fact struct {
+	kind int
+	// Format is the package database format of the manager or database.
+	format string
+	// Path is the manager or database's path, even for whiteouts of one of
+	// its parent directories.
+	path string
+}
+
+// Managers is a map of package manager binaries to the package database
+// format they use.
+var managers = map[string]string{
+	"usr/bin/dpkg":     "dpkg",
+	"usr/bin/apt":      "dpkg",
+	"usr/bin/apt-get":  "dpkg",
+	"bin/rpm":          "rpm",
+	"usr/bin/rpm":      "rpm",
+	"usr/bin/yum":      "rpm",
+	"usr/bin/dnf":      "rpm",
+	"usr/bin/microdnf": "rpm",
+	"sbin/apk":         "apk",
+}
+
+// Databases is a map of package database files to their format.
+var databases = map[string]string{
+	"var/lib/dpkg/status":               "dpkg",
+	"var/lib/rpm/Packages":              "rpm",
+	"var/lib/rpm/Packages.db":           "rpm",
+	"var/lib/rpm/rpmdb.sqlite":          "rpm",
+	"usr/lib/sysimage/rpm/Packages":     "rpm",
+	"usr/lib/sysimage/rpm/Packages.db":  "rpm",
+	"usr/lib/sysimage/rpm/rpmdb.sqlite": "rpm",
+	"lib/apk/db/installed":              "apk",
+}
+
+// Covers reports whether removing the path "rm" removes the path "p".
+func covers(rm, p string) bool {
+	return p == rm || strings.HasPrefix(p, rm+"/")
+}
+
+// Clean normalizes a tar header name into a relative, slash-separated path.
+func clean(n string) string {
+	return strings.TrimPrefix(path.Clean("/"+n), "/")
+}
@@ -0,0 +1,89 @@
+package heuristics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
+)
+
+func TestHeuristics(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Layers [][]string
+		Want   []string
+	}{
+		{
+			Name:   "Clean",
+			Layers: [][]string{{"usr/bin/dpkg", "var/lib/dpkg/status"}},
+		},
+		{
+			Name:   "Scratch",
+			Layers: [][]string{{"usr/local/bin/app"}},
+		},
+		{
+			Name:   "CopiedManager",
+			Layers: [][]string{{"./usr/bin/rpm", "usr/local/bin/app"}},
+			Want:   []string{WarnDatabaseMissing},
+		},
+		{
+			Name: "DatabaseRemoved",
+			Layers: [][]string{
+				{"usr/bin/apt", "var/lib/dpkg/status"},
+				{"var/lib/.wh.dpkg"},
+			},
+			Want: []string{WarnDatabaseRemoved},
+		},
+		{
+			Name: "OpaqueRemoved",
+			Layers: [][]string{
+				{"sbin/apk", "lib/apk/db/installed"},
+				{"lib/apk/db/.wh..wh..opq"},
+			},
+			Want: []string{WarnDatabaseRemoved},
+		},
+		{
+			Name: "Replaced",
+			Layers: [][]string{
+				{"usr/bin/apt", "var/lib/dpkg/status"},
+				{"var/lib/dpkg/.wh..wh..opq", "var/lib/dpkg/status"},
+			},
+		},
+		{
+			Name: "ManagerRemoved",
+			Layers: [][]string{
+				{"usr/bin/yum"},
+				{"usr/bin/.wh.yum"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(context.Background(), t)
+			var ls []*claircore.Layer
+			for _, names := range tc.Layers {
+				l := make(fixture.Layer, len(names))
+				for i, n := range names {
+					l[i] = fixture.Entry{Name: n}
+				}
+				ls = append(ls, l.Local(t))
+			}
+			ws, err := Check(ctx, ls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, w := range ws {
+				t.Log(w.Message)
+				got = append(got, w.Kind)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}
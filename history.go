@@ -0,0 +1,74 @@
+package claircore
+
+import "strings"
+
+// History is an entry in an image configuration's history, describing the
+// step of the build that created a layer.
+type History struct {
+	// CreatedBy is the command that created the layer, such as a Dockerfile
+	// instruction.
+	CreatedBy string `json:"created_by,omitempty"`
+	// EmptyLayer is set if the step didn't create a layer, as with an "ENV"
+	// or "LABEL" instruction.
+	EmptyLayer bool `json:"empty_layer,omitempty"`
+}
+
+// LayerInstructions returns the instruction that created each of the
+// manifest's layers, keyed by the layer's digest, according to the manifest's
+// History.
+//
+// History entries are matched to layers in order, skipping empty layers. If
+// the number of entries doesn't match the number of layers, the History
+// can't be trusted and nil is returned. Layers with no recorded command have
+// no entry in the returned map.
+func LayerInstructions(m *Manifest) map[string]string {
+	if len(m.History) == 0 {
+		return nil
+	}
+	cmds := make([]string, 0, len(m.Layers))
+	for _, h := range m.History {
+		if h.EmptyLayer {
+			continue
+		}
+		cmds = append(cmds, h.CreatedBy)
+	}
+	if len(cmds) != len(m.Layers) {
+		return nil
+	}
+	out := make(map[string]string, len(cmds))
+	for i, l := range m.Layers {
+		if c := Instruction(cmds[i]); c != "" {
+			out[l.Hash.String()] = c
+		}
+	}
+	return out
+}
+
+// Instruction returns the Dockerfile instruction recorded by a builder in a
+// History entry's CreatedBy.
+//
+// Builders record instructions in a few ways: the classic Docker builder as
+// "/bin/sh -c #(nop) COPY ..." for most instructions and "/bin/sh -c ..." for
+// "RUN", and BuildKit as "RUN /bin/sh -c ... # buildkit". These are rewritten
+// to read like the Dockerfile line. Anything else is returned trimmed, but
+// otherwise unchanged.
+func Instruction(createdBy string) string {
+	const (
+		shell    = "/bin/sh -c "
+		nop      = "#(nop) "
+		buildkit = " # buildkit"
+	)
+	c := strings.TrimSpace(createdBy)
+	c = strings.TrimSuffix(c, buildkit)
+	if strings.HasPrefix(c, shell) {
+		c = strings.TrimSpace(strings.TrimPrefix(c, shell))
+		if strings.HasPrefix(c, nop) {
+			return strings.TrimSpace(strings.TrimPrefix(c, nop))
+		}
+		return "RUN " + c
+	}
+	if strings.HasPrefix(c, "RUN "+shell) {
+		return "RUN " + strings.TrimSpace(strings.TrimPrefix(c, "RUN "+shell))
+	}
+	return c
+}
@@ -0,0 +1,54 @@
+package claircore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInstruction(t *testing.T) {
+	tt := []struct {
+		In, Want string
+	}{
+		{"/bin/sh -c apt-get update && apt-get install -y curl", "RUN apt-get update && apt-get install -y curl"},
+		{"/bin/sh -c #(nop) COPY file:abc in /app ", "COPY file:abc in /app"},
+		{"/bin/sh -c #(nop)  CMD [\"node\"]", "CMD [\"node\"]"},
+		{"RUN /bin/sh -c apk add --no-cache nodejs # buildkit", "RUN apk add --no-cache nodejs"},
+		{"COPY . /app # buildkit", "COPY . /app"},
+		{"ADD alpine-minirootfs.tar.gz / ", "ADD alpine-minirootfs.tar.gz /"},
+		{"", ""},
+	}
+	for _, tc := range tt {
+		if got := Instruction(tc.In); got != tc.Want {
+			t.Errorf("%q: got: %q, want: %q", tc.In, got, tc.Want)
+		}
+	}
+}
+
+func TestLayerInstructions(t *testing.T) {
+	a := MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	b := MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	m := Manifest{
+		Layers: []*Layer{{Hash: a}, {Hash: b}},
+		History: []History{
+			{CreatedBy: "/bin/sh -c #(nop) ADD file:123 in / "},
+			{CreatedBy: "/bin/sh -c #(nop)  ENV PATH=/bin", EmptyLayer: true},
+			{CreatedBy: "/bin/sh -c apt-get install -y curl"},
+			{CreatedBy: "/bin/sh -c #(nop)  CMD [\"bash\"]", EmptyLayer: true},
+		},
+	}
+	want := map[string]string{
+		a.String(): "ADD file:123 in /",
+		b.String(): "RUN apt-get install -y curl",
+	}
+	if got := LayerInstructions(&m); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	// A history that doesn't line up with the layers is ignored.
+	m.History = m.History[2:]
+	if got := LayerInstructions(&m); got != nil {
+		t.Errorf("got: %v, want: nil", got)
+	}
+}
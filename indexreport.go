@@ -1,5 +1,11 @@
 package claircore
 
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // IndexRecord is an entry in the IndexReport.
 //
 // IndexRecords provide full access to contextual package
@@ -10,6 +16,10 @@ type IndexRecord struct {
 	Package      *Package
 	Distribution *Distribution
 	Repository   *Repository
+	// Platform is the platform of the image the record was found in, if
+	// known. Matchers may consult it to apply architecture-specific
+	// vulnerabilities; see ArchOp.CmpRecord.
+	Platform *Platform
 }
 
 // IndexReport provides a database for discovered artifacts in an image.
@@ -33,17 +43,74 @@ type IndexReport struct {
 	Success bool `json:"success"`
 	// an error string in the case the index did not succeed
 	Err string `json:"err"`
+	// conditions noticed during indexing that may explain an incomplete report
+	Warnings []IndexWarning `json:"warnings,omitempty"`
+	// well-known labels from the image's configuration
+	Hints map[string]string `json:"hints,omitempty"`
+	// signatures, attestations, and SBOMs attached to the image in its
+	// registry, if discovery was enabled
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// the platform the image is built for, if it was indexed from a
+	// ManifestList
+	Platform *Platform `json:"platform,omitempty"`
+	// content signature matches, if any content scanners were configured
+	Detections []Detection `json:"detections,omitempty"`
+}
+
+// WarnForeignLayerSkipped is the IndexWarning Kind reported for a foreign
+// layer that couldn't be fetched from the registry or any allowed URL. The
+// layer's contents are missing from the IndexReport.
+const WarnForeignLayerSkipped = "foreign-layer-skipped"
+
+// WarnContentScanFailed is the IndexWarning Kind reported for a layer a
+// ContentScanner failed on. The layer's Detections from that scanner are
+// missing from the IndexReport.
+const WarnContentScanFailed = "content-scan-failed"
+
+// IndexWarning describes a condition found while indexing that may cause an
+// IndexReport to be incomplete, such as a package manager with no package
+// database.
+type IndexWarning struct {
+	// Kind is a short, machine-readable identifier for the condition.
+	Kind string `json:"kind"`
+	// Message is a human-readable description of the condition.
+	Message string `json:"message"`
+	// Layer is the layer the condition was noticed in, if any.
+	Layer *Digest `json:"layer,omitempty"`
 }
 
-// IndexRecords returns a list of IndexRecords derived from the IndexReport
+// IndexRecords returns a list of IndexRecords derived from the IndexReport,
+// ordered by package ID.
 func (report *IndexReport) IndexRecords() []*IndexRecord {
 	out := []*IndexRecord{}
-	for _, pkg := range report.Packages {
+	ids := make([]string, 0, len(report.Packages))
+	for id := range report.Packages {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return lessID(ids[i], ids[j]) })
+	// Merged reports record the platform in each Environment.
+	platforms := make(map[string]*Platform)
+	platform := func(env *Environment) *Platform {
+		if env.Platform == "" {
+			return report.Platform
+		}
+		p, ok := platforms[env.Platform]
+		if !ok {
+			if pp, err := ParsePlatform(env.Platform); err == nil {
+				p = &pp
+			}
+			platforms[env.Platform] = p
+		}
+		return p
+	}
+	for _, id := range ids {
+		pkg := report.Packages[id]
 		for _, env := range report.Environments[pkg.ID] {
 			if len(env.RepositoryIDs) == 0 {
 				record := &IndexRecord{}
 				record.Package = pkg
 				record.Distribution = report.Distributions[env.DistributionID]
+				record.Platform = platform(env)
 				out = append(out, record)
 				continue
 			}
@@ -52,6 +119,7 @@ func (report *IndexReport) IndexRecords() []*IndexRecord {
 				record := &IndexRecord{}
 				record.Package = pkg
 				record.Distribution = report.Distributions[env.DistributionID]
+				record.Platform = platform(env)
 				record.Repository = report.Repositories[repositoryID]
 				out = append(out, record)
 			}
@@ -59,3 +127,89 @@ func (report *IndexReport) IndexRecords() []*IndexRecord {
 	}
 	return out
 }
+
+// Sort puts every collection in the IndexReport into a stable order, so that
+// serializing the same report twice produces identical output.
+//
+// Maps are already serialized in key order; this sorts the slices: each
+// package's Environments, their RepositoryIDs, the Warnings, the
+// Attachments, and the Detections.
+func (report *IndexReport) Sort() {
+	sortEnvironments(report.Environments)
+	sort.SliceStable(report.Warnings, func(i, j int) bool {
+		a, b := &report.Warnings[i], &report.Warnings[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if al, bl := layerString(a.Layer), layerString(b.Layer); al != bl {
+			return al < bl
+		}
+		return a.Message < b.Message
+	})
+	sort.SliceStable(report.Attachments, func(i, j int) bool {
+		a, b := &report.Attachments[i], &report.Attachments[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Digest.String() < b.Digest.String()
+	})
+	sort.SliceStable(report.Detections, func(i, j int) bool {
+		a, b := &report.Detections[i], &report.Detections[j]
+		if al, bl := a.Layer.String(), b.Layer.String(); al != bl {
+			return al < bl
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Signature != b.Signature {
+			return a.Signature < b.Signature
+		}
+		return a.Scanner < b.Scanner
+	})
+}
+
+// SortEnvironments orders each package's Environments, and the repositories
+// within them.
+func sortEnvironments(envs map[string][]*Environment) {
+	for _, es := range envs {
+		for _, e := range es {
+			sort.Slice(e.RepositoryIDs, func(i, j int) bool {
+				return lessID(e.RepositoryIDs[i], e.RepositoryIDs[j])
+			})
+		}
+		sort.SliceStable(es, func(i, j int) bool {
+			a, b := es[i], es[j]
+			if a.Platform != b.Platform {
+				return a.Platform < b.Platform
+			}
+			if a.PackageDB != b.PackageDB {
+				return a.PackageDB < b.PackageDB
+			}
+			if ai, bi := a.IntroducedIn.String(), b.IntroducedIn.String(); ai != bi {
+				return ai < bi
+			}
+			if a.DistributionID != b.DistributionID {
+				return lessID(a.DistributionID, b.DistributionID)
+			}
+			return strings.Join(a.RepositoryIDs, ",") < strings.Join(b.RepositoryIDs, ",")
+		})
+	}
+}
+
+// LessID orders IDs numerically if they're both numbers, as database-assigned
+// IDs are, and lexically otherwise.
+func lessID(a, b string) bool {
+	an, aErr := strconv.ParseInt(a, 10, 64)
+	bn, bErr := strconv.ParseInt(b, 10, 64)
+	if aErr == nil && bErr == nil {
+		return an < bn
+	}
+	return a < b
+}
+
+func layerString(d *Digest) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
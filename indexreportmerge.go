@@ -0,0 +1,111 @@
+package claircore
+
+import (
+	"reflect"
+	"strings"
+)
+
+// MergeIndexReports combines the IndexReports for each platform of a
+// ManifestList into a single IndexReport for the list.
+//
+// Every Environment in the merged report has its Platform set from the
+// report it came from, so a package found on several platforms has an
+// Environment for each. Reports indexed separately may use the same ID for
+// different things; when they do, the later entry is given a new ID
+// qualified by its platform.
+//
+// The merged report is only successful if every report was. Hints are kept
+// if every report agrees on them.
+func MergeIndexReports(hash Digest, rs ...*IndexReport) *IndexReport {
+	out := IndexReport{
+		Hash:          hash,
+		State:         "IndexFinished",
+		Success:       true,
+		Packages:      make(map[string]*Package),
+		Distributions: make(map[string]*Distribution),
+		Repositories:  make(map[string]*Repository),
+		Environments:  make(map[string][]*Environment),
+	}
+	var errs []string
+	for i, r := range rs {
+		var platform string
+		if r.Platform != nil {
+			platform = r.Platform.String()
+		}
+		if !r.Success {
+			out.Success = false
+			out.State = r.State
+		}
+		if r.Err != "" {
+			errs = append(errs, platform+": "+r.Err)
+		}
+
+		dists := make(map[string]string, len(r.Distributions))
+		for id, d := range r.Distributions {
+			nid := id
+			if cur, ok := out.Distributions[id]; ok && !reflect.DeepEqual(cur, d) {
+				nid = qualifyID(id, platform)
+				c := *d
+				c.ID = nid
+				d = &c
+			}
+			out.Distributions[nid] = d
+			dists[id] = nid
+		}
+		repos := make(map[string]string, len(r.Repositories))
+		for id, rp := range r.Repositories {
+			nid := id
+			if cur, ok := out.Repositories[id]; ok && !reflect.DeepEqual(cur, rp) {
+				nid = qualifyID(id, platform)
+				c := *rp
+				c.ID = nid
+				rp = &c
+			}
+			out.Repositories[nid] = rp
+			repos[id] = nid
+		}
+		for id, p := range r.Packages {
+			nid := id
+			if cur, ok := out.Packages[id]; ok && !reflect.DeepEqual(cur, p) {
+				nid = qualifyID(id, platform)
+				c := *p
+				c.ID = nid
+				p = &c
+			}
+			out.Packages[nid] = p
+			for _, e := range r.Environments[id] {
+				ne := *e
+				ne.Platform = platform
+				if did, ok := dists[e.DistributionID]; ok {
+					ne.DistributionID = did
+				}
+				ne.RepositoryIDs = make([]string, len(e.RepositoryIDs))
+				for j, rid := range e.RepositoryIDs {
+					ne.RepositoryIDs[j] = rid
+					if rid, ok := repos[rid]; ok {
+						ne.RepositoryIDs[j] = rid
+					}
+				}
+				out.Environments[nid] = append(out.Environments[nid], &ne)
+			}
+		}
+
+		out.Warnings = append(out.Warnings, r.Warnings...)
+		out.Attachments = append(out.Attachments, r.Attachments...)
+		out.Detections = append(out.Detections, r.Detections...)
+		if i == 0 {
+			out.Hints = r.Hints
+		} else if !reflect.DeepEqual(out.Hints, r.Hints) {
+			out.Hints = nil
+		}
+	}
+	out.Err = strings.Join(errs, "; ")
+	out.Sort()
+	return &out
+}
+
+// QualifyID makes an ID taken by a different entry in another platform's
+// report unique.
+func qualifyID(id, platform string) string {
+	return id + "@" + platform
+}
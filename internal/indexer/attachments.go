@@ -0,0 +1,16 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// AttachmentFinder discovers artifacts, such as signatures, attestations, and
+// SBOMs, attached to a manifest in the registry it was fetched from.
+//
+// Discovery is best-effort: an error is reported in the logs, but doesn't
+// fail the index.
+type AttachmentFinder interface {
+	FindAttachments(context.Context, *claircore.Manifest) ([]claircore.Attachment, error)
+}
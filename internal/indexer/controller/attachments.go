@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/quay/zlog"
+)
+
+// FindAttachments records the artifacts attached to the manifest in the
+// report, if an AttachmentFinder is configured.
+//
+// Failing to discover attachments doesn't fail the index; the report just
+// doesn't list any.
+func findAttachments(ctx context.Context, s *Controller) {
+	if s.Attachments == nil {
+		return
+	}
+	as, err := s.Attachments.FindAttachments(ctx, s.manifest)
+	if err != nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Msg("unable to discover attachments")
+		return
+	}
+	zlog.Debug(ctx).
+		Int("count", len(as)).
+		Msg("discovered attachments")
+	s.report.Attachments = as
+}
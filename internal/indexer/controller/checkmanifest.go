@@ -69,6 +69,16 @@ func checkManifest(ctx context.Context, s *Controller) (State, error) {
 	if !ok {
 		return Terminal, fmt.Errorf("failed to retrieve manifest: %w", err)
 	}
+	// Reports persisted without hints, or for a manifest submitted without
+	// labels, pick up the hints from this submission.
+	if sr.Hints == nil {
+		sr.Hints = s.report.Hints
+	}
+	if sr.Platform == nil {
+		sr.Platform = s.report.Platform
+	}
+	// Reports persisted by older versions may not be in a stable order.
+	sr.Sort()
 	s.report = sr
 
 	return Terminal, nil
@@ -17,7 +17,13 @@ func coalesce(ctx context.Context, s *Controller) (State, error) {
 	defer cancel()
 	mu := sync.Mutex{}
 	reports := []*claircore.IndexReport{}
+	fallbacks := []*claircore.IndexReport{}
 	g := errgroup.Group{}
+	dd := newDedupe()
+	removed, err := layerWhiteouts(s)
+	if err != nil {
+		return Terminal, fmt.Errorf("failed to read layer whiteouts: %w", err)
+	}
 	// dispatch a coalescer go routine for each ecosystem
 	for _, ecosystem := range s.Ecosystems {
 		artifacts := []*indexer.LayerArtifacts{}
@@ -27,7 +33,8 @@ func coalesce(ctx context.Context, s *Controller) (State, error) {
 		// pack artifacts var
 		for _, layer := range s.manifest.Layers {
 			la := &indexer.LayerArtifacts{
-				Hash: layer.Hash,
+				Hash:    layer.Hash,
+				Removed: removed[layer.Hash.String()],
 			}
 			var vscnrs indexer.VersionedScanners
 			vscnrs.PStoVS(pkgScanners)
@@ -52,6 +59,9 @@ func coalesce(ctx context.Context, s *Controller) (State, error) {
 				return Terminal, fmt.Errorf("failed to retrieve repositories for %v: %w", layer.Hash, err)
 			}
 			la.Repos = append(la.Repos, repos...)
+			if err := dd.Layer(la); err != nil {
+				return Terminal, fmt.Errorf("failed to deduplicate artifacts for %v: %w", layer.Hash, err)
+			}
 			// pack artifacts array in layer order
 			artifacts = append(artifacts, la)
 		}
@@ -59,6 +69,7 @@ func coalesce(ctx context.Context, s *Controller) (State, error) {
 		if err != nil {
 			return Terminal, fmt.Errorf("failed to get coalescer from ecosystem: %v", err)
 		}
+		fallback := ecosystem.Fallback
 		// dispatch coalescer
 		g.Go(func() error {
 			sr, err := coalescer.Coalesce(cctx, artifacts)
@@ -68,7 +79,11 @@ func coalesce(ctx context.Context, s *Controller) (State, error) {
 
 			mu.Lock()
 			defer mu.Unlock()
-			reports = append(reports, sr)
+			if fallback {
+				fallbacks = append(fallbacks, sr)
+			} else {
+				reports = append(reports, sr)
+			}
 			return nil
 		})
 	}
@@ -76,6 +91,14 @@ func coalesce(ctx context.Context, s *Controller) (State, error) {
 		return Terminal, err
 	}
 	s.report = MergeSR(s.report, reports)
+	// Fallback ecosystems only fill in for a distribution no other ecosystem
+	// could identify.
+	if len(s.report.Distributions) == 0 {
+		s.report = MergeSR(s.report, fallbacks)
+	}
+	checkHeuristics(ctx, s)
+	attributeHistory(ctx, s)
+	findAttachments(ctx, s)
 	return IndexManifest, nil
 }
 
@@ -99,6 +122,8 @@ func MergeSR(source *claircore.IndexReport, merge []*claircore.IndexReport) *cla
 		for k, v := range ir.Repositories {
 			source.Repositories[k] = v
 		}
+
+		source.Warnings = append(source.Warnings, ir.Warnings...)
 	}
 	return source
 }
@@ -2,8 +2,13 @@ package controller
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
 )
 
@@ -42,3 +47,102 @@ func TestCoalesce(t *testing.T) {
 		})
 	}
 }
+
+type staticCoalescer claircore.IndexReport
+
+func (c *staticCoalescer) Coalesce(context.Context, []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := claircore.IndexReport(*c)
+	return &ir, nil
+}
+
+func staticEcosystem(name string, fallback bool, dists ...*claircore.Distribution) *indexer.Ecosystem {
+	ir := staticCoalescer{Distributions: map[string]*claircore.Distribution{}}
+	for _, d := range dists {
+		ir.Distributions[d.ID] = d
+	}
+	return &indexer.Ecosystem{
+		Name:                 name,
+		PackageScanners:      func(context.Context) ([]indexer.PackageScanner, error) { return nil, nil },
+		DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+		RepositoryScanners:   func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		Coalescer:            func(context.Context) (indexer.Coalescer, error) { return &ir, nil },
+		Fallback:             fallback,
+	}
+}
+
+// TestCoalesceFallback confirms a fallback ecosystem's distribution is only
+// reported when no other ecosystem found one.
+func TestCoalesceFallback(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	specific := &claircore.Distribution{ID: "1", DID: "debian"}
+	generic := &claircore.Distribution{ID: "2", DID: "debian"}
+	wolfi := &claircore.Distribution{ID: "3", DID: "wolfi"}
+	tt := []struct {
+		name       string
+		ecosystems []*indexer.Ecosystem
+		want       []string
+	}{
+		{
+			name: "Specific",
+			ecosystems: []*indexer.Ecosystem{
+				staticEcosystem("dpkg", false, specific),
+				staticEcosystem("osrelease", true, generic),
+			},
+			want: []string{"1"},
+		},
+		{
+			name: "Fallback",
+			ecosystems: []*indexer.Ecosystem{
+				staticEcosystem("dpkg", false),
+				staticEcosystem("osrelease", true, wolfi),
+			},
+			want: []string{"3"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			c := New(&indexer.Opts{Ecosystems: tc.ecosystems})
+			if _, err := coalesce(ctx, c); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for id := range c.report.Distributions {
+				got = append(got, id)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+}
+
+// TestAttributeHistory confirms environments are attributed to the
+// instruction that created their layer.
+func TestAttributeHistory(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	base := claircore.MustParseDigest("sha256:" + strings.Repeat("a", 64))
+	app := claircore.MustParseDigest("sha256:" + strings.Repeat("b", 64))
+	c := New(&indexer.Opts{})
+	c.manifest = &claircore.Manifest{
+		Layers: []*claircore.Layer{{Hash: base}, {Hash: app}},
+		History: []claircore.History{
+			{CreatedBy: "/bin/sh -c #(nop) ADD file:123 in / "},
+			{CreatedBy: "/bin/sh -c apt-get install -y curl"},
+		},
+	}
+	c.report.Environments = map[string][]*claircore.Environment{
+		"1": {{IntroducedIn: base}},
+		"2": {{IntroducedIn: app}},
+	}
+	attributeHistory(ctx, c)
+	want := map[string]string{
+		"1": "ADD file:123 in /",
+		"2": "RUN apt-get install -y curl",
+	}
+	for id, w := range want {
+		if got := c.report.Environments[id][0].IntroducedBy; got != w {
+			t.Errorf("%s: got: %q, want: %q", id, got, w)
+		}
+	}
+}
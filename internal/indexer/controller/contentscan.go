@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+)
+
+// ScanContent runs the configured ContentScanners on each layer and records
+// their Detections in the report.
+//
+// A ContentScanner failing doesn't fail the index; it's recorded as a warning
+// and the layer's results from that scanner are left out.
+func scanContent(ctx context.Context, s *Controller) error {
+	if len(s.ContentScanners) == 0 {
+		return nil
+	}
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/indexer/controller/scanContent"))
+	for _, l := range s.layers() {
+		for _, cs := range s.ContentScanners {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			ds, err := cs.Scan(ctx, l)
+			if err != nil {
+				zlog.Warn(ctx).
+					Err(err).
+					Str("scanner", cs.Name()).
+					Stringer("layer", l.Hash).
+					Msg("content scan failed")
+				d := l.Hash
+				s.report.Warnings = append(s.report.Warnings, claircore.IndexWarning{
+					Kind:    claircore.WarnContentScanFailed,
+					Message: fmt.Sprintf("content scanner %q failed: %v", cs.Name(), err),
+					Layer:   &d,
+				})
+				continue
+			}
+			for i := range ds {
+				ds[i].Layer = l.Hash
+				ds[i].Scanner = cs.Name()
+			}
+			s.report.Detections = append(s.report.Detections, ds...)
+		}
+	}
+	zlog.Debug(ctx).
+		Int("count", len(s.report.Detections)).
+		Msg("content scan done")
+	return nil
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/memory"
+)
+
+// FlakyContentScanner reports a detection in every layer but the one it's
+// told to fail on.
+type flakyContentScanner struct {
+	fail string
+}
+
+var _ indexer.ContentScanner = (*flakyContentScanner)(nil)
+
+func (*flakyContentScanner) Name() string    { return "flaky" }
+func (*flakyContentScanner) Version() string { return "1" }
+func (*flakyContentScanner) Kind() string    { return indexer.Content }
+func (s *flakyContentScanner) Scan(_ context.Context, l *claircore.Layer) ([]claircore.Detection, error) {
+	if l.Hash.String() == s.fail {
+		return nil, errors.New("engine unavailable")
+	}
+	return []claircore.Detection{{Path: "tmp/x", Signature: "Test.Sig"}}, nil
+}
+
+// TestScanContent confirms detections are attributed to the layer and
+// scanner, and that a failing scanner produces a warning instead of failing
+// the index.
+func TestScanContent(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	mk := func(n byte) *claircore.Layer {
+		b := make([]byte, sha256.Size)
+		b[0] = n
+		d, err := claircore.NewDigest("sha256", b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &claircore.Layer{Hash: d}
+	}
+	ok, bad := mk(1), mk(2)
+	c := New(&indexer.Opts{
+		Store:           memory.NewStore(),
+		ContentScanners: []indexer.ContentScanner{&flakyContentScanner{fail: bad.Hash.String()}},
+	})
+	c.manifest = &claircore.Manifest{
+		Hash:   mk(0).Hash,
+		Layers: []*claircore.Layer{ok, bad},
+	}
+
+	if err := scanContent(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(c.report.Detections), 1; got != want {
+		t.Fatalf("got: %d detections, want: %d", got, want)
+	}
+	d := c.report.Detections[0]
+	if d.Layer.String() != ok.Hash.String() || d.Scanner != "flaky" || d.Signature != "Test.Sig" {
+		t.Errorf("unexpected detection: %+v", d)
+	}
+	if got, want := len(c.report.Warnings), 1; got != want {
+		t.Fatalf("got: %d warnings, want: %d", got, want)
+	}
+	w := c.report.Warnings[0]
+	if w.Kind != claircore.WarnContentScanFailed || w.Layer == nil || w.Layer.String() != bad.Hash.String() {
+		t.Errorf("unexpected warning: %+v", w)
+	}
+}
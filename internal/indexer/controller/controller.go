@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/rand"
 	"time"
 
@@ -28,6 +29,8 @@ type Controller struct {
 	err error
 	// the current state of the controller
 	currentState State
+	// foreign layers that couldn't be fetched, keyed by digest
+	skipped map[string]struct{}
 }
 
 // New constructs a controller given an Opts struct
@@ -59,6 +62,8 @@ func (s *Controller) Index(ctx context.Context, manifest *claircore.Manifest) (*
 	// set manifest info on controller
 	s.manifest = manifest
 	s.report.Hash = manifest.Hash
+	s.report.Hints = claircore.LabelHints(manifest.Labels)
+	s.report.Platform = manifest.Platform
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/indexer/controller/Controller.Index"),
 		label.String("manifest", s.manifest.Hash.String()))
@@ -67,6 +72,38 @@ func (s *Controller) Index(ctx context.Context, manifest *claircore.Manifest) (*
 	return s.report, s.run(ctx)
 }
 
+// Skip records that the layer couldn't be fetched and won't be scanned.
+func (s *Controller) skip(l *claircore.Layer, err error) {
+	if s.skipped == nil {
+		s.skipped = make(map[string]struct{})
+	}
+	k := l.Hash.String()
+	if _, ok := s.skipped[k]; ok {
+		return
+	}
+	s.skipped[k] = struct{}{}
+	d := l.Hash
+	s.report.Warnings = append(s.report.Warnings, claircore.IndexWarning{
+		Kind:    claircore.WarnForeignLayerSkipped,
+		Message: fmt.Sprintf("unable to fetch foreign layer: %v", err),
+		Layer:   &d,
+	})
+}
+
+// Layers returns the manifest's layers, less any skipped.
+func (s *Controller) layers() []*claircore.Layer {
+	if len(s.skipped) == 0 {
+		return s.manifest.Layers
+	}
+	out := make([]*claircore.Layer, 0, len(s.manifest.Layers))
+	for _, l := range s.manifest.Layers {
+		if _, ok := s.skipped[l.Hash.String()]; !ok {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
 // Run executes each stateFunc and blocks until either an error occurs or a
 // Terminal state is encountered.
 func (s *Controller) run(ctx context.Context) (err error) {
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"hash"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// Dedupe shares identical artifacts between layers.
+//
+// Images built by chaining builds repeat the same packages in many layers.
+// Without deduplication, every layer holds its own copy of every package
+// while coalescing. Artifacts are compared by content rather than ID, as the
+// same package may be reported differently in different layers (e.g. with a
+// different package database).
+//
+// Coalescers must not modify the artifacts they're handed, as they may be
+// shared.
+type dedupe struct {
+	h     hash.Hash
+	enc   *json.Encoder
+	pkgs  map[digest]*claircore.Package
+	dists map[digest]*claircore.Distribution
+	repos map[digest]*claircore.Repository
+	sets  map[digest]*indexer.LayerArtifacts
+}
+
+type digest [sha256.Size]byte
+
+// PkgKey adds the fields of a Package that aren't in its JSON encoding.
+type pkgKey struct {
+	P        *claircore.Package
+	DB, Hint string
+}
+
+func newDedupe() *dedupe {
+	h := sha256.New()
+	return &dedupe{
+		h:     h,
+		enc:   json.NewEncoder(h),
+		pkgs:  make(map[digest]*claircore.Package),
+		dists: make(map[digest]*claircore.Distribution),
+		repos: make(map[digest]*claircore.Repository),
+		sets:  make(map[digest]*indexer.LayerArtifacts),
+	}
+}
+
+// Sum returns the digest of the value's encoding.
+func (d *dedupe) sum(v interface{}) (digest, error) {
+	var out digest
+	d.h.Reset()
+	if err := d.enc.Encode(v); err != nil {
+		return out, err
+	}
+	d.h.Sum(out[:0])
+	return out, nil
+}
+
+// Layer replaces the artifacts in the LayerArtifacts with identical ones seen
+// previously, if any. If the layer has the same artifacts as a previous
+// layer, the slices are shared as well.
+func (d *dedupe) Layer(la *indexer.LayerArtifacts) error {
+	set := sha256.New()
+	for i, p := range la.Pkgs {
+		k, err := d.sum(pkgKey{P: p, DB: p.PackageDB, Hint: p.RepositoryHint})
+		if err != nil {
+			return err
+		}
+		if prev, ok := d.pkgs[k]; ok {
+			la.Pkgs[i] = prev
+		} else {
+			d.pkgs[k] = p
+		}
+		set.Write(k[:])
+	}
+	set.Write([]byte{0})
+	for i, v := range la.Dist {
+		k, err := d.sum(v)
+		if err != nil {
+			return err
+		}
+		if prev, ok := d.dists[k]; ok {
+			la.Dist[i] = prev
+		} else {
+			d.dists[k] = v
+		}
+		set.Write(k[:])
+	}
+	set.Write([]byte{0})
+	for i, r := range la.Repos {
+		k, err := d.sum(r)
+		if err != nil {
+			return err
+		}
+		if prev, ok := d.repos[k]; ok {
+			la.Repos[i] = prev
+		} else {
+			d.repos[k] = r
+		}
+		set.Write(k[:])
+	}
+
+	var k digest
+	set.Sum(k[:0])
+	if prev, ok := d.sets[k]; ok {
+		la.Pkgs, la.Dist, la.Repos = prev.Pkgs, prev.Dist, prev.Repos
+		return nil
+	}
+	d.sets[k] = la
+	return nil
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+func TestDedupe(t *testing.T) {
+	pkgs := func(n int) []*claircore.Package {
+		ps := make([]*claircore.Package, n)
+		for i := range ps {
+			ps[i] = &claircore.Package{
+				ID:        string(rune('a' + i)),
+				Name:      string(rune('a' + i)),
+				Version:   "1",
+				PackageDB: "var/lib/rpm",
+			}
+		}
+		return ps
+	}
+	dist := func() []*claircore.Distribution {
+		return []*claircore.Distribution{{ID: "1", DID: "rhel", VersionID: "8"}}
+	}
+	ls := []*indexer.LayerArtifacts{
+		{Pkgs: pkgs(2), Dist: dist()},
+		{Pkgs: pkgs(3), Dist: dist()},
+		{Pkgs: pkgs(3), Dist: dist()},
+		{Pkgs: pkgs(3)},
+	}
+	// Same package, different database.
+	ls[3].Pkgs[2].PackageDB = "usr/lib/sysimage/rpm"
+
+	dd := newDedupe()
+	for _, la := range ls {
+		if err := dd.Layer(la); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 1; i < len(ls); i++ {
+		for j := 0; j < 2; j++ {
+			if ls[i].Pkgs[j] != ls[0].Pkgs[j] {
+				t.Errorf("layer %d: package %d not shared", i, j)
+			}
+		}
+	}
+	if ls[1].Dist[0] != ls[0].Dist[0] {
+		t.Error("distribution not shared")
+	}
+	if &ls[2].Pkgs[0] != &ls[1].Pkgs[0] {
+		t.Error("identical layers' packages not shared")
+	}
+	if ls[3].Pkgs[2] == ls[1].Pkgs[2] {
+		t.Error("differing packages shared")
+	}
+	if got, want := ls[3].Pkgs[2].PackageDB, "usr/lib/sysimage/rpm"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if len(ls[3].Dist) != 0 {
+		t.Error("distribution added")
+	}
+}
@@ -2,9 +2,15 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"path"
+	"strings"
 
 	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
 )
 
 func fetchLayers(ctx context.Context, s *Controller) (State, error) {
@@ -14,15 +20,110 @@ func fetchLayers(ctx context.Context, s *Controller) (State, error) {
 	if err != nil {
 		return Terminal, fmt.Errorf("failed to determine layers to fetch: %w", err)
 	}
+	// Content scanner and heuristics results and whiteouts aren't stored, so
+	// every layer is needed. A single layer has no lower layers to remove
+	// anything from.
+	if len(s.ContentScanners) != 0 || s.Heuristics || len(s.manifest.Layers) > 1 {
+		toFetch = s.manifest.Layers
+	}
+	// Foreign layers are fetched one at a time, so that a failure can be
+	// recorded and the layer skipped instead of failing the index.
+	var foreign []*claircore.Layer
+	regular := make([]*claircore.Layer, 0, len(toFetch))
+	for _, l := range toFetch {
+		if len(l.URLs) != 0 {
+			foreign = append(foreign, l)
+			continue
+		}
+		regular = append(regular, l)
+	}
 	zlog.Debug(ctx).
-		Int("count", len(toFetch)).
+		Int("count", len(regular)).
+		Int("foreign", len(foreign)).
 		Msg("fetching layers")
-	if err := s.Fetcher.Fetch(ctx, toFetch); err != nil {
+	if err := s.Fetcher.Fetch(ctx, regular); err != nil {
 		zlog.Warn(ctx).
 			Err(err).
 			Msg("layers fetch failure")
 		return Terminal, fmt.Errorf("failed to fetch layers: %w", err)
 	}
+	for _, l := range foreign {
+		if err := fetchForeign(ctx, s, l); err != nil {
+			if ctx.Err() != nil {
+				return Terminal, ctx.Err()
+			}
+			zlog.Warn(ctx).
+				Err(err).
+				Stringer("layer", l.Hash).
+				Msg("skipping foreign layer")
+			s.skip(l, err)
+		}
+	}
 	zlog.Info(ctx).Msg("layers fetch success")
 	return ScanLayers, nil
 }
+
+// FetchForeign tries to fetch a foreign layer from the registry, then from
+// each of its URLs allowed by the configuration, in order.
+func fetchForeign(ctx context.Context, s *Controller, l *claircore.Layer) error {
+	var cand []string
+	if l.URI != "" {
+		cand = append(cand, l.URI)
+	}
+	for _, u := range l.URLs {
+		if foreignAllowed(s.ForeignURLs, u) {
+			cand = append(cand, u)
+		}
+	}
+	if len(cand) == 0 {
+		return errors.New("no allowed URLs")
+	}
+	var errs []string
+	for _, u := range cand {
+		// Fetch a copy, so the layer keeps its registry URI. The registry's
+		// headers carry its credentials, so they're only sent to it.
+		f := *l
+		f.URI = u
+		if u != l.URI {
+			f.Headers = nil
+		}
+		err := s.Fetcher.Fetch(ctx, []*claircore.Layer{&f})
+		if err == nil {
+			zlog.Debug(ctx).
+				Stringer("layer", l.Hash).
+				Str("uri", u).
+				Msg("fetched foreign layer")
+			f.URI, f.Headers = l.URI, l.Headers
+			*l = f
+			return nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+// ForeignAllowed reports whether the URL is under one of the allowed
+// prefixes: it must have the same scheme and host, and its path must be the
+// prefix's path or below it.
+func foreignAllowed(allow []string, u string) bool {
+	tgt, err := url.Parse(u)
+	if err != nil || tgt.User != nil {
+		return false
+	}
+	// Resolve any ".." so it can't climb out of the prefix.
+	tp := path.Clean("/" + tgt.Path)
+	for _, p := range allow {
+		pfx, err := url.Parse(p)
+		if err != nil || pfx.Host == "" {
+			continue
+		}
+		if !strings.EqualFold(tgt.Scheme, pfx.Scheme) || !strings.EqualFold(tgt.Host, pfx.Host) {
+			continue
+		}
+		dir := strings.TrimSuffix(pfx.Path, "/")
+		if tp == dir || strings.HasPrefix(tp, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/memory"
+)
+
+// UriFetcher succeeds for the URIs in its set and records every URI tried,
+// along with the headers sent to it. Fetched layers are backed by the file
+// at local.
+type uriFetcher struct {
+	ok      map[string]bool
+	local   string
+	tried   []string
+	headers map[string]map[string][]string
+}
+
+func (f *uriFetcher) Fetch(_ context.Context, ls []*claircore.Layer) error {
+	for _, l := range ls {
+		f.tried = append(f.tried, l.URI)
+		if f.headers == nil {
+			f.headers = make(map[string]map[string][]string)
+		}
+		f.headers[l.URI] = l.Headers
+		if !f.ok[l.URI] {
+			return errors.New("not found")
+		}
+		l.SetLocal(f.local)
+	}
+	return nil
+}
+
+func (*uriFetcher) Close() error { return nil }
+
+type stubScanner struct{}
+
+func (stubScanner) Name() string    { return "stub" }
+func (stubScanner) Version() string { return "1" }
+func (stubScanner) Kind() string    { return "package" }
+
+// TestFetchForeign confirms foreign layers are fetched from allowed URLs
+// after the registry, and skipped with a warning if that's not possible.
+func TestFetchForeign(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	mk := func(n byte) claircore.Digest {
+		b := make([]byte, sha256.Size)
+		b[0] = n
+		d, err := claircore.NewDigest("sha256", b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	regular := &claircore.Layer{Hash: mk(1), URI: "https://registry.example/v2/r/blobs/1"}
+	auth := map[string][]string{"Authorization": {"Bearer secret"}}
+	allowed := &claircore.Layer{
+		Hash:    mk(2),
+		URI:     "https://registry.example/v2/r/blobs/2",
+		Headers: auth,
+		URLs: []string{
+			"https://other.example/2",
+			"https://allowed.example.evil.example/2",
+			"https://allowed.example/2",
+		},
+	}
+	denied := &claircore.Layer{
+		Hash: mk(3),
+		URLs: []string{"https://other.example/3"},
+	}
+	local := filepath.Join(t.TempDir(), "layer")
+	if err := os.WriteFile(local, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := &uriFetcher{
+		ok: map[string]bool{
+			regular.URI:                 true,
+			"https://allowed.example/2": true,
+			"https://other.example/3":   true,
+		},
+		local: local,
+	}
+	c := New(&indexer.Opts{
+		Store:       memory.NewStore(),
+		Fetcher:     f,
+		Vscnrs:      indexer.VersionedScanners{stubScanner{}},
+		ForeignURLs: []string{"https://allowed.example/"},
+	})
+	c.manifest = &claircore.Manifest{
+		Hash:   mk(0),
+		Layers: []*claircore.Layer{regular, allowed, denied},
+	}
+
+	next, err := fetchLayers(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != ScanLayers {
+		t.Errorf("got: %v, want: %v", next, ScanLayers)
+	}
+	wantTried := []string{
+		regular.URI,
+		"https://registry.example/v2/r/blobs/2",
+		"https://allowed.example/2",
+	}
+	if !cmp.Equal(f.tried, wantTried) {
+		t.Error(cmp.Diff(f.tried, wantTried))
+	}
+	if !cmp.Equal(f.headers[allowed.URI], auth) {
+		t.Errorf("registry headers not sent: %v", f.headers[allowed.URI])
+	}
+	if h := f.headers["https://allowed.example/2"]; h != nil {
+		t.Errorf("registry headers sent to foreign URL: %v", h)
+	}
+	if got, want := allowed.URI, "https://registry.example/v2/r/blobs/2"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if !allowed.Fetched() {
+		t.Error("foreign layer not fetched")
+	}
+
+	if got, want := len(c.report.Warnings), 1; got != want {
+		t.Fatalf("got: %d warnings, want: %d", got, want)
+	}
+	w := c.report.Warnings[0]
+	if w.Kind != claircore.WarnForeignLayerSkipped || w.Layer == nil || w.Layer.String() != denied.Hash.String() {
+		t.Errorf("unexpected warning: %+v", w)
+	}
+	var got []string
+	for _, l := range c.layers() {
+		got = append(got, l.Hash.String())
+	}
+	want := []string{regular.Hash.String(), allowed.Hash.String()}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestForeignAllowed(t *testing.T) {
+	allow := []string{"https://mcr.microsoft.com", "https://example.com/layers/"}
+	tt := []struct {
+		URL  string
+		Want bool
+	}{
+		{URL: "https://mcr.microsoft.com/v2/blobs/1", Want: true},
+		{URL: "https://MCR.microsoft.com/v2/blobs/1", Want: true},
+		{URL: "https://mcr.microsoft.com", Want: true},
+		{URL: "https://mcr.microsoft.com.evil.example/v2/blobs/1", Want: false},
+		{URL: "https://mcr.microsoft.com:8443/v2/blobs/1", Want: false},
+		{URL: "https://user@mcr.microsoft.com/v2/blobs/1", Want: false},
+		{URL: "http://mcr.microsoft.com/v2/blobs/1", Want: false},
+		{URL: "https://example.com/layers/1", Want: true},
+		{URL: "https://example.com/layers", Want: true},
+		{URL: "https://example.com/layers-other/1", Want: false},
+		{URL: "https://example.com/layers/../secret", Want: false},
+		{URL: "https://example.com/", Want: false},
+	}
+	for _, tc := range tt {
+		if got := foreignAllowed(allow, tc.URL); got != tc.Want {
+			t.Errorf("%s: got: %v, want: %v", tc.URL, got, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/heuristics"
+)
+
+// CheckHeuristics runs the image-level heuristics on the layers and records
+// their warnings in the report.
+//
+// The checks only explain gaps in the report, so failing to run them doesn't
+// fail the index.
+func checkHeuristics(ctx context.Context, s *Controller) {
+	if !s.Heuristics {
+		return
+	}
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/indexer/controller/checkHeuristics"))
+	ws, err := heuristics.Check(ctx, s.layers())
+	if err != nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Msg("unable to run heuristics")
+		return
+	}
+	s.report.Warnings = append(s.report.Warnings, ws...)
+}
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// AttributeHistory records the instruction that created the layer each
+// package was introduced in, if the manifest's history is known.
+func attributeHistory(ctx context.Context, s *Controller) {
+	if len(s.manifest.History) == 0 {
+		return
+	}
+	cmds := claircore.LayerInstructions(s.manifest)
+	if cmds == nil {
+		zlog.Debug(ctx).
+			Int("history", len(s.manifest.History)).
+			Int("layers", len(s.manifest.Layers)).
+			Msg("image history doesn't match layers, not attributing packages")
+		return
+	}
+	for _, envs := range s.report.Environments {
+		for _, env := range envs {
+			if c, ok := cmds[env.IntroducedIn.String()]; ok {
+				env.IntroducedBy = c
+			}
+		}
+	}
+}
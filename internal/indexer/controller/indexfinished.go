@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/internal/indexer"
 )
 
 // indexFinished is the terminal stateFunc. once it transitions the
@@ -12,12 +14,22 @@ import (
 // and return an IndexReport to the caller
 func indexFinished(ctx context.Context, s *Controller) (State, error) {
 	s.report.Success = true
+	s.report.Sort()
 	zlog.Info(ctx).Msg("finishing scan")
 
 	err := s.Store.SetIndexFinished(ctx, s.report, s.Vscnrs)
 	if err != nil {
 		return Terminal, fmt.Errorf("failed finish scan: %w", err)
 	}
+	if cp, ok := s.Store.(indexer.Checkpointer); ok {
+		if err := cp.ClearCheckpoints(ctx, s.manifest.Hash); err != nil {
+			// Leftover checkpoints only cause a later re-index to skip
+			// layers that are already fully scanned, so this isn't fatal.
+			zlog.Warn(ctx).
+				Err(err).
+				Msg("failed to clear layer checkpoints")
+		}
+	}
 
 	zlog.Info(ctx).Msg("manifest successfully scanned")
 	return Terminal, nil
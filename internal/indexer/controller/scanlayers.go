@@ -12,10 +12,13 @@ import (
 func scanLayers(ctx context.Context, c *Controller) (State, error) {
 	zlog.Info(ctx).Msg("layers scan start")
 	defer zlog.Info(ctx).Msg("layers scan done")
-	err := c.LayerScanner.Scan(ctx, c.manifest.Hash, c.manifest.Layers)
+	err := c.LayerScanner.Scan(ctx, c.manifest.Hash, c.layers())
 	if err != nil {
 		return Terminal, fmt.Errorf("failed to scan all layer contents: %w", err)
 	}
+	if err := scanContent(ctx, c); err != nil {
+		return Terminal, fmt.Errorf("failed to scan layer contents for signatures: %w", err)
+	}
 	zlog.Debug(ctx).Msg("layers scan ok")
 	return Coalesce, nil
 }
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"path"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Whiteouts returns the paths the layer removes from the layers below it,
+// using OCI whiteout entries: ".wh." files and opaque directories.
+//
+// An opaque directory is reported as removing the directory itself, as
+// everything in it from lower layers is hidden.
+func whiteouts(l *claircore.Layer) ([]string, error) {
+	idx, err := l.Index()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, n := range idx.Names() {
+		dir, base := path.Split(n)
+		if !strings.HasPrefix(base, ".wh.") {
+			continue
+		}
+		rm := path.Join(dir, strings.TrimPrefix(base, ".wh."))
+		if base == ".wh..wh..opq" {
+			rm = path.Clean(dir)
+		}
+		if rm == "." {
+			// An opaque root would hide every lower layer, which no builder
+			// produces.
+			continue
+		}
+		out = append(out, rm)
+	}
+	return out, nil
+}
+
+// LayerWhiteouts returns the whiteouts of every layer but the lowest, keyed
+// by the layer's digest. Skipped foreign layers have none.
+func layerWhiteouts(s *Controller) (map[string][]string, error) {
+	out := make(map[string][]string)
+	ls := s.layers()
+	if len(ls) < 2 {
+		return out, nil
+	}
+	for _, l := range ls[1:] {
+		rm, err := whiteouts(l)
+		if err != nil {
+			return nil, err
+		}
+		if len(rm) != 0 {
+			out[l.Hash.String()] = rm
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
+)
+
+func TestWhiteouts(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := fixture.Layer{
+		fixture.Whiteout("var/lib/dpkg/status"),
+		{Name: "./lib/apk/.wh..wh..opq"},
+		{Name: "etc/os-release", Body: "ID=test\n"},
+		fixture.Whiteout("opt"),
+		fixture.Opaque("."),
+	}
+	if _, err := layer.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var l claircore.Layer
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := whiteouts(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"opt", "lib/apk", "var/lib/dpkg/status"}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
@@ -1,12 +1,6 @@
 package indexer
 
-import (
-	"context"
+import "github.com/quay/claircore/libindex/driver"
 
-	"github.com/quay/claircore"
-)
-
-type DistributionScanner interface {
-	VersionedScanner
-	Scan(context.Context, *claircore.Layer) ([]*claircore.Distribution, error)
-}
+// DistributionScanner is an alias for driver.DistributionScanner.
+type DistributionScanner = driver.DistributionScanner
@@ -6,21 +6,12 @@ import (
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/libindex/driver"
 )
 
-// Ecosystems group together scanners and a Coalescer which are commonly used together.
-//
-// A typical ecosystem is "DPKG" which will use the DPKG package indexer, the "OS-Release"
-// distribution scanner and the "APT" repository scanner.
-//
-// A Controller will scan layers with all scanners present in its configured ecosystems.
-type Ecosystem struct {
-	Name                 string
-	PackageScanners      func(ctx context.Context) ([]PackageScanner, error)
-	DistributionScanners func(ctx context.Context) ([]DistributionScanner, error)
-	RepositoryScanners   func(ctx context.Context) ([]RepositoryScanner, error)
-	Coalescer            func(ctx context.Context) (Coalescer, error)
-}
+// Ecosystem is an alias for driver.Ecosystem.
+type Ecosystem = driver.Ecosystem
 
 // EcosystemsToScanners extracts and dedupes multiple ecosystems and returns their discrete scanners
 func EcosystemsToScanners(ctx context.Context, ecosystems []*Ecosystem, disallowRemote bool) ([]PackageScanner, []DistributionScanner, []RepositoryScanner, error) {
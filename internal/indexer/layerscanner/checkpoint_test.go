@@ -0,0 +1,178 @@
+package layerscanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/memory"
+	"github.com/quay/claircore/test"
+)
+
+// FlakyScanner fails the first time it sees the layer "fail", and counts the
+// number of times it sees each layer.
+//
+// The failure is delayed until another layer has been seen, so that the
+// other layer is guaranteed to be in progress.
+type flakyScanner struct {
+	sync.Mutex
+	fail   claircore.Digest
+	failed bool
+	seen   map[string]int
+	other  chan struct{}
+	once   sync.Once
+}
+
+func (*flakyScanner) Name() string    { return "flaky" }
+func (*flakyScanner) Version() string { return "1" }
+func (*flakyScanner) Kind() string    { return "package" }
+func (s *flakyScanner) Scan(_ context.Context, l *claircore.Layer) ([]*claircore.Package, error) {
+	s.Lock()
+	s.seen[l.Hash.String()]++
+	fail := l.Hash.String() == s.fail.String() && !s.failed
+	s.failed = s.failed || fail
+	s.Unlock()
+	if fail {
+		<-s.other
+		return nil, errors.New("interrupted")
+	}
+	s.once.Do(func() { close(s.other) })
+	return []*claircore.Package{}, nil
+}
+
+// CountingScanner counts the number of times it sees each layer.
+type countingScanner struct {
+	sync.Mutex
+	seen map[string]int
+}
+
+func (*countingScanner) Name() string    { return "counting" }
+func (*countingScanner) Version() string { return "1" }
+func (*countingScanner) Kind() string    { return "package" }
+func (s *countingScanner) Scan(_ context.Context, l *claircore.Layer) ([]*claircore.Package, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.seen[l.Hash.String()]++
+	return []*claircore.Package{}, nil
+}
+
+// TestEcosystem returns an Ecosystem with the package scanners.
+func testEcosystem(ps ...indexer.PackageScanner) *indexer.Ecosystem {
+	return &indexer.Ecosystem{
+		Name: "test-ecosystem",
+		PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+			return ps, nil
+		},
+		DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+		RepositoryScanners:   func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+	}
+}
+
+// TestCheckpointResume confirms that layers checkpointed by an interrupted
+// Scan are not scanned again.
+func TestCheckpointResume(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	_, layers := test.ServeLayers(t, 2)
+	m, err := claircore.NewDigest("sha256", make([]byte, sha256.Size))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &flakyScanner{fail: layers[1].Hash, seen: make(map[string]int), other: make(chan struct{})}
+	store := memory.NewStore()
+	if err := store.RegisterScanners(ctx, indexer.VersionedScanners{s}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.PersistManifest(ctx, claircore.Manifest{Hash: m, Layers: layers}); err != nil {
+		t.Fatal(err)
+	}
+	ls, err := New(ctx, len(layers), &indexer.Opts{
+		Store:      store,
+		Ecosystems: []*indexer.Ecosystem{testEcosystem(s)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ls.Scan(ctx, m, layers); err == nil {
+		t.Fatal("expected error from first scan")
+	}
+	cps, err := store.LayerCheckpoints(ctx, m, indexer.VersionedScanners{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(cps), 1; got != want {
+		t.Fatalf("got: %d checkpoints, want: %d", got, want)
+	}
+	if err := ls.Scan(ctx, m, layers); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range layers {
+		want := 1
+		if l.Hash.String() == s.fail.String() {
+			want = 2
+		}
+		if got := s.seen[l.Hash.String()]; got != want {
+			t.Errorf("%v: got: %d scans, want: %d", l.Hash, got, want)
+		}
+	}
+}
+
+// TestCheckpointScanners confirms that checkpoints recorded by one set of
+// scanners don't cause layers to be skipped by another.
+func TestCheckpointScanners(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	_, layers := test.ServeLayers(t, 2)
+	m, err := claircore.NewDigest("sha256", make([]byte, sha256.Size))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &flakyScanner{fail: layers[1].Hash, seen: make(map[string]int), other: make(chan struct{})}
+	c := &countingScanner{seen: make(map[string]int)}
+	store := memory.NewStore()
+	if err := store.RegisterScanners(ctx, indexer.VersionedScanners{s, c}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.PersistManifest(ctx, claircore.Manifest{Hash: m, Layers: layers}); err != nil {
+		t.Fatal(err)
+	}
+	ls, err := New(ctx, len(layers), &indexer.Opts{
+		Store:      store,
+		Ecosystems: []*indexer.Ecosystem{testEcosystem(s)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.Scan(ctx, m, layers); err == nil {
+		t.Fatal("expected error from first scan")
+	}
+
+	// Resume with a scanner added: the checkpointed layer still needs
+	// scanning by it.
+	ls, err = New(ctx, len(layers), &indexer.Opts{
+		Store:      store,
+		Ecosystems: []*indexer.Ecosystem{testEcosystem(s, c)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.Scan(ctx, m, layers); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range layers {
+		if got, want := c.seen[l.Hash.String()], 1; got != want {
+			t.Errorf("%v: got: %d scans, want: %d", l.Hash, got, want)
+		}
+	}
+}
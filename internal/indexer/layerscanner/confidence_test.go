@@ -0,0 +1,108 @@
+package layerscanner
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/memory"
+)
+
+// ReportingScanner reports a declared confidence, and one package that
+// overrides it.
+type reportingScanner struct{}
+
+var _ indexer.ConfidenceReporter = reportingScanner{}
+
+func (reportingScanner) Name() string    { return "reporting" }
+func (reportingScanner) Version() string { return "1" }
+func (reportingScanner) Kind() string    { return "package" }
+func (reportingScanner) Confidence() claircore.Confidence {
+	return claircore.ConfidenceDeclared
+}
+func (reportingScanner) Scan(context.Context, *claircore.Layer) ([]*claircore.Package, error) {
+	return []*claircore.Package{
+		{Name: "locked", Version: "1.0.0"},
+		{Name: "guessed", Version: "2.0.0", Confidence: claircore.ConfidenceHeuristic},
+	}, nil
+}
+
+// TestConfidence confirms a ConfidenceReporter's confidence is recorded for
+// the packages that don't set their own.
+func TestConfidence(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	dir := t.TempDir()
+	b := make([]byte, sha256.Size)
+	b[0] = 1
+	d, err := claircore.NewDigest("sha256", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "layer")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tar.NewWriter(f).Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	l := &claircore.Layer{Hash: d}
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+	m, err := claircore.NewDigest("sha256", make([]byte, sha256.Size))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := reportingScanner{}
+	store := memory.NewStore()
+	if err := store.RegisterScanners(ctx, indexer.VersionedScanners{s}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.PersistManifest(ctx, claircore.Manifest{Hash: m, Layers: []*claircore.Layer{l}}); err != nil {
+		t.Fatal(err)
+	}
+	ls, err := New(ctx, 1, &indexer.Opts{
+		Store: store,
+		Ecosystems: []*indexer.Ecosystem{{
+			Name: "test-ecosystem",
+			PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+				return []indexer.PackageScanner{s}, nil
+			},
+			DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+			RepositoryScanners:   func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.Scan(ctx, m, []*claircore.Layer{l}); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := store.PackagesByLayer(ctx, d, indexer.VersionedScanners{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]claircore.Confidence{
+		"locked":  claircore.ConfidenceDeclared,
+		"guessed": claircore.ConfidenceHeuristic,
+	}
+	if got, want := len(pkgs), len(want); got != want {
+		t.Fatalf("got: %d packages, want: %d", got, want)
+	}
+	for _, p := range pkgs {
+		if got, want := p.Confidence, want[p.Name]; got != want {
+			t.Errorf("%s: got: %q, want: %q", p.Name, got, want)
+		}
+	}
+}
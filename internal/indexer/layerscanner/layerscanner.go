@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"sync/atomic"
 
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
@@ -26,6 +27,8 @@ type layerScanner struct {
 	ps []indexer.PackageScanner
 	ds []indexer.DistributionScanner
 	rs []indexer.RepositoryScanner
+	// All the above scanners, which layer checkpoints are recorded for.
+	vscnrs indexer.VersionedScanners
 }
 
 // New is the constructor for a LayerScanner.
@@ -76,12 +79,24 @@ func New(ctx context.Context, concurrent int, opts *indexer.Opts) (indexer.Layer
 	}
 	ds = ds[:i]
 
+	vscnrs := make(indexer.VersionedScanners, 0, len(ps)+len(ds)+len(rs))
+	for _, s := range ps {
+		vscnrs = append(vscnrs, s)
+	}
+	for _, s := range ds {
+		vscnrs = append(vscnrs, s)
+	}
+	for _, s := range rs {
+		vscnrs = append(vscnrs, s)
+	}
+
 	return &layerScanner{
 		store:    opts.Store,
 		inflight: int64(concurrent),
 		ps:       ps,
 		ds:       ds,
 		rs:       rs,
+		vscnrs:   vscnrs,
 	}, nil
 }
 
@@ -146,6 +161,11 @@ func configAndFilter(ctx context.Context, opts *indexer.Opts, s indexer.Versione
 //
 // The provided Context controls cancellation for all scanners. The first error
 // reported halts all work and is returned from Scan.
+//
+// If the configured Store is an indexer.Checkpointer, a checkpoint is recorded
+// for each layer once every scanner has completed against it, and layers with
+// an existing checkpoint for the manifest and scanners are skipped. This is
+// the only place checkpoints are consulted.
 func (ls *layerScanner) Scan(ctx context.Context, manifest claircore.Digest, layers []*claircore.Layer) error {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/indexer/layerscannner/layerScanner.Scan"),
@@ -153,6 +173,23 @@ func (ls *layerScanner) Scan(ctx context.Context, manifest claircore.Digest, lay
 
 	layersToScan := make([]*claircore.Layer, 0, len(layers))
 	dedupe := map[string]struct{}{}
+	// If the store supports checkpoints, layers completed by a previous,
+	// interrupted call are skipped entirely.
+	cp, _ := ls.store.(indexer.Checkpointer)
+	if cp != nil {
+		done, err := cp.LayerCheckpoints(ctx, manifest, ls.vscnrs)
+		if err != nil {
+			return fmt.Errorf("unable to load layer checkpoints: %w", err)
+		}
+		for _, d := range done {
+			dedupe[d.String()] = struct{}{}
+		}
+		if len(done) != 0 {
+			zlog.Info(ctx).
+				Int("count", len(done)).
+				Msg("resuming from layer checkpoints")
+		}
+	}
 	for _, layer := range layers {
 		if _, ok := dedupe[layer.Hash.String()]; !ok {
 			layersToScan = append(layersToScan, layer)
@@ -160,6 +197,15 @@ func (ls *layerScanner) Scan(ctx context.Context, manifest claircore.Digest, lay
 		}
 	}
 
+	// Remaining tracks the number of outstanding scanners per layer, so that
+	// a checkpoint can be written once the last one finishes.
+	n := int32(len(ls.vscnrs))
+	remaining := make(map[*claircore.Layer]*int32, len(layersToScan))
+	for _, l := range layersToScan {
+		ct := n
+		remaining[l] = &ct
+	}
+
 	sem := semaphore.NewWeighted(ls.inflight)
 	g, ctx := errgroup.WithContext(ctx)
 	// Launch is a closure to capture the loop variables and then call the
@@ -170,7 +216,15 @@ func (ls *layerScanner) Scan(ctx context.Context, manifest claircore.Digest, lay
 				return err
 			}
 			defer sem.Release(1)
-			return ls.scanLayer(ctx, l, s)
+			if err := ls.scanLayer(ctx, l, s); err != nil {
+				return err
+			}
+			if cp != nil && atomic.AddInt32(remaining[l], -1) == 0 {
+				if err := cp.SetLayerCheckpoint(ctx, manifest, l.Hash, ls.vscnrs); err != nil {
+					return fmt.Errorf("unable to set layer checkpoint: %w", err)
+				}
+			}
+			return nil
 		}
 	}
 	for _, l := range layersToScan {
@@ -208,16 +262,55 @@ func (ls *layerScanner) scanLayer(ctx context.Context, l *claircore.Layer, s ind
 		return nil
 	}
 
+	// Scanners that declare their paths don't need to see layers lacking all
+	// of them. The layer's still marked as scanned, so the skip is
+	// remembered like an empty result.
+	if pd, ok := s.(indexer.PathDeclarer); ok {
+		ok, err := l.Contains(pd.Paths()...)
+		if err != nil {
+			return fmt.Errorf("unable to check declared paths: %w", err)
+		}
+		if !ok {
+			zlog.Debug(ctx).Msg("no declared paths in layer, skipping")
+			if err = ls.store.SetLayerScanned(ctx, l.Hash, s); err != nil {
+				return fmt.Errorf("could not set layer scanned: %v", l)
+			}
+			return nil
+		}
+	}
+
+	sl := l
+	if fl, ok := s.(indexer.FileLimiter); ok {
+		max, truncate := fl.FileLimit()
+		sl = l.Limited(claircore.FileLimit{
+			Max:      max,
+			Truncate: truncate,
+			OnLimit: func(name string, size int64) {
+				zlog.Warn(ctx).
+					Str("file", name).
+					Int64("size", size).
+					Int64("limit", max).
+					Bool("truncated", truncate).
+					Msg("file exceeds scanner read limit")
+			},
+		})
+	}
+
 	var result result
-	if err := result.Do(ctx, s, l); err != nil {
+	if err := result.Do(ctx, s, sl); err != nil {
+		return err
+	}
+
+	// Index the results before marking the layer scanned, so that an
+	// interruption between the two causes a re-scan rather than lost results.
+	if err := result.Store(ctx, ls.store, s, l); err != nil {
 		return err
 	}
 
 	if err = ls.store.SetLayerScanned(ctx, l.Hash, s); err != nil {
 		return fmt.Errorf("could not set layer scanned: %v", l)
 	}
-
-	return result.Store(ctx, ls.store, s, l)
+	return nil
 }
 
 // Result is a type that handles the kind-specific bits of the scan process.
@@ -235,6 +328,14 @@ func (r *result) Do(ctx context.Context, s indexer.VersionedScanner, l *claircor
 	switch s := s.(type) {
 	case indexer.PackageScanner:
 		r.pkgs, err = s.Scan(ctx, l)
+		if cr, ok := s.(indexer.ConfidenceReporter); ok {
+			c := cr.Confidence()
+			for _, p := range r.pkgs {
+				if p != nil && p.Confidence == "" {
+					p.Confidence = c
+				}
+			}
+		}
 	case indexer.DistributionScanner:
 		r.dists, err = s.Scan(ctx, l)
 	case indexer.RepositoryScanner:
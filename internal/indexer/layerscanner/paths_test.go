@@ -0,0 +1,123 @@
+package layerscanner
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/memory"
+)
+
+// DeclaringScanner declares a single path and counts the layers it's asked
+// to scan.
+type declaringScanner struct {
+	sync.Mutex
+	seen map[string]int
+}
+
+var _ indexer.PathDeclarer = (*declaringScanner)(nil)
+
+func (*declaringScanner) Name() string    { return "declaring" }
+func (*declaringScanner) Version() string { return "1" }
+func (*declaringScanner) Kind() string    { return "distribution" }
+func (*declaringScanner) Paths() []string { return []string{"etc/os-release"} }
+func (s *declaringScanner) Scan(_ context.Context, l *claircore.Layer) ([]*claircore.Distribution, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.seen[l.Hash.String()]++
+	return []*claircore.Distribution{}, nil
+}
+
+// TestDeclaredPaths confirms a scanner isn't called on a layer without any of
+// its declared paths, and that the layer is still marked as scanned.
+func TestDeclaredPaths(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	dir := t.TempDir()
+	mk := func(n byte, names ...string) *claircore.Layer {
+		t.Helper()
+		b := make([]byte, sha256.Size)
+		b[0] = n
+		d, err := claircore.NewDigest("sha256", b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(dir, d.String())
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		w := tar.NewWriter(f)
+		for _, n := range names {
+			if err := w.WriteHeader(&tar.Header{Name: n, Typeflag: tar.TypeReg}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		l := &claircore.Layer{Hash: d}
+		if err := l.SetLocal(name); err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	layers := []*claircore.Layer{
+		mk(1, "etc/os-release"),
+		mk(2, "usr/bin/true"),
+	}
+	m, err := claircore.NewDigest("sha256", make([]byte, sha256.Size))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &declaringScanner{seen: make(map[string]int)}
+	store := memory.NewStore()
+	if err := store.RegisterScanners(ctx, indexer.VersionedScanners{s}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.PersistManifest(ctx, claircore.Manifest{Hash: m, Layers: layers}); err != nil {
+		t.Fatal(err)
+	}
+	ls, err := New(ctx, len(layers), &indexer.Opts{
+		Store: store,
+		Ecosystems: []*indexer.Ecosystem{{
+			Name:            "test-ecosystem",
+			PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) { return nil, nil },
+			DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) {
+				return []indexer.DistributionScanner{s}, nil
+			},
+			RepositoryScanners: func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.Scan(ctx, m, layers); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []int{1, 0} {
+		l := layers[i]
+		if got := s.seen[l.Hash.String()]; got != want {
+			t.Errorf("%v: got: %d scans, want: %d", l.Hash, got, want)
+		}
+		ok, err := store.LayerScanned(ctx, l.Hash, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("%v: not marked as scanned", l.Hash)
+		}
+	}
+}
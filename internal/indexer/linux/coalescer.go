@@ -29,7 +29,11 @@ func NewCoalescer() *Coalescer {
 // Coalesce coalesces artifacts found in layers and creates an IndexReport with
 // the final package details found in the image. This method blocks and when its finished
 // the c.ir field will hold the final IndexReport
+//
+// Packages whose database was removed by a higher layer, as recorded in the
+// layer's Removed paths, aren't reported.
 func (c *Coalescer) Coalesce(ctx context.Context, layerArtifacts []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	layerArtifacts = applyWhiteouts(layerArtifacts)
 	distSearcher := NewDistSearcher(layerArtifacts)
 	packageSearcher := NewPackageSearcher(layerArtifacts)
 
@@ -86,3 +86,70 @@ func TestCoalescer(t *testing.T) {
 		}
 	}
 }
+
+func TestCoalescerWhiteout(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	pkg := func(id, db string) *claircore.Package {
+		return &claircore.Package{ID: id, Name: "pkg-" + id, Version: "1", PackageDB: db}
+	}
+	// The databases are reported the way the package scanners do: the rpm
+	// scanner uses absolute paths, the others paths relative to the root.
+	layers := []*indexer.LayerArtifacts{
+		{
+			Hash: test.RandomSHA256Digest(t),
+			Pkgs: []*claircore.Package{
+				pkg("0", "var/lib/dpkg/status"),
+				pkg("1", "lib/apk/db/installed"),
+				pkg("2", "/var/lib/rpm"),
+				pkg("3", "/usr/lib/sysimage/rpm"),
+			},
+		},
+		{
+			// Removes the dpkg database, the apk database's directory, and
+			// an rpm database. The whiteouts in the same layer as a database
+			// don't apply to it.
+			Hash: test.RandomSHA256Digest(t),
+			Pkgs: []*claircore.Package{
+				pkg("4", "var/lib/dpkg/status"),
+			},
+			Removed: []string{
+				"var/lib/dpkg/status",
+				"lib/apk/db",
+				"usr/lib/sysimage/rpm",
+				"var/lib/rpm-backup",
+			},
+		},
+		{
+			// Hides the new dpkg database with an opaque directory.
+			Hash:    test.RandomSHA256Digest(t),
+			Removed: []string{"var/lib/dpkg"},
+		},
+		{
+			Hash: test.RandomSHA256Digest(t),
+			Pkgs: []*claircore.Package{pkg("5", "var/lib/dpkg/status")},
+		},
+	}
+	ir, err := NewCoalescer().Coalesce(ctx, layers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]claircore.Digest{
+		"2": layers[0].Hash,
+		"5": layers[3].Hash,
+	}
+	if got, want := len(ir.Packages), len(want); got != want {
+		t.Errorf("got: %d packages, want: %d", got, want)
+	}
+	for id, d := range want {
+		if _, ok := ir.Packages[id]; !ok {
+			t.Errorf("missing package %q", id)
+			continue
+		}
+		if got := ir.Environments[id][0].IntroducedIn; got.String() != d.String() {
+			t.Errorf("%s: got: %v, want: %v", id, got, d)
+		}
+	}
+	if n := len(layers[0].Pkgs); n != 4 {
+		t.Errorf("artifacts modified: %d packages", n)
+	}
+}
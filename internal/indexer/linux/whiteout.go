@@ -0,0 +1,61 @@
+package linux
+
+import (
+	"path"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// ApplyWhiteouts returns the artifacts without every package whose database
+// was removed by a higher layer. The provided artifacts aren't modified.
+func applyWhiteouts(ls []*indexer.LayerArtifacts) []*indexer.LayerArtifacts {
+	out := make([]*indexer.LayerArtifacts, len(ls))
+	var removed []string
+	for i := len(ls) - 1; i >= 0; i-- {
+		if len(removed) == 0 {
+			out[i] = ls[i]
+		} else {
+			l := *ls[i]
+			l.Pkgs = make([]*claircore.Package, 0, len(ls[i].Pkgs))
+			for _, p := range ls[i].Pkgs {
+				if !removes(removed, p.PackageDB) {
+					l.Pkgs = append(l.Pkgs, p)
+				}
+			}
+			out[i] = &l
+		}
+		// Whiteouts apply only to lower layers.
+		for _, rm := range ls[i].Removed {
+			if rm := cleanPath(rm); rm != "" {
+				removed = append(removed, rm)
+			}
+		}
+	}
+	return out
+}
+
+// Removes reports whether any of the removed paths is the database or one of
+// its parent directories.
+//
+// Package scanners report databases both relative to the layer root and as
+// absolute paths, so the database is cleaned before comparing.
+func removes(removed []string, db string) bool {
+	if db == "" {
+		return false
+	}
+	db = cleanPath(db)
+	for _, rm := range removed {
+		if db == rm || strings.HasPrefix(db, rm+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CleanPath returns the path relative to the layer root, without a leading
+// "/" or "./".
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
@@ -0,0 +1,625 @@
+// Package memory provides an indexer.Store that keeps all state in process
+// memory.
+//
+// It's intended for ephemeral use, such as one-shot CLI scans and tests, where
+// persisting anything is undesirable.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/omnimatcher"
+)
+
+var (
+	_ indexer.Store        = (*Store)(nil)
+	_ indexer.Checkpointer = (*Store)(nil)
+	_ indexer.StaleFinder  = (*Store)(nil)
+	_ indexer.SourceFinder = (*Store)(nil)
+	_ indexer.LayerLister  = (*Store)(nil)
+)
+
+// Store is an in-memory indexer.Store.
+//
+// Store is safe for concurrent use. The zero value is not usable; use NewStore.
+type Store struct {
+	mu sync.RWMutex
+
+	// Scanners is a set of registered scanner keys.
+	scanners map[string]struct{}
+	// Manifests is a map of manifest digest to manifest state.
+	manifests map[string]*manifest
+	// Layers is a map of layer digest to layer state.
+	layers map[string]*layer
+
+	// The intern tables assign stable IDs to identical artifacts, as the
+	// coalescers rely on IDs to correlate artifacts across layers.
+	pkgID, distID, repoID map[string]string
+	next                  int64
+}
+
+type manifest struct {
+	layers  []claircore.Digest
+	scanned map[string]struct{}
+	// Checkpoints is the set of layers completely scanned during an
+	// in-progress Index operation, keyed by indexer.CheckpointKey and then
+	// by layer.
+	checkpoints map[string]map[string]claircore.Digest
+	report      []byte
+	// Index holds the records of the coalesced report, for AffectedManifests.
+	index []claircore.IndexRecord
+}
+
+type layer struct {
+	scanned map[string]struct{}
+	pkgs    map[string][]*claircore.Package
+	dists   map[string][]*claircore.Distribution
+	repos   map[string][]*claircore.Repository
+}
+
+func newLayer() *layer {
+	return &layer{
+		scanned: make(map[string]struct{}),
+		pkgs:    make(map[string][]*claircore.Package),
+		dists:   make(map[string][]*claircore.Distribution),
+		repos:   make(map[string][]*claircore.Repository),
+	}
+}
+
+// NewStore returns a ready-to-use Store.
+func NewStore() *Store {
+	return &Store{
+		scanners:  make(map[string]struct{}),
+		manifests: make(map[string]*manifest),
+		layers:    make(map[string]*layer),
+		pkgID:     make(map[string]string),
+		distID:    make(map[string]string),
+		repoID:    make(map[string]string),
+	}
+}
+
+// ScannerKey returns the key used to identify a scanner.
+func scannerKey(s indexer.VersionedScanner) string {
+	return s.Kind() + "\x00" + s.Name() + "\x00" + s.Version()
+}
+
+func (s *Store) id() string {
+	s.next++
+	return strconv.FormatInt(s.next, 10)
+}
+
+// Close implements indexer.Store.
+func (s *Store) Close(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.manifests = make(map[string]*manifest)
+	s.layers = make(map[string]*layer)
+	return nil
+}
+
+// PersistManifest implements indexer.Setter.
+func (s *Store) PersistManifest(_ context.Context, m claircore.Manifest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mf, ok := s.manifests[m.Hash.String()]
+	if !ok {
+		mf = &manifest{scanned: make(map[string]struct{})}
+		s.manifests[m.Hash.String()] = mf
+	}
+	mf.layers = mf.layers[:0]
+	for _, l := range m.Layers {
+		mf.layers = append(mf.layers, l.Hash)
+		if _, ok := s.layers[l.Hash.String()]; !ok {
+			s.layers[l.Hash.String()] = newLayer()
+		}
+	}
+	return nil
+}
+
+// DeleteManifests implements indexer.Setter.
+func (s *Store) DeleteManifests(_ context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rm := make([]claircore.Digest, 0, len(ds))
+	for _, d := range ds {
+		if _, ok := s.manifests[d.String()]; !ok {
+			continue
+		}
+		delete(s.manifests, d.String())
+		rm = append(rm, d)
+	}
+	// Remove any layers no longer referenced.
+	inUse := make(map[string]struct{})
+	for _, m := range s.manifests {
+		for _, l := range m.layers {
+			inUse[l.String()] = struct{}{}
+		}
+	}
+	for k := range s.layers {
+		if _, ok := inUse[k]; !ok {
+			delete(s.layers, k)
+		}
+	}
+	return rm, nil
+}
+
+// SetLayerScanned implements indexer.Setter.
+func (s *Store) SetLayerScanned(_ context.Context, hash claircore.Digest, scnr indexer.VersionedScanner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.layers[hash.String()]
+	if !ok {
+		l = newLayer()
+		s.layers[hash.String()] = l
+	}
+	l.scanned[scannerKey(scnr)] = struct{}{}
+	return nil
+}
+
+// RegisterScanners implements indexer.Setter.
+func (s *Store) RegisterScanners(_ context.Context, vs indexer.VersionedScanners) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range vs {
+		s.scanners[scannerKey(v)] = struct{}{}
+	}
+	return nil
+}
+
+// SetIndexReport implements indexer.Setter.
+func (s *Store) SetIndexReport(_ context.Context, ir *claircore.IndexReport) error {
+	b, err := json.Marshal(ir)
+	if err != nil {
+		return fmt.Errorf("memory: unable to encode index report: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mf, ok := s.manifests[ir.Hash.String()]
+	if !ok {
+		mf = &manifest{scanned: make(map[string]struct{})}
+		s.manifests[ir.Hash.String()] = mf
+	}
+	mf.report = b
+	return nil
+}
+
+// SetIndexFinished implements indexer.Setter.
+func (s *Store) SetIndexFinished(ctx context.Context, ir *claircore.IndexReport, vs indexer.VersionedScanners) error {
+	if err := s.SetIndexReport(ctx, ir); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mf := s.manifests[ir.Hash.String()]
+	for _, v := range vs {
+		mf.scanned[scannerKey(v)] = struct{}{}
+	}
+	return nil
+}
+
+// ManifestScanned implements indexer.Querier.
+func (s *Store) ManifestScanned(_ context.Context, hash claircore.Digest, vs indexer.VersionedScanners) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mf, ok := s.manifests[hash.String()]
+	if !ok {
+		return false, nil
+	}
+	for _, v := range vs {
+		if _, ok := mf.scanned[scannerKey(v)]; !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// StaleManifests implements indexer.StaleFinder.
+func (s *Store) StaleManifests(_ context.Context, vs indexer.VersionedScanners) ([]indexer.StaleManifest, error) {
+	want := make(map[string]struct{}, len(vs))
+	for _, v := range vs {
+		want[scannerKey(v)] = struct{}{}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []indexer.StaleManifest
+	for h, mf := range s.manifests {
+		if len(mf.scanned) == 0 {
+			// Not completely indexed.
+			continue
+		}
+		var m indexer.StaleManifest
+		for k := range mf.scanned {
+			if _, ok := want[k]; !ok {
+				m.Outdated = append(m.Outdated, scannerInfo(k))
+			}
+		}
+		for k := range want {
+			if _, ok := mf.scanned[k]; !ok {
+				m.Missing = append(m.Missing, scannerInfo(k))
+			}
+		}
+		if m.Missing == nil && m.Outdated == nil {
+			continue
+		}
+		d, err := claircore.ParseDigest(h)
+		if err != nil {
+			return nil, err
+		}
+		m.Manifest = d
+		out = append(out, m)
+	}
+	indexer.SortStaleManifests(out)
+	return out, nil
+}
+
+// ScannerInfo reverses scannerKey.
+func scannerInfo(k string) indexer.ScannerInfo {
+	f := strings.SplitN(k, "\x00", 3)
+	return indexer.ScannerInfo{Kind: f[0], Name: f[1], Version: f[2]}
+}
+
+// LayerScanned implements indexer.Querier.
+func (s *Store) LayerScanned(_ context.Context, hash claircore.Digest, scnr indexer.VersionedScanner) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l, ok := s.layers[hash.String()]
+	if !ok {
+		return false, nil
+	}
+	_, ok = l.scanned[scannerKey(scnr)]
+	return ok, nil
+}
+
+// ManifestLayers implements indexer.LayerLister.
+func (s *Store) ManifestLayers(_ context.Context, m claircore.Digest) ([]claircore.Digest, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mf, ok := s.manifests[m.String()]
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]claircore.Digest(nil), mf.layers...), true, nil
+}
+
+// SetLayerCheckpoint implements indexer.Checkpointer.
+func (s *Store) SetLayerCheckpoint(_ context.Context, m, l claircore.Digest, vs indexer.VersionedScanners) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mf, ok := s.manifests[m.String()]
+	if !ok {
+		return fmt.Errorf("memory: unknown manifest %q", m)
+	}
+	if mf.checkpoints == nil {
+		mf.checkpoints = make(map[string]map[string]claircore.Digest)
+	}
+	k := indexer.CheckpointKey(vs)
+	if mf.checkpoints[k] == nil {
+		mf.checkpoints[k] = make(map[string]claircore.Digest)
+	}
+	mf.checkpoints[k][l.String()] = l
+	return nil
+}
+
+// LayerCheckpoints implements indexer.Checkpointer.
+func (s *Store) LayerCheckpoints(_ context.Context, m claircore.Digest, vs indexer.VersionedScanners) ([]claircore.Digest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mf, ok := s.manifests[m.String()]
+	if !ok {
+		return nil, nil
+	}
+	cps := mf.checkpoints[indexer.CheckpointKey(vs)]
+	out := make([]claircore.Digest, 0, len(cps))
+	for _, d := range cps {
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// ClearCheckpoints implements indexer.Checkpointer.
+func (s *Store) ClearCheckpoints(_ context.Context, m claircore.Digest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if mf, ok := s.manifests[m.String()]; ok {
+		mf.checkpoints = nil
+	}
+	return nil
+}
+
+// PackagesByLayer implements indexer.Querier.
+func (s *Store) PackagesByLayer(_ context.Context, hash claircore.Digest, vs indexer.VersionedScanners) ([]*claircore.Package, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*claircore.Package{}
+	l, ok := s.layers[hash.String()]
+	if !ok {
+		return out, nil
+	}
+	for _, v := range vs {
+		for _, p := range l.pkgs[scannerKey(v)] {
+			c := *p
+			if p.Source != nil {
+				src := *p.Source
+				c.Source = &src
+			}
+			out = append(out, &c)
+		}
+	}
+	return out, nil
+}
+
+// DistributionsByLayer implements indexer.Querier.
+func (s *Store) DistributionsByLayer(_ context.Context, hash claircore.Digest, vs indexer.VersionedScanners) ([]*claircore.Distribution, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*claircore.Distribution{}
+	l, ok := s.layers[hash.String()]
+	if !ok {
+		return out, nil
+	}
+	for _, v := range vs {
+		for _, d := range l.dists[scannerKey(v)] {
+			c := *d
+			out = append(out, &c)
+		}
+	}
+	return out, nil
+}
+
+// RepositoriesByLayer implements indexer.Querier.
+func (s *Store) RepositoriesByLayer(_ context.Context, hash claircore.Digest, vs indexer.VersionedScanners) ([]*claircore.Repository, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*claircore.Repository{}
+	l, ok := s.layers[hash.String()]
+	if !ok {
+		return out, nil
+	}
+	for _, v := range vs {
+		for _, r := range l.repos[scannerKey(v)] {
+			c := *r
+			out = append(out, &c)
+		}
+	}
+	return out, nil
+}
+
+// IndexReport implements indexer.Querier.
+func (s *Store) IndexReport(_ context.Context, hash claircore.Digest) (*claircore.IndexReport, bool, error) {
+	s.mu.RLock()
+	mf, ok := s.manifests[hash.String()]
+	var b []byte
+	if ok {
+		b = mf.report
+	}
+	s.mu.RUnlock()
+	if b == nil {
+		return nil, false, nil
+	}
+	var ir claircore.IndexReport
+	if err := json.Unmarshal(b, &ir); err != nil {
+		return nil, false, fmt.Errorf("memory: unable to decode index report: %w", err)
+	}
+	return &ir, true, nil
+}
+
+// AffectedManifests implements indexer.Querier.
+func (s *Store) AffectedManifests(ctx context.Context, v claircore.Vulnerability) ([]claircore.Digest, error) {
+	if v.Package == nil {
+		return nil, nil
+	}
+	om := omnimatcher.New(nil)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []claircore.Digest{}
+	for k, mf := range s.manifests {
+		for i := range mf.index {
+			r := &mf.index[i]
+			if r.Package.Name != v.Package.Name {
+				continue
+			}
+			if v.Dist != nil && (r.Distribution == nil || !sameDist(v.Dist, r.Distribution)) {
+				continue
+			}
+			if v.Repo != nil && (r.Repository == nil || v.Repo.Name != r.Repository.Name) {
+				continue
+			}
+			ok, err := om.Vulnerable(ctx, r, &v)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				d, err := claircore.ParseDigest(k)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, d)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// BinariesBySource implements indexer.SourceFinder.
+func (s *Store) BinariesBySource(_ context.Context, name, version string) ([]indexer.SourceBinary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []indexer.SourceBinary
+	for k, mf := range s.manifests {
+		var d claircore.Digest
+		seen := make(map[string]struct{})
+		for i := range mf.index {
+			p := mf.index[i].Package
+			src := p.Source
+			if src == nil || src.Name != name || (version != "" && src.Version != version) {
+				continue
+			}
+			if _, ok := seen[p.ID]; ok {
+				continue
+			}
+			seen[p.ID] = struct{}{}
+			if len(seen) == 1 {
+				var err error
+				d, err = claircore.ParseDigest(k)
+				if err != nil {
+					return nil, err
+				}
+			}
+			out = append(out, indexer.SourceBinary{Manifest: d, Package: p})
+		}
+	}
+	indexer.SortSourceBinaries(out)
+	return out, nil
+}
+
+// SameDist reports whether the distribution described in a vulnerability
+// matches an indexed one, treating empty fields in the vulnerability as
+// wildcards.
+func sameDist(want, got *claircore.Distribution) bool {
+	eq := func(a, b string) bool { return a == "" || a == b }
+	return eq(want.DID, got.DID) &&
+		eq(want.Name, got.Name) &&
+		eq(want.Version, got.Version) &&
+		eq(want.VersionCodeName, got.VersionCodeName) &&
+		eq(want.VersionID, got.VersionID) &&
+		eq(want.Arch, got.Arch) &&
+		eq(want.PrettyName, got.PrettyName)
+}
+
+// IndexPackages implements indexer.Indexer.
+func (s *Store) IndexPackages(_ context.Context, pkgs []*claircore.Package, l *claircore.Layer, scnr indexer.VersionedScanner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ly, ok := s.layers[l.Hash.String()]
+	if !ok {
+		ly = newLayer()
+		s.layers[l.Hash.String()] = ly
+	}
+	k := scannerKey(scnr)
+	out := make([]*claircore.Package, 0, len(pkgs))
+	for _, p := range pkgs {
+		if p == nil {
+			continue
+		}
+		c := *p
+		c.ID = s.internPackage(p)
+		src := claircore.Package{}
+		if p.Source != nil {
+			src = *p.Source
+		}
+		src.ID = s.internPackage(&src)
+		c.Source = &src
+		out = append(out, &c)
+	}
+	ly.pkgs[k] = out
+	return nil
+}
+
+func (s *Store) internPackage(p *claircore.Package) string {
+	k := strings.Join([]string{
+		p.Name, p.Kind, p.Version, p.Module, p.Arch,
+		p.NormalizedVersion.String(),
+	}, "\x00")
+	if id, ok := s.pkgID[k]; ok {
+		return id
+	}
+	id := s.id()
+	s.pkgID[k] = id
+	return id
+}
+
+// IndexDistributions implements indexer.Indexer.
+func (s *Store) IndexDistributions(_ context.Context, dists []*claircore.Distribution, l *claircore.Layer, scnr indexer.VersionedScanner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ly, ok := s.layers[l.Hash.String()]
+	if !ok {
+		ly = newLayer()
+		s.layers[l.Hash.String()] = ly
+	}
+	out := make([]*claircore.Distribution, 0, len(dists))
+	for _, d := range dists {
+		if d == nil {
+			continue
+		}
+		c := *d
+		k := strings.Join([]string{
+			d.Name, d.DID, d.Version, d.VersionCodeName,
+			d.VersionID, d.Arch, d.CPE.BindFS(), d.PrettyName,
+		}, "\x00")
+		id, ok := s.distID[k]
+		if !ok {
+			id = s.id()
+			s.distID[k] = id
+		}
+		c.ID = id
+		out = append(out, &c)
+	}
+	ly.dists[scannerKey(scnr)] = out
+	return nil
+}
+
+// IndexRepositories implements indexer.Indexer.
+func (s *Store) IndexRepositories(_ context.Context, repos []*claircore.Repository, l *claircore.Layer, scnr indexer.VersionedScanner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ly, ok := s.layers[l.Hash.String()]
+	if !ok {
+		ly = newLayer()
+		s.layers[l.Hash.String()] = ly
+	}
+	out := make([]*claircore.Repository, 0, len(repos))
+	for _, r := range repos {
+		if r == nil {
+			continue
+		}
+		c := *r
+		k := strings.Join([]string{r.Name, r.Key, r.URI, r.CPE.BindFS()}, "\x00")
+		id, ok := s.repoID[k]
+		if !ok {
+			id = s.id()
+			s.repoID[k] = id
+		}
+		c.ID = id
+		out = append(out, &c)
+	}
+	ly.repos[scannerKey(scnr)] = out
+	return nil
+}
+
+// IndexManifest implements indexer.Indexer.
+func (s *Store) IndexManifest(_ context.Context, ir *claircore.IndexReport) error {
+	if ir.Hash.String() == "" {
+		return fmt.Errorf("received empty hash. cannot associate contents with a manifest hash")
+	}
+	records := ir.IndexRecords()
+	idx := make([]claircore.IndexRecord, 0, len(records))
+	for _, r := range records {
+		if r.Package == nil {
+			continue
+		}
+		idx = append(idx, *r)
+		if r.Package.Source != nil && r.Package.Source.Name != "" {
+			src := *r
+			src.Package = r.Package.Source
+			idx = append(idx, src)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mf, ok := s.manifests[ir.Hash.String()]
+	if !ok {
+		mf = &manifest{scanned: make(map[string]struct{})}
+		s.manifests[ir.Hash.String()] = mf
+	}
+	mf.index = idx
+	return nil
+}
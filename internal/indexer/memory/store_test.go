@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+type scanner struct{ name, version string }
+
+func (s scanner) Name() string    { return s.name }
+func (s scanner) Version() string { return s.version }
+func (s scanner) Kind() string    { return "package" }
+
+func TestStaleManifests(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	old := indexer.VersionedScanners{scanner{"rpm", "1"}, scanner{"dpkg", "1"}}
+	cur := indexer.VersionedScanners{scanner{"rpm", "2"}, scanner{"dpkg", "1"}}
+	a := claircore.MustParseDigest("sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	b := claircore.MustParseDigest("sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	if err := s.SetIndexFinished(ctx, &claircore.IndexReport{Hash: a}, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetIndexFinished(ctx, &claircore.IndexReport{Hash: b}, cur); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.StaleManifests(ctx, cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []indexer.StaleManifest{
+		{
+			Manifest: a,
+			Missing:  []indexer.ScannerInfo{{Name: "rpm", Version: "2", Kind: "package"}},
+			Outdated: []indexer.ScannerInfo{{Name: "rpm", Version: "1", Kind: "package"}},
+		},
+	}
+	cmpDigest := cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })
+	if !cmp.Equal(got, want, cmpDigest) {
+		t.Error(cmp.Diff(got, want, cmpDigest))
+	}
+}
+
+func TestBinariesBySource(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	a := claircore.MustParseDigest("sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	b := claircore.MustParseDigest("sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	src := func(v string) *claircore.Package {
+		return &claircore.Package{ID: "src-" + v, Name: "openssl", Version: v, Kind: claircore.SOURCE}
+	}
+	bin := func(id, name, v string) *claircore.Package {
+		return &claircore.Package{ID: id, Name: name, Version: v, Kind: claircore.BINARY, Source: src(v)}
+	}
+	report := func(h claircore.Digest, ps ...*claircore.Package) *claircore.IndexReport {
+		ir := &claircore.IndexReport{
+			Hash:         h,
+			Packages:     make(map[string]*claircore.Package),
+			Environments: make(map[string][]*claircore.Environment),
+		}
+		for _, p := range ps {
+			ir.Packages[p.ID] = p
+			ir.Environments[p.ID] = []*claircore.Environment{{PackageDB: "var/lib/dpkg/status"}}
+		}
+		return ir
+	}
+	irs := []*claircore.IndexReport{
+		report(a,
+			bin("1", "libssl1.1", "1.1.1k-1"),
+			bin("2", "openssl", "1.1.1k-1"),
+			&claircore.Package{ID: "3", Name: "libc6", Version: "2.31-13", Kind: claircore.BINARY},
+		),
+		report(b, bin("4", "libssl1.1", "1.1.1n-0")),
+	}
+	for _, ir := range irs {
+		if err := s.IndexManifest(ctx, ir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmpDigest := cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })
+
+	got, err := s.BinariesBySource(ctx, "openssl", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []indexer.SourceBinary{
+		{Manifest: a, Package: irs[0].Packages["1"]},
+		{Manifest: a, Package: irs[0].Packages["2"]},
+		{Manifest: b, Package: irs[1].Packages["4"]},
+	}
+	if !cmp.Equal(got, want, cmpDigest) {
+		t.Error(cmp.Diff(got, want, cmpDigest))
+	}
+
+	got, err = s.BinariesBySource(ctx, "openssl", "1.1.1n-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = want[2:]
+	if !cmp.Equal(got, want, cmpDigest) {
+		t.Error(cmp.Diff(got, want, cmpDigest))
+	}
+}
@@ -16,4 +16,16 @@ type Opts struct {
 	Ecosystems   []*Ecosystem
 	Vscnrs       VersionedScanners
 	Airgap       bool
+	// Attachments, if set, is used to discover artifacts attached to the
+	// manifest in its registry.
+	Attachments AttachmentFinder
+	// ForeignURLs are the URL prefixes foreign layers may be fetched from,
+	// if the registry doesn't serve them. See claircore.Layer.URLs.
+	ForeignURLs []string
+	// ContentScanners, if any, are run on every layer of the manifest and
+	// their Detections added to the IndexReport.
+	ContentScanners []ContentScanner
+	// Heuristics enables the image-level checks in the heuristics package,
+	// which are run on every layer of the manifest and report warnings.
+	Heuristics bool
 }
@@ -4,16 +4,11 @@ import (
 	"context"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex/driver"
 )
 
-// PackageScanner provides an interface for unique identification or a PackageScanner
-// and a Scan method for extracting installed packages from an individual container layer
-type PackageScanner interface {
-	VersionedScanner
-	// Scan performs a package scan on the given layer and returns all
-	// the found packages
-	Scan(context.Context, *claircore.Layer) ([]*claircore.Package, error)
-}
+// PackageScanner is an alias for driver.PackageScanner.
+type PackageScanner = driver.PackageScanner
 
 type mockPackageScanner struct {
 	name    string
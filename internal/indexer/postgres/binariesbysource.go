@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var _ indexer.SourceFinder = (*store)(nil)
+
+var (
+	binariesBySourceCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "binariesbysource_total",
+			Help:      "Total number of database queries issued in the BinariesBySource method.",
+		},
+		[]string{"query", "success"},
+	)
+	binariesBySourceDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "binariesbysource_duration_seconds",
+			Help:      "The duration of all queries issued in the BinariesBySource method.",
+		},
+		[]string{"query", "success"},
+	)
+)
+
+// BinariesBySource implements indexer.SourceFinder.
+func (s *store) BinariesBySource(ctx context.Context, name, version string) (_ []indexer.SourceBinary, err error) {
+	const query = `
+SELECT DISTINCT
+	manifest.hash,
+	binary_package.id,
+	binary_package.name,
+	binary_package.kind,
+	binary_package.version,
+	binary_package.module,
+	binary_package.arch,
+	source_package.id,
+	source_package.name,
+	source_package.kind,
+	source_package.version,
+	source_package.module,
+	source_package.arch
+FROM
+	package AS source_package
+	JOIN package_source ON package_source.source_id = source_package.id
+	JOIN package AS binary_package ON binary_package.id = package_source.package_id
+	JOIN manifest_index ON manifest_index.package_id = binary_package.id
+	JOIN manifest ON manifest.id = manifest_index.manifest_id
+WHERE
+	source_package.name = $1
+	AND ($2 = '' OR source_package.version = $2);
+`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/indexer/postgres/BinariesBySource"))
+	ctx, done := context.WithTimeout(ctx, time.Minute)
+	defer done()
+	defer promTimer(binariesBySourceDuration, "query", &err)()
+	defer func() {
+		binariesBySourceCounter.WithLabelValues("query", success(err)).Inc()
+	}()
+
+	rows, err := s.pool.Query(ctx, query, name, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query binaries of source %q: %w", name, err)
+	}
+	defer rows.Close()
+	var out []indexer.SourceBinary
+	for rows.Next() {
+		var b indexer.SourceBinary
+		var pkg, src claircore.Package
+		var id, srcID int64
+		err := rows.Scan(
+			&b.Manifest,
+			&id,
+			&pkg.Name,
+			&pkg.Kind,
+			&pkg.Version,
+			&pkg.Module,
+			&pkg.Arch,
+
+			&srcID,
+			&src.Name,
+			&src.Kind,
+			&src.Version,
+			&src.Module,
+			&src.Arch,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan binaries of source %q: %w", name, err)
+		}
+		pkg.ID = strconv.FormatInt(id, 10)
+		src.ID = strconv.FormatInt(srcID, 10)
+		pkg.Source = &src
+		b.Package = &pkg
+		out = append(out, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read binaries of source %q: %w", name, err)
+	}
+	indexer.SortSourceBinaries(out)
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var _ indexer.Checkpointer = (*store)(nil)
+
+var (
+	checkpointCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "checkpoint_total",
+			Help:      "Total number of database queries issued in the checkpoint methods.",
+		},
+		[]string{"query", "success"},
+	)
+	checkpointDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "checkpoint_duration_seconds",
+			Help:      "The duration of all queries issued in the checkpoint methods.",
+		},
+		[]string{"query", "success"},
+	)
+)
+
+// SetLayerCheckpoint implements indexer.Checkpointer.
+func (s *store) SetLayerCheckpoint(ctx context.Context, manifest, layer claircore.Digest, vs indexer.VersionedScanners) (err error) {
+	const query = `
+INSERT
+INTO
+	index_checkpoint (manifest_id, layer_id, scanners)
+VALUES
+	(
+		(SELECT id FROM manifest WHERE hash = $1),
+		(SELECT id FROM layer WHERE hash = $2),
+		$3
+	)
+ON CONFLICT
+	(manifest_id, layer_id, scanners)
+DO
+	NOTHING;
+`
+	defer promTimer(checkpointDuration, "setLayerCheckpoint", &err)()
+	defer func() {
+		checkpointCounter.WithLabelValues("setLayerCheckpoint", success(err)).Inc()
+	}()
+	ctx, done := context.WithTimeout(ctx, 15*time.Second)
+	defer done()
+	if _, err = s.pool.Exec(ctx, query, manifest, layer, indexer.CheckpointKey(vs)); err != nil {
+		return fmt.Errorf("error setting layer checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LayerCheckpoints implements indexer.Checkpointer.
+func (s *store) LayerCheckpoints(ctx context.Context, manifest claircore.Digest, vs indexer.VersionedScanners) (_ []claircore.Digest, err error) {
+	const query = `
+SELECT
+	layer.hash
+FROM
+	index_checkpoint
+	JOIN manifest ON manifest.id = index_checkpoint.manifest_id
+	JOIN layer ON layer.id = index_checkpoint.layer_id
+WHERE
+	manifest.hash = $1
+	AND index_checkpoint.scanners = $2;
+`
+	defer promTimer(checkpointDuration, "layerCheckpoints", &err)()
+	defer func() {
+		checkpointCounter.WithLabelValues("layerCheckpoints", success(err)).Inc()
+	}()
+	ctx, done := context.WithTimeout(ctx, 15*time.Second)
+	defer done()
+	rows, err := s.pool.Query(ctx, query, manifest, indexer.CheckpointKey(vs))
+	if err != nil {
+		return nil, fmt.Errorf("error querying layer checkpoints: %w", err)
+	}
+	defer rows.Close()
+	var out []claircore.Digest
+	for rows.Next() {
+		var d claircore.Digest
+		if err = rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClearCheckpoints implements indexer.Checkpointer.
+func (s *store) ClearCheckpoints(ctx context.Context, manifest claircore.Digest) (err error) {
+	const query = `
+DELETE FROM
+	index_checkpoint
+WHERE
+	manifest_id = (SELECT id FROM manifest WHERE hash = $1);
+`
+	defer promTimer(checkpointDuration, "clearCheckpoints", &err)()
+	defer func() {
+		checkpointCounter.WithLabelValues("clearCheckpoints", success(err)).Inc()
+	}()
+	ctx, done := context.WithTimeout(ctx, 15*time.Second)
+	defer done()
+	if _, err = s.pool.Exec(ctx, query, manifest); err != nil {
+		return fmt.Errorf("error clearing checkpoints: %w", err)
+	}
+	return nil
+}
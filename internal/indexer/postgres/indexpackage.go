@@ -46,6 +46,9 @@ var (
 // package first and then create a relation between the binary package and
 // source package.
 //
+// The relationship between a binary package and its source package is also
+// recorded on its own, for BinariesBySource.
+//
 // Scan artifacts are used to determine if a particular layer has been scanned by a
 // particular scanner. See the LayerScanned method for more details.
 func (s *store) IndexPackages(ctx context.Context, pkgs []*claircore.Package, layer *claircore.Layer, scnr indexer.VersionedScanner) error {
@@ -88,13 +91,36 @@ func (s *store) IndexPackages(ctx context.Context, pkgs []*claircore.Package, la
 				 WHERE layer.hash = $14
 			 )
 		INSERT
-		INTO package_scanartifact (layer_id, package_db, repository_hint, package_id, source_id, scanner_id)
+		INTO package_scanartifact (layer_id, package_db, repository_hint, package_id, source_id, scanner_id, digests, confidence, files, metadata)
 		VALUES ((SELECT layer_id FROM layer),
 				$15,
 				$16,
 				(SELECT package_id FROM binary_package),
 				(SELECT source_id FROM source_package),
-				(SELECT scanner_id FROM scanner))
+				(SELECT scanner_id FROM scanner),
+				$17::text[],
+				NULLIF($18, ''),
+				$19::text[],
+				$20::jsonb)
+		ON CONFLICT DO NOTHING;
+		`
+
+		insertSource = `
+		INSERT
+		INTO package_source (package_id, source_id)
+		SELECT binary_package.id, source_package.id
+		FROM package AS binary_package,
+			 package AS source_package
+		WHERE binary_package.name = $1
+		  AND binary_package.kind = $2
+		  AND binary_package.version = $3
+		  AND binary_package.module = $4
+		  AND binary_package.arch = $5
+		  AND source_package.name = $6
+		  AND source_package.kind = $7
+		  AND source_package.version = $8
+		  AND source_package.module = $9
+		  AND source_package.arch = $10
 		ON CONFLICT DO NOTHING;
 		`
 	)
@@ -123,6 +149,13 @@ func (s *store) IndexPackages(ctx context.Context, pkgs []*claircore.Package, la
 		return fmt.Errorf("failed to create statement: %w", err)
 	}
 
+	tctx, done = context.WithTimeout(ctx, 5*time.Second)
+	insertSourceStmt, err := tx.Prepare(tctx, "insertPackageSource", insertSource)
+	done()
+	if err != nil {
+		return fmt.Errorf("failed to create statement: %w", err)
+	}
+
 	skipCt := 0
 
 	start := time.Now()
@@ -183,10 +216,34 @@ func (s *store) IndexPackages(ctx context.Context, pkgs []*claircore.Package, la
 			layer.Hash,
 			pkg.PackageDB,
 			pkg.RepositoryHint,
+			digestSlice(pkg.Digests),
+			string(pkg.Confidence),
+			pkg.Files,
+			packageMetadata(pkg.Metadata),
 		)
 		if err != nil {
 			return fmt.Errorf("batch insert failed for package_scanartifact %v: %w", pkg, err)
 		}
+		if pkg.Source.Name == "" {
+			continue
+		}
+		err = mBatcher.Queue(
+			ctx,
+			insertSourceStmt.SQL,
+			pkg.Name,
+			pkg.Kind,
+			pkg.Version,
+			pkg.Module,
+			pkg.Arch,
+			pkg.Source.Name,
+			pkg.Source.Kind,
+			pkg.Source.Version,
+			pkg.Source.Module,
+			pkg.Source.Arch,
+		)
+		if err != nil {
+			return fmt.Errorf("batch insert failed for package_source %v: %w", pkg, err)
+		}
 	}
 	err = mBatcher.Done(ctx)
 	if err != nil {
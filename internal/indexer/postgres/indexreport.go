@@ -37,19 +37,20 @@ var (
 
 func (s *store) IndexReport(ctx context.Context, hash claircore.Digest) (*claircore.IndexReport, bool, error) {
 	const query = `
-	SELECT scan_result
+	SELECT scan_result_zstd, scan_result
 	FROM indexreport
 			 JOIN manifest ON manifest.hash = $1
 	WHERE indexreport.manifest_id = manifest.id;
 	`
-	// we scan into a jsonbIndexReport which has value/scan method set
-	// then type convert back to scanner.domain object
-	var jsr jsonbIndexReport
+	// Reports written before compression was added are only in the jsonb
+	// column, so check both. Whichever is present is decoded through the
+	// matching Scanner implementation.
+	var zb, jb []byte
 
 	ctx, done := context.WithTimeout(ctx, 5*time.Second)
 	defer done()
 	start := time.Now()
-	err := s.pool.QueryRow(ctx, query, hash).Scan(&jsr)
+	err := s.pool.QueryRow(ctx, query, hash).Scan(&zb, &jb)
 	switch {
 	case errors.Is(err, nil):
 	case errors.Is(err, pgx.ErrNoRows):
@@ -60,6 +61,17 @@ func (s *store) IndexReport(ctx context.Context, hash claircore.Digest) (*clairc
 	indexReportCounter.WithLabelValues("query").Add(1)
 	indexReportDuration.WithLabelValues("query").Observe(time.Since(start).Seconds())
 
-	sr := claircore.IndexReport(jsr)
+	var sr claircore.IndexReport
+	switch {
+	case zb != nil:
+		err = (*zstdIndexReport)(&sr).Scan(zb)
+	case jb != nil:
+		err = (*jsonbIndexReport)(&sr).Scan(jb)
+	default:
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode index report: %w", err)
+	}
 	return &sr, true, nil
 }
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestZstdIndexReport(t *testing.T) {
+	want := claircore.IndexReport{
+		Hash:     test.RandomSHA256Digest(t),
+		State:    "IndexFinished",
+		Success:  true,
+		Packages: map[string]*claircore.Package{},
+	}
+	for _, p := range test.GenUniquePackages(100) {
+		// These aren't serialized.
+		p.PackageDB, p.RepositoryHint = "", ""
+		want.Packages[p.ID] = p
+	}
+	v, err := zstdIndexReport(want).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := v.([]byte)
+	js, err := jsonbIndexReport(want).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("jsonb: %d bytes, zstd: %d bytes", len(js.([]byte)), len(b))
+
+	var got claircore.IndexReport
+	if err := (*zstdIndexReport)(&got).Scan(b); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(got, want, cmpOpts) {
+		t.Error(cmp.Diff(got, want, cmpOpts))
+	}
+}
+
+// TestIndexReportLegacy confirms IndexReports stored uncompressed are still
+// returned, and are compressed when rewritten.
+func TestIndexReportLegacy(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDatabase(ctx, t)
+	store := NewStore(pool)
+	defer store.Close(ctx)
+
+	const (
+		insertManifest = `INSERT INTO manifest (hash) VALUES ($1);`
+		insertLegacy   = `
+INSERT INTO indexreport (manifest_id, scan_result)
+SELECT id, $2 FROM manifest WHERE hash = $1;
+`
+		selectColumns = `
+SELECT scan_result IS NULL, scan_result_zstd IS NULL
+FROM indexreport JOIN manifest ON manifest.id = indexreport.manifest_id
+WHERE manifest.hash = $1;
+`
+	)
+	want := claircore.IndexReport{
+		Hash:  test.RandomSHA256Digest(t),
+		State: "IndexFinished",
+	}
+	if _, err := pool.Exec(ctx, insertManifest, want.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, insertLegacy, want.Hash, jsonbIndexReport(want)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok, err := store.IndexReport(ctx, want.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("legacy report not found")
+	}
+	if !cmp.Equal(got, &want, cmpOpts) {
+		t.Error(cmp.Diff(got, &want, cmpOpts))
+	}
+
+	if err := store.SetIndexReport(ctx, &want); err != nil {
+		t.Fatal(err)
+	}
+	var jsNull, zNull bool
+	if err := pool.QueryRow(ctx, selectColumns, want.Hash).Scan(&jsNull, &zNull); err != nil {
+		t.Fatal(err)
+	}
+	if !jsNull || zNull {
+		t.Errorf("rewritten report not compressed: jsonb null: %v, zstd null: %v", jsNull, zNull)
+	}
+	got, ok, err = store.IndexReport(ctx, want.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("rewritten report not found")
+	}
+	if !cmp.Equal(got, &want, cmpOpts) {
+		t.Error(cmp.Diff(got, &want, cmpOpts))
+	}
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var _ indexer.LayerLister = (*store)(nil)
+
+var (
+	manifestLayersCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "manifestlayers_total",
+			Help:      "Total number of database queries issued in the ManifestLayers method.",
+		},
+		[]string{"query", "success"},
+	)
+	manifestLayersDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "manifestlayers_duration_seconds",
+			Help:      "The duration of all queries issued in the ManifestLayers method.",
+		},
+		[]string{"query", "success"},
+	)
+)
+
+// ManifestLayers implements indexer.LayerLister.
+func (s *store) ManifestLayers(ctx context.Context, m claircore.Digest) (_ []claircore.Digest, _ bool, err error) {
+	// A known manifest without layers produces a single row of NULL.
+	const query = `
+SELECT
+	layer.hash
+FROM
+	manifest
+	LEFT JOIN manifest_layer ON manifest_layer.manifest_id = manifest.id
+	LEFT JOIN layer ON layer.id = manifest_layer.layer_id
+WHERE
+	manifest.hash = $1
+ORDER BY
+	manifest_layer.i;
+`
+	ctx, done := context.WithTimeout(ctx, 30*time.Second)
+	defer done()
+	defer promTimer(manifestLayersDuration, "query", &err)()
+	defer func() {
+		manifestLayersCounter.WithLabelValues("query", success(err)).Inc()
+	}()
+
+	rows, err := s.pool.Query(ctx, query, m)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query layers: %w", err)
+	}
+	defer rows.Close()
+	var out []claircore.Digest
+	found := false
+	for rows.Next() {
+		found = true
+		var d claircore.Digest
+		if err := rows.Scan(&d); err != nil {
+			return nil, false, fmt.Errorf("failed to scan layer: %w", err)
+		}
+		if d.String() == "" {
+			continue
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to read layers: %w", err)
+	}
+	return out, found, nil
+}
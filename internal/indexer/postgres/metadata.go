@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"encoding/json"
+
+	"github.com/jackc/pgtype"
+)
+
+// PackageMetadata is a helper to encode a Package's Metadata as JSON, or NULL
+// if there isn't any.
+type packageMetadata map[string]string
+
+func (m packageMetadata) EncodeText(_ *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(map[string]string(m))
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, b...), nil
+}
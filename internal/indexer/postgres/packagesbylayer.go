@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -65,7 +66,11 @@ SELECT
 	source_package.module,
 	source_package.arch,
 	package_scanartifact.package_db,
-	package_scanartifact.repository_hint
+	package_scanartifact.repository_hint,
+	package_scanartifact.digests,
+	package_scanartifact.confidence,
+	package_scanartifact.files,
+	package_scanartifact.metadata
 FROM
 	package_scanartifact
 	LEFT JOIN package ON
@@ -121,6 +126,10 @@ WHERE
 		var id, srcID int64
 		var nKind *string
 		var nVer pgtype.Int4Array
+		var ds pgtype.TextArray
+		var conf *string
+		var fs pgtype.TextArray
+		var md pgtype.JSONB
 		err := rows.Scan(
 			&id,
 			&pkg.Name,
@@ -140,6 +149,10 @@ WHERE
 
 			&pkg.PackageDB,
 			&pkg.RepositoryHint,
+			&ds,
+			&conf,
+			&fs,
+			&md,
 		)
 		pkg.ID = strconv.FormatInt(id, 10)
 		spkg.ID = strconv.FormatInt(srcID, 10)
@@ -152,6 +165,24 @@ WHERE
 				pkg.NormalizedVersion.V[i] = n.Int
 			}
 		}
+		for _, e := range ds.Elements {
+			d, err := claircore.ParseDigest(e.String)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse package digest: %w", err)
+			}
+			pkg.Digests = append(pkg.Digests, d)
+		}
+		if conf != nil {
+			pkg.Confidence = claircore.Confidence(*conf)
+		}
+		for _, e := range fs.Elements {
+			pkg.Files = append(pkg.Files, e.String)
+		}
+		if md.Status == pgtype.Present {
+			if err := json.Unmarshal(md.Bytes, &pkg.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to decode package metadata: %w", err)
+			}
+		}
 		// nest source package
 		pkg.Source = &spkg
 
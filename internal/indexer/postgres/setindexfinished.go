@@ -66,13 +66,13 @@ WITH
 		)
 INSERT
 INTO
-	indexreport (manifest_id, scan_result)
+	indexreport (manifest_id, scan_result_zstd)
 VALUES
 	((SELECT manifest_id FROM manifests), $2)
 ON CONFLICT
 	(manifest_id)
 DO
-	UPDATE SET scan_result = excluded.scan_result;
+	UPDATE SET scan_result_zstd = excluded.scan_result_zstd, scan_result = NULL;
 `
 	)
 
@@ -103,12 +103,12 @@ DO
 	}
 
 	// push IndexReport to the store
-	// we cast claircore.IndexReport to jsonbIndexReport in order to obtain the value/scan
+	// we cast claircore.IndexReport to zstdIndexReport in order to obtain the value/scan
 	// implementations
 
 	tctx, done = context.WithTimeout(ctx, 5*time.Second)
 	start := time.Now()
-	_, err = tx.Exec(tctx, upsertIndexReport, ir.Hash, jsonbIndexReport(*ir))
+	_, err = tx.Exec(tctx, upsertIndexReport, ir.Hash, zstdIndexReport(*ir))
 	done()
 	if err != nil {
 		return fmt.Errorf("failed to upsert scan result: %w", err)
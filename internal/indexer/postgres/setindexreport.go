@@ -47,21 +47,21 @@ WITH
 		)
 INSERT
 INTO
-	indexreport (manifest_id, scan_result)
+	indexreport (manifest_id, scan_result_zstd)
 VALUES
 	((SELECT manifest_id FROM manifests), $2)
 ON CONFLICT
 	(manifest_id)
 DO
-	UPDATE SET scan_result = excluded.scan_result;
+	UPDATE SET scan_result_zstd = excluded.scan_result_zstd, scan_result = NULL;
 `
-	// we cast scanner.IndexReport to jsonbIndexReport in order to obtain the value/scan
+	// we cast scanner.IndexReport to zstdIndexReport in order to obtain the value/scan
 	// implementations
 
 	ctx, done := context.WithTimeout(ctx, 30*time.Second)
 	defer done()
 	start := time.Now()
-	_, err := s.pool.Exec(ctx, query, ir.Hash, jsonbIndexReport(*ir))
+	_, err := s.pool.Exec(ctx, query, ir.Hash, zstdIndexReport(*ir))
 	if err != nil {
 		return fmt.Errorf("failed to upsert index report: %w", err)
 	}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var _ indexer.StaleFinder = (*store)(nil)
+
+var (
+	staleManifestsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "stalemanifests_total",
+			Help:      "Total number of database queries issued in the StaleManifests method.",
+		},
+		[]string{"query", "success"},
+	)
+	staleManifestsDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "indexer",
+			Name:      "stalemanifests_duration_seconds",
+			Help:      "The duration of all queries issued in the StaleManifests method.",
+		},
+		[]string{"query", "success"},
+	)
+)
+
+// StaleManifests implements indexer.StaleFinder.
+func (s *store) StaleManifests(ctx context.Context, vs indexer.VersionedScanners) (_ []indexer.StaleManifest, err error) {
+	const (
+		selectScanners = `SELECT id, name, version, kind FROM scanner;`
+		selectStale    = `
+SELECT
+	manifest.hash, array_agg(scanned_manifest.scanner_id)
+FROM
+	scanned_manifest
+	JOIN manifest ON manifest.id = scanned_manifest.manifest_id
+GROUP BY
+	manifest.hash
+HAVING
+	NOT (array_agg(scanned_manifest.scanner_id) @> $1::int8[]
+		AND array_agg(scanned_manifest.scanner_id) <@ $1::int8[]);
+`
+	)
+	want, err := s.selectScanners(ctx, vs)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, done := context.WithTimeout(ctx, 5*time.Minute)
+	defer done()
+	info := make(map[int64]indexer.ScannerInfo)
+	err = func() (err error) {
+		defer promTimer(staleManifestsDuration, "selectScanners", &err)()
+		defer func() {
+			staleManifestsCounter.WithLabelValues("selectScanners", success(err)).Inc()
+		}()
+		rows, err := s.pool.Query(ctx, selectScanners)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			var i indexer.ScannerInfo
+			if err := rows.Scan(&id, &i.Name, &i.Version, &i.Kind); err != nil {
+				return err
+			}
+			info[id] = i
+		}
+		return rows.Err()
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("failed to select scanners: %w", err)
+	}
+
+	wantSet := make(map[int64]struct{}, len(want))
+	for _, id := range want {
+		wantSet[id] = struct{}{}
+	}
+	var out []indexer.StaleManifest
+	err = func() (err error) {
+		defer promTimer(staleManifestsDuration, "selectStale", &err)()
+		defer func() {
+			staleManifestsCounter.WithLabelValues("selectStale", success(err)).Inc()
+		}()
+		rows, err := s.pool.Query(ctx, selectStale, want)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var m indexer.StaleManifest
+			var have []int64
+			if err := rows.Scan(&m.Manifest, &have); err != nil {
+				return err
+			}
+			haveSet := make(map[int64]struct{}, len(have))
+			for _, id := range have {
+				haveSet[id] = struct{}{}
+				if _, ok := wantSet[id]; !ok {
+					m.Outdated = append(m.Outdated, info[id])
+				}
+			}
+			for _, id := range want {
+				if _, ok := haveSet[id]; !ok {
+					m.Missing = append(m.Missing, info[id])
+				}
+			}
+			out = append(out, m)
+		}
+		return rows.Err()
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("failed to select stale manifests: %w", err)
+	}
+	indexer.SortStaleManifests(out)
+	return out, nil
+}
@@ -4,6 +4,9 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
 
 	"github.com/quay/claircore"
 )
@@ -28,3 +31,55 @@ func (sr *jsonbIndexReport) Scan(value interface{}) error {
 
 	return json.Unmarshal(b, &sr)
 }
+
+// zstdIndexReport is a type definition for claircore.IndexReport, like
+// jsonbIndexReport, but stored as zstd-compressed JSON in a bytea column.
+//
+// IndexReports for large images are mostly repetitive package records, so
+// this is many times smaller than the jsonb representation.
+type zstdIndexReport claircore.IndexReport
+
+func (sr zstdIndexReport) Value() (driver.Value, error) {
+	b, err := json.Marshal(sr)
+	if err != nil {
+		return nil, err
+	}
+	enc, _ := zstdCodec()
+	return enc.EncodeAll(b, make([]byte, 0, len(b)/8)), nil
+}
+
+func (sr *zstdIndexReport) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to type assert IndexReport to []bytes")
+	}
+	_, dec := zstdCodec()
+	b, err := dec.DecodeAll(b, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decompress IndexReport: %w", err)
+	}
+	return json.Unmarshal(b, &sr)
+}
+
+var (
+	zstdOnce sync.Once
+	zstdEnc  *zstd.Encoder
+	zstdDec  *zstd.Decoder
+)
+
+// ZstdCodec returns the shared encoder and decoder, which are only used
+// statelessly and so are safe for concurrent use.
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder) {
+	zstdOnce.Do(func() {
+		var err error
+		zstdEnc, err = zstd.NewWriter(nil)
+		if err != nil {
+			panic(err) // Only possible with invalid options.
+		}
+		zstdDec, err = zstd.NewReader(nil)
+		if err != nil {
+			panic(err) // Only possible with invalid options.
+		}
+	})
+	return zstdEnc, zstdDec
+}
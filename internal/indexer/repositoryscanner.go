@@ -1,12 +1,6 @@
 package indexer
 
-import (
-	"context"
+import "github.com/quay/claircore/libindex/driver"
 
-	"github.com/quay/claircore"
-)
-
-type RepositoryScanner interface {
-	VersionedScanner
-	Scan(context.Context, *claircore.Layer) ([]*claircore.Repository, error)
-}
+// RepositoryScanner is an alias for driver.RepositoryScanner.
+type RepositoryScanner = driver.RepositoryScanner
@@ -0,0 +1,958 @@
+// Package sqlite provides an indexer.Store backed by a single SQLite database
+// file.
+//
+// It's intended for small deployments and CLI tools indexing a handful of
+// images, where running a PostgreSQL instance isn't warranted. Writes are
+// serialized, so it doesn't scale to many concurrent Index calls.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3" // Register the driver.
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/omnimatcher"
+)
+
+var (
+	_ indexer.Store        = (*Store)(nil)
+	_ indexer.Checkpointer = (*Store)(nil)
+	_ indexer.StaleFinder  = (*Store)(nil)
+	_ indexer.SourceFinder = (*Store)(nil)
+	_ indexer.LayerLister  = (*Store)(nil)
+)
+
+// SchemaVersion is the version of the schema this package creates, recorded
+// in the database's "user_version".
+const schemaVersion = 2
+
+// Schema is the database schema.
+//
+// Artifacts are stored as JSON arrays per layer and scanner, as they're only
+// ever retrieved that way. The records of coalesced reports are stored
+// individually, keyed by package and source package name, for
+// AffectedManifests and BinariesBySource.
+const schema = `
+CREATE TABLE IF NOT EXISTS scanner (
+	id      INTEGER PRIMARY KEY,
+	kind    TEXT NOT NULL,
+	name    TEXT NOT NULL,
+	version TEXT NOT NULL,
+	UNIQUE (kind, name, version)
+);
+CREATE TABLE IF NOT EXISTS manifest (
+	id     INTEGER PRIMARY KEY,
+	hash   TEXT NOT NULL UNIQUE,
+	report BLOB
+);
+CREATE TABLE IF NOT EXISTS layer (
+	id   INTEGER PRIMARY KEY,
+	hash TEXT NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS manifest_layer (
+	manifest_id INTEGER NOT NULL REFERENCES manifest (id) ON DELETE CASCADE,
+	i           INTEGER NOT NULL,
+	layer_id    INTEGER NOT NULL REFERENCES layer (id),
+	PRIMARY KEY (manifest_id, i)
+);
+CREATE INDEX IF NOT EXISTS manifest_layer_layer_idx ON manifest_layer (layer_id);
+CREATE TABLE IF NOT EXISTS scanned_manifest (
+	manifest_id INTEGER NOT NULL REFERENCES manifest (id) ON DELETE CASCADE,
+	scanner_id  INTEGER NOT NULL REFERENCES scanner (id),
+	PRIMARY KEY (manifest_id, scanner_id)
+);
+CREATE TABLE IF NOT EXISTS scanned_layer (
+	layer_id   INTEGER NOT NULL REFERENCES layer (id) ON DELETE CASCADE,
+	scanner_id INTEGER NOT NULL REFERENCES scanner (id),
+	PRIMARY KEY (layer_id, scanner_id)
+);
+CREATE TABLE IF NOT EXISTS artifact (
+	layer_id   INTEGER NOT NULL REFERENCES layer (id) ON DELETE CASCADE,
+	scanner_id INTEGER NOT NULL REFERENCES scanner (id),
+	kind       TEXT NOT NULL,
+	data       BLOB NOT NULL,
+	PRIMARY KEY (layer_id, scanner_id, kind)
+);
+CREATE TABLE IF NOT EXISTS artifact_id (
+	id  INTEGER PRIMARY KEY,
+	key TEXT NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS checkpoint (
+	manifest_id INTEGER NOT NULL REFERENCES manifest (id) ON DELETE CASCADE,
+	layer       TEXT NOT NULL,
+	scanners    TEXT NOT NULL,
+	PRIMARY KEY (manifest_id, layer, scanners)
+);
+CREATE TABLE IF NOT EXISTS manifest_index (
+	manifest_id INTEGER NOT NULL REFERENCES manifest (id) ON DELETE CASCADE,
+	package     TEXT NOT NULL,
+	source      TEXT,
+	record      BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS manifest_index_package_idx ON manifest_index (package);
+CREATE INDEX IF NOT EXISTS manifest_index_source_idx ON manifest_index (source);
+`
+
+// Store is an indexer.Store backed by SQLite.
+//
+// Store is safe for concurrent use.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore opens the SQLite database at the named path, creating it and its
+// schema if needed.
+func NewStore(ctx context.Context, path string) (*Store, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/indexer/sqlite/NewStore"),
+		label.String("path", path))
+	if strings.ContainsRune(path, '?') {
+		return nil, fmt.Errorf("sqlite: invalid path %q", path)
+	}
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=1&_busy_timeout=10000")
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: unable to open database: %w", err)
+	}
+	// SQLite allows only one writer at a time; using one connection keeps
+	// writers from failing with "database is locked" and keeps the
+	// per-connection settings in effect.
+	db.SetMaxOpenConns(1)
+	s := &Store{db: db}
+	if err := s.init(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	zlog.Debug(ctx).Msg("opened database")
+	return s, nil
+}
+
+// Init creates the schema, if needed.
+func (s *Store) init(ctx context.Context) error {
+	var v int
+	if err := s.db.QueryRowContext(ctx, `PRAGMA user_version;`).Scan(&v); err != nil {
+		return fmt.Errorf("sqlite: unable to read schema version: %w", err)
+	}
+	if v > schemaVersion {
+		return fmt.Errorf("sqlite: database schema version %d is newer than supported version %d", v, schemaVersion)
+	}
+	if _, err := s.db.ExecContext(ctx, `PRAGMA journal_mode = WAL;`); err != nil {
+		return fmt.Errorf("sqlite: unable to set journal mode: %w", err)
+	}
+	// Version 2 keys checkpoints by the scanner set. Checkpoints only live as
+	// long as an Index call, so the old table is dropped rather than migrated.
+	if v == 1 {
+		if _, err := s.db.ExecContext(ctx, `DROP TABLE IF EXISTS checkpoint;`); err != nil {
+			return fmt.Errorf("sqlite: unable to upgrade schema: %w", err)
+		}
+	}
+	if _, err := s.db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("sqlite: unable to create schema: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d;`, schemaVersion)); err != nil {
+		return fmt.Errorf("sqlite: unable to set schema version: %w", err)
+	}
+	return nil
+}
+
+// Close implements indexer.Store.
+func (s *Store) Close(_ context.Context) error {
+	return s.db.Close()
+}
+
+// Tx runs the function in a transaction, committing if it returns nil.
+func (s *Store) tx(ctx context.Context, f func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: unable to begin transaction: %w", err)
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite: unable to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Querier is the subset of methods shared by *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+// Upsert returns the ID of the row in "table" with the unique column set to
+// the value, creating it if needed.
+func upsert(ctx context.Context, q querier, table, col string, v interface{}) (int64, error) {
+	if _, err := q.ExecContext(ctx,
+		`INSERT INTO `+table+` (`+col+`) VALUES (?) ON CONFLICT DO NOTHING;`, v); err != nil {
+		return 0, fmt.Errorf("sqlite: unable to insert %s: %w", table, err)
+	}
+	var id int64
+	if err := q.QueryRowContext(ctx,
+		`SELECT id FROM `+table+` WHERE `+col+` = ?;`, v).Scan(&id); err != nil {
+		return 0, fmt.Errorf("sqlite: unable to find %s: %w", table, err)
+	}
+	return id, nil
+}
+
+// ScannerID returns the ID of the scanner, registering it if needed.
+func scannerID(ctx context.Context, q querier, v indexer.VersionedScanner) (int64, error) {
+	if _, err := q.ExecContext(ctx,
+		`INSERT INTO scanner (kind, name, version) VALUES (?, ?, ?) ON CONFLICT DO NOTHING;`,
+		v.Kind(), v.Name(), v.Version()); err != nil {
+		return 0, fmt.Errorf("sqlite: unable to register scanner: %w", err)
+	}
+	var id int64
+	if err := q.QueryRowContext(ctx,
+		`SELECT id FROM scanner WHERE kind = ? AND name = ? AND version = ?;`,
+		v.Kind(), v.Name(), v.Version()).Scan(&id); err != nil {
+		return 0, fmt.Errorf("sqlite: unable to find scanner: %w", err)
+	}
+	return id, nil
+}
+
+// LookupID returns the ID of the row in "table" with the hash, reporting
+// false if there isn't one.
+func lookupID(ctx context.Context, q querier, table string, hash claircore.Digest) (int64, bool, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, `SELECT id FROM `+table+` WHERE hash = ?;`, hash.String()).Scan(&id)
+	switch {
+	case errors.Is(err, nil):
+		return id, true, nil
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, false, nil
+	default:
+		return 0, false, fmt.Errorf("sqlite: unable to find %s: %w", table, err)
+	}
+}
+
+// PersistManifest implements indexer.Setter.
+func (s *Store) PersistManifest(ctx context.Context, m claircore.Manifest) error {
+	return s.tx(ctx, func(tx *sql.Tx) error {
+		mid, err := upsert(ctx, tx, "manifest", "hash", m.Hash.String())
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM manifest_layer WHERE manifest_id = ?;`, mid); err != nil {
+			return fmt.Errorf("sqlite: unable to update manifest layers: %w", err)
+		}
+		for i, l := range m.Layers {
+			lid, err := upsert(ctx, tx, "layer", "hash", l.Hash.String())
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO manifest_layer (manifest_id, i, layer_id) VALUES (?, ?, ?);`,
+				mid, i, lid); err != nil {
+				return fmt.Errorf("sqlite: unable to update manifest layers: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteManifests implements indexer.Setter.
+func (s *Store) DeleteManifests(ctx context.Context, ds ...claircore.Digest) ([]claircore.Digest, error) {
+	rm := make([]claircore.Digest, 0, len(ds))
+	err := s.tx(ctx, func(tx *sql.Tx) error {
+		for _, d := range ds {
+			res, err := tx.ExecContext(ctx, `DELETE FROM manifest WHERE hash = ?;`, d.String())
+			if err != nil {
+				return fmt.Errorf("sqlite: unable to delete manifest: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n != 0 {
+				rm = append(rm, d)
+			}
+		}
+		// Remove any layers no longer referenced.
+		if _, err := tx.ExecContext(ctx, `
+DELETE FROM layer
+WHERE NOT EXISTS (SELECT 1 FROM manifest_layer WHERE layer_id = layer.id);`); err != nil {
+			return fmt.Errorf("sqlite: unable to delete layers: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rm, nil
+}
+
+// SetLayerScanned implements indexer.Setter.
+func (s *Store) SetLayerScanned(ctx context.Context, hash claircore.Digest, scnr indexer.VersionedScanner) error {
+	return s.tx(ctx, func(tx *sql.Tx) error {
+		lid, err := upsert(ctx, tx, "layer", "hash", hash.String())
+		if err != nil {
+			return err
+		}
+		sid, err := scannerID(ctx, tx, scnr)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO scanned_layer (layer_id, scanner_id) VALUES (?, ?) ON CONFLICT DO NOTHING;`,
+			lid, sid); err != nil {
+			return fmt.Errorf("sqlite: unable to mark layer scanned: %w", err)
+		}
+		return nil
+	})
+}
+
+// RegisterScanners implements indexer.Setter.
+func (s *Store) RegisterScanners(ctx context.Context, vs indexer.VersionedScanners) error {
+	return s.tx(ctx, func(tx *sql.Tx) error {
+		for _, v := range vs {
+			if _, err := scannerID(ctx, tx, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetIndexReport implements indexer.Setter.
+func (s *Store) SetIndexReport(ctx context.Context, ir *claircore.IndexReport) error {
+	return s.tx(ctx, func(tx *sql.Tx) error {
+		return setIndexReport(ctx, tx, ir)
+	})
+}
+
+func setIndexReport(ctx context.Context, q querier, ir *claircore.IndexReport) error {
+	b, err := json.Marshal(ir)
+	if err != nil {
+		return fmt.Errorf("sqlite: unable to encode index report: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `
+INSERT INTO manifest (hash, report) VALUES (?, ?)
+ON CONFLICT (hash) DO UPDATE SET report = excluded.report;`,
+		ir.Hash.String(), b); err != nil {
+		return fmt.Errorf("sqlite: unable to store index report: %w", err)
+	}
+	return nil
+}
+
+// SetIndexFinished implements indexer.Setter.
+func (s *Store) SetIndexFinished(ctx context.Context, ir *claircore.IndexReport, vs indexer.VersionedScanners) error {
+	return s.tx(ctx, func(tx *sql.Tx) error {
+		if err := setIndexReport(ctx, tx, ir); err != nil {
+			return err
+		}
+		mid, _, err := lookupID(ctx, tx, "manifest", ir.Hash)
+		if err != nil {
+			return err
+		}
+		for _, v := range vs {
+			sid, err := scannerID(ctx, tx, v)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO scanned_manifest (manifest_id, scanner_id) VALUES (?, ?) ON CONFLICT DO NOTHING;`,
+				mid, sid); err != nil {
+				return fmt.Errorf("sqlite: unable to mark manifest scanned: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ManifestScanned implements indexer.Querier.
+func (s *Store) ManifestScanned(ctx context.Context, hash claircore.Digest, vs indexer.VersionedScanners) (bool, error) {
+	const query = `
+SELECT EXISTS (
+	SELECT 1
+	FROM scanned_manifest
+	JOIN manifest ON manifest.id = scanned_manifest.manifest_id
+	JOIN scanner ON scanner.id = scanned_manifest.scanner_id
+	WHERE manifest.hash = ? AND scanner.kind = ? AND scanner.name = ? AND scanner.version = ?
+);`
+	for _, v := range vs {
+		var ok bool
+		if err := s.db.QueryRowContext(ctx, query, hash.String(), v.Kind(), v.Name(), v.Version()).Scan(&ok); err != nil {
+			return false, fmt.Errorf("sqlite: unable to check manifest: %w", err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	if len(vs) == 0 {
+		_, ok, err := lookupID(ctx, s.db, "manifest", hash)
+		return ok, err
+	}
+	return true, nil
+}
+
+// StaleManifests implements indexer.StaleFinder.
+func (s *Store) StaleManifests(ctx context.Context, vs indexer.VersionedScanners) ([]indexer.StaleManifest, error) {
+	want := make(map[indexer.ScannerInfo]struct{}, len(vs))
+	for _, v := range vs {
+		want[indexer.ScannerInfo{Kind: v.Kind(), Name: v.Name(), Version: v.Version()}] = struct{}{}
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT manifest.hash, scanner.kind, scanner.name, scanner.version
+FROM scanned_manifest
+JOIN manifest ON manifest.id = scanned_manifest.manifest_id
+JOIN scanner ON scanner.id = scanned_manifest.scanner_id;`)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: unable to find stale manifests: %w", err)
+	}
+	defer rows.Close()
+	scanned := make(map[string]map[indexer.ScannerInfo]struct{})
+	for rows.Next() {
+		var h string
+		var i indexer.ScannerInfo
+		if err := rows.Scan(&h, &i.Kind, &i.Name, &i.Version); err != nil {
+			return nil, fmt.Errorf("sqlite: unable to find stale manifests: %w", err)
+		}
+		m, ok := scanned[h]
+		if !ok {
+			m = make(map[indexer.ScannerInfo]struct{})
+			scanned[h] = m
+		}
+		m[i] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: unable to find stale manifests: %w", err)
+	}
+
+	var out []indexer.StaleManifest
+	for h, got := range scanned {
+		var m indexer.StaleManifest
+		for i := range got {
+			if _, ok := want[i]; !ok {
+				m.Outdated = append(m.Outdated, i)
+			}
+		}
+		for i := range want {
+			if _, ok := got[i]; !ok {
+				m.Missing = append(m.Missing, i)
+			}
+		}
+		if m.Missing == nil && m.Outdated == nil {
+			continue
+		}
+		d, err := claircore.ParseDigest(h)
+		if err != nil {
+			return nil, err
+		}
+		m.Manifest = d
+		out = append(out, m)
+	}
+	indexer.SortStaleManifests(out)
+	return out, nil
+}
+
+// LayerScanned implements indexer.Querier.
+func (s *Store) LayerScanned(ctx context.Context, hash claircore.Digest, scnr indexer.VersionedScanner) (bool, error) {
+	var ok bool
+	err := s.db.QueryRowContext(ctx, `
+SELECT EXISTS (
+	SELECT 1
+	FROM scanned_layer
+	JOIN layer ON layer.id = scanned_layer.layer_id
+	JOIN scanner ON scanner.id = scanned_layer.scanner_id
+	WHERE layer.hash = ? AND scanner.kind = ? AND scanner.name = ? AND scanner.version = ?
+);`, hash.String(), scnr.Kind(), scnr.Name(), scnr.Version()).Scan(&ok)
+	if err != nil {
+		return false, fmt.Errorf("sqlite: unable to check layer: %w", err)
+	}
+	return ok, nil
+}
+
+// ManifestLayers implements indexer.LayerLister.
+func (s *Store) ManifestLayers(ctx context.Context, m claircore.Digest) ([]claircore.Digest, bool, error) {
+	mid, ok, err := lookupID(ctx, s.db, "manifest", m)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT layer.hash
+FROM manifest_layer
+JOIN layer ON layer.id = manifest_layer.layer_id
+WHERE manifest_layer.manifest_id = ?
+ORDER BY manifest_layer.i;`, mid)
+	if err != nil {
+		return nil, false, fmt.Errorf("sqlite: unable to list layers: %w", err)
+	}
+	out, err := scanDigests(rows)
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+// ScanDigests reads a column of digests, closing the Rows.
+func scanDigests(rows *sql.Rows) ([]claircore.Digest, error) {
+	defer rows.Close()
+	out := []claircore.Digest{}
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, fmt.Errorf("sqlite: unable to read digest: %w", err)
+		}
+		d, err := claircore.ParseDigest(h)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: unable to read digests: %w", err)
+	}
+	return out, nil
+}
+
+// SetLayerCheckpoint implements indexer.Checkpointer.
+func (s *Store) SetLayerCheckpoint(ctx context.Context, m, l claircore.Digest, vs indexer.VersionedScanners) error {
+	mid, ok, err := lookupID(ctx, s.db, "manifest", m)
+	switch {
+	case err != nil:
+		return err
+	case !ok:
+		return fmt.Errorf("sqlite: unknown manifest %q", m)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO checkpoint (manifest_id, layer, scanners) VALUES (?, ?, ?) ON CONFLICT DO NOTHING;`,
+		mid, l.String(), indexer.CheckpointKey(vs)); err != nil {
+		return fmt.Errorf("sqlite: unable to set checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LayerCheckpoints implements indexer.Checkpointer.
+func (s *Store) LayerCheckpoints(ctx context.Context, m claircore.Digest, vs indexer.VersionedScanners) ([]claircore.Digest, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT checkpoint.layer
+FROM checkpoint
+JOIN manifest ON manifest.id = checkpoint.manifest_id
+WHERE manifest.hash = ? AND checkpoint.scanners = ?;`, m.String(), indexer.CheckpointKey(vs))
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: unable to list checkpoints: %w", err)
+	}
+	return scanDigests(rows)
+}
+
+// ClearCheckpoints implements indexer.Checkpointer.
+func (s *Store) ClearCheckpoints(ctx context.Context, m claircore.Digest) error {
+	if _, err := s.db.ExecContext(ctx, `
+DELETE FROM checkpoint
+WHERE manifest_id IN (SELECT id FROM manifest WHERE hash = ?);`, m.String()); err != nil {
+		return fmt.Errorf("sqlite: unable to clear checkpoints: %w", err)
+	}
+	return nil
+}
+
+// Artifact kinds, as stored in the "kind" column of the artifact table.
+const (
+	kindPackage      = "package"
+	kindDistribution = "distribution"
+	kindRepository   = "repository"
+)
+
+// StoredPackage adds the fields of a Package that aren't in its JSON
+// encoding.
+type storedPackage struct {
+	*claircore.Package
+	PackageDB      string `json:"package_db,omitempty"`
+	RepositoryHint string `json:"repository_hint,omitempty"`
+}
+
+// Artifacts calls "add" with the encoded artifacts of the kind found in the
+// layer by each of the scanners, in scanner order.
+func (s *Store) artifacts(ctx context.Context, hash claircore.Digest, vs indexer.VersionedScanners, kind string, add func([]byte) error) error {
+	const query = `
+SELECT artifact.data
+FROM artifact
+JOIN layer ON layer.id = artifact.layer_id
+JOIN scanner ON scanner.id = artifact.scanner_id
+WHERE layer.hash = ? AND artifact.kind = ?
+	AND scanner.kind = ? AND scanner.name = ? AND scanner.version = ?;`
+	for _, v := range vs {
+		var b []byte
+		err := s.db.QueryRowContext(ctx, query, hash.String(), kind, v.Kind(), v.Name(), v.Version()).Scan(&b)
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, sql.ErrNoRows):
+			continue
+		default:
+			return fmt.Errorf("sqlite: unable to retrieve %s artifacts: %w", kind, err)
+		}
+		if err := add(b); err != nil {
+			return fmt.Errorf("sqlite: unable to decode %s artifacts: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+// PackagesByLayer implements indexer.Querier.
+func (s *Store) PackagesByLayer(ctx context.Context, hash claircore.Digest, vs indexer.VersionedScanners) ([]*claircore.Package, error) {
+	out := []*claircore.Package{}
+	err := s.artifacts(ctx, hash, vs, kindPackage, func(b []byte) error {
+		var ps []storedPackage
+		if err := json.Unmarshal(b, &ps); err != nil {
+			return err
+		}
+		for _, p := range ps {
+			p.Package.PackageDB = p.PackageDB
+			p.Package.RepositoryHint = p.RepositoryHint
+			out = append(out, p.Package)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DistributionsByLayer implements indexer.Querier.
+func (s *Store) DistributionsByLayer(ctx context.Context, hash claircore.Digest, vs indexer.VersionedScanners) ([]*claircore.Distribution, error) {
+	out := []*claircore.Distribution{}
+	err := s.artifacts(ctx, hash, vs, kindDistribution, func(b []byte) error {
+		var ds []*claircore.Distribution
+		if err := json.Unmarshal(b, &ds); err != nil {
+			return err
+		}
+		out = append(out, ds...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RepositoriesByLayer implements indexer.Querier.
+func (s *Store) RepositoriesByLayer(ctx context.Context, hash claircore.Digest, vs indexer.VersionedScanners) ([]*claircore.Repository, error) {
+	out := []*claircore.Repository{}
+	err := s.artifacts(ctx, hash, vs, kindRepository, func(b []byte) error {
+		var rs []*claircore.Repository
+		if err := json.Unmarshal(b, &rs); err != nil {
+			return err
+		}
+		out = append(out, rs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IndexReport implements indexer.Querier.
+func (s *Store) IndexReport(ctx context.Context, hash claircore.Digest) (*claircore.IndexReport, bool, error) {
+	var b []byte
+	err := s.db.QueryRowContext(ctx, `SELECT report FROM manifest WHERE hash = ?;`, hash.String()).Scan(&b)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("sqlite: unable to retrieve index report: %w", err)
+	}
+	if b == nil {
+		return nil, false, nil
+	}
+	var ir claircore.IndexReport
+	if err := json.Unmarshal(b, &ir); err != nil {
+		return nil, false, fmt.Errorf("sqlite: unable to decode index report: %w", err)
+	}
+	return &ir, true, nil
+}
+
+// AffectedManifests implements indexer.Querier.
+func (s *Store) AffectedManifests(ctx context.Context, v claircore.Vulnerability) ([]claircore.Digest, error) {
+	if v.Package == nil {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT manifest.hash, manifest_index.record
+FROM manifest_index
+JOIN manifest ON manifest.id = manifest_index.manifest_id
+WHERE manifest_index.package = ?
+ORDER BY manifest.hash;`, v.Package.Name)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: unable to find affected manifests: %w", err)
+	}
+	defer rows.Close()
+	om := omnimatcher.New(nil)
+	out := []claircore.Digest{}
+	var last string
+	for rows.Next() {
+		var h string
+		var b []byte
+		if err := rows.Scan(&h, &b); err != nil {
+			return nil, fmt.Errorf("sqlite: unable to find affected manifests: %w", err)
+		}
+		if h == last {
+			continue
+		}
+		var r claircore.IndexRecord
+		if err := json.Unmarshal(b, &r); err != nil {
+			return nil, fmt.Errorf("sqlite: unable to decode index record: %w", err)
+		}
+		if v.Dist != nil && (r.Distribution == nil || !sameDist(v.Dist, r.Distribution)) {
+			continue
+		}
+		if v.Repo != nil && (r.Repository == nil || v.Repo.Name != r.Repository.Name) {
+			continue
+		}
+		ok, err := om.Vulnerable(ctx, &r, &v)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		d, err := claircore.ParseDigest(h)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+		last = h
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: unable to find affected manifests: %w", err)
+	}
+	return out, nil
+}
+
+// SameDist reports whether the distribution described in a vulnerability
+// matches an indexed one, treating empty fields in the vulnerability as
+// wildcards.
+func sameDist(want, got *claircore.Distribution) bool {
+	eq := func(a, b string) bool { return a == "" || a == b }
+	return eq(want.DID, got.DID) &&
+		eq(want.Name, got.Name) &&
+		eq(want.Version, got.Version) &&
+		eq(want.VersionCodeName, got.VersionCodeName) &&
+		eq(want.VersionID, got.VersionID) &&
+		eq(want.Arch, got.Arch) &&
+		eq(want.PrettyName, got.PrettyName)
+}
+
+// BinariesBySource implements indexer.SourceFinder.
+func (s *Store) BinariesBySource(ctx context.Context, name, version string) ([]indexer.SourceBinary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT manifest.hash, manifest_index.record
+FROM manifest_index
+JOIN manifest ON manifest.id = manifest_index.manifest_id
+WHERE manifest_index.source = ?;`, name)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: unable to find binaries: %w", err)
+	}
+	defer rows.Close()
+	var out []indexer.SourceBinary
+	seen := make(map[string]struct{})
+	for rows.Next() {
+		var h string
+		var b []byte
+		if err := rows.Scan(&h, &b); err != nil {
+			return nil, fmt.Errorf("sqlite: unable to find binaries: %w", err)
+		}
+		var r claircore.IndexRecord
+		if err := json.Unmarshal(b, &r); err != nil {
+			return nil, fmt.Errorf("sqlite: unable to decode index record: %w", err)
+		}
+		p := r.Package
+		if version != "" && p.Source.Version != version {
+			continue
+		}
+		k := h + "\x00" + p.ID
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		d, err := claircore.ParseDigest(h)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, indexer.SourceBinary{Manifest: d, Package: p})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: unable to find binaries: %w", err)
+	}
+	indexer.SortSourceBinaries(out)
+	return out, nil
+}
+
+// SetArtifacts stores the artifacts found in the layer by the scanner,
+// replacing any already stored.
+func (s *Store) setArtifacts(ctx context.Context, l *claircore.Layer, scnr indexer.VersionedScanner, kind string, intern func(*sql.Tx) (interface{}, error)) error {
+	return s.tx(ctx, func(tx *sql.Tx) error {
+		lid, err := upsert(ctx, tx, "layer", "hash", l.Hash.String())
+		if err != nil {
+			return err
+		}
+		sid, err := scannerID(ctx, tx, scnr)
+		if err != nil {
+			return err
+		}
+		v, err := intern(tx)
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("sqlite: unable to encode %s artifacts: %w", kind, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO artifact (layer_id, scanner_id, kind, data) VALUES (?, ?, ?, ?)
+ON CONFLICT (layer_id, scanner_id, kind) DO UPDATE SET data = excluded.data;`,
+			lid, sid, kind, b); err != nil {
+			return fmt.Errorf("sqlite: unable to store %s artifacts: %w", kind, err)
+		}
+		return nil
+	})
+}
+
+// ArtifactID returns the stable ID for the artifact identified by the key
+// fields, as the coalescers rely on IDs to correlate artifacts across layers.
+func artifactID(ctx context.Context, q querier, kind string, fields ...string) (string, error) {
+	k := kind + "\x00" + strings.Join(fields, "\x00")
+	id, err := upsert(ctx, q, "artifact_id", "key", k)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(id), nil
+}
+
+func packageID(ctx context.Context, q querier, p *claircore.Package) (string, error) {
+	return artifactID(ctx, q, kindPackage,
+		p.Name, p.Kind, p.Version, p.Module, p.Arch,
+		p.NormalizedVersion.String())
+}
+
+// IndexPackages implements indexer.Indexer.
+func (s *Store) IndexPackages(ctx context.Context, pkgs []*claircore.Package, l *claircore.Layer, scnr indexer.VersionedScanner) error {
+	return s.setArtifacts(ctx, l, scnr, kindPackage, func(tx *sql.Tx) (interface{}, error) {
+		out := make([]storedPackage, 0, len(pkgs))
+		for _, p := range pkgs {
+			if p == nil {
+				continue
+			}
+			c := *p
+			id, err := packageID(ctx, tx, p)
+			if err != nil {
+				return nil, err
+			}
+			c.ID = id
+			src := claircore.Package{}
+			if p.Source != nil {
+				src = *p.Source
+			}
+			if src.ID, err = packageID(ctx, tx, &src); err != nil {
+				return nil, err
+			}
+			c.Source = &src
+			out = append(out, storedPackage{
+				Package:        &c,
+				PackageDB:      p.PackageDB,
+				RepositoryHint: p.RepositoryHint,
+			})
+		}
+		return out, nil
+	})
+}
+
+// IndexDistributions implements indexer.Indexer.
+func (s *Store) IndexDistributions(ctx context.Context, dists []*claircore.Distribution, l *claircore.Layer, scnr indexer.VersionedScanner) error {
+	return s.setArtifacts(ctx, l, scnr, kindDistribution, func(tx *sql.Tx) (interface{}, error) {
+		out := make([]*claircore.Distribution, 0, len(dists))
+		for _, d := range dists {
+			if d == nil {
+				continue
+			}
+			c := *d
+			id, err := artifactID(ctx, tx, kindDistribution,
+				d.Name, d.DID, d.Version, d.VersionCodeName,
+				d.VersionID, d.Arch, d.CPE.BindFS(), d.PrettyName)
+			if err != nil {
+				return nil, err
+			}
+			c.ID = id
+			out = append(out, &c)
+		}
+		return out, nil
+	})
+}
+
+// IndexRepositories implements indexer.Indexer.
+func (s *Store) IndexRepositories(ctx context.Context, repos []*claircore.Repository, l *claircore.Layer, scnr indexer.VersionedScanner) error {
+	return s.setArtifacts(ctx, l, scnr, kindRepository, func(tx *sql.Tx) (interface{}, error) {
+		out := make([]*claircore.Repository, 0, len(repos))
+		for _, r := range repos {
+			if r == nil {
+				continue
+			}
+			c := *r
+			id, err := artifactID(ctx, tx, kindRepository, r.Name, r.Key, r.URI, r.CPE.BindFS())
+			if err != nil {
+				return nil, err
+			}
+			c.ID = id
+			out = append(out, &c)
+		}
+		return out, nil
+	})
+}
+
+// IndexManifest implements indexer.Indexer.
+func (s *Store) IndexManifest(ctx context.Context, ir *claircore.IndexReport) error {
+	if ir.Hash.String() == "" {
+		return fmt.Errorf("received empty hash. cannot associate contents with a manifest hash")
+	}
+	return s.tx(ctx, func(tx *sql.Tx) error {
+		mid, err := upsert(ctx, tx, "manifest", "hash", ir.Hash.String())
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM manifest_index WHERE manifest_id = ?;`, mid); err != nil {
+			return fmt.Errorf("sqlite: unable to index manifest: %w", err)
+		}
+		add := func(r *claircore.IndexRecord, src interface{}) error {
+			b, err := json.Marshal(r)
+			if err != nil {
+				return fmt.Errorf("sqlite: unable to encode index record: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO manifest_index (manifest_id, package, source, record) VALUES (?, ?, ?, ?);`,
+				mid, r.Package.Name, src, b); err != nil {
+				return fmt.Errorf("sqlite: unable to index manifest: %w", err)
+			}
+			return nil
+		}
+		for _, r := range ir.IndexRecords() {
+			if r.Package == nil {
+				continue
+			}
+			var src interface{}
+			if r.Package.Source != nil && r.Package.Source.Name != "" {
+				src = r.Package.Source.Name
+			}
+			if err := add(r, src); err != nil {
+				return err
+			}
+			if src != nil {
+				sr := *r
+				sr.Package = r.Package.Source
+				if err := add(&sr, nil); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,254 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+type scanner struct{ name, version string }
+
+func (s scanner) Name() string    { return s.name }
+func (s scanner) Version() string { return s.version }
+func (s scanner) Kind() string    { return "package" }
+
+var cmpDigest = cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })
+
+func newStore(ctx context.Context, t *testing.T) *Store {
+	t.Helper()
+	s, err := NewStore(ctx, filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close(ctx) })
+	return s
+}
+
+func TestLayerArtifacts(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	name := filepath.Join(t.TempDir(), "index.db")
+	s, err := NewStore(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := claircore.Manifest{
+		Hash: claircore.MustParseDigest("sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		Layers: []*claircore.Layer{
+			{Hash: claircore.MustParseDigest("sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")},
+			{Hash: claircore.MustParseDigest("sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc")},
+		},
+	}
+	scnr := scanner{"dpkg", "1"}
+	if err := s.PersistManifest(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	l := m.Layers[0]
+	pkgs := []*claircore.Package{
+		{Name: "bash", Version: "5.1", Kind: claircore.BINARY, PackageDB: "var/lib/dpkg/status"},
+		{Name: "libc6", Version: "2.31", Kind: claircore.BINARY, Source: &claircore.Package{Name: "glibc", Version: "2.31", Kind: claircore.SOURCE}},
+	}
+	if err := s.IndexPackages(ctx, pkgs, l, scnr); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.IndexDistributions(ctx, []*claircore.Distribution{{Name: "Debian", DID: "debian", VersionID: "11"}}, l, scnr); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetLayerScanned(ctx, l.Hash, scnr); err != nil {
+		t.Fatal(err)
+	}
+	// Reopen, to check everything was written to the file.
+	if err := s.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s, err = NewStore(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close(ctx)
+
+	ok, err := s.LayerScanned(ctx, l.Hash, scnr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("layer not marked scanned")
+	}
+	ls, ok, err := s.ManifestLayers(ctx, m.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []claircore.Digest{m.Layers[0].Hash, m.Layers[1].Hash}; !ok || !cmp.Equal(ls, want, cmpDigest) {
+		t.Error(cmp.Diff(ls, want, cmpDigest))
+	}
+
+	got, err := s.PackagesByLayer(ctx, l.Hash, indexer.VersionedScanners{scnr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(pkgs) {
+		t.Fatalf("got: %d packages, want: %d", len(got), len(pkgs))
+	}
+	for i, p := range got {
+		if p.ID == "" || p.Source == nil || p.Source.ID == "" {
+			t.Errorf("package %d: missing IDs: %+v", i, p)
+		}
+		if got, want := p.Name, pkgs[i].Name; got != want {
+			t.Errorf("package %d: got: %q, want: %q", i, got, want)
+		}
+		if got, want := p.PackageDB, pkgs[i].PackageDB; got != want {
+			t.Errorf("package %d: got: %q, want: %q", i, got, want)
+		}
+	}
+	// IDs are stable across layers.
+	if err := s.IndexPackages(ctx, pkgs[:1], m.Layers[1], scnr); err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.PackagesByLayer(ctx, m.Layers[1].Hash, indexer.VersionedScanners{scnr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := other[0].ID, got[0].ID; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	dists, err := s.DistributionsByLayer(ctx, l.Hash, indexer.VersionedScanners{scnr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dists) != 1 || dists[0].DID != "debian" {
+		t.Errorf("unexpected distributions: %+v", dists)
+	}
+	repos, err := s.RepositoriesByLayer(ctx, l.Hash, indexer.VersionedScanners{scnr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 0 {
+		t.Errorf("unexpected repositories: %+v", repos)
+	}
+
+	rm, err := s.DeleteManifests(ctx, m.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rm) != 1 {
+		t.Errorf("got: %v, want: %v", rm, m.Hash)
+	}
+	ok, err = s.LayerScanned(ctx, l.Hash, scnr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("layer not removed with manifest")
+	}
+}
+
+func TestStaleManifests(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	s := newStore(ctx, t)
+	old := indexer.VersionedScanners{scanner{"rpm", "1"}, scanner{"dpkg", "1"}}
+	cur := indexer.VersionedScanners{scanner{"rpm", "2"}, scanner{"dpkg", "1"}}
+	a := claircore.MustParseDigest("sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	b := claircore.MustParseDigest("sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	if err := s.SetIndexFinished(ctx, &claircore.IndexReport{Hash: a}, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetIndexFinished(ctx, &claircore.IndexReport{Hash: b}, cur); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.StaleManifests(ctx, cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []indexer.StaleManifest{
+		{
+			Manifest: a,
+			Missing:  []indexer.ScannerInfo{{Name: "rpm", Version: "2", Kind: "package"}},
+			Outdated: []indexer.ScannerInfo{{Name: "rpm", Version: "1", Kind: "package"}},
+		},
+	}
+	if !cmp.Equal(got, want, cmpDigest) {
+		t.Error(cmp.Diff(got, want, cmpDigest))
+	}
+
+	ok, err := s.ManifestScanned(ctx, b, cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("manifest not marked scanned")
+	}
+	ir, ok, err := s.IndexReport(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || ir.Hash.String() != b.String() {
+		t.Errorf("unexpected index report: %+v", ir)
+	}
+}
+
+func TestBinariesBySource(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	s := newStore(ctx, t)
+	a := claircore.MustParseDigest("sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	b := claircore.MustParseDigest("sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	src := func(v string) *claircore.Package {
+		return &claircore.Package{ID: "src-" + v, Name: "openssl", Version: v, Kind: claircore.SOURCE}
+	}
+	bin := func(id, name, v string) *claircore.Package {
+		return &claircore.Package{ID: id, Name: name, Version: v, Kind: claircore.BINARY, Source: src(v)}
+	}
+	report := func(h claircore.Digest, ps ...*claircore.Package) *claircore.IndexReport {
+		ir := &claircore.IndexReport{
+			Hash:         h,
+			Packages:     make(map[string]*claircore.Package),
+			Environments: make(map[string][]*claircore.Environment),
+		}
+		for _, p := range ps {
+			ir.Packages[p.ID] = p
+			ir.Environments[p.ID] = []*claircore.Environment{{PackageDB: "var/lib/dpkg/status"}}
+		}
+		return ir
+	}
+	irs := []*claircore.IndexReport{
+		report(a,
+			bin("1", "libssl1.1", "1.1.1k-1"),
+			bin("2", "openssl", "1.1.1k-1"),
+			&claircore.Package{ID: "3", Name: "libc6", Version: "2.31-13", Kind: claircore.BINARY},
+		),
+		report(b, bin("4", "libssl1.1", "1.1.1n-0")),
+	}
+	for _, ir := range irs {
+		if err := s.IndexManifest(ctx, ir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := s.BinariesBySource(ctx, "openssl", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []indexer.SourceBinary{
+		{Manifest: a, Package: irs[0].Packages["1"]},
+		{Manifest: a, Package: irs[0].Packages["2"]},
+		{Manifest: b, Package: irs[1].Packages["4"]},
+	}
+	if !cmp.Equal(got, want, cmpDigest) {
+		t.Error(cmp.Diff(got, want, cmpDigest))
+	}
+
+	got, err = s.BinariesBySource(ctx, "openssl", "1.1.1n-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = want[2:]
+	if !cmp.Equal(got, want, cmpDigest) {
+		t.Error(cmp.Diff(got, want, cmpDigest))
+	}
+}
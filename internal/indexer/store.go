@@ -2,6 +2,10 @@ package indexer
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
 
 	"github.com/quay/claircore"
 )
@@ -82,3 +86,129 @@ type Indexer interface {
 	// IndexManifest should index the coalesced manifest's content given an IndexReport.
 	IndexManifest(ctx context.Context, ir *claircore.IndexReport) error
 }
+
+// Checkpointer is an optional interface a Store may implement to record
+// per-layer progress of an Index operation.
+//
+// A checkpoint means every scanner in a set has completed against the layer
+// and its results have been indexed. The LayerScanner uses checkpoints to skip
+// already-finished layers when an interrupted Index operation is resumed,
+// without issuing a LayerScanned query per (layer, scanner) pair.
+//
+// Checkpoints are only valid for the scanner set that made them, so stores
+// key them by CheckpointKey: a resumed operation with different scanners, such
+// as after an upgrade, doesn't see the previous operation's checkpoints.
+type Checkpointer interface {
+	// SetLayerCheckpoint records that the layer is completely scanned by the
+	// scanners in the context of the manifest.
+	SetLayerCheckpoint(ctx context.Context, manifest, layer claircore.Digest, scnrs VersionedScanners) error
+	// LayerCheckpoints reports the layers of the manifest that have been
+	// checkpointed for the scanners.
+	LayerCheckpoints(ctx context.Context, manifest claircore.Digest, scnrs VersionedScanners) ([]claircore.Digest, error)
+	// ClearCheckpoints removes all checkpoints for the manifest. It's called
+	// once an Index operation has finished.
+	ClearCheckpoints(ctx context.Context, manifest claircore.Digest) error
+}
+
+// CheckpointKey returns a string identifying the set of scanners, for keying
+// checkpoints. It doesn't depend on the order of the scanners.
+func CheckpointKey(scnrs VersionedScanners) string {
+	ss := make([]string, len(scnrs))
+	for i, s := range scnrs {
+		ss[i] = s.Kind() + "/" + s.Name() + "/" + s.Version()
+	}
+	sort.Strings(ss)
+	h := sha256.Sum256([]byte(strings.Join(ss, "\n")))
+	return hex.EncodeToString(h[:])
+}
+
+// ScannerInfo identifies a versioned scanner.
+type ScannerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// String implements fmt.Stringer.
+func (i ScannerInfo) String() string {
+	return i.Kind + "/" + i.Name + "@" + i.Version
+}
+
+// StaleManifest describes a manifest whose stored results weren't produced by
+// the current set of scanners.
+type StaleManifest struct {
+	Manifest claircore.Digest `json:"manifest"`
+	// Missing are the current scanners that haven't indexed the manifest.
+	Missing []ScannerInfo `json:"missing"`
+	// Outdated are the scanners that indexed the manifest but aren't in the
+	// current set, typically older versions of current scanners.
+	Outdated []ScannerInfo `json:"outdated"`
+}
+
+// StaleFinder is an optional interface a Store may implement to report the
+// manifests that need to be re-indexed to have results from the current set
+// of scanners.
+type StaleFinder interface {
+	// StaleManifests reports the completely indexed manifests that weren't
+	// indexed by all of the provided scanners.
+	StaleManifests(ctx context.Context, vs VersionedScanners) ([]StaleManifest, error)
+}
+
+// SortStaleManifests sorts the StaleManifests by digest, and the scanners
+// within each.
+func SortStaleManifests(ms []StaleManifest) {
+	for _, m := range ms {
+		sortScanners(m.Missing)
+		sortScanners(m.Outdated)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Manifest.String() < ms[j].Manifest.String() })
+}
+
+func sortScanners(s []ScannerInfo) {
+	sort.Slice(s, func(i, j int) bool { return s[i].String() < s[j].String() })
+}
+
+// SourceBinary is a binary package found in a manifest, with the source
+// package it was built from as its Source.
+type SourceBinary struct {
+	Manifest claircore.Digest   `json:"manifest"`
+	Package  *claircore.Package `json:"package"`
+}
+
+// SourceFinder is an optional interface a Store may implement to query the
+// relationships between binary packages and the source packages they were
+// built from.
+//
+// This is useful when an advisory only names a source package, such as
+// "openssl", and the binary packages it was built into ("libssl1.1",
+// "openssl", "libssl-dev") are wanted.
+type SourceFinder interface {
+	// BinariesBySource reports the binary packages built from source
+	// packages with the provided name in all indexed manifests. If version
+	// isn't empty, only source packages with that exact version are
+	// considered.
+	BinariesBySource(ctx context.Context, name, version string) ([]SourceBinary, error)
+}
+
+// SortSourceBinaries sorts the SourceBinaries by manifest digest, then by
+// package name and version.
+func SortSourceBinaries(bs []SourceBinary) {
+	sort.Slice(bs, func(i, j int) bool {
+		a, b := &bs[i], &bs[j]
+		if a.Manifest.String() != b.Manifest.String() {
+			return a.Manifest.String() < b.Manifest.String()
+		}
+		if a.Package.Name != b.Package.Name {
+			return a.Package.Name < b.Package.Name
+		}
+		return a.Package.Version < b.Package.Version
+	})
+}
+
+// LayerLister is an optional interface a Store may implement to report the
+// layers of a stored manifest.
+type LayerLister interface {
+	// ManifestLayers reports the layers of the manifest, in order. It
+	// reports false if the manifest isn't known.
+	ManifestLayers(ctx context.Context, manifest claircore.Digest) ([]claircore.Digest, bool, error)
+}
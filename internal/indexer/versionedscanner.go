@@ -1,43 +1,26 @@
 package indexer
 
-import (
-	"context"
-	"net/http"
-)
-
-const (
-	Package = "package"
-)
-
-// VersionedScanner can be embedded into specific scanner types. This allows for
-// methods and functions which only need to compare names and versions of
-// scanners not to require each scanner type as an argument.
-type VersionedScanner interface {
-	// unique name of the distribution scanner.
-	Name() string
-	// version of this scanner. this information will be persisted with the scan.
-	Version() string
-	// the kind of scanner. currently only package is implemented
-	Kind() string
-}
+import "github.com/quay/claircore/libindex/driver"
 
-// ConfigDeserializer can be thought of as an Unmarshal function with the byte
-// slice provided.
-//
-// This will typically be something like (*json.Decoder).Decode.
-type ConfigDeserializer func(interface{}) error
+// Package is the Kind reported by PackageScanners.
+const Package = driver.Package
 
-// RPCScanner is an interface scanners can implement to receive configuration
-// and denote that they expect to be able to talk to the network at run time.
-type RPCScanner interface {
-	Configure(context.Context, ConfigDeserializer, *http.Client) error
-}
+// Content is the Kind reported by ContentScanners.
+const Content = driver.Content
 
-// ConfigurableScanner is an interface scanners can implement to receive
-// configuration.
-type ConfigurableScanner interface {
-	Configure(context.Context, ConfigDeserializer) error
-}
+// The scanner interfaces are defined in the public libindex/driver package so
+// that scanners can be implemented outside this module. These aliases keep
+// the rest of the indexer code unchanged.
+type (
+	VersionedScanner    = driver.VersionedScanner
+	ConfigDeserializer  = driver.ConfigDeserializer
+	RPCScanner          = driver.RPCScanner
+	ConfigurableScanner = driver.ConfigurableScanner
+	FileLimiter         = driver.FileLimiter
+	PathDeclarer        = driver.PathDeclarer
+	ContentScanner      = driver.ContentScanner
+	ConfidenceReporter  = driver.ConfidenceReporter
+)
 
 // VersionedScanners implements a list with construction methods
 // not concurrency safe
@@ -46,6 +46,11 @@ func (mc *Controller) Match(ctx context.Context, records []*claircore.IndexRecor
 		return map[string][]*claircore.Vulnerability{}, nil
 	}
 
+	interested, err := mc.resolve(ctx, interested)
+	if err != nil {
+		return nil, err
+	}
+
 	remoteMatcher, matchedVulns, err := mc.queryRemoteMatcher(ctx, interested)
 	if remoteMatcher {
 		if err != nil {
@@ -84,6 +89,16 @@ func (mc *Controller) Match(ctx context.Context, records []*claircore.IndexRecor
 	return filteredVulns, nil
 }
 
+// Resolve lets the Matcher refine the interesting records, if it implements
+// driver.Resolver.
+func (mc *Controller) resolve(ctx context.Context, interested []*claircore.IndexRecord) ([]*claircore.IndexRecord, error) {
+	r, ok := mc.m.(driver.Resolver)
+	if !ok {
+		return interested, nil
+	}
+	return r.Resolve(ctx, interested)
+}
+
 // If RemoteMatcher exists, it will call the matcher service which runs on a remote
 // machine and fetches the vulnerabilities associated with the IndexRecords.
 func (mc *Controller) queryRemoteMatcher(ctx context.Context, interested []*claircore.IndexRecord) (bool, map[string][]*claircore.Vulnerability, error) {
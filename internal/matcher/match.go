@@ -59,8 +59,7 @@ func Match(ctx context.Context, ir *claircore.IndexReport, matchers []driver.Mat
 	for vulnsByPackage := range ctrlC {
 		for pkgID, vulns := range vulnsByPackage {
 			for _, vuln := range vulns {
-				vr.Vulnerabilities[vuln.ID] = vuln
-				vr.PackageVulnerabilities[pkgID] = append(vr.PackageVulnerabilities[pkgID], vuln.ID)
+				addVulnerability(vr, pkgID, vuln)
 			}
 		}
 	}
@@ -69,9 +68,26 @@ func Match(ctx context.Context, ir *claircore.IndexReport, matchers []driver.Mat
 		return nil, err
 	default:
 	}
+	vr.Sort()
 	return vr, nil
 }
 
+// AddVulnerability records that the vulnerability affects the package.
+//
+// Withdrawn vulnerabilities are only noted, so that a finding that
+// disappears from a report can be explained.
+func addVulnerability(vr *claircore.VulnerabilityReport, pkgID string, v *claircore.Vulnerability) {
+	if v.Withdrawn {
+		if vr.WithdrawnVulnerabilities == nil {
+			vr.WithdrawnVulnerabilities = make(map[string][]string)
+		}
+		vr.WithdrawnVulnerabilities[pkgID] = append(vr.WithdrawnVulnerabilities[pkgID], v.Name)
+		return
+	}
+	vr.Vulnerabilities[v.ID] = v
+	vr.PackageVulnerabilities[pkgID] = append(vr.PackageVulnerabilities[pkgID], v.ID)
+}
+
 // Store is the interface that can retrieve Enrichments and Vulnerabilities.
 type Store interface {
 	vulnstore.Vulnerability
@@ -144,8 +160,7 @@ func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.M
 		for pkgVuln := range vCh {
 			for pkg, vs := range pkgVuln {
 				for _, v := range vs {
-					vr.Vulnerabilities[v.ID] = v
-					vr.PackageVulnerabilities[pkg] = append(vr.PackageVulnerabilities[pkg], v.ID)
+					addVulnerability(vr, pkg, v)
 				}
 			}
 		}
@@ -224,6 +239,7 @@ func EnrichedMatch(ctx context.Context, ir *claircore.IndexReport, ms []driver.M
 		return nil, err
 	}
 
+	vr.Sort()
 	return vr, nil
 }
 
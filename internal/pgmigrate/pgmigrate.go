@@ -0,0 +1,202 @@
+// Package pgmigrate runs database migrations so that several processes
+// starting at once against the same database don't race each other.
+//
+// The migrate package's Postgres locker takes a session-level advisory lock
+// through a *sql.DB, so the lock, the migrations, and the unlock may all
+// happen on different connections. This package holds one connection for the
+// whole run, takes a lock keyed by the migration table, and checks the
+// versions recorded in the table against the migrations it knows about before
+// applying anything.
+package pgmigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/quay/zlog"
+	"github.com/remind101/migrate"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+)
+
+// ErrSchemaNewer is returned by a Strict Migrator if the database has
+// migrations applied that the Migrator doesn't know about, meaning a newer
+// version of the code has already migrated it.
+var ErrSchemaNewer = errors.New("pgmigrate: database schema is newer than expected")
+
+// Migrator applies migrations, recording them in the same table format as the
+// migrate package.
+type Migrator struct {
+	// Table is where applied migrations are recorded.
+	Table string
+	// Migrations are the known migrations, in any order.
+	Migrations []migrate.Migration
+	// Strict causes ErrSchemaNewer to be returned if the database is at a
+	// newer version than the Migrations describe. Otherwise, this is only
+	// logged.
+	Strict bool
+}
+
+// Up takes the advisory lock for the Migrator's table, checks the schema
+// version, and applies any pending migrations, each in its own transaction.
+//
+// Processes calling Up concurrently wait on each other, and all but the first
+// find nothing to do.
+func (m *Migrator) Up(ctx context.Context, db *sql.DB) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pgmigrate/Migrator.Up"),
+		label.String("table", m.Table))
+	// Session-level advisory locks belong to a connection, so everything
+	// needs to happen on the same one.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("pgmigrate: unable to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	key := m.lockKey()
+	zlog.Debug(ctx).Int64("key", key).Msg("waiting for migration lock")
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, key); err != nil {
+		return fmt.Errorf("pgmigrate: unable to take lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh Context so the lock is released even if the passed-in
+		// one is canceled. Closing the connection would also release it.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, key); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to release migration lock")
+		}
+	}()
+	zlog.Debug(ctx).Msg("took migration lock")
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.Table+` (version integer primary key not null);`); err != nil {
+		return fmt.Errorf("pgmigrate: unable to create table: %w", err)
+	}
+	applied, err := appliedVersions(ctx, conn, m.Table)
+	if err != nil {
+		return err
+	}
+	if err := m.handshake(ctx, applied); err != nil {
+		return err
+	}
+
+	ms := make([]migrate.Migration, len(m.Migrations))
+	copy(ms, m.Migrations)
+	sort.Sort(migrate.ByID(ms))
+	for _, mig := range ms {
+		if _, ok := applied[mig.ID]; ok {
+			continue
+		}
+		if err := m.apply(ctx, conn, mig); err != nil {
+			return err
+		}
+		zlog.Info(ctx).Int("id", mig.ID).Msg("applied migration")
+	}
+	return nil
+}
+
+// Check reports whether the database's schema is compatible with the
+// Migrator, without applying anything.
+//
+// A database that hasn't been migrated at all is not an error.
+func (m *Migrator) Check(ctx context.Context, db *sql.DB) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pgmigrate/Migrator.Check"),
+		label.String("table", m.Table))
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("pgmigrate: unable to get connection: %w", err)
+	}
+	defer conn.Close()
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL;`, m.Table).Scan(&exists); err != nil {
+		return fmt.Errorf("pgmigrate: unable to check for table: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+	applied, err := appliedVersions(ctx, conn, m.Table)
+	if err != nil {
+		return err
+	}
+	return m.handshake(ctx, applied)
+}
+
+// Handshake compares the applied versions to the known migrations.
+func (m *Migrator) handshake(ctx context.Context, applied map[int]struct{}) error {
+	known := make(map[int]struct{}, len(m.Migrations))
+	for _, mig := range m.Migrations {
+		known[mig.ID] = struct{}{}
+	}
+	var unknown []int
+	for v := range applied {
+		if _, ok := known[v]; !ok {
+			unknown = append(unknown, v)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Ints(unknown)
+	if m.Strict {
+		return fmt.Errorf("%w: unknown migrations applied: %v", ErrSchemaNewer, unknown)
+	}
+	zlog.Warn(ctx).
+		Ints("unknown", unknown).
+		Msg("database schema is newer than expected; a newer version may be running")
+	return nil
+}
+
+// Apply runs the migration and records it in a single transaction.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig migrate.Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("pgmigrate: unable to begin transaction: %w", err)
+	}
+	if err := mig.Up(tx); err != nil {
+		tx.Rollback()
+		return &migrate.MigrationError{Migration: mig, Err: err}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO `+m.Table+` (version) VALUES ($1);`, mig.ID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("pgmigrate: unable to record migration %d: %w", mig.ID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("pgmigrate: unable to commit migration %d: %w", mig.ID, err)
+	}
+	return nil
+}
+
+// LockKey returns the advisory lock key for the Migrator's table.
+//
+// Keying on the table lets migrations for different tables sharing a
+// database run independently.
+func (m *Migrator) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("pgmigrate:"))
+	h.Write([]byte(m.Table))
+	return int64(h.Sum64())
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn, table string) (map[int]struct{}, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM `+table+`;`)
+	if err != nil {
+		return nil, fmt.Errorf("pgmigrate: unable to read versions: %w", err)
+	}
+	defer rows.Close()
+	applied := make(map[int]struct{})
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("pgmigrate: unable to read versions: %w", err)
+		}
+		applied[v] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("pgmigrate: unable to read versions: %w", err)
+	}
+	return applied, nil
+}
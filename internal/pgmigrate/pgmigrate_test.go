@@ -0,0 +1,100 @@
+package pgmigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/quay/zlog"
+	"github.com/remind101/migrate"
+
+	"github.com/quay/claircore/test/integration"
+)
+
+func TestMain(m *testing.M) {
+	var c int
+	defer func() { os.Exit(c) }()
+	defer integration.DBSetup()()
+	c = m.Run()
+}
+
+func TestMigrator(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	db, err := integration.NewDB(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close(ctx, t) })
+	cfg := db.Config()
+	dbh := stdlib.OpenDB(*cfg.ConnConfig)
+	defer dbh.Close()
+
+	// The second migration fails if it's run twice.
+	ms := []migrate.Migration{
+		{ID: 2, Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO test_table (n) VALUES (1);`)
+			return err
+		}},
+		{ID: 1, Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE test_table (n integer UNIQUE);`)
+			return err
+		}},
+	}
+
+	t.Run("Concurrent", func(t *testing.T) {
+		const n = 8
+		errs := make(chan error, n)
+		var wg sync.WaitGroup
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			go func() {
+				defer wg.Done()
+				m := Migrator{Table: "test_migrations", Migrations: ms}
+				errs <- m.Up(ctx, dbh)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		var ct int
+		if err := dbh.QueryRowContext(ctx, `SELECT count(*) FROM test_table;`).Scan(&ct); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ct, 1; got != want {
+			t.Errorf("got: %d rows, want: %d", got, want)
+		}
+	})
+
+	t.Run("Newer", func(t *testing.T) {
+		older := Migrator{Table: "test_migrations", Migrations: ms[1:]}
+		if err := older.Up(ctx, dbh); err != nil {
+			t.Errorf("non-strict Up: %v", err)
+		}
+		if err := older.Check(ctx, dbh); err != nil {
+			t.Errorf("non-strict Check: %v", err)
+		}
+		older.Strict = true
+		if err := older.Up(ctx, dbh); !errors.Is(err, ErrSchemaNewer) {
+			t.Errorf("strict Up: got: %v, want: %v", err, ErrSchemaNewer)
+		}
+		if err := older.Check(ctx, dbh); !errors.Is(err, ErrSchemaNewer) {
+			t.Errorf("strict Check: got: %v, want: %v", err, ErrSchemaNewer)
+		}
+	})
+
+	t.Run("Unmigrated", func(t *testing.T) {
+		m := Migrator{Table: "other_migrations", Migrations: ms, Strict: true}
+		if err := m.Check(ctx, dbh); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,441 @@
+// Package sqlite reads tables from SQLite database files.
+//
+// It implements just enough of the file format to scan every row of a table,
+// for importing data distributed as SQLite databases without a cgo
+// dependency. There's no query support, indexes are ignored, and databases
+// must use the UTF-8 text encoding. Changes in a write-ahead log that haven't
+// been checkpointed aren't seen.
+package sqlite
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Magic is the header string every database file starts with.
+const magic = "SQLite format 3\x00"
+
+// Page types used by table b-trees.
+const (
+	pageTableInterior = 0x05
+	pageTableLeaf     = 0x0d
+)
+
+// DB is an open database file.
+type DB struct {
+	r        io.ReaderAt
+	pageSize int
+	usable   int
+	pages    int
+	tables   map[string]*table
+}
+
+// Table is a table's definition.
+type table struct {
+	root    int
+	columns []string
+	// Rowid is the index of the column aliasing the rowid, or -1.
+	rowid int
+}
+
+// Open reads the database's schema from the ReaderAt.
+func Open(r io.ReaderAt) (*DB, error) {
+	var h [100]byte
+	if _, err := r.ReadAt(h[:], 0); err != nil {
+		return nil, fmt.Errorf("sqlite: unable to read header: %w", err)
+	}
+	if string(h[:16]) != magic {
+		return nil, errors.New("sqlite: not a database file")
+	}
+	db := DB{r: r, tables: make(map[string]*table)}
+	db.pageSize = int(binary.BigEndian.Uint16(h[16:]))
+	if db.pageSize == 1 {
+		db.pageSize = 65536
+	}
+	if db.pageSize < 512 || db.pageSize&(db.pageSize-1) != 0 {
+		return nil, fmt.Errorf("sqlite: bad page size %d", db.pageSize)
+	}
+	db.usable = db.pageSize - int(h[20])
+	db.pages = int(binary.BigEndian.Uint32(h[28:]))
+	if enc := binary.BigEndian.Uint32(h[56:]); enc != 0 && enc != 1 {
+		return nil, fmt.Errorf("sqlite: unsupported text encoding %d", enc)
+	}
+
+	// The schema table is rooted at the first page, and always has the same
+	// columns.
+	schema := table{
+		root:    1,
+		columns: []string{"type", "name", "tbl_name", "rootpage", "sql"},
+		rowid:   -1,
+	}
+	err := db.scan(&schema, func(row Row) error {
+		if row.String("type") != "table" {
+			return nil
+		}
+		t, err := parseCreate(row.String("sql"))
+		if err != nil {
+			return fmt.Errorf("table %q: %w", row.String("name"), err)
+		}
+		t.root = int(row.Int("rootpage"))
+		db.tables[row.String("name")] = t
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: unable to read schema: %w", err)
+	}
+	return &db, nil
+}
+
+// Row is a row of a table, keyed by column name. Values are nil, int64,
+// float64, string, or []byte.
+type Row map[string]interface{}
+
+// String returns the named column as a string, or "" if it isn't text or a
+// blob.
+func (r Row) String(col string) string {
+	switch v := r[col].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// Int returns the named column as an integer, or 0 if it isn't one.
+func (r Row) Int(col string) int64 {
+	v, _ := r[col].(int64)
+	return v
+}
+
+// ErrNoTable is returned by Scan if the database doesn't have the table.
+var ErrNoTable = errors.New("sqlite: no such table")
+
+// Scan calls the function with every row of the table, in rowid order. An
+// error returned by the function stops the scan and is returned.
+//
+// The Row is reused between calls.
+func (db *DB) Scan(name string, f func(Row) error) error {
+	t, ok := db.tables[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNoTable, name)
+	}
+	return db.scan(t, f)
+}
+
+// HasTable reports whether the database has the table.
+func (db *DB) HasTable(name string) bool {
+	_, ok := db.tables[name]
+	return ok
+}
+
+func (db *DB) scan(t *table, f func(Row) error) error {
+	row := make(Row, len(t.columns))
+	var buf []byte
+	var walk func(n, depth int) error
+	walk = func(n, depth int) error {
+		// The depth of a b-tree is bounded by the number of pages; this
+		// catches cycles in corrupt files.
+		if depth > 64 {
+			return errors.New("sqlite: b-tree too deep")
+		}
+		p, err := db.page(n)
+		if err != nil {
+			return err
+		}
+		off := 0
+		if n == 1 {
+			off = 100
+		}
+		typ := p[off]
+		cells := int(binary.BigEndian.Uint16(p[off+3:]))
+		hdr := 8
+		if typ == pageTableInterior {
+			hdr = 12
+		}
+		for i := 0; i < cells; i++ {
+			at := off + hdr + 2*i
+			if at+2 > len(p) {
+				return errors.New("sqlite: bad cell pointer")
+			}
+			c := int(binary.BigEndian.Uint16(p[at:]))
+			if c >= len(p) {
+				return errors.New("sqlite: bad cell pointer")
+			}
+			switch typ {
+			case pageTableInterior:
+				if c+4 > len(p) {
+					return errors.New("sqlite: bad cell")
+				}
+				child := int(binary.BigEndian.Uint32(p[c:]))
+				if err := walk(child, depth+1); err != nil {
+					return err
+				}
+			case pageTableLeaf:
+				var rowid int64
+				buf, rowid, err = db.payload(p, c, buf[:0])
+				if err != nil {
+					return err
+				}
+				if err := decodeRecord(buf, t, rowid, row); err != nil {
+					return err
+				}
+				if err := f(row); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("sqlite: unexpected page type %#x", typ)
+			}
+		}
+		if typ == pageTableInterior {
+			right := int(binary.BigEndian.Uint32(p[off+8:]))
+			return walk(right, depth+1)
+		}
+		return nil
+	}
+	return walk(t.root, 0)
+}
+
+// Page reads the numbered page.
+func (db *DB) page(n int) ([]byte, error) {
+	if n < 1 || (db.pages != 0 && n > db.pages) {
+		return nil, fmt.Errorf("sqlite: bad page number %d", n)
+	}
+	p := make([]byte, db.pageSize)
+	if _, err := db.r.ReadAt(p, int64(n-1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("sqlite: unable to read page %d: %w", n, err)
+	}
+	return p, nil
+}
+
+// Payload appends the payload of the table leaf cell at offset "c" in the
+// page to "buf", following any overflow pages, and returns it along with the
+// cell's rowid.
+func (db *DB) payload(p []byte, c int, buf []byte) ([]byte, int64, error) {
+	size, n := varint(p[c:])
+	if n == 0 {
+		return nil, 0, errors.New("sqlite: bad cell")
+	}
+	c += n
+	rowid, n := varint(p[c:])
+	if n == 0 {
+		return nil, 0, errors.New("sqlite: bad cell")
+	}
+	c += n
+
+	total := int(size)
+	u := db.usable
+	local := total
+	if x := u - 35; total > x {
+		m := ((u-12)*32/255 - 23)
+		k := m + (total-m)%(u-4)
+		local = m
+		if k <= x {
+			local = k
+		}
+	}
+	if c+local > len(p) {
+		return nil, 0, errors.New("sqlite: bad cell")
+	}
+	buf = append(buf, p[c:c+local]...)
+	if local == total {
+		return buf, int64(rowid), nil
+	}
+	if c+local+4 > len(p) {
+		return nil, 0, errors.New("sqlite: bad cell")
+	}
+	next := int(binary.BigEndian.Uint32(p[c+local:]))
+	for len(buf) < total {
+		if next == 0 {
+			return nil, 0, errors.New("sqlite: overflow chain too short")
+		}
+		op, err := db.page(next)
+		if err != nil {
+			return nil, 0, err
+		}
+		next = int(binary.BigEndian.Uint32(op))
+		want := total - len(buf)
+		if want > u-4 {
+			want = u - 4
+		}
+		buf = append(buf, op[4:4+want]...)
+	}
+	return buf, int64(rowid), nil
+}
+
+// DecodeRecord decodes a record into the row.
+func decodeRecord(b []byte, t *table, rowid int64, row Row) error {
+	hl, n := varint(b)
+	if n == 0 || int(hl) > len(b) {
+		return errors.New("sqlite: bad record header")
+	}
+	hdr, body := b[n:hl], b[hl:]
+	for i, col := range t.columns {
+		if len(hdr) == 0 {
+			// Columns added after the row was written are absent.
+			row[col] = nil
+			if i == t.rowid {
+				row[col] = rowid
+			}
+			continue
+		}
+		st, n := varint(hdr)
+		if n == 0 {
+			return errors.New("sqlite: bad record header")
+		}
+		hdr = hdr[n:]
+		var v interface{}
+		var sz int
+		switch {
+		case st == 0:
+		case st >= 1 && st <= 6:
+			sz = [...]int{0, 1, 2, 3, 4, 6, 8}[st]
+			if len(body) < sz {
+				return errors.New("sqlite: short record")
+			}
+			v = intBE(body[:sz])
+		case st == 7:
+			sz = 8
+			if len(body) < sz {
+				return errors.New("sqlite: short record")
+			}
+			v = math.Float64frombits(binary.BigEndian.Uint64(body))
+		case st == 8:
+			v = int64(0)
+		case st == 9:
+			v = int64(1)
+		case st >= 12:
+			sz = int(st-12) / 2
+			if len(body) < sz {
+				return errors.New("sqlite: short record")
+			}
+			if st%2 == 0 {
+				v = append([]byte(nil), body[:sz]...)
+			} else {
+				v = string(body[:sz])
+			}
+		default:
+			return fmt.Errorf("sqlite: bad serial type %d", st)
+		}
+		body = body[sz:]
+		if i == t.rowid && v == nil {
+			v = rowid
+		}
+		row[col] = v
+	}
+	return nil
+}
+
+// IntBE decodes a big-endian two's complement integer.
+func intBE(b []byte) int64 {
+	var v int64
+	if b[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+// Varint decodes a SQLite variable-length integer, returning it and the number
+// of bytes read. Zero bytes read means the input was too short.
+func varint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	panic("unreachable")
+}
+
+// ParseCreate parses the column names out of a "CREATE TABLE" statement.
+func parseCreate(sql string) (*table, error) {
+	open, close := strings.IndexByte(sql, '('), strings.LastIndexByte(sql, ')')
+	if open == -1 || close < open {
+		return nil, errors.New("unable to parse table definition")
+	}
+	t := table{rowid: -1}
+	for _, def := range splitDefs(sql[open+1 : close]) {
+		f := strings.Fields(def)
+		if len(f) == 0 {
+			continue
+		}
+		switch strings.ToUpper(f[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			continue
+		}
+		name, rest := columnName(strings.TrimSpace(def))
+		up := strings.ToUpper(strings.Join(strings.Fields(rest), " "))
+		if strings.HasPrefix(up, "INTEGER") && strings.Contains(up, "PRIMARY KEY") {
+			t.rowid = len(t.columns)
+		}
+		t.columns = append(t.columns, name)
+	}
+	if len(t.columns) == 0 {
+		return nil, errors.New("no columns")
+	}
+	return &t, nil
+}
+
+// ColumnName splits the column name from the start of a column definition,
+// removing any quoting.
+func columnName(def string) (name, rest string) {
+	var end byte
+	switch def[0] {
+	case '"', '`', '\'':
+		end = def[0]
+	case '[':
+		end = ']'
+	default:
+		if i := strings.IndexAny(def, " \t\n\r"); i != -1 {
+			return def[:i], def[i:]
+		}
+		return def, ""
+	}
+	i := strings.IndexByte(def[1:], end)
+	if i == -1 {
+		return def[1:], ""
+	}
+	return def[1 : i+1], def[i+2:]
+}
+
+// SplitDefs splits a table's definitions on the commas not inside
+// parentheses or quotes.
+func splitDefs(s string) []string {
+	var out []string
+	var depth int
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The test database has 500 rows in 512-byte pages, so the table is spread
+// over interior pages and some rows spill onto overflow pages. A column was
+// added after the rows were written.
+func TestScan(t *testing.T) {
+	f, err := os.Open("testdata/test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	db, err := Open(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !db.HasTable("rows") {
+		t.Fatal("missing table")
+	}
+	if err := db.Scan("nope", nil); !errors.Is(err, ErrNoTable) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var i int64
+	err = db.Scan("rows", func(r Row) error {
+		i++
+		if got, want := r.Int("id"), i; got != want {
+			return fmt.Errorf("id: got: %d, want: %d", got, want)
+		}
+		if got, want := r.String("name"), fmt.Sprintf("row-%d", i); got != want {
+			return fmt.Errorf("name: got: %q, want: %q", got, want)
+		}
+		n := i * 7
+		if i%2 == 1 {
+			n = i * -1000003
+		}
+		if got, want := r.Int("n"), n; got != want {
+			return fmt.Errorf("row %d: n: got: %d, want: %d", i, got, want)
+		}
+		var f float64
+		switch v := r["f"].(type) {
+		case float64:
+			f = v
+		case int64:
+			// Whole numbers in REAL columns may be stored as integers.
+			f = float64(v)
+		}
+		if got, want := f, float64(i)/4; got != want {
+			return fmt.Errorf("row %d: f: got: %v, want: %v", i, got, want)
+		}
+		if got, want := r["b"].([]byte), bytes.Repeat([]byte{byte(i)}, 3); !bytes.Equal(got, want) {
+			return fmt.Errorf("row %d: b: got: %v, want: %v", i, got, want)
+		}
+		var long interface{}
+		if i%50 == 0 {
+			long = strings.Repeat("x", int(i)*10)
+		}
+		if got, want := r["long, text"], long; got != want {
+			return fmt.Errorf("row %d: long: got: %d bytes, want: %d", i, len(r.String("long, text")), i*10)
+		}
+		if got := r["extra"]; got != nil {
+			return fmt.Errorf("row %d: extra: got: %v, want: nil", i, got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := i, int64(500); got != want {
+		t.Errorf("got: %d rows, want: %d", got, want)
+	}
+}
+
+func TestVarint(t *testing.T) {
+	tt := []struct {
+		in  []byte
+		v   uint64
+		len int
+	}{
+		{[]byte{0x00}, 0, 1},
+		{[]byte{0x7f}, 0x7f, 1},
+		{[]byte{0x81, 0x00}, 0x80, 2},
+		{[]byte{0x81}, 0, 0},
+		{bytes.Repeat([]byte{0xff}, 9), ^uint64(0), 9},
+	}
+	for _, tc := range tt {
+		v, n := varint(tc.in)
+		if v != tc.v || n != tc.len {
+			t.Errorf("%x: got: (%#x, %d), want: (%#x, %d)", tc.in, v, n, tc.v, tc.len)
+		}
+	}
+}
+
+func TestNotDatabase(t *testing.T) {
+	if _, err := Open(strings.NewReader(strings.Repeat("x", 100))); err == nil {
+		t.Error("expected error")
+	}
+}
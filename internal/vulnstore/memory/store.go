@@ -0,0 +1,484 @@
+// Package memory provides a vulnstore.Store that keeps everything in memory.
+//
+// It's intended for tests, CI pipelines, and one-shot CLI use, where
+// persisting the vulnerability database isn't needed. Everything is lost when
+// the Store is discarded.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/clock"
+)
+
+var (
+	_ vulnstore.Store      = (*Store)(nil)
+	_ vulnstore.Summarizer = (*Store)(nil)
+)
+
+// Store is an in-memory vulnstore.Store.
+//
+// Unlike the PostgreSQL implementation, only the vulnerabilities and
+// enrichments from each updater's latest update operation are returned by
+// queries, without waiting for GC.
+//
+// Store is safe for concurrent use.
+type Store struct {
+	mu sync.RWMutex
+	// Ops holds every update operation, oldest first.
+	ops []*operation
+	// VulnID interns vulnerabilities, so the same vulnerability has the same
+	// ID across update operations.
+	vulnID    map[string]string
+	summaries map[string]claircore.VulnerabilitySummary
+}
+
+// Operation is an update operation and its contents.
+type operation struct {
+	driver.UpdateOperation
+	vulns       []*claircore.Vulnerability
+	byName      map[string][]*claircore.Vulnerability
+	enrichments []driver.EnrichmentRecord
+}
+
+// NewStore returns an empty Store.
+func NewStore() *Store {
+	return &Store{
+		vulnID:    make(map[string]string),
+		summaries: make(map[string]claircore.VulnerabilitySummary),
+	}
+}
+
+// Add records a new update operation. The caller must hold the lock.
+func (s *Store) add(ctx context.Context, kind driver.UpdateKind, updater string, fp driver.Fingerprint) *operation {
+	op := &operation{
+		UpdateOperation: driver.UpdateOperation{
+			Ref:         uuid.New(),
+			Updater:     updater,
+			Fingerprint: fp,
+			Date:        clock.Now(ctx),
+			Kind:        kind,
+		},
+	}
+	s.ops = append(s.ops, op)
+	return op
+}
+
+// Latest returns the latest operation of the kind for every updater. The
+// caller must hold the lock.
+func (s *Store) latest(kind driver.UpdateKind) map[string]*operation {
+	out := make(map[string]*operation)
+	for _, op := range s.ops {
+		if op.Kind == kind {
+			out[op.Updater] = op
+		}
+	}
+	return out
+}
+
+// Find returns the operation with the ref, or nil. The caller must hold the
+// lock.
+func (s *Store) find(ref uuid.UUID) *operation {
+	for _, op := range s.ops {
+		if op.Ref == ref {
+			return op
+		}
+	}
+	return nil
+}
+
+// UpdateVulnerabilities implements vulnstore.Updater.
+func (s *Store) UpdateVulnerabilities(ctx context.Context, updater string, fp driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error) {
+	stored := make([]*claircore.Vulnerability, 0, len(vulns))
+	byName := make(map[string][]*claircore.Vulnerability)
+	keys := make([]string, 0, len(vulns))
+	for _, v := range vulns {
+		if v.Package == nil || v.Package.Name == "" {
+			continue
+		}
+		c := copyVuln(v)
+		c.ID = ""
+		b, err := json.Marshal(c)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("memory: unable to encode vulnerability: %w", err)
+		}
+		stored = append(stored, c)
+		keys = append(keys, string(b))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, v := range stored {
+		id, ok := s.vulnID[keys[i]]
+		if !ok {
+			id = strconv.Itoa(len(s.vulnID) + 1)
+			s.vulnID[keys[i]] = id
+		}
+		v.ID = id
+		byName[v.Package.Name] = append(byName[v.Package.Name], v)
+	}
+	op := s.add(ctx, driver.VulnerabilityKind, updater, fp)
+	op.vulns = stored
+	op.byName = byName
+	return op.Ref, nil
+}
+
+// CopyVuln returns a copy of the vulnerability with all of its Package,
+// Dist, and Repo allocated, as the PostgreSQL implementation returns them.
+func copyVuln(v *claircore.Vulnerability) *claircore.Vulnerability {
+	c := *v
+	c.Package = &claircore.Package{}
+	c.Dist = &claircore.Distribution{}
+	c.Repo = &claircore.Repository{}
+	if v.Package != nil {
+		*c.Package = *v.Package
+	}
+	if v.Dist != nil {
+		*c.Dist = *v.Dist
+	}
+	if v.Repo != nil {
+		*c.Repo = *v.Repo
+	}
+	if v.Range != nil {
+		r := *v.Range
+		c.Range = &r
+	}
+	return &c
+}
+
+// UpdateEnrichments implements vulnstore.EnrichmentUpdater.
+func (s *Store) UpdateEnrichments(ctx context.Context, kind string, fp driver.Fingerprint, es []driver.EnrichmentRecord) (uuid.UUID, error) {
+	stored := make([]driver.EnrichmentRecord, len(es))
+	for i, e := range es {
+		stored[i] = driver.EnrichmentRecord{
+			Tags:       append([]string(nil), e.Tags...),
+			Enrichment: append(json.RawMessage(nil), e.Enrichment...),
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op := s.add(ctx, driver.EnrichmentKind, kind, fp)
+	op.enrichments = stored
+	return op.Ref, nil
+}
+
+// GetEnrichment implements vulnstore.Enrichment.
+func (s *Store) GetEnrichment(_ context.Context, kind string, tags []string) ([]driver.EnrichmentRecord, error) {
+	want := make(map[string]struct{}, len(tags))
+	for _, t := range tags {
+		want[t] = struct{}{}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	op, ok := s.latest(driver.EnrichmentKind)[kind]
+	if !ok {
+		return nil, nil
+	}
+	var out []driver.EnrichmentRecord
+Record:
+	for _, e := range op.enrichments {
+		for _, t := range e.Tags {
+			if _, ok := want[t]; ok {
+				out = append(out, e)
+				continue Record
+			}
+		}
+	}
+	return out, nil
+}
+
+// Get implements vulnstore.Vulnerability.
+func (s *Store) Get(_ context.Context, records []*claircore.IndexRecord, opts vulnstore.GetOpts) (map[string][]*claircore.Vulnerability, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	latest := s.latest(driver.VulnerabilityKind)
+	out := make(map[string][]*claircore.Vulnerability)
+	for _, r := range records {
+		if r.Package == nil || r.Package.Name == "" {
+			continue
+		}
+		seen := make(map[string]struct{})
+		for _, op := range latest {
+			for _, name := range []string{r.Package.Name, sourceName(r)} {
+				for _, v := range op.byName[name] {
+					if _, ok := seen[v.ID]; ok {
+						continue
+					}
+					ok, err := matches(r, v, &opts)
+					if err != nil {
+						return nil, err
+					}
+					if !ok {
+						continue
+					}
+					seen[v.ID] = struct{}{}
+					out[r.Package.ID] = append(out[r.Package.ID], copyVuln(v))
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// SourceName returns the name of the record's source package, if any.
+func sourceName(r *claircore.IndexRecord) string {
+	if r.Package.Source == nil {
+		return ""
+	}
+	return r.Package.Source.Name
+}
+
+// Matches reports whether the vulnerability matches the record under the
+// constraints, as the PostgreSQL implementation's query does.
+func matches(r *claircore.IndexRecord, v *claircore.Vulnerability, opts *vulnstore.GetOpts) (bool, error) {
+	p, src := r.Package, r.Package.Source
+	switch {
+	case v.Package.Name == p.Name && v.Package.Kind == p.Kind:
+	case src != nil && src.Name != "" && v.Package.Name == src.Name && v.Package.Kind == src.Kind:
+	default:
+		return false, nil
+	}
+	dist, repo := r.Distribution, r.Repository
+	if dist == nil {
+		dist = &claircore.Distribution{}
+	}
+	if repo == nil {
+		repo = &claircore.Repository{}
+	}
+	for _, m := range opts.Matchers {
+		var ok bool
+		switch m {
+		case driver.PackageModule:
+			ok = v.Package.Module == p.Module
+		case driver.DistributionDID:
+			ok = v.Dist.DID == dist.DID
+		case driver.DistributionName:
+			ok = v.Dist.Name == dist.Name
+		case driver.DistributionVersionID:
+			ok = v.Dist.VersionID == dist.VersionID
+		case driver.DistributionVersion:
+			ok = v.Dist.Version == dist.Version
+		case driver.DistributionVersionCodeName:
+			ok = v.Dist.VersionCodeName == dist.VersionCodeName
+		case driver.DistributionPrettyName:
+			ok = v.Dist.PrettyName == dist.PrettyName
+		case driver.DistributionCPE:
+			ok = v.Dist.CPE.String() == dist.CPE.String()
+		case driver.DistributionArch:
+			ok = v.Dist.Arch == dist.Arch
+		case driver.RepositoryName:
+			ok = v.Repo.Name == repo.Name
+		default:
+			return false, fmt.Errorf("memory: unknown match constraint: %v", m)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	if opts.VersionFiltering {
+		rng, nv := v.Range, &p.NormalizedVersion
+		if rng == nil || rng.Lower.Kind != rng.Upper.Kind || rng.Lower.Kind != nv.Kind {
+			return false, nil
+		}
+		if !rng.Contains(nv) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// GetUpdateOperations implements vulnstore.Updater.
+func (s *Store) GetUpdateOperations(_ context.Context, kind driver.UpdateKind, updaters ...string) (map[string][]driver.UpdateOperation, error) {
+	want := make(map[string]struct{}, len(updaters))
+	for _, u := range updaters {
+		want[u] = struct{}{}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string][]driver.UpdateOperation)
+	for i := len(s.ops) - 1; i >= 0; i-- {
+		op := s.ops[i]
+		if _, ok := want[op.Updater]; len(want) != 0 && !ok {
+			continue
+		}
+		if kind != "" && op.Kind != kind {
+			continue
+		}
+		out[op.Updater] = append(out[op.Updater], op.UpdateOperation)
+	}
+	return out, nil
+}
+
+// GetLatestUpdateRefs implements vulnstore.Updater.
+func (s *Store) GetLatestUpdateRefs(_ context.Context, kind driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string][]driver.UpdateOperation)
+	for i := len(s.ops) - 1; i >= 0; i-- {
+		op := s.ops[i]
+		if kind != "" && op.Kind != kind {
+			continue
+		}
+		if _, ok := out[op.Updater]; !ok {
+			out[op.Updater] = []driver.UpdateOperation{op.UpdateOperation}
+		}
+	}
+	return out, nil
+}
+
+// GetLatestUpdateRef implements vulnstore.Updater.
+func (s *Store) GetLatestUpdateRef(_ context.Context, kind driver.UpdateKind) (uuid.UUID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.ops) - 1; i >= 0; i-- {
+		if op := s.ops[i]; kind == "" || op.Kind == kind {
+			return op.Ref, nil
+		}
+	}
+	return uuid.Nil, nil
+}
+
+// DeleteUpdateOperations implements vulnstore.Updater.
+func (s *Store) DeleteUpdateOperations(_ context.Context, refs ...uuid.UUID) (int64, error) {
+	rm := make(map[uuid.UUID]struct{}, len(refs))
+	for _, r := range refs {
+		rm[r] = struct{}{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	keep := s.ops[:0]
+	for _, op := range s.ops {
+		if _, ok := rm[op.Ref]; ok {
+			n++
+			continue
+		}
+		keep = append(keep, op)
+	}
+	for i := len(keep); i < len(s.ops); i++ {
+		s.ops[i] = nil
+	}
+	s.ops = keep
+	return n, nil
+}
+
+// GetUpdateDiff implements vulnstore.Updater.
+func (s *Store) GetUpdateDiff(_ context.Context, prev, cur uuid.UUID) (*driver.UpdateDiff, error) {
+	if cur == uuid.Nil {
+		return nil, errors.New("nil uuid is invalid as \"current\" endpoint")
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c := s.find(cur)
+	if c == nil {
+		return nil, fmt.Errorf("memory: unknown update operation %v", cur)
+	}
+	var p *operation
+	if prev != uuid.Nil {
+		if p = s.find(prev); p == nil {
+			return nil, fmt.Errorf("memory: unknown update operation %v", prev)
+		}
+	}
+	if c.Kind != driver.VulnerabilityKind || (p != nil && p.Kind != driver.VulnerabilityKind) {
+		return nil, fmt.Errorf("provided ref was not of kind 'vulnerability'")
+	}
+
+	diff := driver.UpdateDiff{Cur: c.UpdateOperation}
+	ids := func(op *operation) map[string]struct{} {
+		m := make(map[string]struct{})
+		if op != nil {
+			for _, v := range op.vulns {
+				m[v.ID] = struct{}{}
+			}
+		}
+		return m
+	}
+	pids, cids := ids(p), ids(c)
+	for _, v := range c.vulns {
+		if _, ok := pids[v.ID]; !ok {
+			diff.Added = append(diff.Added, *copyVuln(v))
+		}
+	}
+	if p == nil {
+		diff.Prev.Ref = prev
+		return &diff, nil
+	}
+	diff.Prev = p.UpdateOperation
+	for _, v := range p.vulns {
+		if _, ok := cids[v.ID]; !ok {
+			diff.Removed = append(diff.Removed, *copyVuln(v))
+		}
+	}
+	return &diff, nil
+}
+
+// GC implements vulnstore.Updater.
+//
+// Every update operation past the newest "keep" for each updater is removed
+// at once, so the returned count is always zero.
+func (s *Store) GC(_ context.Context, keep int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := make(map[string]int)
+	var rm []int
+	for i := len(s.ops) - 1; i >= 0; i-- {
+		op := s.ops[i]
+		k := string(op.Kind) + "\x00" + op.Updater
+		count[k]++
+		if count[k] > keep {
+			rm = append(rm, i)
+		}
+	}
+	sort.Ints(rm)
+	for j := len(rm) - 1; j >= 0; j-- {
+		i := rm[j]
+		copy(s.ops[i:], s.ops[i+1:])
+		s.ops[len(s.ops)-1] = nil
+		s.ops = s.ops[:len(s.ops)-1]
+	}
+	return 0, nil
+}
+
+// Initialized implements vulnstore.Updater.
+func (s *Store) Initialized(_ context.Context) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, op := range s.ops {
+		if len(op.vulns) != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SetSummary implements vulnstore.Summarizer.
+func (s *Store) SetSummary(_ context.Context, sum *claircore.VulnerabilitySummary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summaries[sum.Manifest.String()] = *sum
+	return nil
+}
+
+// GetSummaries implements vulnstore.Summarizer.
+func (s *Store) GetSummaries(_ context.Context, ms []claircore.Digest) ([]claircore.VulnerabilitySummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []claircore.VulnerabilitySummary
+	for _, m := range ms {
+		if sum, ok := s.summaries[m.String()]; ok {
+			out = append(out, sum)
+		}
+	}
+	return out, nil
+}
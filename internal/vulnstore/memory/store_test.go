@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+func vuln(name, pkg, dist string) *claircore.Vulnerability {
+	return &claircore.Vulnerability{
+		Name:    name,
+		Updater: "test",
+		Package: &claircore.Package{Name: pkg, Kind: claircore.BINARY},
+		Dist:    &claircore.Distribution{DID: dist},
+	}
+}
+
+func names(vs []*claircore.Vulnerability) map[string]bool {
+	m := make(map[string]bool)
+	for _, v := range vs {
+		m[v.Name] = true
+	}
+	return m
+}
+
+func TestGet(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	s := NewStore()
+	if ok, _ := s.Initialized(ctx); ok {
+		t.Error("empty store reported as initialized")
+	}
+	prev, err := s.UpdateVulnerabilities(ctx, "test", "1", []*claircore.Vulnerability{
+		vuln("CVE-1", "openssl", "debian"),
+		vuln("CVE-2", "openssl", "debian"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := s.UpdateVulnerabilities(ctx, "test", "2", []*claircore.Vulnerability{
+		vuln("CVE-1", "openssl", "debian"),
+		vuln("CVE-3", "openssl", "alpine"),
+		vuln("CVE-4", "libssl", "debian"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Initialized(ctx); !ok {
+		t.Error("store not reported as initialized")
+	}
+
+	rec := &claircore.IndexRecord{
+		Package: &claircore.Package{
+			ID:     "1",
+			Name:   "libssl",
+			Kind:   claircore.BINARY,
+			Source: &claircore.Package{Name: "openssl", Kind: claircore.BINARY},
+		},
+		Distribution: &claircore.Distribution{DID: "debian"},
+	}
+	got, err := s.Get(ctx, []*claircore.IndexRecord{rec}, vulnstore.GetOpts{
+		Matchers: []driver.MatchConstraint{driver.DistributionDID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the latest update is used, so CVE-2 isn't found.
+	if got, want := names(got["1"]), map[string]bool{"CVE-1": true, "CVE-4": true}; len(got) != len(want) || !got["CVE-1"] || !got["CVE-4"] {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	diff, err := s.GetUpdateDiff(ctx, prev, cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(diff.Added), 2; got != want {
+		t.Errorf("added: got: %d, want: %d", got, want)
+	}
+	if got, want := len(diff.Removed), 1; got != want || diff.Removed[0].Name != "CVE-2" {
+		t.Errorf("removed: got: %+v, want: CVE-2", diff.Removed)
+	}
+	if diff.Prev.Ref != prev || diff.Cur.Ref != cur {
+		t.Errorf("unexpected refs: %v, %v", diff.Prev.Ref, diff.Cur.Ref)
+	}
+	if _, err := s.GetUpdateDiff(ctx, prev, uuid.Nil); err == nil {
+		t.Error("expected error for nil current ref")
+	}
+
+	ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ops["test"]; len(got) != 2 || got[0].Ref != cur {
+		t.Errorf("unexpected operations: %+v", got)
+	}
+	if _, err := s.GC(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	ops, err = s.GetUpdateOperations(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ops["test"]; len(got) != 1 || got[0].Ref != cur {
+		t.Errorf("unexpected operations after GC: %+v", got)
+	}
+	ref, err := s.GetLatestUpdateRef(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != cur {
+		t.Errorf("got: %v, want: %v", ref, cur)
+	}
+}
+
+func TestVersionFiltering(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	s := NewStore()
+	ver := func(v int32) claircore.Version {
+		return claircore.Version{Kind: "test", V: [...]int32{0, v, 0, 0, 0, 0, 0, 0, 0, 0}}
+	}
+	v := vuln("CVE-1", "app", "")
+	v.Range = &claircore.Range{Lower: ver(1), Upper: ver(3)}
+	if _, err := s.UpdateVulnerabilities(ctx, "test", "1", []*claircore.Vulnerability{v, vuln("CVE-2", "app", "")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		V    int32
+		Want int
+	}{{0, 0}, {1, 1}, {2, 1}, {3, 0}} {
+		rec := &claircore.IndexRecord{
+			Package: &claircore.Package{ID: "1", Name: "app", Kind: claircore.BINARY, NormalizedVersion: ver(tc.V)},
+		}
+		got, err := s.Get(ctx, []*claircore.IndexRecord{rec}, vulnstore.GetOpts{VersionFiltering: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got["1"]) != tc.Want {
+			t.Errorf("version %d: got: %d vulnerabilities, want: %d", tc.V, len(got["1"]), tc.Want)
+		}
+	}
+}
+
+func TestEnrichment(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	s := NewStore()
+	rec := func(tag, data string) driver.EnrichmentRecord {
+		return driver.EnrichmentRecord{Tags: []string{tag}, Enrichment: json.RawMessage(data)}
+	}
+	if _, err := s.UpdateEnrichments(ctx, "cvss", "1", []driver.EnrichmentRecord{rec("CVE-1", `1`)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateEnrichments(ctx, "cvss", "2", []driver.EnrichmentRecord{rec("CVE-1", `2`), rec("CVE-2", `3`)}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetEnrichment(ctx, "cvss", []string{"CVE-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || string(got[0].Enrichment) != `2` {
+		t.Errorf("unexpected enrichments: %+v", got)
+	}
+	got, err = s.GetEnrichment(ctx, "other", []string{"CVE-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("unexpected enrichments: %+v", got)
+	}
+}
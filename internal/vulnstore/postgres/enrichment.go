@@ -16,6 +16,7 @@ import (
 	"go.opentelemetry.io/otel/label"
 
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/clock"
 	"github.com/quay/claircore/pkg/microbatch"
 )
 
@@ -66,9 +67,9 @@ func (s *Store) UpdateEnrichments(ctx context.Context, name string, fp driver.Fi
 		create = `
 INSERT
 INTO
-	update_operation (updater, fingerprint, kind)
+	update_operation (updater, fingerprint, kind, date)
 VALUES
-	($1, $2, 'enrichment')
+	($1, $2, 'enrichment', $3)
 RETURNING
 	id, ref;`
 		insert = `
@@ -99,7 +100,7 @@ VALUES
 		),
 		$3,
 		$4,
-		transaction_timestamp()
+		$5
 	)
 ON CONFLICT
 DO
@@ -107,6 +108,7 @@ DO
 	)
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "internal/vulnstore/postgres/UpdateEnrichments"))
+	now := clock.Now(ctx)
 
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
@@ -119,7 +121,7 @@ DO
 
 	start := time.Now()
 
-	if err := s.pool.QueryRow(ctx, create, name, string(fp)).Scan(&id, &ref); err != nil {
+	if err := s.pool.QueryRow(ctx, create, name, string(fp), now).Scan(&id, &ref); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to create update_operation: %w", err)
 	}
 
@@ -140,7 +142,7 @@ DO
 		if err != nil {
 			return uuid.Nil, fmt.Errorf("failed to queue enrichment: %w", err)
 		}
-		if err := batch.Queue(ctx, assoc, hashKind, hash, name, id); err != nil {
+		if err := batch.Queue(ctx, assoc, hashKind, hash, name, id, now); err != nil {
 			return uuid.Nil, fmt.Errorf("failed to queue association: %w", err)
 		}
 	}
@@ -92,6 +92,7 @@ func (s *Store) Get(ctx context.Context, records []*claircore.IndexRecord, opts
 			}
 
 			var id int64
+			var modified *time.Time
 			err := rows.Scan(
 				&id,
 				&v.Name,
@@ -119,8 +120,14 @@ func (s *Store) Get(ctx context.Context, records []*claircore.IndexRecord, opts
 				&v.Repo.URI,
 				&v.FixedInVersion,
 				&v.Updater,
+				&v.Withdrawn,
+				&modified,
+				&v.FixState,
 			)
 			v.ID = strconv.FormatInt(id, 10)
+			if modified != nil {
+				v.Modified = *modified
+			}
 			if err != nil {
 				res.Close()
 				return nil, fmt.Errorf("failed to scan vulnerability: %v", err)
@@ -121,6 +121,9 @@ func buildGetQuery(record *claircore.IndexRecord, opts *vulnstore.GetOpts) (stri
 		"repo_uri",
 		"fixed_in_version",
 		"updater",
+		"withdrawn",
+		"modified",
+		"fix_state",
 	).From("vuln").Where(exps...)
 
 	sql, _, err := query.ToSQL()
@@ -20,7 +20,7 @@ func TestGetQueryBuilderDeterministicArgs(t *testing.T) {
 		"id", "name", "description", "issued", "links", "severity", "normalized_severity", "package_name", "package_version",
 		"package_module", "package_arch", "package_kind", "dist_id", "dist_name", "dist_version", "dist_version_code_name",
 		"dist_version_id", "dist_arch", "dist_cpe", "dist_pretty_name", "arch_operation", "repo_name", "repo_key",
-		"repo_uri", "fixed_in_version", "updater"
+		"repo_uri", "fixed_in_version", "updater", "withdrawn", "modified", "fix_state"
 		FROM "vuln"
 		WHERE `
 		both     = `(((("package_name" = 'package-0') AND ("package_kind" = 'binary')) OR (("package_name" = 'source-package-0') AND ("package_kind" = 'source'))) AND `
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/quay/claircore"
 )
@@ -12,6 +13,7 @@ type scanner interface {
 
 func scanVulnerability(v *claircore.Vulnerability, row scanner) error {
 	var id uint64
+	var modified *time.Time
 	if err := row.Scan(
 		&id,
 		&v.Name,
@@ -39,9 +41,15 @@ func scanVulnerability(v *claircore.Vulnerability, row scanner) error {
 		&v.Repo.Key,
 		&v.Repo.URI,
 		&v.FixedInVersion,
+		&v.Withdrawn,
+		&modified,
+		&v.FixState,
 	); err != nil {
 		return err
 	}
 	v.ID = strconv.FormatUint(id, 10)
+	if modified != nil {
+		v.Modified = *modified
+	}
 	return nil
 }
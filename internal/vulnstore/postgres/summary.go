@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/vulnstore"
+)
+
+var _ vulnstore.Summarizer = (*Store)(nil)
+
+// SetSummary implements vulnstore.Summarizer.
+func (s *Store) SetSummary(ctx context.Context, sum *claircore.VulnerabilitySummary) error {
+	const query = `
+INSERT INTO manifest_summary
+	(manifest_hash, updated, unknown, negligible, low, medium, high, critical)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (manifest_hash) DO UPDATE SET
+	updated = EXCLUDED.updated,
+	unknown = EXCLUDED.unknown,
+	negligible = EXCLUDED.negligible,
+	low = EXCLUDED.low,
+	medium = EXCLUDED.medium,
+	high = EXCLUDED.high,
+	critical = EXCLUDED.critical
+WHERE manifest_summary.updated <= EXCLUDED.updated;
+`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/Store.SetSummary"))
+	_, err := s.pool.Exec(ctx, query, sum.Manifest.String(), sum.Updated,
+		sum.Unknown, sum.Negligible, sum.Low, sum.Medium, sum.High, sum.Critical)
+	if err != nil {
+		return fmt.Errorf("failed to record summary: %w", err)
+	}
+	return nil
+}
+
+// GetSummaries implements vulnstore.Summarizer.
+func (s *Store) GetSummaries(ctx context.Context, ms []claircore.Digest) ([]claircore.VulnerabilitySummary, error) {
+	const query = `
+SELECT
+	manifest_hash, updated, unknown, negligible, low, medium, high, critical
+FROM
+	manifest_summary
+WHERE
+	manifest_hash = ANY($1::text[]);
+`
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "internal/vulnstore/postgres/Store.GetSummaries"))
+	if len(ms) == 0 {
+		return nil, nil
+	}
+	hs := make([]string, len(ms))
+	for i := range ms {
+		hs[i] = ms[i].String()
+	}
+	rows, err := s.pool.Query(ctx, query, hs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query summaries: %w", err)
+	}
+	defer rows.Close()
+	out := make([]claircore.VulnerabilitySummary, 0, len(ms))
+	for rows.Next() {
+		var sum claircore.VulnerabilitySummary
+		if err := rows.Scan(&sum.Manifest, &sum.Updated,
+			&sum.Unknown, &sum.Negligible, &sum.Low, &sum.Medium, &sum.High, &sum.Critical,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan summary: %w", err)
+		}
+		out = append(out, sum)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read summaries: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/integration"
+)
+
+// TestSummary confirms summaries round-trip and that an older summary
+// doesn't replace a newer one.
+func TestSummary(t *testing.T) {
+	integration.NeedDB(t)
+	ctx := zlog.Test(context.Background(), t)
+	pool := TestDB(ctx, t)
+	store := NewVulnStore(pool)
+
+	manifest := claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`)
+	other := claircore.MustParseDigest(`sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a`)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if err := store.SetSummary(ctx, &claircore.VulnerabilitySummary{
+		Manifest: manifest,
+		Updated:  now,
+		High:     2,
+		Low:      1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetSummary(ctx, &claircore.VulnerabilitySummary{
+		Manifest: manifest,
+		Updated:  now.Add(-time.Hour),
+		Critical: 5,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetSummaries(ctx, []claircore.Digest{manifest, other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got: %d summaries, want: 1", len(got))
+	}
+	s := got[0]
+	if s.Manifest.String() != manifest.String() {
+		t.Errorf("got: %v, want: %v", s.Manifest, manifest)
+	}
+	if !s.Updated.Equal(now) {
+		t.Errorf("got: %v, want: %v", s.Updated, now)
+	}
+	if s.High != 2 || s.Low != 1 || s.Critical != 0 {
+		t.Errorf("unexpected counts: %+v", s)
+	}
+}
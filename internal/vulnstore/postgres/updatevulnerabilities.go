@@ -19,6 +19,7 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/clock"
 	"github.com/quay/claircore/pkg/microbatch"
 )
 
@@ -54,7 +55,7 @@ var (
 func updateVulnerabilites(ctx context.Context, pool *pgxpool.Pool, updater string, fingerprint driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error) {
 	const (
 		// Create makes a new update operation and returns the reference and ID.
-		create = `INSERT INTO update_operation (updater, fingerprint, kind) VALUES ($1, $2, 'vulnerability') RETURNING id, ref;`
+		create = `INSERT INTO update_operation (updater, fingerprint, kind, date) VALUES ($1, $2, 'vulnerability', $3) RETURNING id, ref;`
 		// Insert attempts to create a new vulnerability. It fails silently.
 		insert = `
 		INSERT INTO vuln (
@@ -63,14 +64,16 @@ func updateVulnerabilites(ctx context.Context, pool *pgxpool.Pool, updater strin
 			package_name, package_version, package_module, package_arch, package_kind,
 			dist_id, dist_name, dist_version, dist_version_code_name, dist_version_id, dist_arch, dist_cpe, dist_pretty_name,
 			repo_name, repo_key, repo_uri,
-			fixed_in_version, arch_operation, version_kind, vulnerable_range
+			fixed_in_version, arch_operation, version_kind, vulnerable_range,
+			withdrawn, modified, fix_state
 		) VALUES (
 		  $1, $2,
 		  $3, $4, $5, $6, $7, $8, $9,
 		  $10, $11, $12, $13, $14,
 		  $15, $16, $17, $18, $19, $20, $21, $22,
 		  $23, $24, $25,
-		  $26, $27, $28, VersionRange($29, $30)
+		  $26, $27, $28, VersionRange($29, $30),
+		  $31, $32, $33
 		)
 		ON CONFLICT (hash_kind, hash) DO NOTHING;`
 		// Assoc associates an update operation and a vulnerability. It fails
@@ -95,7 +98,7 @@ func updateVulnerabilites(ctx context.Context, pool *pgxpool.Pool, updater strin
 
 	start := time.Now()
 
-	if err := pool.QueryRow(ctx, create, updater, string(fingerprint)).Scan(&id, &ref); err != nil {
+	if err := pool.QueryRow(ctx, create, updater, string(fingerprint), clock.Now(ctx)).Scan(&id, &ref); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to create update_operation: %w", err)
 	}
 
@@ -129,6 +132,10 @@ func updateVulnerabilites(ctx context.Context, pool *pgxpool.Pool, updater strin
 		}
 		hashKind, hash := md5Vuln(vuln)
 		vKind, vrLower, vrUpper := rangefmt(vuln.Range)
+		var modified *time.Time
+		if !vuln.Modified.IsZero() {
+			modified = &vuln.Modified
+		}
 
 		err := mBatcher.Queue(ctx, insert,
 			hashKind, hash,
@@ -137,6 +144,7 @@ func updateVulnerabilites(ctx context.Context, pool *pgxpool.Pool, updater strin
 			dist.DID, dist.Name, dist.Version, dist.VersionCodeName, dist.VersionID, dist.Arch, dist.CPE, dist.PrettyName,
 			repo.Name, repo.Key, repo.URI,
 			vuln.FixedInVersion, vuln.ArchOperation, vKind, vrLower, vrUpper,
+			vuln.Withdrawn, modified, vuln.FixState,
 		)
 		if err != nil {
 			return uuid.Nil, fmt.Errorf("failed to queue vulnerability: %w", err)
@@ -203,6 +211,17 @@ func md5Vuln(v *claircore.Vulnerability) (string, []byte) {
 		b.WriteString(l)
 		b.WriteString(u)
 	}
+	// These are only written when set, so the hashes of existing records
+	// don't change.
+	if v.Withdrawn {
+		b.WriteString("withdrawn")
+	}
+	if !v.Modified.IsZero() {
+		b.WriteString(v.Modified.String())
+	}
+	if v.FixState != "" {
+		b.WriteString(v.FixState)
+	}
 	s := md5.Sum(b.Bytes())
 	return "md5", s[:]
 }
@@ -0,0 +1,18 @@
+package vulnstore
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// Summarizer is an optional interface for stores that can record
+// VulnerabilitySummaries.
+type Summarizer interface {
+	// SetSummary records the summary, replacing any already recorded for the
+	// manifest.
+	SetSummary(context.Context, *claircore.VulnerabilitySummary) error
+	// GetSummaries returns the summaries recorded for the provided manifests.
+	// Manifests without a summary are omitted.
+	GetSummaries(context.Context, []claircore.Digest) ([]claircore.VulnerabilitySummary, error)
+}
@@ -0,0 +1,154 @@
+// Package zreader detects how layer blobs are compressed and decompresses
+// them.
+//
+// Layers are tar archives compressed with gzip or zstd, or not compressed at
+// all. The media type reported for a blob isn't always accurate, so the
+// contents are examined.
+package zreader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is how a layer is compressed.
+type Compression int
+
+// These are the Compressions.
+const (
+	// Unknown means the compression couldn't be determined.
+	Unknown Compression = iota
+	// None is an uncompressed tar archive.
+	None
+	Gzip
+	Zstd
+)
+
+var compressionNames = [...]string{
+	Unknown: "unknown",
+	None:    "none",
+	Gzip:    "gzip",
+	Zstd:    "zstd",
+}
+
+// String implements fmt.Stringer.
+func (c Compression) String() string {
+	if c < 0 || int(c) >= len(compressionNames) {
+		return fmt.Sprintf("Compression(%d)", int(c))
+	}
+	return compressionNames[c]
+}
+
+// PeekSize is the number of bytes Detect needs to recognize everything.
+const PeekSize = 512
+
+var (
+	gzipMagic = []byte{0x1F, 0x8B, 0x08}
+	zstdMagic = []byte{0x28, 0xB5, 0x2F, 0xFD}
+	// Both the POSIX and GNU tar formats have this at offset 257 of every
+	// header.
+	tarMagic = []byte("ustar")
+)
+
+// Detect reports the Compression of the blob starting with the provided
+// bytes, which should be PeekSize long if the blob is at least that big.
+//
+// An archive with no entries is reported as None.
+func Detect(b []byte) Compression {
+	switch {
+	case bytes.HasPrefix(b, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(b, zstdMagic):
+		return Zstd
+	case len(b) >= PeekSize && bytes.HasPrefix(b[257:], tarMagic):
+		return None
+	case len(b) >= PeekSize && allZero(b[:PeekSize]):
+		// The end-of-archive marker is two zeroed blocks.
+		return None
+	}
+	return Unknown
+}
+
+func allZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FromMediaType reports the Compression indicated by a media type, or Unknown
+// if it doesn't indicate one.
+//
+// Both the OCI and Docker layer media types are understood, as are the
+// generic compression media types.
+func FromMediaType(mt string) Compression {
+	if i := strings.IndexByte(mt, ';'); i != -1 {
+		mt = mt[:i]
+	}
+	mt = strings.TrimSpace(strings.ToLower(mt))
+	switch {
+	case mt == "application/vnd.docker.image.rootfs.diff.tar.gzip",
+		mt == "application/gzip", mt == "application/x-gzip",
+		strings.HasSuffix(mt, ".tar+gzip"):
+		return Gzip
+	case mt == "application/zstd", strings.HasSuffix(mt, ".tar+zstd"):
+		return Zstd
+	case mt == "application/x-tar", strings.HasSuffix(mt, ".tar"):
+		return None
+	}
+	return Unknown
+}
+
+// Sniff reports the Compression of the contents of the bufio.Reader, without
+// consuming anything.
+func Sniff(br *bufio.Reader) (Compression, error) {
+	b, err := br.Peek(PeekSize)
+	switch {
+	case err == nil:
+	case err == io.EOF:
+		// Short blobs are fine, Detect will make do.
+	case err == bufio.ErrBufferFull:
+		return Unknown, fmt.Errorf("zreader: buffer too small: %w", err)
+	default:
+		return Unknown, err
+	}
+	return Detect(b), nil
+}
+
+// Reader returns a ReadCloser decompressing "r" according to the
+// Compression.
+//
+// Closing the returned ReadCloser does not close "r".
+func Reader(r io.Reader, c Compression) (io.ReadCloser, error) {
+	switch c {
+	case None:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zstdCloser{d}, nil
+	}
+	return nil, fmt.Errorf("zreader: unable to decompress %v", c)
+}
+
+// ZstdCloser adapts a zstd.Decoder, whose Close method returns nothing.
+type zstdCloser struct {
+	*zstd.Decoder
+}
+
+func (z zstdCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
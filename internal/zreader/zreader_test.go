@@ -0,0 +1,99 @@
+package zreader
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var plain bytes.Buffer
+	tw := tar.NewWriter(&plain)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "file", Size: 5, Mode: 0644}); err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(tw, "hello")
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var empty bytes.Buffer
+	if err := tar.NewWriter(&empty).Close(); err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(plain.Bytes())
+	zw.Close()
+	var zst bytes.Buffer
+	sw, err := zstd.NewWriter(&zst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sw.Write(plain.Bytes())
+	sw.Close()
+
+	tt := []struct {
+		Name string
+		In   []byte
+		Want Compression
+		Out  []byte
+	}{
+		{"None", plain.Bytes(), None, plain.Bytes()},
+		{"Empty", empty.Bytes(), None, empty.Bytes()},
+		{"Gzip", gz.Bytes(), Gzip, plain.Bytes()},
+		{"Zstd", zst.Bytes(), Zstd, plain.Bytes()},
+		{"Garbage", []byte("not a layer"), Unknown, nil},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			br := bufio.NewReader(bytes.NewReader(tc.In))
+			c, err := Sniff(br)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := c, tc.Want; got != want {
+				t.Fatalf("got: %v, want: %v", got, want)
+			}
+			if c == Unknown {
+				return
+			}
+			r, err := Reader(br, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tc.Out) {
+				t.Error("contents differ")
+			}
+		})
+	}
+}
+
+func TestFromMediaType(t *testing.T) {
+	tt := []struct {
+		In   string
+		Want Compression
+	}{
+		{"application/vnd.oci.image.layer.v1.tar+gzip", Gzip},
+		{"application/vnd.oci.image.layer.v1.tar+zstd", Zstd},
+		{"application/vnd.oci.image.layer.v1.tar", None},
+		{"application/vnd.docker.image.rootfs.diff.tar.gzip", Gzip},
+		{"application/vnd.docker.image.rootfs.diff.tar", None},
+		{"application/x-gzip", Gzip},
+		{"application/octet-stream", Unknown},
+	}
+	for _, tc := range tt {
+		if got := FromMediaType(tc.In); got != tc.Want {
+			t.Errorf("%s: got: %v, want: %v", tc.In, got, tc.Want)
+		}
+	}
+}
@@ -8,14 +8,20 @@ import (
 
 var reposcanners = []indexer.RepositoryScanner{&RepoScanner{}}
 
-// NewEcosystem provides the set of scanners for the java ecosystem.
+// NewEcosystem provides the set of scanners for the java ecosystem, along with
+// the name of the matcher covering it.
+//
+// There's no in-tree source of Maven vulnerability data, so no updaters are
+// named; an out-of-tree updater must provide it.
 func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
 	return &indexer.Ecosystem{
+		Name: "java",
 		PackageScanners: func(_ context.Context) ([]indexer.PackageScanner, error) {
 			return []indexer.PackageScanner{&Scanner{}}, nil
 		},
 		DistributionScanners: func(_ context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
 		RepositoryScanners:   func(_ context.Context) ([]indexer.RepositoryScanner, error) { return reposcanners, nil },
 		Coalescer:            NewCoalescer,
+		Matchers:             []string{"java"},
 	}
 }
@@ -59,16 +59,26 @@ var Header = []byte{'P', 'K', 0x03, 0x04}
 // This is the size of an empty zip. Files smaller than this cannot be jars.
 const MinSize = 22
 
+// MaxDepth is how many levels of archives nested inside other archives are
+// examined. The outermost archive is depth 0.
+const MaxDepth = 4
+
 // Parse returns Info structs describing all of the discovered "artifacts" in
 // the jar.
 //
 // POM properties are a preferred source of information, falling back to
 // examining the jar manifest and then looking at the name. Anything that looks
-// like a jar bundled into the archive is also examined.
+// like a jar bundled into the archive is also examined, up to MaxDepth levels
+// deep. An archive that can't be identified itself but contains identifiable
+// jars, such as a Spring Boot application, reports only the bundled jars.
 //
 // The provided name is expected to be the full path within the layer to the jar
 // file being provided as "z".
 func Parse(ctx context.Context, name string, z *zip.Reader) ([]Info, error) {
+	return parse(ctx, name, z, 0)
+}
+
+func parse(ctx context.Context, name string, z *zip.Reader, depth int) ([]Info, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "java/jar/Parse"),
 		label.String("jar", name))
@@ -78,7 +88,7 @@ func Parse(ctx context.Context, name string, z *zip.Reader) ([]Info, error) {
 	// looks good. This does mean that there are restrictions on declarations in
 	// the following block.
 
-	var ret []Info
+	var ret, inner []Info
 	var i Info
 	var err error
 	base := filepath.Base(name)
@@ -92,6 +102,10 @@ func Parse(ctx context.Context, name string, z *zip.Reader) ([]Info, error) {
 		goto Finish
 	case errors.Is(err, errUnpopulated):
 	case strings.HasPrefix(base, "javax") && errors.Is(err, ErrNotAJar):
+	case errors.Is(err, ErrNotAJar):
+		// No metadata directory at all, but this may still be an archive of
+		// jars.
+		goto Unidentified
 	default:
 		return nil, err
 	}
@@ -120,13 +134,26 @@ func Parse(ctx context.Context, name string, z *zip.Reader) ([]Info, error) {
 	default:
 		return nil, err
 	}
-	// If we haven't jumped past this point, this is almost certainly not a jar,
-	// so return an error.
-	return nil, mkErr("", unidentified(base))
+
+Unidentified:
+	// If we haven't jumped past this point, the archive itself couldn't be
+	// identified. Report any jars bundled inside it, or an error if there
+	// aren't any.
+	inner, err = extractInner(ctx, name, z, depth)
+	if err != nil {
+		return nil, err
+	}
+	if len(inner) == 0 {
+		return nil, mkErr("", unidentified(base))
+	}
+	zlog.Debug(ctx).
+		Int("count", len(inner)).
+		Msg("unidentified archive with embedded jars")
+	return inner, nil
 
 Finish:
 	// Now, we need to examine any jars bundled in this jar.
-	inner, err := extractInner(ctx, name, z)
+	inner, err = extractInner(ctx, name, z, depth)
 	if err != nil {
 		return nil, err
 	}
@@ -207,9 +234,16 @@ func extractProperties(ctx context.Context, name string, z *zip.Reader) ([]Info,
 	return ret, nil
 }
 
-// ExtractInner recurses into anything that looks like a jar in "z".
-func extractInner(ctx context.Context, outer string, z *zip.Reader) ([]Info, error) {
+// ExtractInner recurses into anything that looks like a jar in "z", which is
+// at nesting depth "depth".
+func extractInner(ctx context.Context, outer string, z *zip.Reader, depth int) ([]Info, error) {
 	ctx = baggage.ContextWithValues(ctx, label.String("parent", outer))
+	if depth >= MaxDepth {
+		zlog.Info(ctx).
+			Int("depth", depth).
+			Msg("not examining embedded jars: nested too deeply")
+		return nil, nil
+	}
 	var ret []Info
 	// Zips need random access, so allocate a buffer for any we find.
 	var buf bytes.Buffer
@@ -257,7 +291,7 @@ func extractInner(ctx context.Context, outer string, z *zip.Reader) ([]Info, err
 			return err
 		}
 
-		ps, err := Parse(ctx, name, zr)
+		ps, err := parse(ctx, name, zr, depth+1)
 		switch {
 		case errors.Is(err, nil):
 		case errors.Is(err, ErrNotAJar) ||
@@ -274,8 +308,12 @@ func extractInner(ctx context.Context, outer string, z *zip.Reader) ([]Info, err
 		c := make([]byte, sha1.Size)
 		h.Sum(c[:0])
 		for i := range ps {
-			ps[i].SHA = c
-			ps[i].Source = outer + ":" + ps[i].Source
+			// Jars nested more than one level deep already have their
+			// checksum recorded.
+			if ps[i].SHA == nil {
+				ps[i].SHA = c
+			}
+			ps[i].Source = name + ":" + ps[i].Source
 		}
 		ret = append(ret, ps...)
 		return nil
@@ -341,6 +379,10 @@ type Info struct {
 	Version string
 	// Source is the archive member used to populate the information. If the
 	// name of the archive was used, this will be ".".
+	//
+	// For jars discovered inside another archive, the path to the jar within
+	// the archive is prepended, separated by a colon, for every level of
+	// nesting: "WEB-INF/lib/a.jar:META-INF/maven/g/a/pom.properties".
 	Source string
 	// SHA is populated with the SHA1 of the file if this entry was discovered
 	// inside another archive.
@@ -0,0 +1,102 @@
+package jar
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+)
+
+type member struct {
+	Name string
+	Data []byte
+}
+
+func mkZip(t *testing.T, ms ...member) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, m := range ms {
+		f, err := w.Create(m.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(m.Data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func pomProperties(g, a, v string) member {
+	return member{
+		Name: "META-INF/maven/" + g + "/" + a + "/pom.properties",
+		Data: []byte("groupId=" + g + "\nartifactId=" + a + "\nversion=" + v + "\n"),
+	}
+}
+
+func TestNested(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	api := mkZip(t, pomProperties("org.apache.logging.log4j", "log4j-api", "2.14.1"))
+	core := mkZip(t,
+		pomProperties("org.apache.logging.log4j", "log4j-core", "2.14.1"),
+		member{"META-INF/lib/log4j-api-2.14.1.jar", api},
+	)
+	// A Spring Boot style application: no metadata of its own that
+	// identifies it, with its dependencies in BOOT-INF/lib.
+	app := mkZip(t,
+		member{"META-INF/MANIFEST.MF", []byte("Manifest-Version: 1.0\r\nMain-Class: org.springframework.boot.loader.JarLauncher\r\n")},
+		member{"BOOT-INF/classes/App.class", []byte{0xca, 0xfe, 0xba, 0xbe}},
+		member{"BOOT-INF/lib/log4j-core-2.14.1.jar", core},
+	)
+	z, err := zip.NewReader(bytes.NewReader(app), int64(len(app)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(ctx, "srv/app.jar", z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiSum := sha1.Sum(api)
+	coreSum := sha1.Sum(core)
+	want := []Info{
+		{
+			Name:    "org.apache.logging.log4j:log4j-core",
+			Version: "2.14.1",
+			Source:  "BOOT-INF/lib/log4j-core-2.14.1.jar:META-INF/maven/org.apache.logging.log4j/log4j-core/pom.properties",
+			SHA:     coreSum[:],
+		},
+		{
+			Name:    "org.apache.logging.log4j:log4j-api",
+			Version: "2.14.1",
+			Source:  "BOOT-INF/lib/log4j-core-2.14.1.jar:META-INF/lib/log4j-api-2.14.1.jar:META-INF/maven/org.apache.logging.log4j/log4j-api/pom.properties",
+			SHA:     apiSum[:],
+		},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestNestedDepth(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	b := mkZip(t, pomProperties("com.example", "deep", "1.0.0"))
+	for i := 0; i <= MaxDepth; i++ {
+		b = mkZip(t, member{"lib/nested.jar", b})
+	}
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse(ctx, "deep.jar", z); !errors.Is(err, ErrUnidentified) {
+		t.Errorf("got: %v, want: %v", err, ErrUnidentified)
+	}
+}
@@ -0,0 +1,283 @@
+package java
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+var (
+	_ driver.Matcher             = (*Matcher)(nil)
+	_ driver.Resolver            = (*Matcher)(nil)
+	_ driver.MatcherConfigurable = (*Matcher)(nil)
+	_ driver.MatcherFactory      = (*Factory)(nil)
+	_ driver.MatcherConfigurable = (*Factory)(nil)
+)
+
+// Matcher attempts to correlate discovered java packages with reported
+// vulnerabilities.
+//
+// Vulnerabilities are expected to name packages by their Maven coordinates
+// ("group:artifact"). The vulnerable versions are described by the package's
+// Version, as a Maven version range specification such as "[1.0,1.2.3)", and
+// the FixedInVersion. Either may be omitted.
+//
+// Packages discovered without coordinates, such as jars lacking a
+// pom.properties file because they've been shaded or renamed, only have an
+// artifact ID. If the Matcher has been configured with a search API, it will
+// attempt to resolve these to coordinates: first by the jar's SHA1, then by
+// the artifact ID and version, if that's unambiguous.
+//
+// The zero value is ready to use, with the search fallback disabled.
+type Matcher struct {
+	client *http.Client
+	root   *url.URL
+
+	mu    sync.Mutex
+	cache map[string]resolution
+}
+
+// Resolution is the result of resolving a package to Maven coordinates.
+type resolution struct {
+	name, version string
+	ok            bool
+}
+
+// ResolveCacheSize bounds the number of resolutions remembered by a Matcher.
+const resolveCacheSize = 4096
+
+// MatcherConfig is the configuration accepted by the Matcher and Factory.
+//
+// By convention, it's in a map key called "java".
+type MatcherConfig struct {
+	// API is a URL endpoint to a maven-like REST API.
+	// The default is DefaultSearchAPI.
+	API string `yaml:"api" json:"api"`
+}
+
+// Configure implements driver.MatcherConfigurable.
+//
+// Configuring the Matcher enables the search fallback.
+func (m *Matcher) Configure(ctx context.Context, f driver.MatcherConfigUnmarshaler, c *http.Client) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "java/Matcher.Configure"))
+	var cfg MatcherConfig
+	if err := f(&cfg); err != nil {
+		return err
+	}
+	api := DefaultSearchAPI
+	if cfg.API != "" {
+		api = cfg.API
+	}
+	u, err := url.Parse(api)
+	if err != nil {
+		return err
+	}
+	zlog.Debug(ctx).
+		Str("api", api).
+		Msg("configured search API URL")
+	m.client = c
+	m.root = u
+	return nil
+}
+
+// Name implements driver.Matcher.
+func (*Matcher) Name() string { return "java" }
+
+// Filter implements driver.Matcher.
+func (*Matcher) Filter(record *claircore.IndexRecord) bool {
+	return record.Package != nil &&
+		record.Repository != nil &&
+		record.Repository.Name == Repository.Name
+}
+
+// Query implements driver.Matcher.
+func (*Matcher) Query() []driver.MatchConstraint {
+	return []driver.MatchConstraint{driver.PackageName, driver.RepositoryName}
+}
+
+// Vulnerable implements driver.Matcher.
+func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
+	// if the vuln is not associated with any package,
+	// return not vulnerable.
+	if vuln.Package == nil || record.Package.Version == "" {
+		return false, nil
+	}
+	v := parseMvnVersion(record.Package.Version)
+	if spec := vuln.Package.Version; spec != "" {
+		r, err := parseMvnRange(spec)
+		if err != nil {
+			zlog.Debug(ctx).
+				Str("vulnerability", vuln.Name).
+				Err(err).
+				Msg("unable to parse version range")
+			return false, nil
+		}
+		if !r.Contains(v) {
+			return false, nil
+		}
+	}
+	if vuln.FixedInVersion != "" {
+		return v.Compare(parseMvnVersion(vuln.FixedInVersion)) < 0, nil
+	}
+	return true, nil
+}
+
+// Resolve implements driver.Resolver.
+//
+// Records for packages already named by Maven coordinates are returned as-is.
+// Resolution is best-effort: errors talking to the search API are logged and
+// the record is left unchanged.
+func (m *Matcher) Resolve(ctx context.Context, records []*claircore.IndexRecord) ([]*claircore.IndexRecord, error) {
+	if m.root == nil {
+		return records, nil
+	}
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "java/Matcher.Resolve"))
+	out := make([]*claircore.IndexRecord, len(records))
+	for i, r := range records {
+		out[i] = r
+		if strings.Contains(r.Package.Name, ":") {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res := m.resolve(ctx, r.Package)
+		if !res.ok {
+			continue
+		}
+		zlog.Debug(ctx).
+			Str("package", r.Package.Name).
+			Str("resolved", res.name).
+			Msg("resolved artifact coordinates")
+		p := *r.Package
+		p.Name = res.name
+		if res.version != "" {
+			p.Version = res.version
+		}
+		nr := *r
+		nr.Package = &p
+		out[i] = &nr
+	}
+	return out, nil
+}
+
+// Resolve looks up the Maven coordinates for the package, consulting the
+// cache.
+func (m *Matcher) resolve(ctx context.Context, p *claircore.Package) resolution {
+	sum := strings.TrimSpace(strings.TrimPrefix(p.RepositoryHint, "sha1:"))
+	if sum == p.RepositoryHint {
+		sum = ""
+	}
+	key := sum + "|" + p.Name + "|" + p.Version
+	m.mu.Lock()
+	res, ok := m.cache[key]
+	m.mu.Unlock()
+	if ok {
+		return res
+	}
+
+	var err error
+	res, err = m.lookup(ctx, sum, p.Name, p.Version)
+	if err != nil {
+		zlog.Info(ctx).
+			Str("package", p.Name).
+			Err(err).
+			Msg("unable to resolve artifact coordinates")
+		// Don't cache failures, so a later call can retry.
+		return res
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cache == nil || len(m.cache) >= resolveCacheSize {
+		m.cache = make(map[string]resolution)
+	}
+	m.cache[key] = res
+	return res
+}
+
+// Lookup queries the search API, first by the SHA1 sum if provided, then by
+// artifact ID.
+func (m *Matcher) lookup(ctx context.Context, sum, name, version string) (resolution, error) {
+	if sum != "" {
+		docs, err := searchMaven(ctx, m.client, m.root, fmt.Sprintf(`1:"%s"`, sum))
+		if err != nil {
+			return resolution{}, err
+		}
+		if len(docs) != 0 {
+			// Same as the Scanner: the same artifact is sometimes uploaded
+			// under different names, so pick one consistently.
+			sort.SliceStable(docs, func(i, j int) bool { return docs[i].ID < docs[j].ID })
+			d := &docs[0]
+			return resolution{name: d.Group + ":" + d.Artifact, version: d.Version, ok: true}, nil
+		}
+	}
+	// A shaded or renamed jar won't match by checksum. Fall back to the
+	// artifact ID, which may also be the last element of an OSGi symbolic
+	// name.
+	cs := []string{name}
+	if i := strings.LastIndexByte(name, '.'); i != -1 && i != len(name)-1 {
+		cs = append(cs, name[i+1:])
+	}
+	for _, a := range cs {
+		q := fmt.Sprintf(`a:"%s"`, a)
+		if version != "" {
+			q += fmt.Sprintf(` AND v:"%s"`, version)
+		}
+		docs, err := searchMaven(ctx, m.client, m.root, q)
+		if err != nil {
+			return resolution{}, err
+		}
+		groups := make(map[string]struct{})
+		for _, d := range docs {
+			if d.Artifact == a {
+				groups[d.Group] = struct{}{}
+			}
+		}
+		switch len(groups) {
+		case 0:
+			continue
+		case 1:
+			for g := range groups {
+				return resolution{name: g + ":" + a, ok: true}, nil
+			}
+		default:
+			zlog.Debug(ctx).
+				Str("artifact", a).
+				Int("groups", len(groups)).
+				Msg("ambiguous artifact ID")
+			return resolution{}, nil
+		}
+	}
+	return resolution{}, nil
+}
+
+// Factory is a driver.MatcherFactory for the java Matcher.
+//
+// Configuring the Factory configures the Matcher it returns, enabling the
+// search fallback.
+type Factory struct {
+	m Matcher
+}
+
+// Matcher implements driver.MatcherFactory.
+func (f *Factory) Matcher(_ context.Context) ([]driver.Matcher, error) {
+	return []driver.Matcher{&f.m}, nil
+}
+
+// Configure implements driver.MatcherConfigurable.
+func (f *Factory) Configure(ctx context.Context, cfg driver.MatcherConfigUnmarshaler, c *http.Client) error {
+	return f.m.Configure(ctx, cfg, c)
+}
@@ -0,0 +1,252 @@
+package java
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+func TestMvnVersion(t *testing.T) {
+	// Each version should sort before the next.
+	ordered := []string{
+		"1-alpha-1",
+		"1-alpha2",
+		"1-beta-1",
+		"1-milestone-1",
+		"1-rc-1",
+		"1-snapshot",
+		"1",
+		"1-sp",
+		"1-abc",
+		"1-1",
+		"1.0.1",
+		"1.1",
+		"1.2-rc1",
+		"1.2",
+		"1.10",
+		"2.0.0.redhat-00001",
+		"10",
+		"100000000000000000000",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, b := parseMvnVersion(ordered[i]), parseMvnVersion(ordered[i+1])
+		if got := a.Compare(b); got != -1 {
+			t.Errorf("%q <=> %q: got: %d, want: -1", a, b, got)
+		}
+		if got := b.Compare(a); got != 1 {
+			t.Errorf("%q <=> %q: got: %d, want: 1", b, a, got)
+		}
+	}
+	equal := [][2]string{
+		{"1", "1.0.0"},
+		{"1.0-ga", "1"},
+		{"1.0.Final", "1"},
+		{"1-cr1", "1-rc1"},
+		{"1a1", "1-alpha-1"},
+		{"1.0-RELEASE", "1.0"},
+	}
+	for _, p := range equal {
+		a, b := parseMvnVersion(p[0]), parseMvnVersion(p[1])
+		if got := a.Compare(b); got != 0 {
+			t.Errorf("%q <=> %q: got: %d, want: 0", a, b, got)
+		}
+	}
+}
+
+func TestMvnRange(t *testing.T) {
+	tt := []struct {
+		Spec string
+		In   []string
+		Out  []string
+	}{
+		{
+			Spec: "[1.0,2.0)",
+			In:   []string{"1.0", "1.5", "2.0-rc1"},
+			Out:  []string{"0.9", "2.0", "2.1"},
+		},
+		{
+			Spec: "(,1.0],[1.2,)",
+			In:   []string{"0.1", "1.0", "1.2", "5"},
+			Out:  []string{"1.1", "1.0.1"},
+		},
+		{
+			Spec: "[1.5]",
+			In:   []string{"1.5", "1.5.0"},
+			Out:  []string{"1.5.1"},
+		},
+		{
+			Spec: "2.3.4",
+			In:   []string{"2.3.4"},
+			Out:  []string{"2.3.5"},
+		},
+	}
+	for _, tc := range tt {
+		r, err := parseMvnRange(tc.Spec)
+		if err != nil {
+			t.Errorf("%q: %v", tc.Spec, err)
+			continue
+		}
+		for _, v := range tc.In {
+			if !r.Contains(parseMvnVersion(v)) {
+				t.Errorf("%q: expected to contain %q", tc.Spec, v)
+			}
+		}
+		for _, v := range tc.Out {
+			if r.Contains(parseMvnVersion(v)) {
+				t.Errorf("%q: expected not to contain %q", tc.Spec, v)
+			}
+		}
+	}
+	for _, spec := range []string{"", "[1.0,2.0", "[2.0,1.0]", "(1.0)", "[1.0,2.0)x"} {
+		if _, err := parseMvnRange(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestVulnerable(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tt := []struct {
+		Name    string
+		Version string
+		Vuln    claircore.Vulnerability
+		Want    bool
+	}{
+		{
+			Name:    "Fixed",
+			Version: "2.14.1",
+			Vuln: claircore.Vulnerability{
+				Package:        &claircore.Package{},
+				FixedInVersion: "2.15.0",
+			},
+			Want: true,
+		},
+		{
+			Name:    "FixedNotAffected",
+			Version: "2.15.0",
+			Vuln: claircore.Vulnerability{
+				Package:        &claircore.Package{},
+				FixedInVersion: "2.15.0",
+			},
+			Want: false,
+		},
+		{
+			Name:    "RangeMiss",
+			Version: "1.9",
+			Vuln: claircore.Vulnerability{
+				Package:        &claircore.Package{Version: "[2.0,)"},
+				FixedInVersion: "2.15.0",
+			},
+			Want: false,
+		},
+		{
+			Name:    "RangeOnly",
+			Version: "2.0",
+			Vuln: claircore.Vulnerability{
+				Package: &claircore.Package{Version: "[2.0,2.1)"},
+			},
+			Want: true,
+		},
+		{
+			Name:    "BadRange",
+			Version: "2.0",
+			Vuln: claircore.Vulnerability{
+				Package: &claircore.Package{Version: "[2.0"},
+			},
+			Want: false,
+		},
+		{
+			Name:    "NoVersion",
+			Version: "",
+			Vuln: claircore.Vulnerability{
+				Package: &claircore.Package{},
+			},
+			Want: false,
+		},
+	}
+	var m Matcher
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := claircore.IndexRecord{Package: &claircore.Package{Version: tc.Version}}
+			got, err := m.Vulnerable(ctx, &r, &tc.Vuln)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.Want {
+				t.Errorf("got: %v, want: %v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const sum = "35379fb6526fd019f331542b4e9ae2e566c57933"
+	docs := map[string][]searchDoc{
+		`1:"` + sum + `"`: {
+			{ID: "org.example:thing:1.2.3", Group: "org.example", Artifact: "thing", Version: "1.2.3"},
+		},
+		`a:"shaded" AND v:"4.0"`: {
+			{ID: "com.example:shaded:4.0", Group: "com.example", Artifact: "shaded", Version: "4.0"},
+		},
+		`a:"common" AND v:"1.0"`: {
+			{ID: "a.example:common:1.0", Group: "a.example", Artifact: "common", Version: "1.0"},
+			{ID: "b.example:common:1.0", Group: "b.example", Artifact: "common", Version: "1.0"},
+		},
+	}
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var res searchResponse
+		res.Response.Doc = docs[r.URL.Query().Get("q")]
+		if err := json.NewEncoder(w).Encode(&res); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	var m Matcher
+	cfg := func(v interface{}) error {
+		v.(*MatcherConfig).API = srv.URL
+		return nil
+	}
+	if err := m.Configure(ctx, cfg, srv.Client()); err != nil {
+		t.Fatal(err)
+	}
+	in := []*claircore.IndexRecord{
+		{Package: &claircore.Package{ID: "0", Name: "org.example:thing", Version: "1.2.3"}},
+		{Package: &claircore.Package{ID: "1", Name: "renamed", Version: "1.2.3", RepositoryHint: "sha1:" + sum}},
+		{Package: &claircore.Package{ID: "2", Name: "shaded", Version: "4.0", RepositoryHint: "sha1:" + strings.Repeat("0", 40)}},
+		{Package: &claircore.Package{ID: "3", Name: "common", Version: "1.0"}},
+	}
+	want := []string{"org.example:thing", "org.example:thing", "com.example:shaded", "common"}
+	for n := 0; n < 2; n++ {
+		out, err := m.Resolve(ctx, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, r := range out {
+			if got, want := r.Package.Name, want[i]; got != want {
+				t.Errorf("%d: got: %q, want: %q", i, got, want)
+			}
+			if got, want := r.Package.ID, in[i].Package.ID; got != want {
+				t.Errorf("%d: got ID: %q, want: %q", i, got, want)
+			}
+		}
+	}
+	// The second pass should have been served from the cache.
+	if got, want := atomic.LoadInt32(&calls), int32(4); got != want {
+		t.Errorf("got: %d requests, want: %d", got, want)
+	}
+	if got := in[1].Package.Name; got != "renamed" {
+		t.Errorf("input record modified: %q", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,9 +29,10 @@ import (
 )
 
 var (
-	_ indexer.VersionedScanner = (*Scanner)(nil)
-	_ indexer.PackageScanner   = (*Scanner)(nil)
-	_ indexer.RPCScanner       = (*Scanner)(nil)
+	_ indexer.VersionedScanner   = (*Scanner)(nil)
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
+	_ indexer.RPCScanner         = (*Scanner)(nil)
 )
 
 const DefaultSearchAPI = `https://search.maven.org/solrsearch/select`
@@ -57,11 +59,14 @@ type Scanner struct {
 func (*Scanner) Name() string { return "java" }
 
 // Version implements scanner.VersionedScanner.
-func (*Scanner) Version() string { return "3" }
+func (*Scanner) Version() string { return "5" }
 
 // Kind implements scanner.VersionedScanner.
 func (*Scanner) Kind() string { return "package" }
 
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceDeclared }
+
 // Configure implements indexer.RPCScanner.
 func (s *Scanner) Configure(ctx context.Context, f indexer.ConfigDeserializer, c *http.Client) error {
 	ctx = baggage.ContextWithValues(ctx,
@@ -116,7 +121,9 @@ func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircor
 	var h *tar.Header
 	buf := getBuf()
 	sh := sha1.New()
+	sh256 := sha256.New()
 	ck := make([]byte, sha1.Size)
+	ck256 := make([]byte, sha256.Size)
 	doSearch := s.root != nil
 	defer putBuf(buf)
 	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
@@ -126,10 +133,11 @@ func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircor
 		}
 
 		sh.Reset()
+		sh256.Reset()
 		buf.Reset()
-		// Calculate the SHA1 as it's buffered, since it may be needed for
-		// searching later.
-		sz, err := buf.ReadFrom(io.TeeReader(tr, sh))
+		// Calculate the checksums as it's buffered, since the SHA1 may be
+		// needed for searching later.
+		sz, err := buf.ReadFrom(io.TeeReader(tr, io.MultiWriter(sh, sh256)))
 		if err != nil {
 			return nil, err
 		}
@@ -167,6 +175,7 @@ func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircor
 			return nil, err
 		}
 		sh.Sum(ck[:0])
+		sh256.Sum(ck256[:0])
 		ps := make([]*claircore.Package, len(infos))
 		for j := range infos {
 			i := &infos[j]
@@ -193,6 +202,9 @@ func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircor
 				b = i.SHA
 			}
 			pkg.RepositoryHint = fmt.Sprintf(`sha1:%40x`, b)
+			if pkg.Digests, err = artifactDigests(b, i.SHA, ck256); err != nil {
+				return nil, err
+			}
 			// BUG(hank) There's probably some bugs lurking in the jar.Info →
 			// claircore.Package mapping code around embedded jars. There's a
 			// testcase to be written, there.
@@ -224,6 +236,33 @@ func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircor
 	return ret, nil
 }
 
+// ArtifactDigests returns the digests for an archive with the SHA1 "sum".
+//
+// If "inner" is populated, the archive was found inside another archive and
+// only its SHA1 is known. Otherwise, "sum256" is the archive's SHA256.
+func artifactDigests(sum, inner, sum256 []byte) ([]claircore.Digest, error) {
+	sums := [][]byte{sum}
+	if len(inner) == 0 {
+		sums = append(sums, sum256)
+	}
+	ds := make([]claircore.Digest, len(sums))
+	for i, s := range sums {
+		// Copy the sum, as the backing arrays are reused for every archive.
+		b := make([]byte, len(s))
+		copy(b, s)
+		algo := claircore.SHA1
+		if i == 1 {
+			algo = claircore.SHA256
+		}
+		d, err := claircore.NewDigest(algo, b)
+		if err != nil {
+			return nil, err
+		}
+		ds[i] = d
+	}
+	return ds, nil
+}
+
 // Search attempts to search with the configured client and API endpoint.
 //
 // This function modifies the passed Info in-place if successful. The passed
@@ -236,32 +275,56 @@ func (s *Scanner) search(ctx context.Context, i *jar.Info, ck []byte) error {
 	if i.SHA != nil {
 		ck = i.SHA
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.root.String(), nil)
+	docs, err := searchMaven(ctx, s.client, s.root, fmt.Sprintf(`1:"%40x"`, ck))
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		zlog.Debug(ctx).Msg("no matching artifacts found")
+		return nil
+	}
+	// Sort and then take the first one, because apparently the same
+	// artifact is uploaded under different names sometimes?
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].ID < docs[j].ID
+	})
+	i.Source = s.root.String()
+	d := &docs[0]
+	i.Version = d.Version
+	i.Name = d.Group + ":" + d.Artifact
+	return nil
+}
+
+// SearchMaven performs the query "q" against the maven-like search API at
+// "root", returning the matching documents.
+//
+// ErrRPC is reported if anything went wrong making the request or reading the
+// response.
+func searchMaven(ctx context.Context, c *http.Client, root *url.URL, q string) ([]searchDoc, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, root.String(), nil)
 	if err != nil {
 		zlog.Warn(ctx).
 			Err(err).
 			Msg("unable to construct request")
-		return errRPC
+		return nil, errRPC
 	}
 	v := req.URL.Query()
-	// 40 == 2 * sha1.Size. I don't there's a good way to keep it as
-	// a constant.
-	v.Set("q", fmt.Sprintf(`1:"%40x"`, ck))
+	v.Set("q", q)
 	v.Set("wt", "json")
 	req.URL.RawQuery = v.Encode()
-	res, err := s.client.Do(req)
+	res, err := c.Do(req)
 	if err != nil {
 		zlog.Warn(ctx).
 			Err(err).
 			Msg("error making request")
-		return errRPC
+		return nil, errRPC
 	}
 	if res.StatusCode != http.StatusOK {
 		res.Body.Close()
 		zlog.Warn(ctx).
 			Str("status", res.Status).
 			Msg("unexpected reponse status")
-		return errRPC
+		return nil, errRPC
 	}
 	var sr searchResponse
 	err = json.NewDecoder(res.Body).Decode(&sr)
@@ -270,22 +333,9 @@ func (s *Scanner) search(ctx context.Context, i *jar.Info, ck []byte) error {
 		zlog.Warn(ctx).
 			Err(err).
 			Msg("error decoding json")
-		return errRPC
-	}
-	if len(sr.Response.Doc) == 0 {
-		zlog.Debug(ctx).Msg("no matching artifacts found")
-		return nil
+		return nil, errRPC
 	}
-	// Sort and then take the first one, because apparently the same
-	// artifact is uploaded under different names sometimes?
-	sort.SliceStable(sr.Response.Doc, func(i, j int) bool {
-		return sr.Response.Doc[i].ID < sr.Response.Doc[j].ID
-	})
-	i.Source = s.root.String()
-	d := &sr.Response.Doc[0]
-	i.Version = d.Version
-	i.Name = d.Group + ":" + d.Artifact
-	return nil
+	return sr.Response.Doc, nil
 }
 
 var errRPC = errors.New("search rpc failed")
@@ -296,12 +346,15 @@ var errRPC = errors.New("search rpc failed")
 // https://search.maven.org/solrsearch/select?q=1:%2235379fb6526fd019f331542b4e9ae2e566c57933%22&wt=json
 type searchResponse struct {
 	Response struct {
-		Doc []struct {
-			ID         string `json:"id"`
-			Group      string `json:"g"`
-			Artifact   string `json:"a"`
-			Version    string `json:"v"`
-			Classifier string `json:"p"`
-		} `json:"docs"`
+		Doc []searchDoc `json:"docs"`
 	} `json:"response"`
 }
+
+// SearchDoc is a single artifact in a searchResponse.
+type searchDoc struct {
+	ID         string `json:"id"`
+	Group      string `json:"g"`
+	Artifact   string `json:"a"`
+	Version    string `json:"v"`
+	Classifier string `json:"p"`
+}
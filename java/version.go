@@ -0,0 +1,347 @@
+package java
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// This file implements Maven's version ordering, following the algorithm in
+// org.apache.maven.artifact.versioning.ComparableVersion, and Maven's version
+// range syntax.
+
+// MvnVersion is a parsed Maven version.
+type mvnVersion struct {
+	orig  string
+	items mvnList
+}
+
+func parseMvnVersion(v string) mvnVersion {
+	return mvnVersion{orig: v, items: parseMvnItems(v)}
+}
+
+func (v mvnVersion) String() string { return v.orig }
+
+// Compare returns an integer comparing two versions. The result will be 0 if
+// v == o, -1 if v < o, and +1 if v > o.
+func (v mvnVersion) Compare(o mvnVersion) int {
+	return v.items.compare(o.items)
+}
+
+// MvnItem is one element of a parsed version: a mvnInt, a mvnString, or a
+// mvnList.
+type mvnItem interface {
+	compare(mvnItem) int
+	isNull() bool
+}
+
+// MvnInt is a numeric element. It's kept as a string of digits without
+// leading zeros, so arbitrarily large numbers compare correctly.
+type mvnInt string
+
+func newMvnInt(s string) mvnInt {
+	s = strings.TrimLeft(s, "0")
+	return mvnInt(s)
+}
+
+func (i mvnInt) isNull() bool { return i == "" }
+
+func (i mvnInt) compare(o mvnItem) int {
+	switch o := o.(type) {
+	case nil:
+		if i.isNull() {
+			return 0
+		}
+		return 1
+	case mvnInt:
+		if len(i) != len(o) {
+			return cmpInt(len(i), len(o))
+		}
+		return strings.Compare(string(i), string(o))
+	case mvnString:
+		return 1
+	case mvnList:
+		return 1
+	}
+	panic(fmt.Sprintf("unknown item type: %T", o))
+}
+
+// MvnString is a qualifier element.
+type mvnString string
+
+// Qualifiers are the well-known qualifiers, in order. The empty string is the
+// release.
+var qualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+var releaseIndex = comparableQualifier("")
+
+func newMvnString(s string, followedByDigit bool) mvnString {
+	if followedByDigit && len(s) == 1 {
+		switch s {
+		case "a":
+			s = "alpha"
+		case "b":
+			s = "beta"
+		case "m":
+			s = "milestone"
+		}
+	}
+	switch s {
+	case "ga", "final", "release":
+		s = ""
+	case "cr":
+		s = "rc"
+	}
+	return mvnString(s)
+}
+
+// ComparableQualifier returns a string that sorts well-known qualifiers in
+// their defined order, before any unknown qualifiers, which sort lexically.
+func comparableQualifier(q string) string {
+	for i, k := range qualifiers {
+		if q == k {
+			return strconv.Itoa(i)
+		}
+	}
+	return strconv.Itoa(len(qualifiers)) + "-" + q
+}
+
+func (s mvnString) isNull() bool { return comparableQualifier(string(s)) == releaseIndex }
+
+func (s mvnString) compare(o mvnItem) int {
+	switch o := o.(type) {
+	case nil:
+		return strings.Compare(comparableQualifier(string(s)), releaseIndex)
+	case mvnInt:
+		return -1
+	case mvnString:
+		return strings.Compare(comparableQualifier(string(s)), comparableQualifier(string(o)))
+	case mvnList:
+		return -1
+	}
+	panic(fmt.Sprintf("unknown item type: %T", o))
+}
+
+// MvnList is a sequence of elements. Elements separated by a hyphen, or by a
+// transition between digits and letters, start a new sub-list.
+type mvnList []mvnItem
+
+func (l mvnList) isNull() bool { return len(l) == 0 }
+
+func (l mvnList) compare(o mvnItem) int {
+	switch o := o.(type) {
+	case nil:
+		if len(l) == 0 {
+			return 0
+		}
+		return l[0].compare(nil)
+	case mvnInt:
+		return -1
+	case mvnString:
+		return 1
+	case mvnList:
+		for i := 0; i < len(l) || i < len(o); i++ {
+			var a, b mvnItem
+			if i < len(l) {
+				a = l[i]
+			}
+			if i < len(o) {
+				b = o[i]
+			}
+			var r int
+			switch {
+			case a == nil && b == nil:
+			case a == nil:
+				r = -b.compare(a)
+			default:
+				r = a.compare(b)
+			}
+			if r != 0 {
+				return r
+			}
+		}
+		return 0
+	}
+	panic(fmt.Sprintf("unknown item type: %T", o))
+}
+
+// Normalize removes trailing null items.
+func (l mvnList) normalize() mvnList {
+	for i := len(l) - 1; i >= 0; i-- {
+		it := l[i]
+		if it.isNull() {
+			l = append(l[:i], l[i+1:]...)
+			continue
+		}
+		if _, ok := it.(mvnList); !ok {
+			break
+		}
+	}
+	return l
+}
+
+func parseMvnItems(v string) mvnList {
+	v = strings.ToLower(v)
+	// Each new sub-list is the last element of the one before it, so the
+	// lists are kept as a stack and attached to their parents at the end,
+	// once they've been normalized.
+	stack := []mvnList{nil}
+	push := func() { stack = append(stack, nil) }
+	add := func(it mvnItem) { stack[len(stack)-1] = append(stack[len(stack)-1], it) }
+	parseItem := func(digit bool, s string) mvnItem {
+		if digit {
+			return newMvnInt(s)
+		}
+		return newMvnString(s, false)
+	}
+
+	digit := false
+	start := 0
+	for i, c := range v {
+		switch {
+		case c == '.':
+			if i == start {
+				add(mvnInt(""))
+			} else {
+				add(parseItem(digit, v[start:i]))
+			}
+			start = i + 1
+		case c == '-':
+			if i == start {
+				add(mvnInt(""))
+			} else {
+				add(parseItem(digit, v[start:i]))
+			}
+			start = i + 1
+			push()
+		case unicode.IsDigit(c):
+			if !digit && i > start {
+				add(newMvnString(v[start:i], true))
+				start = i
+				push()
+			}
+			digit = true
+		default:
+			if digit && i > start {
+				add(parseItem(true, v[start:i]))
+				start = i
+				push()
+			}
+			digit = false
+		}
+	}
+	if len(v) > start {
+		add(parseItem(digit, v[start:]))
+	}
+	for i := len(stack) - 1; i > 0; i-- {
+		stack[i-1] = append(stack[i-1], stack[i].normalize())
+	}
+	return stack[0].normalize()
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// MvnRange is a Maven version range specification, such as "[1.0,2.0)" or
+// "(,1.0],[1.2,)".
+type mvnRange []mvnRestriction
+
+type mvnRestriction struct {
+	lower, upper         *mvnVersion
+	lowerIncl, upperIncl bool
+}
+
+// ParseMvnRange parses a version range specification. A specification without
+// any brackets is taken to mean exactly that version.
+func parseMvnRange(spec string) (mvnRange, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("java: empty version range")
+	}
+	if spec[0] != '[' && spec[0] != '(' {
+		v := parseMvnVersion(spec)
+		return mvnRange{{lower: &v, upper: &v, lowerIncl: true, upperIncl: true}}, nil
+	}
+	var out mvnRange
+	for rest := spec; rest != ""; {
+		end := strings.IndexAny(rest, ")]")
+		if end == -1 {
+			return nil, fmt.Errorf("java: unbalanced version range %q", spec)
+		}
+		r, err := parseMvnRestriction(rest[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("java: bad version range %q: %w", spec, err)
+		}
+		out = append(out, r)
+		rest = strings.TrimSpace(rest[end+1:])
+		rest = strings.TrimPrefix(rest, ",")
+		rest = strings.TrimSpace(rest)
+		if rest != "" && rest[0] != '[' && rest[0] != '(' {
+			return nil, fmt.Errorf("java: bad version range %q", spec)
+		}
+	}
+	return out, nil
+}
+
+func parseMvnRestriction(s string) (mvnRestriction, error) {
+	var r mvnRestriction
+	r.lowerIncl = s[0] == '['
+	r.upperIncl = s[len(s)-1] == ']'
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	i := strings.IndexByte(inner, ',')
+	if i == -1 {
+		if !r.lowerIncl || !r.upperIncl || inner == "" {
+			return r, fmt.Errorf("single version must be surrounded by []")
+		}
+		v := parseMvnVersion(inner)
+		r.lower, r.upper = &v, &v
+		return r, nil
+	}
+	if lo := strings.TrimSpace(inner[:i]); lo != "" {
+		v := parseMvnVersion(lo)
+		r.lower = &v
+	}
+	if hi := strings.TrimSpace(inner[i+1:]); hi != "" {
+		v := parseMvnVersion(hi)
+		r.upper = &v
+	}
+	if r.lower != nil && r.upper != nil && r.lower.Compare(*r.upper) > 0 {
+		return r, fmt.Errorf("lower bound greater than upper bound")
+	}
+	return r, nil
+}
+
+// Contains reports whether the version is within any restriction of the
+// range.
+func (r mvnRange) Contains(v mvnVersion) bool {
+	for _, x := range r {
+		if x.contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (x mvnRestriction) contains(v mvnVersion) bool {
+	if x.lower != nil {
+		c := v.Compare(*x.lower)
+		if c < 0 || (c == 0 && !x.lowerIncl) {
+			return false
+		}
+	}
+	if x.upper != nil {
+		c := v.Compare(*x.upper)
+		if c > 0 || (c == 0 && !x.upperIncl) {
+			return false
+		}
+	}
+	return true
+}
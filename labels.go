@@ -0,0 +1,57 @@
+package claircore
+
+import "strings"
+
+// Well-known image labels.
+//
+// The "org.opencontainers.image" labels are defined by the OCI image
+// specification. The rest are set by the Red Hat container build system.
+const (
+	LabelOCIPrefix       = "org.opencontainers.image."
+	LabelOCIBaseName     = "org.opencontainers.image.base.name"
+	LabelOCIBaseDigest   = "org.opencontainers.image.base.digest"
+	LabelOCISource       = "org.opencontainers.image.source"
+	LabelOCIVendor       = "org.opencontainers.image.vendor"
+	LabelOCIVersion      = "org.opencontainers.image.version"
+	LabelRedHatComponent = "com.redhat.component"
+	LabelRedHatLicense   = "com.redhat.license_terms"
+)
+
+// RedHatLabels are the labels set by the Red Hat container build system that
+// are kept as hints. Some of these are generic names, but are only
+// meaningful alongside the "com.redhat.component" label.
+var redhatLabels = map[string]struct{}{
+	LabelRedHatComponent:    {},
+	LabelRedHatLicense:      {},
+	"com.redhat.build-host": {},
+	"architecture":          {},
+	"distribution-scope":    {},
+	"name":                  {},
+	"release":               {},
+	"url":                   {},
+	"vcs-ref":               {},
+	"vcs-type":              {},
+	"vendor":                {},
+	"version":               {},
+}
+
+// LabelHints returns the well-known labels from the provided image labels.
+//
+// Labels in the OCI namespace are always kept. Red Hat build labels are kept
+// only if the image has a "com.redhat.component" label. A nil map is
+// returned if there are no well-known labels.
+func LabelHints(labels map[string]string) map[string]string {
+	var out map[string]string
+	_, redhat := labels[LabelRedHatComponent]
+	for k, v := range labels {
+		_, rh := redhatLabels[k]
+		if !strings.HasPrefix(k, LabelOCIPrefix) && !(redhat && rh) {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string)
+		}
+		out[k] = v
+	}
+	return out
+}
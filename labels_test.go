@@ -0,0 +1,56 @@
+package claircore
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLabelHints(t *testing.T) {
+	tt := []struct {
+		Name string
+		In   map[string]string
+		Want map[string]string
+	}{
+		{
+			Name: "None",
+			In:   map[string]string{"maintainer": "someone"},
+			Want: nil,
+		},
+		{
+			Name: "OCI",
+			In: map[string]string{
+				LabelOCISource: "https://github.com/quay/claircore",
+				"version":      "1.0",
+				"maintainer":   "someone",
+			},
+			Want: map[string]string{
+				LabelOCISource: "https://github.com/quay/claircore",
+			},
+		},
+		{
+			Name: "RedHat",
+			In: map[string]string{
+				LabelRedHatComponent: "ubi8-container",
+				"name":               "ubi8",
+				"vendor":             "Red Hat, Inc.",
+				"version":            "8.4",
+				"maintainer":         "Red Hat, Inc.",
+			},
+			Want: map[string]string{
+				LabelRedHatComponent: "ubi8-container",
+				"name":               "ubi8",
+				"vendor":             "Red Hat, Inc.",
+				"version":            "8.4",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := LabelHints(tc.In)
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Layer is a container image filesystem layer. Layers are stacked
@@ -18,13 +19,22 @@ type Layer struct {
 	Hash    Digest              `json:"hash"`
 	URI     string              `json:"uri"`
 	Headers map[string][]string `json:"headers"`
+	// URLs are the locations the image manifest declares the layer can be
+	// fetched from. Only foreign, or "non-distributable", layers have URLs;
+	// the registry may not serve them at URI.
+	URLs []string `json:"urls,omitempty"`
 
 	// path to local file containing uncompressed tar archive of the layer's content
 	localPath string
+	// Idx is the index of the file names in the archive at localPath.
+	idx *LayerIndex
+	// Limit is the read cap enforced by Files, if any.
+	limit *FileLimit
 }
 
 func (l *Layer) SetLocal(f string) error {
 	l.localPath = f
+	l.idx = &LayerIndex{}
 	return nil
 }
 
@@ -65,90 +75,133 @@ var ErrNotFound = errors.New("claircore: unable to find any requested files")
 
 // Files retrieves specific files from the layer's tar archive.
 //
-// An error is returned only if none of the requested files are found.
+// Files are found using the layer's Index, so the archive is only read in
+// full once no matter how many times Files is called.
+//
+// An error is returned only if none of the requested files are found. Files
+// exceeding the Layer's FileLimit, if any, are skipped or truncated; see
+// Limited.
 //
 // The returned map may contain more entries than the number of paths requested.
 // All entries in the map are keyed by paths that are relative to the tar-root.
 // For example, requesting paths of "/etc/os-release", "./etc/os-release", and
 // "etc/os-release" will all result in any found content being stored with the
 // key "etc/os-release".
+//
+// Paths containing any of the special characters "*?[\\" are patterns, and are
+// expanded using Glob. For example, "etc/*release" retrieves both
+// "etc/os-release" and "etc/lsb-release", and "**/*.dist-info/METADATA" every
+// installed python distribution's metadata. A pattern matching nothing isn't
+// an error on its own.
 func (l *Layer) Files(paths ...string) (map[string]*bytes.Buffer, error) {
-	r, err := l.Reader()
+	return l.files(paths, nil)
+}
+
+// LayerFile is a file from a layer's archive.
+type LayerFile struct {
+	// Header is the header of the file's entry in the archive. For a file
+	// found by way of links, it's the header of the regular file the links
+	// resolve to.
+	Header *tar.Header
+	// Contents is the file's contents, subject to the Layer's FileLimit.
+	Contents *bytes.Buffer
+}
+
+// FilesWithHeaders is like Files, but also returns each file's tar header.
+func (l *Layer) FilesWithHeaders(paths ...string) (map[string]LayerFile, error) {
+	hdrs := make(map[string]*tar.Header)
+	fs, err := l.files(paths, hdrs)
 	if err != nil {
 		return nil, err
 	}
-	defer r.Close()
-	rs := r.(io.ReadSeeker)
+	out := make(map[string]LayerFile, len(fs))
+	for n, b := range fs {
+		out[n] = LayerFile{Header: hdrs[n], Contents: b}
+	}
+	return out, nil
+}
+
+// HasMeta reports whether the path contains any of the special characters
+// recognized by Glob.
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, `*?[\`)
+}
+
+// Files implements Files and FilesWithHeaders, recording the headers if
+// "hdrs" isn't nil.
+func (l *Layer) files(paths []string, hdrs map[string]*tar.Header) (map[string]*bytes.Buffer, error) {
+	idx, err := l.Index()
+	if err != nil {
+		return nil, err
+	}
+	ra, err := l.ReaderAt()
+	if err != nil {
+		return nil, err
+	}
+	defer ra.Close()
 
-	// Clean the input paths.
-	want := make(map[string]struct{})
+	// Clean the input paths, and expand any patterns.
+	want := make([]string, 0, len(paths))
 	for i, p := range paths {
+		if hasMeta(p) {
+			ms, err := l.Glob(p)
+			if err != nil {
+				return nil, err
+			}
+			want = append(want, ms...)
+			continue
+		}
 		p := normalizeIn("/", p)
 		paths[i] = p
-		want[p] = struct{}{}
+		want = append(want, p)
 	}
 
-	alias := make(map[string]string)
 	f := make(map[string]*bytes.Buffer)
-	again := true // again is our flag for re-reading the tarball.
-	for rs.Seek(0, io.SeekStart); again; rs.Seek(0, io.SeekStart) {
-		again = false
-		tr := tar.NewReader(rs)
-		hdr, err := tr.Next()
-		for ; err == nil; hdr, err = tr.Next() {
-			name := filepath.Clean(hdr.Name)
-			// check if the current header has a path name we are
-			// searching for.
-			if _, ok := want[name]; !ok {
+	skipped := make(map[string]struct{})
+	for _, p := range want {
+		// Chase any links, recording every name along the way so that they
+		// can all be keyed to the contents.
+		var chain []string
+		var b *bytes.Buffer
+		var h *tar.Header
+		n := p
+		for hops := 0; hops < maxLinks; hops++ {
+			if buf, ok := f[n]; ok {
+				b, h = buf, hdrs[n]
+				break
+			}
+			if _, ok := skipped[n]; ok {
+				break
+			}
+			e, ok := idx.entries[n]
+			if !ok {
+				break
+			}
+			chain = append(chain, n)
+			if e.Typeflag != tar.TypeReg {
+				n = normalizeIn(filepath.Join("/", filepath.Dir(n)), e.Linkname)
 				continue
 			}
-			delete(want, name)
-
-			switch hdr.Typeflag {
-			case tar.TypeLink, tar.TypeSymlink:
-				n := normalizeIn(filepath.Join("/", filepath.Dir(name)), hdr.Linkname)
-				if _, ok := f[n]; !ok { // If we don't already have it, add to the want set.
-					want[n] = struct{}{}
-					again = true
-				}
-				alias[name] = n
-			case tar.TypeReg:
-				b := make([]byte, hdr.Size)
-				if n, err := io.ReadFull(tr, b); int64(n) != hdr.Size || err != nil {
-					return nil, fmt.Errorf("claircore: unable to read file from archive: read %d bytes (wanted: %d): %w", n, hdr.Size, err)
-				}
-				f[name] = bytes.NewBuffer(b)
-			default:
-				// skip
+			b, err = l.readEntry(ra, e)
+			if err != nil {
+				return nil, err
+			}
+			h = e.Header
+			if b == nil {
+				skipped[n] = struct{}{}
 			}
+			break
 		}
-		if err != io.EOF {
-			return nil, err
+		if b == nil {
+			continue
 		}
-	}
-
-	// Fixup the names from chasing symlinks.
-	notfound := &bytes.Buffer{}
-	for n := range want {
-		// If the target is still in the want list, we didn't find it. So,
-		// use the notfound sentinel.
-		f[n] = notfound
-	}
-	for len(alias) != 0 {
-		for from, to := range alias {
-			f[from] = f[to]
-			// Once we've chased any symlinks all the way through, remove them.
-			if f[from] != nil {
-				delete(alias, from)
+		for _, n := range chain {
+			f[n] = b
+			if hdrs != nil {
+				hdrs[n] = h
 			}
 		}
 	}
-	// Now remove anything that's resolved to the notfound sentinel.
-	for n, v := range f {
-		if v == notfound {
-			delete(f, n)
-		}
-	}
 
 	// If there's nothing in the "f" map, we didn't find anything.
 	if len(f) == 0 {
@@ -156,3 +209,76 @@ func (l *Layer) Files(paths ...string) (map[string]*bytes.Buffer, error) {
 	}
 	return f, nil
 }
+
+// MaxLinks is the number of links Files will follow before giving up on a
+// path.
+const maxLinks = 40
+
+// ReadEntry reads the contents of a regular file in the layer, enforcing the
+// Layer's FileLimit. A nil Buffer is returned if the file is skipped.
+func (l *Layer) readEntry(ra io.ReaderAt, e LayerEntry) (*bytes.Buffer, error) {
+	size := e.Size
+	if lim := l.limit; lim != nil && lim.Max > 0 && size > lim.Max {
+		if lim.OnLimit != nil {
+			lim.OnLimit(e.Name, size)
+		}
+		if !lim.Truncate {
+			return nil, nil
+		}
+		size = lim.Max
+	}
+	b := make([]byte, size)
+	var n int
+	var err error
+	if e.Offset < 0 {
+		n, err = l.readStream(e.Name, b)
+	} else {
+		n, err = ra.ReadAt(b, e.Offset)
+	}
+	if int64(n) != size {
+		return nil, fmt.Errorf("claircore: unable to read file from archive: read %d bytes (wanted: %d): %w", n, size, err)
+	}
+	return bytes.NewBuffer(b), nil
+}
+
+// ReadStream reads the start of the named file's contents into "b" by walking
+// the archive, for files whose contents can't be read directly.
+func (l *Layer) readStream(name string, b []byte) (int, error) {
+	r, err := l.Reader()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	for ; err == nil; hdr, err = tr.Next() {
+		if hdr.Typeflag == tar.TypeReg && normalizeIn("/", hdr.Name) == name {
+			return io.ReadFull(tr, b)
+		}
+	}
+	return 0, err
+}
+
+// FileLimit caps the size of files returned by Layer.Files.
+type FileLimit struct {
+	// Max is the largest file, in bytes, returned in full. A Max of zero
+	// disables the limit.
+	Max int64
+	// Truncate, if set, causes files over Max to be returned truncated to Max
+	// bytes instead of being skipped.
+	Truncate bool
+	// OnLimit, if set, is called with the tar-root relative name and size of
+	// every file over Max.
+	OnLimit func(name string, size int64)
+}
+
+// Limited returns a copy of the Layer whose Files method enforces the provided
+// FileLimit.
+//
+// This protects scanners from reading enormous files into memory just because
+// they happen to be at a path the scanner is interested in.
+func (l *Layer) Limited(lim FileLimit) *Layer {
+	out := *l
+	out.limit = &lim
+	return &out
+}
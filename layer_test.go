@@ -0,0 +1,86 @@
+package claircore
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLayerFileLimit(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := tar.NewWriter(f)
+	for n, c := range map[string]string{
+		"etc/os-release": "ID=test\n",
+		"var/log/big":    strings.Repeat("x", 4096),
+	} {
+		if err := w.WriteHeader(&tar.Header{
+			Name:     n,
+			Typeflag: tar.TypeReg,
+			Size:     int64(len(c)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var l Layer
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Skip", func(t *testing.T) {
+		var hit []string
+		ll := l.Limited(FileLimit{
+			Max:     1024,
+			OnLimit: func(n string, _ int64) { hit = append(hit, n) },
+		})
+		fs, err := ll.Files("etc/os-release", "var/log/big")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := fs["var/log/big"]; ok {
+			t.Error("oversized file returned")
+		}
+		if got, want := fs["etc/os-release"].String(), "ID=test\n"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if len(hit) != 1 || hit[0] != "var/log/big" {
+			t.Errorf("unexpected OnLimit calls: %v", hit)
+		}
+		if _, err := ll.Files("var/log/big"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got: %v, want: %v", err, ErrNotFound)
+		}
+	})
+	t.Run("Truncate", func(t *testing.T) {
+		fs, err := l.Limited(FileLimit{Max: 1024, Truncate: true}).Files("var/log/big")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := fs["var/log/big"].Len(), 1024; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+	t.Run("Unlimited", func(t *testing.T) {
+		fs, err := l.Files("var/log/big")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := fs["var/log/big"].Len(), 4096; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+}
@@ -0,0 +1,286 @@
+package claircore
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LayerIndex is an index of the files in a layer's tar archive, recording
+// where each file's contents are in the archive.
+//
+// It's built once, on first use, and shared by all copies of the Layer it
+// was created for, so scanners looking up files by name don't each need to
+// read the whole archive. Use the Layer's ReaderAt to read the contents of an
+// indexed file.
+type LayerIndex struct {
+	once    sync.Once
+	err     error
+	names   []string // sorted
+	entries map[string]LayerEntry
+}
+
+// LayerEntry describes a regular file or link in a layer's archive.
+type LayerEntry struct {
+	// Name is the tar-root relative path of the file.
+	Name string
+	// Typeflag is the entry's type, one of tar.TypeReg, tar.TypeLink, or
+	// tar.TypeSymlink.
+	Typeflag byte
+	// Linkname is the target of a link, as recorded in the archive.
+	Linkname string
+	// Offset is the position of a regular file's contents in the archive.
+	// It's -1 if the contents can't be read directly, as with sparse files.
+	Offset int64
+	// Size is the length of a regular file's contents.
+	Size int64
+	// Header is the entry's header as read from the archive.
+	Header *tar.Header
+}
+
+// Names returns the sorted names of the regular files and links in the layer.
+// The returned slice must not be modified.
+func (i *LayerIndex) Names() []string {
+	return i.names
+}
+
+// Lookup returns the entry for the named file, reporting whether there is one.
+// The name is relative to the tar-root; a leading "/" or "./" is ignored.
+//
+// If the archive has more than one entry with the name, the first is
+// returned.
+func (i *LayerIndex) Lookup(name string) (LayerEntry, bool) {
+	e, ok := i.entries[normalizeIn("/", name)]
+	return e, ok
+}
+
+// Index returns the layer's file index, building it if needed.
+func (l *Layer) Index() (*LayerIndex, error) {
+	idx := l.idx
+	if idx == nil {
+		idx = &LayerIndex{}
+	}
+	idx.once.Do(func() {
+		idx.err = l.buildIndex(idx)
+	})
+	if idx.err != nil {
+		return nil, idx.err
+	}
+	return idx, nil
+}
+
+// Names returns the sorted names of the regular files and links in the layer,
+// building the index if needed.
+func (l *Layer) names() ([]string, error) {
+	idx, err := l.Index()
+	if err != nil {
+		return nil, err
+	}
+	return idx.names, nil
+}
+
+func (l *Layer) buildIndex(idx *LayerIndex) error {
+	r, err := l.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	// The tar reader doesn't read ahead, so the position in the file after
+	// reading a header is the start of the entry's contents.
+	rs, _ := r.(io.Seeker)
+	idx.entries = make(map[string]LayerEntry)
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	for ; err == nil; hdr, err = tr.Next() {
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeLink, tar.TypeSymlink:
+		default:
+			continue
+		}
+		n := normalizeIn("/", hdr.Name)
+		if _, ok := idx.entries[n]; ok {
+			continue
+		}
+		e := LayerEntry{
+			Name:     n,
+			Typeflag: hdr.Typeflag,
+			Linkname: hdr.Linkname,
+			Offset:   -1,
+			Size:     hdr.Size,
+			Header:   hdr,
+		}
+		if hdr.Typeflag == tar.TypeReg && rs != nil && !sparse(hdr) {
+			if e.Offset, err = rs.Seek(0, io.SeekCurrent); err != nil {
+				return fmt.Errorf("claircore: unable to index layer: %w", err)
+			}
+		}
+		idx.entries[n] = e
+		idx.names = append(idx.names, n)
+	}
+	if !errors.Is(err, io.EOF) {
+		return fmt.Errorf("claircore: unable to index layer: %w", err)
+	}
+	sort.Strings(idx.names)
+	return nil
+}
+
+// Sparse reports whether the header describes a sparse file, whose contents
+// aren't stored contiguously in the archive.
+func sparse(h *tar.Header) bool {
+	if h.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for k := range h.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadAtCloser is the interface that groups the ReadAt and Close methods.
+type ReadAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// ReaderAt returns a ReadAtCloser of the layer's tar archive, for reading the
+// contents of the files in its Index.
+func (l *Layer) ReaderAt() (ReadAtCloser, error) {
+	r, err := l.Reader()
+	if err != nil {
+		return nil, err
+	}
+	ra, ok := r.(ReadAtCloser)
+	if !ok {
+		r.Close()
+		return nil, fmt.Errorf("claircore: layer does not support random access")
+	}
+	return ra, nil
+}
+
+// Glob returns the paths of the files in the layer matching the pattern,
+// sorted.
+//
+// Patterns use the syntax of path.Match, with the addition that a "**"
+// element matches zero or more path elements. Patterns are relative to the
+// tar-root, like the keys returned by Files; a leading "/" or "./" is
+// ignored. For example, "**/pom.properties" matches "pom.properties" and
+// "usr/share/java/META-INF/maven/g/a/pom.properties".
+//
+// The returned paths are suitable for passing to Files. Only regular files and
+// links are reported.
+func (l *Layer) Glob(pattern string) ([]string, error) {
+	pattern = strings.TrimPrefix(path.Clean("/"+pattern), "/")
+	pat := strings.Split(pattern, "/")
+	for _, p := range pat {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, err
+		}
+	}
+	names, err := l.names()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, n := range names {
+		if globMatch(pat, strings.Split(n, "/")) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+// Contains reports whether any file in the layer matches any of the patterns,
+// which use the syntax of Glob.
+func (l *Layer) Contains(patterns ...string) (bool, error) {
+	pats := make([][]string, len(patterns))
+	for i, pattern := range patterns {
+		pattern = strings.TrimPrefix(path.Clean("/"+pattern), "/")
+		pats[i] = strings.Split(pattern, "/")
+		for _, p := range pats[i] {
+			if _, err := path.Match(p, ""); err != nil {
+				return false, err
+			}
+		}
+	}
+	if len(pats) == 0 {
+		return false, nil
+	}
+	names, err := l.names()
+	if err != nil {
+		return false, err
+	}
+	for _, n := range names {
+		name := strings.Split(n, "/")
+		for _, pat := range pats {
+			if globMatch(pat, name) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Prefix returns the paths of the files in the layer beginning with the
+// prefix, sorted. The prefix is relative to the tar-root, like the keys
+// returned by Files; a leading "/" or "./" is ignored. A trailing "/" limits
+// the results to files within that directory: "usr/lib/" doesn't match
+// "usr/lib64/libc.so.6", but "usr/lib" does.
+//
+// The returned paths are suitable for passing to Files. Only regular files and
+// links are reported.
+func (l *Layer) Prefix(prefix string) ([]string, error) {
+	dir := strings.HasSuffix(prefix, "/")
+	prefix = strings.TrimPrefix(path.Clean("/"+prefix), "/")
+	if dir && prefix != "" {
+		prefix += "/"
+	}
+	names, err := l.names()
+	if err != nil {
+		return nil, err
+	}
+	i := sort.SearchStrings(names, prefix)
+	j := i
+	for j < len(names) && strings.HasPrefix(names[j], prefix) {
+		j++
+	}
+	if i == j {
+		return nil, nil
+	}
+	return append([]string(nil), names[i:j]...), nil
+}
+
+// GlobMatch reports whether the path elements match the pattern elements.
+func globMatch(pat, name []string) bool {
+	for len(pat) != 0 {
+		if pat[0] == "**" {
+			// Collapse runs of "**".
+			for len(pat) != 0 && pat[0] == "**" {
+				pat = pat[1:]
+			}
+			if len(pat) == 0 {
+				return true
+			}
+			for i := range name {
+				if globMatch(pat, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,250 @@
+package claircore
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLayerGlob(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := tar.NewWriter(f)
+	for _, h := range []tar.Header{
+		{Name: "./etc/os-release", Typeflag: tar.TypeReg},
+		{Name: "usr/", Typeflag: tar.TypeDir},
+		{Name: "usr/lib/python3.9/site-packages/six-1.16.0.dist-info/METADATA", Typeflag: tar.TypeReg},
+		{Name: "usr/lib64/libc.so.6", Typeflag: tar.TypeReg},
+		{Name: "/app/pom.properties", Typeflag: tar.TypeReg},
+		{Name: "app/lib/META-INF/maven/g/a/pom.properties", Typeflag: tar.TypeReg},
+		{Name: "usr/lib/os-release", Typeflag: tar.TypeSymlink, Linkname: "../../etc/os-release"},
+	} {
+		h := h
+		if err := w.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var l Layer
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Glob", func(t *testing.T) {
+		tt := []struct {
+			Pattern string
+			Want    []string
+		}{
+			{"**/pom.properties", []string{"app/lib/META-INF/maven/g/a/pom.properties", "app/pom.properties"}},
+			{"/etc/os-release", []string{"etc/os-release"}},
+			{"usr/lib/python3.*/site-packages/*.dist-info/METADATA", []string{"usr/lib/python3.9/site-packages/six-1.16.0.dist-info/METADATA"}},
+			{"usr/**", []string{
+				"usr/lib/os-release",
+				"usr/lib/python3.9/site-packages/six-1.16.0.dist-info/METADATA",
+				"usr/lib64/libc.so.6",
+			}},
+			{"**/*.jar", nil},
+		}
+		for _, tc := range tt {
+			got, err := l.Glob(tc.Pattern)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Errorf("%s: %s", tc.Pattern, cmp.Diff(got, tc.Want))
+			}
+		}
+		if _, err := l.Glob("[-"); err == nil {
+			t.Error("expected bad pattern error")
+		}
+	})
+	t.Run("Prefix", func(t *testing.T) {
+		tt := []struct {
+			Prefix string
+			Want   []string
+		}{
+			{"usr/lib/", []string{"usr/lib/os-release", "usr/lib/python3.9/site-packages/six-1.16.0.dist-info/METADATA"}},
+			{"/usr/lib", []string{
+				"usr/lib/os-release",
+				"usr/lib/python3.9/site-packages/six-1.16.0.dist-info/METADATA",
+				"usr/lib64/libc.so.6",
+			}},
+			{"opt/", nil},
+		}
+		for _, tc := range tt {
+			got, err := l.Prefix(tc.Prefix)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Errorf("%s: %s", tc.Prefix, cmp.Diff(got, tc.Want))
+			}
+		}
+	})
+	t.Run("Contains", func(t *testing.T) {
+		tt := []struct {
+			Patterns []string
+			Want     bool
+		}{
+			{[]string{"etc/redhat-release", "etc/os-release"}, true},
+			{[]string{"**/*.jar", "var/lib/dpkg/status"}, false},
+			{[]string{"usr/lib/os-release"}, true},
+			{nil, false},
+		}
+		for _, tc := range tt {
+			got, err := l.Contains(tc.Patterns...)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if got != tc.Want {
+				t.Errorf("%v: got: %v, want: %v", tc.Patterns, got, tc.Want)
+			}
+		}
+		if _, err := l.Contains("etc/os-release", "[-"); err == nil {
+			t.Error("expected bad pattern error")
+		}
+	})
+	t.Run("Files", func(t *testing.T) {
+		ns, err := l.Glob("**/os-release")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs, err := l.Files(ns...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(fs), 2; got != want {
+			t.Errorf("got: %d files, want: %d", got, want)
+		}
+	})
+}
+
+func TestLayerIndex(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := tar.NewWriter(f)
+	for _, e := range []struct {
+		Name, Body, Link string
+	}{
+		{Name: "./etc/os-release", Body: "ID=test\n"},
+		{Name: "etc/big", Body: strings.Repeat("x", 2000)},
+		{Name: "etc/os-release", Body: "ID=duplicate\n"},
+		{Name: "etc/issue", Link: "os-release"},
+		{Name: "loop/a", Link: "b"},
+		{Name: "loop/b", Link: "a"},
+	} {
+		h := tar.Header{Name: e.Name, Typeflag: tar.TypeReg, Size: int64(len(e.Body))}
+		if e.Link != "" {
+			h.Typeflag, h.Linkname = tar.TypeSymlink, e.Link
+		}
+		if err := w.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, e.Body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var l Layer
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := l.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"etc/big", "etc/issue", "etc/os-release", "loop/a", "loop/b"}
+	if got := idx.Names(); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if again, _ := l.Index(); again != idx {
+		t.Error("index rebuilt")
+	}
+
+	ra, err := l.ReaderAt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ra.Close()
+	for n, want := range map[string]string{
+		"/etc/os-release": "ID=test\n",
+		"etc/big":         strings.Repeat("x", 2000),
+	} {
+		e, ok := idx.Lookup(n)
+		if !ok {
+			t.Fatalf("%s: not found", n)
+		}
+		b := make([]byte, e.Size)
+		if _, err := ra.ReadAt(b, e.Offset); err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != want {
+			t.Errorf("%s: got: %q, want: %q", n, got, want)
+		}
+	}
+	if e, _ := idx.Lookup("etc/issue"); e.Typeflag != tar.TypeSymlink || e.Linkname != "os-release" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+
+	fs, err := l.Files("etc/issue", "loop/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fs["etc/issue"].String(), "ID=test\n"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if _, ok := fs["loop/a"]; ok {
+		t.Error("symlink loop resolved")
+	}
+
+	fs, err = l.Files("etc/*release", "**/nothing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(fs), 1; got != want {
+		t.Errorf("got: %d files, want: %d", got, want)
+	}
+
+	hs, err := l.FilesWithHeaders("etc/issue", "etc/big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, want := range map[string]int64{"etc/issue": 8, "etc/big": 2000} {
+		f, ok := hs[n]
+		switch {
+		case !ok:
+			t.Errorf("%s: not found", n)
+		case f.Header == nil:
+			t.Errorf("%s: missing header", n)
+		case f.Header.Typeflag != tar.TypeReg || f.Header.Size != want:
+			t.Errorf("%s: unexpected header: %+v", n, f.Header)
+		case int64(f.Contents.Len()) != want:
+			t.Errorf("%s: got: %d bytes, want: %d", n, f.Contents.Len(), want)
+		}
+	}
+}
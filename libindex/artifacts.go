@@ -0,0 +1,89 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// LayerArtifacts is what the configured scanners found in one layer of a
+// manifest.
+type LayerArtifacts struct {
+	Layer claircore.Digest `json:"layer"`
+	// Scanners has an entry for each configured scanner that has scanned the
+	// layer, in the order the scanners are configured.
+	Scanners []ScannerArtifacts `json:"scanners"`
+}
+
+// ScannerArtifacts is what one scanner found in a layer.
+//
+// These are the artifacts as stored, before coalescing: a package found in
+// several layers is reported for each of them, and the packages a later layer
+// removes are still reported for the earlier one.
+type ScannerArtifacts struct {
+	Scanner       indexer.ScannerInfo       `json:"scanner"`
+	Packages      []*claircore.Package      `json:"packages,omitempty"`
+	Distributions []*claircore.Distribution `json:"distributions,omitempty"`
+	Repositories  []*claircore.Repository   `json:"repositories,omitempty"`
+}
+
+// ErrLayersUnsupported is returned by LayerArtifacts if the configured store
+// can't report the layers of a manifest.
+var ErrLayersUnsupported = errors.New("libindex: store does not support listing manifest layers")
+
+// LayerArtifacts reports the artifacts each configured scanner found in each
+// layer of the stored manifest, in layer order. It reports false if the
+// manifest isn't known.
+//
+// This is meant for debugging and for showing what each layer contributed to
+// an IndexReport.
+func (l *Libindex) LayerArtifacts(ctx context.Context, manifest claircore.Digest) ([]LayerArtifacts, bool, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.LayerArtifacts"),
+		label.Stringer("manifest", manifest))
+	f, ok := l.store.(indexer.LayerLister)
+	if !ok {
+		return nil, false, ErrLayersUnsupported
+	}
+	layers, ok, err := f.ManifestLayers(ctx, manifest)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	out := make([]LayerArtifacts, len(layers))
+	for i, h := range layers {
+		out[i].Layer = h
+		for _, s := range l.Opts.vscnrs {
+			ok, err := l.store.LayerScanned(ctx, h, s)
+			if err != nil {
+				return nil, false, fmt.Errorf("libindex: unable to check layer %v: %w", h, err)
+			}
+			if !ok {
+				continue
+			}
+			a := ScannerArtifacts{
+				Scanner: indexer.ScannerInfo{Name: s.Name(), Version: s.Version(), Kind: s.Kind()},
+			}
+			vs := indexer.VersionedScanners{s}
+			switch s.(type) {
+			case indexer.PackageScanner:
+				a.Packages, err = l.store.PackagesByLayer(ctx, h, vs)
+			case indexer.DistributionScanner:
+				a.Distributions, err = l.store.DistributionsByLayer(ctx, h, vs)
+			case indexer.RepositoryScanner:
+				a.Repositories, err = l.store.RepositoriesByLayer(ctx, h, vs)
+			}
+			if err != nil {
+				return nil, false, fmt.Errorf("libindex: unable to retrieve artifacts for layer %v: %w", h, err)
+			}
+			out[i].Scanners = append(out[i].Scanners, a)
+		}
+	}
+	return out, true, nil
+}
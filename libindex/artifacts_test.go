@@ -0,0 +1,88 @@
+package libindex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/memory"
+)
+
+type staticDistScanner struct{}
+
+func (staticDistScanner) Name() string    { return "static-dist" }
+func (staticDistScanner) Version() string { return "1" }
+func (staticDistScanner) Kind() string    { return "distribution" }
+func (staticDistScanner) Scan(context.Context, *claircore.Layer) ([]*claircore.Distribution, error) {
+	return nil, nil
+}
+
+func TestLayerArtifacts(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	store := memory.NewStore()
+	vs := indexer.VersionedScanners{staticScanner{}, staticDistScanner{}}
+	l := &Libindex{store: store, Opts: &Opts{vscnrs: vs}}
+	if err := store.RegisterScanners(ctx, vs); err != nil {
+		t.Fatal(err)
+	}
+
+	m := claircore.Manifest{
+		Hash: digest("manifest"),
+		Layers: []*claircore.Layer{
+			{Hash: digest("layer 0")},
+			{Hash: digest("layer 1")},
+		},
+	}
+	if err := store.PersistManifest(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	// Only the first layer has been scanned by the package scanner, and
+	// both by the distribution scanner.
+	pkg := &claircore.Package{Name: "static", Version: "1.0", Kind: claircore.BINARY, PackageDB: "static"}
+	if err := store.IndexPackages(ctx, []*claircore.Package{pkg}, m.Layers[0], staticScanner{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetLayerScanned(ctx, m.Layers[0].Hash, staticScanner{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range m.Layers {
+		if err := store.SetLayerScanned(ctx, l.Hash, staticDistScanner{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, ok, err := l.LayerArtifacts(ctx, m.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("manifest not found")
+	}
+	if len(got) != 2 {
+		t.Fatalf("got: %d layers, want: 2", len(got))
+	}
+	for i, la := range got {
+		if got, want := la.Layer.String(), m.Layers[i].Hash.String(); got != want {
+			t.Errorf("layer %d: got: %s, want: %s", i, got, want)
+		}
+	}
+	if got, want := len(got[0].Scanners), 2; got != want {
+		t.Fatalf("layer 0: got: %d scanners, want: %d", got, want)
+	}
+	if got, want := got[0].Scanners[0].Scanner.Name, "static"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if ps := got[0].Scanners[0].Packages; len(ps) != 1 || ps[0].Name != "static" {
+		t.Errorf("unexpected packages: %v", ps)
+	}
+	if got, want := len(got[1].Scanners), 1; got != want {
+		t.Errorf("layer 1: got: %d scanners, want: %d", got, want)
+	}
+
+	if _, ok, err := l.LayerArtifacts(ctx, digest("missing")); err != nil || ok {
+		t.Errorf("missing manifest: got: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,259 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// ManifestResolver returns the Manifest with the digest, with layer URIs that
+// can be fetched, so that it can be indexed again. The stored results only
+// record digests.
+//
+// If the manifest is no longer available, the resolver should return an error
+// wrapping ErrManifestGone so it's skipped rather than counted as a failure.
+type ManifestResolver func(context.Context, claircore.Digest) (*claircore.Manifest, error)
+
+// ErrManifestGone is returned by a ManifestResolver for a manifest that no
+// longer exists.
+var ErrManifestGone = errors.New("libindex: manifest no longer available")
+
+// BackfillConfig configures a Backfill.
+//
+// The zero value re-indexes one manifest at a time, as fast as possible.
+type BackfillConfig struct {
+	// Rate is the sustained number of manifests re-indexed per second. A
+	// value of zero or less means re-indexing is not rate limited.
+	Rate float64
+	// Burst is the number of manifests allowed to start at once before the
+	// Rate takes effect. If Rate is set and Burst is less than 1, a Burst of 1
+	// is used.
+	Burst int
+	// Concurrency is the maximum number of manifests re-indexed at once. If
+	// less than 1, one is used.
+	Concurrency int
+	// Less, if set, orders the stale manifests: manifests that sort first are
+	// re-indexed first. By default, manifests missing results from more
+	// scanners go first.
+	Less func(a, b *indexer.StaleManifest) bool
+	// OnProgress, if set, is called after every manifest is handled. Calls
+	// aren't concurrent, and may use the Backfill's methods.
+	OnProgress func(context.Context, BackfillProgress)
+}
+
+// BackfillProgress reports how far along a Backfill is.
+type BackfillProgress struct {
+	// Total is the number of stale manifests found.
+	Total int `json:"total"`
+	// Done is the number of manifests successfully re-indexed.
+	Done int `json:"done"`
+	// Failed is the number of manifests that couldn't be re-indexed.
+	Failed int `json:"failed"`
+	// Skipped is the number of manifests the ManifestResolver reported as
+	// gone.
+	Skipped int `json:"skipped"`
+	// Paused reports whether the Backfill is paused.
+	Paused bool `json:"paused"`
+}
+
+// Remaining reports the number of manifests not yet handled.
+func (p BackfillProgress) Remaining() int {
+	return p.Total - p.Done - p.Failed - p.Skipped
+}
+
+// Backfill re-indexes stale manifests, such as after a scanner is upgraded, at
+// a bounded rate so that the churn doesn't crowd out regular indexing.
+//
+// A Backfill can be paused and resumed while it runs, from any goroutine.
+// Failing to re-index a manifest is logged and counted, and doesn't stop the
+// Backfill.
+type Backfill struct {
+	cfg     BackfillConfig
+	resolve ManifestResolver
+	stale   func(context.Context) ([]indexer.StaleManifest, error)
+	index   func(context.Context, *claircore.Manifest) (*claircore.IndexReport, error)
+
+	cbMu   sync.Mutex // serializes calls to OnProgress
+	mu     sync.Mutex
+	prog   BackfillProgress
+	paused chan struct{} // non-nil while paused; closed on resume
+}
+
+// Backfill returns a Backfill that re-indexes this instance's stale manifests,
+// as reported by StaleManifests, using the ManifestResolver to find them. A
+// nil config uses the defaults.
+func (l *Libindex) Backfill(resolve ManifestResolver, cfg *BackfillConfig) *Backfill {
+	b := &Backfill{
+		resolve: resolve,
+		stale:   l.StaleManifests,
+		index:   l.Index,
+	}
+	if cfg != nil {
+		b.cfg = *cfg
+	}
+	if b.cfg.Concurrency < 1 {
+		b.cfg.Concurrency = 1
+	}
+	if b.cfg.Less == nil {
+		b.cfg.Less = lessStale
+	}
+	return b
+}
+
+// LessStale orders manifests with more stale scanners first, then by digest.
+func lessStale(a, b *indexer.StaleManifest) bool {
+	an, bn := len(a.Missing)+len(a.Outdated), len(b.Missing)+len(b.Outdated)
+	if an != bn {
+		return an > bn
+	}
+	return a.Manifest.String() < b.Manifest.String()
+}
+
+// Run finds the stale manifests and re-indexes them, returning once all have
+// been handled or the Context is canceled.
+//
+// An error is only returned if the stale manifests couldn't be found or the
+// Context is canceled; see Progress for the outcome.
+func (b *Backfill) Run(ctx context.Context) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Backfill.Run"))
+	ms, err := b.stale(ctx)
+	if err != nil {
+		return fmt.Errorf("libindex: unable to find stale manifests: %w", err)
+	}
+	sort.SliceStable(ms, func(i, j int) bool { return b.cfg.Less(&ms[i], &ms[j]) })
+	b.mu.Lock()
+	b.prog = BackfillProgress{Total: len(ms), Paused: b.paused != nil}
+	b.mu.Unlock()
+	zlog.Info(ctx).
+		Int("count", len(ms)).
+		Msg("starting backfill")
+
+	lim := rate.NewLimiter(rate.Inf, 0)
+	if b.cfg.Rate > 0 {
+		burst := b.cfg.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		lim = rate.NewLimiter(rate.Limit(b.cfg.Rate), burst)
+	}
+	sem := semaphore.NewWeighted(int64(b.cfg.Concurrency))
+	g, gctx := errgroup.WithContext(ctx)
+	for i := range ms {
+		// These only fail if the Context is canceled, which is checked below.
+		// The pause is checked once a worker is free, so that a Pause during
+		// the previous manifest takes effect.
+		if sem.Acquire(gctx, 1) != nil || b.wait(gctx) != nil || lim.Wait(gctx) != nil {
+			break
+		}
+		m := &ms[i]
+		g.Go(func() error {
+			defer sem.Release(1)
+			b.reindex(gctx, m)
+			return nil
+		})
+	}
+	g.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p := b.Progress()
+	zlog.Info(ctx).
+		Int("done", p.Done).
+		Int("failed", p.Failed).
+		Int("skipped", p.Skipped).
+		Msg("backfill done")
+	return nil
+}
+
+// Reindex handles one manifest, recording the outcome.
+func (b *Backfill) reindex(ctx context.Context, sm *indexer.StaleManifest) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.Stringer("manifest", sm.Manifest))
+	m, err := b.resolve(ctx, sm.Manifest)
+	if err == nil {
+		_, err = b.index(ctx, m)
+	}
+	if ctx.Err() != nil {
+		// Canceled; don't record the manifest as handled.
+		return
+	}
+
+	b.cbMu.Lock()
+	defer b.cbMu.Unlock()
+	b.mu.Lock()
+	switch {
+	case err == nil:
+		b.prog.Done++
+		zlog.Debug(ctx).Msg("re-indexed manifest")
+	case errors.Is(err, ErrManifestGone):
+		b.prog.Skipped++
+		zlog.Debug(ctx).Err(err).Msg("skipping manifest")
+	default:
+		b.prog.Failed++
+		zlog.Warn(ctx).Err(err).Msg("unable to re-index manifest")
+	}
+	p := b.prog
+	b.mu.Unlock()
+	if b.cfg.OnProgress != nil {
+		b.cfg.OnProgress(ctx, p)
+	}
+}
+
+// Wait blocks while the Backfill is paused.
+func (b *Backfill) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		ch := b.paused
+		b.mu.Unlock()
+		if ch == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+// Pause stops the Backfill from starting to re-index more manifests.
+// Manifests already being re-indexed are finished.
+func (b *Backfill) Pause() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.paused == nil {
+		b.paused = make(chan struct{})
+		b.prog.Paused = true
+	}
+}
+
+// Resume undoes Pause.
+func (b *Backfill) Resume() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.paused != nil {
+		close(b.paused)
+		b.paused = nil
+		b.prog.Paused = false
+	}
+}
+
+// Progress reports the progress of the current or most recent Run.
+func (b *Backfill) Progress() BackfillProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.prog
+}
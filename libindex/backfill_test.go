@@ -0,0 +1,104 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+func TestBackfill(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	missing := []indexer.ScannerInfo{{Name: "a"}, {Name: "b"}}
+	stale := []indexer.StaleManifest{
+		{Manifest: digest("one"), Missing: missing[:1]},
+		{Manifest: digest("two"), Missing: missing},
+		{Manifest: digest("gone"), Missing: missing[:1]},
+		{Manifest: digest("fail"), Missing: missing[:1]},
+	}
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	var b *Backfill
+	l := &Libindex{}
+	b = l.Backfill(func(_ context.Context, d claircore.Digest) (*claircore.Manifest, error) {
+		switch d.String() {
+		case digest("gone").String():
+			return nil, fmt.Errorf("not found: %w", ErrManifestGone)
+		}
+		return &claircore.Manifest{Hash: d}, nil
+	}, &BackfillConfig{
+		Rate: 1000,
+		OnProgress: func(_ context.Context, p BackfillProgress) {
+			// Pause after the first manifest, to check that nothing more is
+			// started until resumed.
+			if p.Remaining() == len(stale)-1 {
+				b.Pause()
+				go func() {
+					mu.Lock()
+					n := len(order)
+					mu.Unlock()
+					if n != 1 {
+						t.Errorf("got: %d manifests indexed while paused, want: 1", n)
+					}
+					b.Resume()
+				}()
+			}
+		},
+	})
+	b.stale = func(context.Context) ([]indexer.StaleManifest, error) {
+		return append([]indexer.StaleManifest(nil), stale...), nil
+	}
+	b.index = func(_ context.Context, m *claircore.Manifest) (*claircore.IndexReport, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, m.Hash.String())
+		if m.Hash.String() == digest("fail").String() {
+			return nil, errors.New("failed")
+		}
+		return &claircore.IndexReport{Hash: m.Hash}, nil
+	}
+
+	if err := b.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got := b.Progress()
+	want := BackfillProgress{Total: 4, Done: 2, Failed: 1, Skipped: 1}
+	if got != want {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+	// The manifest missing the most scanners goes first.
+	if len(order) == 0 || order[0] != digest("two").String() {
+		t.Errorf("unexpected order: %v", order)
+	}
+}
+
+func TestBackfillCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(zlog.Test(context.Background(), t))
+	l := &Libindex{}
+	b := l.Backfill(func(_ context.Context, d claircore.Digest) (*claircore.Manifest, error) {
+		return &claircore.Manifest{Hash: d}, nil
+	}, nil)
+	b.stale = func(context.Context) ([]indexer.StaleManifest, error) {
+		return []indexer.StaleManifest{{Manifest: digest("one")}}, nil
+	}
+	b.index = func(context.Context, *claircore.Manifest) (*claircore.IndexReport, error) {
+		t.Error("unexpected index")
+		return nil, nil
+	}
+	b.Pause()
+	cancel()
+	if err := b.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("got: %v, want: %v", err, context.Canceled)
+	}
+	if got := b.Progress(); !got.Paused || got.Remaining() != 1 {
+		t.Errorf("unexpected progress: %+v", got)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/quay/claircore/internal/indexer"
 	"github.com/quay/claircore/internal/indexer/controller"
 	"github.com/quay/claircore/internal/indexer/layerscanner"
+	"github.com/quay/claircore/internal/indexer/memory"
+	"github.com/quay/claircore/pkg/referrers"
 )
 
 // ControllerFactory is a factory method to return a Controller during libindex runtime.
@@ -13,14 +15,29 @@ type ControllerFactory func(_ context.Context, lib *Libindex, opts *Opts) (*cont
 
 // controllerFactory is the default ControllerFactory
 func controllerFactory(ctx context.Context, lib *Libindex, opts *Opts) (*controller.Controller, error) {
+	store := lib.store
+	if opts.Ephemeral {
+		// Use a throwaway store, so nothing is retained between calls.
+		ms := memory.NewStore()
+		if err := ms.RegisterScanners(ctx, lib.vscnrs); err != nil {
+			return nil, err
+		}
+		store = ms
+	}
 	// convert libindex.Opts to indexer.Opts
 	sOpts := &indexer.Opts{
-		Store:         lib.store,
-		Fetcher:       lib.fetchArena.Fetcher(),
-		Ecosystems:    opts.Ecosystems,
-		Vscnrs:        lib.vscnrs,
-		Client:        lib.client,
-		ScannerConfig: opts.ScannerConfig,
+		Store:           store,
+		Fetcher:         lib.fetchArena.Fetcher(),
+		Ecosystems:      opts.Ecosystems,
+		Vscnrs:          lib.vscnrs,
+		Client:          lib.client,
+		ScannerConfig:   opts.ScannerConfig,
+		ForeignURLs:     opts.ForeignURLs,
+		ContentScanners: opts.ContentScanners,
+		Heuristics:      opts.Heuristics,
+	}
+	if opts.Attachments != nil {
+		sOpts.Attachments = referrers.NewFinder(lib.client, opts.Attachments)
 	}
 	var err error
 	sOpts.LayerScanner, err = layerscanner.New(ctx, opts.LayerScanConcurrency, sOpts)
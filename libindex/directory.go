@@ -0,0 +1,200 @@
+package libindex
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/vmimage"
+)
+
+// IndexDirectory indexes the contents of a local directory tree, such as an
+// unpacked root filesystem or a mounted VM image, as if it were a container
+// image consisting of a single layer.
+//
+// The directory is archived into a temporary file for the duration of the
+// call. File ownership and modification times are not recorded, so the
+// synthetic layer's digest, which is also used as the manifest digest, only
+// changes when the contents of the directory do. Files that can't be read due
+// to permissions are skipped.
+func (l *Libindex) IndexDirectory(ctx context.Context, dir string) (*claircore.IndexReport, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.IndexDirectory"),
+		label.String("dir", dir))
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("libindex: %q is not a directory", dir)
+	}
+
+	u := url.URL{Scheme: "file", Path: dir}
+	return l.indexArchive(ctx, u.String(), func(w io.Writer) error {
+		return archiveDir(ctx, w, dir)
+	})
+}
+
+// IndexDiskImage indexes the contents of a virtual machine disk image, as if
+// it were a container image consisting of a single layer.
+//
+// Raw and qcow2 images are supported, containing filesystems readable by the
+// vmimage package. See vmimage.WriteLayer for how the image's filesystems are
+// assembled. As with IndexDirectory, the synthetic layer's digest is used as
+// the manifest digest.
+func (l *Libindex) IndexDiskImage(ctx context.Context, name string) (*claircore.IndexReport, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.IndexDiskImage"),
+		label.String("image", name))
+	name, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	img, err := vmimage.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+	u := url.URL{Scheme: "file", Path: name}
+	return l.indexArchive(ctx, u.String(), func(w io.Writer) error {
+		return vmimage.WriteLayer(ctx, w, img)
+	})
+}
+
+// IndexArchive writes a layer using the provided function into a temporary
+// file, then indexes it as the sole layer of a manifest.
+func (l *Libindex) indexArchive(ctx context.Context, uri string, write func(io.Writer) error) (*claircore.IndexReport, error) {
+	f, err := os.CreateTemp("", "claircore-layer.")
+	if err != nil {
+		return nil, fmt.Errorf("libindex: unable to create layer file: %w", err)
+	}
+	defer func() {
+		if err := os.Remove(f.Name()); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to remove layer file")
+		}
+	}()
+	defer f.Close()
+
+	h := sha256.New()
+	if err := write(io.MultiWriter(f, h)); err != nil {
+		return nil, fmt.Errorf("libindex: unable to archive %q: %w", uri, err)
+	}
+	d, err := claircore.NewDigest("sha256", h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	layer := &claircore.Layer{
+		Hash: d,
+		URI:  uri,
+	}
+	if err := layer.SetLocal(f.Name()); err != nil {
+		return nil, err
+	}
+	zlog.Debug(ctx).
+		Stringer("digest", d).
+		Msg("archived layer")
+	return l.Index(ctx, &claircore.Manifest{
+		Hash:   d,
+		Layers: []*claircore.Layer{layer},
+	})
+}
+
+// ArchiveDir writes a tar of the directory "root" to "w".
+//
+// Only regular files, directories, and symlinks are included. Ownership and
+// timestamps are omitted.
+func archiveDir(ctx context.Context, w io.Writer, root string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsPermission(err) {
+				zlog.Debug(ctx).Str("path", p).Err(err).Msg("skipping unreadable path")
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		switch m := fi.Mode(); {
+		case m.IsRegular(), m.IsDir():
+		case m&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		default:
+			// Devices, sockets, and the like have no content worth scanning.
+			return nil
+		}
+		var rd io.ReadCloser
+		if fi.Mode().IsRegular() {
+			rd, err = os.Open(p)
+			switch {
+			case err == nil:
+				defer rd.Close()
+			case os.IsPermission(err):
+				zlog.Debug(ctx).Str("path", p).Err(err).Msg("skipping unreadable file")
+				return nil
+			default:
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "", ""
+		hdr.ModTime = time.Unix(0, 0)
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if rd != nil {
+			if _, err := io.Copy(tw, rd); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
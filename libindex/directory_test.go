@@ -0,0 +1,65 @@
+package libindex
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/heuristics"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+func TestIndexDirectory(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+
+	root := t.TempDir()
+	bin := filepath.Join(root, "usr", "bin")
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "rpm"), []byte("#!/bin/false\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("rpm", filepath.Join(bin, "yum")); err != nil {
+		t.Fatal(err)
+	}
+
+	lib, err := New(ctx, &Opts{
+		Ephemeral:  true,
+		Ecosystems: []*indexer.Ecosystem{},
+		Heuristics: true,
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close(ctx)
+
+	ir, err := lib.IndexDirectory(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ir.Success {
+		t.Fatalf("index failed: %s", ir.Err)
+	}
+	if got, want := len(ir.Warnings), 1; got != want {
+		t.Fatalf("got: %d warnings, want: %d", got, want)
+	}
+	if got, want := ir.Warnings[0].Kind, heuristics.WarnDatabaseMissing; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	// The digest should only depend on the contents.
+	again, err := lib.IndexDirectory(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := again.Hash.String(), ir.Hash.String(); got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+}
@@ -0,0 +1,30 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// LayerArtifacts aggregates the artifacts found within a layer.
+type LayerArtifacts struct {
+	Hash  claircore.Digest
+	Pkgs  []*claircore.Package
+	Dist  []*claircore.Distribution // each layer can only have a single distribution
+	Repos []*claircore.Repository
+	// Removed is the paths the layer's whiteouts remove from lower layers,
+	// relative to the layer root. It's read from the layer while coalescing
+	// and isn't stored.
+	Removed []string
+}
+
+// Coalescer takes a set of layers and creates coalesced IndexReport.
+//
+// A coalesced IndexReport should provide only the packages present in the
+// final container image once all layers were applied.
+//
+// Identical artifacts are shared between layers, so a Coalescer must not
+// modify them.
+type Coalescer interface {
+	Coalesce(ctx context.Context, artifacts []*LayerArtifacts) (*claircore.IndexReport, error)
+}
@@ -0,0 +1,15 @@
+// Package driver defines the interfaces implemented by indexer components:
+// the scanners that find packages, distributions, and repositories in a
+// layer, the Coalescers that combine their results into an IndexReport, and
+// the Ecosystems that group them. It also defines the Transport that layers
+// are fetched with and the Middleware that can be composed around it.
+//
+// Out-of-tree scanners should implement these interfaces and be passed to
+// libindex through an Ecosystem in its Opts.
+//
+// The interfaces in this package are covered by the module's compatibility
+// guarantees: methods will not be added to or removed from them, so
+// implementations outside this module keep compiling. New capabilities are
+// added as new, optional interfaces that a scanner may additionally
+// implement, as RPCScanner, ConfigurableScanner, and FileLimiter are.
+package driver
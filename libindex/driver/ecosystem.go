@@ -0,0 +1,31 @@
+package driver
+
+import "context"
+
+// Ecosystems group together scanners and a Coalescer which are commonly used together.
+//
+// A typical ecosystem is "DPKG" which will use the DPKG package indexer, the "OS-Release"
+// distribution scanner and the "APT" repository scanner.
+//
+// A Controller will scan layers with all scanners present in its configured ecosystems.
+//
+// An Ecosystem may also name the updater sets and matchers that provide
+// vulnerability coverage for what it indexes. Passing the same Ecosystem to
+// libvuln enables those, so that complete coverage for an ecosystem is a
+// single option.
+type Ecosystem struct {
+	Name                 string
+	PackageScanners      func(ctx context.Context) ([]PackageScanner, error)
+	DistributionScanners func(ctx context.Context) ([]DistributionScanner, error)
+	RepositoryScanners   func(ctx context.Context) ([]RepositoryScanner, error)
+	Coalescer            func(ctx context.Context) (Coalescer, error)
+	// UpdaterSets names the registered updater sets providing vulnerability
+	// data for the ecosystem.
+	UpdaterSets []string
+	// Matchers names the registered matchers for the ecosystem.
+	Matchers []string
+	// Fallback marks an ecosystem whose IndexReport is only used if no other
+	// ecosystem reports a distribution, such as one with a generic
+	// distribution scanner.
+	Fallback bool
+}
@@ -0,0 +1,46 @@
+package driver_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex/driver"
+)
+
+// OSTreeScanner is an out-of-tree DistributionScanner.
+type osTreeScanner struct{}
+
+var _ driver.DistributionScanner = (*osTreeScanner)(nil)
+
+func (*osTreeScanner) Name() string    { return "ostree" }
+func (*osTreeScanner) Version() string { return "1" }
+func (*osTreeScanner) Kind() string    { return "distribution" }
+
+func (*osTreeScanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Distribution, error) {
+	return nil, nil
+}
+
+// Scanners implemented outside claircore are added to libindex through an
+// Ecosystem in its Opts.
+func ExampleEcosystem() {
+	ctx := context.Background()
+	e := &driver.Ecosystem{
+		Name: "ostree",
+		PackageScanners: func(context.Context) ([]driver.PackageScanner, error) {
+			return nil, nil
+		},
+		DistributionScanners: func(context.Context) ([]driver.DistributionScanner, error) {
+			return []driver.DistributionScanner{&osTreeScanner{}}, nil
+		},
+		RepositoryScanners: func(context.Context) ([]driver.RepositoryScanner, error) {
+			return nil, nil
+		},
+	}
+	ds, _ := e.DistributionScanners(ctx)
+	for _, s := range ds {
+		fmt.Println(s.Name(), s.Kind())
+	}
+	// Output:
+	// ostree distribution
+}
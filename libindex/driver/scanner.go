@@ -0,0 +1,112 @@
+package driver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/quay/claircore"
+)
+
+// Package is the Kind reported by PackageScanners.
+const Package = "package"
+
+// Content is the Kind reported by ContentScanners.
+const Content = "content"
+
+// VersionedScanner can be embedded into specific scanner types. This allows for
+// methods and functions which only need to compare names and versions of
+// scanners not to require each scanner type as an argument.
+type VersionedScanner interface {
+	// unique name of the distribution scanner.
+	Name() string
+	// version of this scanner. this information will be persisted with the scan.
+	Version() string
+	// the kind of scanner. currently only package is implemented
+	Kind() string
+}
+
+// PackageScanner provides an interface for unique identification or a PackageScanner
+// and a Scan method for extracting installed packages from an individual container layer
+type PackageScanner interface {
+	VersionedScanner
+	// Scan performs a package scan on the given layer and returns all
+	// the found packages
+	Scan(context.Context, *claircore.Layer) ([]*claircore.Package, error)
+}
+
+// DistributionScanner reports the distribution an individual container layer
+// belongs to, if it can tell.
+type DistributionScanner interface {
+	VersionedScanner
+	Scan(context.Context, *claircore.Layer) ([]*claircore.Distribution, error)
+}
+
+// RepositoryScanner reports the package repositories configured or
+// referenced in an individual container layer.
+type RepositoryScanner interface {
+	VersionedScanner
+	Scan(context.Context, *claircore.Layer) ([]*claircore.Repository, error)
+}
+
+// ContentScanner matches the contents of the files in an individual container
+// layer against signatures, such as those for malware or indicators of
+// compromise, and reports what's found.
+//
+// ContentScanners run in the same pass as the other scanners, on every layer
+// of the manifest. Their results aren't stored per layer, so layers are
+// scanned again each time a manifest is indexed.
+type ContentScanner interface {
+	VersionedScanner
+	Scan(context.Context, *claircore.Layer) ([]claircore.Detection, error)
+}
+
+// ConfigDeserializer can be thought of as an Unmarshal function with the byte
+// slice provided.
+//
+// This will typically be something like (*json.Decoder).Decode.
+type ConfigDeserializer func(interface{}) error
+
+// RPCScanner is an interface scanners can implement to receive configuration
+// and denote that they expect to be able to talk to the network at run time.
+type RPCScanner interface {
+	Configure(context.Context, ConfigDeserializer, *http.Client) error
+}
+
+// ConfigurableScanner is an interface scanners can implement to receive
+// configuration.
+type ConfigurableScanner interface {
+	Configure(context.Context, ConfigDeserializer) error
+}
+
+// FileLimiter is an interface scanners can implement to declare the largest
+// file, in bytes, they expect to read from a layer.
+//
+// The layer scanner enforces the limit on the Layer handed to the scanner:
+// larger files are left out of the results of Files, unless Truncate is
+// reported, in which case they're cut short at the limit.
+type FileLimiter interface {
+	FileLimit() (max int64, truncate bool)
+}
+
+// PathDeclarer is an interface scanners can implement to declare the files
+// they read from a layer.
+//
+// Paths returns patterns in the syntax accepted by (*claircore.Layer).Glob:
+// relative to the tar-root, with "**" matching any number of path elements.
+// The list must not change over the life of the scanner and must cover every
+// file the scanner could read, including the names of links it follows.
+//
+// The layer scanner doesn't call Scan on layers containing none of the
+// declared paths, and records the layer as scanned with no results.
+type PathDeclarer interface {
+	Paths() []string
+}
+
+// ConfidenceReporter is an interface PackageScanners can implement to report
+// how certain their findings are.
+//
+// The layer scanner sets the Confidence of every Package the scanner returns
+// that doesn't already have one.
+type ConfidenceReporter interface {
+	Confidence() claircore.Confidence
+}
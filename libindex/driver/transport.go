@@ -0,0 +1,53 @@
+package driver
+
+import (
+	"context"
+	"io"
+
+	"github.com/quay/claircore"
+)
+
+// Blob is a layer's contents as retrieved by a Transport: the archive as it
+// was stored, which may be compressed.
+type Blob struct {
+	io.ReadCloser
+	// MediaType is the media type the source reported for the contents, if
+	// any. If it's empty or a generic type such as
+	// "application/octet-stream", the compression is detected from the
+	// contents.
+	MediaType string
+}
+
+// Transport retrieves the contents of layers.
+//
+// Libindex fetches layers over HTTP by default. Deployments with other
+// sources, such as artifact proxies or peer-to-peer distribution, can provide
+// their own Transport in libindex's Opts.
+//
+// Implementations must not modify the Layer. The caller closes the returned
+// Blob.
+type Transport interface {
+	Fetch(context.Context, *claircore.Layer) (*Blob, error)
+}
+
+// TransportFunc is an ordinary function used as a Transport.
+type TransportFunc func(context.Context, *claircore.Layer) (*Blob, error)
+
+// Fetch implements Transport.
+func (f TransportFunc) Fetch(ctx context.Context, l *claircore.Layer) (*Blob, error) {
+	return f(ctx, l)
+}
+
+// Middleware wraps a Transport to add behavior, such as authentication,
+// caching, rate limiting, or integrity checking.
+type Middleware func(Transport) Transport
+
+// Chain returns the Transport wrapped in the provided Middleware. The first
+// Middleware is outermost, so it sees every Fetch first and the Blob returned
+// last.
+func Chain(t Transport, ms ...Middleware) Transport {
+	for i := len(ms) - 1; i >= 0; i-- {
+		t = ms[i](t)
+	}
+	return t
+}
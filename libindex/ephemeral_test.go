@@ -0,0 +1,80 @@
+package libindex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/linux"
+	"github.com/quay/claircore/test"
+)
+
+type staticScanner struct{}
+
+func (staticScanner) Name() string    { return "static" }
+func (staticScanner) Version() string { return "1" }
+func (staticScanner) Kind() string    { return "package" }
+func (staticScanner) Scan(context.Context, *claircore.Layer) ([]*claircore.Package, error) {
+	return []*claircore.Package{
+		{Name: "static", Version: "1.0", Kind: claircore.BINARY, PackageDB: "static"},
+	}, nil
+}
+
+func TestEphemeral(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	c, layers := test.ServeLayers(t, 2)
+
+	eco := &indexer.Ecosystem{
+		Name: "static",
+		PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+			return []indexer.PackageScanner{staticScanner{}}, nil
+		},
+		DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+		RepositoryScanners:   func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		Coalescer: func(context.Context) (indexer.Coalescer, error) {
+			return linux.NewCoalescer(), nil
+		},
+	}
+	lib, err := New(ctx, &Opts{
+		Ephemeral:  true,
+		Ecosystems: []*indexer.Ecosystem{eco},
+	}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close(ctx)
+
+	m := &claircore.Manifest{
+		Hash:   digest("ephemeral"),
+		Layers: layers,
+	}
+	for i := 0; i < 2; i++ {
+		ir, err := lib.Index(ctx, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ir.Success {
+			t.Fatalf("index failed: %s", ir.Err)
+		}
+		if got, want := len(ir.Packages), 1; got != want {
+			t.Errorf("got: %d packages, want: %d", got, want)
+		}
+	}
+	// Nothing should be retained between calls.
+	if _, ok, err := lib.IndexReport(ctx, m.Hash); err != nil || ok {
+		t.Errorf("unexpected stored report: %v, %v", ok, err)
+	}
+
+	h := lib.Healthz(ctx)
+	if !h.OK() {
+		t.Errorf("unhealthy: %+v", h)
+	}
+	if h.State == "" {
+		t.Error("missing state")
+	}
+}
@@ -2,27 +2,30 @@ package libindex
 
 import (
 	"bufio"
-	"bytes"
 	"context"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
+	"time"
 
-	"github.com/klauspost/compress/gzip"
-	"github.com/klauspost/compress/zstd"
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/zreader"
+	"github.com/quay/claircore/libindex/driver"
+	"github.com/quay/claircore/pkg/blob"
+	"github.com/quay/claircore/pkg/retry"
 )
 
 // FetchArena is a struct that keeps track of all the layers fetched into it,
@@ -30,7 +33,6 @@ import (
 //
 // Exported for use in cctool. If cctool goes away, this can get unexported.
 type FetchArena struct {
-	wc *http.Client
 	sf *singleflight.Group
 
 	mu sync.Mutex
@@ -38,19 +40,115 @@ type FetchArena struct {
 	rc map[string]int
 
 	root string
+	// Tr is the Transport layers are fetched with.
+	tr driver.Transport
+	// Local is set if every fetch may read local layers.
+	local bool
+	// Mw is the additional Middleware configured with Use.
+	mw []driver.Middleware
+	// Lim is the per-host limiter shared by all fetches in the arena.
+	lim *hostLimiter
+	// Cache, if not nil, holds fetched layers for reuse.
+	cache blob.Store
+	// Retry, if not nil, is applied to failed layer fetches.
+	retry *retry.Policy
+	// Bw, if not nil, limits the bytes read by all fetches.
+	bw *rate.Limiter
+	// Workers is the number of layers a FetchProxy fetches at once.
+	workers int
 }
 
-// Init initializes the FetchArena.
+// Init initializes the FetchArena to fetch layers over HTTP using the provided
+// client. Layers with "file" and "containers-storage" URIs are only read from
+// the local filesystem if allowed; see AllowLocal.
 //
 // This method is provided instead of a constructor function to make embedding
 // easier.
 func (a *FetchArena) Init(wc *http.Client, root string) {
-	a.wc = wc
+	a.tr = HTTPTransport(wc)
 	a.root = root
 	a.sf = &singleflight.Group{}
 	a.rc = make(map[string]int)
 }
 
+// SetTransport configures the Transport layers are fetched with, in place of
+// HTTP. If nil, only local layers can be fetched.
+func (a *FetchArena) SetTransport(t driver.Transport) {
+	a.tr = t
+}
+
+// AllowLocal configures whether every fetch may read layers with "file" and
+// "containers-storage" URIs from the local filesystem. Otherwise, only fetches
+// for IndexLayout and IndexContainerStorage may.
+func (a *FetchArena) AllowLocal(ok bool) {
+	a.local = ok
+}
+
+// Use adds Middleware around the Transport. The Middleware is inside the
+// cache and digest verification, and outside the rate limits; see
+// Opts.FetchMiddleware. Use should be called before any fetches are started.
+func (a *FetchArena) Use(ms ...driver.Middleware) {
+	a.mw = append(a.mw, ms...)
+}
+
+// SetLimits configures per-host rate and concurrency limits for all fetches
+// done through the FetchArena.
+//
+// Limiter state is kept for the lifetime of the FetchArena, so limits are
+// enforced across all concurrent Index calls. SetLimits should be called
+// before any fetches are started.
+func (a *FetchArena) SetLimits(l FetchLimits) {
+	a.lim = newHostLimiter(l)
+}
+
+// SetCache configures a Store to hold fetched layers.
+//
+// Layers found in the cache are used instead of being fetched, and newly
+// fetched layers are added to it as they were fetched, after their digests
+// are verified. The cached contents are trusted.
+func (a *FetchArena) SetCache(s blob.Store) {
+	a.cache = s
+}
+
+// SetRetry configures a Policy for retrying layer fetches that fail with
+// transient errors, such as a 503 response or a connection broken partway
+// through the contents. The whole fetch is retried.
+//
+// This is in addition to any retrying done by the Transport itself.
+func (a *FetchArena) SetRetry(p *retry.Policy) {
+	a.retry = p
+}
+
+// SetBandwidth limits the rate at which all fetches done through the
+// FetchArena read layer contents, in bytes per second. A value of zero or
+// less removes the limit. Cached layers aren't limited.
+func (a *FetchArena) SetBandwidth(bps int64) {
+	a.bw = newBandwidthLimiter(bps)
+}
+
+// SetConcurrency configures the number of layers fetched at once by each
+// Fetcher. A value of zero or less means all of a manifest's layers are
+// fetched at once.
+func (a *FetchArena) SetConcurrency(n int) {
+	a.workers = n
+}
+
+// Transport returns the configured Transport wrapped in all the configured
+// Middleware.
+func (a *FetchArena) transport() driver.Transport {
+	ms := make([]driver.Middleware, 0, len(a.mw)+4)
+	if a.cache != nil {
+		ms = append(ms, Cache(a.cache))
+	}
+	ms = append(ms, VerifyDigest)
+	ms = append(ms, a.mw...)
+	ms = append(ms, a.lim.middleware)
+	if a.bw != nil {
+		ms = append(ms, bandwidth(a.bw))
+	}
+	return driver.Chain(&localTransport{next: a.tr, always: a.local}, ms...)
+}
+
 func (a *FetchArena) incRef(digest string) error {
 	a.mu.Lock()
 	a.rc[digest]++
@@ -124,20 +222,6 @@ func (a *FetchArena) realizeLayer(ctx context.Context, l *claircore.Layer) (stri
 		label.String("uri", l.URI))
 	zlog.Debug(ctx).Msg("layer fetch start")
 
-	// Validate the layer input.
-	if l.URI == "" {
-		return "", fmt.Errorf("empty uri for layer %v", l.Hash)
-	}
-	url, err := url.ParseRequestURI(l.URI)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse remote path uri: %v", err)
-	}
-	if l.Hash.Checksum() == nil {
-		return "", fmt.Errorf("digest is empty")
-	}
-	vh := l.Hash.Hash()
-	want := l.Hash.Checksum()
-
 	// Open our target file before hitting the network.
 	name := a.filename(l)
 	rm := true
@@ -158,114 +242,126 @@ func (a *FetchArena) realizeLayer(ctx context.Context, l *claircore.Layer) (stri
 	// It'd be nice to be able to pre-allocate our file on disk, but we can't
 	// because of decompression.
 
-	req := &http.Request{
-		ProtoMajor: 1,
-		ProtoMinor: 1,
-		Method:     http.MethodGet,
-		URL:        url,
-		Header:     l.Headers,
+	for n := 1; ; n++ {
+		err = a.writeLayer(ctx, l, fd)
+		if err == nil || a.retry == nil || n >= a.retry.Attempts() || !temporary(ctx, err) {
+			break
+		}
+		wait := a.retry.Backoff(n)
+		zlog.Info(ctx).
+			Err(err).
+			Int("attempt", n).
+			Dur("wait", wait).
+			Msg("retrying layer fetch")
+		if _, err := fd.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		if err := fd.Truncate(0); err != nil {
+			return "", err
+		}
+		tm := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			tm.Stop()
+			return "", ctx.Err()
+		case <-tm.C:
+		}
 	}
-	req = req.WithContext(ctx)
-	resp, err := a.wc.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("fetcher: request failed: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
-	switch resp.StatusCode {
-	case http.StatusOK:
-	default:
-		// Especially for 4xx errors, the response body may indicate what's going
-		// on, so include some of it in the error message. Capped at 256 bytes in
-		// order to not flood the log.
-		bodyStart, err := io.ReadAll(io.LimitReader(resp.Body, 256))
-		if err == nil {
-			return "", fmt.Errorf("fetcher: unexpected status code: %s (body starts: %q)",
-				resp.Status, bodyStart)
-		}
-		return "", fmt.Errorf("fetcher: unexpected status code: %s", resp.Status)
+
+	zlog.Debug(ctx).Msg("layer fetch ok")
+	rm = false
+	return name, nil
+}
+
+// WriteLayer fetches the layer and writes its decompressed contents to "fd".
+func (a *FetchArena) writeLayer(ctx context.Context, l *claircore.Layer, fd *os.File) error {
+	b, err := a.transport().Fetch(ctx, l)
+	if err != nil {
+		return err
 	}
-	tr := io.TeeReader(resp.Body, vh)
+	defer b.Close()
 
-	br := bufio.NewReader(tr)
-	// Look at the content-type and optionally fix it up.
-	ct := resp.Header.Get("content-type")
+	br := bufio.NewReader(b)
+	// The contents are trusted over the reported content-type: some
+	// registries report a generic type, and some report a compressed type for
+	// uncompressed layers or vice versa.
+	ct := b.MediaType
+	c, err := zreader.Sniff(br)
+	if err != nil {
+		return err
+	}
 	zlog.Debug(ctx).
 		Str("content-type", ct).
+		Stringer("detected", c).
 		Msg("reported content-type")
-	if ct == "" || ct == "text/plain" || ct == "binary/octet-stream" || ct == "application/octet-stream" {
-		zlog.Debug(ctx).
+	switch reported := zreader.FromMediaType(ct); {
+	case c == zreader.Unknown && reported != zreader.Unknown:
+		c = reported
+	case c == zreader.Unknown && isGenericType(ct):
+		// Assume anything unrecognizable is an uncompressed tar without
+		// the magic in its header, as before content detection.
+		c = zreader.None
+	case c == zreader.Unknown:
+		return permanentError{fmt.Errorf("fetcher: unknown content-type %q", ct)}
+	case reported != zreader.Unknown && reported != c:
+		zlog.Info(ctx).
 			Str("content-type", ct).
-			Msg("guessing compression")
-		b, err := br.Peek(4)
-		if err != nil {
-			return "", err
-		}
-		switch detectCompression(b) {
-		case cmpGzip:
-			ct = "application/gzip"
-		case cmpZstd:
-			ct = "application/zstd"
-		case cmpNone:
-			ct = "application/x-tar"
-		}
-		zlog.Debug(ctx).
-			Str("format", ct).
-			Msg("guessed compression")
+			Stringer("detected", c).
+			Msg("layer compression differs from content-type")
 	}
-
-	var r io.Reader
-	switch {
-	case ct == "application/vnd.docker.image.rootfs.diff.tar.gzip":
-		// Catch the old docker media type.
-		fallthrough
-	case ct == "application/gzip" || ct == "application/x-gzip":
-		// GHCR reports gzipped layers as the latter.
-		fallthrough
-	case strings.HasSuffix(ct, ".tar+gzip"):
-		g, err := gzip.NewReader(br)
-		if err != nil {
-			return "", err
-		}
-		defer g.Close()
-		r = g
-	case ct == "application/zstd":
-		fallthrough
-	case strings.HasSuffix(ct, ".tar+zstd"):
-		s, err := zstd.NewReader(br)
-		if err != nil {
-			return "", err
-		}
-		defer s.Close()
-		r = s
-	case ct == "application/x-tar":
-		fallthrough
-	case strings.HasSuffix(ct, ".tar"):
-		r = br
-	default:
-		return "", fmt.Errorf("fetcher: unknown content-type %q", ct)
+	r, err := zreader.Reader(br, c)
+	if err != nil {
+		return err
 	}
+	defer r.Close()
 
 	buf := bufio.NewWriter(fd)
 	n, err := io.Copy(buf, r)
 	zlog.Debug(ctx).Int64("size", n).Msg("wrote file")
 	if err != nil {
-		return "", err
+		return err
 	}
 	if err := buf.Flush(); err != nil {
-		return "", err
+		return err
 	}
-	if got := vh.Sum(nil); !bytes.Equal(got, want) {
-		err := fmt.Errorf("fetcher: validation failed: got %q, expected %q",
-			hex.EncodeToString(got),
-			hex.EncodeToString(want))
-		return "", err
+	// Read anything the decompressor left, so the digest is checked.
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Temporary reports whether a failed fetch may succeed if tried again.
+//
+// Errors are assumed to be temporary, such as a connection breaking partway
+// through, unless they're a missing file or marked as permanent.
+func temporary(ctx context.Context, err error) bool {
+	var p permanent
+	switch {
+	case ctx.Err() != nil:
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission):
+	case errors.As(err, &p):
+	default:
+		return true
 	}
+	return false
+}
 
-	zlog.Debug(ctx).Msg("layer fetch ok")
-	rm = false
-	return name, nil
+// Permanent is implemented by errors that retrying won't fix.
+type permanent interface {
+	error
+	permanent()
 }
 
+// PermanentError marks an error as permanent.
+type permanentError struct{ error }
+
+func (permanentError) permanent()      {}
+func (e permanentError) Unwrap() error { return e.error }
+
 // Fetcher returns an indexer.Fetcher.
 func (a *FetchArena) Fetcher() *FetchProxy {
 	return &FetchProxy{a: a}
@@ -283,11 +379,27 @@ type FetchProxy struct {
 // Fetch populates all the layers locally.
 func (p *FetchProxy) Fetch(ctx context.Context, ls []*claircore.Layer) error {
 	g, ctx := errgroup.WithContext(ctx)
+	n := p.a.workers
+	if n <= 0 || n > len(ls) {
+		n = len(ls)
+	}
+	sem := semaphore.NewWeighted(int64(n))
+	var err error
 	for _, l := range ls {
-		g.Go(p.fetchOne(ctx, l))
+		if err = sem.Acquire(ctx, 1); err != nil {
+			break
+		}
+		f := p.fetchOne(ctx, l)
+		g.Go(func() error {
+			defer sem.Release(1)
+			return f()
+		})
+	}
+	if werr := g.Wait(); werr != nil {
+		err = werr
 	}
-	if err := g.Wait(); err != nil {
-		return fmt.Errorf("encountered error while fetching a layer: %v", err)
+	if err != nil {
+		return fmt.Errorf("encountered error while fetching a layer: %w", err)
 	}
 	return nil
 }
@@ -299,6 +411,10 @@ func (p *FetchProxy) fetchOne(ctx context.Context, l *claircore.Layer) func() er
 		return p.a.realizeLayer(ctx, l)
 	}
 	return func() error {
+		// Layers constructed from local content have nothing to fetch.
+		if l.Fetched() {
+			return nil
+		}
 		h := l.Hash.String()
 		select {
 		case res := <-p.a.sf.DoChan(h, fn):
@@ -352,27 +468,12 @@ func (p *FetchProxy) Close() error {
 	return nil
 }
 
-type compression int
-
-const (
-	cmpGzip compression = iota
-	cmpZstd
-	cmpNone
-)
-
-var cmpHeaders = [...][]byte{
-	{0x1F, 0x8B, 0x08},       // cmpGzip
-	{0x28, 0xB5, 0x2F, 0xFD}, // cmpZstd
-}
-
-func detectCompression(b []byte) compression {
-	for c, h := range cmpHeaders {
-		if len(b) < len(h) {
-			continue
-		}
-		if bytes.Equal(h, b[:len(h)]) {
-			return compression(c)
-		}
+// IsGenericType reports whether the content-type is one reported for blobs
+// of unknown type.
+func isGenericType(ct string) bool {
+	switch ct {
+	case "", "text/plain", "binary/octet-stream", "application/octet-stream":
+		return true
 	}
-	return cmpNone
+	return false
 }
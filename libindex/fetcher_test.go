@@ -1,15 +1,29 @@
 package libindex
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/quay/zlog"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex/driver"
+	"github.com/quay/claircore/pkg/blob"
+	"github.com/quay/claircore/pkg/retry"
 	"github.com/quay/claircore/test"
 )
 
@@ -102,3 +116,293 @@ func TestFetchInvalid(t *testing.T) {
 		})
 	}
 }
+
+func TestFetchCache(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	c, layers := test.ServeLayers(t, 2)
+	cache, err := blob.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &FetchArena{}
+	a.Init(c, t.TempDir())
+	a.SetCache(cache)
+	fetcher := a.Fetcher()
+	if err := fetcher.Fetch(ctx, layers); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetcher.Close(); err != nil {
+		t.Error(err)
+	}
+	for _, l := range layers {
+		rc, err := cache.Get(ctx, cacheKey(l))
+		if err != nil {
+			t.Fatalf("%v: %v", l.Hash, err)
+		}
+		rc.Close()
+	}
+
+	// A client that can't fetch anything should still be able to realize the
+	// layers, from the cache.
+	b := &FetchArena{}
+	b.Init(&http.Client{Transport: failTransport{}}, t.TempDir())
+	b.SetCache(cache)
+	fetcher = b.Fetcher()
+	if err := fetcher.Fetch(ctx, layers); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range layers {
+		if !l.Fetched() {
+			t.Errorf("%v: not realized", l.Hash)
+		}
+	}
+	if err := fetcher.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+type failTransport struct{}
+
+func (failTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("unexpected request")
+}
+
+// TestFetchCompression checks that layers are decompressed according to their
+// contents, whatever content-type they're served with.
+func TestFetchCompression(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	const contents = "layer contents\n"
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "etc/file", Size: int64(len(contents)), Mode: 0644}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(tw, contents); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(tarball.Bytes())
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var zst bytes.Buffer
+	sw, err := zstd.NewWriter(&zst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sw.Write(tarball.Bytes())
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		Name        string
+		Blob        []byte
+		ContentType string
+	}{
+		{"Zstd", zst.Bytes(), "application/vnd.oci.image.layer.v1.tar+zstd"},
+		{"ZstdOctetStream", zst.Bytes(), "application/octet-stream"},
+		{"Plain", tarball.Bytes(), "application/vnd.oci.image.layer.v1.tar"},
+		{"PlainDocker", tarball.Bytes(), "application/vnd.docker.image.rootfs.diff.tar"},
+		{"PlainMislabeled", tarball.Bytes(), "application/vnd.oci.image.layer.v1.tar+gzip"},
+		{"GzipMislabeled", gz.Bytes(), "application/vnd.oci.image.layer.v1.tar"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("content-type", tc.ContentType)
+				w.Write(tc.Blob)
+			}))
+			defer srv.Close()
+			sum := sha256.Sum256(tc.Blob)
+			d, err := claircore.NewDigest("sha256", sum[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := &claircore.Layer{Hash: d, URI: srv.URL}
+
+			a := &FetchArena{}
+			a.Init(srv.Client(), t.TempDir())
+			fetcher := a.Fetcher()
+			defer fetcher.Close()
+			if err := fetcher.Fetch(ctx, []*claircore.Layer{l}); err != nil {
+				t.Fatal(err)
+			}
+			fs, err := l.Files("etc/file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := fs["etc/file"].String(), contents; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+		})
+	}
+}
+
+// TestFetchRetry checks that transient failures, including a connection
+// broken partway through the contents, are retried and that permanent ones
+// aren't.
+func TestFetchRetry(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "etc/file", Size: 4096, Mode: 0644}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(bytes.Repeat([]byte("x"), 4096))
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	blob := tarball.Bytes()
+	sum := sha256.Sum256(blob)
+	d, err := claircore.NewDigest("sha256", sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &retry.Policy{BaseDelay: time.Millisecond, NoJitter: true}
+
+	var reqs int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&reqs, 1)
+		switch {
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case n == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case n == 2:
+			// Claim the whole blob, but send half.
+			w.Header().Set("content-length", strconv.Itoa(len(blob)))
+			w.Write(blob[:len(blob)/2])
+		default:
+			w.Write(blob)
+		}
+	}))
+	defer srv.Close()
+
+	a := &FetchArena{}
+	a.Init(srv.Client(), t.TempDir())
+	a.SetRetry(policy)
+	fetcher := a.Fetcher()
+	l := &claircore.Layer{Hash: d, URI: srv.URL + "/layer"}
+	if err := fetcher.Fetch(ctx, []*claircore.Layer{l}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetcher.Close(); err != nil {
+		t.Error(err)
+	}
+	if got, want := atomic.LoadInt32(&reqs), int32(3); got != want {
+		t.Errorf("got: %d requests, want: %d", got, want)
+	}
+
+	atomic.StoreInt32(&reqs, 0)
+	l = &claircore.Layer{Hash: d, URI: srv.URL + "/missing"}
+	if err := a.Fetcher().Fetch(ctx, []*claircore.Layer{l}); err == nil {
+		t.Error("expected error")
+	}
+	if got, want := atomic.LoadInt32(&reqs), int32(1); got != want {
+		t.Errorf("got: %d requests, want: %d", got, want)
+	}
+}
+
+func TestFetchConcurrency(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	c, layers := test.ServeLayers(t, 8)
+	var cur, max int32
+	count := func(next driver.Transport) driver.Transport {
+		return driver.TransportFunc(func(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+			n := atomic.AddInt32(&cur, 1)
+			defer atomic.AddInt32(&cur, -1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			// Give the other fetches a chance to start.
+			time.Sleep(10 * time.Millisecond)
+			return next.Fetch(ctx, l)
+		})
+	}
+
+	a := &FetchArena{}
+	a.Init(c, t.TempDir())
+	a.SetConcurrency(2)
+	a.Use(count)
+	fetcher := a.Fetcher()
+	defer fetcher.Close()
+	if err := fetcher.Fetch(ctx, layers); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := atomic.LoadInt32(&max), int32(2); got != want {
+		t.Errorf("got: %d concurrent fetches, want: %d", got, want)
+	}
+}
+
+// TestFetchDigest checks that layers are verified against digests of any
+// supported algorithm, and that a mismatch is reported as a
+// *claircore.ChecksumError.
+func TestFetchDigest(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	blob := tarball.Bytes()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(blob)
+	}))
+	defer srv.Close()
+	s256 := sha256.Sum256(blob)
+	s512 := sha512.Sum512(blob)
+	bad := sha256.Sum256([]byte("something else"))
+
+	tt := []struct {
+		Name string
+		Algo string
+		Sum  []byte
+		OK   bool
+	}{
+		{"SHA256", claircore.SHA256, s256[:], true},
+		{"SHA512", claircore.SHA512, s512[:], true},
+		{"Mismatch", claircore.SHA256, bad[:], false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			d, err := claircore.NewDigest(tc.Algo, tc.Sum)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := &FetchArena{}
+			a.Init(srv.Client(), t.TempDir())
+			fetcher := a.Fetcher()
+			defer fetcher.Close()
+			err = fetcher.Fetch(ctx, []*claircore.Layer{{Hash: d, URI: srv.URL}})
+			var ce *claircore.ChecksumError
+			switch {
+			case tc.OK && err != nil:
+				t.Fatal(err)
+			case tc.OK:
+			case !errors.As(err, &ce):
+				t.Fatalf("got: %v, want: %T", err, ce)
+			default:
+				t.Log(err)
+				if got, want := ce.Want, d; got.String() != want.String() {
+					t.Errorf("got: %v, want: %v", got, want)
+				}
+				if got, want := ce.Got, s256[:]; !bytes.Equal(got, want) {
+					t.Errorf("got: %x, want: %x", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package libindex
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/libindex/migrations"
+	"github.com/quay/claircore/pkg/health"
+)
+
+// Health reports the state of Libindex's dependencies.
+type Health struct {
+	// Store reports whether the database is reachable.
+	Store health.Check `json:"store"`
+	// Migrations reports whether the database schema is up to date.
+	Migrations health.Check `json:"migrations"`
+	// Locks reports whether the lock provider is connected.
+	Locks health.Check `json:"locks"`
+	// State is the indexer state, as returned by the State method.
+	State string `json:"state"`
+}
+
+// OK reports whether all the checks passed.
+func (h *Health) OK() bool {
+	return h.Store.OK && h.Migrations.OK && h.Locks.OK
+}
+
+// Healthz probes Libindex's dependencies, for use in service health and
+// readiness checks.
+//
+// The provided Context bounds the time spent probing.
+func (l *Libindex) Healthz(ctx context.Context) *Health {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.Healthz"))
+	h := Health{State: l.state}
+	if l.pool == nil {
+		h.Store = health.Skipped("in-memory store")
+		h.Migrations = health.Skipped("in-memory store")
+	} else {
+		h.Store = health.Ping(ctx, l.pool)
+		h.Migrations = health.Migrations(ctx, l.pool, migrations.MigrationTable, migrations.Migrations)
+	}
+	if c, ok := l.cl.(interface{ Err() error }); ok {
+		h.Locks = health.Err(c.Err())
+	} else {
+		h.Locks = health.Skipped("process-local locks")
+	}
+	return &h
+}
@@ -7,11 +7,12 @@ import (
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	_ "github.com/jackc/pgx/v4/stdlib"
-	"github.com/remind101/migrate"
 
 	"github.com/quay/claircore/internal/indexer"
 	"github.com/quay/claircore/internal/indexer/postgres"
+	"github.com/quay/claircore/internal/pgmigrate"
 	"github.com/quay/claircore/libindex/migrations"
+	"github.com/quay/claircore/pkg/pgtrace"
 )
 
 // initialize a postgres pgxpool.Pool based on the given libindex.Opts
@@ -23,6 +24,9 @@ func initDB(ctx context.Context, opts *Opts) (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("failed to parse ConnString: %v", err)
 	}
 	cfg.MaxConns = 30
+	if opts.QueryTrace != nil {
+		pgtrace.Configure(cfg.ConnConfig, opts.QueryTrace)
+	}
 	pool, err := pgxpool.ConnectConfig(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ConnPool: %v", err)
@@ -39,14 +43,20 @@ func initStore(ctx context.Context, pool *pgxpool.Pool, opts *Opts) (indexer.Sto
 	}
 	defer db.Close()
 
-	// do migrations if requested
-	if opts.Migrations {
-		migrator := migrate.NewPostgresMigrator(db)
-		migrator.Table = migrations.MigrationTable
-		err := migrator.Exec(migrate.Up, migrations.Migrations...)
-		if err != nil {
+	migrator := pgmigrate.Migrator{
+		Table:      migrations.MigrationTable,
+		Migrations: migrations.Migrations,
+		Strict:     opts.RejectNewerSchema,
+	}
+	switch {
+	case opts.Migrations:
+		if err := migrator.Up(ctx, db); err != nil {
 			return nil, fmt.Errorf("failed to perform migrations: %w", err)
 		}
+	case opts.RejectNewerSchema:
+		if err := migrator.Check(ctx, db); err != nil {
+			return nil, err
+		}
 	}
 
 	store := postgres.NewStore(pool)
@@ -0,0 +1,64 @@
+package libindex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/containerstorage"
+	"github.com/quay/claircore/pkg/ocilayout"
+)
+
+// IndexLayout indexes an image in an OCI image layout directory, such as one
+// written by "skopeo copy --format oci". The image is named by "ref", which
+// may be empty if the layout holds a single image. If the image is
+// multi-platform, the manifest for the platform is indexed; a nil platform
+// selects registry.DefaultPlatform.
+//
+// Layers are read from the layout, so nothing is fetched over the network.
+// This works with Opts.Airgap set.
+func (l *Libindex) IndexLayout(ctx context.Context, dir, ref string, p *claircore.Platform) (*claircore.IndexReport, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.IndexLayout"),
+		label.String("layout", dir),
+		label.String("reference", ref))
+	lo, err := ocilayout.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	m, err := lo.Manifest(ctx, ref, p)
+	if err != nil {
+		return nil, fmt.Errorf("libindex: unable to resolve %q: %w", ref, err)
+	}
+	zlog.Debug(ctx).
+		Stringer("manifest", m.Hash).
+		Int("layers", len(m.Layers)).
+		Msg("resolved image in layout")
+	return l.Index(withLocalLayers(ctx), m)
+}
+
+// IndexContainerStorage indexes an image in a containers/storage root, as
+// used by CRI-O and podman, such as containerstorage.DefaultRoot. The image
+// is named by "ref": its ID, a unique prefix of its ID, or one of its names.
+//
+// Layers are read from the storage root, so nothing is fetched over the
+// network. This works with Opts.Airgap set.
+func (l *Libindex) IndexContainerStorage(ctx context.Context, root, ref string) (*claircore.IndexReport, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.IndexContainerStorage"),
+		label.String("root", root),
+		label.String("reference", ref))
+	s, err := containerstorage.Open(root)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.Manifest(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("libindex: unable to resolve %q: %w", ref, err)
+	}
+	return l.Index(withLocalLayers(ctx), m)
+}
@@ -0,0 +1,78 @@
+package libindex
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/linux"
+	"github.com/quay/claircore/test"
+)
+
+func TestIndexLayout(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "etc/hostname", Size: 5, Mode: 0o644}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte("local"))
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	md := test.WriteLayout(t, dir, "latest", buf.Bytes())
+
+	eco := &indexer.Ecosystem{
+		Name: "static",
+		PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+			return []indexer.PackageScanner{staticScanner{}}, nil
+		},
+		DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+		RepositoryScanners:   func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		Coalescer: func(context.Context) (indexer.Coalescer, error) {
+			return linux.NewCoalescer(), nil
+		},
+	}
+	c, layers := test.ServeLayers(t, 1)
+	lib, err := New(ctx, &Opts{
+		Ephemeral:  true,
+		Airgap:     true,
+		Ecosystems: []*indexer.Ecosystem{eco},
+	}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close(ctx)
+
+	ir, err := lib.IndexLayout(ctx, dir, "latest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ir.Success {
+		t.Fatalf("index failed: %s", ir.Err)
+	}
+	if got, want := ir.Hash.String(), md; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := len(ir.Packages), 1; got != want {
+		t.Errorf("got: %d packages, want: %d", got, want)
+	}
+
+	// Remote layers aren't fetched when airgapped.
+	ir, err = lib.Index(ctx, &claircore.Manifest{
+		Hash:   digest("remote"),
+		Layers: layers,
+	})
+	if err == nil && ir.Success {
+		t.Error("remote layer fetched while airgapped")
+	}
+}
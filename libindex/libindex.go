@@ -11,6 +11,7 @@ import (
 	"os"
 	"sort"
 
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
@@ -19,7 +20,13 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/memory"
+	"github.com/quay/claircore/libvuln/updates"
 	"github.com/quay/claircore/pkg/ctxlock"
+	"github.com/quay/claircore/pkg/headers"
+	"github.com/quay/claircore/pkg/registryauth"
+	"github.com/quay/claircore/pkg/reportsig"
+	"github.com/quay/claircore/pkg/retry"
 )
 
 const versionMagic = "libindex number: 2\n"
@@ -30,10 +37,12 @@ type Libindex struct {
 	*Opts
 	// a Store which will be shared between scanner instances
 	store indexer.Store
+	// Pool is the database connection pool backing the Store, if any.
+	pool *pgxpool.Pool
 	// a shareable http client
 	client *http.Client
 	// Cl provides system-wide locks.
-	cl *ctxlock.Locker
+	cl lockSource
 	// an opaque and unique string representing the configured
 	// state of the indexer. see setState for more information.
 	state string
@@ -56,33 +65,63 @@ func New(ctx context.Context, opts *Opts, cl *http.Client) (*Libindex, error) {
 	if cl == nil {
 		return nil, errors.New("invalid *http.Client")
 	}
-	// TODO(hank) If "airgap" is set, we should wrap the client and return
-	// errors on non-RFC1918 and non-RFC4193 addresses. As of go1.17, the net.IP
-	// type has a method for this purpose.
-
-	dbPool, err := initDB(ctx, opts)
-	if err != nil {
-		return nil, err
+	if opts.RequestHeaders != nil {
+		cl = headers.Client(cl, opts.RequestHeaders)
 	}
-	zlog.Info(ctx).Msg("created database connection")
-
-	store, err := initStore(ctx, dbPool, opts)
-	if err != nil {
-		return nil, err
+	if opts.RegistryAuth != nil {
+		cl = registryauth.Client(cl, opts.RegistryAuth)
 	}
-
-	ctxLocker, err := ctxlock.New(ctx, dbPool)
-	if err != nil {
-		return nil, err
+	if opts.RetryPolicy != nil {
+		cl = retry.Client(cl, opts.RetryPolicy)
 	}
+	// TODO(hank) If "airgap" is set, we should wrap the client and return
+	// errors on non-RFC1918 and non-RFC4193 addresses. As of go1.17, the net.IP
+	// type has a method for this purpose.
 
 	l := &Libindex{
 		Opts:   opts,
-		store:  store,
 		client: cl,
-		cl:     ctxLocker,
+	}
+	switch {
+	case opts.Ephemeral:
+		zlog.Info(ctx).Msg("ephemeral mode: nothing will be persisted")
+		l.store = memory.NewStore()
+		l.cl = localLocks{updates.NewLocalLockSource()}
+	case opts.Store != nil:
+		l.store = opts.Store
+		l.cl = localLocks{updates.NewLocalLockSource()}
+	default:
+		dbPool, err := initDB(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		zlog.Info(ctx).Msg("created database connection")
+		l.pool = dbPool
+
+		l.store, err = initStore(ctx, dbPool, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		l.cl, err = ctxlock.New(ctx, dbPool)
+		if err != nil {
+			return nil, err
+		}
 	}
 	l.fetchArena.Init(cl, os.TempDir()) // TODO(hank) Add an option field for this 'root' argument.
+	l.fetchArena.SetLimits(opts.FetchLimits)
+	l.fetchArena.SetConcurrency(opts.FetchConcurrency)
+	l.fetchArena.SetRetry(opts.FetchRetry)
+	l.fetchArena.SetBandwidth(opts.FetchBandwidth)
+	l.fetchArena.SetCache(opts.LayerCache)
+	switch {
+	case opts.FetchTransport != nil:
+		l.fetchArena.SetTransport(opts.FetchTransport)
+	case opts.Airgap:
+		l.fetchArena.SetTransport(nil)
+	}
+	l.fetchArena.AllowLocal(opts.LocalLayers)
+	l.fetchArena.Use(opts.FetchMiddleware...)
 
 	// register any new scanners.
 	pscnrs, dscnrs, rscnrs, err := indexer.EcosystemsToScanners(ctx, opts.Ecosystems, opts.Airgap)
@@ -104,9 +143,42 @@ func New(ctx context.Context, opts *Opts, cl *http.Client) (*Libindex, error) {
 
 	zlog.Info(ctx).Msg("registered configured scanners")
 	l.Opts.vscnrs = vscnrs
+
+	if opts.OnStaleManifests != nil {
+		ms, err := l.StaleManifests(ctx)
+		switch {
+		case errors.Is(err, nil):
+			zlog.Info(ctx).
+				Int("count", len(ms)).
+				Msg("found manifests needing re-indexing")
+			if len(ms) != 0 {
+				opts.OnStaleManifests(ctx, ms)
+			}
+		default:
+			zlog.Warn(ctx).Err(err).Msg("unable to check for stale manifests")
+		}
+	}
 	return l, nil
 }
 
+// LockSource abstracts over how locks are implemented.
+//
+// An online system needs distributed locks, ephemeral use can use
+// process-local locks.
+type lockSource interface {
+	Lock(context.Context, string) (context.Context, context.CancelFunc)
+	Close(context.Context) error
+}
+
+// LocalLocks adapts the process-local locks used by the updater machinery to
+// the lockSource interface.
+type localLocks struct {
+	updates.LockSource
+}
+
+// Close implements lockSource.
+func (localLocks) Close(_ context.Context) error { return nil }
+
 // Close releases held resources.
 func (l *Libindex) Close(ctx context.Context) error {
 	l.cl.Close(ctx)
@@ -119,6 +191,10 @@ func (l *Libindex) Close(ctx context.Context) error {
 //
 // If the index operation cannot start an error will be returned.
 // If an error occurs during scan the error will be propagated inside the IndexReport.
+//
+// If the Libindex was constructed with the Ephemeral option, every call runs
+// against fresh in-memory state and the returned IndexReport is the only
+// record of the operation.
 func (l *Libindex) Index(ctx context.Context, manifest *claircore.Manifest) (*claircore.IndexReport, error) {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "libindex/Libindex.Index"),
@@ -183,9 +259,61 @@ func (l *Libindex) setState(ctx context.Context, vscnrs indexer.VersionedScanner
 	return nil
 }
 
+// ErrStaleUnsupported is returned by StaleManifests if the configured store
+// can't report stale manifests.
+var ErrStaleUnsupported = errors.New("libindex: store does not support finding stale manifests")
+
+// StaleManifests reports the indexed manifests that need to be re-indexed to
+// have results from the configured scanners, such as after a scanner's
+// version changes.
+func (l *Libindex) StaleManifests(ctx context.Context) ([]indexer.StaleManifest, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.StaleManifests"))
+	f, ok := l.store.(indexer.StaleFinder)
+	if !ok {
+		return nil, ErrStaleUnsupported
+	}
+	return f.StaleManifests(ctx, l.Opts.vscnrs)
+}
+
+// ErrSourceUnsupported is returned by BinariesBySource if the configured
+// store can't report the binary packages built from a source package.
+var ErrSourceUnsupported = errors.New("libindex: store does not support querying source packages")
+
+// BinariesBySource reports the binary packages built from source packages
+// with the provided name, and the manifests they're in, across all indexed
+// manifests. If version isn't empty, only source packages with that exact
+// version are considered.
+//
+// This is useful when responding to an advisory that only names a source
+// package.
+func (l *Libindex) BinariesBySource(ctx context.Context, name, version string) ([]indexer.SourceBinary, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.BinariesBySource"))
+	f, ok := l.store.(indexer.SourceFinder)
+	if !ok {
+		return nil, ErrSourceUnsupported
+	}
+	return f.BinariesBySource(ctx, name, version)
+}
+
 // IndexReport retrieves an IndexReport for a particular manifest hash, if it exists.
 func (l *Libindex) IndexReport(ctx context.Context, hash claircore.Digest) (*claircore.IndexReport, bool, error) {
-	return l.store.IndexReport(ctx, hash)
+	ir, ok, err := l.store.IndexReport(ctx, hash)
+	if err != nil || !ok {
+		return ir, ok, err
+	}
+	// Reports stored by older versions may not be in a stable order.
+	ir.Sort()
+	return ir, ok, nil
+}
+
+// SignIndexReport returns the canonical encoding of the IndexReport and a
+// detached signature over it, made with the configured Signer.
+//
+// reportsig.ErrNoSigner is returned if Opts.Signer isn't set.
+func (l *Libindex) SignIndexReport(ctx context.Context, ir *claircore.IndexReport) (*reportsig.Detached, error) {
+	return reportsig.Sign(ctx, l.Opts.Signer, ir)
 }
 
 // AffectedManifests retrieves a list of affected manifests when provided a list of vulnerabilities.
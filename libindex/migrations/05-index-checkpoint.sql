@@ -0,0 +1,7 @@
+-- Per-layer progress of an in-flight Index operation. Rows are removed once
+-- the manifest's index report is finished.
+CREATE TABLE IF NOT EXISTS index_checkpoint (
+	manifest_id BIGINT REFERENCES manifest(id) ON DELETE CASCADE,
+	layer_id BIGINT REFERENCES layer(id) ON DELETE CASCADE,
+	PRIMARY KEY (manifest_id, layer_id)
+);
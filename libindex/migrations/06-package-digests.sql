@@ -0,0 +1,4 @@
+-- Content digests of the artifact a package was found in, such as a jar or
+-- wheel. These are recorded per scan artifact, as the same package may be
+-- found in different artifacts.
+ALTER TABLE package_scanartifact ADD COLUMN IF NOT EXISTS digests text[];
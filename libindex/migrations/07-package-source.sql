@@ -0,0 +1,21 @@
+-- Binary to source package relationships, independent of the layers the
+-- binary packages were found in, so that the binaries built from a source
+-- package can be found directly.
+CREATE TABLE IF NOT EXISTS package_source (
+	package_id BIGINT REFERENCES package(id) ON DELETE CASCADE,
+	source_id BIGINT REFERENCES package(id) ON DELETE CASCADE,
+	PRIMARY KEY (package_id, source_id)
+);
+CREATE INDEX IF NOT EXISTS package_source_source_idx ON package_source (source_id);
+-- Binary packages without a source package are recorded against the empty
+-- package, which isn't a relationship worth keeping.
+INSERT INTO package_source (package_id, source_id)
+SELECT DISTINCT
+	package_scanartifact.package_id, package_scanartifact.source_id
+FROM
+	package_scanartifact
+	JOIN package ON package.id = package_scanartifact.source_id
+WHERE
+	package_scanartifact.package_id IS NOT NULL
+	AND package.name <> ''
+ON CONFLICT DO NOTHING;
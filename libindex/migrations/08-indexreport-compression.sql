@@ -0,0 +1,4 @@
+-- IndexReports are stored zstd-compressed in this column. Reports stored
+-- before this migration are left in scan_result and are still readable;
+-- they're compressed when next written.
+ALTER TABLE indexreport ADD COLUMN IF NOT EXISTS scan_result_zstd bytea;
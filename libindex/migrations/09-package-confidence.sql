@@ -0,0 +1,4 @@
+-- How a package was discovered, as reported by the scanner that found it.
+-- Like the package database and repository hint, this is recorded per scan
+-- artifact.
+ALTER TABLE package_scanartifact ADD COLUMN IF NOT EXISTS confidence text;
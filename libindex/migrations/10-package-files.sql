@@ -0,0 +1,4 @@
+-- The files a package installed, for package databases that record them.
+-- Recorded per scan artifact, as the same package may be installed with
+-- different files in different layers.
+ALTER TABLE package_scanartifact ADD COLUMN IF NOT EXISTS files text[];
@@ -0,0 +1,3 @@
+-- Additional information about a package found by the scanner, such as its
+-- license. Recorded per scan artifact, like the files a package installed.
+ALTER TABLE package_scanartifact ADD COLUMN IF NOT EXISTS metadata jsonb;
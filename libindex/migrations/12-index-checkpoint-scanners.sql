@@ -0,0 +1,7 @@
+-- Checkpoints are only valid for the set of scanners that made them, so key
+-- them by it. Checkpoints only live as long as an Index operation, so any
+-- existing ones are dropped rather than assigned a scanner set.
+DELETE FROM index_checkpoint;
+ALTER TABLE index_checkpoint ADD COLUMN scanners TEXT NOT NULL;
+ALTER TABLE index_checkpoint DROP CONSTRAINT index_checkpoint_pkey;
+ALTER TABLE index_checkpoint ADD PRIMARY KEY (manifest_id, layer_id, scanners);
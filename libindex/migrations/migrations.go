@@ -42,4 +42,36 @@ var Migrations = []migrate.Migration{
 		ID: 4,
 		Up: runFile("04-foreign-key-cascades.sql"),
 	},
+	{
+		ID: 5,
+		Up: runFile("05-index-checkpoint.sql"),
+	},
+	{
+		ID: 6,
+		Up: runFile("06-package-digests.sql"),
+	},
+	{
+		ID: 7,
+		Up: runFile("07-package-source.sql"),
+	},
+	{
+		ID: 8,
+		Up: runFile("08-indexreport-compression.sql"),
+	},
+	{
+		ID: 9,
+		Up: runFile("09-package-confidence.sql"),
+	},
+	{
+		ID: 10,
+		Up: runFile("10-package-files.sql"),
+	},
+	{
+		ID: 11,
+		Up: runFile("11-package-metadata.sql"),
+	},
+	{
+		ID: 12,
+		Up: runFile("12-index-checkpoint-scanners.sql"),
+	},
 }
@@ -6,9 +6,23 @@ import (
 	"time"
 
 	"github.com/quay/claircore/alpine"
+	"github.com/quay/claircore/composer"
+	"github.com/quay/claircore/dotnet"
 	"github.com/quay/claircore/dpkg"
+	"github.com/quay/claircore/gem"
+	"github.com/quay/claircore/gobin"
 	"github.com/quay/claircore/internal/indexer"
 	"github.com/quay/claircore/java"
+	"github.com/quay/claircore/libindex/driver"
+	"github.com/quay/claircore/npm"
+	"github.com/quay/claircore/osrelease"
+	"github.com/quay/claircore/pkg/blob"
+	"github.com/quay/claircore/pkg/headers"
+	"github.com/quay/claircore/pkg/pgtrace"
+	"github.com/quay/claircore/pkg/referrers"
+	"github.com/quay/claircore/pkg/registryauth"
+	"github.com/quay/claircore/pkg/reportsig"
+	"github.com/quay/claircore/pkg/retry"
 	"github.com/quay/claircore/python"
 	"github.com/quay/claircore/rhel"
 	"github.com/quay/claircore/rpm"
@@ -37,14 +51,142 @@ type Opts struct {
 	NoLayerValidation bool
 	// set to true to have libindex check and potentially run migrations
 	Migrations bool
+	// RejectNewerSchema causes New to fail if the database has migrations
+	// applied that this version doesn't know about, as happens when a newer
+	// version has already migrated it. Otherwise, this is only logged.
+	RejectNewerSchema bool
+	// QueryTrace, if set, configures reporting of database query durations
+	// and logging of slow queries.
+	QueryTrace *pgtrace.Config
+	// Ephemeral configures libindex to run scanners and coalescers without
+	// persisting anything: no database is used, and every Index call works
+	// against fresh in-memory state. This is useful for one-shot CLI or CI
+	// use. ConnString and Migrations are ignored when set.
+	Ephemeral bool
+	// Store, if set, is used to persist results instead of a PostgreSQL
+	// database, e.g. one returned by the libindex/sqlite package's Open.
+	// Locks are then only process-local, so the Store must not be shared
+	// between processes. ConnString and Migrations are ignored when set, and
+	// the Store is closed when the Libindex is. Ephemeral takes precedence
+	// over Store.
+	Store indexer.Store
 	// provides an alternative method for creating a scanner during libindex runtime
 	// if nil the default factory will be used. useful for testing purposes
 	ControllerFactory ControllerFactory
 	// a list of ecosystems to use which define which package databases and coalescing methods we use
-	Ecosystems []*indexer.Ecosystem
+	//
+	// Ecosystems with out-of-tree scanners can be built with the types in the
+	// libindex/driver package.
+	//
+	// If nil, a default list is used. The electron package's ecosystem reads
+	// every large executable in a layer, so it's not in the default list and
+	// must be added explicitly.
+	Ecosystems []*driver.Ecosystem
 	// Airgap should be set to disallow any scanners that mark themselves as
-	// making network calls.
+	// making network calls. Layers are then only read from the local
+	// filesystem (see LocalLayers), unless a FetchTransport is provided.
 	Airgap bool
+	// LocalLayers allows the layers of any indexed manifest to be read from
+	// the local filesystem, given "file" or "containers-storage" URIs.
+	// Otherwise, only IndexLayout and IndexContainerStorage read local layers.
+	//
+	// This lets whoever supplies a manifest read any file the process can, so
+	// it should only be set if manifests come from trusted callers.
+	LocalLayers bool
+	// FetchLimits configures per-registry-host rate limits and concurrency
+	// caps for layer fetches. Limits are shared across all Index calls made
+	// on a Libindex instance.
+	//
+	// If nil, no limits are applied.
+	FetchLimits FetchLimits
+	// FetchConcurrency is the number of a manifest's layers fetched at once.
+	// If zero, all of them are.
+	FetchConcurrency int
+	// FetchRetry, if set, is applied to layer fetches that fail with
+	// transient errors, such as a 503 response or a connection broken
+	// partway through the contents, so a single failure doesn't fail the
+	// whole Index call. The layer is fetched again from the start.
+	//
+	// Unlike RetryPolicy, this also covers failures reading the contents and
+	// applies to any FetchTransport.
+	FetchRetry *retry.Policy
+	// FetchBandwidth, if positive, limits the rate at which all layer
+	// fetches, taken together, read layer contents, in bytes per second.
+	// Layers found in the LayerCache aren't limited.
+	FetchBandwidth int64
+	// RetryPolicy, if set, is applied to all requests made with the
+	// *http.Client passed to New: layer fetches and any requests made by
+	// scanners.
+	RetryPolicy *retry.Policy
+	// RequestHeaders, if set, configures the User-Agent and additional
+	// headers set on all requests made with the *http.Client passed to New.
+	RequestHeaders *headers.Config
+	// RegistryAuth, if set, enables answering registry bearer token
+	// challenges for layer fetches, as needed to pull from Docker Hub and
+	// Amazon ECR Public. Tokens are cached and reused across fetches.
+	//
+	// A zero Config requests anonymous tokens.
+	RegistryAuth *registryauth.Config
+	// Attachments, if set, enables discovering the signatures, attestations,
+	// and SBOMs attached to indexed images in their registries. What's found
+	// is recorded in the IndexReport, including any SLSA provenance if
+	// enabled in the Config.
+	Attachments *referrers.Config
+	// Signer, if set, is used by Libindex.SignIndexReport to produce
+	// detached signatures over IndexReports.
+	Signer reportsig.Signer
+	// OnStaleManifests, if set, is called during New with the manifests that
+	// need to be re-indexed to have results from the configured scanners, so
+	// that they can be queued for re-indexing. It's not called if there are
+	// none. An error finding them is logged and doesn't cause New to fail.
+	//
+	// See also Libindex.StaleManifests, and Libindex.Backfill to re-index them.
+	OnStaleManifests func(context.Context, []indexer.StaleManifest)
+	// ForeignURLs lists the URL prefixes, such as "https://mcr.microsoft.com/",
+	// that foreign layers may be fetched from. A foreign layer is one the
+	// image manifest declares URLs for; see claircore.Layer.URLs. The
+	// registry is always tried first, then each allowed URL in order.
+	//
+	// A URL is allowed if it has a prefix's scheme and host and its path is
+	// at or below the prefix's path. The registry's request headers aren't
+	// sent to allowed URLs.
+	//
+	// Foreign layers that can't be fetched are skipped and reported in the
+	// IndexReport's Warnings, rather than failing the index.
+	ForeignURLs []string
+	// ContentScanners, if set, are run against every layer of an indexed
+	// image to look for byte signatures, such as malware or indicators of
+	// compromise, in its files. Matches are reported in the IndexReport's
+	// Detections. See the pkg/sigscan package for a ContentScanner with
+	// pluggable matching engines.
+	//
+	// Content scanning needs every layer on every Index call, so layers
+	// already scanned by the other scanners are fetched anyway.
+	ContentScanners []driver.ContentScanner
+	// Heuristics enables image-level checks that explain why an IndexReport
+	// may be missing packages, such as a package manager present with no
+	// package database. Their findings are reported in the IndexReport's
+	// Warnings. See the heuristics package.
+	//
+	// Like content scanning, the checks need every layer on every Index call.
+	Heuristics bool
+	// LayerCache, if set, is where fetched layers are stored for reuse.
+	// Layers found in the cache aren't fetched again, so sharing the cache
+	// through object storage lets several instances avoid refetching the same
+	// layers. See blob.NewLRU for a local cache with a bounded size.
+	LayerCache blob.Store
+	// FetchTransport, if set, is used to retrieve layers in place of HTTP
+	// requests made with the *http.Client passed to New. This allows layers
+	// to be fetched from sources such as artifact proxies or peer-to-peer
+	// distribution systems.
+	//
+	// Layers' digests are verified, and the LayerCache and FetchLimits are
+	// applied, regardless of the Transport.
+	FetchTransport driver.Transport
+	// FetchMiddleware, if set, wraps the Transport layers are fetched with,
+	// the first being outermost. It's applied inside the LayerCache and digest
+	// verification, and outside the FetchLimits.
+	FetchMiddleware []driver.Middleware
 	// ScannerConfig holds functions that can be passed into configurable
 	// scanners. They're broken out by kind, and only used if a scanner
 	// implements the appropriate interface.
@@ -60,7 +202,7 @@ type Opts struct {
 
 func (o *Opts) Parse(ctx context.Context) error {
 	// required
-	if o.ConnString == "" {
+	if o.ConnString == "" && o.Store == nil && !o.Ephemeral {
 		return fmt.Errorf("ConnString not provided")
 	}
 
@@ -82,6 +224,12 @@ func (o *Opts) Parse(ctx context.Context) error {
 			rpm.NewEcosystem(ctx),
 			python.NewEcosystem(ctx),
 			java.NewEcosystem(ctx),
+			npm.NewEcosystem(ctx),
+			gem.NewEcosystem(ctx),
+			gobin.NewEcosystem(ctx),
+			composer.NewEcosystem(ctx),
+			dotnet.NewEcosystem(ctx),
+			osrelease.NewEcosystem(ctx),
 		}
 	}
 	o.LayerFetchOpt = DefaultLayerFetchOpt
@@ -0,0 +1,72 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+)
+
+// ErrPlatformNotFound is returned by IndexPlatform if the ManifestList has no
+// manifest for the requested platform.
+var ErrPlatformNotFound = errors.New("libindex: no manifest for platform")
+
+// IndexPlatform indexes the manifest in the ManifestList built for the
+// platform. A platform without a variant selects the first manifest for any
+// variant.
+func (l *Libindex) IndexPlatform(ctx context.Context, ml *claircore.ManifestList, p claircore.Platform) (*claircore.IndexReport, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.IndexPlatform"),
+		label.Stringer("manifest_list", ml.Hash),
+		label.Stringer("platform", p))
+	m, ok := ml.Select(p)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v (have %v)", ErrPlatformNotFound, p, ml.Platforms())
+	}
+	zlog.Debug(ctx).
+		Stringer("manifest", m.Hash).
+		Msg("selected manifest")
+	return l.Index(ctx, m)
+}
+
+// IndexPlatforms indexes every manifest in the ManifestList, returning an
+// IndexReport for each in the same order. Each report's Platform is set from
+// its manifest.
+//
+// If any manifest can't be indexed, the error is returned and no reports are.
+func (l *Libindex) IndexPlatforms(ctx context.Context, ml *claircore.ManifestList) ([]*claircore.IndexReport, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.IndexPlatforms"),
+		label.Stringer("manifest_list", ml.Hash))
+	out := make([]*claircore.IndexReport, len(ml.Manifests))
+	for i, m := range ml.Manifests {
+		ir, err := l.Index(ctx, m)
+		if err != nil {
+			return nil, fmt.Errorf("libindex: unable to index manifest %v: %w", m.Hash, err)
+		}
+		if ir.Platform == nil {
+			ir.Platform = m.Platform
+		}
+		out[i] = ir
+	}
+	return out, nil
+}
+
+// IndexMerged indexes every manifest in the ManifestList and returns a single
+// IndexReport for the list, with each Environment annotated with the platform
+// it was found on. See claircore.MergeIndexReports.
+//
+// The merged report is not persisted; IndexReport only returns the reports
+// for the individual manifests.
+func (l *Libindex) IndexMerged(ctx context.Context, ml *claircore.ManifestList) (*claircore.IndexReport, error) {
+	rs, err := l.IndexPlatforms(ctx, ml)
+	if err != nil {
+		return nil, err
+	}
+	return claircore.MergeIndexReports(ml.Hash, rs...), nil
+}
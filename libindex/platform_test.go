@@ -0,0 +1,113 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/linux"
+	"github.com/quay/claircore/test"
+)
+
+func TestManifestList(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	c, layers := test.ServeLayers(t, 2)
+
+	eco := &indexer.Ecosystem{
+		Name: "static",
+		PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+			return []indexer.PackageScanner{staticScanner{}}, nil
+		},
+		DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+		RepositoryScanners:   func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		Coalescer: func(context.Context) (indexer.Coalescer, error) {
+			return linux.NewCoalescer(), nil
+		},
+	}
+	lib, err := New(ctx, &Opts{
+		Ephemeral:  true,
+		Ecosystems: []*indexer.Ecosystem{eco},
+	}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close(ctx)
+
+	amd64 := claircore.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := claircore.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}
+	ml := &claircore.ManifestList{
+		Hash: digest("list"),
+		Manifests: []*claircore.Manifest{
+			{Hash: digest("amd64"), Layers: layers[:1], Platform: &amd64},
+			{Hash: digest("arm64"), Layers: layers[1:], Platform: &arm64},
+		},
+	}
+
+	t.Run("Select", func(t *testing.T) {
+		ir, err := lib.IndexPlatform(ctx, ml, claircore.Platform{OS: "linux", Architecture: "arm64"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ir.Hash.String(), ml.Manifests[1].Hash.String(); got != want {
+			t.Errorf("got: %s, want: %s", got, want)
+		}
+		if ir.Platform == nil || *ir.Platform != arm64 {
+			t.Errorf("got platform: %v, want: %v", ir.Platform, arm64)
+		}
+		_, err = lib.IndexPlatform(ctx, ml, claircore.Platform{OS: "linux", Architecture: "s390x"})
+		if !errors.Is(err, ErrPlatformNotFound) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("All", func(t *testing.T) {
+		rs, err := lib.IndexPlatforms(ctx, ml)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(rs), 2; got != want {
+			t.Fatalf("got: %d reports, want: %d", got, want)
+		}
+		for i, ir := range rs {
+			if !ir.Success {
+				t.Errorf("%d: index failed: %s", i, ir.Err)
+			}
+			if got, want := ir.Hash.String(), ml.Manifests[i].Hash.String(); got != want {
+				t.Errorf("%d: got: %s, want: %s", i, got, want)
+			}
+			if ir.Platform != ml.Manifests[i].Platform {
+				t.Errorf("%d: got platform: %v", i, ir.Platform)
+			}
+		}
+	})
+	t.Run("Merged", func(t *testing.T) {
+		ir, err := lib.IndexMerged(ctx, ml)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ir.Success {
+			t.Fatalf("index failed: %s", ir.Err)
+		}
+		if got, want := ir.Hash.String(), ml.Hash.String(); got != want {
+			t.Errorf("got: %s, want: %s", got, want)
+		}
+		// The static scanner reports the same package on both platforms.
+		if got, want := len(ir.Packages), 1; got != want {
+			t.Fatalf("got: %d packages, want: %d", got, want)
+		}
+		for id := range ir.Packages {
+			envs := ir.Environments[id]
+			if got, want := len(envs), 2; got != want {
+				t.Fatalf("got: %d environments, want: %d", got, want)
+			}
+			if envs[0].Platform != amd64.String() || envs[1].Platform != arm64.String() {
+				t.Errorf("unexpected platforms: %q, %q", envs[0].Platform, envs[1].Platform)
+			}
+		}
+	})
+}
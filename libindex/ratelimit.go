@@ -0,0 +1,254 @@
+package libindex
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex/driver"
+	"github.com/quay/claircore/pkg/containerstorage"
+)
+
+// DefaultFetchLimitKey is the key in a FetchLimits map used for any host
+// that doesn't have an explicit entry.
+const DefaultFetchLimitKey = "*"
+
+// FetchLimit describes the limits applied to layer fetches made to a single
+// registry host.
+//
+// The zero value imposes no limits.
+type FetchLimit struct {
+	// Rate is the sustained number of requests per second allowed to the host.
+	// A value of zero or less means requests are not rate limited.
+	Rate float64
+	// Burst is the number of requests allowed to happen at once before the
+	// Rate takes effect. If Rate is set and Burst is less than 1, a Burst of 1
+	// is used.
+	Burst int
+	// Concurrency is the maximum number of in-flight fetches to the host. A
+	// value of zero or less means in-flight fetches are not limited.
+	Concurrency int
+}
+
+// FetchLimits is a map of registry hosts to the limits that should be applied
+// to them.
+//
+// Keys should be in the "host" or "host:port" form, as found in a layer URI.
+// The DefaultFetchLimitKey entry, if present, is applied to every host
+// without an explicit entry.
+type FetchLimits map[string]FetchLimit
+
+// PublicRegistryFetchLimits returns limits suitable for fetching from Docker
+// Hub and Amazon ECR Public, for merging into a configured FetchLimits.
+//
+// The limits keep bursts of fetches for popular base images from tripping the
+// registries' abuse protections; a 429 that slips through anyway is handled
+// by a configured retry policy honoring "Retry-After".
+func PublicRegistryFetchLimits() FetchLimits {
+	return FetchLimits{
+		"registry-1.docker.io": {Rate: 10, Burst: 20, Concurrency: 10},
+		"public.ecr.aws":       {Rate: 10, Burst: 20, Concurrency: 10},
+	}
+}
+
+// HostLimiter tracks limiter state for every host requests are made to.
+//
+// A single hostLimiter is meant to be shared by all Index calls, so that
+// concurrent requests are accounted for together.
+type hostLimiter struct {
+	cfg FetchLimits
+
+	mu    sync.Mutex
+	hosts map[string]*limit
+}
+
+// Limit is the per-host limiter state.
+type limit struct {
+	rate *rate.Limiter
+	sem  *semaphore.Weighted
+}
+
+// NewHostLimiter returns a hostLimiter using the provided configuration.
+//
+// A nil hostLimiter is returned if there's no configuration, which is valid to
+// call methods on.
+func newHostLimiter(cfg FetchLimits) *hostLimiter {
+	if len(cfg) == 0 {
+		return nil
+	}
+	return &hostLimiter{
+		cfg:   cfg,
+		hosts: make(map[string]*limit),
+	}
+}
+
+// Get returns the limiter state for the named host, creating it if needed.
+func (h *hostLimiter) get(host string) *limit {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if l, ok := h.hosts[host]; ok {
+		return l
+	}
+	c, ok := h.cfg[host]
+	if !ok {
+		c = h.cfg[DefaultFetchLimitKey]
+	}
+	l := &limit{}
+	if c.Rate > 0 {
+		b := c.Burst
+		if b < 1 {
+			b = 1
+		}
+		l.rate = rate.NewLimiter(rate.Limit(c.Rate), b)
+	}
+	if c.Concurrency > 0 {
+		l.sem = semaphore.NewWeighted(int64(c.Concurrency))
+	}
+	h.hosts[host] = l
+	return l
+}
+
+// Acquire blocks until a request to the host in the provided URL is allowed to
+// proceed, or the Context is canceled.
+//
+// The returned function must be called once the request is completely
+// finished, including reading the response body.
+func (h *hostLimiter) acquire(ctx context.Context, u *url.URL) (func(), error) {
+	if h == nil {
+		return func() {}, nil
+	}
+	l := h.get(u.Host)
+	if l.sem != nil {
+		if err := l.sem.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+	}
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			if l.sem != nil {
+				l.sem.Release(1)
+			}
+			return nil, err
+		}
+	}
+	return func() {
+		if l.sem != nil {
+			l.sem.Release(1)
+		}
+	}, nil
+}
+
+// RateLimit returns a driver.Middleware applying the provided limits to the
+// hosts in layers' URIs. A fetch holds its host's concurrency slot until the
+// returned Blob is closed.
+//
+// Limiter state is kept for the lifetime of the Middleware.
+func RateLimit(l FetchLimits) driver.Middleware {
+	return newHostLimiter(l).middleware
+}
+
+// Middleware implements driver.Middleware.
+func (h *hostLimiter) middleware(next driver.Transport) driver.Transport {
+	if h == nil {
+		return next
+	}
+	return driver.TransportFunc(func(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+		u, err := url.Parse(l.URI)
+		if err != nil || u.Scheme == "file" || u.Scheme == containerstorage.Scheme {
+			// Not a URI this middleware can make sense of, or a local layer,
+			// so let the next Transport decide what to do with it.
+			return next.Fetch(ctx, l)
+		}
+		release, err := h.acquire(ctx, u)
+		if err != nil {
+			return nil, fmt.Errorf("fetcher: unable to acquire limiter: %w", err)
+		}
+		b, err := next.Fetch(ctx, l)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		b.ReadCloser = &releaseCloser{ReadCloser: b.ReadCloser, release: release}
+		return b, nil
+	})
+}
+
+// ReleaseCloser calls its release function once, when closed.
+type releaseCloser struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseCloser) Close() error {
+	r.once.Do(r.release)
+	return r.ReadCloser.Close()
+}
+
+// BandwidthLimit returns a driver.Middleware limiting the rate at which the
+// contents of all layers fetched through it are read, in bytes per second.
+//
+// Limiter state is kept for the lifetime of the Middleware.
+func BandwidthLimit(bps int64) driver.Middleware {
+	lim := newBandwidthLimiter(bps)
+	if lim == nil {
+		return func(next driver.Transport) driver.Transport { return next }
+	}
+	return bandwidth(lim)
+}
+
+// NewBandwidthLimiter returns a limiter allowing "bps" bytes per second, or
+// nil if "bps" isn't positive.
+func newBandwidthLimiter(bps int64) *rate.Limiter {
+	if bps <= 0 {
+		return nil
+	}
+	// Allow reads of up to a tenth of a second's worth at once, so the rate
+	// is smooth, but not so small that reads are inefficient.
+	b := int(bps / 10)
+	if b < 4096 {
+		b = 4096
+	}
+	return rate.NewLimiter(rate.Limit(bps), b)
+}
+
+// Bandwidth returns a driver.Middleware that reads contents through the
+// limiter.
+func bandwidth(lim *rate.Limiter) driver.Middleware {
+	return func(next driver.Transport) driver.Transport {
+		return driver.TransportFunc(func(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+			b, err := next.Fetch(ctx, l)
+			if err != nil {
+				return nil, err
+			}
+			b.ReadCloser = &limitReader{ReadCloser: b.ReadCloser, ctx: ctx, lim: lim}
+			return b, nil
+		})
+	}
+}
+
+// LimitReader waits on a limiter for every byte read.
+type limitReader struct {
+	io.ReadCloser
+	ctx context.Context
+	lim *rate.Limiter
+}
+
+func (r *limitReader) Read(b []byte) (int, error) {
+	if max := r.lim.Burst(); len(b) > max {
+		b = b[:max]
+	}
+	n, err := r.ReadCloser.Read(b)
+	if n > 0 {
+		if werr := r.lim.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
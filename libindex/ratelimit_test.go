@@ -0,0 +1,140 @@
+package libindex
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex/driver"
+	"github.com/quay/claircore/test"
+)
+
+func TestHostLimiterConcurrency(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	const max = 2
+	h := newHostLimiter(FetchLimits{
+		"registry.example.com": {Concurrency: max},
+	})
+	u, _ := url.Parse("https://registry.example.com/v2/")
+	other, _ := url.Parse("https://other.example.com/v2/")
+
+	var cur, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := h.acquire(ctx, u)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			n := atomic.AddInt32(&cur, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&cur, -1)
+		}()
+	}
+	// Hosts without an entry (and no default) should not be limited.
+	release, err := h.acquire(ctx, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	wg.Wait()
+	if got, want := atomic.LoadInt32(&peak), int32(max); got > want {
+		t.Errorf("got: %d concurrent requests, want: <= %d", got, want)
+	}
+}
+
+func TestHostLimiterRate(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	h := newHostLimiter(FetchLimits{
+		DefaultFetchLimitKey: {Rate: 1, Burst: 1},
+	})
+	u, _ := url.Parse("https://registry.example.com/v2/")
+
+	release, err := h.acquire(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	// The next request should have to wait on the limiter, so a short
+	// deadline should be exceeded.
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := h.acquire(tctx, u); err == nil {
+		t.Error("expected request to be rate limited")
+	}
+}
+
+func TestFetchLimited(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	c, layers := test.ServeLayers(t, 8)
+	p, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Error(err)
+	}
+
+	a := &FetchArena{}
+	a.Init(c, p)
+	a.SetLimits(FetchLimits{
+		DefaultFetchLimitKey: {Concurrency: 1},
+	})
+
+	fetcher := a.Fetcher()
+	if err := fetcher.Fetch(ctx, layers); err != nil {
+		t.Error(err)
+	}
+	if err := fetcher.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBandwidthLimit(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const bps = 100000
+	contents := bytes.Repeat([]byte("x"), 3*bps/10)
+	tr := driver.Chain(driver.TransportFunc(func(context.Context, *claircore.Layer) (*driver.Blob, error) {
+		return &driver.Blob{ReadCloser: io.NopCloser(bytes.NewReader(contents))}, nil
+	}), BandwidthLimit(bps))
+
+	start := time.Now()
+	b, err := tr.Fetch(ctx, &claircore.Layer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	n, err := io.Copy(io.Discard, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(len(contents)); got != want {
+		t.Errorf("got: %d bytes, want: %d", got, want)
+	}
+	// The first tenth of a second's worth is allowed at once.
+	if got, want := time.Since(start), 150*time.Millisecond; got < want {
+		t.Errorf("read took %v, want at least %v", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package libindex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/registry"
+)
+
+// IndexReference indexes the image named by the reference, such as
+// "quay.io/projectquay/clair:4.3.0", resolving it with the image's registry.
+// If the reference names a multi-platform image, the manifest for the
+// platform is indexed; a nil platform selects registry.DefaultPlatform.
+//
+// Requests are made with the client passed to New, so Opts.RegistryAuth
+// applies. Layers are fetched as with Index; setting Opts.FetchTransport to a
+// registry.Client adds resumption of interrupted downloads.
+func (l *Libindex) IndexReference(ctx context.Context, ref string, p *claircore.Platform) (*claircore.IndexReport, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libindex/Libindex.IndexReference"),
+		label.String("reference", ref))
+	r, err := registry.ParseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	c := registry.Client{HTTP: l.client}
+	m, err := c.Manifest(ctx, r, p)
+	if err != nil {
+		return nil, fmt.Errorf("libindex: unable to resolve %q: %w", ref, err)
+	}
+	zlog.Debug(ctx).
+		Stringer("manifest", m.Hash).
+		Int("layers", len(m.Layers)).
+		Msg("resolved reference")
+	return l.Index(ctx, m)
+}
@@ -0,0 +1,22 @@
+// Package sqlite provides a Store for libindex backed by a single SQLite
+// database file.
+//
+// The SQLite driver needs cgo, so it's kept out of libindex itself: only
+// programs importing this package need a C toolchain.
+package sqlite
+
+import (
+	"context"
+
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/sqlite"
+)
+
+// Open opens the SQLite database at the named path for use as
+// libindex.Opts.Store, creating it if needed.
+//
+// This is intended for single-process deployments and tools that want results
+// to persist without running a database server.
+func Open(ctx context.Context, path string) (indexer.Store, error) {
+	return sqlite.NewStore(ctx, path)
+}
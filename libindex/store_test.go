@@ -0,0 +1,87 @@
+package libindex
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/linux"
+	"github.com/quay/claircore/libindex/sqlite"
+	"github.com/quay/claircore/test"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	c, layers := test.ServeLayers(t, 2)
+
+	eco := &indexer.Ecosystem{
+		Name: "static",
+		PackageScanners: func(context.Context) ([]indexer.PackageScanner, error) {
+			return []indexer.PackageScanner{staticScanner{}}, nil
+		},
+		DistributionScanners: func(context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
+		RepositoryScanners:   func(context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		Coalescer: func(context.Context) (indexer.Coalescer, error) {
+			return linux.NewCoalescer(), nil
+		},
+	}
+	name := filepath.Join(t.TempDir(), "index.db")
+	m := &claircore.Manifest{
+		Hash:   digest("sqlite"),
+		Layers: layers,
+	}
+	open := func() *Libindex {
+		t.Helper()
+		s, err := sqlite.Open(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lib, err := New(ctx, &Opts{
+			Store:      s,
+			Ecosystems: []*indexer.Ecosystem{eco},
+		}, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lib
+	}
+
+	lib := open()
+	ir, err := lib.Index(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ir.Success {
+		t.Fatalf("index failed: %s", ir.Err)
+	}
+	if err := lib.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The report should persist across instances.
+	lib = open()
+	defer lib.Close(ctx)
+	got, ok, err := lib.IndexReport(ctx, m.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("missing stored report")
+	}
+	if got, want := len(got.Packages), 1; got != want {
+		t.Errorf("got: %d packages, want: %d", got, want)
+	}
+	ok, err = lib.store.ManifestScanned(ctx, m.Hash, lib.vscnrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("manifest not marked scanned")
+	}
+}
@@ -0,0 +1,346 @@
+package libindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex/driver"
+	"github.com/quay/claircore/pkg/blob"
+	"github.com/quay/claircore/pkg/containerstorage"
+)
+
+// Layers are fetched through a driver.Transport wrapped in Middleware. The
+// FetchArena's chain is, from the outside in:
+//
+//	Cache, VerifyDigest, any Middleware from Opts, rate limits, the Transport
+//
+// so that cache hits don't count against rate limits, and only verified
+// contents are added to the cache.
+
+// HTTPTransport returns a driver.Transport that fetches layers from their URI
+// using the provided client, sending the layer's Headers with the request.
+//
+// This is the Transport libindex uses by default.
+func HTTPTransport(c *http.Client) driver.Transport {
+	return &httpTransport{c: c}
+}
+
+type httpTransport struct {
+	c *http.Client
+}
+
+// Fetch implements driver.Transport.
+func (t *httpTransport) Fetch(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+	if l.URI == "" {
+		return nil, fmt.Errorf("empty uri for layer %v", l.Hash)
+	}
+	u, err := url.ParseRequestURI(l.URI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse remote path uri: %v", err)
+	}
+	req := &http.Request{
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Method:     http.MethodGet,
+		URL:        u,
+		Header:     l.Headers,
+	}
+	req = req.WithContext(ctx)
+	resp, err := t.c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetcher: request failed: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	default:
+		defer resp.Body.Close()
+		// Especially for 4xx errors, the response body may indicate what's going
+		// on, so include some of it in the error message. Capped at 256 bytes in
+		// order to not flood the log.
+		bodyStart, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		err := &statusError{
+			status: resp.Status,
+			body:   bodyStart,
+		}
+		switch {
+		case resp.StatusCode == http.StatusRequestTimeout,
+			resp.StatusCode == http.StatusTooManyRequests,
+			resp.StatusCode >= 500:
+			return nil, err
+		}
+		return nil, permanentError{err}
+	}
+	return &driver.Blob{
+		ReadCloser: resp.Body,
+		MediaType:  resp.Header.Get("content-type"),
+	}, nil
+}
+
+// StatusError is returned by the HTTP Transport for unexpected response
+// statuses.
+type statusError struct {
+	status string
+	body   []byte
+}
+
+func (e *statusError) Error() string {
+	if len(e.body) == 0 {
+		return fmt.Sprintf("fetcher: unexpected status code: %s", e.status)
+	}
+	return fmt.Sprintf("fetcher: unexpected status code: %s (body starts: %q)", e.status, e.body)
+}
+
+// FileTransport returns a driver.Transport that reads layers with "file" URIs
+// from the local filesystem, such as those in an OCI image layout (see
+// IndexLayout), and hands all other layers to "next".
+//
+// If "next" is nil, layers without "file" URIs are rejected.
+//
+// Any path the process can read is allowed, so this shouldn't be used for
+// manifests from untrusted callers. Libindex only reads local layers for
+// IndexLayout and IndexContainerStorage, or when Opts.LocalLayers is set.
+func FileTransport(next driver.Transport) driver.Transport {
+	return &fileTransport{next: next}
+}
+
+type fileTransport struct {
+	next driver.Transport
+}
+
+// Fetch implements driver.Transport.
+func (t *fileTransport) Fetch(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+	u, err := url.Parse(l.URI)
+	switch {
+	case err == nil && u.Scheme == "file":
+	case t.next != nil:
+		return t.next.Fetch(ctx, l)
+	default:
+		return nil, fmt.Errorf("fetcher: refusing to fetch non-file uri %q for layer %v", l.URI, l.Hash)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("fetcher: unsupported host in file uri %q", l.URI)
+	}
+	f, err := os.Open(filepath.FromSlash(u.Path))
+	if err != nil {
+		return nil, fmt.Errorf("fetcher: unable to open layer: %w", err)
+	}
+	return &driver.Blob{ReadCloser: f}, nil
+}
+
+// LocalKey is the Context key marking fetches that may read local layers.
+type localKey struct{}
+
+// WithLocalLayers returns a Context that allows the FetchArena to read layers
+// with "file" and "containers-storage" URIs.
+//
+// This is for manifests Libindex builds itself from local files, so the
+// layer URIs are known to be ones the caller asked to read.
+func withLocalLayers(ctx context.Context) context.Context {
+	return context.WithValue(ctx, localKey{}, true)
+}
+
+// LocalTransport reads layers with "file" and "containers-storage" URIs from the
+// local filesystem, if allowed for every fetch or by the Context, and hands
+// all other layers to "next". If "next" is nil, other layers are rejected.
+type localTransport struct {
+	next   driver.Transport
+	always bool
+}
+
+// Fetch implements driver.Transport.
+func (t *localTransport) Fetch(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+	u, err := url.Parse(l.URI)
+	local := err == nil && (u.Scheme == "file" || u.Scheme == containerstorage.Scheme)
+	switch {
+	case local && (t.always || ctx.Value(localKey{}) != nil):
+		return FileTransport(containerstorage.Transport(nil)).Fetch(ctx, l)
+	case local:
+		return nil, permanentError{fmt.Errorf("fetcher: reading local uri %q for layer %v not allowed", l.URI, l.Hash)}
+	case t.next == nil:
+		return nil, permanentError{fmt.Errorf("fetcher: refusing to fetch uri %q for layer %v", l.URI, l.Hash)}
+	}
+	return t.next.Fetch(ctx, l)
+}
+
+// Authorize returns a driver.Middleware that adds the headers returned by the
+// provided function to every fetch, replacing any of the layer's own headers
+// with the same names.
+//
+// Transports that make HTTP requests send the headers with them. Registry
+// token authentication for the default Transport is better handled by
+// Opts.RegistryAuth.
+func Authorize(f func(context.Context, *claircore.Layer) (http.Header, error)) driver.Middleware {
+	return func(next driver.Transport) driver.Transport {
+		return driver.TransportFunc(func(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+			h, err := f(ctx, l)
+			if err != nil {
+				return nil, fmt.Errorf("fetcher: unable to authorize: %w", err)
+			}
+			if len(h) == 0 {
+				return next.Fetch(ctx, l)
+			}
+			// Transports aren't allowed to modify the Layer, so a copy with
+			// the new headers is passed along.
+			c := *l
+			c.Headers = make(map[string][]string, len(l.Headers)+len(h))
+			for k, v := range l.Headers {
+				c.Headers[k] = v
+			}
+			for k, v := range h {
+				c.Headers[k] = v
+			}
+			return next.Fetch(ctx, &c)
+		})
+	}
+}
+
+// VerifyDigest is a driver.Middleware that checks a layer's contents against
+// its digest.
+//
+// The check happens as the contents are read: a mismatch is reported in place
+// of the io.EOF at the end, so callers must read the Blob completely. The
+// reported error wraps a *claircore.ChecksumError.
+func VerifyDigest(next driver.Transport) driver.Transport {
+	return driver.TransportFunc(func(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+		if l.Hash.Checksum() == nil {
+			return nil, fmt.Errorf("digest is empty")
+		}
+		b, err := next.Fetch(ctx, l)
+		if err != nil {
+			return nil, err
+		}
+		b.ReadCloser = &verifyReader{
+			ReadCloser: b.ReadCloser,
+			h:          l.Hash.Hash(),
+			want:       l.Hash,
+		}
+		return b, nil
+	})
+}
+
+// VerifyReader hashes everything read through it and compares the result
+// at EOF.
+type verifyReader struct {
+	io.ReadCloser
+	h    hash.Hash
+	want claircore.Digest
+}
+
+func (r *verifyReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.h.Write(b[:n])
+	if errors.Is(err, io.EOF) {
+		if err := r.want.Verify(r.h.Sum(nil)); err != nil {
+			return n, fmt.Errorf("fetcher: layer %v: %w", r.want, err)
+		}
+	}
+	return n, err
+}
+
+// Cache returns a driver.Middleware that serves layers from the provided
+// Store, and adds layers that aren't there as they're read.
+//
+// A layer is only added once its contents have been read completely without
+// error, so VerifyDigest should be inside Cache in a chain to keep unverified
+// contents out of it. Contents served from the Store are trusted.
+func Cache(s blob.Store) driver.Middleware {
+	return func(next driver.Transport) driver.Transport {
+		return driver.TransportFunc(func(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+			key := cacheKey(l)
+			rc, err := s.Get(ctx, key)
+			switch {
+			case errors.Is(err, nil):
+				zlog.Debug(ctx).Msg("layer found in cache")
+				return &driver.Blob{ReadCloser: rc}, nil
+			case errors.Is(err, blob.ErrNotExist):
+			default:
+				zlog.Warn(ctx).Err(err).Msg("unable to read layer from cache")
+			}
+			b, err := next.Fetch(ctx, l)
+			if err != nil {
+				return nil, err
+			}
+			pr, pw := io.Pipe()
+			done := make(chan error, 1)
+			go func() {
+				err := s.Put(ctx, key, pr)
+				pr.CloseWithError(err)
+				done <- err
+			}()
+			b.ReadCloser = &cacheWriter{
+				ctx:        ctx,
+				ReadCloser: b.ReadCloser,
+				pw:         pw,
+				done:       done,
+			}
+			return b, nil
+		})
+	}
+}
+
+// CacheKey is the key a layer is stored under in the cache.
+func cacheKey(l *claircore.Layer) string {
+	return blob.Join("layers", l.Hash.String())
+}
+
+// CacheWriter copies everything read through it into a pipe feeding a Put.
+//
+// The pipe is closed normally at EOF, committing the Put, and with an error
+// if reading fails or the reader is closed early, abandoning it.
+type cacheWriter struct {
+	ctx context.Context
+	io.ReadCloser
+	pw   *io.PipeWriter
+	done <-chan error
+	// Closed is set once the pipe is closed.
+	closed bool
+	// Failed is set if the Put stopped reading.
+	failed bool
+}
+
+// ErrIncomplete abandons a Put when the layer isn't read completely.
+var errIncomplete = errors.New("fetcher: layer not read completely")
+
+func (c *cacheWriter) Read(b []byte) (int, error) {
+	n, err := c.ReadCloser.Read(b)
+	if n > 0 && !c.failed {
+		if _, err := c.pw.Write(b[:n]); err != nil {
+			c.failed = true
+		}
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF):
+		c.finish(nil)
+	default:
+		c.finish(err)
+	}
+	return n, err
+}
+
+// Finish closes the pipe and waits for the Put to return.
+func (c *cacheWriter) finish(err error) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.pw.CloseWithError(err)
+	if err := <-c.done; err != nil && !errors.Is(err, errIncomplete) {
+		zlog.Warn(c.ctx).Err(err).Msg("unable to add layer to cache")
+	}
+}
+
+func (c *cacheWriter) Close() error {
+	c.finish(errIncomplete)
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,208 @@
+package libindex
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex/driver"
+	"github.com/quay/claircore/pkg/blob"
+)
+
+// MemTransport serves gzipped layers from memory, keyed by digest.
+type memTransport map[string][]byte
+
+func (m memTransport) Fetch(_ context.Context, l *claircore.Layer) (*driver.Blob, error) {
+	b, ok := m[l.Hash.String()]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &driver.Blob{ReadCloser: io.NopCloser(bytes.NewReader(b))}, nil
+}
+
+// MemLayer returns a gzipped layer holding a single file, and a Layer
+// describing it.
+func memLayer(t *testing.T, name string) ([]byte, *claircore.Layer) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Size: int64(len(name)), Mode: 0644}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(tw, name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	d, err := claircore.NewDigest("sha256", sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), &claircore.Layer{Hash: d, URI: "mem:" + name}
+}
+
+func TestFetchTransport(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	tr := memTransport{}
+	var ls []*claircore.Layer
+	for _, n := range []string{"a", "b", "c"} {
+		b, l := memLayer(t, n)
+		tr[l.Hash.String()] = b
+		ls = append(ls, l)
+	}
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) driver.Middleware {
+		return func(next driver.Transport) driver.Transport {
+			return driver.TransportFunc(func(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+				mu.Lock()
+				calls = append(calls, name+":"+l.Headers["X-Token"][0])
+				mu.Unlock()
+				return next.Fetch(ctx, l)
+			})
+		}
+	}
+	auth := Authorize(func(context.Context, *claircore.Layer) (http.Header, error) {
+		return http.Header{"X-Token": {"secret"}}, nil
+	})
+
+	a := &FetchArena{}
+	a.Init(nil, t.TempDir())
+	a.SetTransport(tr)
+	a.Use(auth, record("mw"))
+	f := a.Fetcher()
+	if err := f.Fetch(ctx, ls); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, l := range ls {
+		if !l.Fetched() {
+			t.Errorf("%v: not realized", l.Hash)
+		}
+		if l.Headers != nil {
+			t.Errorf("%v: layer modified: %v", l.Hash, l.Headers)
+		}
+	}
+	if got, want := len(calls), len(ls); got != want {
+		t.Errorf("got: %d calls, want: %d", got, want)
+	}
+	for _, c := range calls {
+		if c != "mw:secret" {
+			t.Errorf("unexpected call: %q", c)
+		}
+	}
+}
+
+func TestFetchTransportVerify(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	good, l := memLayer(t, "good")
+	bad, _ := memLayer(t, "bad")
+	cache, err := blob.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &FetchArena{}
+	a.Init(nil, t.TempDir())
+	a.SetCache(cache)
+	a.SetTransport(memTransport{l.Hash.String(): bad})
+	f := a.Fetcher()
+	err = f.Fetch(ctx, []*claircore.Layer{l})
+	f.Close()
+	if err == nil || !strings.Contains(err.Error(), "validation failed") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := cache.Get(ctx, cacheKey(l)); !errors.Is(err, blob.ErrNotExist) {
+		t.Errorf("unverified layer cached: %v", err)
+	}
+
+	a.SetTransport(memTransport{l.Hash.String(): good})
+	f = a.Fetcher()
+	if err := f.Fetch(ctx, []*claircore.Layer{l}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	rc, err := cache.Get(ctx, cacheKey(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, good) {
+		t.Error("cached contents differ from fetched contents")
+	}
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	mw := func(name string) driver.Middleware {
+		return func(next driver.Transport) driver.Transport {
+			return driver.TransportFunc(func(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+				order = append(order, name)
+				return next.Fetch(ctx, l)
+			})
+		}
+	}
+	base := driver.TransportFunc(func(context.Context, *claircore.Layer) (*driver.Blob, error) {
+		order = append(order, "transport")
+		return nil, nil
+	})
+	driver.Chain(base, mw("outer"), mw("inner")).Fetch(context.Background(), &claircore.Layer{})
+	if got, want := strings.Join(order, ","), "outer,inner,transport"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestFetchLocal(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	b, l := memLayer(t, "local")
+	name := filepath.Join(t.TempDir(), "layer.tar.gz")
+	if err := os.WriteFile(name, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	u := url.URL{Scheme: "file", Path: name}
+	l.URI = u.String()
+
+	fetch := func(ctx context.Context, local bool) error {
+		a := &FetchArena{}
+		a.Init(http.DefaultClient, t.TempDir())
+		a.AllowLocal(local)
+		f := a.Fetcher()
+		defer f.Close()
+		c := *l
+		return f.Fetch(ctx, []*claircore.Layer{&c})
+	}
+	if err := fetch(ctx, false); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := fetch(withLocalLayers(ctx), false); err != nil {
+		t.Error(err)
+	}
+	if err := fetch(ctx, true); err != nil {
+		t.Error(err)
+	}
+}
@@ -66,3 +66,14 @@ type VersionFilter interface {
 	// be completely normalized into a claircore.Version.
 	VersionAuthoritative() bool
 }
+
+// Resolver is an additional interface that a Matcher can implement to refine
+// the IndexRecords it's interested in before the vulnstore is queried.
+//
+// This allows a Matcher to fill in information the indexer was unable to
+// determine, such as a package's canonical name. The returned records are used
+// for the query and passed to Vulnerable. Package IDs must be preserved, so
+// that results are reported against the original packages.
+type Resolver interface {
+	Resolve(ctx context.Context, records []*claircore.IndexRecord) ([]*claircore.IndexRecord, error)
+}
@@ -0,0 +1,21 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+)
+
+// Policy decides whether a manifest is acceptable, given its
+// VulnerabilityReport.
+//
+// Policies are evaluated after matching and enrichment, and their verdict is
+// included in the report. This allows gates, such as in CI, to be built on
+// the report alone.
+type Policy interface {
+	// Name identifies the policy in verdicts.
+	Name() string
+	// Evaluate returns a verdict for the report. The report must not be
+	// modified.
+	Evaluate(context.Context, *claircore.VulnerabilityReport) (*claircore.PolicyVerdict, error)
+}
@@ -0,0 +1,94 @@
+package libvuln
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+type staticUpdater struct{}
+
+func (staticUpdater) Name() string { return "static" }
+func (staticUpdater) Fetch(context.Context, driver.Fingerprint) (io.ReadCloser, driver.Fingerprint, error) {
+	return io.NopCloser(strings.NewReader("")), "1", nil
+}
+func (staticUpdater) Parse(context.Context, io.ReadCloser) ([]*claircore.Vulnerability, error) {
+	return []*claircore.Vulnerability{{
+		Name:    "CVE-0000-0001",
+		Updater: "static",
+		Package: &claircore.Package{Name: "static", Kind: claircore.BINARY},
+	}}, nil
+}
+
+type staticMatcher struct{}
+
+func (staticMatcher) Name() string                         { return "static" }
+func (staticMatcher) Filter(r *claircore.IndexRecord) bool { return r.Package.Name == "static" }
+func (staticMatcher) Query() []driver.MatchConstraint      { return nil }
+func (staticMatcher) Vulnerable(context.Context, *claircore.IndexRecord, *claircore.Vulnerability) (bool, error) {
+	return true, nil
+}
+
+func TestEphemeral(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = zlog.Test(ctx, t)
+	lib, err := New(ctx, &Opts{
+		Ephemeral:                true,
+		Client:                   http.DefaultClient,
+		UpdaterSets:              []string{},
+		Updaters:                 []driver.Updater{&staticUpdater{}},
+		MatcherNames:             []string{},
+		Matchers:                 []driver.Matcher{&staticMatcher{}},
+		DisableBackgroundUpdates: true,
+		RecordSummaries:          true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lib.Close(ctx)
+
+	if ok, err := lib.Initialized(ctx); err != nil || ok {
+		t.Errorf("unexpected initialized state: %v, %v", ok, err)
+	}
+	if err := lib.FetchUpdates(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := lib.Initialized(ctx); err != nil || !ok {
+		t.Errorf("unexpected initialized state: %v, %v", ok, err)
+	}
+
+	pkg := &claircore.Package{ID: "1", Name: "static", Kind: claircore.BINARY}
+	ir := &claircore.IndexReport{
+		Hash:     claircore.MustParseDigest("sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		Packages: map[string]*claircore.Package{pkg.ID: pkg},
+		Environments: map[string][]*claircore.Environment{
+			pkg.ID: {{PackageDB: "static"}},
+		},
+		Success: true,
+	}
+	vr, err := lib.Scan(ctx, ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(vr.PackageVulnerabilities[pkg.ID]), 1; got != want {
+		t.Errorf("got: %d vulnerabilities, want: %d", got, want)
+	}
+	sums, err := lib.Summaries(ctx, ir.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sums) != 1 {
+		t.Errorf("got: %d summaries, want: 1", len(sums))
+	}
+	if h := lib.Healthz(ctx); !h.OK() {
+		t.Errorf("unhealthy: %+v", h)
+	}
+}
@@ -0,0 +1,67 @@
+package libvuln
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/libvuln/migrations"
+	"github.com/quay/claircore/pkg/health"
+)
+
+// Health reports the state of Libvuln's dependencies.
+type Health struct {
+	// Store reports whether the database is reachable.
+	Store health.Check `json:"store"`
+	// Migrations reports whether the database schema is up to date.
+	Migrations health.Check `json:"migrations"`
+	// Locks reports whether the lock provider is connected.
+	Locks health.Check `json:"locks"`
+	// Updaters reports whether the latest update operations could be
+	// examined.
+	Updaters health.Check `json:"updaters"`
+	// LastUpdate is the time of the latest successful update operation, keyed
+	// by updater.
+	LastUpdate map[string]time.Time `json:"last_update"`
+}
+
+// OK reports whether all the checks passed.
+func (h *Health) OK() bool {
+	return h.Store.OK && h.Migrations.OK && h.Locks.OK && h.Updaters.OK
+}
+
+// Healthz probes Libvuln's dependencies, for use in service health and
+// readiness checks.
+//
+// The provided Context bounds the time spent probing.
+func (l *Libvuln) Healthz(ctx context.Context) *Health {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/Libvuln.Healthz"))
+	var h Health
+	if l.pool == nil {
+		h.Store = health.Skipped("in-memory store")
+		h.Migrations = health.Skipped("in-memory store")
+	} else {
+		h.Store = health.Ping(ctx, l.pool)
+		h.Migrations = health.Migrations(ctx, l.pool, migrations.MigrationTable, migrations.Migrations)
+	}
+	if c, ok := l.locks.(interface{ Err() error }); ok {
+		h.Locks = health.Err(c.Err())
+	} else {
+		h.Locks = health.Skipped("process-local locks")
+	}
+	ops, err := l.store.GetLatestUpdateRefs(ctx, driver.VulnerabilityKind)
+	h.Updaters = health.Err(err)
+	if err == nil {
+		h.LastUpdate = make(map[string]time.Time, len(ops))
+		for name, ops := range ops {
+			if len(ops) != 0 {
+				h.LastUpdate[name] = ops[0].Date
+			}
+		}
+	}
+	return &h
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/vulnstore"
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/clock"
 )
 
 var _ vulnstore.Updater = (*Store)(nil)
@@ -160,15 +161,19 @@ func (s *Store) Entries() map[uuid.UUID]*Entry {
 }
 
 // UpdateVulnerabilities records all provided vulnerabilities.
-func (s *Store) UpdateVulnerabilities(_ context.Context, updater string, fingerprint driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error) {
-	now := time.Now()
+//
+// The update operation's date is taken from the Clock in the Context. If it's
+// not clock.System, the operation's ref is derived from its updater,
+// fingerprint, and date, so that the same updates produce the same output.
+func (s *Store) UpdateVulnerabilities(ctx context.Context, updater string, fingerprint driver.Fingerprint, vulns []*claircore.Vulnerability) (uuid.UUID, error) {
+	now := clock.Now(ctx)
 	e := Entry{
 		Vuln: vulns,
 	}
 	e.Date = now
 	e.Updater = updater
 	e.Fingerprint = fingerprint
-	ref := uuid.New() // God help you if this wasn't unique.
+	ref := newRef(ctx, updater, fingerprint, now)
 	s.Lock()
 	defer s.Unlock()
 	s.latest[driver.VulnerabilityKind] = ref
@@ -266,15 +271,17 @@ func (s *Store) GC(_ context.Context, _ int) (int64, error) {
 // UpdateEnrichments creates a new EnrichmentUpdateOperation, inserts the provided
 // EnrichmentRecord(s), and ensures enrichments from previous updates are not
 // queries by clients.
+//
+// The date and ref are chosen as with UpdateVulnerabilities.
 func (s *Store) UpdateEnrichments(ctx context.Context, kind string, fp driver.Fingerprint, es []driver.EnrichmentRecord) (uuid.UUID, error) {
-	now := time.Now()
+	now := clock.Now(ctx)
 	e := Entry{
 		Enrichment: es,
 	}
 	e.Date = now
 	e.Updater = kind
 	e.Fingerprint = fp
-	ref := uuid.New() // God help you if this wasn't unique.
+	ref := newRef(ctx, kind, fp, now)
 	s.Lock()
 	defer s.Unlock()
 	s.latest[driver.EnrichmentKind] = ref
@@ -288,3 +295,11 @@ func (s *Store) UpdateEnrichments(ctx context.Context, kind string, fp driver.Fi
 	}}, s.ops[kind]...)
 	return ref, nil
 }
+
+// NewRef returns the ref for a new update operation.
+func newRef(ctx context.Context, updater string, fp driver.Fingerprint, t time.Time) uuid.UUID {
+	if clock.FromContext(ctx) == clock.System {
+		return uuid.New() // God help you if this wasn't unique.
+	}
+	return uuid.NewSHA1(uuid.Nil, []byte(updater+"\x00"+string(fp)+"\x00"+t.UTC().Format(time.RFC3339Nano)))
+}
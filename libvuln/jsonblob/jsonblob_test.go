@@ -1,15 +1,20 @@
 package jsonblob
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/clock"
 	"github.com/quay/claircore/test"
 )
 
@@ -71,3 +76,40 @@ func TestRoundtrip(t *testing.T) {
 		t.Error(cmp.Diff(got, vs))
 	}
 }
+
+// TestFixedClock checks that the same updates made with a fixed Clock are
+// recorded identically.
+func TestFixedClock(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	ctx := clock.WithClock(context.Background(), clock.Fixed(now))
+	vs := test.GenUniqueVulnerabilities(10, "test")
+
+	var out [2]bytes.Buffer
+	var refs [2]uuid.UUID
+	for i := range out {
+		s, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs[i], err = s.UpdateVulnerabilities(ctx, "test", "fp", vs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops, err := s.GetUpdateOperations(ctx, driver.VulnerabilityKind, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ops["test"][0].Date; !got.Equal(now) {
+			t.Errorf("got: %v, want: %v", got, now)
+		}
+		if err := s.Store(&out[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if refs[0] != refs[1] {
+		t.Errorf("refs differ: %v, %v", refs[0], refs[1])
+	}
+	if !bytes.Equal(out[0].Bytes(), out[1].Bytes()) {
+		t.Error("output differs")
+	}
+}
@@ -2,6 +2,7 @@ package libvuln
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 
@@ -15,11 +16,14 @@ import (
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/matcher"
 	"github.com/quay/claircore/internal/vulnstore"
+	"github.com/quay/claircore/internal/vulnstore/memory"
 	"github.com/quay/claircore/internal/vulnstore/postgres"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/libvuln/updates"
 	"github.com/quay/claircore/matchers"
+	"github.com/quay/claircore/pkg/clock"
 	"github.com/quay/claircore/pkg/ctxlock"
+	"github.com/quay/claircore/pkg/reportsig"
 )
 
 // Libvuln exports methods for scanning an IndexReport and created
@@ -30,11 +34,15 @@ import (
 type Libvuln struct {
 	store           vulnstore.Store
 	pool            *pgxpool.Pool
-	locks           *ctxlock.Locker
+	locks           updates.LockSource
 	matchers        []driver.Matcher
 	enrichers       []driver.Enricher
 	updateRetention int
 	updaters        *updates.Manager
+	signer          reportsig.Signer
+	summarize       bool
+	policy          driver.Policy
+	clock           clock.Clock
 }
 
 // New creates a new instance of the Libvuln library
@@ -47,22 +55,35 @@ func New(ctx context.Context, opts *Opts) (*Libvuln, error) {
 		return nil, err
 	}
 
-	zlog.Info(ctx).
-		Int32("count", opts.MaxConnPool).
-		Msg("initializing store")
-	if err := opts.migrations(ctx); err != nil {
-		return nil, err
-	}
-	pool, err := opts.pool(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	l := &Libvuln{
-		store:           postgres.NewVulnStore(pool),
-		pool:            pool,
 		updateRetention: opts.UpdateRetention,
 		enrichers:       opts.Enrichers,
+		signer:          opts.Signer,
+		summarize:       opts.RecordSummaries,
+		policy:          opts.Policy,
+		clock:           opts.Clock,
+	}
+	if opts.Ephemeral {
+		zlog.Info(ctx).Msg("ephemeral mode: vulnerabilities kept in memory")
+		l.store = memory.NewStore()
+		l.locks = updates.NewLocalLockSource()
+	} else {
+		zlog.Info(ctx).
+			Int32("count", opts.MaxConnPool).
+			Msg("initializing store")
+		if err := opts.migrations(ctx); err != nil {
+			return nil, err
+		}
+		pool, err := opts.pool(ctx)
+		if err != nil {
+			return nil, err
+		}
+		l.store = postgres.NewVulnStore(pool)
+		l.pool = pool
+		l.locks, err = ctxlock.New(ctx, pool)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// create matchers based on the provided config.
@@ -71,6 +92,7 @@ func New(ctx context.Context, opts *Opts) (*Libvuln, error) {
 		matchers.WithEnabled(opts.MatcherNames),
 		matchers.WithConfigs(opts.MatcherConfigs),
 		matchers.WithOutOfTree(opts.Matchers),
+		matchers.WithKernel(opts.Kernel),
 	)
 	if err != nil {
 		return nil, err
@@ -79,13 +101,9 @@ func New(ctx context.Context, opts *Opts) (*Libvuln, error) {
 	zlog.Info(ctx).Array("matchers", matcherLog(l.matchers)).Msg("matchers created")
 
 	// create update manager
-	locks, err := ctxlock.New(ctx, pool)
-	if err != nil {
-		return nil, err
-	}
 	l.updaters, err = updates.NewManager(ctx,
 		l.store,
-		locks,
+		l.locks,
 		opts.Client,
 		updates.WithBatchSize(opts.UpdateWorkers),
 		updates.WithInterval(opts.UpdateInterval),
@@ -93,6 +111,7 @@ func New(ctx context.Context, opts *Opts) (*Libvuln, error) {
 		updates.WithConfigs(opts.UpdaterConfigs),
 		updates.WithOutOfTree(opts.Updaters),
 		updates.WithGC(opts.UpdateRetention),
+		updates.WithClock(opts.Clock),
 	)
 	if err != nil {
 		return nil, err
@@ -107,8 +126,12 @@ func New(ctx context.Context, opts *Opts) (*Libvuln, error) {
 }
 
 func (l *Libvuln) Close(ctx context.Context) error {
-	l.locks.Close(ctx)
-	l.pool.Close()
+	if c, ok := l.locks.(interface{ Close(context.Context) error }); ok {
+		c.Close(ctx)
+	}
+	if l.pool != nil {
+		l.pool.Close()
+	}
 	return nil
 }
 
@@ -118,11 +141,99 @@ func (l *Libvuln) FetchUpdates(ctx context.Context) error {
 }
 
 // Scan creates a VulnerabilityReport given a manifest's IndexReport.
+//
+// If Opts.RecordSummaries was set, a VulnerabilitySummary of the report is
+// recorded for retrieval with Summaries. If Opts.Policy was set, its verdict is
+// included in the report; failing to evaluate the policy fails the Scan.
 func (l *Libvuln) Scan(ctx context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	ctx = clock.WithClock(ctx, l.clock)
+	var vr *claircore.VulnerabilityReport
+	var err error
 	if s, ok := l.store.(matcher.Store); ok {
-		return matcher.EnrichedMatch(ctx, ir, l.matchers, l.enrichers, s)
+		vr, err = matcher.EnrichedMatch(ctx, ir, l.matchers, l.enrichers, s)
+	} else {
+		vr, err = matcher.Match(ctx, ir, l.matchers, l.store)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := evaluatePolicy(ctx, l.policy, vr); err != nil {
+		return nil, err
 	}
-	return matcher.Match(ctx, ir, l.matchers, l.store)
+	if l.summarize {
+		l.recordSummary(ctx, vr)
+	}
+	return vr, nil
+}
+
+// EvaluatePolicy evaluates the policy, if any, against the report and records
+// the verdict in it.
+func evaluatePolicy(ctx context.Context, p driver.Policy, vr *claircore.VulnerabilityReport) error {
+	if p == nil {
+		return nil
+	}
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/evaluatePolicy"),
+		label.String("policy", p.Name()))
+	v, err := p.Evaluate(ctx, vr)
+	if err != nil {
+		return fmt.Errorf("libvuln: unable to evaluate policy %q: %w", p.Name(), err)
+	}
+	v.Policy = p.Name()
+	v.Sort()
+	zlog.Debug(ctx).
+		Bool("pass", v.Pass).
+		Int("violations", len(v.Violations)).
+		Msg("evaluated policy")
+	vr.Policy = v
+	return nil
+}
+
+// RecordSummary stores a summary of the VulnerabilityReport, if the store
+// supports it. Failures are only logged, as the report itself is still good.
+func (l *Libvuln) recordSummary(ctx context.Context, vr *claircore.VulnerabilityReport) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/Libvuln.recordSummary"))
+	s, ok := l.store.(vulnstore.Summarizer)
+	if !ok {
+		return
+	}
+	sum := claircore.Summarize(vr)
+	sum.Updated = clock.Now(ctx)
+	if err := s.SetSummary(ctx, sum); err != nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Stringer("manifest", vr.Hash).
+			Msg("unable to record vulnerability summary")
+	}
+}
+
+// ErrSummaryUnsupported is returned by Summaries if the configured store
+// can't record vulnerability summaries.
+var ErrSummaryUnsupported = errors.New("libvuln: store does not support vulnerability summaries")
+
+// Summaries reports the severity counts recorded for the provided manifests
+// by previous calls to Scan, without re-running any matchers. Manifests
+// without a recorded summary are omitted.
+//
+// Summaries are only recorded if Opts.RecordSummaries is set.
+func (l *Libvuln) Summaries(ctx context.Context, ms ...claircore.Digest) ([]claircore.VulnerabilitySummary, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/Libvuln.Summaries"))
+	s, ok := l.store.(vulnstore.Summarizer)
+	if !ok {
+		return nil, ErrSummaryUnsupported
+	}
+	return s.GetSummaries(ctx, ms)
+}
+
+// SignVulnerabilityReport returns the canonical encoding of the
+// VulnerabilityReport and a detached signature over it, made with the
+// configured Signer.
+//
+// reportsig.ErrNoSigner is returned if Opts.Signer isn't set.
+func (l *Libvuln) SignVulnerabilityReport(ctx context.Context, vr *claircore.VulnerabilityReport) (*reportsig.Detached, error) {
+	return reportsig.Sign(ctx, l.signer, vr)
 }
 
 // UpdateOperations returns UpdateOperations in date descending order keyed by the
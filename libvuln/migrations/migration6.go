@@ -0,0 +1,9 @@
+package migrations
+
+const (
+	// this migration adds a column recording whether a vulnerability has
+	// been withdrawn by its source
+	migration6 = `
+ALTER TABLE vuln ADD COLUMN IF NOT EXISTS withdrawn boolean NOT NULL DEFAULT false;
+`
+)
@@ -0,0 +1,9 @@
+package migrations
+
+const (
+	// this migration adds a column recording when a vulnerability was last
+	// modified by its source
+	migration7 = `
+ALTER TABLE vuln ADD COLUMN IF NOT EXISTS modified timestamptz;
+`
+)
@@ -0,0 +1,18 @@
+package migrations
+
+const (
+	// this migration adds a table recording per-manifest counts of
+	// vulnerabilities by severity
+	migration8 = `
+CREATE TABLE IF NOT EXISTS manifest_summary (
+	manifest_hash TEXT PRIMARY KEY,
+	updated       timestamptz NOT NULL,
+	unknown       integer NOT NULL DEFAULT 0,
+	negligible    integer NOT NULL DEFAULT 0,
+	low           integer NOT NULL DEFAULT 0,
+	medium        integer NOT NULL DEFAULT 0,
+	high          integer NOT NULL DEFAULT 0,
+	critical      integer NOT NULL DEFAULT 0
+);
+`
+)
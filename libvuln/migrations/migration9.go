@@ -0,0 +1,9 @@
+package migrations
+
+const (
+	// this migration adds a column recording why a vulnerability has no fix,
+	// if its source says
+	migration9 = `
+ALTER TABLE vuln ADD COLUMN IF NOT EXISTS fix_state TEXT NOT NULL DEFAULT '';
+`
+)
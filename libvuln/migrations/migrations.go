@@ -46,4 +46,32 @@ var Migrations = []migrate.Migration{
 			return err
 		},
 	},
+	{
+		ID: 6,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration6)
+			return err
+		},
+	},
+	{
+		ID: 7,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration7)
+			return err
+		},
+	},
+	{
+		ID: 8,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration8)
+			return err
+		},
+	},
+	{
+		ID: 9,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration9)
+			return err
+		},
+	},
 }
@@ -11,14 +11,22 @@ import (
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/jackc/pgx/v4/stdlib"
-	"github.com/remind101/migrate"
 	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/label"
 
 	"github.com/quay/zlog"
 
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/pgmigrate"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/libvuln/migrations"
+	"github.com/quay/claircore/matchers/kernel"
+	"github.com/quay/claircore/pkg/clock"
+	"github.com/quay/claircore/pkg/feedmirror"
+	"github.com/quay/claircore/pkg/headers"
+	"github.com/quay/claircore/pkg/pgtrace"
+	"github.com/quay/claircore/pkg/reportsig"
+	"github.com/quay/claircore/pkg/retry"
 )
 
 const (
@@ -42,6 +50,19 @@ type Opts struct {
 	UpdateInterval time.Duration
 	// Determines if Libvuln will manage database migrations
 	Migrations bool
+	// RejectNewerSchema causes New to fail if the database has migrations
+	// applied that this version doesn't know about, as happens when a newer
+	// version has already migrated it. Otherwise, this is only logged.
+	RejectNewerSchema bool
+	// QueryTrace, if set, configures reporting of database query durations
+	// and logging of slow queries.
+	QueryTrace *pgtrace.Config
+	// Ephemeral configures libvuln to keep the vulnerability database in
+	// memory instead of a database, for the life of the Libvuln. This is
+	// useful for tests and one-shot CLI or CI use, where updaters are run
+	// once with FetchUpdates. ConnString, MaxConnPool, and Migrations are
+	// ignored when set.
+	Ephemeral bool
 	// A slice of strings representing which updaters libvuln will create.
 	//
 	// If nil all default UpdaterSets will be used.
@@ -92,6 +113,21 @@ type Opts struct {
 	// This list will me merged with the default matchers.
 	Matchers []driver.Matcher
 
+	// Ecosystems enables the updater sets and matchers named by each
+	// Ecosystem, in addition to those in UpdaterSets and MatcherNames.
+	//
+	// Passing the same Ecosystems given to libindex enables matching for
+	// everything indexed. If UpdaterSets or MatcherNames is nil, all the
+	// defaults are already used and the corresponding names are ignored.
+	Ecosystems []*indexer.Ecosystem
+
+	// Kernel, if set, changes how kernel packages are matched. Containers run
+	// on their host's kernel, so by default findings against kernel packages
+	// in images are suppressed; the Config can instead supply the version of
+	// the kernel actually running, as can kernel.WithRunning on the Context
+	// passed to Libvuln.Scan.
+	Kernel *kernel.Config
+
 	// Enrichers is a slice of enrichers to use with all VulnerabilityReport
 	// requests.
 	Enrichers []driver.Enricher
@@ -112,11 +148,41 @@ type Opts struct {
 	DisableBackgroundUpdates bool
 
 	// UpdaterConfigs is a map of functions for configuration of Updaters.
+	//
+	// In addition to its own configuration, every Updater accepts a
+	// "severity_floor" key naming the lowest normalized severity of
+	// vulnerability to store, such as "Low".
 	UpdaterConfigs map[string]driver.ConfigUnmarshaler
 
 	// Client is an http.Client for use by all updaters. If unset,
 	// http.DefaultClient will be used.
 	Client *http.Client
+
+	// RetryPolicy, if set, is applied to all requests made with Client.
+	RetryPolicy *retry.Policy
+	// RequestHeaders, if set, configures the User-Agent and additional
+	// headers set on all requests made with Client.
+	RequestHeaders *headers.Config
+	// FeedMirror, if set, records the documents fetched by updaters, so that
+	// this instance can serve them to others acting as a mirror. See the
+	// feedmirror package for details.
+	FeedMirror *feedmirror.Mirror
+	// Signer, if set, is used by Libvuln.SignVulnerabilityReport to produce
+	// detached signatures over VulnerabilityReports.
+	Signer reportsig.Signer
+	// RecordSummaries causes Libvuln.Scan to record a count of the
+	// vulnerabilities of each severity found for the manifest, which can be
+	// retrieved cheaply in bulk with Libvuln.Summaries.
+	RecordSummaries bool
+	// Policy, if set, is evaluated by Libvuln.Scan against every
+	// VulnerabilityReport, and its verdict included in the report. The
+	// policy package provides a simple rule language.
+	Policy driver.Policy
+	// Clock, if set, is used in place of the system clock for the dates
+	// recorded by updates and vulnerability summaries, and is passed to
+	// matchers and enrichers in the Context (see clock.FromContext). Setting a
+	// fixed Clock makes update runs reproducible.
+	Clock clock.Clock
 }
 
 // parse is an internal method for constructing
@@ -126,7 +192,7 @@ func (o *Opts) parse(ctx context.Context) error {
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "libvuln/Opts.parse"))
 	// required
-	if o.ConnString == "" {
+	if o.ConnString == "" && !o.Ephemeral {
 		return fmt.Errorf("no connection string provided")
 	}
 	if o.UpdateRetention == 1 || o.UpdateRetention < 0 {
@@ -155,6 +221,23 @@ func (o *Opts) parse(ctx context.Context) error {
 			Msg("using default HTTP client; this will become an error in the future")
 		o.Client = http.DefaultClient // TODO(hank) Remove DefaultClient
 	}
+	if o.RequestHeaders != nil {
+		o.Client = headers.Client(o.Client, o.RequestHeaders)
+	}
+	if o.RetryPolicy != nil {
+		o.Client = retry.Client(o.Client, o.RetryPolicy)
+	}
+	if o.FeedMirror != nil {
+		o.Client = o.FeedMirror.Client(o.Client)
+	}
+	for _, e := range o.Ecosystems {
+		if o.UpdaterSets != nil {
+			o.UpdaterSets = mergeNames(o.UpdaterSets, e.UpdaterSets)
+		}
+		if o.MatcherNames != nil {
+			o.MatcherNames = mergeNames(o.MatcherNames, e.Matchers)
+		}
+	}
 	if o.UpdaterConfigs == nil {
 		o.UpdaterConfigs = make(map[string]driver.ConfigUnmarshaler)
 	}
@@ -169,6 +252,9 @@ func (o *Opts) pool(ctx context.Context) (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("failed to parse ConnString: %v", err)
 	}
 	cfg.MaxConns = o.MaxConnPool
+	if o.QueryTrace != nil {
+		pgtrace.Configure(cfg.ConnConfig, o.QueryTrace)
+	}
 
 	pool, err := pgxpool.ConnectConfig(ctx, cfg)
 	if err != nil {
@@ -177,10 +263,10 @@ func (o *Opts) pool(ctx context.Context) (*pgxpool.Pool, error) {
 	return pool, nil
 }
 
-// Migrations performs migrations if the configuration asks for it.
-func (o *Opts) migrations(_ context.Context) error {
-	// The migrate package doesn't use the context, which is... disconcerting.
-	if !o.Migrations {
+// Migrations performs migrations if the configuration asks for it, or checks
+// the schema version if RejectNewerSchema is set.
+func (o *Opts) migrations(ctx context.Context) error {
+	if !o.Migrations && !o.RejectNewerSchema {
 		return nil
 	}
 	cfg, err := pgx.ParseConfig(o.ConnString)
@@ -193,11 +279,27 @@ func (o *Opts) migrations(_ context.Context) error {
 	}
 	defer db.Close()
 
-	migrator := migrate.NewPostgresMigrator(db)
-	migrator.Table = migrations.MigrationTable
-	if err := migrator.Exec(migrate.Up, migrations.Migrations...); err != nil {
-		return err
+	migrator := pgmigrate.Migrator{
+		Table:      migrations.MigrationTable,
+		Migrations: migrations.Migrations,
+		Strict:     o.RejectNewerSchema,
+	}
+	if !o.Migrations {
+		return migrator.Check(ctx, db)
 	}
+	return migrator.Up(ctx, db)
+}
 
-	return nil
+// MergeNames appends the names in "add" not already in "to".
+func mergeNames(to, add []string) []string {
+Add:
+	for _, n := range add {
+		for _, m := range to {
+			if n == m {
+				continue Add
+			}
+		}
+		to = append(to, n)
+	}
+	return to
 }
@@ -0,0 +1,37 @@
+package libvuln
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/java"
+	"github.com/quay/claircore/python"
+)
+
+func TestEcosystems(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	o := Opts{
+		ConnString:   "host=localhost",
+		Client:       http.DefaultClient,
+		UpdaterSets:  []string{"rhel"},
+		MatcherNames: []string{"rhel", "python"},
+		Ecosystems: []*indexer.Ecosystem{
+			python.NewEcosystem(ctx),
+			java.NewEcosystem(ctx),
+		},
+	}
+	if err := o.parse(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := o.UpdaterSets, []string{"rhel", "pyupio"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got, want := o.MatcherNames, []string{"rhel", "python", "java"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
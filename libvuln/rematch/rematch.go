@@ -0,0 +1,363 @@
+// Package rematch provides a background component that re-matches stored
+// manifests when vulnerability updates affect them.
+//
+// After each update operation, the Scheduler finds the stored manifests
+// affected by the vulnerabilities added or removed by the update, generates a
+// fresh VulnerabilityReport for each, and hands a Delta describing the change
+// to a Sink. This lets integrators keep reports fresh without building their
+// own loops around the update diff APIs.
+package rematch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// DefaultInterval is how often the Scheduler checks for new update operations
+// if not configured otherwise.
+const DefaultInterval = 5 * time.Minute
+
+// Indexer is the subset of libindex.Libindex used by the Scheduler.
+type Indexer interface {
+	AffectedManifests(context.Context, []claircore.Vulnerability) (*claircore.AffectedManifests, error)
+	IndexReport(context.Context, claircore.Digest) (*claircore.IndexReport, bool, error)
+}
+
+// Matcher is the subset of libvuln.Libvuln used by the Scheduler.
+type Matcher interface {
+	Scan(context.Context, *claircore.IndexReport) (*claircore.VulnerabilityReport, error)
+	LatestUpdateOperations(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error)
+	UpdateOperations(context.Context, driver.UpdateKind, ...string) (map[string][]driver.UpdateOperation, error)
+	UpdateDiff(ctx context.Context, prev, cur uuid.UUID) (*driver.UpdateDiff, error)
+}
+
+// Delta describes how an update operation changed a manifest's
+// VulnerabilityReport.
+type Delta struct {
+	// Manifest is the manifest that was re-matched.
+	Manifest claircore.Digest `json:"manifest"`
+	// Update is the update operation that caused the re-match.
+	Update driver.UpdateOperation `json:"update"`
+	// Report is the new VulnerabilityReport for the manifest.
+	Report *claircore.VulnerabilityReport `json:"report"`
+	// Added are the vulnerabilities added by the update that now affect the
+	// manifest.
+	Added []*claircore.Vulnerability `json:"added"`
+	// Removed are the vulnerabilities removed by the update that affected
+	// the manifest.
+	Removed []*claircore.Vulnerability `json:"removed"`
+}
+
+// Sink receives Deltas, to persist or forward them.
+//
+// Deliver may be called concurrently. An error is logged and does not stop
+// the delivery of other Deltas.
+type Sink interface {
+	Deliver(context.Context, *Delta) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(context.Context, *Delta) error
+
+// Deliver implements Sink.
+func (f SinkFunc) Deliver(ctx context.Context, d *Delta) error { return f(ctx, d) }
+
+// Scheduler re-matches stored manifests after update operations.
+//
+// The first run only records the latest update operations; manifests are
+// re-matched for update operations that happen after that.
+type Scheduler struct {
+	indexer  Indexer
+	matcher  Matcher
+	sink     Sink
+	interval time.Duration
+	workers  int
+
+	mu sync.Mutex
+	// Seen is the last update operation processed, keyed by updater.
+	seen map[string]uuid.UUID
+}
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithInterval sets how often the Scheduler checks for new update
+// operations.
+func WithInterval(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.interval = d
+	}
+}
+
+// WithWorkers sets the number of manifests re-matched concurrently.
+func WithWorkers(n int) Option {
+	return func(s *Scheduler) {
+		s.workers = n
+	}
+}
+
+// New returns a Scheduler ready to have its Start or Run methods called.
+func New(idx Indexer, m Matcher, sink Sink, opts ...Option) (*Scheduler, error) {
+	if idx == nil || m == nil || sink == nil {
+		return nil, errors.New("rematch: indexer, matcher, and sink must be provided")
+	}
+	s := &Scheduler{
+		indexer:  idx,
+		matcher:  m,
+		sink:     sink,
+		interval: DefaultInterval,
+		workers:  4,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if s.interval <= 0 {
+		return nil, errors.New("rematch: interval must be positive")
+	}
+	if s.workers < 1 {
+		s.workers = 1
+	}
+	return s, nil
+}
+
+// Start runs the Scheduler at its configured interval.
+//
+// Start is designed to be ran as a goroutine. Cancel the provided Context to
+// end the loop.
+func (s *Scheduler) Start(ctx context.Context) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/rematch/Scheduler.Start"))
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		if err := s.Run(ctx); err != nil {
+			zlog.Error(ctx).Err(err).Msg("errors encountered during rematch run")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Run checks for update operations that haven't been processed and
+// re-matches the manifests affected by them.
+//
+// Run is safe to call concurrently with Start, but concurrent runs are
+// serialized.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "libvuln/rematch/Scheduler.Run"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latest, err := s.matcher.LatestUpdateOperations(ctx, driver.VulnerabilityKind)
+	if err != nil {
+		return fmt.Errorf("rematch: unable to get latest update operations: %w", err)
+	}
+	if s.seen == nil {
+		s.seen = make(map[string]uuid.UUID, len(latest))
+		for name, ops := range latest {
+			if len(ops) != 0 {
+				s.seen[name] = ops[0].Ref
+			}
+		}
+		zlog.Debug(ctx).
+			Int("updaters", len(s.seen)).
+			Msg("recorded initial update operations")
+		return nil
+	}
+
+	names := make([]string, 0, len(latest))
+	for name := range latest {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.updater(ctx, name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("rematch: %d updaters failed: %v", len(errs), errs)
+	}
+	return nil
+}
+
+// Updater processes the update operations for the named updater that
+// happened after the last one processed.
+func (s *Scheduler) updater(ctx context.Context, name string) error {
+	ctx = baggage.ContextWithValues(ctx, label.String("updater", name))
+	opmap, err := s.matcher.UpdateOperations(ctx, driver.VulnerabilityKind, name)
+	if err != nil {
+		return err
+	}
+	ops := opmap[name] // date descending
+	prev := s.seen[name]
+	// Find the unprocessed operations.
+	n := 0
+	for n < len(ops) && ops[n].Ref != prev {
+		n++
+	}
+	if n == len(ops) {
+		// The last processed operation isn't known: either the updater is
+		// new or the operation has been garbage collected. Only the latest
+		// operation is processed, against the one before it, if any.
+		if len(ops) == 0 {
+			return nil
+		}
+		n = 1
+		prev = uuid.Nil
+		if len(ops) > 1 {
+			prev = ops[1].Ref
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		cur := ops[i]
+		if err := s.process(ctx, prev, cur); err != nil {
+			return err
+		}
+		prev = cur.Ref
+		s.seen[name] = prev
+	}
+	return nil
+}
+
+// Process re-matches the manifests affected by the difference between two
+// update operations.
+func (s *Scheduler) process(ctx context.Context, prev uuid.UUID, cur driver.UpdateOperation) error {
+	ctx = baggage.ContextWithValues(ctx, label.String("ref", cur.Ref.String()))
+	diff, err := s.matcher.UpdateDiff(ctx, prev, cur.Ref)
+	if err != nil {
+		return err
+	}
+	changed := make([]claircore.Vulnerability, 0, len(diff.Added)+len(diff.Removed))
+	changed = append(changed, diff.Added...)
+	changed = append(changed, diff.Removed...)
+	if len(changed) == 0 {
+		return nil
+	}
+	affected, err := s.indexer.AffectedManifests(ctx, changed)
+	if err != nil {
+		return err
+	}
+	added := idSet(diff.Added)
+	removed := idSet(diff.Removed)
+	zlog.Info(ctx).
+		Int("added", len(diff.Added)).
+		Int("removed", len(diff.Removed)).
+		Int("manifests", len(affected.VulnerableManifests)).
+		Msg("re-matching affected manifests")
+
+	ms := make([]string, 0, len(affected.VulnerableManifests))
+	for m := range affected.VulnerableManifests {
+		ms = append(ms, m)
+	}
+	sort.Strings(ms)
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range work {
+				d, err := s.rematch(ctx, m, cur, affected, added, removed)
+				switch {
+				case err != nil:
+					zlog.Warn(ctx).
+						Err(err).
+						Str("manifest", m).
+						Msg("unable to re-match manifest")
+					continue
+				case d == nil:
+					continue
+				}
+				if err := s.sink.Deliver(ctx, d); err != nil {
+					zlog.Warn(ctx).
+						Err(err).
+						Str("manifest", m).
+						Msg("unable to deliver delta")
+				}
+			}
+		}()
+	}
+Send:
+	for _, m := range ms {
+		select {
+		case work <- m:
+		case <-ctx.Done():
+			break Send
+		}
+	}
+	close(work)
+	wg.Wait()
+	return ctx.Err()
+}
+
+// Rematch generates a new VulnerabilityReport for the manifest and the Delta
+// describing the change. A nil Delta is returned if the manifest is no longer
+// stored.
+func (s *Scheduler) rematch(ctx context.Context, m string, op driver.UpdateOperation, affected *claircore.AffectedManifests, added, removed map[string]struct{}) (*Delta, error) {
+	d, err := claircore.ParseDigest(m)
+	if err != nil {
+		return nil, err
+	}
+	ir, ok, err := s.indexer.IndexReport(ctx, d)
+	switch {
+	case err != nil:
+		return nil, err
+	case !ok:
+		return nil, nil
+	}
+	vr, err := s.matcher.Scan(ctx, ir)
+	if err != nil {
+		return nil, err
+	}
+	delta := Delta{
+		Manifest: d,
+		Update:   op,
+		Report:   vr,
+	}
+	for id, v := range vr.Vulnerabilities {
+		if _, ok := added[id]; ok {
+			delta.Added = append(delta.Added, v)
+		}
+	}
+	for _, id := range affected.VulnerableManifests[m] {
+		if _, ok := removed[id]; ok {
+			delta.Removed = append(delta.Removed, affected.Vulnerabilities[id])
+		}
+	}
+	sortVulns(delta.Added)
+	sortVulns(delta.Removed)
+	return &delta, nil
+}
+
+func idSet(vs []claircore.Vulnerability) map[string]struct{} {
+	out := make(map[string]struct{}, len(vs))
+	for i := range vs {
+		out[vs[i].ID] = struct{}{}
+	}
+	return out
+}
+
+func sortVulns(vs []*claircore.Vulnerability) {
+	sort.Slice(vs, func(i, j int) bool { return vs[i].ID < vs[j].ID })
+}
@@ -0,0 +1,124 @@
+package rematch
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+type fakeIndexer struct {
+	manifest claircore.Digest
+}
+
+func (f *fakeIndexer) AffectedManifests(_ context.Context, vs []claircore.Vulnerability) (*claircore.AffectedManifests, error) {
+	a := claircore.NewAffectedManifests()
+	for i := range vs {
+		a.Add(&vs[i], f.manifest)
+	}
+	return &a, nil
+}
+
+func (f *fakeIndexer) IndexReport(_ context.Context, d claircore.Digest) (*claircore.IndexReport, bool, error) {
+	if d.String() != f.manifest.String() {
+		return nil, false, nil
+	}
+	return &claircore.IndexReport{Hash: d}, true, nil
+}
+
+type fakeMatcher struct {
+	ops   []driver.UpdateOperation // date descending
+	diffs map[uuid.UUID]*driver.UpdateDiff
+	vulns map[string]*claircore.Vulnerability
+}
+
+func (f *fakeMatcher) Scan(_ context.Context, ir *claircore.IndexReport) (*claircore.VulnerabilityReport, error) {
+	return &claircore.VulnerabilityReport{Hash: ir.Hash, Vulnerabilities: f.vulns}, nil
+}
+
+func (f *fakeMatcher) LatestUpdateOperations(context.Context, driver.UpdateKind) (map[string][]driver.UpdateOperation, error) {
+	return map[string][]driver.UpdateOperation{"test": f.ops[:1]}, nil
+}
+
+func (f *fakeMatcher) UpdateOperations(_ context.Context, _ driver.UpdateKind, _ ...string) (map[string][]driver.UpdateOperation, error) {
+	return map[string][]driver.UpdateOperation{"test": f.ops}, nil
+}
+
+func (f *fakeMatcher) UpdateDiff(_ context.Context, prev, cur uuid.UUID) (*driver.UpdateDiff, error) {
+	return f.diffs[cur], nil
+}
+
+func TestScheduler(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	manifest := claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`)
+	first := driver.UpdateOperation{Ref: uuid.New(), Updater: "test"}
+	second := driver.UpdateOperation{Ref: uuid.New(), Updater: "test"}
+	added := claircore.Vulnerability{ID: "1", Name: "CVE-2021-0001"}
+	removed := claircore.Vulnerability{ID: "2", Name: "CVE-2021-0002"}
+	m := &fakeMatcher{
+		ops: []driver.UpdateOperation{first},
+		diffs: map[uuid.UUID]*driver.UpdateDiff{
+			second.Ref: {
+				Prev:    first,
+				Cur:     second,
+				Added:   []claircore.Vulnerability{added},
+				Removed: []claircore.Vulnerability{removed},
+			},
+		},
+		vulns: map[string]*claircore.Vulnerability{"1": &added},
+	}
+	var mu sync.Mutex
+	var got []*Delta
+	sink := SinkFunc(func(_ context.Context, d *Delta) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, d)
+		return nil
+	})
+	s, err := New(&fakeIndexer{manifest: manifest}, m, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first run only records the current state.
+	if err := s.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("got: %d deltas, want: 0", len(got))
+	}
+
+	m.ops = []driver.UpdateOperation{second, first}
+	if err := s.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got: %d deltas, want: 1", len(got))
+	}
+	d := got[0]
+	if got, want := d.Manifest.String(), manifest.String(); got != want {
+		t.Errorf("manifest: got: %q, want: %q", got, want)
+	}
+	if got, want := d.Update.Ref, second.Ref; got != want {
+		t.Errorf("update: got: %v, want: %v", got, want)
+	}
+	if len(d.Added) != 1 || d.Added[0].Name != added.Name {
+		t.Errorf("added: got: %v, want: [%s]", d.Added, added.Name)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Name != removed.Name {
+		t.Errorf("removed: got: %v, want: [%s]", d.Removed, removed.Name)
+	}
+
+	// Nothing new, so nothing should be delivered.
+	if err := s.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("got: %d deltas, want: 1", len(got))
+	}
+}
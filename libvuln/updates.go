@@ -13,6 +13,7 @@ import (
 	"github.com/quay/claircore/internal/vulnstore/postgres"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/libvuln/jsonblob"
+	"github.com/quay/claircore/pkg/blob"
 )
 
 // OfflineImport takes the format written into the io.Writer provided to
@@ -69,3 +70,14 @@ Update:
 	}
 	return nil
 }
+
+// OfflineImportBlob is like OfflineImport, but reads the bundle stored under
+// "key" in the provided blob.Store.
+func OfflineImportBlob(ctx context.Context, pool *pgxpool.Pool, s blob.Store, key string) error {
+	rc, err := s.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return OfflineImport(ctx, pool, rc)
+}
@@ -0,0 +1,82 @@
+package updates
+
+import (
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// FloorConfig is the configuration every updater accepts, in addition to its
+// own.
+type floorConfig struct {
+	// SeverityFloor is the lowest normalized severity of vulnerability to
+	// store. Vulnerabilities with a lower severity are dropped after parsing,
+	// except those of Unknown severity.
+	SeverityFloor claircore.Severity `json:"severity_floor" yaml:"severity_floor"`
+}
+
+// ConfiguredFloor returns the severity floor configured for the named
+// updater, or Unknown if there's none.
+func (m *Manager) configuredFloor(name string) (claircore.Severity, error) {
+	f := m.configs[name]
+	if f == nil {
+		return claircore.Unknown, nil
+	}
+	var cfg floorConfig
+	if err := f(&cfg); err != nil {
+		return claircore.Unknown, err
+	}
+	return cfg.SeverityFloor, nil
+}
+
+// FloorMarker separates an updater's fingerprint from the severity floor
+// recorded alongside it.
+//
+// The floor is recorded so that it's visible on the UpdateOperation, and so
+// that changing it forces a full update: an updater would otherwise report its
+// database as unchanged and the stored vulnerabilities would reflect the old
+// floor.
+const floorMarker = "\n# severity floor: "
+
+// SplitFingerprint separates a stored fingerprint into the updater's
+// fingerprint and the recorded severity floor.
+func splitFingerprint(fp driver.Fingerprint) (driver.Fingerprint, claircore.Severity) {
+	i := strings.LastIndex(string(fp), floorMarker)
+	if i == -1 {
+		return fp, claircore.Unknown
+	}
+	var s claircore.Severity
+	if err := s.UnmarshalText([]byte(fp[i+len(floorMarker):])); err != nil {
+		return fp, claircore.Unknown
+	}
+	return fp[:i], s
+}
+
+// JoinFingerprint records the severity floor with the updater's fingerprint.
+func joinFingerprint(fp driver.Fingerprint, floor claircore.Severity) driver.Fingerprint {
+	if floor == claircore.Unknown {
+		return fp
+	}
+	return fp + driver.Fingerprint(floorMarker+floor.String())
+}
+
+// ApplyFloor removes vulnerabilities below the severity floor, in place,
+// returning the kept vulnerabilities and the number dropped.
+func applyFloor(vulns []*claircore.Vulnerability, floor claircore.Severity) ([]*claircore.Vulnerability, int) {
+	if floor == claircore.Unknown {
+		return vulns, 0
+	}
+	out := vulns[:0]
+	for _, v := range vulns {
+		if v.NormalizedSeverity != claircore.Unknown && v.NormalizedSeverity < floor {
+			continue
+		}
+		out = append(out, v)
+	}
+	dropped := len(vulns) - len(out)
+	for i := len(out); i < len(vulns); i++ {
+		vulns[i] = nil
+	}
+	return out, dropped
+}
@@ -0,0 +1,75 @@
+package updates
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+func TestApplyFloor(t *testing.T) {
+	mk := func(ss ...claircore.Severity) []*claircore.Vulnerability {
+		out := make([]*claircore.Vulnerability, len(ss))
+		for i, s := range ss {
+			out[i] = &claircore.Vulnerability{NormalizedSeverity: s}
+		}
+		return out
+	}
+	in := mk(claircore.Negligible, claircore.Unknown, claircore.Low, claircore.High, claircore.Medium)
+	got, dropped := applyFloor(in, claircore.Medium)
+	if dropped != 2 {
+		t.Errorf("got: %d dropped, want: %d", dropped, 2)
+	}
+	want := []claircore.Severity{claircore.Unknown, claircore.High, claircore.Medium}
+	if len(got) != len(want) {
+		t.Fatalf("got: %d vulnerabilities, want: %d", len(got), len(want))
+	}
+	for i, v := range got {
+		if v.NormalizedSeverity != want[i] {
+			t.Errorf("%d: got: %v, want: %v", i, v.NormalizedSeverity, want[i])
+		}
+	}
+
+	in = mk(claircore.Negligible, claircore.Low)
+	if got, dropped := applyFloor(in, claircore.Unknown); dropped != 0 || len(got) != 2 {
+		t.Errorf("got: %d dropped, %d kept; want no change", dropped, len(got))
+	}
+}
+
+func TestFingerprintFloor(t *testing.T) {
+	const fp = driver.Fingerprint(`{"etag":"abc"}`)
+	for _, floor := range []claircore.Severity{claircore.Unknown, claircore.Low, claircore.Critical} {
+		gotFP, gotFloor := splitFingerprint(joinFingerprint(fp, floor))
+		if gotFP != fp {
+			t.Errorf("%v: got: %q, want: %q", floor, gotFP, fp)
+		}
+		if gotFloor != floor {
+			t.Errorf("%v: got: %v, want: %v", floor, gotFloor, floor)
+		}
+	}
+	if got := joinFingerprint(fp, claircore.Unknown); got != fp {
+		t.Errorf("got: %q, want: %q", got, fp)
+	}
+}
+
+func TestConfiguredFloor(t *testing.T) {
+	m := Manager{
+		configs: Configs{
+			"floored": func(v interface{}) error {
+				return (&v.(*floorConfig).SeverityFloor).UnmarshalText([]byte("Low"))
+			},
+		},
+	}
+	for name, want := range map[string]claircore.Severity{
+		"floored":   claircore.Low,
+		"unfloored": claircore.Unknown,
+	} {
+		got, err := m.configuredFloor(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: got: %v, want: %v", name, got, want)
+		}
+	}
+}
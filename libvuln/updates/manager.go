@@ -19,6 +19,7 @@ import (
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/vulnstore"
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/clock"
 	"github.com/quay/claircore/updater"
 )
 
@@ -60,6 +61,8 @@ type Manager struct {
 	locks  LockSource
 	client *http.Client
 	store  vulnstore.Updater
+	// clock, if not nil, is carried in the Context of every Run.
+	clock clock.Clock
 }
 
 // NewManager will return a manager ready to have its Start or Run methods called.
@@ -142,6 +145,7 @@ func (m *Manager) Run(ctx context.Context) error {
 		ctx,
 		label.String("component", "libvuln/updates/Manager.Run"),
 	)
+	ctx = clock.WithClock(ctx, m.clock)
 
 	updaters := []driver.Updater{}
 	// Constructing updater sets may require network access
@@ -159,6 +163,7 @@ func (m *Manager) Run(ctx context.Context) error {
 
 	// configure updaters
 	toRun := make([]driver.Updater, 0, len(updaters))
+	floors := make(map[string]claircore.Severity)
 	for _, u := range updaters {
 		if f, ok := u.(driver.Configurable); ok {
 			name := u.Name()
@@ -174,6 +179,15 @@ func (m *Manager) Run(ctx context.Context) error {
 				continue
 			}
 		}
+		floor, err := m.configuredFloor(u.Name())
+		if err != nil {
+			zlog.Warn(ctx).
+				Err(err).
+				Str("updater", u.Name()).
+				Msg("failed configuring severity floor, excluding from current run")
+			continue
+		}
+		floors[u.Name()] = floor
 		toRun = append(toRun, u)
 	}
 
@@ -206,7 +220,7 @@ func (m *Manager) Run(ctx context.Context) error {
 				return
 			}
 
-			err = m.driveUpdater(ctx, u)
+			err = m.driveUpdater(ctx, u, floors[u.Name()])
 			if err != nil {
 				errChan <- fmt.Errorf("%v: %w", u.Name(), err)
 			}
@@ -253,7 +267,9 @@ func (m *Manager) Run(ctx context.Context) error {
 
 // DriveUpdater performs the business logic of fetching, parsing, and loading
 // vulnerabilities discovered by an updater into the database.
-func (m *Manager) driveUpdater(ctx context.Context, u driver.Updater) error {
+//
+// Vulnerabilities below the provided severity floor are not loaded.
+func (m *Manager) driveUpdater(ctx context.Context, u driver.Updater, floor claircore.Severity) error {
 	name := u.Name()
 	ctx = baggage.ContextWithValues(ctx,
 		label.String("component", "libvuln/updates/Manager.driveUpdater"),
@@ -279,6 +295,17 @@ func (m *Manager) driveUpdater(ctx context.Context, u driver.Updater) error {
 	if s := opmap[name]; len(s) > 0 {
 		prevFP = s[0].Fingerprint
 	}
+	if !euOK {
+		var prevFloor claircore.Severity
+		prevFP, prevFloor = splitFingerprint(prevFP)
+		if prevFloor != floor {
+			zlog.Info(ctx).
+				Stringer("previous", prevFloor).
+				Stringer("current", floor).
+				Msg("severity floor changed, forcing update")
+			prevFP = ""
+		}
+	}
 
 	var vulnDB io.ReadCloser
 	var newFP driver.Fingerprint
@@ -316,8 +343,21 @@ func (m *Manager) driveUpdater(ctx context.Context, u driver.Updater) error {
 		if err != nil {
 			return fmt.Errorf("vulnerability database parse failed: %v", err)
 		}
+		if ct := markWithdrawn(vulns); ct != 0 {
+			zlog.Info(ctx).
+				Int("withdrawn", ct).
+				Msg("found withdrawn vulnerabilities")
+		}
+		var dropped int
+		vulns, dropped = applyFloor(vulns, floor)
+		if dropped != 0 {
+			zlog.Info(ctx).
+				Stringer("floor", floor).
+				Int("dropped", dropped).
+				Msg("dropped vulnerabilities below severity floor")
+		}
 
-		ref, err = m.store.UpdateVulnerabilities(ctx, name, newFP, vulns)
+		ref, err = m.store.UpdateVulnerabilities(ctx, name, joinFingerprint(newFP, floor), vulns)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to update: %v", err)
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/clock"
 )
 
 // ManagerOption specify optional configuration for a Manager.
@@ -99,3 +100,12 @@ func WithFactories(f map[string]driver.UpdaterSetFactory) ManagerOption {
 		m.factories = f
 	}
 }
+
+// WithClock configures the Clock updaters and the store are given in the
+// Context of every run, for recording the time of update operations. See the
+// clock package.
+func WithClock(c clock.Clock) ManagerOption {
+	return func(m *Manager) {
+		m.clock = c
+	}
+}
@@ -0,0 +1,36 @@
+package updates
+
+import (
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// RejectedPrefixes are the prefixes NVD puts on the descriptions of rejected
+// CVEs. Distributions that copy NVD descriptions carry these along.
+var rejectedPrefixes = []string{
+	"** REJECT **",
+	"Rejected reason:",
+}
+
+// MarkWithdrawn marks vulnerabilities that have been rejected as withdrawn,
+// in addition to any the updater marked itself, and returns the number of
+// withdrawn vulnerabilities.
+func markWithdrawn(vulns []*claircore.Vulnerability) int {
+	var ct int
+	for _, v := range vulns {
+		if !v.Withdrawn {
+			d := strings.TrimSpace(v.Description)
+			for _, p := range rejectedPrefixes {
+				if strings.HasPrefix(d, p) {
+					v.Withdrawn = true
+					break
+				}
+			}
+		}
+		if v.Withdrawn {
+			ct++
+		}
+	}
+	return ct
+}
@@ -0,0 +1,24 @@
+package updates
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func TestMarkWithdrawn(t *testing.T) {
+	vulns := []*claircore.Vulnerability{
+		{Name: "CVE-2020-0001", Description: "A real problem."},
+		{Name: "CVE-2020-0002", Description: "** REJECT ** DO NOT USE THIS CANDIDATE NUMBER."},
+		{Name: "CVE-2020-0003", Description: "  Rejected reason: This CVE ID is a duplicate."},
+		{Name: "GHSA-xxxx-xxxx-xxxx", Withdrawn: true},
+	}
+	if got, want := markWithdrawn(vulns), 3; got != want {
+		t.Errorf("got: %d withdrawn, want: %d", got, want)
+	}
+	for i, want := range []bool{false, true, true, true} {
+		if got := vulns[i].Withdrawn; got != want {
+			t.Errorf("%s: got: %v, want: %v", vulns[i].Name, got, want)
+		}
+	}
+}
@@ -8,4 +8,14 @@ type Manifest struct {
 	Hash Digest `json:"hash"`
 	// an array of filesystem layers indexed in the same order as the cooresponding image
 	Layers []*Layer `json:"layers"`
+	// the labels from the image's configuration, if known. Well-known labels
+	// are carried into the IndexReport as hints; see LabelHints.
+	Labels map[string]string `json:"labels,omitempty"`
+	// the platform the image is built for, if it was found through a
+	// ManifestList
+	Platform *Platform `json:"platform,omitempty"`
+	// the history from the image's configuration, if known. It's used to
+	// attribute packages to the instruction that installed them; see
+	// LayerInstructions.
+	History []History `json:"history,omitempty"`
 }
@@ -0,0 +1,81 @@
+package claircore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platform is the operating system and CPU an image is built for, as recorded
+// in an image index or manifest list.
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// ParsePlatform parses a platform in the "os/architecture[/variant]" form
+// used by container tooling, for example "linux/arm64/v8".
+func ParsePlatform(s string) (Platform, error) {
+	f := strings.Split(s, "/")
+	if len(f) < 2 || len(f) > 3 {
+		return Platform{}, fmt.Errorf("claircore: invalid platform %q", s)
+	}
+	for _, e := range f {
+		if e == "" {
+			return Platform{}, fmt.Errorf("claircore: invalid platform %q", s)
+		}
+	}
+	p := Platform{OS: f[0], Architecture: f[1]}
+	if len(f) == 3 {
+		p.Variant = f[2]
+	}
+	return p, nil
+}
+
+// String returns the platform in the "os/architecture[/variant]" form.
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// Match reports whether the platform satisfies the wanted platform. A wanted
+// platform without a Variant matches any variant.
+func (p Platform) Match(want Platform) bool {
+	return p.OS == want.OS &&
+		p.Architecture == want.Architecture &&
+		(want.Variant == "" || p.Variant == want.Variant)
+}
+
+// ManifestList represents a multi-platform image: an OCI image index or a
+// docker manifest list. Each Manifest should have its Platform set.
+type ManifestList struct {
+	// the content addressable hash of the index or list itself
+	Hash Digest `json:"hash"`
+	// the manifest for each platform, in the order the list declares them
+	Manifests []*Manifest `json:"manifests"`
+}
+
+// Select returns the first Manifest in the list matching the platform.
+func (ml *ManifestList) Select(p Platform) (*Manifest, bool) {
+	for _, m := range ml.Manifests {
+		if m.Platform != nil && m.Platform.Match(p) {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// Platforms returns the platforms in the list, in order. Manifests without a
+// platform are skipped.
+func (ml *ManifestList) Platforms() []Platform {
+	out := make([]Platform, 0, len(ml.Manifests))
+	for _, m := range ml.Manifests {
+		if m.Platform != nil {
+			out = append(out, *m.Platform)
+		}
+	}
+	return out
+}
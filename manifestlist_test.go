@@ -0,0 +1,177 @@
+package claircore
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePlatform(t *testing.T) {
+	tt := []struct {
+		In   string
+		Want Platform
+		Err  bool
+	}{
+		{In: "linux/amd64", Want: Platform{OS: "linux", Architecture: "amd64"}},
+		{In: "linux/arm64/v8", Want: Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+		{In: "linux", Err: true},
+		{In: "linux//v7", Err: true},
+		{In: "linux/arm/v7/extra", Err: true},
+	}
+	for _, tc := range tt {
+		got, err := ParsePlatform(tc.In)
+		if (err != nil) != tc.Err {
+			t.Errorf("%q: unexpected error: %v", tc.In, err)
+			continue
+		}
+		if !cmp.Equal(got, tc.Want) {
+			t.Errorf("%q: %s", tc.In, cmp.Diff(got, tc.Want))
+		}
+		if !tc.Err && got.String() != tc.In {
+			t.Errorf("got: %q, want: %q", got.String(), tc.In)
+		}
+	}
+}
+
+func TestManifestListSelect(t *testing.T) {
+	amd64 := &Platform{OS: "linux", Architecture: "amd64"}
+	armv6 := &Platform{OS: "linux", Architecture: "arm", Variant: "v6"}
+	armv7 := &Platform{OS: "linux", Architecture: "arm", Variant: "v7"}
+	ml := ManifestList{
+		Manifests: []*Manifest{
+			{Hash: digestFor(t, "a"), Platform: amd64},
+			{Hash: digestFor(t, "b"), Platform: armv6},
+			{Hash: digestFor(t, "c"), Platform: armv7},
+		},
+	}
+	tt := []struct {
+		Platform string
+		Want     int
+	}{
+		{"linux/amd64", 0},
+		{"linux/arm/v7", 2},
+		{"linux/arm", 1},
+		{"linux/s390x", -1},
+		{"windows/amd64", -1},
+	}
+	for _, tc := range tt {
+		p, err := ParsePlatform(tc.Platform)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, ok := ml.Select(p)
+		switch {
+		case tc.Want < 0 && ok:
+			t.Errorf("%s: unexpected match: %v", tc.Platform, m.Hash)
+		case tc.Want >= 0 && !ok:
+			t.Errorf("%s: no match", tc.Platform)
+		case tc.Want >= 0 && m != ml.Manifests[tc.Want]:
+			t.Errorf("%s: got: %v, want: %v", tc.Platform, m.Hash, ml.Manifests[tc.Want].Hash)
+		}
+	}
+	if got, want := len(ml.Platforms()), 3; got != want {
+		t.Errorf("got: %d platforms, want: %d", got, want)
+	}
+}
+
+func digestFor(t *testing.T, s string) Digest {
+	t.Helper()
+	b := make([]byte, 32)
+	copy(b, s)
+	d, err := NewDigest("sha256", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestMergeIndexReports(t *testing.T) {
+	layer := digestFor(t, "layer")
+	deb := &Distribution{ID: "1", DID: "debian", VersionID: "11"}
+	amd := &IndexReport{
+		Hash:          digestFor(t, "amd64"),
+		State:         "IndexFinished",
+		Success:       true,
+		Platform:      &Platform{OS: "linux", Architecture: "amd64"},
+		Distributions: map[string]*Distribution{"1": deb},
+		Packages: map[string]*Package{
+			"1": {ID: "1", Name: "libc6", Version: "2.31", Arch: "amd64"},
+			"2": {ID: "2", Name: "tzdata", Version: "2021a", Arch: "all"},
+		},
+		Environments: map[string][]*Environment{
+			"1": {{PackageDB: "var/lib/dpkg/status", IntroducedIn: layer, DistributionID: "1"}},
+			"2": {{PackageDB: "var/lib/dpkg/status", IntroducedIn: layer, DistributionID: "1"}},
+		},
+		Hints: map[string]string{"vendor": "x"},
+	}
+	// The arm64 report was indexed separately and reuses ID "1" for a
+	// different package.
+	arm := &IndexReport{
+		Hash:          digestFor(t, "arm64"),
+		State:         "IndexFinished",
+		Success:       true,
+		Platform:      &Platform{OS: "linux", Architecture: "arm64"},
+		Distributions: map[string]*Distribution{"1": deb},
+		Packages: map[string]*Package{
+			"1": {ID: "1", Name: "libc6", Version: "2.31", Arch: "arm64"},
+			"2": {ID: "2", Name: "tzdata", Version: "2021a", Arch: "all"},
+		},
+		Environments: map[string][]*Environment{
+			"1": {{PackageDB: "var/lib/dpkg/status", IntroducedIn: layer, DistributionID: "1"}},
+			"2": {{PackageDB: "var/lib/dpkg/status", IntroducedIn: layer, DistributionID: "1"}},
+		},
+		Hints: map[string]string{"vendor": "x"},
+	}
+	list := digestFor(t, "list")
+	got := MergeIndexReports(list, amd, arm)
+
+	if got.Hash.String() != list.String() {
+		t.Errorf("got hash: %v, want: %v", got.Hash, list)
+	}
+	if !got.Success {
+		t.Error("expected success")
+	}
+	if got, want := len(got.Distributions), 1; got != want {
+		t.Errorf("got: %d distributions, want: %d", got, want)
+	}
+	wantPkgs := map[string]string{
+		"1":             "amd64",
+		"1@linux/arm64": "arm64",
+		"2":             "all",
+	}
+	if len(got.Packages) != len(wantPkgs) {
+		t.Errorf("got: %d packages, want: %d", len(got.Packages), len(wantPkgs))
+	}
+	for id, arch := range wantPkgs {
+		p, ok := got.Packages[id]
+		if !ok {
+			t.Errorf("missing package %q", id)
+			continue
+		}
+		if p.ID != id || p.Arch != arch {
+			t.Errorf("%s: got: %s/%s", id, p.ID, p.Arch)
+		}
+	}
+	var plats []string
+	for _, e := range got.Environments["2"] {
+		plats = append(plats, e.Platform)
+	}
+	if want := []string{"linux/amd64", "linux/arm64"}; !cmp.Equal(plats, want) {
+		t.Error(cmp.Diff(plats, want))
+	}
+	if got, want := got.Hints, amd.Hints; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	// The inputs aren't modified.
+	if amd.Environments["1"][0].Platform != "" || arm.Packages["1"].ID != "1" {
+		t.Error("input reports modified")
+	}
+
+	arm.Success = false
+	arm.State = "IndexError"
+	arm.Err = "fetch failed"
+	got = MergeIndexReports(list, amd, arm)
+	if got.Success || got.State != "IndexError" || got.Err != "linux/arm64: fetch failed" {
+		t.Errorf("unexpected result: %v %q %q", got.Success, got.State, got.Err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/quay/claircore/aws"
 	"github.com/quay/claircore/crda"
 	"github.com/quay/claircore/debian"
+	"github.com/quay/claircore/java"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/matchers/registry"
 	"github.com/quay/claircore/oracle"
@@ -54,6 +55,7 @@ var defaultMatchers = []driver.Matcher{
 
 func inner(ctx context.Context) error {
 	registry.Register("crda", &crda.Factory{})
+	registry.Register("java", &java.Factory{})
 
 	for _, m := range defaultMatchers {
 		mf := driver.MatcherStatic(m)
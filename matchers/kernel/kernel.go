@@ -0,0 +1,144 @@
+// Package kernel adjusts matching of operating system kernel packages.
+//
+// Container images often carry kernel packages, pulled in as dependencies or
+// left over from a base image, but a container always runs on its host's
+// kernel. Findings against those packages are noise at best. Matchers wrapped
+// with Wrap ignore kernel packages, unless the version of the kernel actually
+// running is supplied, in which case that version is matched in place of the
+// version found in the image.
+package kernel
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Config configures kernel matching.
+//
+// The zero value suppresses all findings against kernel packages.
+type Config struct {
+	// Running, if set, is the version of the kernel the scanned images run
+	// on, as the distribution's package manager reports it. For example:
+	//
+	//	rpm -q --qf '%{VERSION}-%{RELEASE}' kernel-core-$(uname -r)
+	//	dpkg-query -W -f '${Version}' linux-image-$(uname -r)
+	//
+	// Kernel packages are matched as if they were this version. A version
+	// set on the Context with WithRunning takes precedence.
+	Running string
+}
+
+type runningKey struct{}
+
+// WithRunning returns a Context carrying the running kernel version to match
+// kernel packages against, overriding Config.Running for calls using it. This
+// allows the version to be supplied per report, such as when scanning images
+// for a particular node.
+func WithRunning(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, runningKey{}, version)
+}
+
+// Running returns the running kernel version set on the Context by WithRunning,
+// if any.
+func Running(ctx context.Context) string {
+	v, _ := ctx.Value(runningKey{}).(string)
+	return v
+}
+
+// Wrap returns a Matcher that handles kernel packages as described by the
+// Config before handing records to "m". Non-kernel packages are unaffected.
+//
+// The returned Matcher doesn't opt in to database-side version filtering, as
+// the versions it matches aren't the indexed ones. Remote matchers are
+// returned as-is, as they do their own matching.
+func Wrap(m driver.Matcher, cfg *Config) driver.Matcher {
+	if _, ok := m.(driver.RemoteMatcher); ok {
+		return m
+	}
+	w := matcher{Matcher: m}
+	if cfg != nil {
+		w.running = cfg.Running
+	}
+	return &w
+}
+
+// Matcher wraps a driver.Matcher.
+type matcher struct {
+	driver.Matcher
+	running string
+}
+
+var _ driver.Resolver = (*matcher)(nil)
+
+// Resolve implements driver.Resolver.
+//
+// Kernel packages are removed, or have their versions replaced by the running
+// kernel's version if one is known. Package IDs are kept, so findings are
+// reported against the kernel packages in the image.
+func (m *matcher) Resolve(ctx context.Context, records []*claircore.IndexRecord) ([]*claircore.IndexRecord, error) {
+	if r, ok := m.Matcher.(driver.Resolver); ok {
+		var err error
+		records, err = r.Resolve(ctx, records)
+		if err != nil {
+			return nil, err
+		}
+	}
+	running := Running(ctx)
+	if running == "" {
+		running = m.running
+	}
+	out := make([]*claircore.IndexRecord, 0, len(records))
+	for _, r := range records {
+		if !IsKernel(r.Package) {
+			out = append(out, r)
+			continue
+		}
+		if running == "" {
+			continue
+		}
+		p := *r.Package
+		p.Version = running
+		p.NormalizedVersion = claircore.Version{}
+		rr := *r
+		rr.Package = &p
+		out = append(out, &rr)
+	}
+	return out, nil
+}
+
+var (
+	// KernelRPM matches RPM kernel packages, such as "kernel", "kernel-core",
+	// "kernel-rt", "kernel-uek", and "kernel-default".
+	kernelRPM = regexp.MustCompile(`^kernel(-[a-z0-9_]+)*$`)
+	// KernelDeb matches Debian and Ubuntu kernel binary packages.
+	kernelDeb = regexp.MustCompile(`^linux-(image|headers|modules|modules-extra|kbuild|tools|cloud-tools|buildinfo)-`)
+	// KernelSource matches the Debian, Ubuntu, and Alpine kernel source
+	// packages and flavor metapackages, such as "linux", "linux-signed-aws",
+	// "linux-meta-gcp", "linux-generic", and "linux-lts".
+	kernelSource = regexp.MustCompile(`^linux(-signed|-meta)?(-(aws|azure|gcp|gke|gkeop|oracle|ibm|kvm|hwe|lowlatency|raspi|oem|intel|nvidia|generic|virtual|lts|virt|edge|rt)[a-z0-9.-]*)?$`)
+)
+
+// IsKernel reports whether the package, or the source package it was built
+// from, is an operating system kernel package.
+func IsKernel(p *claircore.Package) bool {
+	if p == nil {
+		return false
+	}
+	names := []string{p.Name}
+	if p.Source != nil && p.Source.Name != "" {
+		names = append(names, p.Source.Name)
+	}
+	for _, n := range names {
+		if strings.Contains(n, "firmware") {
+			return false
+		}
+		if kernelRPM.MatchString(n) || kernelDeb.MatchString(n) || kernelSource.MatchString(n) {
+			return true
+		}
+	}
+	return false
+}
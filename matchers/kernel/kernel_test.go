@@ -0,0 +1,112 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+type testMatcher struct{}
+
+func (testMatcher) Name() string                       { return "test" }
+func (testMatcher) Filter(*claircore.IndexRecord) bool { return true }
+func (testMatcher) Query() []driver.MatchConstraint {
+	return []driver.MatchConstraint{driver.PackageName}
+}
+func (testMatcher) Vulnerable(context.Context, *claircore.IndexRecord, *claircore.Vulnerability) (bool, error) {
+	return true, nil
+}
+
+func TestIsKernel(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Source string
+		Want   bool
+	}{
+		{Name: "kernel", Want: true},
+		{Name: "kernel-core", Source: "kernel", Want: true},
+		{Name: "kernel-uek", Want: true},
+		{Name: "kernel-default", Want: true},
+		{Name: "kernel-firmware", Want: false},
+		{Name: "linux-image-5.10.0-9-amd64", Source: "linux-signed-amd64", Want: true},
+		{Name: "linux-image-5.4.0-1055-aws", Source: "linux-aws", Want: true},
+		{Name: "linux-aws", Source: "linux-meta-aws", Want: true},
+		{Name: "linux-generic", Want: true},
+		{Name: "linux-libc-dev", Source: "linux", Want: true},
+		{Name: "linux-lts", Want: true},
+		{Name: "linux-firmware", Want: false},
+		{Name: "linux-pam", Want: false},
+		{Name: "libc6", Source: "glibc", Want: false},
+		{Name: "kernelshark", Want: false},
+	}
+	for _, tc := range tt {
+		p := &claircore.Package{Name: tc.Name}
+		if tc.Source != "" {
+			p.Source = &claircore.Package{Name: tc.Source}
+		}
+		if got := IsKernel(p); got != tc.Want {
+			t.Errorf("%s (%s): got: %v, want: %v", tc.Name, tc.Source, got, tc.Want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	records := []*claircore.IndexRecord{
+		{Package: &claircore.Package{ID: "1", Name: "openssl", Version: "1:1.1.1k-5.el8_5"}},
+		{Package: &claircore.Package{ID: "2", Name: "kernel-core", Version: "4.18.0-348.el8"}},
+	}
+	openssl := records[0]
+	running := func(v string) []*claircore.IndexRecord {
+		return []*claircore.IndexRecord{
+			openssl,
+			{Package: &claircore.Package{ID: "2", Name: "kernel-core", Version: v}},
+		}
+	}
+	tt := []struct {
+		Name string
+		Ctx  context.Context
+		Cfg  *Config
+		Want []*claircore.IndexRecord
+	}{
+		{
+			Name: "Suppress",
+			Ctx:  context.Background(),
+			Want: []*claircore.IndexRecord{openssl},
+		},
+		{
+			Name: "Config",
+			Ctx:  context.Background(),
+			Cfg:  &Config{Running: "4.18.0-372.el8"},
+			Want: running("4.18.0-372.el8"),
+		},
+		{
+			Name: "Context",
+			Ctx:  WithRunning(context.Background(), "4.18.0-425.el8"),
+			Cfg:  &Config{Running: "4.18.0-372.el8"},
+			Want: running("4.18.0-425.el8"),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			m := Wrap(testMatcher{}, tc.Cfg)
+			if _, ok := m.(driver.VersionFilter); ok {
+				t.Error("wrapped matcher claims version filtering")
+			}
+			got, err := m.(driver.Resolver).Resolve(tc.Ctx, records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+	// The input must not be modified.
+	if got, want := records[1].Package.Version, "4.18.0-348.el8"; got != want {
+		t.Errorf("input modified: got: %q, want: %q", got, want)
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/quay/claircore/libvuln/driver"
 	_ "github.com/quay/claircore/matchers/defaults"
+	"github.com/quay/claircore/matchers/kernel"
 	"github.com/quay/claircore/matchers/registry"
 )
 
@@ -25,6 +26,8 @@ type Matchers struct {
 	client  *http.Client
 	// out-of-tree matchers.
 	matchers []driver.Matcher
+	// kernel package handling, if any.
+	kernel *kernel.Config
 }
 
 type MatchersOption func(m *Matchers)
@@ -69,5 +72,11 @@ func NewMatchers(ctx context.Context, client *http.Client, opts ...MatchersOptio
 	// merge default matchers with any out-of-tree specified.
 	matchers = append(matchers, m.matchers...)
 
+	if m.kernel != nil {
+		for i, mt := range matchers {
+			matchers[i] = kernel.Wrap(mt, m.kernel)
+		}
+	}
+
 	return matchers, nil
 }
@@ -2,6 +2,7 @@ package matchers
 
 import (
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/matchers/kernel"
 )
 
 // WithEnabled configures the Matchers to only run the specified
@@ -47,3 +48,13 @@ func WithOutOfTree(outOfTree []driver.Matcher) MatchersOption {
 		m.matchers = outOfTree
 	}
 }
+
+// WithKernel wraps all the matchers, including out-of-tree ones, to handle
+// kernel packages as described by the kernel.Config.
+//
+// If cfg is nil, kernel packages are matched like any other package.
+func WithKernel(cfg *kernel.Config) MatchersOption {
+	return func(m *Matchers) {
+		m.kernel = cfg
+	}
+}
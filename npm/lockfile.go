@@ -0,0 +1,83 @@
+package npm
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+)
+
+// Lockfile names, in order of preference. The last is the "hidden" lockfile
+// npm 7 and later keeps inside node_modules.
+var lockfileNames = []string{
+	"npm-shrinkwrap.json",
+	"package-lock.json",
+	"node_modules/.package-lock.json",
+}
+
+// Lockfile maps a package's directory relative to the lockfile's root, such as
+// "node_modules/a/node_modules/@s/b", to its entry.
+type lockfile map[string]lockEntry
+
+type lockEntry struct {
+	Version  string `json:"version"`
+	Resolved string `json:"resolved"`
+	// Dev is set for packages only needed by the devDependencies.
+	Dev bool `json:"dev"`
+	// Dependencies is only used by version 1 lockfiles.
+	Dependencies map[string]lockEntry `json:"dependencies"`
+}
+
+// IsLockfile reports whether the path is a lockfile for an install root.
+// Lockfiles published inside packages are ignored.
+func isLockfile(p string) bool {
+	dir, base := path.Split(p)
+	switch base {
+	case "npm-shrinkwrap.json", "package-lock.json":
+		return !strings.Contains("/"+dir, "/node_modules/")
+	case ".package-lock.json":
+		return path.Base(dir) == "node_modules" && strings.Count("/"+dir, "/node_modules/") == 1
+	}
+	return false
+}
+
+// FindLockfile returns the preferred lockfile for the root directory, or nil.
+func findLockfile(locks map[string]lockfile, root string) lockfile {
+	for _, n := range lockfileNames {
+		if lf, ok := locks[path.Join(root, n)]; ok {
+			return lf
+		}
+	}
+	return nil
+}
+
+// ParseLockfile reads a package-lock.json, npm-shrinkwrap.json, or hidden
+// lockfile.
+//
+// Version 2 and 3 lockfiles list packages by path in the "packages" object.
+// Version 1 lockfiles nest dependencies by name in "dependencies", and are
+// flattened into the same form.
+func parseLockfile(r io.Reader) (lockfile, error) {
+	var lf struct {
+		Packages     map[string]lockEntry `json:"packages"`
+		Dependencies map[string]lockEntry `json:"dependencies"`
+	}
+	if err := json.NewDecoder(r).Decode(&lf); err != nil {
+		return nil, err
+	}
+	if lf.Packages != nil {
+		return lockfile(lf.Packages), nil
+	}
+	out := make(lockfile)
+	var walk func(string, map[string]lockEntry)
+	walk = func(prefix string, deps map[string]lockEntry) {
+		for name, e := range deps {
+			k := path.Join(prefix, "node_modules", name)
+			walk(k, e.Dependencies)
+			e.Dependencies = nil
+			out[k] = e
+		}
+	}
+	walk("", lf.Dependencies)
+	return out, nil
+}
@@ -0,0 +1,237 @@
+// Package npm contains components for finding Node.js packages installed
+// into node_modules directories.
+//
+// Applications bundled into single files or Electron application directories
+// are handled by the electron package; this package skips Electron
+// application directories so packages aren't reported twice.
+package npm
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime/trace"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var (
+	_ indexer.VersionedScanner   = (*Scanner)(nil)
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
+)
+
+// RepositoryHint is used for packages that don't record where they were
+// resolved from.
+const RepositoryHint = "https://registry.npmjs.org"
+
+const (
+	// MaxManifest is the largest package.json that will be read.
+	maxManifest = 1 << 20
+	// MaxLockfile is the largest lockfile that will be read.
+	maxLockfile = 64 << 20
+)
+
+// Scanner implements the indexer.PackageScanner interface.
+//
+// It looks for the package.json files of packages installed into
+// node_modules directories, including nested and scoped packages. If the
+// directory containing the outermost node_modules directory has a lockfile,
+// packages the lockfile marks as only needed for development are skipped and
+// the registry each package was resolved from is used as its RepositoryHint.
+//
+// The zero value is ready to use.
+type Scanner struct{}
+
+// Name implements indexer.VersionedScanner.
+func (*Scanner) Name() string { return "npm" }
+
+// Version implements indexer.VersionedScanner.
+func (*Scanner) Version() string { return "1" }
+
+// Kind implements indexer.VersionedScanner.
+func (*Scanner) Kind() string { return "package" }
+
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceDeclared }
+
+// Scan attempts to find installed Node.js packages.
+//
+// A return of (nil, nil) is expected if there's nothing found.
+func (s *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	trace.Log(ctx, "layer", layer.Hash.String())
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "npm/Scanner.Scan"),
+		label.String("version", s.Version()),
+		label.String("layer", layer.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r, err := layer.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var found []*installed
+	locks := make(map[string]lockfile)
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
+		if err != nil {
+			return nil, err
+		}
+		n = filepath.ToSlash(n)
+		switch {
+		case inElectronApp(n):
+			continue
+		case isLockfile(n):
+			if h.Size > maxLockfile {
+				zlog.Info(ctx).
+					Str("file", n).
+					Int64("size", h.Size).
+					Msg("skipping oversized lockfile")
+				continue
+			}
+			lf, err := parseLockfile(tr)
+			if err != nil {
+				zlog.Info(ctx).
+					Str("file", n).
+					Err(err).
+					Msg("unable to parse lockfile, skipping")
+				continue
+			}
+			locks[n] = lf
+		case manifestPath.MatchString(n):
+			b, err := io.ReadAll(io.LimitReader(tr, maxManifest))
+			if err != nil {
+				return nil, err
+			}
+			m, err := parseManifest(b)
+			if err != nil {
+				zlog.Debug(ctx).
+					Str("file", n).
+					Err(err).
+					Msg("unable to parse manifest, skipping")
+				continue
+			}
+			i := strings.Index("/"+n, "/node_modules/")
+			found = append(found, &installed{
+				Root:     strings.TrimSuffix(n[:i], "/"),
+				Key:      path.Dir(n[i:]),
+				manifest: m,
+			})
+		}
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var ret []*claircore.Package
+	for _, in := range found {
+		lf := findLockfile(locks, in.Root)
+		e, inLock := lf[in.Key]
+		if inLock && e.Dev {
+			zlog.Debug(ctx).
+				Str("package", in.Name).
+				Str("root", in.Root).
+				Msg("skipping development dependency")
+			continue
+		}
+		resolved := in.Resolved
+		if inLock && e.Resolved != "" {
+			resolved = e.Resolved
+		}
+		root := in.Root
+		if root == "" {
+			root = "."
+		}
+		ret = append(ret, &claircore.Package{
+			Name:           in.Name,
+			Version:        in.Version,
+			Kind:           claircore.BINARY,
+			PackageDB:      "npm:" + root,
+			RepositoryHint: registry(resolved),
+		})
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].PackageDB != ret[j].PackageDB {
+			return ret[i].PackageDB < ret[j].PackageDB
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+// ManifestPath matches the package.json of an installed package, scoped or
+// not, at any depth of node_modules nesting. Directories starting with "."
+// are npm's own bookkeeping.
+var manifestPath = regexp.MustCompile(`(^|/)node_modules/(@[^/]+/)?[^/@.][^/]*/package\.json$`)
+
+// InElectronApp reports whether the path is within an Electron application
+// directory, which the electron package's scanner handles.
+func inElectronApp(p string) bool {
+	p = "/" + p
+	return strings.Contains(p, "/resources/app/") ||
+		strings.Contains(p, "/resources/app.asar.unpacked/")
+}
+
+// Installed is an installed package.
+type installed struct {
+	// Root is the directory containing the outermost node_modules
+	// directory.
+	Root string
+	// Key is the package's directory relative to Root, as used in lockfiles.
+	Key string
+	manifest
+}
+
+// Manifest is the subset of package.json used.
+type manifest struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Resolved is recorded by older versions of npm on install.
+	Resolved string `json:"_resolved"`
+}
+
+func parseManifest(b []byte) (manifest, error) {
+	var m manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, err
+	}
+	if m.Name == "" || m.Version == "" {
+		return m, errors.New("npm: missing name or version")
+	}
+	return m, nil
+}
+
+// Registry returns the registry URL for a package resolved from "resolved",
+// or the default RepositoryHint if it's not a registry URL.
+func registry(resolved string) string {
+	u, err := url.Parse(resolved)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return RepositoryHint
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,115 @@
+package npm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
+)
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func mkManifest(t *testing.T, name, version string) []byte {
+	return mustJSON(t, map[string]string{"name": name, "version": version})
+}
+
+func pkg(name, version, db, hint string) *claircore.Package {
+	return &claircore.Package{
+		Name:           name,
+		Version:        version,
+		Kind:           claircore.BINARY,
+		PackageDB:      db,
+		RepositoryHint: hint,
+	}
+}
+
+func TestScan(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const mirror = "https://npm.example.com"
+	l := fixture.Layer{
+		// An application with a version 2 lockfile.
+		{Name: "srv/app/package.json", Body: string(mkManifest(t, "app", "1.0.0"))},
+		{Name: "srv/app/package-lock.json", Body: string(mustJSON(t, map[string]interface{}{
+			"lockfileVersion": 2,
+			"packages": map[string]interface{}{
+				"":                               map[string]interface{}{"name": "app", "version": "1.0.0"},
+				"node_modules/express":           map[string]interface{}{"version": "4.17.1", "resolved": mirror + "/express/-/express-4.17.1.tgz"},
+				"node_modules/@types/node":       map[string]interface{}{"version": "16.11.6", "dev": true},
+				"node_modules/jest":              map[string]interface{}{"version": "27.3.1", "dev": true},
+				"node_modules/a/node_modules/ms": map[string]interface{}{"version": "2.0.0", "resolved": "https://registry.npmjs.org/ms/-/ms-2.0.0.tgz"},
+				"node_modules/a":                 map[string]interface{}{"version": "1.2.3"},
+			},
+		}))},
+		{Name: "srv/app/node_modules/express/package.json", Body: string(mkManifest(t, "express", "4.17.1"))},
+		{Name: "srv/app/node_modules/@types/node/package.json", Body: string(mkManifest(t, "@types/node", "16.11.6"))},
+		{Name: "srv/app/node_modules/jest/package.json", Body: string(mkManifest(t, "jest", "27.3.1"))},
+		{Name: "srv/app/node_modules/a/package.json", Body: string(mkManifest(t, "a", "1.2.3"))},
+		{Name: "srv/app/node_modules/a/node_modules/ms/package.json", Body: string(mkManifest(t, "ms", "2.0.0"))},
+		// Not a package root.
+		{Name: "srv/app/node_modules/a/lib/package.json", Body: string(mkManifest(t, "internal", "0.0.0"))},
+		// Npm bookkeeping.
+		{Name: "srv/app/node_modules/.bin/package.json", Body: string(mkManifest(t, "bin", "0.0.0"))},
+		// A global install with a version 1 lockfile recording an old
+		// install's resolved URL.
+		{Name: "usr/lib/package-lock.json", Body: string(mustJSON(t, map[string]interface{}{
+			"lockfileVersion": 1,
+			"dependencies": map[string]interface{}{
+				"npm": map[string]interface{}{
+					"version": "6.14.15",
+					"dependencies": map[string]interface{}{
+						"semver": map[string]interface{}{"version": "5.7.1", "dev": true},
+					},
+				},
+			},
+		}))},
+		{Name: "usr/lib/node_modules/npm/package.json", Body: string(mkManifest(t, "npm", "6.14.15"))},
+		{Name: "usr/lib/node_modules/npm/node_modules/semver/package.json", Body: string(mkManifest(t, "semver", "5.7.1"))},
+		{Name: "usr/lib/node_modules/npm/node_modules/abbrev/package.json", Body: string(mustJSON(t, map[string]string{
+			"name": "abbrev", "version": "1.1.1", "_resolved": mirror + "/abbrev/-/abbrev-1.1.1.tgz",
+		}))},
+		// Handled by the electron scanner.
+		{Name: "opt/App/resources/app/node_modules/ms/package.json", Body: string(mkManifest(t, "ms", "2.1.3"))},
+	}.Local(t)
+
+	got, err := (&Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Package{
+		pkg("a", "1.2.3", "npm:srv/app", RepositoryHint),
+		pkg("express", "4.17.1", "npm:srv/app", mirror),
+		pkg("ms", "2.0.0", "npm:srv/app", RepositoryHint),
+		pkg("abbrev", "1.1.1", "npm:usr/lib", mirror),
+		pkg("npm", "6.14.15", "npm:usr/lib", RepositoryHint),
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestIsLockfile(t *testing.T) {
+	for p, want := range map[string]bool{
+		"package-lock.json":                                      true,
+		"srv/app/npm-shrinkwrap.json":                            true,
+		"srv/app/node_modules/.package-lock.json":                true,
+		"srv/app/node_modules/a/package-lock.json":               false,
+		"srv/app/node_modules/a/node_modules/.package-lock.json": false,
+		"srv/app/package.json":                                   false,
+	} {
+		if got := isLockfile(p); got != want {
+			t.Errorf("%s: got: %v, want: %v", p, got, want)
+		}
+	}
+}
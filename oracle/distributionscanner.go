@@ -22,42 +22,52 @@ import (
 
 const (
 	scannerName    = "oracle"
-	scannerVersion = "v0.0.1"
+	scannerVersion = "v0.0.2"
 	scannerKind    = "distribution"
 )
 
-const osReleasePath = `etc/os-release`
-
-// Oracle Linux 5 will not have os-release and only has etc/issue
-const issuePath = `etc/issue`
+const (
+	// Oracle-release is present in every release and names only Oracle Linux,
+	// so it's checked first.
+	oracleReleasePath = `etc/oracle-release`
+	osReleasePath     = `etc/os-release`
+	// Oracle Linux 5 will not have os-release and only has etc/issue
+	issuePath = `etc/issue`
+)
 
 type oracleRegex struct {
 	release Release
 	regexp  *regexp.Regexp
 }
 
+// The regexps match both os-release's PRETTY_NAME ("Oracle Linux Server 8.4")
+// and the oracle-release and issue files ("Oracle Linux Server release 8.4").
 var oracleRegexes = []oracleRegex{
 	{
 		release: Five,
-		// regex for /etc/issue
-		regexp: regexp.MustCompile(`(?is)Oracle Linux Server release ?5(\.\d*)?`),
+		regexp:  regexp.MustCompile(`(?is)Oracle Linux Server (release )?5(\.\d*)?`),
 	},
 	{
 		release: Six,
-		regexp:  regexp.MustCompile(`(?is)Oracle Linux Server 6(\.\d*)?`),
+		regexp:  regexp.MustCompile(`(?is)Oracle Linux Server (release )?6(\.\d*)?`),
 	},
 	{
 		release: Seven,
-		regexp:  regexp.MustCompile(`(?is)Oracle Linux Server 7(\.\d*)?`),
+		regexp:  regexp.MustCompile(`(?is)Oracle Linux Server (release )?7(\.\d*)?`),
 	},
 	{
 		release: Eight,
-		regexp:  regexp.MustCompile(`(?is)Oracle Linux Server 8(\.\d*)?`),
+		regexp:  regexp.MustCompile(`(?is)Oracle Linux Server (release )?8(\.\d*)?`),
+	},
+	{
+		release: Nine,
+		regexp:  regexp.MustCompile(`(?is)Oracle Linux Server (release )?9(\.\d*)?`),
 	},
 }
 
 var _ indexer.DistributionScanner = (*DistributionScanner)(nil)
 var _ indexer.VersionedScanner = (*DistributionScanner)(nil)
+var _ indexer.PathDeclarer = (*DistributionScanner)(nil)
 
 // DistributionScanner attempts to discover if a layer
 // displays characteristics of a Oracle distribution
@@ -72,10 +82,16 @@ func (*DistributionScanner) Version() string { return scannerVersion }
 // Kind implements scanner.VersionedScanner.
 func (*DistributionScanner) Kind() string { return scannerKind }
 
-// Scan will inspect the layer for an os-release or lsb-release file
-// and perform a regex match for keywords indicating the associated Oracle release
+// Paths implements indexer.PathDeclarer.
+func (*DistributionScanner) Paths() []string {
+	return []string{oracleReleasePath, osReleasePath, issuePath}
+}
+
+// Scan will inspect the layer for an oracle-release, os-release, or issue
+// file, in that order, and perform a regex match for keywords indicating the
+// associated Oracle release.
 //
-// If neither file is found a (nil,nil) is returned.
+// If no file is found a (nil,nil) is returned.
 // If the files are found but all regexp fail to match an empty slice is returned.
 func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Distribution, error) {
 	defer trace.StartRegion(ctx, "Scanner.Scan").End()
@@ -85,12 +101,16 @@ func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]
 		label.String("layer", l.Hash.String()))
 	zlog.Debug(ctx).Msg("start")
 	defer zlog.Debug(ctx).Msg("done")
-	files, err := l.Files(osReleasePath, issuePath)
-	if err != nil {
-		zlog.Debug(ctx).Msg("didn't find an os-release or issues file")
+	files, err := l.Files(oracleReleasePath, osReleasePath, issuePath)
+	if err != nil || len(files) == 0 {
+		zlog.Debug(ctx).Msg("didn't find an oracle-release, os-release, or issue file")
 		return nil, nil
 	}
-	for _, buff := range files {
+	for _, p := range []string{oracleReleasePath, osReleasePath, issuePath} {
+		buff, ok := files[p]
+		if !ok {
+			continue
+		}
 		dist := ds.parse(buff)
 		if dist != nil {
 			return []*claircore.Distribution{dist}, nil
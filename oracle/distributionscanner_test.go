@@ -2,11 +2,35 @@ package oracle
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
 )
 
+var nineOSRelease []byte = []byte(`NAME="Oracle Linux Server"
+VERSION="9.0"
+ID="ol"
+ID_LIKE="fedora"
+VARIANT="Server"
+VARIANT_ID="server"
+VERSION_ID="9.0"
+PLATFORM_ID="platform:el9"
+PRETTY_NAME="Oracle Linux Server 9.0"
+ANSI_COLOR="0;31"
+CPE_NAME="cpe:/o:oracle:linux:9:0:server"
+HOME_URL="https://linux.oracle.com/"
+BUG_REPORT_URL="https://bugzilla.oracle.com/"
+
+ORACLE_BUGZILLA_PRODUCT="Oracle Linux 9"
+ORACLE_BUGZILLA_PRODUCT_VERSION=9.0
+ORACLE_SUPPORT_PRODUCT="Oracle Linux"
+ORACLE_SUPPORT_PRODUCT_VERSION=9.0`)
+
 var eightOSRelease []byte = []byte(`NAME="Oracle Linux Server"
 VERSION="8.0"
 ID="ol"
@@ -71,6 +95,21 @@ func TestDistributionScanner(t *testing.T) {
 		release Release
 		file    []byte
 	}{
+		{
+			name:    "9.0",
+			release: Nine,
+			file:    nineOSRelease,
+		},
+		{
+			name:    "oracle-release 9.0",
+			release: Nine,
+			file:    []byte("Oracle Linux Server release 9.0\n"),
+		},
+		{
+			name:    "oracle-release 7.9",
+			release: Seven,
+			file:    []byte("Oracle Linux Server release 7.9\n"),
+		},
 		{
 			name:    "8.0",
 			release: Eight,
@@ -102,3 +141,60 @@ func TestDistributionScanner(t *testing.T) {
 		})
 	}
 }
+
+func TestDistributionScannerFiles(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	table := []struct {
+		name  string
+		files map[string]string
+		want  *claircore.Distribution
+	}{
+		{
+			name: "OracleRelease",
+			files: map[string]string{
+				"etc/oracle-release": "Oracle Linux Server release 8.6\n",
+				// A stale os-release shouldn't win.
+				"etc/os-release": string(sevenOSRelease),
+			},
+			want: eightDist,
+		},
+		{
+			name: "OSRelease",
+			files: map[string]string{
+				"etc/os-release": string(nineOSRelease),
+				"etc/issue":      "\\S\nKernel \\r on an \\m\n",
+			},
+			want: nineDist,
+		},
+		{
+			name: "Issue",
+			files: map[string]string{
+				"etc/issue": string(fiveIssue),
+			},
+			want: fiveDist,
+		},
+		{
+			name: "None",
+			files: map[string]string{
+				"etc/hostname": "localhost\n",
+			},
+		},
+	}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			var s DistributionScanner
+			ds, err := s.Scan(ctx, fixture.Files(tc.files).Local(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got *claircore.Distribution
+			if len(ds) != 0 {
+				got = ds[0]
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+}
@@ -61,5 +61,5 @@ func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, v
 		vulnVer = version.NewVersion(vuln.FixedInVersion)
 		cmp = func(i int) bool { return i == version.LESS }
 	}
-	return cmp(pkgVer.Compare(vulnVer)) && vuln.ArchOperation.Cmp(record.Package.Arch, vuln.Package.Arch), nil
+	return cmp(pkgVer.Compare(vulnVer)) && vuln.ArchOperation.CmpRecord(record, vuln.Package.Arch), nil
 }
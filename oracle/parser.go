@@ -22,6 +22,7 @@ const (
 	OracleLinux6Platform = "Oracle Linux 6"
 	OracleLinux7Platform = "Oracle Linux 7"
 	OracleLinux8Platform = "Oracle Linux 8"
+	OracleLinux9Platform = "Oracle Linux 9"
 )
 
 // a mapping between oval platform string to claircore distribution
@@ -30,6 +31,7 @@ var platformToDist = map[string]*claircore.Distribution{
 	OracleLinux6Platform: sixDist,
 	OracleLinux7Platform: sevenDist,
 	OracleLinux8Platform: eightDist,
+	OracleLinux9Platform: nineDist,
 }
 
 var _ driver.Parser = (*Updater)(nil)
@@ -46,6 +48,7 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 		return nil, fmt.Errorf("oracle: unable to decode OVAL document: %w", err)
 	}
 	zlog.Debug(ctx).Msg("xml decoded")
+	ovalutil.ApplyClassPolicy(ctx, &root, u.classes)
 	protoVulns := func(def oval.Definition) ([]*claircore.Vulnerability, error) {
 		// In all oracle databases tested a single
 		// and correct platform string can be found inside a definition
@@ -55,6 +58,7 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 			Name:               def.Title,
 			Description:        def.Description,
 			Issued:             def.Advisory.Issued.Date,
+			Modified:           def.Advisory.Updated.Date,
 			Links:              ovalutil.Links(def),
 			Severity:           def.Advisory.Severity,
 			NormalizedSeverity: NormalizeSeverity(def.Advisory.Severity),
@@ -1,11 +1,16 @@
 package oracle
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
 
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/pkg/ovalutil"
 )
@@ -18,6 +23,7 @@ const (
 // Updater implements driver.Updater for Oracle Linux.
 type Updater struct {
 	year             int
+	classes          ovalutil.ClassPolicy
 	ovalutil.Fetcher // Fetch method promoted via embed
 }
 
@@ -33,7 +39,8 @@ func NewUpdater(year int, opts ...Option) (*Updater, error) {
 		uri = fmt.Sprintf(baseURL, year)
 	}
 	u := Updater{
-		year: year,
+		year:    year,
+		classes: ovalutil.PreferPatch,
 	}
 	var err error
 	u.Fetcher.URL, err = url.Parse(uri)
@@ -80,6 +87,44 @@ func WithURL(uri, compression string) Option {
 	}
 }
 
+// WithClassPolicy returns an Option that sets how the Updater handles the
+// classes of definitions in the database.
+//
+// If this Option is not supplied, "patch" class definitions, which the ELSA
+// advisories are published as, are preferred.
+func WithClassPolicy(p ovalutil.ClassPolicy) Option {
+	return func(u *Updater) error {
+		u.classes = p
+		return nil
+	}
+}
+
+// Config is the configuration for an Updater, in addition to the keys of
+// ovalutil.FetcherConfig.
+type Config struct {
+	Classes *ovalutil.ClassPolicy `json:"classes" yaml:"classes"`
+}
+
+// Configure implements driver.Configurable.
+func (u *Updater) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "oracle/Updater.Configure"))
+	if err := u.Fetcher.Configure(ctx, f, c); err != nil {
+		return err
+	}
+	var cfg Config
+	if err := f(&cfg); err != nil {
+		return err
+	}
+	if cfg.Classes != nil {
+		u.classes = *cfg.Classes
+		zlog.Info(ctx).
+			Stringer("policy", u.classes).
+			Msg("configured class policy")
+	}
+	return nil
+}
+
 var (
 	_ driver.Updater      = (*Updater)(nil)
 	_ driver.Configurable = (*Updater)(nil)
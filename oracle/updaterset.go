@@ -3,14 +3,14 @@ package oracle
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/clock"
 )
 
-func UpdaterSet(_ context.Context) (driver.UpdaterSet, error) {
+func UpdaterSet(ctx context.Context) (driver.UpdaterSet, error) {
 	us := driver.NewUpdaterSet()
-	for year, lim := 2007, time.Now().Year(); year != lim; year++ {
+	for year, lim := 2007, clock.Now(ctx).Year(); year != lim; year++ {
 		u, err := NewUpdater(year)
 		if err != nil {
 			return us, fmt.Errorf("unable to create oracle updater: %v", err)
@@ -0,0 +1,40 @@
+package osrelease
+
+import (
+	"context"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+var _ indexer.Coalescer = (*Coalescer)(nil)
+
+// Coalescer reports the distribution described by the os-release file in the
+// final filesystem: the one found in the highest layer.
+//
+// The returned IndexReport contains no packages, only Distributions.
+type Coalescer struct{}
+
+// NewCoalescer is a constructor for a Coalescer.
+func NewCoalescer(_ context.Context) (indexer.Coalescer, error) {
+	return &Coalescer{}, nil
+}
+
+// Coalesce implements indexer.Coalescer.
+func (*Coalescer) Coalesce(ctx context.Context, ls []*indexer.LayerArtifacts) (*claircore.IndexReport, error) {
+	ir := &claircore.IndexReport{
+		Environments:  map[string][]*claircore.Environment{},
+		Packages:      map[string]*claircore.Package{},
+		Distributions: map[string]*claircore.Distribution{},
+		Repositories:  map[string]*claircore.Repository{},
+	}
+	for i := len(ls) - 1; i >= 0; i-- {
+		if len(ls[i].Dist) == 0 {
+			continue
+		}
+		d := ls[i].Dist[0]
+		ir.Distributions[d.ID] = d
+		break
+	}
+	return ir, nil
+}
@@ -0,0 +1,26 @@
+package osrelease
+
+import (
+	"context"
+
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// NewEcosystem provides the generic os-release distribution scanner.
+//
+// The ecosystem is a fallback: its distribution is only reported when no
+// distribution-specific scanner identified one, so that images of
+// distributions without dedicated support, such as Arch, Wolfi, or Gentoo,
+// still report what they are.
+func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
+	return &indexer.Ecosystem{
+		Name:            "osrelease",
+		PackageScanners: func(_ context.Context) ([]indexer.PackageScanner, error) { return nil, nil },
+		DistributionScanners: func(_ context.Context) ([]indexer.DistributionScanner, error) {
+			return []indexer.DistributionScanner{&Scanner{}}, nil
+		},
+		RepositoryScanners: func(_ context.Context) ([]indexer.RepositoryScanner, error) { return nil, nil },
+		Coalescer:          NewCoalescer,
+		Fallback:           true,
+	}
+}
@@ -0,0 +1,108 @@
+package osrelease
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/test/fixture"
+)
+
+const wolfiOSRelease = `ID=wolfi
+NAME="Wolfi"
+PRETTY_NAME="Wolfi"
+VERSION_ID="20230201"
+HOME_URL="https://wolfi.dev"
+`
+
+func TestScanFiles(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	wolfi := &claircore.Distribution{
+		DID:        "wolfi",
+		Name:       "Wolfi",
+		PrettyName: "Wolfi",
+		VersionID:  "20230201",
+	}
+	tt := []struct {
+		name  string
+		files map[string]string
+		links map[string]string
+		want  []*claircore.Distribution
+	}{
+		{
+			name:  "Etc",
+			files: map[string]string{"etc/os-release": wolfiOSRelease},
+			want:  []*claircore.Distribution{wolfi},
+		},
+		{
+			name:  "UsrLib",
+			files: map[string]string{"usr/lib/os-release": wolfiOSRelease},
+			want:  []*claircore.Distribution{wolfi},
+		},
+		{
+			name:  "Symlink",
+			files: map[string]string{"usr/lib/os-release": wolfiOSRelease},
+			links: map[string]string{"etc/os-release": "../usr/lib/os-release"},
+			want:  []*claircore.Distribution{wolfi},
+		},
+		{
+			name: "EtcFirst",
+			files: map[string]string{
+				"etc/os-release":     wolfiOSRelease,
+				"usr/lib/os-release": "ID=arch\n",
+			},
+			want: []*claircore.Distribution{wolfi},
+		},
+		{
+			name: "Lenient",
+			files: map[string]string{
+				"etc/os-release": "ID=gentoo\nthis isn't a pair\nVERSION_ID=\nNAME=Gentoo\n",
+			},
+			want: []*claircore.Distribution{{DID: "gentoo", Name: "Gentoo"}},
+		},
+		{
+			name:  "Elsewhere",
+			files: map[string]string{"opt/app/etc/os-release": wolfiOSRelease},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			var s Scanner
+			l := fixture.Files(tc.files)
+			for n, to := range tc.links {
+				l = append(l, fixture.Entry{Name: n, Link: to})
+			}
+			got, err := s.Scan(ctx, l.Local(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+}
+
+func TestCoalescer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	lower := &claircore.Distribution{ID: "1", DID: "wolfi", VersionID: "20230101"}
+	upper := &claircore.Distribution{ID: "2", DID: "wolfi", VersionID: "20230201"}
+	c, _ := NewCoalescer(ctx)
+	ir, err := c.Coalesce(ctx, []*indexer.LayerArtifacts{
+		{Dist: []*claircore.Distribution{lower}},
+		{Dist: []*claircore.Distribution{upper}},
+		{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*claircore.Distribution{"2": upper}
+	if got := ir.Distributions; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
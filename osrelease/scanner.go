@@ -2,13 +2,12 @@
 package osrelease
 
 import (
-	"archive/tar"
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"path/filepath"
 	"runtime/trace"
 	"strings"
 
@@ -23,14 +22,19 @@ import (
 
 const (
 	scannerName    = "os-release"
-	scannerVersion = "v0.0.2"
+	scannerVersion = "v0.0.3"
 	scannerKind    = "distribution"
 )
 
-const fpath = `etc/os-release`
+const (
+	osReleasePath    = `etc/os-release`
+	libOSReleasePath = `usr/lib/os-release`
+)
 
 var _ indexer.DistributionScanner = (*Scanner)(nil)
 var _ indexer.VersionedScanner = (*Scanner)(nil)
+var _ indexer.FileLimiter = (*Scanner)(nil)
+var _ indexer.PathDeclarer = (*Scanner)(nil)
 
 // Scanner implements a scanner.DistributionScanner that examines os-release
 // files, as documented at
@@ -46,8 +50,18 @@ func (*Scanner) Version() string { return scannerVersion }
 // Kind implements scanner.VersionedScanner.
 func (*Scanner) Kind() string { return scannerKind }
 
+// Paths implements indexer.PathDeclarer.
+func (*Scanner) Paths() []string {
+	return []string{osReleasePath, libOSReleasePath}
+}
+
+// FileLimit implements indexer.FileLimiter.
+//
+// An os-release file is a handful of lines; anything over a megabyte isn't one.
+func (*Scanner) FileLimit() (int64, bool) { return 1 << 20, false }
+
 // Scan reports any found os-release Distribution information in the provided
-// layer.
+// layer, from "/etc/os-release" or, failing that, "/usr/lib/os-release".
 //
 // It's an expected outcome to return (nil, nil) when the os-release file is not
 // present in the layer.
@@ -60,40 +74,31 @@ func (s *Scanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Di
 	zlog.Debug(ctx).Msg("start")
 	defer zlog.Debug(ctx).Msg("done")
 
-	r, err := l.Reader()
-	if err != nil {
-		return nil, fmt.Errorf("osrelease: unable to open layer: %w", err)
-	}
-	defer r.Close()
-
-	// iterate through the tar and attempt to parse each os-release file encountered.
-	// on a successful parse return the distribution.
-	tr := tar.NewReader(r)
-	hdr, err := tr.Next()
-	for ; err == nil && ctx.Err() == nil; hdr, err = tr.Next() {
-		switch hdr.Typeflag {
-		case tar.TypeReg:
-			base := filepath.Base(hdr.Name)
-			if base != "os-release" {
-				continue
-			}
-			d, err := parse(ctx, tr)
-			if err == nil {
-				return []*claircore.Distribution{d}, nil
-			}
-		}
-	}
-	switch err {
-	case nil:
-	case io.EOF: // OK
+	files, err := l.Files(osReleasePath, libOSReleasePath)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, claircore.ErrNotFound):
+		zlog.Debug(ctx).Msg("didn't find an os-release file")
+		return nil, nil
 	default:
-		return nil, fmt.Errorf("encountered a tar error: %v", err)
+		return nil, fmt.Errorf("osrelease: unable to read layer: %w", err)
 	}
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+	// The file in etc takes precedence, per os-release(5).
+	for _, p := range []string{osReleasePath, libOSReleasePath} {
+		b, ok := files[p]
+		if !ok {
+			continue
+		}
+		d, err := parse(ctx, b)
+		if err != nil {
+			zlog.Info(ctx).
+				Err(err).
+				Str("path", p).
+				Msg("unable to parse os-release file")
+			continue
+		}
+		return []*claircore.Distribution{d}, nil
 	}
-
-	zlog.Debug(ctx).Msg("didn't find an os-release file")
 	return nil, nil
 }
 
@@ -119,10 +124,17 @@ func parse(ctx context.Context, r io.Reader) (*claircore.Distribution, error) {
 		}
 		eq := bytes.IndexRune(b, '=')
 		if eq == -1 {
-			return nil, fmt.Errorf("osrelease: malformed line %q", s.Text())
+			// Be lenient: a stray line shouldn't cost the rest of the file.
+			zlog.Debug(ctx).
+				Str("line", s.Text()).
+				Msg("skipping malformed line")
+			continue
 		}
 		key := strings.TrimSpace(string(b[:eq]))
 		value := strings.TrimSpace(string(b[eq+1:]))
+		if value == "" {
+			continue
+		}
 
 		// The value side is defined to follow shell-like quoting rules, which I
 		// take to mean:
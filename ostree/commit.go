@@ -0,0 +1,49 @@
+package ostree
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// Commit is the information from an ostree commit object.
+type commit struct {
+	// Metadata holds the string-valued entries of the commit metadata, such
+	// as "version".
+	Metadata map[string]string
+	// Parent is the checksum of the parent commit, if any.
+	Parent  string
+	Subject string
+	Body    string
+	// Timestamp is the commit time.
+	Timestamp time.Time
+}
+
+// CommitMembers describes the commit object's type, "(a{sv}aya(say)sstayay)":
+// metadata, parent checksum, related objects, subject, body, timestamp, root
+// tree checksum, and root metadata checksum.
+var commitMembers = []member{
+	var8Member, varMember, varMember, varMember, varMember, u64Member, varMember, varMember,
+}
+
+// ParseCommit parses the serialized commit object "b".
+func parseCommit(b []byte) (*commit, error) {
+	ms, err := splitTuple(b, commitMembers)
+	if err != nil {
+		return nil, err
+	}
+	var c commit
+	if c.Metadata, err = readStringDict(ms[0]); err != nil {
+		return nil, err
+	}
+	c.Parent = hex.EncodeToString(ms[1])
+	if c.Subject, err = readString(ms[3]); err != nil {
+		return nil, err
+	}
+	if c.Body, err = readString(ms[4]); err != nil {
+		return nil, err
+	}
+	// Ostree stores the timestamp big-endian, regardless of the host.
+	c.Timestamp = time.Unix(int64(binary.BigEndian.Uint64(ms[5])), 0).UTC()
+	return &c, nil
+}
@@ -0,0 +1,164 @@
+package ostree
+
+import (
+	"bytes"
+	"errors"
+)
+
+// This file implements just enough of the GVariant serialization format to
+// read ostree commit objects. See "GVariant Serialisation" by Allison Lortie
+// for the details of the format.
+
+var errMalformed = errors.New("ostree: malformed gvariant")
+
+// Member describes a member of a tuple or an element of an array.
+type member struct {
+	// Align is the member's alignment.
+	Align int
+	// Fixed is the member's size, or 0 if the member is variable-sized.
+	Fixed int
+}
+
+var (
+	// Var is a variable-sized, byte aligned member, such as "s" or "ay".
+	varMember = member{Align: 1}
+	// Var8 is a variable-sized, 8-byte aligned member, such as "a{sv}" or "v".
+	var8Member = member{Align: 8}
+	// U64 is an unsigned 64-bit integer, "t".
+	u64Member = member{Align: 8, Fixed: 8}
+)
+
+// OffsetSize returns the size of the framing offsets in a container of n
+// bytes.
+func offsetSize(n int) int {
+	switch {
+	case n == 0:
+		return 0
+	case n <= 0xff:
+		return 1
+	case n <= 0xffff:
+		return 2
+	case n <= 0xffffffff:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// ReadOffset reads a little-endian framing offset.
+func readOffset(b []byte) int {
+	var n uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		n = n<<8 | uint64(b[i])
+	}
+	return int(n)
+}
+
+func align(n, a int) int {
+	return (n + a - 1) &^ (a - 1)
+}
+
+// SplitTuple returns the serialized members of the tuple "b".
+func splitTuple(b []byte, ms []member) ([][]byte, error) {
+	osz := offsetSize(len(b))
+	out := make([][]byte, len(ms))
+	pos, end, n := 0, len(b), 0
+	for i, m := range ms {
+		pos = align(pos, m.Align)
+		var stop int
+		switch {
+		case m.Fixed != 0:
+			stop = pos + m.Fixed
+		case i == len(ms)-1:
+			stop = end
+		default:
+			n++
+			at := len(b) - n*osz
+			if at < 0 {
+				return nil, errMalformed
+			}
+			stop = readOffset(b[at : at+osz])
+			end = at
+		}
+		if pos > stop || stop > end {
+			return nil, errMalformed
+		}
+		out[i] = b[pos:stop]
+		pos = stop
+	}
+	return out, nil
+}
+
+// SplitArray returns the serialized elements of the array "b", which has
+// variable-sized elements with the alignment "a".
+func splitArray(b []byte, a int) ([][]byte, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	osz := offsetSize(len(b))
+	last := readOffset(b[len(b)-osz:])
+	if last > len(b) || (len(b)-last)%osz != 0 {
+		return nil, errMalformed
+	}
+	out := make([][]byte, (len(b)-last)/osz)
+	pos := 0
+	for i := range out {
+		pos = align(pos, a)
+		at := last + i*osz
+		end := readOffset(b[at : at+osz])
+		if end < pos || end > last {
+			return nil, errMalformed
+		}
+		out[i] = b[pos:end]
+		pos = end
+	}
+	return out, nil
+}
+
+// ReadString returns the string "b" without its trailing nul.
+func readString(b []byte) (string, error) {
+	if len(b) == 0 || b[len(b)-1] != 0 {
+		return "", errMalformed
+	}
+	return string(b[:len(b)-1]), nil
+}
+
+// SplitVariant returns the value and type string of the variant "b".
+func splitVariant(b []byte) ([]byte, string, error) {
+	i := bytes.LastIndexByte(b, 0)
+	if i == -1 {
+		return nil, "", errMalformed
+	}
+	return b[:i], string(b[i+1:]), nil
+}
+
+// ReadStringDict returns the string-valued entries of the a{sv} dictionary
+// "b". Entries with other types of values are skipped.
+func readStringDict(b []byte) (map[string]string, error) {
+	es, err := splitArray(b, 8)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(es))
+	for _, e := range es {
+		kv, err := splitTuple(e, []member{varMember, var8Member})
+		if err != nil {
+			return nil, err
+		}
+		k, err := readString(kv[0])
+		if err != nil {
+			return nil, err
+		}
+		v, t, err := splitVariant(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		if t != "s" {
+			continue
+		}
+		if out[k], err = readString(v); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
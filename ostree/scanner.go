@@ -0,0 +1,235 @@
+// Package ostree contains components for interrogating ostree repositories in
+// container layers.
+//
+// Ostree-native images, such as bootc images and Fedora Silverblue
+// derivatives, carry the repository they were built from under
+// "sysroot/ostree/repo" alongside the checked-out tree. The packages in such
+// images are recorded in the rpm database at "usr/share/rpm", which the rpm
+// package scanner finds like any other; the scanner here reports which ostree
+// commits the layer contains.
+package ostree
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"runtime/trace"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// RepositoryKey is the Key of the Repositories reported by the
+// RepositoryScanner.
+const RepositoryKey = "ostree"
+
+var (
+	_ indexer.VersionedScanner  = (*RepositoryScanner)(nil)
+	_ indexer.RepositoryScanner = (*RepositoryScanner)(nil)
+)
+
+// RepositoryScanner reports the ostree commits found in a layer.
+//
+// A Repository is reported for every ref, named for the ref. Commits that
+// aren't referenced by any ref are reported with their checksum as the name.
+// The URI is of the form "ostree:{checksum}", with the commit's "version"
+// metadata, if any, as a query parameter.
+//
+// The zero value is ready to use.
+type RepositoryScanner struct{}
+
+// Name implements scanner.VersionedScanner.
+func (*RepositoryScanner) Name() string { return "ostree" }
+
+// Version implements scanner.VersionedScanner.
+func (*RepositoryScanner) Version() string { return "1" }
+
+// Kind implements scanner.VersionedScanner.
+func (*RepositoryScanner) Kind() string { return "repository" }
+
+// Scan implements scanner.RepositoryScanner.
+//
+// A return of (nil, nil) is expected if there's no ostree repository.
+func (rs *RepositoryScanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Repository, error) {
+	defer trace.StartRegion(ctx, "RepositoryScanner.Scan").End()
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "ostree/RepositoryScanner.Scan"),
+		label.String("version", rs.Version()),
+		label.String("layer", l.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+
+	r, err := l.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	repos := make(map[string]*repo)
+	get := func(root string) *repo {
+		r, ok := repos[root]
+		if !ok {
+			r = &repo{
+				Refs:    make(map[string]string),
+				Commits: make(map[string]*commit),
+			}
+			repos[root] = r
+		}
+		return r
+	}
+	var buf bytes.Buffer
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		n := strings.TrimPrefix(path.Clean("/"+h.Name), "/")
+		root, kind, rest := splitRepoPath(n)
+		switch kind {
+		case "refs":
+			buf.Reset()
+			if _, err := buf.ReadFrom(io.LimitReader(tr, 1024)); err != nil {
+				return nil, err
+			}
+			sum := strings.TrimSpace(buf.String())
+			if !isChecksum(sum) {
+				continue
+			}
+			// Refs in the "heads" directory are local, and named without
+			// the prefix. Remote refs are named "remote:ref", like ostree
+			// does.
+			ref := rest
+			switch {
+			case strings.HasPrefix(ref, "heads/"):
+				ref = strings.TrimPrefix(ref, "heads/")
+			case strings.HasPrefix(ref, "remotes/"):
+				ref = strings.Replace(strings.TrimPrefix(ref, "remotes/"), "/", ":", 1)
+			}
+			get(root).Refs[ref] = sum
+		case "objects":
+			sum := strings.Replace(strings.TrimSuffix(rest, ".commit"), "/", "", 1)
+			if !strings.HasSuffix(rest, ".commit") || !isChecksum(sum) {
+				continue
+			}
+			buf.Reset()
+			if _, err := buf.ReadFrom(tr); err != nil {
+				return nil, err
+			}
+			c, err := parseCommit(buf.Bytes())
+			if err != nil {
+				zlog.Info(ctx).
+					Err(err).
+					Str("file", n).
+					Msg("unable to parse commit, skipping")
+				continue
+			}
+			get(root).Commits[sum] = c
+		}
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var out []*claircore.Repository
+	for root, r := range repos {
+		zlog.Debug(ctx).
+			Str("repo", root).
+			Int("refs", len(r.Refs)).
+			Int("commits", len(r.Commits)).
+			Msg("found ostree repository")
+		out = append(out, r.Repositories()...)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name == out[j].Name {
+			return out[i].URI < out[j].URI
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// Repo is the refs and commits found for an ostree repository.
+type repo struct {
+	// Refs maps ref names to commit checksums.
+	Refs map[string]string
+	// Commits maps checksums to commits.
+	Commits map[string]*commit
+}
+
+// Repositories returns a Repository for every ref and every unreferenced
+// commit.
+func (r *repo) Repositories() []*claircore.Repository {
+	var out []*claircore.Repository
+	seen := make(map[string]struct{}, len(r.Refs))
+	for ref, sum := range r.Refs {
+		seen[sum] = struct{}{}
+		out = append(out, r.repository(ref, sum))
+	}
+	for sum := range r.Commits {
+		if _, ok := seen[sum]; !ok {
+			out = append(out, r.repository(sum, sum))
+		}
+	}
+	return out
+}
+
+func (r *repo) repository(name, sum string) *claircore.Repository {
+	u := url.URL{Scheme: "ostree", Opaque: sum}
+	if c, ok := r.Commits[sum]; ok {
+		if v := c.Metadata["version"]; v != "" {
+			u.RawQuery = url.Values{"version": {v}}.Encode()
+		}
+	}
+	return &claircore.Repository{
+		Name: name,
+		Key:  RepositoryKey,
+		URI:  u.String(),
+	}
+}
+
+// SplitRepoPath splits a path within an ostree repository into the path of
+// the repository, the top-level directory within it ("refs" or "objects"),
+// and the remainder. Empty strings are returned for paths that don't look
+// like they're within a repository.
+//
+// Repositories are recognized by an "ostree/repo" or "repo" directory
+// containing the "refs" or "objects" directories.
+func splitRepoPath(p string) (root, kind, rest string) {
+	for _, k := range []string{"refs", "objects"} {
+		i := strings.Index(p, "/"+k+"/")
+		if i == -1 {
+			continue
+		}
+		root := p[:i]
+		if path.Base(root) != "repo" {
+			continue
+		}
+		return root, k, p[i+len(k)+2:]
+	}
+	return "", "", ""
+}
+
+// IsChecksum reports whether "s" looks like an ostree object checksum: 64
+// lower-case hex digits.
+func isChecksum(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, r := range s {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package ostree
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// The functions here serialize GVariants, to construct test commit objects.
+
+func encString(s string) []byte { return append([]byte(s), 0) }
+
+func encVariant(v []byte, t string) []byte {
+	return append(append(append([]byte(nil), v...), 0), t...)
+}
+
+func appendOffset(b []byte, off, sz int) []byte {
+	for i := 0; i < sz; i++ {
+		b = append(b, byte(off>>(8*i)))
+	}
+	return b
+}
+
+func encTuple(vs [][]byte, ms []member) []byte {
+	for sz := 1; ; sz *= 2 {
+		var b []byte
+		var offs []int
+		for i, m := range ms {
+			for len(b)%m.Align != 0 {
+				b = append(b, 0)
+			}
+			b = append(b, vs[i]...)
+			if m.Fixed == 0 && i != len(ms)-1 {
+				offs = append(offs, len(b))
+			}
+		}
+		for i := len(offs) - 1; i >= 0; i-- {
+			b = appendOffset(b, offs[i], sz)
+		}
+		if len(offs) == 0 || offsetSize(len(b)) == sz {
+			return b
+		}
+	}
+}
+
+func encArray(es [][]byte, a int) []byte {
+	if len(es) == 0 {
+		return nil
+	}
+	for sz := 1; ; sz *= 2 {
+		var b []byte
+		var offs []int
+		for _, e := range es {
+			for len(b)%a != 0 {
+				b = append(b, 0)
+			}
+			b = append(b, e...)
+			offs = append(offs, len(b))
+		}
+		for _, o := range offs {
+			b = appendOffset(b, o, sz)
+		}
+		if offsetSize(len(b)) == sz {
+			return b
+		}
+	}
+}
+
+func checksum(s string) []byte {
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
+
+func mkCommit(t testing.TB, version, subject string, parent []byte, ts time.Time) []byte {
+	t.Helper()
+	entry := func(k string, v []byte) []byte {
+		return encTuple([][]byte{encString(k), v}, []member{varMember, var8Member})
+	}
+	md := encArray([][]byte{
+		entry("ostree.bootable", encVariant([]byte{1}, "b")),
+		entry("version", encVariant(encString(version), "s")),
+	}, 8)
+	tb := make([]byte, 8)
+	binary.BigEndian.PutUint64(tb, uint64(ts.Unix()))
+	return encTuple([][]byte{
+		md,
+		parent,
+		nil,
+		encString(subject),
+		encString(""),
+		tb,
+		checksum("dirtree"),
+		checksum("dirmeta"),
+	}, commitMembers)
+}
+
+func TestParseCommit(t *testing.T) {
+	ts := time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC)
+	for _, subject := range []string{"", "short", string(make([]byte, 300))} {
+		b := mkCommit(t, "35.20211101.0", subject, checksum("parent"), ts)
+		got, err := parseCommit(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &commit{
+			Metadata:  map[string]string{"version": "35.20211101.0"},
+			Parent:    hex.EncodeToString(checksum("parent")),
+			Subject:   subject,
+			Timestamp: ts,
+		}
+		if !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	}
+	if _, err := parseCommit([]byte("not a commit")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestRepositoryScanner(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ts := time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC)
+	head := hex.EncodeToString(checksum("head"))
+	old := hex.EncodeToString(checksum("old"))
+	const root = "sysroot/ostree/repo/"
+	files := map[string][]byte{
+		root + "config": []byte("[core]\nrepo_version=1\nmode=bare\n"),
+		root + "refs/heads/fedora/35/x86_64/silverblue":           []byte(head + "\n"),
+		root + "refs/remotes/fedora/fedora/35/x86_64/base":        []byte(head + "\n"),
+		root + "refs/heads/broken":                                []byte("nope\n"),
+		root + "objects/" + head[:2] + "/" + head[2:] + ".commit": mkCommit(t, "35.20211101.0", "", checksum("old"), ts),
+		root + "objects/" + old[:2] + "/" + old[2:] + ".commit":   mkCommit(t, "35.20211001.0", "", nil, ts),
+		root + "objects/" + old[:2] + "/" + old[2:] + ".dirtree":  nil,
+		"usr/share/rpm/rpmdb.sqlite":                              nil,
+	}
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := tar.NewWriter(f)
+	for n, b := range files {
+		if err := w.WriteHeader(&tar.Header{
+			Name:     n,
+			Typeflag: tar.TypeReg,
+			Size:     int64(len(b)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var l claircore.Layer
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := (&RepositoryScanner{}).Scan(ctx, &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Repository{
+		{Name: old, Key: RepositoryKey, URI: "ostree:" + old + "?version=35.20211001.0"},
+		{Name: "fedora/35/x86_64/silverblue", Key: RepositoryKey, URI: "ostree:" + head + "?version=35.20211101.0"},
+		{Name: "fedora:fedora/35/x86_64/base", Key: RepositoryKey, URI: "ostree:" + head + "?version=35.20211101.0"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -19,6 +19,9 @@ type Package struct {
 	PackageDB string `json:"-"`
 	// a hint on which repository this package was downloaded from
 	RepositoryHint string `json:"-"`
+	// Digests are content hashes of the artifact the package was discovered
+	// in, such as a jar or wheel, if the scanner was able to compute them.
+	Digests []Digest `json:"digests,omitempty"`
 	// NormalizedVersion is a representation of a version string that's
 	// correctly ordered when compared with other representations from the same
 	// producer.
@@ -29,9 +32,47 @@ type Package struct {
 	Arch string `json:"arch,omitempty"`
 	// CPE name for package
 	CPE cpe.WFN `json:"cpe,omitempty"`
+	// Files are the paths of the files the package installed, relative to
+	// the root, if the package database records them.
+	Files []string `json:"files,omitempty"`
+	// Confidence describes how the package was discovered. It's empty if the
+	// scanner that found it didn't say.
+	Confidence Confidence `json:"confidence,omitempty"`
+	// Metadata holds additional information the scanner found about the
+	// package, such as its license. See the Metadata constants for the keys
+	// with defined meanings.
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
+// MetadataLicense is the Package.Metadata key for the package's license, as
+// an SPDX-like expression of the license names the package declares.
+const MetadataLicense = "license"
+
+// MetadataModified is the Package.Metadata key for the package's installed
+// files whose contents don't match what the package manager recorded, as a
+// newline-separated, sorted list of paths relative to the root.
+//
+// This indicates the files were patched or tampered with after installation.
+const MetadataModified = "modified"
+
 const (
 	BINARY = "binary"
 	SOURCE = "source"
 )
+
+// Confidence describes how a package was discovered, so that consumers can
+// weight findings from less certain sources differently.
+type Confidence string
+
+const (
+	// ConfidenceAuthoritative is for packages recorded in a package
+	// manager's database, such as dpkg's status file or the rpm database.
+	ConfidenceAuthoritative Confidence = "authoritative"
+	// ConfidenceDeclared is for packages declared in metadata shipped
+	// alongside them, such as a lockfile, a jar's manifest, or a Go binary's
+	// build information.
+	ConfidenceDeclared Confidence = "declared"
+	// ConfidenceHeuristic is for packages inferred by examining files, such
+	// as versions found by looking for strings in a binary.
+	ConfidenceHeuristic Confidence = "heuristic"
+)
@@ -51,6 +51,7 @@ var photonRegexes = []photonRegex{
 
 var _ indexer.DistributionScanner = (*DistributionScanner)(nil)
 var _ indexer.VersionedScanner = (*DistributionScanner)(nil)
+var _ indexer.PathDeclarer = (*DistributionScanner)(nil)
 
 // DistributionScanner attempts to discover if a layer
 // displays characteristics of a photon distribution
@@ -65,6 +66,11 @@ func (*DistributionScanner) Version() string { return scannerVersion }
 // Kind implements scanner.VersionedScanner.
 func (*DistributionScanner) Kind() string { return scannerKind }
 
+// Paths implements indexer.PathDeclarer.
+func (*DistributionScanner) Paths() []string {
+	return []string{osReleasePath, photonReleasePath}
+}
+
 // Scan will inspect the layer for an os-release or lsb-release file
 // and perform a regex match for keywords indicating the associated photon release
 //
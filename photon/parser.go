@@ -39,6 +39,7 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 				Name:               def.Title,
 				Description:        def.Description,
 				Issued:             def.Advisory.Issued.Date,
+				Modified:           def.Advisory.Updated.Date,
 				Links:              ovalutil.Links(def),
 				Severity:           def.Advisory.Severity,
 				NormalizedSeverity: NormalizeSeverity(def.Advisory.Severity),
@@ -0,0 +1,156 @@
+// Package blob provides storage for large artifacts, such as fetched layers
+// and offline update bundles.
+//
+// Storing these behind the Store interface lets stateless deployments share
+// them through object storage instead of each instance's local disk.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotExist is returned when a key does not exist in a Store.
+var ErrNotExist = errors.New("blob: does not exist")
+
+// Store is bulk storage for large artifacts, addressed by key.
+//
+// Keys are slash-separated paths. Implementations must be safe for concurrent
+// use, and a Get must never observe a partially written Put.
+type Store interface {
+	// Get returns the contents stored under the key. The caller must close
+	// the returned ReadCloser. ErrNotExist is returned if there's nothing
+	// stored under the key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Put stores the contents of the Reader under the key, replacing
+	// anything already there.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Delete removes the contents stored under the key. It's not an error to
+	// delete a key that doesn't exist.
+	Delete(ctx context.Context, key string) error
+}
+
+var _ Store = (*Dir)(nil)
+
+// Dir is a Store backed by a directory on a filesystem.
+type Dir struct {
+	root string
+}
+
+// NewDir returns a Dir rooted at the named directory, creating it if needed.
+func NewDir(root string) (*Dir, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &Dir{root: root}, nil
+}
+
+func (d *Dir) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("blob: invalid key %q", key)
+	}
+	return filepath.Join(d.root, filepath.FromSlash(key)), nil
+}
+
+// Get implements Store.
+func (d *Dir) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("%w: %q", ErrNotExist, key)
+	default:
+		return nil, err
+	}
+	return f, nil
+}
+
+// Put implements Store.
+//
+// The contents are written to a temporary file and renamed into place.
+func (d *Dir) Put(ctx context.Context, key string, r io.Reader) error {
+	p, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".")
+	if err != nil {
+		return err
+	}
+	rm := true
+	defer func() {
+		if rm {
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err := io.Copy(f, &ctxReader{ctx: ctx, r: r}); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), p); err != nil {
+		return err
+	}
+	rm = false
+	return nil
+}
+
+// Delete implements Store.
+func (d *Dir) Delete(ctx context.Context, key string) error {
+	p, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// CtxReader stops reading once its Context is canceled.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
+
+// ValidKey reports whether the key is a clean, relative path that stays
+// within the Store.
+func validKey(key string) bool {
+	return key != "" &&
+		!path.IsAbs(key) &&
+		path.Clean(key) == key &&
+		key != ".." && !strings.HasPrefix(key, "../")
+}
+
+// Join returns a key made from the provided elements.
+func Join(elem ...string) string {
+	out := make([]string, 0, len(elem))
+	for _, e := range elem {
+		if e = strings.Trim(e, "/"); e != "" {
+			out = append(out, e)
+		}
+	}
+	return strings.Join(out, "/")
+}
@@ -0,0 +1,211 @@
+package blob
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ Store = (*LRU)(nil)
+
+// LRU is a Store backed by a directory on a filesystem, holding at most a
+// configured number of bytes.
+//
+// When adding contents takes the LRU over its size, the least recently used
+// contents are removed until it fits again. Contents larger than the whole LRU
+// are not kept. Contents are used when they're added or read. The order is kept in the files' modification times, so it
+// survives restarts.
+//
+// Contents being read when they're evicted remain readable until closed.
+type LRU struct {
+	dir *Dir
+	max int64
+
+	mu   sync.Mutex
+	size int64
+	// Order has the most recently used entry at the front.
+	order *list.List
+	index map[string]*list.Element
+}
+
+// LruEntry is the value of the LRU's list elements.
+type lruEntry struct {
+	key  string
+	size int64
+}
+
+// NewLRU returns an LRU rooted at the named directory, creating it if needed,
+// and holding at most "max" bytes.
+//
+// Contents already in the directory are kept, unless they take it over the
+// size.
+func NewLRU(root string, max int64) (*LRU, error) {
+	if max <= 0 {
+		return nil, fmt.Errorf("blob: invalid LRU size %d", max)
+	}
+	d, err := NewDir(root)
+	if err != nil {
+		return nil, err
+	}
+	l := LRU{
+		dir:   d,
+		max:   max,
+		order: list.New(),
+		index: make(map[string]*list.Element),
+	}
+
+	type entry struct {
+		lruEntry
+		mod time.Time
+	}
+	var found []entry
+	err = filepath.WalkDir(root, func(p string, e fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case e.IsDir():
+			return nil
+		case strings.HasPrefix(e.Name(), "."):
+			// Left behind by an interrupted Put.
+			return os.Remove(p)
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		found = append(found, entry{
+			lruEntry: lruEntry{key: filepath.ToSlash(rel), size: fi.Size()},
+			mod:      fi.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("blob: unable to index %q: %w", root, err)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].mod.After(found[j].mod) })
+	for _, f := range found {
+		l.index[f.key] = l.order.PushBack(&lruEntry{key: f.key, size: f.size})
+		l.size += f.size
+	}
+	if err := l.evict(context.Background()); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Size reports the number of bytes held.
+func (l *LRU) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+// Get implements Store.
+func (l *LRU) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	rc, err := l.dir.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	if e, ok := l.index[key]; ok {
+		l.order.MoveToFront(e)
+	}
+	l.mu.Unlock()
+	l.touch(key)
+	return rc, nil
+}
+
+// Put implements Store.
+func (l *LRU) Put(ctx context.Context, key string, r io.Reader) error {
+	if err := l.dir.Put(ctx, key, r); err != nil {
+		return err
+	}
+	p, err := l.dir.path(key)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if fi.Size() > l.max {
+		// Keeping these would flush everything else.
+		if e, ok := l.index[key]; ok {
+			l.remove(e)
+		}
+		return l.dir.Delete(ctx, key)
+	}
+	if e, ok := l.index[key]; ok {
+		ent := e.Value.(*lruEntry)
+		l.size -= ent.size
+		ent.size = fi.Size()
+		l.order.MoveToFront(e)
+	} else {
+		l.index[key] = l.order.PushFront(&lruEntry{key: key, size: fi.Size()})
+	}
+	l.size += fi.Size()
+	return l.evict(ctx)
+}
+
+// Delete implements Store.
+func (l *LRU) Delete(ctx context.Context, key string) error {
+	if err := l.dir.Delete(ctx, key); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.index[key]; ok {
+		l.remove(e)
+	}
+	return nil
+}
+
+// Evict removes the least recently used contents until the LRU is within
+// its size. The caller must hold the lock, if needed.
+func (l *LRU) evict(ctx context.Context) error {
+	var errs []string
+	for l.size > l.max {
+		e := l.order.Back()
+		key := e.Value.(*lruEntry).key
+		l.remove(e)
+		if err := l.dir.Delete(ctx, key); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return errors.New("blob: unable to evict: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (l *LRU) remove(e *list.Element) {
+	ent := l.order.Remove(e).(*lruEntry)
+	delete(l.index, ent.key)
+	l.size -= ent.size
+}
+
+// Touch records the use of the key in its file's modification time. Failure
+// only means the order is less accurate after a restart, so it's ignored.
+func (l *LRU) touch(key string) {
+	p, err := l.dir.path(key)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	os.Chtimes(p, now, now)
+}
@@ -0,0 +1,79 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	l, err := NewLRU(root, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(k string, n int) {
+		t.Helper()
+		if err := l.Put(ctx, k, strings.NewReader(strings.Repeat("x", n))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	has := func(k string) bool {
+		t.Helper()
+		rc, err := l.Get(ctx, k)
+		switch {
+		case errors.Is(err, nil):
+			rc.Close()
+			return true
+		case errors.Is(err, ErrNotExist):
+			return false
+		default:
+			t.Fatal(err)
+		}
+		panic("unreachable")
+	}
+
+	put("layers/a", 10)
+	put("layers/b", 10)
+	put("layers/c", 10)
+	// Using "a" makes "b" the least recently used.
+	if !has("layers/a") {
+		t.Fatal("missing a")
+	}
+	put("layers/d", 10)
+	if has("layers/b") {
+		t.Error("b not evicted")
+	}
+	for _, k := range []string{"layers/a", "layers/c", "layers/d"} {
+		if !has(k) {
+			t.Errorf("%s evicted", k)
+		}
+	}
+	if got, want := l.Size(), int64(30); got != want {
+		t.Errorf("size: got: %d, want: %d", got, want)
+	}
+
+	// Contents bigger than the LRU aren't kept.
+	put("layers/huge", 31)
+	if has("layers/huge") {
+		t.Error("huge content kept")
+	}
+
+	// A new LRU over the same directory picks up the contents, and evicts
+	// down to its size.
+	l, err = NewLRU(root, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := l.Size(), int64(20); got != want {
+		t.Errorf("size: got: %d, want: %d", got, want)
+	}
+	if err := l.Delete(ctx, "layers/d"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := l.Size(), int64(10); got != want {
+		t.Errorf("size: got: %d, want: %d", got, want)
+	}
+}
@@ -0,0 +1,277 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var _ Store = (*S3)(nil)
+
+// S3Config configures an S3 Store.
+type S3Config struct {
+	// Endpoint is the URL of the S3-compatible service, such as
+	// "https://s3.us-east-1.amazonaws.com" or "http://minio:9000".
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// Region is the region used for request signing. The default is
+	// "us-east-1", which S3-compatible services generally accept.
+	Region string `yaml:"region" json:"region"`
+	// Bucket is the bucket objects are stored in.
+	Bucket string `yaml:"bucket" json:"bucket"`
+	// Prefix is prepended to every key.
+	Prefix string `yaml:"prefix" json:"prefix"`
+	// AccessKeyID, SecretAccessKey, and SessionToken are the credentials used
+	// to sign requests. If AccessKeyID is empty, the values of the standard
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN
+	// environment variables are used.
+	AccessKeyID     string `yaml:"access_key_id" json:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key" json:"secret_access_key"`
+	SessionToken    string `yaml:"session_token" json:"session_token"`
+}
+
+// S3 is a Store backed by an S3-compatible object storage service.
+//
+// Objects are addressed with path-style URLs, which every S3-compatible
+// service supports.
+type S3 struct {
+	c        *http.Client
+	endpoint *url.URL
+	cfg      S3Config
+	now      func() time.Time
+}
+
+// NewS3 returns an S3 Store using the provided configuration and client.
+func NewS3(cfg S3Config, c *http.Client) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("blob: s3 endpoint and bucket must be provided")
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("blob: bad s3 endpoint: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.AccessKeyID == "" {
+		cfg.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		cfg.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		cfg.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return &S3{
+		c:        c,
+		endpoint: u,
+		cfg:      cfg,
+		now:      time.Now,
+	}, nil
+}
+
+func (s *S3) url(key string) (*url.URL, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("blob: invalid key %q", key)
+	}
+	u := *s.endpoint
+	u.Path = "/" + Join(u.Path, s.cfg.Bucket, s.cfg.Prefix, key)
+	return &u, nil
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body io.Reader, size int64) (*http.Response, error) {
+	u, err := s.url(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+		if size == 0 {
+			// Otherwise, the length is treated as unknown.
+			req.Body = http.NoBody
+		}
+	}
+	s.sign(req, unsignedPayload, s.now())
+	return s.c.Do(req)
+}
+
+// Get implements Store.
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, key, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, fmt.Errorf("%w: %q", ErrNotExist, key)
+	default:
+		return nil, s3Error(res)
+	}
+}
+
+// Put implements Store.
+//
+// S3 requires the size of an object up front, so if the Reader isn't an
+// io.Seeker, the contents are first copied to a temporary file.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader) error {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		f, err := os.CreateTemp("", "blob.")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			f.Close()
+			os.Remove(f.Name())
+		}()
+		if _, err := io.Copy(f, &ctxReader{ctx: ctx, r: r}); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		rs = f
+	}
+	cur, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := rs.Seek(cur, io.SeekStart); err != nil {
+		return err
+	}
+	// Hide any other methods, so the client doesn't try to be clever.
+	body := struct{ io.Reader }{rs}
+	res, err := s.do(ctx, http.MethodPut, key, body, end-cur)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return s3Error(res)
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return s3Error(res)
+	}
+}
+
+func s3Error(res *http.Response) error {
+	defer res.Body.Close()
+	// The body is an XML document describing the error; include some of it,
+	// capped so as to not flood the log.
+	b, _ := io.ReadAll(io.LimitReader(res.Body, 256))
+	return fmt.Errorf("blob: s3: unexpected status: %s (body starts: %q)", res.Status, b)
+}
+
+// UnsignedPayload is used in place of the payload hash, so that bodies can
+// be streamed.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// Sign adds AWS Signature Version 4 headers to the request.
+//
+// The host and all headers already set on the request are signed.
+func (s *S3) sign(req *http.Request, payload string, now time.Time) {
+	now = now.UTC()
+	stamp := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("x-amz-date", stamp)
+	req.Header.Set("x-amz-content-sha256", payload)
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.cfg.SessionToken)
+	}
+
+	hdrs := map[string]string{"host": req.URL.Host}
+	for k, vs := range req.Header {
+		hdrs[strings.ToLower(k)] = strings.Join(vs, ",")
+	}
+	names := make([]string, 0, len(hdrs))
+	for k := range hdrs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canon strings.Builder
+	canon.WriteString(req.Method)
+	canon.WriteByte('\n')
+	canon.WriteString(escapePath(req.URL.Path))
+	canon.WriteByte('\n')
+	canon.WriteString(req.URL.Query().Encode())
+	canon.WriteByte('\n')
+	for _, k := range names {
+		canon.WriteString(k)
+		canon.WriteByte(':')
+		canon.WriteString(strings.TrimSpace(hdrs[k]))
+		canon.WriteByte('\n')
+	}
+	canon.WriteByte('\n')
+	signed := strings.Join(names, ";")
+	canon.WriteString(signed)
+	canon.WriteByte('\n')
+	canon.WriteString(payload)
+
+	scope := day + "/" + s.cfg.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canon.String()))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := []byte("AWS4" + s.cfg.SecretAccessKey)
+	for _, v := range []string{day, s.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signed, sig))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// EscapePath escapes a path the way S3 expects in a canonical request: every
+// byte except the unreserved characters and the path separator is
+// percent-encoded.
+func escapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
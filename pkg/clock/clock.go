@@ -0,0 +1,90 @@
+// Package clock provides a pluggable notion of the current time.
+//
+// Updaters, stores, and matchers that need the current time, such as for
+// recording when an update happened, ask the Clock carried in their Context.
+// Providing a Clock other than System makes these components deterministic,
+// which is useful for tests and for reproducible offline update bundles, and
+// gives every component handling a request the same idea of "now".
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Func is an ordinary function used as a Clock.
+type Func func() time.Time
+
+// Now implements Clock.
+func (f Func) Now() time.Time { return f() }
+
+// System is the Clock reporting the system's time.
+var System Clock = system{}
+
+type system struct{}
+
+func (system) Now() time.Time { return time.Now() }
+
+// Fixed returns a Clock that always reports the provided time.
+func Fixed(t time.Time) Clock {
+	return fixed(t)
+}
+
+type fixed time.Time
+
+func (f fixed) Now() time.Time { return time.Time(f) }
+
+// Step returns a Clock that reports "start" the first time it's asked, and
+// advances by "step" every time after. It's safe for concurrent use.
+//
+// This keeps times distinct and ordered, as with a real clock, while still
+// being deterministic for sequential use.
+func Step(start time.Time, step time.Duration) Clock {
+	return &stepClock{next: start, step: step}
+}
+
+type stepClock struct {
+	mu   sync.Mutex
+	next time.Time
+	step time.Duration
+}
+
+func (c *stepClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.next
+	c.next = c.next.Add(c.step)
+	return t
+}
+
+type ctxKey struct{}
+
+// WithClock returns a Context carrying the Clock. A nil Clock returns the
+// Context unchanged.
+func WithClock(ctx context.Context, c Clock) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKey{}, c)
+}
+
+// FromContext returns the Clock carried in the Context, or System if there
+// isn't one.
+func FromContext(ctx context.Context) Clock {
+	if c, ok := ctx.Value(ctxKey{}).(Clock); ok {
+		return c
+	}
+	return System
+}
+
+// Now reports the current time according to the Clock carried in the
+// Context.
+func Now(ctx context.Context) time.Time {
+	return FromContext(ctx).Now()
+}
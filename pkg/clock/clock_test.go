@@ -0,0 +1,35 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if got := FromContext(ctx); got != System {
+		t.Errorf("got: %v, want: System", got)
+	}
+	if got := WithClock(ctx, nil); got != ctx {
+		t.Error("nil Clock changed the Context")
+	}
+
+	want := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	ctx = WithClock(ctx, Fixed(want))
+	for i := 0; i < 2; i++ {
+		if got := Now(ctx); !got.Equal(want) {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+	}
+}
+
+func TestStep(t *testing.T) {
+	start := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	c := Step(start, time.Second)
+	for i := 0; i < 3; i++ {
+		if got, want := c.Now(), start.Add(time.Duration(i)*time.Second); !got.Equal(want) {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+	}
+}
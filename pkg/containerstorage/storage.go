@@ -0,0 +1,351 @@
+// Package containerstorage reads images from a host's containers/storage
+// directories, as used by CRI-O and podman, so node-resident scanners can
+// index local images without exporting them.
+//
+// Only the "overlay" driver is supported. Layers are reassembled from their
+// "diff" directories using the tar-split metadata recorded when they were
+// stored, which reproduces the original uncompressed tar exactly, so layers
+// are verified against their digests like any other.
+package containerstorage
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex/driver"
+)
+
+// DefaultRoot is the default storage root for a system-wide installation.
+const DefaultRoot = "/var/lib/containers/storage"
+
+// Scheme is the URI scheme of the layers in Manifests returned by a Store.
+//
+// The URIs have the storage root as the path and the layer ID as the
+// fragment, e.g. "containers-storage:///var/lib/containers/storage#ID".
+const Scheme = "containers-storage"
+
+// Store is a containers/storage root directory.
+type Store struct {
+	root string
+}
+
+// Open returns the Store in the named directory.
+func Open(root string) (*Store, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(root, "overlay-images", "images.json")); err != nil {
+		return nil, fmt.Errorf("containerstorage: %q is not an overlay storage root: %w", root, err)
+	}
+	return &Store{root: root}, nil
+}
+
+// Image describes an image in the Store.
+type Image struct {
+	ID    string   `json:"id"`
+	Names []string `json:"names,omitempty"`
+	// Digest is the digest of the image's manifest, if known.
+	Digest string `json:"digest,omitempty"`
+	// Layer is the ID of the image's topmost layer.
+	Layer string `json:"layer,omitempty"`
+	// BigDataDigests has the digests of the documents stored with the image,
+	// keyed by name.
+	BigDataDigests map[string]string `json:"big-data-digests,omitempty"`
+}
+
+// Layer describes a layer in the Store.
+type layer struct {
+	ID         string `json:"id"`
+	Parent     string `json:"parent,omitempty"`
+	DiffDigest string `json:"diff-digest,omitempty"`
+}
+
+// Images reports the images in the Store.
+func (s *Store) Images() ([]Image, error) {
+	var out []Image
+	if err := readJSON(filepath.Join(s.root, "overlay-images", "images.json"), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Image returns the image named by "ref". A name without a tag also matches
+// the "latest" tag.
+func (s *Store) image(ref string) (*Image, error) {
+	imgs, err := s.Images()
+	if err != nil {
+		return nil, err
+	}
+	ref = strings.TrimPrefix(ref, "sha256:")
+	var found *Image
+	for i := range imgs {
+		img := &imgs[i]
+		if img.ID == ref {
+			return img, nil
+		}
+		for _, n := range img.Names {
+			if n == ref || n == ref+":latest" {
+				return img, nil
+			}
+		}
+		if len(ref) >= 3 && strings.HasPrefix(img.ID, ref) {
+			if found != nil {
+				return nil, fmt.Errorf("containerstorage: %q is ambiguous", ref)
+			}
+			found = img
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("containerstorage: no image %q: %w", ref, os.ErrNotExist)
+	}
+	return found, nil
+}
+
+// Manifest resolves the image named by "ref" to a Manifest. The image may be
+// named by its ID, a unique prefix of its ID, or one of its names.
+//
+// The Manifest's layers are identified by their uncompressed digests, and
+// have URIs that Transport reads from the Store. Its Labels and History are
+// set from the image configuration, if present.
+func (s *Store) Manifest(ctx context.Context, ref string) (*claircore.Manifest, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pkg/containerstorage/Store.Manifest"),
+		label.String("root", s.root),
+		label.String("reference", ref))
+	img, err := s.image(ref)
+	if err != nil {
+		return nil, err
+	}
+	md := img.Digest
+	if md == "" {
+		md = img.BigDataDigests["manifest"]
+	}
+	if md == "" {
+		// Images that have never been pushed or pulled may not have a
+		// manifest; use the configuration's digest, which is the ID.
+		md = "sha256:" + img.ID
+	}
+	d, err := claircore.ParseDigest(md)
+	if err != nil {
+		return nil, fmt.Errorf("containerstorage: bad manifest digest: %w", err)
+	}
+	out := claircore.Manifest{Hash: d}
+	if err := s.config(img, &out); err != nil {
+		return nil, err
+	}
+
+	ls, err := s.layers()
+	if err != nil {
+		return nil, err
+	}
+	var chain []*claircore.Layer
+	for id := img.Layer; id != ""; {
+		l, ok := ls[id]
+		if !ok {
+			return nil, fmt.Errorf("containerstorage: missing layer %q", id)
+		}
+		d, err := claircore.ParseDigest(l.DiffDigest)
+		if err != nil {
+			return nil, fmt.Errorf("containerstorage: layer %q: bad digest: %w", id, err)
+		}
+		u := url.URL{Scheme: Scheme, Path: s.root, Fragment: l.ID}
+		chain = append(chain, &claircore.Layer{Hash: d, URI: u.String()})
+		id = l.Parent
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		out.Layers = append(out.Layers, chain[i])
+	}
+	zlog.Debug(ctx).
+		Str("image", img.ID).
+		Stringer("manifest", out.Hash).
+		Int("layers", len(out.Layers)).
+		Msg("resolved image")
+	return &out, nil
+}
+
+// Layers returns the Store's layers, keyed by ID.
+func (s *Store) layers() (map[string]*layer, error) {
+	out := make(map[string]*layer)
+	for _, n := range []string{"layers.json", "volatile-layers.json"} {
+		var ls []layer
+		err := readJSON(filepath.Join(s.root, "overlay-layers", n), &ls)
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, os.ErrNotExist) && n != "layers.json":
+			continue
+		default:
+			return nil, err
+		}
+		for i := range ls {
+			out[ls[i].ID] = &ls[i]
+		}
+	}
+	return out, nil
+}
+
+// Config reads the image configuration, if present, setting the Labels and
+// History in the Manifest.
+func (s *Store) config(img *Image, m *claircore.Manifest) error {
+	// Documents stored with an image are named by the base64 encoding of
+	// their key; the configuration's key is its digest.
+	key := "=" + base64.StdEncoding.EncodeToString([]byte("sha256:"+img.ID))
+	var cfg struct {
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"config"`
+		History []struct {
+			CreatedBy  string `json:"created_by"`
+			EmptyLayer bool   `json:"empty_layer"`
+		} `json:"history"`
+	}
+	err := readJSON(filepath.Join(s.root, "overlay-images", img.ID, key), &cfg)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	default:
+		return err
+	}
+	m.Labels = cfg.Config.Labels
+	for _, h := range cfg.History {
+		m.History = append(m.History, claircore.History{
+			CreatedBy:  h.CreatedBy,
+			EmptyLayer: h.EmptyLayer,
+		})
+	}
+	return nil
+}
+
+// ReadJSON decodes the named file into "v".
+func readJSON(name string, v interface{}) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("containerstorage: %w", err)
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("containerstorage: unable to decode %q: %w", name, err)
+	}
+	return nil
+}
+
+// Transport returns a driver.Transport that reads layers with Scheme URIs from
+// the storage root named in the URI, and hands all other layers to "next".
+//
+// If "next" is nil, other layers are rejected.
+func Transport(next driver.Transport) driver.Transport {
+	return driver.TransportFunc(func(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+		u, err := url.Parse(l.URI)
+		switch {
+		case err == nil && u.Scheme == Scheme:
+		case next != nil:
+			return next.Fetch(ctx, l)
+		default:
+			return nil, fmt.Errorf("containerstorage: refusing to fetch uri %q for layer %v", l.URI, l.Hash)
+		}
+		id := u.Fragment
+		if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+			return nil, fmt.Errorf("containerstorage: bad layer id in uri %q", l.URI)
+		}
+		root := filepath.FromSlash(u.Path)
+		f, err := os.Open(filepath.Join(root, "overlay-layers", id+".tar-split.gz"))
+		if err != nil {
+			return nil, fmt.Errorf("containerstorage: unable to open layer metadata: %w", err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("containerstorage: unable to read layer metadata: %w", err)
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			defer f.Close()
+			pw.CloseWithError(assemble(pw, zr, filepath.Join(root, "overlay", id, "diff")))
+		}()
+		return &driver.Blob{
+			ReadCloser: pr,
+			MediaType:  "application/vnd.oci.image.layer.v1.tar",
+		}, nil
+	})
+}
+
+// Entry is a tar-split entry.
+//
+// Segments are the raw bytes of the tar stream outside of file contents:
+// headers and padding. Files are read from the layer's diff directory.
+type entry struct {
+	Type    int    `json:"type"`
+	Name    string `json:"name,omitempty"`
+	NameRaw []byte `json:"name_raw,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	Payload []byte `json:"payload"`
+}
+
+const (
+	entryFile = 1 + iota
+	entrySegment
+)
+
+// Assemble writes the tar stream described by the tar-split metadata in "r",
+// reading file contents from "dir".
+func assemble(w io.Writer, r io.Reader, dir string) error {
+	dec := json.NewDecoder(r)
+	for {
+		var e entry
+		err := dec.Decode(&e)
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, io.EOF):
+			return nil
+		default:
+			return fmt.Errorf("containerstorage: bad layer metadata: %w", err)
+		}
+		switch e.Type {
+		case entrySegment:
+			if _, err := w.Write(e.Payload); err != nil {
+				return err
+			}
+		case entryFile:
+			if e.Size == 0 {
+				continue
+			}
+			name := e.Name
+			if len(e.NameRaw) != 0 {
+				name = string(e.NameRaw)
+			}
+			if err := copyFile(w, filepath.Join(dir, filepath.Clean("/"+name)), e.Size); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("containerstorage: unknown layer metadata entry type %d", e.Type)
+		}
+	}
+}
+
+// CopyFile copies "n" bytes of the named file to "w".
+func copyFile(w io.Writer, name string, n int64) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("containerstorage: unable to open layer file: %w", err)
+	}
+	defer f.Close()
+	if _, err := io.CopyN(w, f, n); err != nil {
+		return fmt.Errorf("containerstorage: unable to read layer file %q: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package containerstorage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+type testFile struct {
+	Name, Contents string
+}
+
+// WriteLayer adds a layer with the files to the storage root, as
+// containers/storage would, and returns the layer's tar.
+func writeLayer(t *testing.T, root, id string, fs ...testFile) []byte {
+	t.Helper()
+	diff := filepath.Join(root, "overlay", id, "diff")
+	var buf bytes.Buffer
+	var es []entry
+	var last int
+	tw := tar.NewWriter(&buf)
+	for _, f := range fs {
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: f.Name, Size: int64(len(f.Contents)), Mode: 0644}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Flush()
+		es = append(es, entry{Type: entrySegment, Payload: append([]byte(nil), buf.Bytes()[last:]...)})
+		io.WriteString(tw, f.Contents)
+		es = append(es, entry{Type: entryFile, Name: f.Name, Size: hdr.Size})
+		last = buf.Len()
+
+		p := filepath.Join(diff, f.Name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f.Contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	es = append(es, entry{Type: entrySegment, Payload: buf.Bytes()[last:]})
+
+	if err := os.MkdirAll(filepath.Join(root, "overlay-layers"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(root, "overlay-layers", id+".tar-split.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	for _, e := range es {
+		if err := enc.Encode(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func writeJSON(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func digestOf(b []byte) string {
+	s := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(s[:])
+}
+
+func TestStore(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	root := t.TempDir()
+	base := writeLayer(t, root, "base",
+		testFile{"etc/os-release", "ID=test\n"},
+		testFile{"etc/passwd", "root:x:0:0::/root:/bin/sh\n"})
+	top := writeLayer(t, root, "top",
+		testFile{"usr/bin/app", "#!/bin/sh\n"})
+	writeJSON(t, filepath.Join(root, "overlay-layers", "layers.json"), []map[string]string{
+		{"id": "base", "diff-digest": digestOf(base)},
+		{"id": "top", "parent": "base", "diff-digest": digestOf(top)},
+	})
+	const id = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	manifest := digestOf([]byte("manifest"))
+	writeJSON(t, filepath.Join(root, "overlay-images", "images.json"), []Image{
+		{ID: id, Names: []string{"localhost/app:latest"}, Digest: manifest, Layer: "top"},
+	})
+	cfg := "=" + base64.StdEncoding.EncodeToString([]byte("sha256:"+id))
+	writeJSON(t, filepath.Join(root, "overlay-images", id, cfg), map[string]interface{}{
+		"config": map[string]interface{}{
+			"Labels": map[string]string{"name": "app"},
+		},
+	})
+
+	s, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []string{id, "sha256:" + id, id[:12], "localhost/app:latest", "localhost/app"} {
+		m, err := s.Manifest(ctx, ref)
+		if err != nil {
+			t.Fatalf("%s: %v", ref, err)
+		}
+		if got, want := m.Hash.String(), manifest; got != want {
+			t.Errorf("%s: got: %s, want: %s", ref, got, want)
+		}
+	}
+	if _, err := s.Manifest(ctx, "missing"); err == nil {
+		t.Error("expected error for missing image")
+	}
+
+	m, err := s.Manifest(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Labels["name"], "app"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	want := [][]byte{base, top}
+	if len(m.Layers) != len(want) {
+		t.Fatalf("got: %d layers, want: %d", len(m.Layers), len(want))
+	}
+	tr := Transport(nil)
+	for i, l := range m.Layers {
+		if got, want := l.Hash.String(), digestOf(want[i]); got != want {
+			t.Errorf("layer %d: got: %s, want: %s", i, got, want)
+		}
+		b, err := tr.Fetch(ctx, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(b)
+		b.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want[i]) {
+			t.Errorf("layer %d: reassembled tar differs", i)
+		}
+	}
+
+	if _, err := tr.Fetch(ctx, &claircore.Layer{URI: "https://example.com/"}); err == nil {
+		t.Error("expected error for non-storage uri")
+	}
+}
@@ -125,6 +125,22 @@ func (l *Locker) Close(_ context.Context) (_ error) {
 	return nil
 }
 
+// Err reports why the Locker is unable to hand out locks, if it is.
+//
+// A nil error means the Locker currently holds a connection to the lock
+// provider.
+func (l *Locker) Err() error {
+	l.rc.L.Lock()
+	defer l.rc.L.Unlock()
+	switch {
+	case l.gen < 0:
+		return errExiting
+	case l.conn == nil:
+		return errConnGone
+	}
+	return nil
+}
+
 // Reconnect is the inner part of the Run method.
 //
 // It acquires a connection, stashes it in the Locker object, then suspends
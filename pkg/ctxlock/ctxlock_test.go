@@ -11,6 +11,9 @@ import (
 
 func TestUncontested(t *testing.T) {
 	ctx, l := basicSetup(t)
+	if err := l.Err(); err != nil {
+		t.Fatal(err)
+	}
 	const (
 		w  = 4
 		ct = 100
@@ -0,0 +1,287 @@
+// Package feedmirror lets one claircore instance act as a mirror of the
+// vulnerability feeds its updaters fetch.
+//
+// A Mirror records the documents fetched through an *http.Client it wraps, and
+// serves them over HTTP. Instances without internet access can then point
+// their updaters at the mirror instead of the upstream hosts.
+//
+// Documents are served at a path made of the upstream host and path, so the
+// document fetched from
+//
+//	https://www.debian.org/security/oval/oval-definitions-buster.xml
+//
+// is served at
+//
+//	/www.debian.org/security/oval/oval-definitions-buster.xml
+//
+// relative to wherever the Mirror's handler is mounted.
+package feedmirror
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore/pkg/blob"
+	"github.com/quay/claircore/pkg/clock"
+	"github.com/quay/claircore/pkg/tmp"
+)
+
+// Mirror persists fetched feed documents to a blob.Store and serves them.
+type Mirror struct {
+	store  blob.Store
+	maxAge time.Duration
+}
+
+var _ http.Handler = (*Mirror)(nil)
+
+// New returns a Mirror keeping documents in the provided Store.
+//
+// Responses tell clients they may cache documents for "maxAge". If zero,
+// clients are told to revalidate every time, which is cheap because the
+// Mirror answers conditional requests.
+func New(store blob.Store, maxAge time.Duration) *Mirror {
+	return &Mirror{store: store, maxAge: maxAge}
+}
+
+// Client returns a copy of the provided client, with its Transport wrapped to
+// record every document successfully fetched with it.
+//
+// If the client is nil, a new client is returned.
+func (m *Mirror) Client(c *http.Client) *http.Client {
+	var out http.Client
+	if c != nil {
+		out = *c
+	}
+	next := out.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	out.Transport = &transport{m: m, next: next}
+	return &out
+}
+
+// Header is the metadata stored alongside a document.
+//
+// It's written as a single line of JSON before the document itself, so that
+// both are replaced by a single Put.
+type header struct {
+	URL          string    `json:"url"`
+	ContentType  string    `json:"content_type,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Digest       string    `json:"digest"`
+	Size         int64     `json:"size"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+// ETag returns the entity tag the Mirror serves the document with.
+//
+// Upstream entity tags are not reused, because upstream hosts may use weak or
+// per-server tags.
+func (h *header) ETag() string {
+	return strconv.Quote(h.Digest)
+}
+
+// ModTime returns the document's modification time, falling back to when it
+// was fetched.
+func (h *header) ModTime() time.Time {
+	if t, err := http.ParseTime(h.LastModified); err == nil {
+		return t
+	}
+	return h.Fetched.UTC().Truncate(time.Second)
+}
+
+// Key returns the Store key for the document at the provided host and path.
+//
+// The query string, if any, is folded into the last element.
+func key(host, p, rawQuery string) (string, error) {
+	k := blob.Join(host, p)
+	if rawQuery != "" {
+		k += "@" + url.QueryEscape(rawQuery)
+	}
+	if k == "" || path.Clean("/" + k)[1:] != k {
+		return "", fmt.Errorf("feedmirror: invalid path %q", p)
+	}
+	return k, nil
+}
+
+// Transport is the http.RoundTripper returned by Mirror.Client.
+type transport struct {
+	m    *Mirror
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+//
+// Successful GET responses are spooled to disk so that they can be both
+// stored and returned to the caller. Failing to store a document is logged
+// and otherwise ignored: mirroring shouldn't stop the local instance from
+// updating.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+	ctx := baggage.ContextWithValues(req.Context(),
+		label.String("component", "feedmirror/transport.RoundTrip"),
+		label.String("url", req.URL.String()))
+	k, err := key(req.URL.Host, req.URL.Path, req.URL.RawQuery)
+	if err != nil {
+		zlog.Info(ctx).Err(err).Msg("not mirroring document")
+		return res, nil
+	}
+
+	f, err := tmp.NewFile("", "feedmirror.")
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), res.Body)
+	res.Body.Close()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	hdr := header{
+		URL:          req.URL.String(),
+		ContentType:  res.Header.Get("Content-Type"),
+		LastModified: res.Header.Get("Last-Modified"),
+		Digest:       "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		Size:         n,
+		Fetched:      clock.Now(ctx),
+	}
+	if err := t.m.put(ctx, k, &hdr, f); err != nil {
+		zlog.Warn(ctx).Err(err).Msg("unable to mirror document")
+	} else {
+		zlog.Debug(ctx).Str("key", k).Int64("size", n).Msg("mirrored document")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	res.Body = f
+	res.ContentLength = n
+	return res, nil
+}
+
+func (m *Mirror) put(ctx context.Context, k string, hdr *header, f io.ReadSeeker) error {
+	b, err := json.Marshal(hdr)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return m.store.Put(ctx, k, io.MultiReader(strings.NewReader(string(b)), f))
+}
+
+// ServeHTTP implements http.Handler.
+//
+// Documents are served with ETag, Last-Modified, and Cache-Control headers,
+// and conditional requests are answered with "304 Not Modified" when
+// appropriate.
+func (m *Mirror) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := baggage.ContextWithValues(r.Context(),
+		label.String("component", "feedmirror/Mirror.ServeHTTP"))
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	k, err := key("", r.URL.Path, r.URL.RawQuery)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	rc, err := m.store.Get(ctx, k)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, blob.ErrNotExist):
+		http.NotFound(w, r)
+		return
+	default:
+		zlog.Warn(ctx).Err(err).Str("key", k).Msg("unable to open document")
+		http.Error(w, "unable to open document", http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+	br := bufio.NewReader(rc)
+	line, err := br.ReadBytes('\n')
+	if err != nil {
+		zlog.Warn(ctx).Err(err).Str("key", k).Msg("malformed document")
+		http.Error(w, "malformed document", http.StatusInternalServerError)
+		return
+	}
+	var hdr header
+	if err := json.Unmarshal(line, &hdr); err != nil {
+		zlog.Warn(ctx).Err(err).Str("key", k).Msg("malformed document")
+		http.Error(w, "malformed document", http.StatusInternalServerError)
+		return
+	}
+
+	mod := hdr.ModTime()
+	wh := w.Header()
+	wh.Set("ETag", hdr.ETag())
+	wh.Set("Last-Modified", mod.UTC().Format(http.TimeFormat))
+	if m.maxAge > 0 {
+		wh.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(m.maxAge/time.Second)))
+	} else {
+		wh.Set("Cache-Control", "no-cache")
+	}
+	if notModified(r, &hdr, mod) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if hdr.ContentType != "" {
+		wh.Set("Content-Type", hdr.ContentType)
+	} else {
+		wh.Set("Content-Type", "application/octet-stream")
+	}
+	wh.Set("Content-Length", strconv.FormatInt(hdr.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, br); err != nil {
+		zlog.Info(ctx).Err(err).Str("key", k).Msg("error writing document")
+	}
+}
+
+// NotModified reports whether the request's preconditions allow responding
+// with "304 Not Modified".
+//
+// As in RFC 7232, If-None-Match takes precedence over If-Modified-Since.
+func notModified(r *http.Request, hdr *header, mod time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := hdr.ETag()
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+			if t == "*" || t == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !mod.Truncate(time.Second).After(t)
+	}
+	return false
+}
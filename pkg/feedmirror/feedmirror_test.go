@@ -0,0 +1,138 @@
+package feedmirror
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/pkg/blob"
+)
+
+const doc = `<?xml version="1.0"?><oval_definitions/>`
+
+func TestMirror(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oval/feed.xml":
+			if r.Header.Get("If-None-Match") == `"upstream"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			w.Header().Set("ETag", `"upstream"`)
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			io.WriteString(w, doc)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := blob.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(store, time.Hour)
+	c := m.Client(upstream.Client())
+	fetch := func(t *testing.T, c *http.Client, u string, h http.Header) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range h {
+			req.Header[k] = v
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res, string(b)
+	}
+
+	// Fetching through the Mirror's client should be transparent.
+	res, body := fetch(t, c, upstream.URL+"/oval/feed.xml", nil)
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+	if got, want := body, doc; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	// Neither of these should be recorded.
+	fetch(t, c, upstream.URL+"/oval/feed.xml", http.Header{"If-None-Match": {`"upstream"`}})
+	fetch(t, c, upstream.URL+"/missing.xml", nil)
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+	mirrored := srv.URL + "/" + u.Host + "/oval/feed.xml"
+
+	t.Run("Get", func(t *testing.T) {
+		res, body := fetch(t, srv.Client(), mirrored, nil)
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		if got, want := body, doc; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		for k, want := range map[string]string{
+			"Content-Type":  "application/xml",
+			"Last-Modified": "Mon, 02 Jan 2006 15:04:05 GMT",
+			"Cache-Control": "public, max-age=3600",
+		} {
+			if got := res.Header.Get(k); got != want {
+				t.Errorf("%s: got: %q, want: %q", k, got, want)
+			}
+		}
+		if res.Header.Get("ETag") == "" {
+			t.Error("missing ETag")
+		}
+	})
+	t.Run("IfNoneMatch", func(t *testing.T) {
+		res, _ := fetch(t, srv.Client(), mirrored, nil)
+		res, _ = fetch(t, srv.Client(), mirrored, http.Header{"If-None-Match": {res.Header.Get("ETag")}})
+		if got, want := res.StatusCode, http.StatusNotModified; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+		res, _ = fetch(t, srv.Client(), mirrored, http.Header{"If-None-Match": {`"other"`}})
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+	t.Run("IfModifiedSince", func(t *testing.T) {
+		res, _ := fetch(t, srv.Client(), mirrored, http.Header{"If-Modified-Since": {"Mon, 02 Jan 2006 15:04:05 GMT"}})
+		if got, want := res.StatusCode, http.StatusNotModified; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+		res, _ = fetch(t, srv.Client(), mirrored, http.Header{"If-Modified-Since": {"Sun, 01 Jan 2006 15:04:05 GMT"}})
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got: %d, want: %d", got, want)
+		}
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		for _, p := range []string{
+			"/" + u.Host + "/missing.xml",
+			"/" + u.Host + "/oval/other.xml",
+		} {
+			res, _ := fetch(t, srv.Client(), srv.URL+p, nil)
+			if got, want := res.StatusCode, http.StatusNotFound; got != want {
+				t.Errorf("%s: got: %d, want: %d", p, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+// Package headers provides an http.RoundTripper that sets a configured
+// User-Agent and additional headers on outbound requests.
+//
+// Like the retry package, it's applied by way of the *http.Client handed to
+// libindex and libvuln, so that layer fetches and updater feed fetches are
+// treated uniformly.
+package headers
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Config describes the headers to set on outbound requests.
+type Config struct {
+	// UserAgent, if set, replaces the User-Agent of every request.
+	UserAgent string
+	// Header is set on every request, replacing any values already present
+	// for the same keys.
+	Header http.Header
+	// Hosts holds headers set only on requests to the keyed host, after
+	// Header. Keys may be a bare hostname or include a port; a key with a
+	// port only matches requests to that port.
+	//
+	// Credentials such as API keys for a mirror should be configured here, so
+	// they're not sent to other hosts.
+	Hosts map[string]http.Header
+}
+
+// Transport is an http.RoundTripper that applies a Config.
+type Transport struct {
+	cfg  Config
+	next http.RoundTripper
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// NewTransport returns a Transport applying the Config to requests before
+// handing them to "next". If "next" is nil, http.DefaultTransport is used.
+func NewTransport(cfg *Config, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := Transport{next: next}
+	if cfg != nil {
+		t.cfg.UserAgent = cfg.UserAgent
+		t.cfg.Header = cfg.Header.Clone()
+		t.cfg.Hosts = make(map[string]http.Header, len(cfg.Hosts))
+		for h, v := range cfg.Hosts {
+			t.cfg.Hosts[strings.ToLower(h)] = v.Clone()
+		}
+	}
+	return &t
+}
+
+// Client returns a copy of the provided client, with its Transport wrapped to
+// apply the Config.
+//
+// If the client is nil, a new client is returned.
+func Client(c *http.Client, cfg *Config) *http.Client {
+	var out http.Client
+	if c != nil {
+		out = *c
+	}
+	out.Transport = NewTransport(cfg, out.Transport)
+	return &out
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := t.hostHeader(req.URL.Host)
+	if t.cfg.UserAgent == "" && len(t.cfg.Header) == 0 && host == nil {
+		return t.next.RoundTrip(req)
+	}
+	// RoundTrippers aren't allowed to modify the request, so make a copy.
+	r := req.Clone(req.Context())
+	if t.cfg.UserAgent != "" {
+		r.Header.Set("User-Agent", t.cfg.UserAgent)
+	}
+	for k, vs := range t.cfg.Header {
+		r.Header[http.CanonicalHeaderKey(k)] = append([]string(nil), vs...)
+	}
+	for k, vs := range host {
+		r.Header[http.CanonicalHeaderKey(k)] = append([]string(nil), vs...)
+	}
+	return t.next.RoundTrip(r)
+}
+
+// HostHeader returns the headers configured for the host, preferring an entry
+// with a matching port.
+func (t *Transport) hostHeader(hostport string) http.Header {
+	if len(t.cfg.Hosts) == 0 {
+		return nil
+	}
+	hostport = strings.ToLower(hostport)
+	if h, ok := t.cfg.Hosts[hostport]; ok {
+		return h
+	}
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return t.cfg.Hosts[host]
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package headers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Client(srv.Client(), &Config{
+		UserAgent: "example/1.0",
+		Header:    http.Header{"x-mirror-tenant": {"blue"}},
+		Hosts: map[string]http.Header{
+			u.Host:        {"X-Api-Key": {"secret"}},
+			"example.com": {"X-Api-Key": {"other"}},
+		},
+	})
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", "claircore/test")
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	for k, want := range map[string]string{
+		"User-Agent":      "example/1.0",
+		"X-Mirror-Tenant": "blue",
+		"X-Api-Key":       "secret",
+	} {
+		if got := got.Get(k); got != want {
+			t.Errorf("%s: got: %q, want: %q", k, got, want)
+		}
+	}
+	// The caller's request must be left alone.
+	if got, want := req.Header.Get("User-Agent"), "claircore/test"; got != want {
+		t.Errorf("request modified: got: %q, want: %q", got, want)
+	}
+}
+
+func TestHostHeader(t *testing.T) {
+	tr := NewTransport(&Config{
+		Hosts: map[string]http.Header{
+			"Mirror.Example.com":      {"X-Key": {"any"}},
+			"mirror.example.com:8443": {"X-Key": {"port"}},
+		},
+	}, nil)
+	tt := []struct {
+		Host string
+		Want string
+	}{
+		{"mirror.example.com", "any"},
+		{"mirror.example.com:443", "any"},
+		{"mirror.example.com:8443", "port"},
+		{"registry.example.com", ""},
+	}
+	for _, tc := range tt {
+		if got := tr.hostHeader(tc.Host).Get("X-Key"); got != tc.Want {
+			t.Errorf("%s: got: %q, want: %q", tc.Host, got, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package health holds the types used to report the health of libindex and
+// libvuln dependencies.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/remind101/migrate"
+)
+
+// Check is the result of probing a single dependency.
+type Check struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// Err returns a Check reporting the provided error, which may be nil.
+func Err(err error) Check {
+	if err != nil {
+		return Check{Message: err.Error()}
+	}
+	return Check{OK: true}
+}
+
+// Skipped returns a passing Check noting why nothing was probed.
+func Skipped(why string) Check {
+	return Check{OK: true, Message: why}
+}
+
+// Ping checks that a connection can be acquired from the pool and used.
+func Ping(ctx context.Context, pool *pgxpool.Pool) Check {
+	c, err := pool.Acquire(ctx)
+	if err != nil {
+		return Err(err)
+	}
+	defer c.Release()
+	return Err(c.Conn().Ping(ctx))
+}
+
+// Migrations checks that every provided migration has been recorded as
+// applied in the named migration table.
+func Migrations(ctx context.Context, pool *pgxpool.Pool, table string, ms []migrate.Migration) Check {
+	rows, err := pool.Query(ctx, `SELECT version FROM `+table+`;`)
+	if err != nil {
+		return Err(err)
+	}
+	defer rows.Close()
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return Err(err)
+		}
+		applied[v] = true
+	}
+	if err := rows.Err(); err != nil {
+		return Err(err)
+	}
+	var missing []int
+	for _, m := range ms {
+		if !applied[m.ID] {
+			missing = append(missing, m.ID)
+		}
+	}
+	if len(missing) != 0 {
+		sort.Ints(missing)
+		return Check{Message: fmt.Sprintf("migrations not applied: %v", missing)}
+	}
+	return Check{OK: true, Message: "at version " + strconv.Itoa(len(applied))}
+}
@@ -0,0 +1,235 @@
+// Package logging lets embedders route claircore's logs into their own logging
+// stack.
+//
+// All logging in claircore goes through the zlog package, which by default
+// writes to the zerolog global logger. Passing a Config to Set replaces the
+// logger zlog writes to with one that hands every record to a Logger, after
+// filtering by the record's component. As zlog has a single logger, this is
+// process-wide; libindex and libvuln don't call Set on their own.
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/quay/zlog"
+	"github.com/rs/zerolog"
+)
+
+// Level is the severity of a log record.
+type Level int8
+
+// These are the levels claircore logs at.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	// LevelDisabled can be used in a Config to discard records.
+	LevelDisabled
+)
+
+// String implements fmt.Stringer.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelDisabled:
+		return "disabled"
+	}
+	return fmt.Sprintf("Level(%d)", int8(l))
+}
+
+// ParseLevel parses the string form of a Level.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "disabled", "off":
+		return LevelDisabled, nil
+	}
+	return LevelDisabled, fmt.Errorf("logging: unknown level %q", s)
+}
+
+// Logger is the interface embedders implement to receive claircore's logs.
+//
+// The fields are the key-value pairs attached to the record, including the
+// "component" key claircore uses to identify the code that logged. Log may
+// be called concurrently.
+type Logger interface {
+	Log(lvl Level, msg string, fields map[string]interface{})
+}
+
+// LoggerFunc adapts a function to a Logger.
+type LoggerFunc func(Level, string, map[string]interface{})
+
+// Log implements Logger.
+func (f LoggerFunc) Log(lvl Level, msg string, fields map[string]interface{}) {
+	f(lvl, msg, fields)
+}
+
+// ComponentKey is the field claircore uses to identify the code that logged.
+const ComponentKey = "component"
+
+// Config configures the routing of claircore's logs.
+type Config struct {
+	// Logger receives the records. It must not be nil.
+	Logger Logger
+	// Level is the lowest level passed to Logger, for components not
+	// configured in Components.
+	Level Level
+	// Components sets the lowest level passed to Logger for records whose
+	// component is, or is below, the key. For example, "rhel" configures
+	// "rhel/Updater.Fetch" and "rhel/Scanner.Scan". If multiple keys match,
+	// the longest wins.
+	Components map[string]Level
+}
+
+// Set configures zlog, and so all of claircore, to log via the Logger in the
+// provided Config.
+//
+// Like zlog.Set, this is process-wide and unsafe to call concurrently with
+// logging.
+func Set(cfg Config) error {
+	l, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	zlog.Set(l)
+	return nil
+}
+
+// New returns a zerolog.Logger that routes records to the Logger in the
+// provided Config.
+func New(cfg Config) (*zerolog.Logger, error) {
+	if cfg.Logger == nil {
+		return nil, fmt.Errorf("logging: no Logger provided")
+	}
+	w := &writer{
+		l:     cfg.Logger,
+		def:   cfg.Level,
+		comps: make(map[string]Level, len(cfg.Components)),
+		cache: make(map[string]Level),
+	}
+	// The zerolog level needs to be the lowest of all the configured levels,
+	// so that records for more verbose components aren't dropped before
+	// reaching the writer.
+	min := cfg.Level
+	for c, lvl := range cfg.Components {
+		w.comps[strings.Trim(c, "/")] = lvl
+		if lvl < min {
+			min = lvl
+		}
+	}
+	l := zerolog.New(w).Level(toZerolog(min))
+	return &l, nil
+}
+
+// Writer decodes the records zerolog emits and passes them to a Logger.
+type writer struct {
+	l     Logger
+	def   Level
+	comps map[string]Level
+
+	mu    sync.RWMutex
+	cache map[string]Level
+}
+
+var _ zerolog.LevelWriter = (*writer)(nil)
+
+// Write implements io.Writer.
+//
+// Records written without a level are logged at LevelInfo.
+func (w *writer) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter.
+func (w *writer) WriteLevel(zl zerolog.Level, p []byte) (int, error) {
+	var fields map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return 0, err
+	}
+	lvl := fromZerolog(zl)
+	c, _ := fields[ComponentKey].(string)
+	if lvl < w.level(c) {
+		return len(p), nil
+	}
+	msg, _ := fields[zerolog.MessageFieldName].(string)
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.LevelFieldName)
+	w.l.Log(lvl, msg, fields)
+	return len(p), nil
+}
+
+// Level reports the configured level for the component.
+func (w *writer) level(c string) Level {
+	if len(w.comps) == 0 {
+		return w.def
+	}
+	w.mu.RLock()
+	lvl, ok := w.cache[c]
+	w.mu.RUnlock()
+	if ok {
+		return lvl
+	}
+	lvl = w.def
+	for k := c; ; {
+		if l, ok := w.comps[k]; ok {
+			lvl = l
+			break
+		}
+		i := strings.LastIndexByte(k, '/')
+		if i == -1 {
+			break
+		}
+		k = k[:i]
+	}
+	w.mu.Lock()
+	w.cache[c] = lvl
+	w.mu.Unlock()
+	return lvl
+}
+
+func toZerolog(l Level) zerolog.Level {
+	switch l {
+	case LevelDebug:
+		return zerolog.DebugLevel
+	case LevelInfo:
+		return zerolog.InfoLevel
+	case LevelWarn:
+		return zerolog.WarnLevel
+	case LevelError:
+		return zerolog.ErrorLevel
+	}
+	return zerolog.Disabled
+}
+
+func fromZerolog(l zerolog.Level) Level {
+	switch {
+	case l <= zerolog.DebugLevel:
+		return LevelDebug
+	case l == zerolog.InfoLevel, l == zerolog.NoLevel:
+		return LevelInfo
+	case l == zerolog.WarnLevel:
+		return LevelWarn
+	}
+	return LevelError
+}
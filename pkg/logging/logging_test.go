@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+)
+
+type record struct {
+	Level   Level
+	Message string
+	Fields  map[string]interface{}
+}
+
+type recorder struct {
+	mu  sync.Mutex
+	got []record
+}
+
+func (r *recorder) Log(lvl Level, msg string, fields map[string]interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.got = append(r.got, record{lvl, msg, fields})
+}
+
+func TestRouting(t *testing.T) {
+	var r recorder
+	err := Set(Config{
+		Logger: &r,
+		Level:  LevelWarn,
+		Components: map[string]Level{
+			"rhel":               LevelDebug,
+			"rhel/Updater.Fetch": LevelError,
+			"debian/":            LevelDisabled,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := func(c string, lvl Level, msg string) {
+		ctx := baggage.ContextWithValues(context.Background(), label.String(ComponentKey, c))
+		zlog.WithLevel(ctx, toZerolog(lvl)).Int("n", 1).Msg(msg)
+	}
+	log("libindex/New", LevelInfo, "dropped")
+	log("libindex/New", LevelWarn, "kept")
+	log("rhel/Scanner.Scan", LevelDebug, "kept")
+	log("rhel/Updater.Fetch", LevelWarn, "dropped")
+	log("rhel/Updater.Fetch", LevelError, "kept")
+	log("debian/Updater.Fetch", LevelError, "dropped")
+	log("rhelish/Scanner.Scan", LevelInfo, "dropped")
+
+	want := []record{
+		{LevelWarn, "kept", map[string]interface{}{"component": "libindex/New", "n": json.Number("1")}},
+		{LevelDebug, "kept", map[string]interface{}{"component": "rhel/Scanner.Scan", "n": json.Number("1")}},
+		{LevelError, "kept", map[string]interface{}{"component": "rhel/Updater.Fetch", "n": json.Number("1")}},
+	}
+	if !cmp.Equal(r.got, want) {
+		t.Error(cmp.Diff(r.got, want))
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, l := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelDisabled} {
+		got, err := ParseLevel(l.String())
+		if err != nil {
+			t.Error(err)
+		}
+		if got != l {
+			t.Errorf("got: %v, want: %v", got, l)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected error")
+	}
+}
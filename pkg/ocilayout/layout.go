@@ -0,0 +1,267 @@
+// Package ocilayout reads images from OCI image layout directories, such as
+// those written by "skopeo copy" to an "oci:" destination, so they can be
+// indexed without a registry.
+//
+// A Layout resolves the images in it to claircore.Manifests whose layers have
+// "file" URIs pointing into the layout, which libindex reads directly.
+package ocilayout
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/registry"
+)
+
+// RefAnnotation is the annotation naming the images in a layout's index.
+const RefAnnotation = "org.opencontainers.image.ref.name"
+
+// MaxDocumentSize is the size limit for indexes, manifests, and image
+// configurations.
+const maxDocumentSize = 4 << 20
+
+// Layout is an OCI image layout directory.
+type Layout struct {
+	root string
+}
+
+// Open returns the Layout in the named directory.
+func Open(dir string) (*Layout, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "oci-layout"))
+	if err != nil {
+		return nil, fmt.Errorf("ocilayout: %q is not an image layout: %w", dir, err)
+	}
+	var v struct {
+		Version string `json:"imageLayoutVersion"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("ocilayout: unable to decode %q: %w", "oci-layout", err)
+	}
+	if !strings.HasPrefix(v.Version, "1.") {
+		return nil, fmt.Errorf("ocilayout: unsupported layout version %q", v.Version)
+	}
+	return &Layout{root: dir}, nil
+}
+
+// Descriptor is the subset of an OCI descriptor used here.
+type descriptor struct {
+	MediaType   string              `json:"mediaType"`
+	Digest      string              `json:"digest"`
+	URLs        []string            `json:"urls"`
+	Platform    *claircore.Platform `json:"platform"`
+	Annotations map[string]string   `json:"annotations"`
+}
+
+// Refs reports the names of the images in the layout.
+func (l *Layout) Refs() ([]string, error) {
+	ms, err := l.index()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, m := range ms {
+		if n, ok := m.Annotations[RefAnnotation]; ok {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+// Manifest resolves the image named by "ref" to a Manifest. If "ref" is
+// empty, the layout must hold a single image.
+//
+// If the image is an index, the manifest for the platform is selected; a nil
+// platform selects registry.DefaultPlatform.
+//
+// The returned Manifest's layers have "file" URIs, and its Labels and History
+// are set from the image configuration.
+func (l *Layout) Manifest(ctx context.Context, ref string, p *claircore.Platform) (*claircore.Manifest, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pkg/ocilayout/Layout.Manifest"),
+		label.String("layout", l.root),
+		label.String("reference", ref))
+	ms, err := l.index()
+	if err != nil {
+		return nil, err
+	}
+	var sel *descriptor
+	switch {
+	case ref != "":
+		for i := range ms {
+			if ms[i].Annotations[RefAnnotation] == ref {
+				sel = &ms[i]
+				break
+			}
+		}
+		if sel == nil {
+			return nil, fmt.Errorf("ocilayout: no image %q in layout", ref)
+		}
+	case len(ms) == 1:
+		sel = &ms[0]
+	default:
+		return nil, fmt.Errorf("ocilayout: layout has %d images, name one", len(ms))
+	}
+
+	var plat *claircore.Platform
+	if sel.MediaType == registry.MediaTypeOCIIndex || sel.MediaType == registry.MediaTypeDockerList {
+		want := registry.DefaultPlatform
+		if p != nil {
+			want = *p
+		}
+		var idx struct {
+			Manifests []descriptor `json:"manifests"`
+		}
+		if err := l.document(sel.Digest, &idx); err != nil {
+			return nil, err
+		}
+		var found *descriptor
+		var have []string
+		for i := range idx.Manifests {
+			m := &idx.Manifests[i]
+			if m.Platform == nil {
+				continue
+			}
+			have = append(have, m.Platform.String())
+			if m.Platform.Match(want) {
+				found = m
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("ocilayout: no manifest for platform %v (have %v)", want, have)
+		}
+		zlog.Debug(ctx).
+			Str("platform", found.Platform.String()).
+			Str("manifest", found.Digest).
+			Msg("selected manifest from index")
+		sel, plat = found, found.Platform
+	}
+
+	var m struct {
+		MediaType string       `json:"mediaType"`
+		Config    descriptor   `json:"config"`
+		Layers    []descriptor `json:"layers"`
+	}
+	if err := l.document(sel.Digest, &m); err != nil {
+		return nil, err
+	}
+	if mt := m.MediaType; mt != "" && mt != registry.MediaTypeOCIManifest && mt != registry.MediaTypeDockerManifest {
+		return nil, fmt.Errorf("ocilayout: unsupported manifest type %q", mt)
+	}
+	d, err := claircore.ParseDigest(sel.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("ocilayout: bad manifest digest: %w", err)
+	}
+	out := claircore.Manifest{
+		Hash:     d,
+		Platform: plat,
+	}
+	if err := l.config(m.Config, &out); err != nil {
+		return nil, err
+	}
+	for _, ld := range m.Layers {
+		d, err := claircore.ParseDigest(ld.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("ocilayout: bad layer digest: %w", err)
+		}
+		u := url.URL{Scheme: "file", Path: l.blobPath(d)}
+		out.Layers = append(out.Layers, &claircore.Layer{
+			Hash: d,
+			URI:  u.String(),
+			URLs: ld.URLs,
+		})
+	}
+	return &out, nil
+}
+
+// Index returns the descriptors in the layout's index.
+func (l *Layout) index() ([]descriptor, error) {
+	b, err := os.ReadFile(filepath.Join(l.root, "index.json"))
+	if err != nil {
+		return nil, fmt.Errorf("ocilayout: unable to read index: %w", err)
+	}
+	var idx struct {
+		Manifests []descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil, fmt.Errorf("ocilayout: unable to decode index: %w", err)
+	}
+	return idx.Manifests, nil
+}
+
+// BlobPath returns the path of the blob with the digest.
+func (l *Layout) blobPath(d claircore.Digest) string {
+	return filepath.Join(l.root, "blobs", d.Algorithm(), hex.EncodeToString(d.Checksum()))
+}
+
+// Document reads the JSON blob with the named digest into "v", checking its
+// digest.
+func (l *Layout) document(digest string, v interface{}) error {
+	d, err := claircore.ParseDigest(digest)
+	if err != nil {
+		return fmt.Errorf("ocilayout: bad digest: %w", err)
+	}
+	f, err := os.Open(l.blobPath(d))
+	if err != nil {
+		return fmt.Errorf("ocilayout: unable to open blob: %w", err)
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, maxDocumentSize+1))
+	if err != nil {
+		return fmt.Errorf("ocilayout: unable to read blob: %w", err)
+	}
+	if len(b) > maxDocumentSize {
+		return errors.New("ocilayout: document too large")
+	}
+	h := d.Hash()
+	h.Write(b)
+	if err := d.Verify(h.Sum(nil)); err != nil {
+		return fmt.Errorf("ocilayout: %s: %w", digest, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("ocilayout: unable to decode %s: %w", digest, err)
+	}
+	return nil
+}
+
+// Config reads the image configuration, setting the Labels and History in the
+// Manifest.
+func (l *Layout) config(desc descriptor, m *claircore.Manifest) error {
+	var cfg struct {
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"config"`
+		History []struct {
+			CreatedBy  string `json:"created_by"`
+			EmptyLayer bool   `json:"empty_layer"`
+		} `json:"history"`
+	}
+	if err := l.document(desc.Digest, &cfg); err != nil {
+		return err
+	}
+	m.Labels = cfg.Config.Labels
+	for _, h := range cfg.History {
+		m.History = append(m.History, claircore.History{
+			CreatedBy:  h.CreatedBy,
+			EmptyLayer: h.EmptyLayer,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package ocilayout
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore/test"
+)
+
+func TestLayout(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	dir := t.TempDir()
+	layers := [][]byte{[]byte("layer 0"), []byte("layer 1")}
+	md := test.WriteLayout(t, dir, "latest", layers...)
+
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := l.Refs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := refs, []string{"latest"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	for _, ref := range []string{"latest", ""} {
+		m, err := l.Manifest(ctx, ref, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.Hash.String(), md; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := m.Labels["org.opencontainers.image.title"], "latest"; got != want {
+			t.Errorf("got: %q, want: %q", got, want)
+		}
+		if got, want := len(m.Layers), len(layers); got != want {
+			t.Fatalf("got: %d layers, want: %d", got, want)
+		}
+		for i, ly := range m.Layers {
+			u, err := url.Parse(ly.URI)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Scheme != "file" {
+				t.Errorf("unexpected uri: %q", ly.URI)
+			}
+			b, err := os.ReadFile(u.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := string(b), string(layers[i]); got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+		}
+	}
+
+	if _, err := l.Manifest(ctx, "missing", nil); err == nil {
+		t.Error("expected error for missing ref")
+	}
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("expected error for non-layout")
+	}
+}
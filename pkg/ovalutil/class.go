@@ -0,0 +1,283 @@
+package ovalutil
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/quay/goval-parser/oval"
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+)
+
+// These are the definition classes from the OVAL definitions schema.
+const (
+	ClassCompliance    = "compliance"
+	ClassInventory     = "inventory"
+	ClassMiscellaneous = "miscellaneous"
+	ClassPatch         = "patch"
+	ClassVulnerability = "vulnerability"
+)
+
+// ClassPolicy controls which classes of definitions are turned into
+// vulnerabilities.
+//
+// Some feeds publish "patch" class definitions, describing an advisory and
+// the packages it updates, alongside "vulnerability" class definitions,
+// describing a single vulnerability and the packages fixing it. Using both
+// reports the same fix twice, and using only one misses fixes published only
+// in the other.
+type ClassPolicy uint
+
+// These are the ClassPolicies.
+//
+// The policies other than AllClasses drop definitions of classes that don't
+// describe vulnerabilities at all, like "inventory". Definitions without a
+// class are always used.
+const (
+	// AllClasses uses every definition.
+	AllClasses ClassPolicy = iota // all
+	// OnlyVulnerability uses only "vulnerability" class definitions.
+	OnlyVulnerability // vulnerability
+	// OnlyPatch uses only "patch" class definitions.
+	OnlyPatch // patch
+	// PreferVulnerability uses "vulnerability" class definitions, and the
+	// package checks of "patch" class definitions that aren't also in a
+	// "vulnerability" class definition.
+	PreferVulnerability // prefer-vulnerability
+	// PreferPatch is like PreferVulnerability, with the classes swapped.
+	PreferPatch // prefer-patch
+)
+
+var classPolicyNames = [...]string{
+	AllClasses:          "all",
+	OnlyVulnerability:   "vulnerability",
+	OnlyPatch:           "patch",
+	PreferVulnerability: "prefer-vulnerability",
+	PreferPatch:         "prefer-patch",
+}
+
+// String implements fmt.Stringer.
+func (p ClassPolicy) String() string {
+	if int(p) < len(classPolicyNames) {
+		return classPolicyNames[p]
+	}
+	return fmt.Sprintf("ClassPolicy(%d)", uint(p))
+}
+
+// ParseClassPolicy reports the ClassPolicy named by the passed in string, as
+// returned by its String method.
+func ParseClassPolicy(s string) (ClassPolicy, error) {
+	for i, n := range classPolicyNames {
+		if strings.EqualFold(s, n) {
+			return ClassPolicy(i), nil
+		}
+	}
+	return AllClasses, fmt.Errorf("ovalutil: unknown class policy %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p ClassPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, so a ClassPolicy can be
+// used directly in configuration.
+func (p *ClassPolicy) UnmarshalText(b []byte) error {
+	v, err := ParseClassPolicy(string(b))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// ApplyClassPolicy modifies the definitions in the root according to the
+// policy. It should be called before the root is handed to RPMDefsToVulns or
+// DpkgDefsToVulns.
+//
+// For the "prefer" policies, a package check is the same in two definitions
+// if it's for the same package and version, and it's done under the same
+// other criteria (like a check that a product is installed) and for the same
+// affected platforms.
+func ApplyClassPolicy(ctx context.Context, root *oval.Root, p ClassPolicy) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "ovalutil/ApplyClassPolicy"))
+	if p == AllClasses {
+		return
+	}
+	var preferred, other string
+	switch p {
+	case OnlyVulnerability, PreferVulnerability:
+		preferred, other = ClassVulnerability, ClassPatch
+	case OnlyPatch, PreferPatch:
+		preferred, other = ClassPatch, ClassVulnerability
+	}
+
+	// Collect the package checks in the preferred definitions.
+	seen := make(map[string]struct{})
+	if p == PreferVulnerability || p == PreferPatch {
+		for i := range root.Definitions.Definitions {
+			def := &root.Definitions.Definitions[i]
+			if !strings.EqualFold(def.Class, preferred) {
+				continue
+			}
+			pfx := platforms(def)
+			forEachCheck(root, &def.Criteria, pfx, func(key string) {
+				seen[key] = struct{}{}
+			})
+		}
+	}
+
+	defs := root.Definitions.Definitions[:0:0]
+	var dropped, pruned int
+	for _, def := range root.Definitions.Definitions {
+		switch strings.ToLower(def.Class) {
+		case "", preferred:
+		case other:
+			if p == OnlyVulnerability || p == OnlyPatch {
+				dropped++
+				continue
+			}
+			c, n, ok := pruneChecks(root, def.Criteria, platforms(&def), seen)
+			if !ok {
+				dropped++
+				continue
+			}
+			pruned += n
+			def.Criteria = c
+		default:
+			dropped++
+			continue
+		}
+		defs = append(defs, def)
+	}
+	root.Definitions.Definitions = defs
+	zlog.Debug(ctx).
+		Stringer("policy", p).
+		Int("dropped", dropped).
+		Int("pruned", pruned).
+		Msg("applied class policy")
+}
+
+// Platforms returns a prefix for the keys of a definition's package checks,
+// naming its affected platforms.
+func platforms(def *oval.Definition) string {
+	var ps []string
+	for _, a := range def.Affecteds {
+		ps = append(ps, a.Platforms...)
+	}
+	sort.Strings(ps)
+	return strings.Join(ps, "\x00")
+}
+
+// ForEachCheck calls the function with the key of every package check in the
+// criteria.
+func forEachCheck(root *oval.Root, node *oval.Criteria, scope string, f func(string)) {
+	scope = nodeScope(root, node, scope)
+	for i := range node.Criterions {
+		if k, ok := checkKey(root, &node.Criterions[i]); ok {
+			f(scope + "\x01" + k)
+		}
+	}
+	for i := range node.Criterias {
+		forEachCheck(root, &node.Criterias[i], scope, f)
+	}
+}
+
+// PruneChecks returns a copy of the criteria with the package checks in
+// "seen" removed, and the number removed. It reports false if the criteria
+// had package checks and none remain.
+func pruneChecks(root *oval.Root, node oval.Criteria, scope string, seen map[string]struct{}) (oval.Criteria, int, bool) {
+	var total, removed int
+	var prune func(node oval.Criteria, scope string) oval.Criteria
+	prune = func(node oval.Criteria, scope string) oval.Criteria {
+		scope = nodeScope(root, &node, scope)
+		out := node
+		out.Criterions = nil
+		for _, c := range node.Criterions {
+			if k, ok := checkKey(root, &c); ok {
+				total++
+				if _, ok := seen[scope+"\x01"+k]; ok {
+					removed++
+					continue
+				}
+			}
+			out.Criterions = append(out.Criterions, c)
+		}
+		out.Criterias = make([]oval.Criteria, len(node.Criterias))
+		for i, c := range node.Criterias {
+			out.Criterias[i] = prune(c, scope)
+		}
+		return out
+	}
+	out := prune(node, scope)
+	return out, removed, total == 0 || removed < total
+}
+
+// NodeScope extends the scope with the criteria in the node that aren't
+// package checks.
+func nodeScope(root *oval.Root, node *oval.Criteria, scope string) string {
+	var refs []string
+	for i := range node.Criterions {
+		c := &node.Criterions[i]
+		if _, ok := checkKey(root, c); !ok {
+			refs = append(refs, c.TestRef)
+		}
+	}
+	if len(refs) == 0 {
+		return scope
+	}
+	sort.Strings(refs)
+	return scope + "\x00" + strings.Join(refs, "\x00")
+}
+
+// CheckKey returns a key identifying the package and version checked by the
+// criterion, reporting false if it's not a package version check.
+func checkKey(root *oval.Root, c *oval.Criterion) (string, bool) {
+	t, err := TestLookup(root, c.TestRef, func(k string) bool {
+		return k == "rpminfo_test" || k == "dpkginfo_test"
+	})
+	if err != nil {
+		return "", false
+	}
+	objs, states := t.ObjectRef(), t.StateRef()
+	if len(objs) == 0 || len(states) == 0 {
+		return "", false
+	}
+	var name, evr, arch string
+	switch t.(type) {
+	case *oval.RPMInfoTest:
+		obj, err := rpmObjectLookup(root, objs[0].ObjectRef)
+		if err != nil {
+			return "", false
+		}
+		st, err := rpmStateLookup(root, states[0].StateRef)
+		if err != nil || st.EVR == nil {
+			return "", false
+		}
+		name, evr = obj.Name, st.EVR.Body
+		if st.Arch != nil {
+			arch = st.Arch.Body
+		}
+	case *oval.DpkgInfoTest:
+		obj, err := dpkgObjectLookup(root, objs[0].ObjectRef)
+		if err != nil {
+			return "", false
+		}
+		st, err := dpkgStateLookup(root, states[0].StateRef)
+		if err != nil || st.EVR == nil || obj.Name == nil {
+			return "", false
+		}
+		// Names may be a reference to a variable listing them.
+		name, evr = obj.Name.Body+obj.Name.Ref, st.EVR.Body
+		if st.Arch != nil {
+			arch = st.Arch.Body
+		}
+	default:
+		return "", false
+	}
+	return name + "\x00" + evr + "\x00" + arch, true
+}
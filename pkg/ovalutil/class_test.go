@@ -0,0 +1,94 @@
+package ovalutil
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/goval-parser/oval"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// TestClassPolicy checks the vulnerabilities produced from a document with
+// overlapping "patch" and "vulnerability" definitions under each policy.
+func TestClassPolicy(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	b, err := os.ReadFile("testdata/classes.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proto := func(def oval.Definition) ([]*claircore.Vulnerability, error) {
+		return []*claircore.Vulnerability{{Name: def.Title}}, nil
+	}
+
+	tt := []struct {
+		Policy ClassPolicy
+		Want   []string
+	}{
+		{
+			Policy: AllClasses,
+			Want:   []string{"CVE-2021-0001 foo", "PATCH-2021:1 bar", "PATCH-2021:1 foo", "PATCH-2021:2 foo", "PATCH-2021:3 foo"},
+		},
+		{
+			Policy: OnlyVulnerability,
+			Want:   []string{"CVE-2021-0001 foo"},
+		},
+		{
+			Policy: OnlyPatch,
+			Want:   []string{"PATCH-2021:1 bar", "PATCH-2021:1 foo", "PATCH-2021:2 foo", "PATCH-2021:3 foo"},
+		},
+		{
+			// The fix for "foo" is only used from the patch for another
+			// product.
+			Policy: PreferVulnerability,
+			Want:   []string{"CVE-2021-0001 foo", "PATCH-2021:1 bar", "PATCH-2021:3 foo"},
+		},
+		{
+			Policy: PreferPatch,
+			Want:   []string{"PATCH-2021:1 bar", "PATCH-2021:1 foo", "PATCH-2021:2 foo", "PATCH-2021:3 foo"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Policy.String(), func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			var root oval.Root
+			if err := xml.Unmarshal(b, &root); err != nil {
+				t.Fatal(err)
+			}
+			ApplyClassPolicy(ctx, &root, tc.Policy)
+			vs, err := RPMDefsToVulns(ctx, &root, proto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(vs))
+			for i, v := range vs {
+				got[i] = v.Name + " " + v.Package.Name
+			}
+			sort.Strings(got)
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+}
+
+func TestClassPolicyConfig(t *testing.T) {
+	var cfg struct {
+		Classes *ClassPolicy `json:"classes"`
+	}
+	if err := json.Unmarshal([]byte(`{"classes":"prefer-patch"}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := *cfg.Classes, PreferPatch; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if err := json.Unmarshal([]byte(`{"classes":"some"}`), &cfg); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -123,7 +123,7 @@ func DpkgDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulns
 					if state != nil {
 						vuln.FixedInVersion = state.EVR.Body
 						if state.Arch != nil {
-							vuln.ArchOperation = mapArchOp(state.Arch.Operation)
+							vuln.ArchOperation = MapArchOp(state.Arch.Operation)
 							vuln.Package.Arch = state.Arch.Body
 						}
 					}
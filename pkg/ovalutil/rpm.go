@@ -137,7 +137,7 @@ func RPMDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulnsF
 					if state != nil {
 						vuln.FixedInVersion = state.EVR.Body
 						if state.Arch != nil {
-							vuln.ArchOperation = mapArchOp(state.Arch.Operation)
+							vuln.ArchOperation = MapArchOp(state.Arch.Operation)
 							vuln.Package.Arch = state.Arch.Body
 						}
 					}
@@ -150,7 +150,9 @@ func RPMDefsToVulns(ctx context.Context, root *oval.Root, protoVulns ProtoVulnsF
 	return vulns, nil
 }
 
-func mapArchOp(op oval.Operation) claircore.ArchOp {
+// MapArchOp returns the claircore.ArchOp corresponding to the OVAL operation,
+// or the zero ArchOp if there isn't one.
+func MapArchOp(op oval.Operation) claircore.ArchOp {
 	switch op {
 	case oval.OpEquals:
 		return claircore.OpEquals
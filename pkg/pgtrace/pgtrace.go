@@ -0,0 +1,206 @@
+// Package pgtrace provides a pgx.Logger that reports query durations through
+// zlog and prometheus, and logs slow queries.
+//
+// This allows finding the queries responsible for poor performance without
+// access to the database server's own statistics, which usually requires
+// superuser access.
+package pgtrace
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/quay/zlog"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+)
+
+var (
+	queryDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "claircore",
+			Subsystem: "pgx",
+			Name:      "query_duration_seconds",
+			Help:      "The duration of all queries issued through a traced pool.",
+		},
+		[]string{"op"},
+	)
+	queryErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "pgx",
+			Name:      "query_errors_total",
+			Help:      "The number of queries issued through a traced pool that returned an error.",
+		},
+		[]string{"op"},
+	)
+	slowQueries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "claircore",
+			Subsystem: "pgx",
+			Name:      "slow_queries_total",
+			Help:      "The number of queries issued through a traced pool that exceeded the slow query threshold.",
+		},
+		[]string{"op"},
+	)
+)
+
+// Config configures query tracing.
+type Config struct {
+	// SlowQuery is the duration beyond which a query is logged at warn level.
+	// If zero, queries aren't logged as slow.
+	SlowQuery time.Duration
+	// Queries causes every query to be logged at debug level.
+	Queries bool
+	// Args causes query arguments to be included in logs. Long strings and
+	// byte slices are truncated and arrays are replaced by their length, but
+	// values may still contain data that shouldn't be logged.
+	Args bool
+}
+
+// Logger is a pgx.Logger implementing the Config.
+//
+// Records that aren't about a query are passed to the next Logger, if any.
+type Logger struct {
+	cfg  Config
+	next pgx.Logger
+	// Level is the most verbose level passed to next.
+	level pgx.LogLevel
+}
+
+var _ pgx.Logger = (*Logger)(nil)
+
+// NewLogger returns a Logger applying the Config, passing other records to
+// "next". If "next" is nil, other records are discarded.
+func NewLogger(cfg *Config, next pgx.Logger) *Logger {
+	l := Logger{next: next, level: pgx.LogLevelTrace}
+	if cfg != nil {
+		l.cfg = *cfg
+	}
+	return &l
+}
+
+// Configure sets up tracing on the provided pgx.ConnConfig, wrapping any
+// Logger already configured.
+//
+// Because pgx only reports query durations at the info level, the ConnConfig's
+// LogLevel is raised to at least that. The configured Logger still only sees
+// records at its original level.
+func Configure(c *pgx.ConnConfig, cfg *Config) {
+	l := NewLogger(cfg, c.Logger)
+	if c.Logger == nil {
+		c.LogLevel = pgx.LogLevelNone
+	}
+	l.level = c.LogLevel
+	c.Logger = l
+	if c.LogLevel < pgx.LogLevelInfo {
+		c.LogLevel = pgx.LogLevelInfo
+	}
+}
+
+// Log implements pgx.Logger.
+func (l *Logger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	switch msg {
+	case "Query", "Exec":
+		l.query(ctx, level, msg, data)
+	default:
+		l.forward(ctx, level, msg, data)
+	}
+}
+
+func (l *Logger) forward(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	if l.next != nil && level <= l.level {
+		l.next.Log(ctx, level, msg, data)
+	}
+}
+
+func (l *Logger) query(ctx context.Context, level pgx.LogLevel, op string, data map[string]interface{}) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pgtrace/Logger.Log"))
+	// Let the wrapped Logger see everything it asked for, as before.
+	l.forward(ctx, level, op, data)
+
+	if err, ok := data["err"].(error); ok {
+		queryErrors.WithLabelValues(op).Inc()
+		l.event(zlog.Debug(ctx), data).
+			Err(err).
+			Str("op", op).
+			Msg("query error")
+		return
+	}
+	d, ok := data["time"].(time.Duration)
+	if !ok {
+		return
+	}
+	queryDuration.WithLabelValues(op).Observe(d.Seconds())
+	var ev *zerolog.Event
+	switch {
+	case l.cfg.SlowQuery > 0 && d >= l.cfg.SlowQuery:
+		slowQueries.WithLabelValues(op).Inc()
+		ev = zlog.Warn(ctx).Str("threshold", l.cfg.SlowQuery.String())
+	case l.cfg.Queries:
+		ev = zlog.Debug(ctx)
+	default:
+		return
+	}
+	if n, ok := data["rowCount"].(int); ok {
+		ev = ev.Int("rows", n)
+	}
+	l.event(ev, data).
+		Str("op", op).
+		Dur("duration", d).
+		Msg("query")
+}
+
+// Event adds the query and, if configured, its arguments to the event.
+func (l *Logger) event(ev *zerolog.Event, data map[string]interface{}) *zerolog.Event {
+	if s, ok := data["sql"].(string); ok {
+		ev = ev.Str("sql", strings.Join(strings.Fields(s), " "))
+	}
+	if args, ok := data["args"].([]interface{}); ok && l.cfg.Args {
+		ev = ev.Strs("args", sanitize(args))
+	}
+	return ev
+}
+
+// MaxArg is the longest argument, in bytes, logged.
+const maxArg = 64
+
+// Sanitize formats query arguments for logging.
+//
+// The pgx package has already hex-encoded byte slices and truncated strings,
+// but arrays are passed through whole.
+func sanitize(args []interface{}) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		var s string
+		switch v := a.(type) {
+		case nil:
+			s = "NULL"
+		case string:
+			s = v
+		case fmt.Stringer:
+			s = v.String()
+		default:
+			rv := reflect.ValueOf(a)
+			switch rv.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				s = fmt.Sprintf("%T (%d elements)", a, rv.Len())
+			default:
+				s = fmt.Sprint(a)
+			}
+		}
+		if len(s) > maxArg {
+			s = fmt.Sprintf("%s (truncated %d bytes)", s[:maxArg], len(s)-maxArg)
+		}
+		out[i] = s
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package pgtrace
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v4"
+	"github.com/quay/zlog"
+)
+
+type recorder struct {
+	msgs []string
+}
+
+func (r *recorder) Log(_ context.Context, _ pgx.LogLevel, msg string, _ map[string]interface{}) {
+	r.msgs = append(r.msgs, msg)
+}
+
+func TestConfigure(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var rec recorder
+	c := pgx.ConnConfig{Logger: &rec, LogLevel: pgx.LogLevelWarn}
+	Configure(&c, &Config{SlowQuery: time.Millisecond})
+	if got, want := c.LogLevel, pgx.LogLevel(pgx.LogLevelInfo); got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	c.Logger.Log(ctx, pgx.LogLevelInfo, "Dialing PostgreSQL server", nil)
+	c.Logger.Log(ctx, pgx.LogLevelInfo, "Query", map[string]interface{}{
+		"sql":  "SELECT 1;",
+		"time": 10 * time.Millisecond,
+	})
+	c.Logger.Log(ctx, pgx.LogLevelError, "Query", map[string]interface{}{
+		"sql": "SELECT 1;",
+		"err": context.Canceled,
+	})
+	// The wrapped Logger should only see what it would've before.
+	if got, want := rec.msgs, []string{"Query"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	got := sanitize([]interface{}{
+		nil,
+		"short",
+		long,
+		[]string{"a", "b", "c"},
+		42,
+	})
+	want := []string{
+		"NULL",
+		"short",
+		strings.Repeat("a", 64) + " (truncated 36 bytes)",
+		"[]string (3 elements)",
+		"42",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
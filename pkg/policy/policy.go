@@ -0,0 +1,382 @@
+// Package policy implements a small rule language for deciding whether a
+// manifest is acceptable given its VulnerabilityReport.
+//
+// A policy is a list of rules, one per line. Each rule is "allow" or "deny"
+// followed by a condition on a vulnerability affecting a package:
+//
+//	# Nothing fixable at High or above.
+//	deny severity >= High and fixed
+//	deny severity == Critical
+//	# Accepted risk.
+//	allow id == "CVE-2021-44228" and package == "log4j"
+//
+// Every affecting vulnerability matching a "deny" rule, and no "allow" rule,
+// is a violation; a manifest with no violations passes. Conditions are
+// comparisons joined by "and", each optionally preceded by "not". The
+// comparisons available are:
+//
+//	id         the vulnerability's name; ==, !=, matches
+//	package    the package's name; ==, !=, matches
+//	source     the package's source package name; ==, !=, matches
+//	version    the package's version; ==, !=, matches
+//	fixed_in   the version the vulnerability is fixed in; ==, !=, matches
+//	severity   the normalized severity; ==, !=, <, <=, >, >=
+//	fixed      whether a fixed version is known; used alone
+//
+// The "matches" operator uses path.Match patterns. Values may be quoted with
+// double quotes, as in Go, and must be if they contain spaces or operators.
+// Severities are compared in the order Unknown, Negligible, Low, Medium,
+// High, Critical, and may be written in any case. A "#" outside of quotes
+// starts a comment.
+package policy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libvuln/driver"
+)
+
+// Rules is a policy written in the rule language. It implements
+// driver.Policy.
+type Rules struct {
+	name  string
+	allow []rule
+	deny  []rule
+}
+
+var _ driver.Policy = (*Rules)(nil)
+
+// Parse reads a policy from the provided Reader, naming it "name".
+func Parse(name string, r io.Reader) (*Rules, error) {
+	rs := Rules{name: name}
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		toks, err := lex(s.Text())
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("policy: line %d: %w", n, err)
+		case len(toks) == 0:
+			continue
+		}
+		r, err := parseRule(toks)
+		if err != nil {
+			return nil, fmt.Errorf("policy: line %d: %w", n, err)
+		}
+		switch toks[0].text {
+		case "allow":
+			rs.allow = append(rs.allow, r)
+		case "deny":
+			rs.deny = append(rs.deny, r)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	return &rs, nil
+}
+
+// Name implements driver.Policy.
+func (rs *Rules) Name() string { return rs.name }
+
+// Evaluate implements driver.Policy.
+func (rs *Rules) Evaluate(ctx context.Context, vr *claircore.VulnerabilityReport) (*claircore.PolicyVerdict, error) {
+	v := claircore.PolicyVerdict{Policy: rs.name}
+	pkgs := make([]string, 0, len(vr.PackageVulnerabilities))
+	for id := range vr.PackageVulnerabilities {
+		pkgs = append(pkgs, id)
+	}
+	sort.Strings(pkgs)
+	for _, pid := range pkgs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p, ok := vr.Packages[pid]
+		if !ok {
+			continue
+		}
+		for _, vid := range vr.PackageVulnerabilities[pid] {
+			vuln, ok := vr.Vulnerabilities[vid]
+			if !ok {
+				continue
+			}
+			f := finding{pkg: p, vuln: vuln}
+			if rs.allowed(&f) {
+				continue
+			}
+			for _, r := range rs.deny {
+				if !r.eval(&f) {
+					continue
+				}
+				v.Violations = append(v.Violations, claircore.PolicyViolation{
+					Rule:            r.src,
+					Message:         fmt.Sprintf("%s (%v) affects %s %s", vuln.Name, vuln.NormalizedSeverity, p.Name, p.Version),
+					PackageID:       pid,
+					VulnerabilityID: vid,
+				})
+			}
+		}
+	}
+	v.Pass = len(v.Violations) == 0
+	return &v, nil
+}
+
+// Allowed reports whether any allow rule matches the finding.
+func (rs *Rules) allowed(f *finding) bool {
+	for _, r := range rs.allow {
+		if r.eval(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Finding is a vulnerability affecting a package, which rules are evaluated
+// against.
+type finding struct {
+	pkg  *claircore.Package
+	vuln *claircore.Vulnerability
+}
+
+// Rule is a parsed rule: a conjunction of conditions.
+type rule struct {
+	src   string
+	conds []cond
+}
+
+func (r *rule) eval(f *finding) bool {
+	for _, c := range r.conds {
+		if !c.eval(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// Cond is a single, possibly negated, comparison.
+type cond struct {
+	not   bool
+	field string
+	op    string
+	value string
+	sev   claircore.Severity
+}
+
+func (c *cond) eval(f *finding) bool {
+	var ok bool
+	switch c.field {
+	case "fixed":
+		ok = f.vuln.FixedInVersion != ""
+	case "severity":
+		a, b := f.vuln.NormalizedSeverity, c.sev
+		switch c.op {
+		case "==":
+			ok = a == b
+		case "!=":
+			ok = a != b
+		case "<":
+			ok = a < b
+		case "<=":
+			ok = a <= b
+		case ">":
+			ok = a > b
+		case ">=":
+			ok = a >= b
+		}
+	default:
+		var a string
+		switch c.field {
+		case "id":
+			a = f.vuln.Name
+		case "package":
+			a = f.pkg.Name
+		case "source":
+			if f.pkg.Source != nil {
+				a = f.pkg.Source.Name
+			}
+		case "version":
+			a = f.pkg.Version
+		case "fixed_in":
+			a = f.vuln.FixedInVersion
+		}
+		switch c.op {
+		case "==":
+			ok = a == c.value
+		case "!=":
+			ok = a != c.value
+		case "matches":
+			// The pattern was checked when parsing.
+			ok, _ = path.Match(c.value, a)
+		}
+	}
+	return ok != c.not
+}
+
+// Fields maps the fields comparisons may use to the operators allowed with
+// them.
+var fields = map[string][]string{
+	"id":       stringOps,
+	"package":  stringOps,
+	"source":   stringOps,
+	"version":  stringOps,
+	"fixed_in": stringOps,
+	"severity": {"==", "!=", "<", "<=", ">", ">="},
+	"fixed":    nil,
+}
+
+var stringOps = []string{"==", "!=", "matches"}
+
+// ParseRule parses a rule from its tokens. The first token is the action.
+func parseRule(toks []token) (rule, error) {
+	var r rule
+	switch a := toks[0]; {
+	case a.quoted, a.text != "allow" && a.text != "deny":
+		return r, fmt.Errorf("unknown action %q", a.text)
+	}
+	src := make([]string, len(toks)-1)
+	for i, t := range toks[1:] {
+		src[i] = t.String()
+	}
+	r.src = strings.Join(src, " ")
+
+	toks = toks[1:]
+	for {
+		if len(toks) == 0 {
+			return r, errors.New("missing condition")
+		}
+		var c cond
+		if t := toks[0]; !t.quoted && t.text == "not" {
+			c.not = true
+			toks = toks[1:]
+			if len(toks) == 0 {
+				return r, errors.New("missing condition after \"not\"")
+			}
+		}
+		f := toks[0]
+		ops, ok := fields[f.text]
+		if f.quoted || !ok {
+			return r, fmt.Errorf("unknown field %q", f.text)
+		}
+		c.field = f.text
+		toks = toks[1:]
+		if ops != nil {
+			if len(toks) < 2 {
+				return r, fmt.Errorf("incomplete comparison on %q", c.field)
+			}
+			op, val := toks[0], toks[1]
+			if op.quoted || !contains(ops, op.text) {
+				return r, fmt.Errorf("operator %q not allowed with %q", op.text, c.field)
+			}
+			c.op, c.value = op.text, val.text
+			toks = toks[2:]
+			switch {
+			case c.field == "severity":
+				sev, ok := parseSeverity(c.value)
+				if !ok {
+					return r, fmt.Errorf("unknown severity %q", c.value)
+				}
+				c.sev = sev
+			case c.op == "matches":
+				if _, err := path.Match(c.value, ""); err != nil {
+					return r, fmt.Errorf("bad pattern %q: %w", c.value, err)
+				}
+			}
+		}
+		r.conds = append(r.conds, c)
+		if len(toks) == 0 {
+			return r, nil
+		}
+		if t := toks[0]; t.quoted || t.text != "and" {
+			return r, fmt.Errorf("unexpected %q, expected \"and\"", t.text)
+		}
+		toks = toks[1:]
+	}
+}
+
+// ParseSeverity parses a severity name, ignoring case.
+func parseSeverity(s string) (claircore.Severity, bool) {
+	for sev := claircore.Unknown; sev <= claircore.Critical; sev++ {
+		if strings.EqualFold(s, sev.String()) {
+			return sev, true
+		}
+	}
+	return claircore.Unknown, false
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Token is a word, operator, or quoted string in a rule.
+type token struct {
+	text   string
+	quoted bool
+}
+
+func (t token) String() string {
+	if t.quoted {
+		return strconv.Quote(t.text)
+	}
+	return t.text
+}
+
+// Lex splits a line into tokens, stopping at a comment.
+func lex(line string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			return toks, nil
+		case c == '"':
+			j := i + 1
+			for ; j < len(line) && line[j] != '"'; j++ {
+				if line[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(line) {
+				return nil, errors.New("unterminated string")
+			}
+			s, err := strconv.Unquote(line[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("bad string %s: %w", line[i:j+1], err)
+			}
+			toks = append(toks, token{text: s, quoted: true})
+			i = j + 1
+		case isOp(c):
+			j := i
+			for j < len(line) && isOp(line[j]) {
+				j++
+			}
+			toks = append(toks, token{text: line[i:j]})
+			i = j
+		default:
+			j := i
+			for j < len(line) && !isOp(line[j]) && !strings.ContainsRune(" \t\r#\"", rune(line[j])) {
+				j++
+			}
+			toks = append(toks, token{text: line[i:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+func isOp(c byte) bool {
+	return c == '=' || c == '!' || c == '<' || c == '>'
+}
@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+)
+
+func testReport() *claircore.VulnerabilityReport {
+	return &claircore.VulnerabilityReport{
+		Packages: map[string]*claircore.Package{
+			"1": {ID: "1", Name: "openssl", Version: "1.1.1k"},
+			"2": {ID: "2", Name: "log4j", Version: "2.14.1"},
+			"3": {ID: "3", Name: "libcurl4", Version: "7.74.0", Source: &claircore.Package{Name: "curl"}},
+		},
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"a": {ID: "a", Name: "CVE-2021-3711", NormalizedSeverity: claircore.Critical, FixedInVersion: "1.1.1l"},
+			"b": {ID: "b", Name: "CVE-2021-44228", NormalizedSeverity: claircore.Critical, FixedInVersion: "2.15.0"},
+			"c": {ID: "c", Name: "CVE-2021-22945", NormalizedSeverity: claircore.Medium},
+			"d": {ID: "d", Name: "CVE-2021-22946", NormalizedSeverity: claircore.High},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"1": {"a"},
+			"2": {"b"},
+			"3": {"c", "d"},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+	tt := []struct {
+		Name   string
+		Policy string
+		Want   []claircore.PolicyViolation
+	}{
+		{
+			Name:   "Empty",
+			Policy: "# nothing\n\n",
+		},
+		{
+			Name:   "Fixable",
+			Policy: "deny severity >= high and fixed",
+			Want: []claircore.PolicyViolation{
+				{Rule: "severity >= high and fixed", PackageID: "1", VulnerabilityID: "a"},
+				{Rule: "severity >= high and fixed", PackageID: "2", VulnerabilityID: "b"},
+			},
+		},
+		{
+			Name: "Allow",
+			Policy: `deny severity>=High # no space needed
+allow id == "CVE-2021-44228" and package == log4j
+allow source matches "cu*" and not fixed and severity < Critical`,
+			Want: []claircore.PolicyViolation{
+				{Rule: "severity >= High", PackageID: "1", VulnerabilityID: "a"},
+			},
+		},
+		{
+			Name:   "NotEqual",
+			Policy: `deny severity == Medium and version != "7.74.0"`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			p, err := Parse(tc.Name, strings.NewReader(tc.Policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := p.Evaluate(ctx, testReport())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := v.Pass, len(tc.Want) == 0; got != want {
+				t.Errorf("pass: got: %v, want: %v", got, want)
+			}
+			if got, want := v.Policy, tc.Name; got != want {
+				t.Errorf("policy: got: %q, want: %q", got, want)
+			}
+			for i := range v.Violations {
+				if v.Violations[i].Message == "" {
+					t.Error("missing message")
+				}
+				v.Violations[i].Message = ""
+			}
+			if !cmp.Equal(v.Violations, tc.Want) {
+				t.Error(cmp.Diff(v.Violations, tc.Want))
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, p := range []string{
+		"permit severity > Low",
+		"deny",
+		"deny not",
+		"deny severity > Dangerous",
+		"deny severity matches High",
+		"deny package > openssl",
+		"deny package ==",
+		"deny colour == red",
+		"deny fixed or severity > Low",
+		`deny id == "CVE`,
+		"deny id matches [",
+		`"deny" fixed`,
+	} {
+		if _, err := Parse("test", strings.NewReader(p)); err == nil {
+			t.Errorf("%q: expected error", p)
+		} else {
+			t.Logf("%q: %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package referrers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// Attestations are in-toto statements, usually wrapped in a DSSE envelope and
+// sometimes further wrapped in a sigstore bundle. Cosign stores one envelope
+// per layer of the attestation manifest.
+const (
+	payloadTypeInToto = "application/vnd.in-toto+json"
+
+	predicateSLSA02 = "https://slsa.dev/provenance/v0.2"
+	predicateSLSA1  = "https://slsa.dev/provenance/v1"
+)
+
+// MaxAttestation is the largest attestation layer that will be read.
+const maxAttestation = 16 << 20
+
+// Provenance reads the attestation "d" and returns the first SLSA provenance
+// about the image "subject" found in it, or nil if there's none.
+func (f *Finder) provenance(ctx context.Context, repo repository, subject, d claircore.Digest) (*claircore.Provenance, error) {
+	ls, err := f.contents(ctx, repo, d)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range ls {
+		rc, err := f.blob(ctx, repo, l.Digest)
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(io.LimitReader(rc, maxAttestation))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		st, err := parseStatement(b)
+		if err != nil {
+			zlog.Debug(ctx).
+				Err(err).
+				Str("layer", l.Digest).
+				Msg("skipping attestation layer")
+			continue
+		}
+		if !st.About(subject) {
+			zlog.Debug(ctx).
+				Str("layer", l.Digest).
+				Msg("skipping attestation about another subject")
+			continue
+		}
+		if p := st.Provenance(); p != nil {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// Statement is an in-toto statement.
+type statement struct {
+	Type    string `json:"_type"`
+	Subject []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// ParseStatement unwraps an in-toto statement from a DSSE envelope or sigstore
+// bundle, if needed, and parses it.
+func parseStatement(b []byte) (*statement, error) {
+	var wrapper struct {
+		// DSSE envelope
+		PayloadType string `json:"payloadType"`
+		Payload     string `json:"payload"`
+		// Sigstore bundle
+		DSSEEnvelope *struct {
+			PayloadType string `json:"payloadType"`
+			Payload     string `json:"payload"`
+		} `json:"dsseEnvelope"`
+		// Bare statement
+		Type string `json:"_type"`
+	}
+	if err := json.Unmarshal(b, &wrapper); err != nil {
+		return nil, fmt.Errorf("referrers: malformed attestation: %w", err)
+	}
+	if e := wrapper.DSSEEnvelope; e != nil {
+		wrapper.PayloadType, wrapper.Payload = e.PayloadType, e.Payload
+	}
+	switch {
+	case wrapper.Type != "":
+	case wrapper.PayloadType == payloadTypeInToto:
+		var err error
+		b, err = base64.StdEncoding.DecodeString(wrapper.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("referrers: malformed envelope payload: %w", err)
+		}
+	case wrapper.PayloadType != "":
+		return nil, fmt.Errorf("referrers: unknown payload type %q", wrapper.PayloadType)
+	default:
+		return nil, errors.New("referrers: not an attestation")
+	}
+	var st statement
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("referrers: malformed statement: %w", err)
+	}
+	if !strings.HasPrefix(st.Type, "https://in-toto.io/Statement/") {
+		return nil, fmt.Errorf("referrers: unknown statement type %q", st.Type)
+	}
+	return &st, nil
+}
+
+// About reports whether the statement has the digest "d" as a subject.
+func (st *statement) About(d claircore.Digest) bool {
+	want := strings.TrimPrefix(d.String(), d.Algorithm()+":")
+	for _, s := range st.Subject {
+		if s.Digest[d.Algorithm()] == want {
+			return true
+		}
+	}
+	return false
+}
+
+// Provenance returns the build provenance in the statement's predicate, or
+// nil if it's not a known SLSA provenance predicate.
+func (st *statement) Provenance() *claircore.Provenance {
+	switch st.PredicateType {
+	case predicateSLSA02:
+		var p struct {
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+			BuildType  string `json:"buildType"`
+			Invocation struct {
+				ConfigSource struct {
+					URI    string            `json:"uri"`
+					Digest map[string]string `json:"digest"`
+				} `json:"configSource"`
+			} `json:"invocation"`
+			Materials []struct {
+				URI    string            `json:"uri"`
+				Digest map[string]string `json:"digest"`
+			} `json:"materials"`
+		}
+		if err := json.Unmarshal(st.Predicate, &p); err != nil {
+			return nil
+		}
+		out := claircore.Provenance{
+			PredicateType: st.PredicateType,
+			BuilderID:     p.Builder.ID,
+			BuildType:     p.BuildType,
+		}
+		// The config source is where the build definition came from, which
+		// is the source repository for most builders. Otherwise, the first
+		// material is conventionally the source.
+		cs := p.Invocation.ConfigSource
+		switch {
+		case cs.URI != "":
+			out.SourceURI, out.SourceRevision = cs.URI, revision(cs.Digest)
+		case len(p.Materials) != 0:
+			out.SourceURI, out.SourceRevision = p.Materials[0].URI, revision(p.Materials[0].Digest)
+		}
+		return &out
+	case predicateSLSA1:
+		var p struct {
+			BuildDefinition struct {
+				BuildType            string `json:"buildType"`
+				ResolvedDependencies []struct {
+					URI    string            `json:"uri"`
+					Digest map[string]string `json:"digest"`
+				} `json:"resolvedDependencies"`
+			} `json:"buildDefinition"`
+			RunDetails struct {
+				Builder struct {
+					ID string `json:"id"`
+				} `json:"builder"`
+			} `json:"runDetails"`
+		}
+		if err := json.Unmarshal(st.Predicate, &p); err != nil {
+			return nil
+		}
+		out := claircore.Provenance{
+			PredicateType: st.PredicateType,
+			BuilderID:     p.RunDetails.Builder.ID,
+			BuildType:     p.BuildDefinition.BuildType,
+		}
+		// Builders list the source first among the resolved dependencies.
+		if ds := p.BuildDefinition.ResolvedDependencies; len(ds) != 0 {
+			out.SourceURI, out.SourceRevision = ds[0].URI, revision(ds[0].Digest)
+		}
+		return &out
+	}
+	return nil
+}
+
+// Revision picks the source revision out of a digest set.
+func revision(d map[string]string) string {
+	for _, k := range []string{"gitCommit", "sha1", "sha256"} {
+		if v, ok := d[k]; ok {
+			return v
+		}
+	}
+	return ""
+}
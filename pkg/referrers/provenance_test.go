@@ -0,0 +1,148 @@
+package referrers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+func mkEnvelope(t *testing.T, subject, predicateType string, predicate interface{}) []byte {
+	t.Helper()
+	st, err := json.Marshal(map[string]interface{}{
+		"_type": "https://in-toto.io/Statement/v0.1",
+		"subject": []map[string]interface{}{
+			{"name": "quay.io/org/app", "digest": map[string]string{"sha256": subject}},
+		},
+		"predicateType": predicateType,
+		"predicate":     predicate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"payloadType": payloadTypeInToto,
+		"payload":     base64.StdEncoding.EncodeToString(st),
+		"signatures":  []interface{}{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestProvenance(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const (
+		tag   = "sha256-1111111111111111111111111111111111111111111111111111111111111111"
+		sbom  = "sha256:6666666666666666666666666666666666666666666666666666666666666666"
+		prov  = "sha256:7777777777777777777777777777777777777777777777777777777777777777"
+		other = "sha256:8888888888888888888888888888888888888888888888888888888888888888"
+	)
+	subject := imageDigest[len("sha256:"):]
+	blobs := map[string][]byte{
+		// An SBOM attestation, which isn't provenance.
+		sbom: mkEnvelope(t, subject, "https://spdx.dev/Document", map[string]string{}),
+		// Provenance about some other image.
+		other: mkEnvelope(t, "ffff", predicateSLSA02, map[string]interface{}{
+			"builder": map[string]string{"id": "https://example.com/evil"},
+		}),
+		prov: mkEnvelope(t, subject, predicateSLSA02, map[string]interface{}{
+			"builder":   map[string]string{"id": "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_container_slsa3.yml@refs/tags/v1.4.0"},
+			"buildType": "https://github.com/slsa-framework/slsa-github-generator/container@v1",
+			"invocation": map[string]interface{}{
+				"configSource": map[string]interface{}{
+					"uri":    "git+https://github.com/org/app@refs/heads/main",
+					"digest": map[string]string{"sha1": "0123456789abcdef0123456789abcdef01234567"},
+				},
+			},
+		}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/org/app/manifests/"+tag+".att", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Content-Digest", attDigest)
+	})
+	mux.HandleFunc("/v2/org/app/manifests/"+attDigest, func(w http.ResponseWriter, r *http.Request) {
+		var ls []map[string]string
+		for _, d := range []string{sbom, other, prov} {
+			ls = append(ls, map[string]string{"mediaType": "application/vnd.dsse.envelope.v1+json", "digest": d})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"layers": ls})
+	})
+	mux.HandleFunc("/v2/org/app/blobs/", func(w http.ResponseWriter, r *http.Request) {
+		b, ok := blobs[r.URL.Path[len("/v2/org/app/blobs/"):]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	got, err := NewFinder(srv.Client(), &Config{Provenance: true}).FindAttachments(ctx, manifest(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []claircore.Attachment{
+		{
+			Kind:   claircore.AttachmentAttestation,
+			Digest: claircore.MustParseDigest(attDigest),
+			Source: "tag",
+			Provenance: &claircore.Provenance{
+				PredicateType:  predicateSLSA02,
+				BuilderID:      "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_container_slsa3.yml@refs/tags/v1.4.0",
+				BuildType:      "https://github.com/slsa-framework/slsa-github-generator/container@v1",
+				SourceURI:      "git+https://github.com/org/app@refs/heads/main",
+				SourceRevision: "0123456789abcdef0123456789abcdef01234567",
+			},
+		},
+	}
+	if !cmp.Equal(got, want, digestCmp) {
+		t.Error(cmp.Diff(got, want, digestCmp))
+	}
+}
+
+func TestStatementSLSA1(t *testing.T) {
+	b, err := json.Marshal(map[string]interface{}{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"predicateType": predicateSLSA1,
+		"predicate": map[string]interface{}{
+			"buildDefinition": map[string]interface{}{
+				"buildType": "https://actions.github.io/buildtypes/workflow/v1",
+				"resolvedDependencies": []map[string]interface{}{
+					{"uri": "git+https://github.com/org/app@refs/heads/main", "digest": map[string]string{"gitCommit": "abc123"}},
+				},
+			},
+			"runDetails": map[string]interface{}{
+				"builder": map[string]string{"id": "https://github.com/actions/runner/github-hosted"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := parseStatement(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := st.Provenance()
+	want := &claircore.Provenance{
+		PredicateType:  predicateSLSA1,
+		BuilderID:      "https://github.com/actions/runner/github-hosted",
+		BuildType:      "https://actions.github.io/buildtypes/workflow/v1",
+		SourceURI:      "git+https://github.com/org/app@refs/heads/main",
+		SourceRevision: "abc123",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -0,0 +1,354 @@
+// Package referrers discovers the artifacts attached to an image in its
+// registry: cosign signatures, in-toto attestations, and SBOMs.
+//
+// Attachments are found using the OCI referrers API where the registry
+// supports it, and by cosign's tag naming convention ("sha256-<hex>.sig",
+// ".att", and ".sbom") where it doesn't.
+//
+// The registry and repository are inferred from the URIs of the manifest's
+// layers, so discovery only works for layers fetched from a registry's blob
+// endpoint.
+package referrers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+// Media types used when talking to the registry.
+const (
+	mediaTypeIndex          = "application/vnd.oci.image.index.v1+json"
+	mediaTypeManifest       = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// ArtifactKinds maps known artifact types to Attachment kinds. Artifacts with
+// other types aren't reported.
+var artifactKinds = map[string]string{
+	"application/vnd.dev.cosign.artifact.sig.v1+json":      claircore.AttachmentSignature,
+	"application/vnd.dev.cosign.simplesigning.v1+json":     claircore.AttachmentSignature,
+	"application/vnd.dev.sigstore.bundle+json;version=0.1": claircore.AttachmentSignature,
+	"application/vnd.dev.sigstore.bundle+json;version=0.2": claircore.AttachmentSignature,
+	"application/vnd.dev.sigstore.bundle.v0.3+json":        claircore.AttachmentSignature,
+	"application/vnd.dsse.envelope.v1+json":                claircore.AttachmentAttestation,
+	"application/vnd.in-toto+json":                         claircore.AttachmentAttestation,
+	"application/vnd.dev.cosign.artifact.sbom.v1+json":     claircore.AttachmentSBOM,
+	"application/spdx+json":                                claircore.AttachmentSBOM,
+	"text/spdx":                                            claircore.AttachmentSBOM,
+	"text/spdx+json":                                       claircore.AttachmentSBOM,
+	"application/vnd.cyclonedx+json":                       claircore.AttachmentSBOM,
+	"application/vnd.cyclonedx+xml":                        claircore.AttachmentSBOM,
+	"application/vnd.syft+json":                            claircore.AttachmentSBOM,
+}
+
+// TagSuffixes maps cosign's tag suffixes to Attachment kinds.
+var tagSuffixes = []struct {
+	Suffix string
+	Kind   string
+}{
+	{".sig", claircore.AttachmentSignature},
+	{".att", claircore.AttachmentAttestation},
+	{".sbom", claircore.AttachmentSBOM},
+}
+
+// Config configures a Finder.
+type Config struct {
+	// OnSBOM, if set, is called with the contents of every attached SBOM
+	// found, along with the media type of the contents. This is the hook for
+	// feeding attached SBOMs into an ingestion process.
+	//
+	// An error returned by OnSBOM is logged and otherwise ignored.
+	OnSBOM func(ctx context.Context, m *claircore.Manifest, a claircore.Attachment, mediaType string, r io.Reader) error
+	// Provenance enables reading attached attestations and recording any
+	// SLSA provenance about the image found in them.
+	//
+	// Attestation signatures are not verified, so the recorded provenance is
+	// only as trustworthy as the registry it came from.
+	Provenance bool
+}
+
+// Finder discovers attachments using the OCI distribution API.
+type Finder struct {
+	c   *http.Client
+	cfg Config
+}
+
+var _ indexer.AttachmentFinder = (*Finder)(nil)
+
+// NewFinder returns a Finder making requests with the provided client.
+//
+// The client should answer registry authentication challenges; see the
+// registryauth package.
+func NewFinder(c *http.Client, cfg *Config) *Finder {
+	f := Finder{c: c}
+	if cfg != nil {
+		f.cfg = *cfg
+	}
+	return &f
+}
+
+// Repository is the location of an image's repository.
+type repository struct {
+	// Base is the URL of the repository, without a trailing slash. For
+	// example: "https://quay.io/v2/projectquay/clair".
+	Base   string
+	Header http.Header
+}
+
+// FindAttachments implements indexer.AttachmentFinder.
+func (f *Finder) FindAttachments(ctx context.Context, m *claircore.Manifest) ([]claircore.Attachment, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pkg/referrers/Finder.FindAttachments"))
+	repo, ok := locate(m)
+	if !ok {
+		zlog.Debug(ctx).Msg("no registry layer URIs, skipping attachment discovery")
+		return nil, nil
+	}
+	ctx = baggage.ContextWithValues(ctx, label.String("repository", repo.Base))
+
+	out, ok, err := f.referrers(ctx, repo, m.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		zlog.Debug(ctx).Msg("referrers API unsupported, falling back to tags")
+		if out, err = f.tags(ctx, repo, m.Hash); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Digest.String() < out[j].Digest.String()
+	})
+
+	if f.cfg.Provenance {
+		for i := range out {
+			if out[i].Kind != claircore.AttachmentAttestation {
+				continue
+			}
+			p, err := f.provenance(ctx, repo, m.Hash, out[i].Digest)
+			if err != nil {
+				zlog.Warn(ctx).
+					Err(err).
+					Stringer("attachment", out[i].Digest).
+					Msg("unable to read attestation")
+				continue
+			}
+			out[i].Provenance = p
+		}
+	}
+	if f.cfg.OnSBOM != nil {
+		for _, a := range out {
+			if a.Kind != claircore.AttachmentSBOM {
+				continue
+			}
+			if err := f.feed(ctx, repo, m, a); err != nil {
+				zlog.Warn(ctx).
+					Err(err).
+					Stringer("attachment", a.Digest).
+					Msg("unable to process attached SBOM")
+			}
+		}
+	}
+	return out, nil
+}
+
+// Locate finds the repository the manifest's layers were fetched from.
+func locate(m *claircore.Manifest) (repository, bool) {
+	for _, l := range m.Layers {
+		u, err := url.Parse(l.URI)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			continue
+		}
+		i := strings.LastIndex(u.Path, "/blobs/")
+		if !strings.HasPrefix(u.Path, "/v2/") || i < len("/v2/") {
+			continue
+		}
+		return repository{
+			Base:   u.Scheme + "://" + u.Host + u.Path[:i],
+			Header: l.Headers,
+		}, true
+	}
+	return repository{}, false
+}
+
+// Do makes a request against the repository.
+func (f *Finder) do(ctx context.Context, repo repository, method, p string, accept ...string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, repo.Base+p, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range repo.Header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+	if len(accept) != 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	return f.c.Do(req)
+}
+
+// Referrers queries the OCI referrers API, reporting false if the registry
+// doesn't support it.
+func (f *Finder) referrers(ctx context.Context, repo repository, d claircore.Digest) ([]claircore.Attachment, bool, error) {
+	res, err := f.do(ctx, repo, http.MethodGet, "/referrers/"+d.String(), mediaTypeIndex)
+	if err != nil {
+		return nil, false, fmt.Errorf("referrers: request failed: %w", err)
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusBadRequest, http.StatusMethodNotAllowed:
+		// Registries without the API either don't route it or treat
+		// "referrers" as a malformed reference.
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("referrers: unexpected status code: %s", res.Status)
+	}
+	var idx struct {
+		Manifests []struct {
+			MediaType    string `json:"mediaType"`
+			Digest       string `json:"digest"`
+			ArtifactType string `json:"artifactType"`
+		} `json:"manifests"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 4<<20)).Decode(&idx); err != nil {
+		return nil, false, fmt.Errorf("referrers: unable to decode index: %w", err)
+	}
+	var out []claircore.Attachment
+	for _, m := range idx.Manifests {
+		k, ok := artifactKinds[m.ArtifactType]
+		if !ok {
+			continue
+		}
+		dg, err := claircore.ParseDigest(m.Digest)
+		if err != nil {
+			zlog.Debug(ctx).
+				Err(err).
+				Str("digest", m.Digest).
+				Msg("skipping referrer with bad digest")
+			continue
+		}
+		out = append(out, claircore.Attachment{
+			Kind:         k,
+			Digest:       dg,
+			ArtifactType: m.ArtifactType,
+			Source:       "referrers",
+		})
+	}
+	return out, true, nil
+}
+
+// Tags looks for attachments using cosign's tag naming convention.
+func (f *Finder) tags(ctx context.Context, repo repository, d claircore.Digest) ([]claircore.Attachment, error) {
+	prefix := d.Algorithm() + "-" + strings.TrimPrefix(d.String(), d.Algorithm()+":")
+	var out []claircore.Attachment
+	for _, s := range tagSuffixes {
+		res, err := f.do(ctx, repo, http.MethodHead, "/manifests/"+prefix+s.Suffix,
+			mediaTypeManifest, mediaTypeDockerManifest)
+		if err != nil {
+			return nil, fmt.Errorf("referrers: request failed: %w", err)
+		}
+		res.Body.Close()
+		switch res.StatusCode {
+		case http.StatusOK:
+		case http.StatusNotFound:
+			continue
+		default:
+			return nil, fmt.Errorf("referrers: unexpected status code: %s", res.Status)
+		}
+		dg, err := claircore.ParseDigest(res.Header.Get("Docker-Content-Digest"))
+		if err != nil {
+			zlog.Debug(ctx).
+				Err(err).
+				Str("tag", prefix+s.Suffix).
+				Msg("skipping attachment tag without digest")
+			continue
+		}
+		out = append(out, claircore.Attachment{
+			Kind:   s.Kind,
+			Digest: dg,
+			Source: "tag",
+		})
+	}
+	return out, nil
+}
+
+// Feed fetches the contents of an attached SBOM and hands them to the OnSBOM
+// hook.
+func (f *Finder) feed(ctx context.Context, repo repository, m *claircore.Manifest, a claircore.Attachment) error {
+	ls, err := f.contents(ctx, repo, a.Digest)
+	if err != nil {
+		return err
+	}
+	for _, l := range ls {
+		rc, err := f.blob(ctx, repo, l.Digest)
+		if err != nil {
+			return err
+		}
+		err = f.cfg.OnSBOM(ctx, m, a, l.MediaType, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Descriptor is the subset of an OCI content descriptor used.
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+}
+
+// Contents returns the descriptors of the layers of the artifact manifest
+// "d", which hold the artifact's contents.
+func (f *Finder) contents(ctx context.Context, repo repository, d claircore.Digest) ([]descriptor, error) {
+	res, err := f.do(ctx, repo, http.MethodGet, "/manifests/"+d.String(),
+		mediaTypeManifest, mediaTypeDockerManifest)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("referrers: unexpected status code: %s", res.Status)
+	}
+	var am struct {
+		Layers []descriptor `json:"layers"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 4<<20)).Decode(&am); err != nil {
+		return nil, fmt.Errorf("referrers: unable to decode manifest: %w", err)
+	}
+	if len(am.Layers) == 0 {
+		return nil, errors.New("referrers: attachment has no content")
+	}
+	return am.Layers, nil
+}
+
+// Blob opens the blob "d" in the repository.
+func (f *Finder) blob(ctx context.Context, repo repository, d string) (io.ReadCloser, error) {
+	res, err := f.do(ctx, repo, http.MethodGet, "/blobs/"+d)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("referrers: unexpected status code: %s", res.Status)
+	}
+	return res.Body, nil
+}
@@ -0,0 +1,145 @@
+package referrers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+const (
+	imageDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	sigDigest   = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	sbomDigest  = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+	blobDigest  = "sha256:4444444444444444444444444444444444444444444444444444444444444444"
+	attDigest   = "sha256:5555555555555555555555555555555555555555555555555555555555555555"
+)
+
+var digestCmp = cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })
+
+func manifest(srv *httptest.Server) *claircore.Manifest {
+	return &claircore.Manifest{
+		Hash: claircore.MustParseDigest(imageDigest),
+		Layers: []*claircore.Layer{
+			{URI: srv.URL + "/v2/org/app/blobs/" + blobDigest},
+		},
+	}
+}
+
+func TestReferrers(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/org/app/referrers/"+imageDigest, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", mediaTypeIndex)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"schemaVersion": 2,
+			"mediaType":     mediaTypeIndex,
+			"manifests": []map[string]string{
+				{"mediaType": mediaTypeManifest, "digest": sbomDigest, "artifactType": "application/spdx+json"},
+				{"mediaType": mediaTypeManifest, "digest": sigDigest, "artifactType": "application/vnd.dev.cosign.artifact.sig.v1+json"},
+				{"mediaType": mediaTypeManifest, "digest": attDigest, "artifactType": "application/vnd.example.unknown"},
+			},
+		})
+	})
+	mux.HandleFunc("/v2/org/app/manifests/"+sbomDigest, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"layers": []map[string]string{
+				{"mediaType": "application/spdx+json", "digest": blobDigest},
+			},
+		})
+	})
+	mux.HandleFunc("/v2/org/app/blobs/"+blobDigest, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"spdxVersion":"SPDX-2.2"}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var gotSBOM, gotType string
+	f := NewFinder(srv.Client(), &Config{
+		OnSBOM: func(_ context.Context, _ *claircore.Manifest, a claircore.Attachment, mt string, r io.Reader) error {
+			b, err := io.ReadAll(r)
+			gotSBOM, gotType = string(b), mt
+			return err
+		},
+	})
+	got, err := f.FindAttachments(ctx, manifest(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []claircore.Attachment{
+		{
+			Kind:         claircore.AttachmentSBOM,
+			Digest:       claircore.MustParseDigest(sbomDigest),
+			ArtifactType: "application/spdx+json",
+			Source:       "referrers",
+		},
+		{
+			Kind:         claircore.AttachmentSignature,
+			Digest:       claircore.MustParseDigest(sigDigest),
+			ArtifactType: "application/vnd.dev.cosign.artifact.sig.v1+json",
+			Source:       "referrers",
+		},
+	}
+	if !cmp.Equal(got, want, digestCmp) {
+		t.Error(cmp.Diff(got, want, digestCmp))
+	}
+	if got, want := gotSBOM, `{"spdxVersion":"SPDX-2.2"}`; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := gotType, "application/spdx+json"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestTags(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const tag = "sha256-1111111111111111111111111111111111111111111111111111111111111111"
+	mux := http.NewServeMux()
+	for p, d := range map[string]string{
+		"/v2/org/app/manifests/" + tag + ".sig": sigDigest,
+		"/v2/org/app/manifests/" + tag + ".att": attDigest,
+	} {
+		d := d
+		mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodHead {
+				t.Errorf("unexpected method: %s", r.Method)
+			}
+			w.Header().Set("Docker-Content-Digest", d)
+		})
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	got, err := NewFinder(srv.Client(), nil).FindAttachments(ctx, manifest(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []claircore.Attachment{
+		{Kind: claircore.AttachmentAttestation, Digest: claircore.MustParseDigest(attDigest), Source: "tag"},
+		{Kind: claircore.AttachmentSignature, Digest: claircore.MustParseDigest(sigDigest), Source: "tag"},
+	}
+	if !cmp.Equal(got, want, digestCmp) {
+		t.Error(cmp.Diff(got, want, digestCmp))
+	}
+}
+
+func TestLocate(t *testing.T) {
+	m := &claircore.Manifest{Layers: []*claircore.Layer{
+		{URI: "file:///tmp/layer.tar"},
+		{URI: "https://quay.io/v2/projectquay/clair/blobs/" + blobDigest},
+	}}
+	repo, ok := locate(m)
+	if !ok {
+		t.Fatal("no repository found")
+	}
+	if got, want := repo.Base, "https://quay.io/v2/projectquay/clair"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Reference names an image in a registry, by tag or by digest.
+type Reference struct {
+	// Registry is the registry's host, including the port if any.
+	Registry string
+	// Repository is the repository's path in the registry.
+	Repository string
+	// Tag is the tag naming the image, if the Reference has no Digest.
+	Tag string
+	// Digest is the digest of the image's manifest or index, if known.
+	Digest string
+}
+
+// DockerHub is the registry images are assumed to be in if a reference doesn't
+// name one, and dockerHubAPI the host its API is served from.
+const (
+	dockerHub    = "docker.io"
+	dockerHubAPI = "registry-1.docker.io"
+)
+
+// ParseReference parses an image reference in the form the docker client
+// accepts, such as "ubuntu", "quay.io/projectquay/clair:4.3.0", or
+// "registry.example.com:5000/app@sha256:...".
+//
+// References that don't name a registry are in Docker Hub, and references
+// with neither a tag nor a digest name the "latest" tag.
+func ParseReference(s string) (Reference, error) {
+	var r Reference
+	name := s
+	if i := strings.IndexByte(name, '@'); i != -1 {
+		d, err := claircore.ParseDigest(name[i+1:])
+		if err != nil {
+			return r, fmt.Errorf("registry: bad reference %q: %w", s, err)
+		}
+		r.Digest = d.String()
+		name = name[:i]
+	}
+	if i := strings.LastIndexByte(name, ':'); i != -1 && !strings.ContainsRune(name[i:], '/') {
+		r.Tag = name[i+1:]
+		name = name[:i]
+		if r.Tag == "" {
+			return r, fmt.Errorf("registry: bad reference %q: empty tag", s)
+		}
+	}
+	if i := strings.IndexByte(name, '/'); i != -1 {
+		if h := name[:i]; strings.ContainsAny(h, ".:") || h == "localhost" {
+			r.Registry = h
+			name = name[i+1:]
+		}
+	}
+	if r.Registry == "" {
+		r.Registry = dockerHub
+		if !strings.ContainsRune(name, '/') {
+			name = "library/" + name
+		}
+	}
+	if !validRepository(name) {
+		return r, fmt.Errorf("registry: bad reference %q: invalid repository %q", s, name)
+	}
+	r.Repository = name
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+	return r, nil
+}
+
+// ValidRepository reports whether the repository name is made of lowercase
+// path components, as the distribution specification requires.
+func validRepository(n string) bool {
+	if n == "" {
+		return false
+	}
+	for _, c := range strings.Split(n, "/") {
+		if c == "" {
+			return false
+		}
+		for _, r := range c {
+			switch {
+			case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			case r == '.', r == '_', r == '-':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// String returns the reference in the form ParseReference accepts.
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Host returns the host the registry's API is served from.
+func (r Reference) host() string {
+	if r.Registry == dockerHub {
+		return dockerHubAPI
+	}
+	return r.Registry
+}
+
+// Ref returns the tag or digest to request the manifest by. The digest is
+// preferred.
+func (r Reference) ref() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	d := "sha256:" + strings.Repeat("a", 64)
+	tt := []struct {
+		In   string
+		Want Reference
+		Host string
+	}{
+		{"ubuntu", Reference{Registry: "docker.io", Repository: "library/ubuntu", Tag: "latest"}, "registry-1.docker.io"},
+		{"grafana/grafana:8.2.0", Reference{Registry: "docker.io", Repository: "grafana/grafana", Tag: "8.2.0"}, "registry-1.docker.io"},
+		{"quay.io/projectquay/clair:4.3.0", Reference{Registry: "quay.io", Repository: "projectquay/clair", Tag: "4.3.0"}, "quay.io"},
+		{"localhost:5000/app@" + d, Reference{Registry: "localhost:5000", Repository: "app", Digest: d}, "localhost:5000"},
+		{"localhost/app:v1@" + d, Reference{Registry: "localhost", Repository: "app", Tag: "v1", Digest: d}, "localhost"},
+	}
+	for _, tc := range tt {
+		got, err := ParseReference(tc.In)
+		if err != nil {
+			t.Errorf("%q: %v", tc.In, err)
+			continue
+		}
+		if got != tc.Want {
+			t.Errorf("%q: got: %+v, want: %+v", tc.In, got, tc.Want)
+		}
+		if got := got.host(); got != tc.Host {
+			t.Errorf("%q: got host: %q, want: %q", tc.In, got, tc.Host)
+		}
+		again, err := ParseReference(got.String())
+		if err != nil || again != got {
+			t.Errorf("%q: round trip: got: %+v (%v)", tc.In, again, err)
+		}
+	}
+	for _, in := range []string{
+		"",
+		"Ubuntu",
+		"ubuntu:",
+		"ubuntu@sha256:nope",
+		"quay.io//clair",
+	} {
+		if _, err := ParseReference(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
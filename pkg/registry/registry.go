@@ -0,0 +1,403 @@
+// Package registry talks to OCI Distribution registries directly, resolving
+// image references to claircore.Manifests and fetching their layers.
+//
+// Without it, callers of libindex must resolve every layer of an image to a
+// URI themselves. A Client resolves a Reference to a Manifest whose layers
+// point at the registry's blob endpoint, and also implements
+// driver.Transport, so it can be given to libindex as Opts.FetchTransport to
+// fetch those layers: interrupted downloads are resumed with range requests,
+// and every blob is checked against its digest.
+//
+// Registry token authentication is handled by the http.Client, as one
+// returned by registryauth.Client; see New.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex/driver"
+	"github.com/quay/claircore/pkg/registryauth"
+)
+
+// Media types of manifests and indexes.
+const (
+	MediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// DefaultPlatform is the platform selected from an index if the caller
+// doesn't name one.
+var DefaultPlatform = claircore.Platform{OS: "linux", Architecture: "amd64"}
+
+// MaxResumes is the number of times a single blob download is resumed after
+// failing.
+const maxResumes = 5
+
+// MaxManifestSize is the size limit for manifests, indexes, and image
+// configurations.
+const maxManifestSize = 4 << 20
+
+var _ driver.Transport = (*Client)(nil)
+
+// Client makes requests to registries.
+//
+// The zero value is ready to use, and makes anonymous requests with
+// http.DefaultClient.
+type Client struct {
+	// HTTP is the client requests are made with. It should answer registry
+	// token challenges; see New.
+	HTTP *http.Client
+	// PlainHTTP makes requests to registries over plain HTTP instead of
+	// HTTPS. It's meant for testing and local registries.
+	PlainHTTP bool
+}
+
+// New returns a Client making requests with the provided http.Client, which
+// is wrapped to answer registry token challenges using the Config. The
+// Config's Keychain may be a registryauth.DockerConfig to use the docker
+// client's credentials.
+//
+// If the http.Client is nil, a new one is used.
+func New(c *http.Client, cfg *registryauth.Config) *Client {
+	return &Client{HTTP: registryauth.Client(c, cfg)}
+}
+
+func (c *Client) client() *http.Client {
+	if c.HTTP == nil {
+		return http.DefaultClient
+	}
+	return c.HTTP
+}
+
+func (c *Client) url(r Reference, kind, ref string) string {
+	u := url.URL{
+		Scheme: "https",
+		Host:   r.host(),
+		Path:   "/v2/" + r.Repository + "/" + kind + "/" + ref,
+	}
+	if c.PlainHTTP {
+		u.Scheme = "http"
+	}
+	return u.String()
+}
+
+// Descriptor is the subset of an OCI descriptor used here.
+type descriptor struct {
+	MediaType string              `json:"mediaType"`
+	Digest    string              `json:"digest"`
+	Size      int64               `json:"size"`
+	URLs      []string            `json:"urls"`
+	Platform  *claircore.Platform `json:"platform"`
+}
+
+// Manifest resolves the reference to a Manifest.
+//
+// If the reference names an index or manifest list, the manifest for the
+// platform is selected; a nil platform selects DefaultPlatform. The
+// Manifest's Platform is set in that case, as it is by IndexPlatform.
+//
+// The returned Manifest's layers are fetched from the registry's blob
+// endpoint, and its Labels and History are set from the image configuration.
+func (c *Client) Manifest(ctx context.Context, r Reference, p *claircore.Platform) (*claircore.Manifest, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pkg/registry/Client.Manifest"),
+		label.String("reference", r.String()))
+	b, mt, d, err := c.getManifest(ctx, r, r.ref())
+	if err != nil {
+		return nil, err
+	}
+	if r.Digest != "" && d.String() != r.Digest {
+		return nil, fmt.Errorf("registry: manifest digest mismatch: got %q, expected %q", d, r.Digest)
+	}
+
+	var plat *claircore.Platform
+	if mt == MediaTypeOCIIndex || mt == MediaTypeDockerList {
+		want := DefaultPlatform
+		if p != nil {
+			want = *p
+		}
+		var idx struct {
+			Manifests []descriptor `json:"manifests"`
+		}
+		if err := json.Unmarshal(b, &idx); err != nil {
+			return nil, fmt.Errorf("registry: unable to decode index: %w", err)
+		}
+		var sel *descriptor
+		var have []string
+		for i := range idx.Manifests {
+			m := &idx.Manifests[i]
+			if m.Platform == nil {
+				continue
+			}
+			have = append(have, m.Platform.String())
+			if m.Platform.Match(want) {
+				sel = m
+				break
+			}
+		}
+		if sel == nil {
+			return nil, fmt.Errorf("registry: no manifest for platform %v (have %v)", want, have)
+		}
+		zlog.Debug(ctx).
+			Str("platform", sel.Platform.String()).
+			Str("manifest", sel.Digest).
+			Msg("selected manifest from index")
+		plat = sel.Platform
+		b, mt, d, err = c.getManifest(ctx, r, sel.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if d.String() != sel.Digest {
+			return nil, fmt.Errorf("registry: manifest digest mismatch: got %q, expected %q", d, sel.Digest)
+		}
+	}
+	if mt != MediaTypeOCIManifest && mt != MediaTypeDockerManifest {
+		return nil, fmt.Errorf("registry: unsupported manifest type %q", mt)
+	}
+
+	var m struct {
+		Config descriptor   `json:"config"`
+		Layers []descriptor `json:"layers"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("registry: unable to decode manifest: %w", err)
+	}
+	out := claircore.Manifest{
+		Hash:     d,
+		Platform: plat,
+	}
+	if err := c.config(ctx, r, m.Config, &out); err != nil {
+		return nil, err
+	}
+	for _, l := range m.Layers {
+		ld, err := claircore.ParseDigest(l.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("registry: bad layer digest: %w", err)
+		}
+		out.Layers = append(out.Layers, &claircore.Layer{
+			Hash: ld,
+			URI:  c.url(r, "blobs", l.Digest),
+			URLs: l.URLs,
+		})
+	}
+	return &out, nil
+}
+
+// GetManifest fetches a manifest or index by tag or digest, returning its
+// contents, media type, and digest.
+func (c *Client) getManifest(ctx context.Context, r Reference, ref string) ([]byte, string, claircore.Digest, error) {
+	var d claircore.Digest
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(r, "manifests", ref), nil)
+	if err != nil {
+		return nil, "", d, err
+	}
+	req.Header.Set("Accept", strings.Join([]string{
+		MediaTypeOCIIndex, MediaTypeDockerList, MediaTypeOCIManifest, MediaTypeDockerManifest,
+	}, ", "))
+	res, err := c.client().Do(req)
+	if err != nil {
+		return nil, "", d, fmt.Errorf("registry: request failed: %w", err)
+	}
+	defer res.Body.Close()
+	if err := checkStatus(res, http.StatusOK); err != nil {
+		return nil, "", d, err
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, "", d, fmt.Errorf("registry: unable to read manifest: %w", err)
+	}
+	if len(b) > maxManifestSize {
+		return nil, "", d, errors.New("registry: manifest too large")
+	}
+	sum := sha256.Sum256(b)
+	d, err = claircore.NewDigest("sha256", sum[:])
+	if err != nil {
+		return nil, "", d, err
+	}
+	mt := res.Header.Get("Content-Type")
+	if i := strings.IndexByte(mt, ';'); i != -1 {
+		mt = mt[:i]
+	}
+	if mt == "" || mt == "application/json" || mt == "application/octet-stream" {
+		// Some registries don't report a useful type; fall back to the one
+		// in the document.
+		var doc struct {
+			MediaType string `json:"mediaType"`
+		}
+		if err := json.Unmarshal(b, &doc); err == nil {
+			mt = doc.MediaType
+		}
+	}
+	return b, strings.TrimSpace(mt), d, nil
+}
+
+// Config fetches the image configuration, setting the Labels and History in
+// the Manifest.
+func (c *Client) config(ctx context.Context, r Reference, desc descriptor, m *claircore.Manifest) error {
+	d, err := claircore.ParseDigest(desc.Digest)
+	if err != nil {
+		return fmt.Errorf("registry: bad config digest: %w", err)
+	}
+	blob, err := c.Fetch(ctx, &claircore.Layer{Hash: d, URI: c.url(r, "blobs", desc.Digest)})
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	var cfg struct {
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"config"`
+		History []struct {
+			CreatedBy  string `json:"created_by"`
+			EmptyLayer bool   `json:"empty_layer"`
+		} `json:"history"`
+	}
+	b, err := io.ReadAll(io.LimitReader(blob, maxManifestSize))
+	if err != nil {
+		return fmt.Errorf("registry: unable to read config: %w", err)
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("registry: unable to decode config: %w", err)
+	}
+	m.Labels = cfg.Config.Labels
+	for _, h := range cfg.History {
+		m.History = append(m.History, claircore.History{
+			CreatedBy:  h.CreatedBy,
+			EmptyLayer: h.EmptyLayer,
+		})
+	}
+	return nil
+}
+
+// Fetch implements driver.Transport.
+//
+// The layer's URI is requested with its Headers. If reading the response
+// fails partway, the download is resumed with a range request, if the server
+// supports them. The contents are checked against the layer's digest as
+// they're read: a mismatch is reported in place of the io.EOF at the end.
+func (c *Client) Fetch(ctx context.Context, l *claircore.Layer) (*driver.Blob, error) {
+	if l.Hash.Checksum() == nil {
+		return nil, errors.New("registry: digest is empty")
+	}
+	res, err := c.get(ctx, l, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &driver.Blob{
+		ReadCloser: &blobReader{
+			ctx:  ctx,
+			c:    c,
+			l:    l,
+			body: res.Body,
+			h:    l.Hash.Hash(),
+		},
+		MediaType: res.Header.Get("Content-Type"),
+	}, nil
+}
+
+// Get requests the layer, starting at the offset.
+func (c *Client) get(ctx context.Context, l *claircore.Layer, off int64) (*http.Response, error) {
+	if l.URI == "" {
+		return nil, fmt.Errorf("registry: empty uri for layer %v", l.Hash)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.URI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("registry: bad uri for layer %v: %w", l.Hash, err)
+	}
+	for k, vs := range l.Headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	status := http.StatusOK
+	if off > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", off))
+		status = http.StatusPartialContent
+	}
+	res, err := c.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("registry: request failed: %w", err)
+	}
+	if err := checkStatus(res, status); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+// CheckStatus returns an error if the response doesn't have the wanted status,
+// including the start of the body to help explain why.
+func checkStatus(res *http.Response, want int) error {
+	if res.StatusCode == want {
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(res.Body, 256))
+	return fmt.Errorf("registry: unexpected status code: %s (body starts: %q)", res.Status, b)
+}
+
+// BlobReader reads a blob, resuming the download on errors and checking the
+// digest at EOF.
+type blobReader struct {
+	ctx     context.Context
+	c       *Client
+	l       *claircore.Layer
+	body    io.ReadCloser
+	h       hash.Hash
+	n       int64
+	resumes int
+}
+
+func (r *blobReader) Read(b []byte) (int, error) {
+	n, err := r.body.Read(b)
+	r.h.Write(b[:n])
+	r.n += int64(n)
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF):
+		if err := r.l.Hash.Verify(r.h.Sum(nil)); err != nil {
+			return n, fmt.Errorf("registry: %w", err)
+		}
+	default:
+		if r.resumes >= maxResumes || r.ctx.Err() != nil {
+			break
+		}
+		r.resumes++
+		zlog.Debug(r.ctx).
+			Err(err).
+			Stringer("layer", r.l.Hash).
+			Int64("offset", r.n).
+			Int("attempt", r.resumes).
+			Msg("resuming download")
+		res, rerr := r.c.get(r.ctx, r.l, r.n)
+		if rerr != nil {
+			zlog.Debug(r.ctx).
+				Err(rerr).
+				Msg("unable to resume download")
+			break
+		}
+		r.body.Close()
+		r.body = res.Body
+		err = nil
+	}
+	return n, err
+}
+
+func (r *blobReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,222 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/registryauth"
+)
+
+// TestRegistry is a minimal registry serving a single multi-platform image
+// from memory, requiring a token and cutting off the first download of every
+// blob halfway through.
+type testRegistry struct {
+	*httptest.Server
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	types     map[string]string
+	tag       string
+	cut       map[string]bool
+	ranges    int32
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return fmt.Sprintf("sha256:%x", sum)
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	r := &testRegistry{
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string][]byte),
+		types:     make(map[string]string),
+		cut:       make(map[string]bool),
+	}
+	layer := bytes.Repeat([]byte("layer contents "), 1000)
+	r.blobs[digestOf(layer)] = layer
+	cfg, _ := json.Marshal(map[string]interface{}{
+		"config": map[string]interface{}{
+			"Labels": map[string]string{"org.opencontainers.image.version": "1.0"},
+		},
+		"history": []map[string]interface{}{
+			{"created_by": "/bin/sh -c #(nop) ADD file:123 in / "},
+			{"created_by": "/bin/sh -c #(nop)  CMD [\"sh\"]", "empty_layer": true},
+		},
+	})
+	r.blobs[digestOf(cfg)] = cfg
+	m, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeOCIManifest,
+		"config":        map[string]interface{}{"mediaType": "application/vnd.oci.image.config.v1+json", "digest": digestOf(cfg), "size": len(cfg)},
+		"layers": []map[string]interface{}{
+			{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": digestOf(layer), "size": len(layer)},
+		},
+	})
+	r.manifests[digestOf(m)] = m
+	r.types[digestOf(m)] = MediaTypeOCIManifest
+	idx, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeOCIIndex,
+		"manifests": []map[string]interface{}{
+			{"mediaType": MediaTypeOCIManifest, "digest": "sha256:" + strings.Repeat("0", 64), "size": 1, "platform": map[string]string{"os": "linux", "architecture": "s390x"}},
+			{"mediaType": MediaTypeOCIManifest, "digest": digestOf(m), "size": len(m), "platform": map[string]string{"os": "linux", "architecture": "arm64", "variant": "v8"}},
+		},
+	})
+	r.tag = "1.0"
+	r.manifests[r.tag] = idx
+	r.types[r.tag] = MediaTypeOCIIndex
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		if u, p, _ := req.BasicAuth(); u != "user" || p != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"token": "tok", "expires_in": 300})
+	})
+	mux.HandleFunc("/v2/", r.serve)
+	r.Server = httptest.NewTLSServer(mux)
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *testRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Authorization") != "Bearer tok" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+			`Bearer realm="%s/token",service="test",scope="repository:test/app:pull"`, r.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	p := strings.TrimPrefix(req.URL.Path, "/v2/test/app/")
+	switch {
+	case strings.HasPrefix(p, "manifests/"):
+		ref := strings.TrimPrefix(p, "manifests/")
+		b, ok := r.manifests[ref]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", r.types[ref])
+		w.Write(b)
+	case strings.HasPrefix(p, "blobs/"):
+		d := strings.TrimPrefix(p, "blobs/")
+		b, ok := r.blobs[d]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if rg := req.Header.Get("Range"); rg != "" {
+			atomic.AddInt32(&r.ranges, 1)
+			off, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rg, "bytes="), "-"))
+			if err != nil || off > len(b) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(b)-off))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(b[off:])
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		if !r.cut[d] {
+			// Write half the blob and drop the connection.
+			r.cut[d] = true
+			w.Write(b[:len(b)/2])
+			return
+		}
+		w.Write(b)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	reg := newTestRegistry(t)
+	host := reg.Listener.Addr().String()
+	kc, err := registryauth.ParseDockerConfig(strings.NewReader(
+		fmt.Sprintf(`{"auths":{"https://%s":{"auth":"dXNlcjpwYXNz"}}}`, host)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(reg.Client(), &registryauth.Config{Keychain: kc})
+
+	ref, err := ParseReference(host + "/test/app:" + reg.tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := claircore.Platform{OS: "linux", Architecture: "arm64"}
+	m, err := c.Manifest(ctx, ref, &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Platform.String(), "linux/arm64/v8"; got != want {
+		t.Errorf("platform: got: %q, want: %q", got, want)
+	}
+	if got, want := m.Labels["org.opencontainers.image.version"], "1.0"; got != want {
+		t.Errorf("label: got: %q, want: %q", got, want)
+	}
+	if got, want := len(m.History), 2; got != want {
+		t.Errorf("history: got: %d entries, want: %d", got, want)
+	}
+	if got, want := len(m.Layers), 1; got != want {
+		t.Fatalf("got: %d layers, want: %d", got, want)
+	}
+	l := m.Layers[0]
+	if _, ok := reg.manifests[m.Hash.String()]; !ok {
+		t.Errorf("unexpected manifest digest: %v", m.Hash)
+	}
+
+	b, err := c.Fetch(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	got, err := io.ReadAll(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, reg.blobs[l.Hash.String()]) {
+		t.Error("layer contents differ")
+	}
+	// The config and the layer were each resumed once.
+	if got, want := atomic.LoadInt32(&reg.ranges), int32(2); got != want {
+		t.Errorf("got: %d range requests, want: %d", got, want)
+	}
+
+	if _, err := c.Manifest(ctx, ref, &claircore.Platform{OS: "windows", Architecture: "amd64"}); err == nil {
+		t.Error("expected missing platform error")
+	}
+}
+
+func TestFetchVerify(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, "not the layer")
+	}))
+	defer srv.Close()
+	d := claircore.MustParseDigest(digestOf([]byte("the layer")))
+	var c Client
+	b, err := c.Fetch(ctx, &claircore.Layer{Hash: d, URI: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if _, err := io.ReadAll(b); err == nil || !strings.Contains(err.Error(), "validation failed") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
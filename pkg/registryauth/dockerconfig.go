@@ -0,0 +1,186 @@
+package registryauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DockerConfig is a Keychain using the credentials in a docker client
+// configuration file, as written by "docker login".
+//
+// Credentials stored in the file itself are used, as are credential helpers
+// configured with "credsStore" or "credHelpers". Identity tokens, used for
+// OAuth2 logins, aren't supported.
+type DockerConfig struct {
+	auths   map[string]Credential
+	store   string
+	helpers map[string]string
+	// Run invokes a credential helper, returning its output.
+	run func(ctx context.Context, helper, server string) ([]byte, error)
+}
+
+var _ Keychain = (*DockerConfig)(nil)
+
+// LoadDockerConfig reads the docker client configuration at the provided
+// path.
+//
+// If the path is empty, the file the docker client uses is read: "config.json"
+// in the directory named by the DOCKER_CONFIG environment variable, or in
+// "$HOME/.docker". A missing file in the default location results in a
+// DockerConfig with no credentials.
+func LoadDockerConfig(path string) (*DockerConfig, error) {
+	def := path == ""
+	if def {
+		dir := os.Getenv("DOCKER_CONFIG")
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("registryauth: unable to find docker config: %w", err)
+			}
+			dir = filepath.Join(home, ".docker")
+		}
+		path = filepath.Join(dir, "config.json")
+	}
+	f, err := os.Open(path)
+	switch {
+	case errors.Is(err, nil):
+	case def && errors.Is(err, os.ErrNotExist):
+		return ParseDockerConfig(strings.NewReader("{}"))
+	default:
+		return nil, fmt.Errorf("registryauth: unable to open docker config: %w", err)
+	}
+	defer f.Close()
+	return ParseDockerConfig(f)
+}
+
+// ParseDockerConfig reads a docker client configuration from the Reader.
+func ParseDockerConfig(r io.Reader) (*DockerConfig, error) {
+	var cfg struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+		CredsStore  string            `json:"credsStore"`
+		CredHelpers map[string]string `json:"credHelpers"`
+	}
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("registryauth: unable to decode docker config: %w", err)
+	}
+	dc := DockerConfig{
+		auths:   make(map[string]Credential, len(cfg.Auths)),
+		store:   cfg.CredsStore,
+		helpers: make(map[string]string, len(cfg.CredHelpers)),
+		run:     runHelper,
+	}
+	for k, a := range cfg.Auths {
+		c := Credential{Username: a.Username, Password: a.Password}
+		if a.Auth != "" {
+			b, err := base64.StdEncoding.DecodeString(a.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("registryauth: bad auth for %q: %w", k, err)
+			}
+			i := bytes.IndexByte(b, ':')
+			if i == -1 {
+				return nil, fmt.Errorf("registryauth: bad auth for %q: missing separator", k)
+			}
+			c = Credential{Username: string(b[:i]), Password: string(b[i+1:])}
+		}
+		if c.Username == "" && c.Password == "" {
+			// Entries like this are left behind when the credentials are
+			// kept by a helper.
+			continue
+		}
+		dc.auths[configHost(k)] = c
+	}
+	for k, h := range cfg.CredHelpers {
+		dc.helpers[configHost(k)] = h
+	}
+	return &dc, nil
+}
+
+// Resolve implements Keychain.
+//
+// A credential helper configured for the host takes precedence over the
+// credentials in the file, which take precedence over the default helper.
+func (dc *DockerConfig) Resolve(ctx context.Context, host string) (Credential, bool, error) {
+	k := configHost(host)
+	if h, ok := dc.helpers[k]; ok {
+		return dc.helper(ctx, h, k)
+	}
+	if c, ok := dc.auths[k]; ok {
+		return c, true, nil
+	}
+	if dc.store != "" {
+		return dc.helper(ctx, dc.store, k)
+	}
+	return Credential{}, false, nil
+}
+
+// Helper asks the named credential helper for the host's credentials.
+func (dc *DockerConfig) helper(ctx context.Context, helper, host string) (Credential, bool, error) {
+	server := host
+	if host == dockerHub {
+		// This is the name the docker client stores Docker Hub credentials
+		// under.
+		server = "https://index.docker.io/v1/"
+	}
+	out, err := dc.run(ctx, helper, server)
+	if err != nil {
+		if strings.Contains(string(out), "credentials not found") {
+			return Credential{}, false, nil
+		}
+		return Credential{}, false, fmt.Errorf("credential helper %q: %w", helper, err)
+	}
+	var res struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return Credential{}, false, fmt.Errorf("credential helper %q: %w", helper, err)
+	}
+	return Credential{Username: res.Username, Password: res.Secret}, true, nil
+}
+
+// RunHelper runs "docker-credential-<helper> get", as the docker client does.
+// On failure, the helper's output is returned along with the error, as
+// helpers report missing credentials there.
+func runHelper(ctx context.Context, helper, server string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.Bytes(), err
+}
+
+// DockerHub is the key Docker Hub credentials are normalized to.
+const dockerHub = "docker.io"
+
+// ConfigHost normalizes a key in a docker config, which may be a URL, or a
+// registry host, to a lowercase host. The names Docker Hub is known by are
+// all normalized to the same host.
+func configHost(k string) string {
+	k = strings.ToLower(k)
+	if i := strings.Index(k, "://"); i != -1 {
+		k = k[i+3:]
+	}
+	if i := strings.IndexByte(k, '/'); i != -1 {
+		k = k[:i]
+	}
+	switch k {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		k = dockerHub
+	}
+	return k
+}
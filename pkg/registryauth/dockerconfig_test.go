@@ -0,0 +1,63 @@
+package registryauth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDockerConfig(t *testing.T) {
+	ctx := context.Background()
+	const cfg = `{
+	"auths": {
+		"https://index.docker.io/v1/": {"auth": "aHViOnNlY3JldA=="},
+		"quay.io": {"username": "quay", "password": "pw"},
+		"gcr.io": {}
+	},
+	"credsStore": "desktop",
+	"credHelpers": {"gcr.io": "gcloud"}
+}`
+	dc, err := ParseDockerConfig(strings.NewReader(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	dc.run = func(_ context.Context, helper, server string) ([]byte, error) {
+		calls = append(calls, helper+" "+server)
+		switch helper {
+		case "gcloud":
+			return []byte(`{"ServerURL":"gcr.io","Username":"_token","Secret":"gtok"}`), nil
+		default:
+			return []byte("credentials not found in native keychain\n"), errors.New("exit status 1")
+		}
+	}
+
+	tt := []struct {
+		Host  string
+		Want  Credential
+		Found bool
+	}{
+		{"registry-1.docker.io", Credential{Username: "hub", Password: "secret"}, true},
+		{"quay.io", Credential{Username: "quay", Password: "pw"}, true},
+		{"gcr.io", Credential{Username: "_token", Password: "gtok"}, true},
+		{"registry.example.com:5000", Credential{}, false},
+	}
+	for _, tc := range tt {
+		got, ok, err := dc.Resolve(ctx, tc.Host)
+		if err != nil {
+			t.Errorf("%s: %v", tc.Host, err)
+			continue
+		}
+		if ok != tc.Found || got != tc.Want {
+			t.Errorf("%s: got: %+v (%v), want: %+v (%v)", tc.Host, got, ok, tc.Want, tc.Found)
+		}
+	}
+	if got, want := strings.Join(calls, ","), "gcloud gcr.io,desktop registry.example.com:5000"; got != want {
+		t.Errorf("helper calls: got: %q, want: %q", got, want)
+	}
+
+	if _, err := ParseDockerConfig(strings.NewReader(`{"auths":{"x":{"auth":"bm9jb2xvbg=="}}}`)); err == nil {
+		t.Error("expected bad auth error")
+	}
+}
@@ -0,0 +1,459 @@
+// Package registryauth provides an http.RoundTripper that performs the token
+// authentication flow used by container registries.
+//
+// Public registries such as Docker Hub and Amazon ECR Public require a bearer
+// token even for anonymous pulls. Without one, every layer fetch is answered
+// with a 401 and a "WWW-Authenticate" challenge. The Transport answers these
+// challenges, caches the resulting tokens for as long as they're valid, and
+// attaches them to subsequent requests to the same registry so that the token
+// endpoint is only consulted once per repository (or once per registry, for
+// registries that issue registry-wide tokens).
+//
+// Like the retry and headers packages, it's applied by way of the
+// *http.Client handed to libindex.
+package registryauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultTokenLifetime is how long a token is assumed to be valid when the
+// token endpoint doesn't say. This is the minimum the distribution token
+// specification allows.
+const DefaultTokenLifetime = 60 * time.Second
+
+// Expiry slop: tokens are considered expired this long before they actually
+// are, so that a token doesn't expire while a request is in flight.
+const expirySlop = 5 * time.Second
+
+// Config describes credentials to use when requesting tokens.
+type Config struct {
+	// Credentials holds credentials for the keyed registry hosts. Requests to
+	// hosts without an entry consult the Keychain, if any, and otherwise
+	// request anonymous tokens.
+	//
+	// Credentials are only ever sent to the token endpoint named in a
+	// registry's challenge, never to the registry itself.
+	Credentials map[string]Credential
+	// Keychain, if set, is asked for credentials for hosts not in
+	// Credentials. See DockerConfig for a Keychain using the docker client's
+	// configuration.
+	Keychain Keychain
+}
+
+// Keychain looks up credentials for registry hosts.
+type Keychain interface {
+	// Resolve returns the credential for the host, reporting whether there
+	// is one. The host is lowercase, and includes the port if the registry
+	// is addressed with one.
+	Resolve(ctx context.Context, host string) (Credential, bool, error)
+}
+
+// Credential is a username and password presented to a token endpoint using
+// HTTP basic authentication.
+type Credential struct {
+	Username string
+	Password string
+}
+
+// Transport is an http.RoundTripper that answers bearer token challenges.
+//
+// Requests that already carry an "Authorization" header are passed through
+// untouched.
+type Transport struct {
+	creds map[string]Credential
+	kc    Keychain
+	next  http.RoundTripper
+	sf    singleflight.Group
+
+	mu     sync.Mutex
+	hosts  map[string]*challenge
+	tokens map[challenge]*token
+	// Remaining records the last reported rate limit for each host.
+	remaining map[string]int
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// Challenge is a parsed "Bearer" challenge.
+//
+// A challenge stored for a host has its Scope cleared if the scope is
+// repository specific.
+type challenge struct {
+	Realm   string
+	Service string
+	Scope   string
+}
+
+// Token is a cached bearer token.
+type token struct {
+	Value   string
+	Expires time.Time
+}
+
+// NewTransport returns a Transport using the Config and making requests with
+// "next". If "next" is nil, http.DefaultTransport is used.
+func NewTransport(cfg *Config, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := Transport{
+		next:      next,
+		creds:     make(map[string]Credential),
+		hosts:     make(map[string]*challenge),
+		tokens:    make(map[challenge]*token),
+		remaining: make(map[string]int),
+	}
+	if cfg != nil {
+		for h, c := range cfg.Credentials {
+			t.creds[strings.ToLower(h)] = c
+		}
+		t.kc = cfg.Keychain
+	}
+	return &t
+}
+
+// Client returns a copy of the provided client, with its Transport wrapped to
+// answer token challenges.
+//
+// If the client is nil, a new client is returned.
+func Client(c *http.Client, cfg *Config) *http.Client {
+	var out http.Client
+	if c != nil {
+		out = *c
+	}
+	out.Transport = NewTransport(cfg, out.Transport)
+	return &out
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("authorization") != "" {
+		return t.next.RoundTrip(req)
+	}
+	ctx := baggage.ContextWithValues(req.Context(),
+		label.String("component", "pkg/registryauth/Transport.RoundTrip"),
+		label.String("host", req.URL.Host))
+	host := strings.ToLower(req.URL.Host)
+
+	r := req
+	if tok := t.cached(host, req.URL.Path); tok != "" {
+		r = withToken(req, tok)
+	}
+	res, err := t.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	t.observe(ctx, host, res)
+	if res.StatusCode != http.StatusUnauthorized {
+		return res, nil
+	}
+	c, ok := parseChallenge(res.Header.Values("www-authenticate"))
+	if !ok {
+		return res, nil
+	}
+	// Only requests that can be replayed can be answered.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return res, nil
+	}
+	tok, err := t.token(ctx, host, c)
+	if err != nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Str("realm", c.Realm).
+			Msg("unable to fetch registry token")
+		return res, nil
+	}
+	// Drain a bit of the body so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	res.Body.Close()
+
+	r = withToken(req, tok)
+	if req.GetBody != nil {
+		if r.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	res, err = t.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	t.observe(ctx, host, res)
+	return res, nil
+}
+
+// WithToken returns a copy of the request with the bearer token attached.
+func withToken(req *http.Request, tok string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+tok)
+	return r
+}
+
+// Cached returns a valid cached token for a request to the host and path, or
+// an empty string.
+func (t *Transport) cached(host, p string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.hosts[host]
+	if !ok {
+		return ""
+	}
+	k := *c
+	if k.Scope == "" {
+		k.Scope = repositoryScope(p)
+		if k.Scope == "" {
+			return ""
+		}
+	}
+	tok, ok := t.tokens[k]
+	if !ok || time.Now().After(tok.Expires) {
+		return ""
+	}
+	return tok.Value
+}
+
+// Token returns a token answering the challenge, fetching one if needed.
+//
+// The challenge is remembered for the host, so later requests can use the
+// token without being challenged.
+func (t *Transport) token(ctx context.Context, host string, c challenge) (string, error) {
+	hc := c
+	if strings.HasPrefix(c.Scope, "repository:") {
+		hc.Scope = ""
+	}
+	t.mu.Lock()
+	t.hosts[host] = &hc
+	tok, ok := t.tokens[c]
+	t.mu.Unlock()
+	if ok && time.Now().Before(tok.Expires) {
+		// Another request fetched a token between this request being sent and
+		// being challenged.
+		return tok.Value, nil
+	}
+
+	key := c.Realm + "\x00" + c.Service + "\x00" + c.Scope
+	v, err, _ := t.sf.Do(key, func() (interface{}, error) {
+		tok, err := t.fetch(ctx, host, c)
+		if err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.tokens[c] = tok
+		t.mu.Unlock()
+		return tok.Value, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// Fetch requests a token from the challenge's realm.
+func (t *Transport) fetch(ctx context.Context, host string, c challenge) (*token, error) {
+	u, err := url.Parse(c.Realm)
+	if err != nil {
+		return nil, fmt.Errorf("registryauth: bad realm %q: %w", c.Realm, err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("registryauth: bad realm %q: unsupported scheme", c.Realm)
+	}
+	v := u.Query()
+	if c.Service != "" {
+		v.Set("service", c.Service)
+	}
+	if c.Scope != "" {
+		v.Set("scope", c.Scope)
+	}
+	u.RawQuery = v.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	cred, ok := t.creds[host]
+	if !ok && t.kc != nil {
+		cred, ok, err = t.kc.Resolve(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("registryauth: unable to look up credentials: %w", err)
+		}
+	}
+	if ok {
+		req.SetBasicAuth(cred.Username, cred.Password)
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, fmt.Errorf("registryauth: token request failed: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registryauth: unexpected status code from token endpoint: %s", res.Status)
+	}
+	var body struct {
+		Token       string    `json:"token"`
+		AccessToken string    `json:"access_token"`
+		ExpiresIn   int       `json:"expires_in"`
+		IssuedAt    time.Time `json:"issued_at"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("registryauth: unable to decode token response: %w", err)
+	}
+	tok := token{Value: body.Token}
+	if tok.Value == "" {
+		tok.Value = body.AccessToken
+	}
+	if tok.Value == "" {
+		return nil, errors.New("registryauth: token endpoint returned no token")
+	}
+	life := DefaultTokenLifetime
+	if body.ExpiresIn > 0 {
+		life = time.Duration(body.ExpiresIn) * time.Second
+	}
+	// Issued_at is deliberately ignored: clock skew between us and the token
+	// endpoint would make it misleading.
+	tok.Expires = time.Now().Add(life - expirySlop)
+	zlog.Debug(ctx).
+		Str("realm", c.Realm).
+		Str("scope", c.Scope).
+		Dur("lifetime", life).
+		Msg("fetched registry token")
+	return &tok, nil
+}
+
+// Observe inspects the response for rate limit information.
+//
+// Docker Hub reports the remaining requests in the current window in the
+// "RateLimit-Remaining" header, as "<count>;w=<window seconds>". Running out
+// is logged once, so that a 429 further down the line has some context.
+func (t *Transport) observe(ctx context.Context, host string, res *http.Response) {
+	v := res.Header.Get("ratelimit-remaining")
+	if v == "" {
+		return
+	}
+	if i := strings.IndexByte(v, ';'); i != -1 {
+		v = v[:i]
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	prev, seen := t.remaining[host]
+	t.remaining[host] = n
+	t.mu.Unlock()
+	switch {
+	case n == 0 && (!seen || prev != 0):
+		zlog.Warn(ctx).
+			Str("limit", res.Header.Get("ratelimit-limit")).
+			Msg("registry rate limit exhausted")
+	default:
+		zlog.Debug(ctx).
+			Int("remaining", n).
+			Msg("registry rate limit")
+	}
+}
+
+// Remaining reports the most recent number of remaining requests reported by
+// the host, and whether the host has reported one at all.
+func (t *Transport) Remaining(host string) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, ok := t.remaining[strings.ToLower(host)]
+	return n, ok
+}
+
+// RepositoryScope returns the pull scope for the repository named in a
+// registry API path, or an empty string if the path doesn't name one.
+func repositoryScope(p string) string {
+	const prefix = "/v2/"
+	if !strings.HasPrefix(p, prefix) {
+		return ""
+	}
+	p = p[len(prefix):]
+	for _, sep := range []string{"/blobs/", "/manifests/", "/tags/"} {
+		if i := strings.LastIndex(p, sep); i > 0 {
+			return "repository:" + p[:i] + ":pull"
+		}
+	}
+	return ""
+}
+
+// ParseChallenge finds and parses a "Bearer" challenge in the provided
+// "WWW-Authenticate" header values.
+func parseChallenge(vs []string) (challenge, bool) {
+	for _, v := range vs {
+		v = strings.TrimSpace(v)
+		i := strings.IndexByte(v, ' ')
+		if i == -1 || !strings.EqualFold(v[:i], "bearer") {
+			continue
+		}
+		ps := parseParams(v[i+1:])
+		c := challenge{
+			Realm:   ps["realm"],
+			Service: ps["service"],
+			Scope:   ps["scope"],
+		}
+		if c.Realm == "" {
+			continue
+		}
+		return c, true
+	}
+	return challenge{}, false
+}
+
+// ParseParams parses a comma-separated list of auth-params, as found in a
+// challenge. Keys are lower-cased.
+func parseParams(s string) map[string]string {
+	out := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		i := strings.IndexByte(s, '=')
+		if i == -1 {
+			return out
+		}
+		k := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimLeft(s[i+1:], " \t")
+		var v string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			j := 1
+			for ; j < len(s); j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+					b.WriteByte(s[j])
+					continue
+				}
+				if s[j] == '"' {
+					break
+				}
+				b.WriteByte(s[j])
+			}
+			v = b.String()
+			if j < len(s) {
+				j++
+			}
+			s = s[j:]
+		} else {
+			j := strings.IndexByte(s, ',')
+			if j == -1 {
+				j = len(s)
+			}
+			v = strings.TrimSpace(s[:j])
+			s = s[j:]
+		}
+		out[k] = v
+	}
+}
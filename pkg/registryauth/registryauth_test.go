@@ -0,0 +1,128 @@
+package registryauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/quay/zlog"
+)
+
+func TestTransport(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	var tokens, challenges int32
+	var gotScope, gotUser string
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokens, 1)
+		gotScope = r.URL.Query().Get("scope")
+		gotUser, _, _ = r.BasicAuth()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      "tok",
+			"expires_in": 300,
+		})
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			atomic.AddInt32(&challenges, 1)
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="%s/token",service="registry.test",scope="repository:library/ubuntu:pull"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("RateLimit-Limit", "100;w=21600")
+		w.Header().Set("RateLimit-Remaining", "99;w=21600")
+		w.WriteHeader(http.StatusOK)
+	})
+	host := srv.Listener.Addr().String()
+	c := Client(srv.Client(), &Config{
+		Credentials: map[string]Credential{host: {Username: "user", Password: "pass"}},
+	})
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v2/library/ubuntu/blobs/sha256:abc", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("got: %d, want: %d", got, want)
+		}
+		if req.Header.Get("Authorization") != "" {
+			t.Error("request modified")
+		}
+	}
+	if got, want := atomic.LoadInt32(&tokens), int32(1); got != want {
+		t.Errorf("token requests: got: %d, want: %d", got, want)
+	}
+	if got, want := atomic.LoadInt32(&challenges), int32(1); got != want {
+		t.Errorf("challenges: got: %d, want: %d", got, want)
+	}
+	if got, want := gotScope, "repository:library/ubuntu:pull"; got != want {
+		t.Errorf("scope: got: %q, want: %q", got, want)
+	}
+	if got, want := gotUser, "user"; got != want {
+		t.Errorf("username: got: %q, want: %q", got, want)
+	}
+	n, ok := c.Transport.(*Transport).Remaining(host)
+	if !ok || n != 99 {
+		t.Errorf("remaining: got: %d (%v), want: 99", n, ok)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	tt := []struct {
+		In   string
+		Want challenge
+		OK   bool
+	}{
+		{
+			In: `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/ubuntu:pull"`,
+			Want: challenge{
+				Realm:   "https://auth.docker.io/token",
+				Service: "registry.docker.io",
+				Scope:   "repository:library/ubuntu:pull",
+			},
+			OK: true,
+		},
+		{
+			In: `Bearer realm="https://public.ecr.aws/token/",service="public.ecr.aws",scope="aws"`,
+			Want: challenge{
+				Realm:   "https://public.ecr.aws/token/",
+				Service: "public.ecr.aws",
+				Scope:   "aws",
+			},
+			OK: true,
+		},
+		{In: `Basic realm="registry"`},
+	}
+	for _, tc := range tt {
+		got, ok := parseChallenge([]string{tc.In})
+		if ok != tc.OK || got != tc.Want {
+			t.Errorf("%s: got: %+v (%v), want: %+v (%v)", tc.In, got, ok, tc.Want, tc.OK)
+		}
+	}
+}
+
+func TestRepositoryScope(t *testing.T) {
+	for in, want := range map[string]string{
+		"/v2/library/ubuntu/blobs/sha256:abc": "repository:library/ubuntu:pull",
+		"/v2/a/b/c/manifests/latest":          "repository:a/b/c:pull",
+		"/v2/":                                "",
+		"/token":                              "",
+	} {
+		if got := repositoryScope(in); got != want {
+			t.Errorf("%s: got: %q, want: %q", in, got, want)
+		}
+	}
+}
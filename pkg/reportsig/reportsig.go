@@ -0,0 +1,193 @@
+// Package reportsig produces and checks detached signatures over IndexReports
+// and VulnerabilityReports.
+//
+// Reports are signed over their canonical JSON encoding (see Canonical), and
+// signatures are made the same way "cosign sign-blob" makes them: a SHA-256
+// digest of the payload signed with the key, base64 encoded. This means a
+// consumer holding the payload, the signature, and the public key can check
+// a report with "cosign verify-blob" as well as with Verify.
+package reportsig
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ErrNoSigner is returned when a report is asked to be signed but no Signer is
+// configured.
+var ErrNoSigner = errors.New("reportsig: no signer configured")
+
+// ErrInvalid is returned by Verify when a signature doesn't match the
+// payload.
+var ErrInvalid = errors.New("reportsig: invalid signature")
+
+// Signer produces a raw signature over a payload.
+//
+// Implementations backed by a KMS or HSM can be provided in place of the key
+// signer returned by NewSigner.
+type Signer interface {
+	Sign(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+// Detached is a report's payload and its signature.
+type Detached struct {
+	// Payload is the canonical encoding of the report. Consumers should
+	// verify these exact bytes, not a re-encoding of the report.
+	Payload []byte
+	// Signature is the raw signature.
+	Signature []byte
+}
+
+// Encoded returns the signature in the base64 form "cosign sign-blob" writes
+// and "cosign verify-blob" reads.
+func (d *Detached) Encoded() string {
+	return base64.StdEncoding.EncodeToString(d.Signature)
+}
+
+// Canonical returns the canonical JSON encoding of a report.
+//
+// This is the compact encoding produced by encoding/json, which writes struct
+// fields in declaration order and map keys in sorted order, without escaping
+// HTML characters and without a trailing newline.
+func Canonical(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("reportsig: unable to encode report: %w", err)
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// Sign encodes the report "v" canonically and signs it with "s".
+//
+// If "s" is nil, ErrNoSigner is returned.
+func Sign(ctx context.Context, s Signer, v interface{}) (*Detached, error) {
+	if s == nil {
+		return nil, ErrNoSigner
+	}
+	p, err := Canonical(v)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.Sign(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("reportsig: unable to sign report: %w", err)
+	}
+	return &Detached{Payload: p, Signature: sig}, nil
+}
+
+// NewSigner returns a Signer using the provided key.
+//
+// ECDSA, RSA (PKCS #1 v1.5), and Ed25519 keys are supported, which are the key
+// types cosign supports.
+func NewSigner(k crypto.Signer) (Signer, error) {
+	switch k.Public().(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("reportsig: unsupported key type %T", k.Public())
+	}
+	return &keySigner{k: k}, nil
+}
+
+// KeySigner is a Signer backed by a crypto.Signer.
+type keySigner struct {
+	k crypto.Signer
+}
+
+// Sign implements Signer.
+func (s *keySigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// Ed25519 signs the message itself, not a digest of it.
+	if _, ok := s.k.Public().(ed25519.PublicKey); ok {
+		return s.k.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+	d := sha256.Sum256(payload)
+	return s.k.Sign(rand.Reader, d[:], crypto.SHA256)
+}
+
+// Verify checks that "sig" is a valid signature over "payload" by the holder
+// of the private half of "pub". The signature may be raw or base64 encoded.
+//
+// ErrInvalid is returned if the signature doesn't match.
+func Verify(pub crypto.PublicKey, payload, sig []byte) error {
+	if b, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig))); err == nil {
+		sig = b
+	}
+	d := sha256.Sum256(payload)
+	var ok bool
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(k, d[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, d[:], sig) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, payload, sig)
+	default:
+		return fmt.Errorf("reportsig: unsupported key type %T", pub)
+	}
+	if !ok {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// ParsePrivateKey parses an unencrypted PEM-encoded private key in PKCS #8,
+// SEC 1 ("EC PRIVATE KEY"), or PKCS #1 ("RSA PRIVATE KEY") form.
+//
+// Cosign's own encrypted key format isn't supported; export the key with
+// "openssl" or use a Signer backed by a KMS instead.
+func ParsePrivateKey(b []byte) (crypto.Signer, error) {
+	blk, _ := pem.Decode(b)
+	if blk == nil {
+		return nil, errors.New("reportsig: no PEM block found")
+	}
+	var k interface{}
+	var err error
+	switch blk.Type {
+	case "PRIVATE KEY":
+		k, err = x509.ParsePKCS8PrivateKey(blk.Bytes)
+	case "EC PRIVATE KEY":
+		k, err = x509.ParseECPrivateKey(blk.Bytes)
+	case "RSA PRIVATE KEY":
+		k, err = x509.ParsePKCS1PrivateKey(blk.Bytes)
+	default:
+		return nil, fmt.Errorf("reportsig: unsupported PEM block %q", blk.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reportsig: unable to parse key: %w", err)
+	}
+	s, ok := k.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("reportsig: unsupported key type %T", k)
+	}
+	return s, nil
+}
+
+// ParsePublicKey parses a PEM-encoded PKIX public key, such as the
+// "cosign.pub" file written by "cosign generate-key-pair".
+func ParsePublicKey(b []byte) (crypto.PublicKey, error) {
+	blk, _ := pem.Decode(b)
+	if blk == nil {
+		return nil, errors.New("reportsig: no PEM block found")
+	}
+	k, err := x509.ParsePKIXPublicKey(blk.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("reportsig: unable to parse key: %w", err)
+	}
+	return k, nil
+}
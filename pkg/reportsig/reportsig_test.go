@@ -0,0 +1,133 @@
+package reportsig
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+func report() *claircore.IndexReport {
+	return &claircore.IndexReport{
+		Hash:  claircore.MustParseDigest("sha256:1111111111111111111111111111111111111111111111111111111111111111"),
+		State: "IndexFinished",
+		Packages: map[string]*claircore.Package{
+			"2": {ID: "2", Name: "b", Version: "2.0"},
+			"1": {ID: "1", Name: "a<b>", Version: "1.0"},
+		},
+		Success: true,
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	a, err := Canonical(report())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Canonical(report())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Errorf("encodings differ:\n%s\n%s", a, b)
+	}
+	if bytes.HasSuffix(a, []byte("\n")) {
+		t.Error("trailing newline")
+	}
+	if !bytes.Contains(a, []byte(`"a<b>"`)) {
+		t.Errorf("HTML characters escaped: %s", a)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	ctx := context.Background()
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, k := range map[string]crypto.Signer{
+		"ECDSA":   ec,
+		"RSA":     rk,
+		"Ed25519": ed,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewSigner(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := Sign(ctx, s, report())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Verify(k.Public(), d.Payload, d.Signature); err != nil {
+				t.Errorf("raw signature: %v", err)
+			}
+			if err := Verify(k.Public(), d.Payload, []byte(d.Encoded()+"\n")); err != nil {
+				t.Errorf("encoded signature: %v", err)
+			}
+			tampered := bytes.Replace(d.Payload, []byte(`"2.0"`), []byte(`"2.1"`), 1)
+			if err := Verify(k.Public(), tampered, d.Signature); !errors.Is(err, ErrInvalid) {
+				t.Errorf("tampered payload: got: %v, want: %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestNoSigner(t *testing.T) {
+	if _, err := Sign(context.Background(), nil, report()); !errors.Is(err, ErrNoSigner) {
+		t.Errorf("got: %v, want: %v", err, ErrNoSigner)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = x509.MarshalPKIXPublicKey(k.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSigner(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Sign(context.Background(), s, report())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(pub, d.Payload, d.Signature); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,287 @@
+// Package retry provides a shared retry and backoff policy for outbound HTTP
+// requests.
+//
+// The policy is applied as an http.RoundTripper, so that both layer fetching
+// and updater feed fetching can use it by way of the *http.Client handed to
+// them.
+//
+// Nothing is retried unless a Policy is configured: see the RetryPolicy and
+// FetchRetry fields of libindex.Opts, and the RetryPolicy field of
+// libvuln.Opts. Updaters don't retry requests themselves. Those that try a
+// list of mirrors, like the aws updater, try each mirror under the Policy
+// before moving on to the next.
+package retry
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults used when the corresponding Policy field is unset.
+const (
+	DefaultMaxAttempts     = 4
+	DefaultBaseDelay       = 500 * time.Millisecond
+	DefaultMaxDelay        = 30 * time.Second
+	DefaultBreakerCooldown = time.Minute
+)
+
+// ErrCircuitOpen is returned when a request is not attempted because the
+// circuit breaker for the request's host is open.
+var ErrCircuitOpen = errors.New("retry: circuit open for host")
+
+// Policy describes how requests are retried.
+//
+// The zero value is usable and retries up to DefaultMaxAttempts times with
+// exponential backoff and no circuit breaking.
+type Policy struct {
+	// MaxAttempts is the maximum number of times a request is tried,
+	// including the first attempt.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. Every subsequent retry
+	// doubles the previous delay.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts, including any delay requested
+	// by a server via the "Retry-After" header.
+	MaxDelay time.Duration
+	// NoJitter disables randomizing delays. By default, delays are chosen
+	// uniformly from [delay/2, delay).
+	NoJitter bool
+	// BreakerThreshold is the number of consecutive failed requests to a host
+	// before requests to that host are short-circuited. Zero disables circuit
+	// breaking.
+	BreakerThreshold int
+	// BreakerCooldown is how long a circuit stays open before a single
+	// request is let through to probe the host.
+	BreakerCooldown time.Duration
+}
+
+// Attempts reports the maximum number of times something is tried, including
+// the first attempt.
+func (p *Policy) Attempts() int {
+	if p.MaxAttempts < 1 {
+		return DefaultMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p *Policy) maxDelay() time.Duration {
+	if p.MaxDelay <= 0 {
+		return DefaultMaxDelay
+	}
+	return p.MaxDelay
+}
+
+func (p *Policy) cooldown() time.Duration {
+	if p.BreakerCooldown <= 0 {
+		return DefaultBreakerCooldown
+	}
+	return p.BreakerCooldown
+}
+
+// Backoff reports how long to wait before the retry numbered "n", starting at
+// 1.
+//
+// Attempts and Backoff allow the Policy to be applied to operations other
+// than single HTTP requests.
+func (p *Policy) Backoff(n int) time.Duration {
+	d := p.BaseDelay
+	if d <= 0 {
+		d = DefaultBaseDelay
+	}
+	max := p.maxDelay()
+	for i := 1; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	if !p.NoJitter && d > 1 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)))
+	}
+	return d
+}
+
+// Retryable reports whether the response status indicates a transient error.
+func retryable(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// RetryAfter parses a "Retry-After" header value, returning false if it's not
+// present or malformed.
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	v := h.Get("retry-after")
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// Transport is an http.RoundTripper that retries requests according to a
+// Policy.
+//
+// Only requests that can be safely replayed are retried: requests with no
+// body, or with a GetBody function.
+type Transport struct {
+	policy Policy
+	next   http.RoundTripper
+
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+// Breaker is the circuit breaker state for a single host.
+type breaker struct {
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewTransport returns a Transport applying the Policy to requests made using
+// "next". If "next" is nil, http.DefaultTransport is used.
+func NewTransport(p *Policy, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &Transport{
+		next:  next,
+		hosts: make(map[string]*breaker),
+	}
+	if p != nil {
+		t.policy = *p
+	}
+	return t
+}
+
+// Client returns a copy of the provided client, with its Transport wrapped to
+// apply the Policy.
+//
+// If the client is nil, a new client is returned.
+func Client(c *http.Client, p *Policy) *http.Client {
+	var out http.Client
+	if c != nil {
+		out = *c
+	}
+	out.Transport = NewTransport(p, out.Transport)
+	return &out
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Host
+	replay := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	max := t.policy.Attempts()
+	if !replay {
+		max = 1
+	}
+
+	var res *http.Response
+	var err error
+	for n := 1; ; n++ {
+		if !t.allow(host) {
+			return nil, ErrCircuitOpen
+		}
+		r := req
+		if n > 1 && req.GetBody != nil {
+			r = req.Clone(ctx)
+			if r.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		res, err = t.next.RoundTrip(r)
+		failed := err != nil || retryable(res.StatusCode)
+		t.record(host, !failed)
+		if !failed || n >= max {
+			return res, err
+		}
+
+		wait := t.policy.Backoff(n)
+		if res != nil {
+			if d, ok := retryAfter(res.Header, time.Now()); ok {
+				wait = d
+				if m := t.policy.maxDelay(); wait > m {
+					wait = m
+				}
+			}
+			// Drain a bit of the body so the connection can be reused.
+			io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+			res.Body.Close()
+		}
+		tm := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			tm.Stop()
+			return nil, ctx.Err()
+		case <-tm.C:
+		}
+	}
+}
+
+// Allow reports whether a request to the host should be attempted.
+func (t *Transport) allow(host string) bool {
+	if t.policy.BreakerThreshold <= 0 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.hosts[host]
+	if !ok || b.failures < t.policy.BreakerThreshold {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < t.policy.cooldown() {
+		return false
+	}
+	// Half-open: let exactly one request through.
+	b.probing = true
+	return true
+}
+
+// Record updates the circuit breaker state for the host.
+func (t *Transport) record(host string, ok bool) {
+	if t.policy.BreakerThreshold <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, exists := t.hosts[host]
+	if !exists {
+		b = &breaker{}
+		t.hosts[host] = b
+	}
+	b.probing = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= t.policy.BreakerThreshold {
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,120 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("retry-after", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	c := Client(srv.Client(), &Policy{
+		BaseDelay: time.Millisecond,
+	})
+	res, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	if got, want := atomic.LoadInt32(&calls), int32(3); got != want {
+		t.Errorf("got: %d calls, want: %d", got, want)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c := Client(srv.Client(), &Policy{
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+	})
+	res, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, http.StatusBadGateway; got != want {
+		t.Errorf("got: %d, want: %d", got, want)
+	}
+	if got, want := atomic.LoadInt32(&calls), int32(2); got != want {
+		t.Errorf("got: %d calls, want: %d", got, want)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := Client(srv.Client(), &Policy{
+		MaxAttempts:      1,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+	})
+	for i := 0; i < 2; i++ {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	_, err := c.Get(srv.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("got: %v, want: %v", err, ErrCircuitOpen)
+	}
+	if got, want := atomic.LoadInt32(&calls), int32(2); got != want {
+		t.Errorf("got: %d calls, want: %d", got, want)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tt := []struct {
+		In   string
+		Want time.Duration
+		OK   bool
+	}{
+		{In: "", OK: false},
+		{In: "10", Want: 10 * time.Second, OK: true},
+		{In: "-1", OK: false},
+		{In: now.Add(time.Minute).Format(http.TimeFormat), Want: time.Minute, OK: true},
+		{In: "garbage", OK: false},
+	}
+	for _, tc := range tt {
+		h := http.Header{}
+		if tc.In != "" {
+			h.Set("retry-after", tc.In)
+		}
+		got, ok := retryAfter(h, now)
+		if ok != tc.OK || got != tc.Want {
+			t.Errorf("%q: got: (%v, %v), want: (%v, %v)", tc.In, got, ok, tc.Want, tc.OK)
+		}
+	}
+}
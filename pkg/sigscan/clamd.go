@@ -0,0 +1,119 @@
+package sigscan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// Clamd is an Engine that streams files to a ClamAV daemon with the INSTREAM
+// command, so that its signature database is used for matching.
+//
+// A connection is made per file. The daemon's StreamMaxLength should be at
+// least the Scanner's MaxSize, or large files will fail to be scanned.
+type Clamd struct {
+	// Network and Address are passed to net.Dialer.DialContext, for example
+	// "tcp" and "localhost:3310", or "unix" and "/run/clamd.scan/clamd.sock".
+	Network, Address string
+}
+
+var _ Engine = (*Clamd)(nil)
+
+// ClamdChunk is the size of the chunks a file is sent to the daemon in.
+const clamdChunk = 32 * 1024
+
+// Name implements Engine.
+func (*Clamd) Name() string { return "clamd" }
+
+// Version implements Engine.
+//
+// The daemon's signature database is updated out from under the Engine, so
+// this only reports the version of the protocol handling.
+func (*Clamd) Version() string { return "1" }
+
+// Match implements Engine.
+func (c *Clamd) Match(ctx context.Context, r io.Reader) ([]string, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: %w", err)
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	// Unblock any reads or writes if the context is canceled.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	w := bufio.NewWriterSize(conn, clamdChunk+4)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return nil, fmt.Errorf("clamd: %w", err)
+	}
+	buf := make([]byte, clamdChunk)
+	var sz [4]byte
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(sz[:], uint32(n))
+			w.Write(sz[:])
+			if _, err := w.Write(buf[:n]); err != nil {
+				return nil, fmt.Errorf("clamd: %w", err)
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	binary.BigEndian.PutUint32(sz[:], 0)
+	w.Write(sz[:])
+	if err := w.Flush(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("clamd: %w", err)
+	}
+
+	res, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && !(errors.Is(err, io.EOF) && len(res) != 0) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("clamd: reading response: %w", err)
+	}
+	return parseClamdResponse(string(bytes.TrimRight(res, "\x00\n")))
+}
+
+// ParseClamdResponse interprets a reply to INSTREAM, which looks like
+// "stream: OK", "stream: Eicar-Signature FOUND", or "<message> ERROR".
+func parseClamdResponse(res string) ([]string, error) {
+	switch {
+	case strings.HasSuffix(res, " OK"):
+		return nil, nil
+	case strings.HasSuffix(res, " FOUND"):
+		sig := strings.TrimSuffix(res, " FOUND")
+		if i := strings.LastIndex(sig, ": "); i != -1 {
+			sig = sig[i+2:]
+		}
+		return []string{sig}, nil
+	case strings.HasSuffix(res, " ERROR"):
+		return nil, fmt.Errorf("clamd: %s", strings.TrimSuffix(res, " ERROR"))
+	}
+	return nil, fmt.Errorf("clamd: unexpected response %q", res)
+}
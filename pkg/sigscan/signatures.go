@@ -0,0 +1,88 @@
+package sigscan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Signatures is an Engine that matches a fixed set of byte strings anywhere in
+// a file. It's meant for small sets of indicators; files are read into memory
+// to be matched, so a Scanner's MaxSize should be kept reasonable.
+type Signatures struct {
+	version string
+	names   []string
+	sigs    [][]byte
+}
+
+var _ Engine = (*Signatures)(nil)
+
+// NewSignatures returns a Signatures engine matching the provided byte
+// strings, keyed by signature name. The version should change whenever the set
+// of signatures does.
+func NewSignatures(version string, sigs map[string][]byte) *Signatures {
+	s := Signatures{version: version}
+	for n := range sigs {
+		s.names = append(s.names, n)
+	}
+	sort.Strings(s.names)
+	for _, n := range s.names {
+		s.sigs = append(s.sigs, sigs[n])
+	}
+	return &s
+}
+
+// ParseSignatures reads signatures, one per line, in the form
+// "Name:HexBytes". Blank lines and lines beginning with "#" are ignored.
+func ParseSignatures(version string, r io.Reader) (*Signatures, error) {
+	sigs := make(map[string][]byte)
+	s := bufio.NewScanner(r)
+	for ln := 1; s.Scan(); ln++ {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || l[0] == '#' {
+			continue
+		}
+		i := strings.LastIndexByte(l, ':')
+		if i < 1 {
+			return nil, fmt.Errorf("sigscan: line %d: missing name", ln)
+		}
+		b, err := hex.DecodeString(l[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("sigscan: line %d: %w", ln, err)
+		}
+		if len(b) == 0 {
+			return nil, fmt.Errorf("sigscan: line %d: empty signature", ln)
+		}
+		sigs[l[:i]] = b
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("sigscan: reading signatures: %w", err)
+	}
+	return NewSignatures(version, sigs), nil
+}
+
+// Name implements Engine.
+func (*Signatures) Name() string { return "signatures" }
+
+// Version implements Engine.
+func (s *Signatures) Version() string { return s.version }
+
+// Match implements Engine.
+func (s *Signatures) Match(_ context.Context, r io.Reader) ([]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for i, sig := range s.sigs {
+		if bytes.Contains(b, sig) {
+			out = append(out, s.names[i])
+		}
+	}
+	return out, nil
+}
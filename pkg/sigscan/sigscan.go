@@ -0,0 +1,183 @@
+// Package sigscan implements a ContentScanner that matches the files in a layer
+// against byte signatures, such as those for malware or indicators of
+// compromise.
+//
+// The matching itself is done by an Engine. This package provides one that
+// matches a fixed set of byte strings (see Signatures) and one that streams
+// files to a ClamAV daemon (see Clamd). Other engines can be plugged in by
+// implementing the interface.
+package sigscan
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"runtime/trace"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/libindex/driver"
+)
+
+// Engine matches the contents of a single file against its signatures.
+type Engine interface {
+	// Name identifies the engine.
+	Name() string
+	// Version identifies the engine's version, including its signatures if
+	// they're versioned.
+	Version() string
+	// Match reads the file's contents from the Reader and returns the names of
+	// all matched signatures. An empty result means nothing matched.
+	Match(context.Context, io.Reader) ([]string, error)
+}
+
+// DefaultMaxSize is the size, in bytes, above which files aren't examined if a
+// Config doesn't say otherwise.
+const DefaultMaxSize = 32 * 1024 * 1024
+
+// Config configures a Scanner.
+type Config struct {
+	// Patterns, if set, limits scanning to the files matching any of the
+	// patterns, which use the syntax of path.Match and are relative to the
+	// tar-root. A pattern without a "/" is matched against the file's base
+	// name, so "*.sh" matches shell scripts anywhere.
+	Patterns []string
+	// MaxSize is the size, in bytes, above which files aren't examined. If
+	// zero, DefaultMaxSize is used. If negative, there's no limit.
+	MaxSize int64
+}
+
+// Scanner is a ContentScanner that hands each regular file in a layer to an
+// Engine.
+type Scanner struct {
+	engine   Engine
+	patterns []string
+	max      int64
+}
+
+var _ driver.ContentScanner = (*Scanner)(nil)
+
+// New returns a Scanner using the provided Engine. The Config may be nil.
+func New(e Engine, cfg *Config) (*Scanner, error) {
+	if e == nil {
+		return nil, errors.New("sigscan: nil Engine")
+	}
+	s := Scanner{engine: e, max: DefaultMaxSize}
+	if cfg != nil {
+		for _, p := range cfg.Patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("sigscan: bad pattern %q: %w", p, err)
+			}
+		}
+		s.patterns = cfg.Patterns
+		if cfg.MaxSize != 0 {
+			s.max = cfg.MaxSize
+		}
+	}
+	return &s, nil
+}
+
+// Name implements scanner.VersionedScanner.
+func (s *Scanner) Name() string { return "sigscan/" + s.engine.Name() }
+
+// Version implements scanner.VersionedScanner.
+func (s *Scanner) Version() string { return s.engine.Version() }
+
+// Kind implements scanner.VersionedScanner.
+func (*Scanner) Kind() string { return driver.Content }
+
+// Scan implements driver.ContentScanner.
+//
+// The returned Detections have their Path and Signature filled in.
+func (s *Scanner) Scan(ctx context.Context, l *claircore.Layer) ([]claircore.Detection, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "sigscan/Scanner.Scan"),
+		label.String("engine", s.engine.Name()),
+		label.String("layer", l.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+
+	rd, err := l.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("sigscan: opening layer failed: %w", err)
+	}
+	defer rd.Close()
+
+	var out []claircore.Detection
+	var n int
+	tr := tar.NewReader(rd)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := normalize(h.Name)
+		if !s.wanted(name) {
+			continue
+		}
+		if s.max > 0 && h.Size > s.max {
+			zlog.Debug(ctx).
+				Str("file", name).
+				Int64("size", h.Size).
+				Msg("skipping large file")
+			continue
+		}
+		n++
+		sigs, err := s.engine.Match(ctx, tr)
+		if err != nil {
+			return nil, fmt.Errorf("sigscan: %s: %w", name, err)
+		}
+		for _, sig := range sigs {
+			zlog.Info(ctx).
+				Str("file", name).
+				Str("signature", sig).
+				Msg("signature matched")
+			out = append(out, claircore.Detection{
+				Path:      name,
+				Signature: sig,
+			})
+		}
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("sigscan: reading layer failed: %w", err)
+	}
+	zlog.Debug(ctx).
+		Int("files", n).
+		Int("detections", len(out)).
+		Msg("scanned files")
+	return out, nil
+}
+
+// Wanted reports whether the file should be scanned.
+func (s *Scanner) wanted(name string) bool {
+	if len(s.patterns) == 0 {
+		return true
+	}
+	base := path.Base(name)
+	for _, p := range s.patterns {
+		n := name
+		if !strings.Contains(p, "/") {
+			n = base
+		}
+		if ok, _ := path.Match(p, n); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize makes a tar entry name relative to the tar-root.
+func normalize(n string) string {
+	return path.Clean("/" + n)[1:]
+}
@@ -0,0 +1,199 @@
+package sigscan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
+)
+
+// Eicar is the standard antivirus test file.
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// The Scanner doesn't fill in Layer; the controller does.
+var ignoreLayer = cmpopts.IgnoreFields(claircore.Detection{}, "Layer")
+
+func TestScanner(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := fixture.Files(map[string]string{
+		"usr/bin/miner":    "prefix xmrig suffix",
+		"tmp/eicar.com":    eicar,
+		"etc/motd":         "hello",
+		"./opt/big/eicar":  eicar + strings.Repeat(" ", 100),
+		"usr/bin/clean.sh": "#!/bin/sh\n",
+	}).Local(t)
+	e := NewSignatures("1", map[string][]byte{
+		"Test.Eicar":  []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE"),
+		"Miner.Xmrig": []byte("xmrig"),
+	})
+	tt := []struct {
+		Name string
+		Cfg  *Config
+		Want []claircore.Detection
+	}{
+		{
+			Name: "All",
+			Want: []claircore.Detection{
+				{Path: "opt/big/eicar", Signature: "Test.Eicar"},
+				{Path: "tmp/eicar.com", Signature: "Test.Eicar"},
+				{Path: "usr/bin/miner", Signature: "Miner.Xmrig"},
+			},
+		},
+		{
+			Name: "Patterns",
+			Cfg:  &Config{Patterns: []string{"usr/bin/*", "*.com"}},
+			Want: []claircore.Detection{
+				{Path: "tmp/eicar.com", Signature: "Test.Eicar"},
+				{Path: "usr/bin/miner", Signature: "Miner.Xmrig"},
+			},
+		},
+		{
+			Name: "MaxSize",
+			Cfg:  &Config{MaxSize: int64(len(eicar))},
+			Want: []claircore.Detection{
+				{Path: "tmp/eicar.com", Signature: "Test.Eicar"},
+				{Path: "usr/bin/miner", Signature: "Miner.Xmrig"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			s, err := New(e, tc.Cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.Scan(ctx, l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i].Path < got[j].Path })
+			if !cmp.Equal(got, tc.Want, ignoreLayer) {
+				t.Error(cmp.Diff(got, tc.Want, ignoreLayer))
+			}
+		})
+	}
+}
+
+func TestParseSignatures(t *testing.T) {
+	in := "# comment\n\nTest.Eicar:4549434152\nOdd:Name:00ff\n"
+	s, err := ParseSignatures("1", strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Match(context.Background(), strings.NewReader("x\x00\xffEICAR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Odd:Name", "Test.Eicar"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	for _, bad := range []string{"nohex", "Name:zz", "Name:"} {
+		if _, err := ParseSignatures("1", strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+// FakeClamd answers INSTREAM requests, reporting a match for any stream
+// containing the EICAR string.
+func fakeClamd(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, err := r.ReadString(0)
+				if err != nil || cmd != "zINSTREAM\x00" {
+					io.WriteString(conn, "UNKNOWN COMMAND\x00")
+					return
+				}
+				var buf bytes.Buffer
+				for {
+					var sz uint32
+					if err := binary.Read(r, binary.BigEndian, &sz); err != nil {
+						return
+					}
+					if sz == 0 {
+						break
+					}
+					if _, err := io.CopyN(&buf, r, int64(sz)); err != nil {
+						return
+					}
+				}
+				switch {
+				case buf.Len() > 1<<20:
+					io.WriteString(conn, "INSTREAM size limit exceeded. ERROR\x00")
+				case bytes.Contains(buf.Bytes(), []byte("EICAR")):
+					io.WriteString(conn, "stream: Win.Test.EICAR_HDB-1 FOUND\x00")
+				default:
+					io.WriteString(conn, "stream: OK\x00")
+				}
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestClamd(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ln := fakeClamd(t)
+	e := &Clamd{Network: "tcp", Address: ln.Addr().String()}
+
+	got, err := e.Match(ctx, strings.NewReader(eicar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Win.Test.EICAR_HDB-1"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	// Larger than a single chunk.
+	got, err = e.Match(ctx, io.MultiReader(bytes.NewReader(make([]byte, 3*clamdChunk+7)), strings.NewReader("clean")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("unexpected match: %v", got)
+	}
+	if _, err := e.Match(ctx, bytes.NewReader(make([]byte, 2<<20))); err == nil {
+		t.Error("expected error")
+	}
+
+	s, err := New(e, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := s.Scan(ctx, fixture.Files(map[string]string{
+		"tmp/eicar.com": eicar,
+		"etc/motd":      "hello",
+	}).Local(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []claircore.Detection{{Path: "tmp/eicar.com", Signature: "Win.Test.EICAR_HDB-1"}}
+	if !cmp.Equal(ds, want, ignoreLayer) {
+		t.Error(cmp.Diff(ds, want, ignoreLayer))
+	}
+}
@@ -0,0 +1,421 @@
+package vmimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Ext4 reads ext2, ext3, and ext4 filesystems.
+//
+// Files using inline data larger than the inode's block area, and encrypted
+// files, can't be read.
+type ext4 struct{}
+
+func (ext4) Name() string { return "ext4" }
+
+const (
+	extSuperOffset = 1024
+	extMagic       = 0xef53
+
+	extIncompatFiletype   = 0x2
+	extIncompatRecover    = 0x4
+	extIncompatMetaBG     = 0x10
+	extIncompatExtents    = 0x40
+	extIncompat64Bit      = 0x80
+	extIncompatFlexBG     = 0x200
+	extIncompatEAInode    = 0x400
+	extIncompatDirData    = 0x1000
+	extIncompatCsumSeed   = 0x2000
+	extIncompatLargeDir   = 0x4000
+	extIncompatInlineData = 0x8000
+	extIncompatEncrypt    = 0x10000
+	extIncompatCasefold   = 0x20000
+
+	// ExtIncompatKnown is the set of incompatible features that don't
+	// prevent reading.
+	extIncompatKnown = extIncompatFiletype | extIncompatRecover | extIncompatExtents |
+		extIncompat64Bit | extIncompatFlexBG | extIncompatEAInode | extIncompatCsumSeed |
+		extIncompatLargeDir | extIncompatInlineData | extIncompatEncrypt | extIncompatCasefold
+
+	extInodeExtents    = 0x80000
+	extInodeInlineData = 0x10000000
+	extInodeEncrypt    = 0x800
+
+	extExtentMagic = 0xf30a
+	extRootIno     = 2
+)
+
+func (ext4) Probe(r io.ReaderAt) bool {
+	var b [2]byte
+	if _, err := r.ReadAt(b[:], extSuperOffset+56); err != nil {
+		return false
+	}
+	return binary.LittleEndian.Uint16(b[:]) == extMagic
+}
+
+func (ext4) Open(r io.ReaderAt, size int64) (FS, error) {
+	sb := make([]byte, 1024)
+	if _, err := r.ReadAt(sb, extSuperOffset); err != nil {
+		return nil, fmt.Errorf("vmimage: ext4: reading superblock: %w", err)
+	}
+	le := binary.LittleEndian
+	if le.Uint16(sb[56:]) != extMagic {
+		return nil, fmt.Errorf("vmimage: ext4: bad magic")
+	}
+	// Check the shift before doing it: a large enough one wraps to zero.
+	logBlockSize := le.Uint32(sb[24:])
+	if logBlockSize > 6 {
+		return nil, fmt.Errorf("vmimage: ext4: implausible superblock")
+	}
+	t := &extTree{
+		r:              r,
+		blockSize:      1024 << logBlockSize,
+		firstDataBlock: le.Uint32(sb[20:]),
+		inodesPerGroup: le.Uint32(sb[40:]),
+		inodeSize:      128,
+		incompat:       le.Uint32(sb[96:]),
+		descSize:       32,
+	}
+	t.blocks = size / t.blockSize
+	if le.Uint32(sb[76:]) >= 1 {
+		t.inodeSize = uint32(le.Uint16(sb[88:]))
+	}
+	if t.incompat&extIncompat64Bit != 0 {
+		if ds := le.Uint16(sb[254:]); ds != 0 {
+			t.descSize = uint32(ds)
+		}
+	}
+	copy(t.id[:], sb[104:120])
+	switch {
+	case t.incompat&^extIncompatKnown != 0:
+		return nil, fmt.Errorf("%w: ext4 incompatible features %#x", ErrUnsupported, t.incompat&^extIncompatKnown)
+	case t.incompat&extIncompatMetaBG != 0:
+		return nil, fmt.Errorf("%w: ext4 meta_bg", ErrUnsupported)
+	case t.inodesPerGroup == 0 || t.inodeSize < 128 || t.descSize < 32 ||
+		t.blockSize < 1024 || t.blockSize > 65536:
+		return nil, fmt.Errorf("vmimage: ext4: implausible superblock")
+	}
+	return &treeFS{t: t}, nil
+}
+
+// ExtTree implements tree for ext filesystems.
+type extTree struct {
+	r              io.ReaderAt
+	blockSize      int64
+	firstDataBlock uint32
+	inodesPerGroup uint32
+	inodeSize      uint32
+	descSize       uint32
+	incompat       uint32
+	id             [16]byte
+	// Blocks is the number of blocks in the filesystem, bounding how many
+	// a single file can map.
+	blocks int64
+}
+
+func (t *extTree) root() uint64   { return extRootIno }
+func (t *extTree) uuid() [16]byte { return t.id }
+
+// InodeTable returns the block number of the inode table for group "g".
+func (t *extTree) inodeTable(g uint32) (int64, error) {
+	off := (int64(t.firstDataBlock)+1)*t.blockSize + int64(g)*int64(t.descSize)
+	d := make([]byte, t.descSize)
+	if _, err := t.r.ReadAt(d, off); err != nil {
+		return 0, fmt.Errorf("reading group descriptor %d: %w", g, err)
+	}
+	blk := int64(binary.LittleEndian.Uint32(d[8:]))
+	if t.descSize >= 64 {
+		blk |= int64(binary.LittleEndian.Uint32(d[0x28:])) << 32
+	}
+	return blk, nil
+}
+
+func (t *extTree) inode(ino uint64) (*inode, error) {
+	if ino == 0 {
+		return nil, fmt.Errorf("invalid inode 0")
+	}
+	g := uint32((ino - 1) / uint64(t.inodesPerGroup))
+	idx := int64((ino - 1) % uint64(t.inodesPerGroup))
+	tbl, err := t.inodeTable(g)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, t.inodeSize)
+	if _, err := t.r.ReadAt(b, tbl*t.blockSize+idx*int64(t.inodeSize)); err != nil {
+		return nil, fmt.Errorf("reading inode %d: %w", ino, err)
+	}
+	le := binary.LittleEndian
+	i := &inode{
+		ino:   ino,
+		mode:  le.Uint16(b[0:]),
+		size:  int64(le.Uint32(b[4:])) | int64(le.Uint32(b[0x6c:]))<<32,
+		mtime: time.Unix(int64(int32(le.Uint32(b[0x10:]))), 0),
+	}
+	switch {
+	case i.size < 0:
+		return nil, fmt.Errorf("inode %d: implausible size %d", ino, i.size)
+	case i.mode&sIFMT == sIFREG && i.size > t.blocks*t.blockSize:
+		// Sparse files can be this large, but copying out all the zeros
+		// isn't useful.
+		return nil, fmt.Errorf("%w: inode %d: larger than filesystem", ErrUnsupported, ino)
+	}
+	flags := le.Uint32(b[0x20:])
+	blk := b[0x28 : 0x28+60]
+	switch {
+	case i.mode&sIFMT != sIFREG && i.mode&sIFMT != sIFDIR && i.mode&sIFMT != sIFLNK:
+		i.size = 0
+		i.data = bytes.NewReader(nil)
+	case i.mode&sIFMT == sIFLNK && i.size < 60 && flags&(extInodeExtents|extInodeInlineData) == 0:
+		// Fast symlink: the target is stored in the block array. Longer
+		// targets are stored in data blocks, like file contents.
+		i.data = bytes.NewReader(append([]byte(nil), blk[:i.size]...))
+	case flags&extInodeInlineData != 0:
+		if i.size > 60 {
+			return nil, fmt.Errorf("%w: inode %d: inline data beyond block area", ErrUnsupported, ino)
+		}
+		i.data = bytes.NewReader(append([]byte(nil), blk[:i.size]...))
+	case flags&extInodeEncrypt != 0:
+		return nil, fmt.Errorf("%w: inode %d: encrypted", ErrUnsupported, ino)
+	case flags&extInodeExtents != 0:
+		budget := t.blocks
+		exts, err := t.extents(blk, 0, &budget)
+		if err != nil {
+			return nil, fmt.Errorf("inode %d: %w", ino, err)
+		}
+		i.data = &extentReader{r: t.r, bs: t.blockSize, size: i.size, exts: exts}
+	default:
+		exts, err := t.blockMap(blk, i.size)
+		if err != nil {
+			return nil, fmt.Errorf("inode %d: %w", ino, err)
+		}
+		i.data = &extentReader{r: t.r, bs: t.blockSize, size: i.size, exts: exts}
+	}
+	return i, nil
+}
+
+// Extents walks an extent tree node, returning all the leaf extents.
+//
+// Every node and extent is charged against the budget, so a corrupt tree
+// can't describe more than the filesystem holds.
+func (t *extTree) extents(node []byte, depth int, budget *int64) ([]extent, error) {
+	if depth > 5 {
+		return nil, fmt.Errorf("extent tree too deep")
+	}
+	if *budget--; *budget < 0 {
+		return nil, fmt.Errorf("extent tree larger than filesystem")
+	}
+	le := binary.LittleEndian
+	if len(node) < 12 || le.Uint16(node[0:]) != extExtentMagic {
+		return nil, fmt.Errorf("bad extent header")
+	}
+	n := int(le.Uint16(node[2:]))
+	if 12+12*n > len(node) {
+		return nil, fmt.Errorf("bad extent entry count")
+	}
+	var out []extent
+	if le.Uint16(node[6:]) == 0 {
+		if *budget -= int64(n); *budget < 0 {
+			return nil, fmt.Errorf("extent tree larger than filesystem")
+		}
+		for i := 0; i < n; i++ {
+			e := node[12+12*i:]
+			l := int64(le.Uint16(e[4:]))
+			unwritten := false
+			if l > 32768 {
+				l -= 32768
+				unwritten = true
+			}
+			out = append(out, extent{
+				logical:  int64(le.Uint32(e[0:])),
+				physical: int64(le.Uint16(e[6:]))<<32 | int64(le.Uint32(e[8:])),
+				length:   l,
+				zero:     unwritten,
+			})
+		}
+		return out, nil
+	}
+	child := make([]byte, t.blockSize)
+	for i := 0; i < n; i++ {
+		e := node[12+12*i:]
+		blk := int64(le.Uint16(e[8:]))<<32 | int64(le.Uint32(e[4:]))
+		if _, err := t.r.ReadAt(child, blk*t.blockSize); err != nil {
+			return nil, err
+		}
+		exts, err := t.extents(child, depth+1, budget)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, exts...)
+	}
+	return out, nil
+}
+
+// BlockMap resolves the legacy direct and indirect block map into extents.
+func (t *extTree) blockMap(blk []byte, size int64) ([]extent, error) {
+	nblk := (size + t.blockSize - 1) / t.blockSize
+	per := t.blockSize / 4
+	var out []extent
+	var lblk int64
+	// Mapped blocks and indirect blocks read can't outnumber the blocks in
+	// the filesystem.
+	budget := t.blocks
+	errTooBig := fmt.Errorf("block map larger than filesystem")
+	add := func(p int64) error {
+		defer func() { lblk++ }()
+		if p == 0 {
+			return nil // Sparse.
+		}
+		if budget--; budget < 0 {
+			return errTooBig
+		}
+		if n := len(out); n != 0 {
+			e := &out[n-1]
+			if e.logical+e.length == lblk && e.physical+e.length == p {
+				e.length++
+				return nil
+			}
+		}
+		out = append(out, extent{logical: lblk, physical: p, length: 1})
+		return nil
+	}
+	var walk func(p int64, level int) error
+	walk = func(p int64, level int) error {
+		if p == 0 {
+			// A missing indirect block is a hole covering all its blocks.
+			n := int64(1)
+			for i := 0; i < level; i++ {
+				n *= per
+			}
+			lblk += n
+			return nil
+		}
+		if level == 0 {
+			return add(p)
+		}
+		if budget--; budget < 0 {
+			return errTooBig
+		}
+		b := make([]byte, t.blockSize)
+		if _, err := t.r.ReadAt(b, p*t.blockSize); err != nil {
+			return err
+		}
+		for i := int64(0); i < per && lblk < nblk; i++ {
+			if err := walk(int64(binary.LittleEndian.Uint32(b[4*i:])), level-1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	le := binary.LittleEndian
+	for i := 0; i < 12 && lblk < nblk; i++ {
+		if err := add(int64(le.Uint32(blk[4*i:]))); err != nil {
+			return nil, err
+		}
+	}
+	for level := 1; level <= 3 && lblk < nblk; level++ {
+		if err := walk(int64(le.Uint32(blk[4*(11+level):])), level); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (t *extTree) readDir(i *inode) ([]dirent, error) {
+	b, err := readAll(i)
+	if err != nil {
+		return nil, err
+	}
+	le := binary.LittleEndian
+	var out []dirent
+	for off := 0; off+8 <= len(b); {
+		ino := le.Uint32(b[off:])
+		rl := int(le.Uint16(b[off+4:]))
+		nl := int(b[off+6])
+		if t.incompat&extIncompatFiletype == 0 {
+			nl = int(le.Uint16(b[off+6:]))
+		}
+		if rl < 8 || off+rl > len(b) || 8+nl > rl {
+			return nil, fmt.Errorf("inode %d: corrupt directory entry at %d", i.ino, off)
+		}
+		if ino != 0 {
+			out = append(out, dirent{name: string(b[off+8 : off+8+nl]), ino: uint64(ino)})
+		}
+		off += rl
+	}
+	return out, nil
+}
+
+// Extent maps a run of logical blocks to physical blocks.
+type extent struct {
+	logical, physical, length int64
+	// Zero is set for unwritten (preallocated) extents.
+	zero bool
+}
+
+// ExtentReader reads file contents via a list of extents. Unmapped blocks read
+// as zeros.
+type extentReader struct {
+	r    io.ReaderAt
+	bs   int64
+	size int64
+	exts []extent
+}
+
+func (e *extentReader) ReadAt(p []byte, off int64) (int, error) {
+	if off >= e.size {
+		return 0, io.EOF
+	}
+	var err error
+	if rem := e.size - off; int64(len(p)) > rem {
+		p = p[:rem]
+		err = io.EOF
+	}
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		lblk := pos / e.bs
+		in := pos % e.bs
+		chunk := p[n:]
+		ext := e.find(lblk)
+		var run int64
+		if ext != nil {
+			run = (ext.logical+ext.length-lblk)*e.bs - in
+		} else {
+			run = e.bs - in
+		}
+		if int64(len(chunk)) > run {
+			chunk = chunk[:run]
+		}
+		if ext == nil || ext.zero {
+			zero(chunk)
+		} else {
+			phys := (ext.physical+lblk-ext.logical)*e.bs + in
+			if _, err := e.r.ReadAt(chunk, phys); err != nil && err != io.EOF {
+				return n, err
+			}
+		}
+		n += len(chunk)
+	}
+	return n, err
+}
+
+// Find returns the extent containing the logical block, or nil.
+func (e *extentReader) find(lblk int64) *extent {
+	lo, hi := 0, len(e.exts)
+	for lo < hi {
+		m := (lo + hi) / 2
+		x := &e.exts[m]
+		switch {
+		case lblk < x.logical:
+			hi = m
+		case lblk >= x.logical+x.length:
+			lo = m + 1
+		default:
+			return x
+		}
+	}
+	return nil
+}
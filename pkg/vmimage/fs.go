@@ -0,0 +1,424 @@
+package vmimage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Filesystem is a reader for one kind of filesystem.
+//
+// Implementations are registered with Register, and selected by probing each
+// partition of an image.
+type Filesystem interface {
+	// Name reports the filesystem's name, such as "ext4".
+	Name() string
+	// Probe reports whether the region looks like this filesystem.
+	Probe(r io.ReaderAt) bool
+	// Open returns an FS for the region.
+	Open(r io.ReaderAt, size int64) (FS, error)
+}
+
+// FS is a read-only view of a filesystem.
+//
+// Symbolic links are followed by Open, Stat, and ReadDir. ReadDir reports
+// symbolic links as such.
+type FS interface {
+	fs.StatFS
+	fs.ReadDirFS
+	// ReadLink returns the target of the named symbolic link.
+	ReadLink(name string) (string, error)
+	// UUID reports the filesystem's UUID in the canonical textual form.
+	UUID() string
+}
+
+var registry struct {
+	sync.Mutex
+	fs []Filesystem
+}
+
+// Register adds a Filesystem to the set used to probe partitions. A
+// Filesystem with the same name as one already registered replaces it.
+func Register(f Filesystem) {
+	registry.Lock()
+	defer registry.Unlock()
+	for i, e := range registry.fs {
+		if e.Name() == f.Name() {
+			registry.fs[i] = f
+			return
+		}
+	}
+	registry.fs = append(registry.fs, f)
+}
+
+// Probe returns the first registered Filesystem that recognizes the region,
+// or nil.
+func Probe(r io.ReaderAt) Filesystem {
+	registry.Lock()
+	defer registry.Unlock()
+	for _, f := range registry.fs {
+		if f.Probe(r) {
+			return f
+		}
+	}
+	return nil
+}
+
+func init() {
+	Register(ext4{})
+	Register(xfs{})
+}
+
+// Tree is the interface filesystem implementations provide to treeFS.
+type tree interface {
+	root() uint64
+	inode(ino uint64) (*inode, error)
+	readDir(*inode) ([]dirent, error)
+	uuid() [16]byte
+}
+
+// Inode is the filesystem-independent representation of an inode.
+type inode struct {
+	ino   uint64
+	mode  uint16 // Unix mode bits, including type.
+	size  int64
+	mtime time.Time
+	// Data is the contents of the file, directory, or symlink.
+	data io.ReaderAt
+}
+
+type dirent struct {
+	name string
+	ino  uint64
+}
+
+// Unix file type bits.
+const (
+	sIFMT   = 0xf000
+	sIFSOCK = 0xc000
+	sIFLNK  = 0xa000
+	sIFREG  = 0x8000
+	sIFBLK  = 0x6000
+	sIFDIR  = 0x4000
+	sIFCHR  = 0x2000
+	sIFIFO  = 0x1000
+)
+
+func (i *inode) fileMode() fs.FileMode {
+	m := fs.FileMode(i.mode & 0o777)
+	if i.mode&0o4000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if i.mode&0o2000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if i.mode&0o1000 != 0 {
+		m |= fs.ModeSticky
+	}
+	switch i.mode & sIFMT {
+	case sIFDIR:
+		m |= fs.ModeDir
+	case sIFLNK:
+		m |= fs.ModeSymlink
+	case sIFCHR:
+		m |= fs.ModeDevice | fs.ModeCharDevice
+	case sIFBLK:
+		m |= fs.ModeDevice
+	case sIFIFO:
+		m |= fs.ModeNamedPipe
+	case sIFSOCK:
+		m |= fs.ModeSocket
+	}
+	return m
+}
+
+// TreeFS implements FS on top of a tree.
+type treeFS struct {
+	t tree
+
+	mu sync.Mutex
+	// Dirs caches recently read directories, as every lookup walks from the
+	// root.
+	dirs map[uint64][]dirent
+}
+
+const dirCacheSize = 256
+
+// Entries returns the entries of the directory, consulting the cache.
+func (f *treeFS) entries(i *inode) ([]dirent, error) {
+	f.mu.Lock()
+	ents, ok := f.dirs[i.ino]
+	f.mu.Unlock()
+	if ok {
+		return ents, nil
+	}
+	ents, err := f.t.readDir(i)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.dirs == nil || len(f.dirs) >= dirCacheSize {
+		f.dirs = make(map[uint64][]dirent)
+	}
+	f.dirs[i.ino] = ents
+	return ents, nil
+}
+
+var _ FS = (*treeFS)(nil)
+
+// MaxLinks is the maximum number of symlinks followed in one lookup.
+const maxLinks = 40
+
+// Lookup resolves "name" to an inode, following symlinks in every element
+// and, if "follow" is set, the final one.
+func (f *treeFS) lookup(op, name string, follow bool) (*inode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	fail := func(err error) (*inode, error) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	cur, err := f.t.inode(f.t.root())
+	if err != nil {
+		return fail(err)
+	}
+	var todo []string
+	if name != "." {
+		todo = strings.Split(name, "/")
+	}
+	// Dirs is the stack of directories traversed, for resolving "..".
+	dirs := []*inode{cur}
+	links := 0
+	for len(todo) != 0 {
+		el := todo[0]
+		todo = todo[1:]
+		switch el {
+		case "", ".":
+			continue
+		case "..":
+			if len(dirs) > 1 {
+				dirs = dirs[:len(dirs)-1]
+			}
+			cur = dirs[len(dirs)-1]
+			continue
+		}
+		if cur.mode&sIFMT != sIFDIR {
+			return fail(errNotDir)
+		}
+		ents, err := f.entries(cur)
+		if err != nil {
+			return fail(err)
+		}
+		var next *inode
+		for _, e := range ents {
+			if e.name == el {
+				if next, err = f.t.inode(e.ino); err != nil {
+					return fail(err)
+				}
+				break
+			}
+		}
+		if next == nil {
+			return fail(fs.ErrNotExist)
+		}
+		if next.mode&sIFMT == sIFLNK && (len(todo) != 0 || follow) {
+			links++
+			if links > maxLinks {
+				return fail(errLoop)
+			}
+			tgt, err := readAll(next)
+			if err != nil {
+				return fail(err)
+			}
+			t := string(tgt)
+			if strings.HasPrefix(t, "/") {
+				dirs = dirs[:1]
+			}
+			todo = append(strings.Split(t, "/"), todo...)
+			cur = dirs[len(dirs)-1]
+			continue
+		}
+		cur = next
+		if cur.mode&sIFMT == sIFDIR {
+			dirs = append(dirs, cur)
+		}
+	}
+	return cur, nil
+}
+
+var (
+	errNotDir = errors.New("not a directory")
+	errLoop   = errors.New("too many levels of symbolic links")
+)
+
+// MaxReadAll is the largest directory or symlink target readAll reads.
+const maxReadAll = 64 << 20
+
+// ReadAll reads the inode's contents. It's used for directories and symlink
+// targets, which are read into memory whole.
+func readAll(i *inode) ([]byte, error) {
+	if i.size < 0 || i.size > maxReadAll {
+		return nil, fmt.Errorf("inode %d: implausible size %d", i.ino, i.size)
+	}
+	b := make([]byte, i.size)
+	if _, err := i.data.ReadAt(b, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Open implements fs.FS.
+func (f *treeFS) Open(name string) (fs.File, error) {
+	i, err := f.lookup("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &file{
+		fs:   f,
+		name: name,
+		i:    i,
+		r:    io.NewSectionReader(i.data, 0, i.size),
+	}, nil
+}
+
+// Stat implements fs.StatFS.
+func (f *treeFS) Stat(name string) (fs.FileInfo, error) {
+	i, err := f.lookup("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: path.Base(name), i: i}, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f *treeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	i, err := f.lookup("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return f.readDir(name, i)
+}
+
+func (f *treeFS) readDir(name string, i *inode) ([]fs.DirEntry, error) {
+	if i.mode&sIFMT != sIFDIR {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	ents, err := f.entries(i)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	out := make([]fs.DirEntry, 0, len(ents))
+	for _, e := range ents {
+		if e.name == "." || e.name == ".." {
+			continue
+		}
+		ci, err := f.t.inode(e.ino)
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, ErrUnsupported):
+			// Omit entries that can't be read, rather than failing the
+			// whole directory.
+			continue
+		default:
+			return nil, &fs.PathError{Op: "readdir", Path: path.Join(name, e.name), Err: err}
+		}
+		out = append(out, &fileInfo{name: e.name, i: ci})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out, nil
+}
+
+// ReadLink implements FS.
+func (f *treeFS) ReadLink(name string) (string, error) {
+	i, err := f.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if i.mode&sIFMT != sIFLNK {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	b, err := readAll(i)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return string(b), nil
+}
+
+// UUID implements FS.
+func (f *treeFS) UUID() string {
+	u := f.t.uuid()
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// File implements fs.File and fs.ReadDirFile.
+type file struct {
+	fs   *treeFS
+	name string
+	i    *inode
+	r    *io.SectionReader
+	ents []fs.DirEntry
+	read bool
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return &fileInfo{name: path.Base(f.name), i: f.i}, nil
+}
+
+func (f *file) Read(b []byte) (int, error) {
+	if f.i.mode&sIFMT == sIFDIR {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: errors.New("is a directory")}
+	}
+	return f.r.Read(b)
+}
+
+func (f *file) ReadAt(b []byte, off int64) (int, error) { return f.r.ReadAt(b, off) }
+
+func (f *file) Seek(off int64, whence int) (int64, error) { return f.r.Seek(off, whence) }
+
+func (f *file) Close() error { return nil }
+
+func (f *file) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.read {
+		ents, err := f.fs.readDir(f.name, f.i)
+		if err != nil {
+			return nil, err
+		}
+		f.ents, f.read = ents, true
+	}
+	if n <= 0 {
+		out := f.ents
+		f.ents = nil
+		return out, nil
+	}
+	if len(f.ents) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(f.ents) {
+		n = len(f.ents)
+	}
+	out := f.ents[:n]
+	f.ents = f.ents[n:]
+	return out, nil
+}
+
+// FileInfo implements fs.FileInfo and fs.DirEntry.
+type fileInfo struct {
+	name string
+	i    *inode
+}
+
+func (fi *fileInfo) Name() string               { return fi.name }
+func (fi *fileInfo) Size() int64                { return fi.i.size }
+func (fi *fileInfo) Mode() fs.FileMode          { return fi.i.fileMode() }
+func (fi *fileInfo) ModTime() time.Time         { return fi.i.mtime }
+func (fi *fileInfo) IsDir() bool                { return fi.i.mode&sIFMT == sIFDIR }
+func (fi *fileInfo) Sys() interface{}           { return nil }
+func (fi *fileInfo) Type() fs.FileMode          { return fi.Mode().Type() }
+func (fi *fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
@@ -0,0 +1,70 @@
+// Package vmimage reads virtual machine disk images, so that their
+// filesystems can be indexed like container layers.
+//
+// Disk image formats (raw and qcow2) are handled by Open. Filesystems are
+// located inside the image's partitions by the registered Filesystem
+// implementations; ext4 and xfs readers are registered by default. All access
+// is read-only.
+package vmimage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrUnsupported is returned when an image or filesystem uses a feature this
+// package can't read.
+var ErrUnsupported = errors.New("vmimage: unsupported feature")
+
+// Image is a virtual disk, presented as the contents the guest would see.
+type Image interface {
+	io.ReaderAt
+	io.Closer
+	// Size reports the guest-visible size of the disk, in bytes.
+	Size() int64
+}
+
+// Open opens the named disk image, detecting its format.
+//
+// Qcow2 images are detected by their magic number; anything else is treated
+// as a raw image.
+func Open(name string) (Image, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var magic [4]byte
+	switch _, err := f.ReadAt(magic[:], 0); {
+	case errors.Is(err, nil):
+	case errors.Is(err, io.EOF):
+		// Too small to be anything but raw.
+	default:
+		f.Close()
+		return nil, err
+	}
+	if bytes.Equal(magic[:], qcowMagic) {
+		img, err := openQcow2(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("vmimage: qcow2: %w", err)
+		}
+		return img, nil
+	}
+	return &raw{File: f, size: fi.Size()}, nil
+}
+
+// Raw is a raw disk image, which is just the disk contents.
+type raw struct {
+	*os.File
+	size int64
+}
+
+func (r *raw) Size() int64 { return r.size }
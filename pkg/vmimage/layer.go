@@ -0,0 +1,235 @@
+package vmimage
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+)
+
+// Mount is a filesystem found in an Image.
+type Mount struct {
+	FS
+	// Filesystem is the name of the Filesystem implementation that opened
+	// the filesystem.
+	Filesystem string
+	// Partition is the partition the filesystem was found in.
+	Partition Partition
+	// Path is where the filesystem is mounted in the guest, if known. The
+	// root filesystem is "/".
+	Path string
+}
+
+// Mounts finds the filesystems in the image.
+//
+// The root filesystem is the one containing an os-release file. Other
+// filesystems are placed according to the root filesystem's /etc/fstab, if
+// they're referenced there by UUID. Filesystems that are found but can't be
+// placed are returned with an empty Path.
+func Mounts(ctx context.Context, img Image) ([]Mount, error) {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pkg/vmimage/Mounts"))
+	ps, err := Partitions(img)
+	if err != nil {
+		return nil, err
+	}
+	var ms []Mount
+	for _, p := range ps {
+		sr := p.Section(img)
+		f := Probe(sr)
+		if f == nil {
+			zlog.Debug(ctx).
+				Int("partition", p.Index).
+				Msg("no filesystem recognized")
+			continue
+		}
+		fsys, err := f.Open(sr, p.Size)
+		if err != nil {
+			zlog.Warn(ctx).
+				Int("partition", p.Index).
+				Str("filesystem", f.Name()).
+				Err(err).
+				Msg("unable to open filesystem")
+			continue
+		}
+		ms = append(ms, Mount{FS: fsys, Filesystem: f.Name(), Partition: p})
+	}
+	if len(ms) == 0 {
+		return nil, errors.New("vmimage: no readable filesystems found")
+	}
+
+	root := -1
+	for i, m := range ms {
+		if isRoot(m) {
+			root = i
+			break
+		}
+	}
+	if root == -1 {
+		if len(ms) != 1 {
+			return nil, errors.New("vmimage: unable to determine root filesystem")
+		}
+		root = 0
+	}
+	ms[root].Path = "/"
+	fstab, err := readFstab(ms[root])
+	if err != nil {
+		zlog.Debug(ctx).Err(err).Msg("no usable fstab")
+		return ms, nil
+	}
+	for i := range ms {
+		if i == root {
+			continue
+		}
+		if p, ok := fstab["UUID="+ms[i].UUID()]; ok && p != "/" {
+			ms[i].Path = p
+		}
+	}
+	return ms, nil
+}
+
+func isRoot(m Mount) bool {
+	for _, p := range []string{"etc/os-release", "usr/lib/os-release"} {
+		if _, err := m.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFstab returns a map of fstab device specifications to mount points.
+func readFstab(m Mount) (map[string]string, error) {
+	f, err := m.Open("etc/fstab")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	out := make(map[string]string)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fs := strings.Fields(s.Text())
+		if len(fs) < 2 || strings.HasPrefix(fs[0], "#") || !strings.HasPrefix(fs[1], "/") {
+			continue
+		}
+		spec := fs[0]
+		if strings.HasPrefix(spec, "UUID=") {
+			spec = "UUID=" + strings.ToLower(strings.Trim(strings.TrimPrefix(spec, "UUID="), `"`))
+		}
+		out[spec] = path.Clean(fs[1])
+	}
+	return out, s.Err()
+}
+
+// WriteLayer writes the image's filesystems to "w" as a tar archive suitable
+// for use as a claircore.Layer.
+//
+// Filesystems that couldn't be placed in the tree are omitted. Files that
+// can't be read because they use unsupported features are skipped.
+func WriteLayer(ctx context.Context, w io.Writer, img Image) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "pkg/vmimage/WriteLayer"))
+	ms, err := Mounts(ctx, img)
+	if err != nil {
+		return err
+	}
+	// Write shallower mounts first, so deeper ones land on top.
+	sort.SliceStable(ms, func(i, j int) bool {
+		return strings.Count(ms[i].Path, "/") < strings.Count(ms[j].Path, "/")
+	})
+	tw := tar.NewWriter(w)
+	for _, m := range ms {
+		if m.Path == "" {
+			zlog.Info(ctx).
+				Int("partition", m.Partition.Index).
+				Str("filesystem", m.Filesystem).
+				Msg("skipping filesystem with unknown mount point")
+			continue
+		}
+		zlog.Debug(ctx).
+			Int("partition", m.Partition.Index).
+			Str("filesystem", m.Filesystem).
+			Str("path", m.Path).
+			Msg("writing filesystem")
+		if err := writeTree(ctx, tw, m.FS, strings.TrimPrefix(m.Path, "/")); err != nil {
+			return fmt.Errorf("vmimage: partition %d: %w", m.Partition.Index, err)
+		}
+	}
+	return tw.Close()
+}
+
+func writeTree(ctx context.Context, tw *tar.Writer, fsys FS, prefix string) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, ErrUnsupported):
+			zlog.Debug(ctx).Str("path", p).Err(err).Msg("skipping unreadable path")
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		default:
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := path.Join(prefix, p)
+		if name == "." || name == "" {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		switch m := fi.Mode(); {
+		case m.IsRegular(), m.IsDir():
+		case m&fs.ModeSymlink != 0:
+			if link, err = fsys.ReadLink(p); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+		if !fi.Mode().IsRegular() {
+			return tw.WriteHeader(hdr)
+		}
+		// Open the file before writing its header, so a file that can't be
+		// read doesn't leave a header without contents in the archive.
+		f, err := fsys.Open(p)
+		switch {
+		case errors.Is(err, nil):
+		case errors.Is(err, ErrUnsupported):
+			zlog.Debug(ctx).Str("path", p).Err(err).Msg("skipping unreadable path")
+			return nil
+		default:
+			return err
+		}
+		defer f.Close()
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, f); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		return nil
+	})
+}
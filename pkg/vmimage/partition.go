@@ -0,0 +1,108 @@
+package vmimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const sectorSize = 512
+
+// Partition is a region of an Image.
+type Partition struct {
+	// Index is the partition's position in the partition table, starting at
+	// 1. An image without a partition table is reported as a single
+	// Partition with Index 0.
+	Index int
+	// Start and Size are in bytes.
+	Start, Size int64
+}
+
+// Section returns a reader for the partition's contents.
+func (p Partition) Section(r io.ReaderAt) *io.SectionReader {
+	return io.NewSectionReader(r, p.Start, p.Size)
+}
+
+// Partitions reads the MBR or GPT partition table of the Image.
+//
+// If there's no recognizable partition table, the whole image is returned as
+// a single Partition. Extended MBR partitions are not followed.
+func Partitions(img Image) ([]Partition, error) {
+	whole := []Partition{{Start: 0, Size: img.Size()}}
+	mbr := make([]byte, sectorSize)
+	if _, err := img.ReadAt(mbr, 0); err != nil {
+		if err == io.EOF {
+			return whole, nil
+		}
+		return nil, err
+	}
+	if mbr[510] != 0x55 || mbr[511] != 0xaa {
+		return whole, nil
+	}
+	var ps []Partition
+	for i := 0; i < 4; i++ {
+		e := mbr[446+16*i : 446+16*(i+1)]
+		typ := e[4]
+		start := int64(binary.LittleEndian.Uint32(e[8:])) * sectorSize
+		size := int64(binary.LittleEndian.Uint32(e[12:])) * sectorSize
+		switch typ {
+		case 0x00:
+			continue
+		case 0xee:
+			return gptPartitions(img)
+		case 0x05, 0x0f, 0x85:
+			// Extended partitions are not followed.
+			continue
+		}
+		ps = append(ps, Partition{Index: i + 1, Start: start, Size: size})
+	}
+	if len(ps) == 0 {
+		// A boot sector signature with no entries is likely a filesystem
+		// with a boot block, like a FAT volume.
+		return whole, nil
+	}
+	return ps, nil
+}
+
+var gptSignature = []byte("EFI PART")
+
+func gptPartitions(img Image) ([]Partition, error) {
+	h := make([]byte, 92)
+	if _, err := img.ReadAt(h, sectorSize); err != nil {
+		return nil, fmt.Errorf("vmimage: reading GPT header: %w", err)
+	}
+	if !bytes.Equal(h[:8], gptSignature) {
+		return nil, fmt.Errorf("vmimage: bad GPT signature")
+	}
+	lba := int64(binary.LittleEndian.Uint64(h[72:]))
+	n := int(binary.LittleEndian.Uint32(h[80:]))
+	sz := int(binary.LittleEndian.Uint32(h[84:]))
+	// Entries are 128 bytes times a power of two; in practice always 128.
+	if sz < 128 || sz > 4096 || sz&(sz-1) != 0 || n > 1024 {
+		return nil, fmt.Errorf("vmimage: implausible GPT entries: %d of size %d", n, sz)
+	}
+	tbl := make([]byte, n*sz)
+	if _, err := img.ReadAt(tbl, lba*sectorSize); err != nil {
+		return nil, fmt.Errorf("vmimage: reading GPT entries: %w", err)
+	}
+	var ps []Partition
+	var unused [16]byte
+	for i := 0; i < n; i++ {
+		e := tbl[i*sz : (i+1)*sz]
+		if bytes.Equal(e[:16], unused[:]) {
+			continue
+		}
+		first := int64(binary.LittleEndian.Uint64(e[32:]))
+		last := int64(binary.LittleEndian.Uint64(e[40:]))
+		if last < first {
+			continue
+		}
+		ps = append(ps, Partition{
+			Index: i + 1,
+			Start: first * sectorSize,
+			Size:  (last - first + 1) * sectorSize,
+		})
+	}
+	return ps, nil
+}
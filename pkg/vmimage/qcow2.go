@@ -0,0 +1,225 @@
+package vmimage
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var qcowMagic = []byte{'Q', 'F', 'I', 0xfb}
+
+// Qcow2 header incompatible feature bits.
+const (
+	qcowDirty        = 1 << 0
+	qcowCorrupt      = 1 << 1
+	qcowExternalData = 1 << 2
+	qcowCompression  = 1 << 3
+	qcowExtendedL2   = 1 << 4
+)
+
+// Qcow2 table entry bits.
+const (
+	qcowOffsetMask = 0x00fffffffffffe00
+	qcowCompressed = 1 << 62
+	qcowZero       = 1 << 0
+)
+
+// Qcow2 is a reader for qcow2 (version 2 and 3) images.
+//
+// Backing files, encryption, external data files, extended L2 entries, and
+// non-deflate compression are not supported.
+type qcow2 struct {
+	f           *os.File
+	size        int64
+	clusterBits uint
+	clusterSize int64
+	l1          []uint64
+
+	mu sync.Mutex
+	// L2 is a small cache of L2 tables, keyed by host offset.
+	l2 map[uint64][]uint64
+}
+
+const qcowL2CacheSize = 64
+
+// QcowMaxL1Size is the most L1 entries read, matching QEMU's 32 MiB limit on
+// the table.
+const qcowMaxL1Size = 1 << 22
+
+func openQcow2(f *os.File) (*qcow2, error) {
+	var h struct {
+		Magic                 uint32
+		Version               uint32
+		BackingFileOffset     uint64
+		BackingFileSize       uint32
+		ClusterBits           uint32
+		Size                  uint64
+		CryptMethod           uint32
+		L1Size                uint32
+		L1TableOffset         uint64
+		RefcountTableOffset   uint64
+		RefcountTableClusters uint32
+		NbSnapshots           uint32
+		SnapshotsOffset       uint64
+	}
+	sr := io.NewSectionReader(f, 0, 1<<20)
+	if err := binary.Read(sr, binary.BigEndian, &h); err != nil {
+		return nil, err
+	}
+	switch h.Version {
+	case 2:
+	case 3:
+		var incompat uint64
+		if err := binary.Read(sr, binary.BigEndian, &incompat); err != nil {
+			return nil, err
+		}
+		if incompat&qcowCorrupt != 0 {
+			return nil, fmt.Errorf("image marked corrupt")
+		}
+		if incompat&^qcowDirty != 0 {
+			return nil, fmt.Errorf("%w: incompatible features %#x", ErrUnsupported, incompat)
+		}
+	default:
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupported, h.Version)
+	}
+	switch {
+	case h.BackingFileOffset != 0:
+		return nil, fmt.Errorf("%w: backing file", ErrUnsupported)
+	case h.CryptMethod != 0:
+		return nil, fmt.Errorf("%w: encryption", ErrUnsupported)
+	case h.ClusterBits < 9 || h.ClusterBits > 21:
+		return nil, fmt.Errorf("invalid cluster bits: %d", h.ClusterBits)
+	case h.Size > 1<<62:
+		return nil, fmt.Errorf("invalid size: %d", h.Size)
+	}
+	// Each L1 entry maps an L2 table's worth of clusters, so the virtual size
+	// determines how many entries are used. Any past that map nothing.
+	perL1 := uint64(1) << (2*h.ClusterBits - 3)
+	need := (h.Size + perL1 - 1) / perL1
+	switch {
+	case uint64(h.L1Size) < need:
+		return nil, fmt.Errorf("L1 table too small: %d entries for size %d", h.L1Size, h.Size)
+	case need > qcowMaxL1Size:
+		return nil, fmt.Errorf("%w: L1 table of %d entries", ErrUnsupported, need)
+	}
+
+	q := &qcow2{
+		f:           f,
+		size:        int64(h.Size),
+		clusterBits: uint(h.ClusterBits),
+		clusterSize: int64(1) << h.ClusterBits,
+		l1:          make([]uint64, need),
+		l2:          make(map[uint64][]uint64),
+	}
+	l1 := io.NewSectionReader(f, int64(h.L1TableOffset), int64(need)*8)
+	if err := binary.Read(l1, binary.BigEndian, q.l1); err != nil {
+		return nil, fmt.Errorf("reading L1 table: %w", err)
+	}
+	return q, nil
+}
+
+func (q *qcow2) Size() int64  { return q.size }
+func (q *qcow2) Close() error { return q.f.Close() }
+
+// L2Table returns the L2 table at the host offset "off".
+func (q *qcow2) l2Table(off uint64) ([]uint64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if t, ok := q.l2[off]; ok {
+		return t, nil
+	}
+	t := make([]uint64, q.clusterSize/8)
+	sr := io.NewSectionReader(q.f, int64(off), q.clusterSize)
+	if err := binary.Read(sr, binary.BigEndian, t); err != nil {
+		return nil, fmt.Errorf("reading L2 table: %w", err)
+	}
+	if len(q.l2) >= qcowL2CacheSize {
+		q.l2 = make(map[uint64][]uint64)
+	}
+	q.l2[off] = t
+	return t, nil
+}
+
+// ReadAt implements io.ReaderAt.
+func (q *qcow2) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("vmimage: negative offset")
+	}
+	if off >= q.size {
+		return 0, io.EOF
+	}
+	var err error
+	if rem := q.size - off; int64(len(p)) > rem {
+		p = p[:rem]
+		err = io.EOF
+	}
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		inCluster := pos & (q.clusterSize - 1)
+		chunk := p[n:]
+		if max := q.clusterSize - inCluster; int64(len(chunk)) > max {
+			chunk = chunk[:max]
+		}
+		if err := q.readCluster(chunk, pos>>q.clusterBits, inCluster); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return n, err
+}
+
+// ReadCluster fills "p" from guest cluster "c", starting at "off" within the
+// cluster.
+func (q *qcow2) readCluster(p []byte, c, off int64) error {
+	l2Entries := q.clusterSize / 8
+	l1i, l2i := c/l2Entries, c%l2Entries
+	if l1i >= int64(len(q.l1)) || q.l1[l1i]&qcowOffsetMask == 0 {
+		zero(p)
+		return nil
+	}
+	t, err := q.l2Table(q.l1[l1i] & qcowOffsetMask)
+	if err != nil {
+		return err
+	}
+	e := t[l2i]
+	switch {
+	case e&qcowCompressed != 0:
+		return q.readCompressed(p, e, off)
+	case e&qcowZero != 0, e&qcowOffsetMask == 0:
+		zero(p)
+		return nil
+	}
+	_, err = q.f.ReadAt(p, int64(e&qcowOffsetMask)+off)
+	return err
+}
+
+// ReadCompressed decompresses the cluster described by "e".
+func (q *qcow2) readCompressed(p []byte, e uint64, off int64) error {
+	x := 62 - (q.clusterBits - 8)
+	host := int64(e & (1<<x - 1))
+	sectors := int64((e>>x)&(1<<(q.clusterBits-8)-1)) + 1
+	n := sectors*512 - host&511
+	buf := make([]byte, n)
+	if _, err := q.f.ReadAt(buf, host); err != nil && err != io.EOF {
+		return err
+	}
+	out := make([]byte, q.clusterSize)
+	zr := flate.NewReader(bytes.NewReader(buf))
+	defer zr.Close()
+	if _, err := io.ReadFull(zr, out); err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("decompressing cluster: %w", err)
+	}
+	copy(p, out[off:])
+	return nil
+}
+
+func zero(p []byte) {
+	for i := range p {
+		p[i] = 0
+	}
+}
@@ -0,0 +1,502 @@
+package vmimage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/quay/zlog"
+)
+
+// MkRoot populates a directory with a small root filesystem and returns the
+// expected contents of the regular files and symlinks in it.
+func mkRoot(t *testing.T) (string, map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	want := map[string]string{
+		"etc/os-release": "ID=test\n",
+		"usr/bin/rpm":    "#!/bin/false\n",
+	}
+	// Big enough to need indirect blocks or multiple extents.
+	big := make([]byte, 300*1024)
+	rand.New(rand.NewSource(1)).Read(big)
+	want["var/lib/big"] = string(big)
+	// Enough entries to need multi-block directories.
+	for i := 0; i < 300; i++ {
+		want[fmt.Sprintf("usr/share/many/file-with-a-long-name-%03d", i)] = fmt.Sprint(i)
+	}
+	for n, c := range want {
+		p := filepath.Join(root, filepath.FromSlash(n))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("usr/bin", filepath.Join(root, "bin")); err != nil {
+		t.Fatal(err)
+	}
+	want["bin"] = "->usr/bin"
+	return root, want
+}
+
+// MkExt writes a filesystem image of the given type containing "dir",
+// starting at "offset" within the file. If offset is non-zero, an MBR is
+// written describing a single partition.
+func mkExt(t *testing.T, typ, dir string, offset int64) string {
+	t.Helper()
+	if _, err := exec.LookPath("mke2fs"); err != nil {
+		t.Skip("mke2fs not found")
+	}
+	const size = 16 << 20
+	img := filepath.Join(t.TempDir(), "disk.raw")
+	f, err := os.Create(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(offset + size); err != nil {
+		t.Fatal(err)
+	}
+	if offset != 0 {
+		mbr := make([]byte, sectorSize)
+		e := mbr[446:]
+		e[4] = 0x83
+		binary.LittleEndian.PutUint32(e[8:], uint32(offset/sectorSize))
+		binary.LittleEndian.PutUint32(e[12:], uint32(size/sectorSize))
+		mbr[510], mbr[511] = 0x55, 0xaa
+		if _, err := f.WriteAt(mbr, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("mke2fs", "-q", "-F", "-t", typ, "-b", "1024",
+		"-E", fmt.Sprintf("offset=%d", offset), "-d", dir, img, fmt.Sprint(size/1024))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("mke2fs: %v\n%s", err, out)
+	}
+	return img
+}
+
+// ToQcow2 converts a raw image into a qcow2 image, compressing every other
+// populated cluster.
+func toQcow2(t *testing.T, raw string) string {
+	t.Helper()
+	in, err := os.ReadFile(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const bits = 16
+	const cs = 1 << bits
+	nc := (len(in) + cs - 1) / cs
+	l2n := cs / 8
+	l1n := (nc + l2n - 1) / l2n
+
+	var out bytes.Buffer
+	// Header, then the L1 table, then the L2 tables, then data.
+	hdr := make([]byte, cs)
+	copy(hdr, qcowMagic)
+	be := binary.BigEndian
+	be.PutUint32(hdr[4:], 2)
+	be.PutUint32(hdr[20:], bits)
+	be.PutUint64(hdr[24:], uint64(len(in)))
+	be.PutUint32(hdr[36:], uint32(l1n))
+	be.PutUint64(hdr[40:], cs)
+	out.Write(hdr)
+	l1 := make([]byte, cs)
+	out.Write(l1)
+	l2 := make([]byte, l1n*cs)
+	l2Off := int64(2 * cs)
+	for i := 0; i < l1n; i++ {
+		be.PutUint64(l1[8*i:], uint64(l2Off+int64(i*cs)))
+	}
+	out.Write(l2)
+	var data bytes.Buffer
+	dataOff := l2Off + int64(len(l2))
+	zeros := make([]byte, cs)
+	for c := 0; c < nc; c++ {
+		chunk := make([]byte, cs)
+		copy(chunk, in[c*cs:])
+		if bytes.Equal(chunk, zeros) {
+			continue
+		}
+		host := dataOff + int64(data.Len())
+		var e uint64
+		if c%2 == 0 {
+			// Uncompressed clusters must be cluster-aligned.
+			if pad := host % cs; pad != 0 {
+				data.Write(zeros[:cs-pad])
+				host += cs - pad
+			}
+			data.Write(chunk)
+			e = uint64(host)
+		} else {
+			var z bytes.Buffer
+			zw, _ := flate.NewWriter(&z, flate.BestSpeed)
+			zw.Write(chunk)
+			zw.Close()
+			data.Write(z.Bytes())
+			x := uint(62 - (bits - 8))
+			sectors := (host&511 + int64(z.Len()) + 511) / 512
+			e = qcowCompressed | uint64(sectors-1)<<x | uint64(host)
+		}
+		be.PutUint64(l2[8*c:], e)
+	}
+	b := out.Bytes()
+	copy(b[cs:], l1)
+	copy(b[2*cs:], l2)
+	name := filepath.Join(t.TempDir(), "disk.qcow2")
+	if err := os.WriteFile(name, append(b, data.Bytes()...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestWriteLayer(t *testing.T) {
+	root, want := mkRoot(t)
+	tt := []struct {
+		Name string
+		Img  func(*testing.T) string
+	}{
+		{
+			Name: "ext4",
+			Img:  func(t *testing.T) string { return mkExt(t, "ext4", root, 0) },
+		},
+		{
+			Name: "ext2",
+			Img:  func(t *testing.T) string { return mkExt(t, "ext2", root, 0) },
+		},
+		{
+			Name: "MBR",
+			Img:  func(t *testing.T) string { return mkExt(t, "ext4", root, 1<<20) },
+		},
+		{
+			Name: "Qcow2",
+			Img:  func(t *testing.T) string { return toQcow2(t, mkExt(t, "ext4", root, 1<<20)) },
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(context.Background(), t)
+			img, err := Open(tc.Img(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer img.Close()
+
+			var buf bytes.Buffer
+			if err := WriteLayer(ctx, &buf, img); err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			tr := tar.NewReader(&buf)
+			for {
+				h, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				switch h.Typeflag {
+				case tar.TypeReg:
+					b, err := io.ReadAll(tr)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got[h.Name] = string(b)
+				case tar.TypeSymlink:
+					got[h.Name] = "->" + h.Linkname
+				}
+			}
+			for n, c := range want {
+				g, ok := got[n]
+				switch {
+				case !ok:
+					t.Errorf("missing %q", n)
+				case g != c:
+					t.Errorf("%q: contents differ (got %d bytes, want %d)", n, len(g), len(c))
+				}
+			}
+		})
+	}
+}
+
+func TestFS(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	root, _ := mkRoot(t)
+	img, err := Open(mkExt(t, "ext4", root, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer img.Close()
+	ms, err := Mounts(ctx, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].Path != "/" || ms[0].Filesystem != "ext4" {
+		t.Fatalf("unexpected mounts: %+v", ms)
+	}
+	fsys := ms[0].FS
+	// Resolving through a symlink.
+	b, err := fsReadFile(fsys, "bin/rpm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "#!/bin/false\n"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, err := fsys.ReadLink("bin"); err != nil || got != "usr/bin" {
+		t.Errorf("got: %q, %v", got, err)
+	}
+	if _, err := fsys.Stat("nonexistent"); !os.IsNotExist(err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func fsReadFile(fsys FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func TestXfsExtent(t *testing.T) {
+	// startoff=5, startblock=0x123456789, blockcount=7, unwritten
+	const off, blk, n = 5, 0x123456789, 7
+	hi := uint64(1)<<63 | uint64(off)<<9 | uint64(blk)>>43
+	lo := uint64(blk)<<21 | n
+	e := xfsExtent(hi, lo)
+	if e.logical != off || e.physical != blk || e.length != n || !e.zero {
+		t.Errorf("got: %+v", e)
+	}
+}
+
+// MkBadExt returns a tiny ext2 image with 1 KiB blocks and a root directory
+// inode, after calling "edit" with the superblock and the root inode.
+func mkBadExt(edit func(sb, root []byte)) []byte {
+	const bs = 1024
+	img := make([]byte, 64*bs)
+	le := binary.LittleEndian
+	sb := img[extSuperOffset:]
+	le.PutUint16(sb[56:], extMagic)
+	le.PutUint32(sb[20:], 1)  // First data block.
+	le.PutUint32(sb[40:], 16) // Inodes per group.
+	// The single group descriptor's inode table is at block 5.
+	le.PutUint32(img[2*bs+8:], 5)
+	root := img[5*bs+(extRootIno-1)*128:][:128]
+	le.PutUint16(root[0:], sIFDIR|0755)
+	edit(sb, root)
+	return img
+}
+
+func TestExtMalformed(t *testing.T) {
+	le := binary.LittleEndian
+	tt := []struct {
+		Name string
+		Edit func(sb, root []byte)
+	}{
+		{
+			Name: "NegativeSize",
+			Edit: func(_, root []byte) {
+				le.PutUint32(root[4:], 10)
+				le.PutUint32(root[0x6c:], 0x80000000)
+			},
+		},
+		{
+			Name: "HugeBlockSize",
+			Edit: func(sb, _ []byte) { le.PutUint32(sb[24:], 54) },
+		},
+		{
+			Name: "ShortDescriptor",
+			Edit: func(sb, _ []byte) {
+				le.PutUint32(sb[96:], extIncompat64Bit)
+				le.PutUint16(sb[254:], 4)
+			},
+		},
+		{
+			Name: "InlineData",
+			Edit: func(_, root []byte) {
+				le.PutUint32(root[4:], 100)
+				le.PutUint32(root[0x20:], extInodeInlineData)
+			},
+		},
+		{
+			Name: "HugeDirectory",
+			Edit: func(_, root []byte) { le.PutUint32(root[0x6c:], 1) },
+		},
+		{
+			// Every indirect block points back at itself, describing far
+			// more blocks than the image has.
+			Name: "BlockMapLoop",
+			Edit: func(sb, root []byte) {
+				le.PutUint32(root[4:], 1<<30)
+				le.PutUint32(root[0x28+4*14:], 10)
+				blk := sb[9*1024:][:1024]
+				for i := 0; i < len(blk); i += 4 {
+					le.PutUint32(blk[i:], 10)
+				}
+			},
+		},
+		{
+			Name: "ExtentLoop",
+			Edit: func(sb, root []byte) {
+				le.PutUint32(root[4:], 1<<30)
+				le.PutUint32(root[0x20:], extInodeExtents)
+				// Index nodes whose entries all point at block 10, which
+				// is another such node.
+				node := func(b []byte, n, max int) {
+					le.PutUint16(b[0:], extExtentMagic)
+					le.PutUint16(b[2:], uint16(n))
+					le.PutUint16(b[4:], uint16(max))
+					le.PutUint16(b[6:], 1)
+					for i := 0; i < n; i++ {
+						le.PutUint32(b[12+12*i+4:], 10)
+					}
+				}
+				node(root[0x28:], 4, 4)
+				node(sb[9*1024:], 84, 84)
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			img := mkBadExt(tc.Edit)
+			r := bytes.NewReader(img)
+			if !(ext4{}).Probe(r) {
+				t.Fatal("image not recognized")
+			}
+			fsys, err := ext4{}.Open(r, int64(len(img)))
+			if err != nil {
+				t.Log(err)
+				return
+			}
+			_, err = fsys.ReadDir(".")
+			t.Log(err)
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// TestCorrupt flips random bytes in the metadata of a real image and checks
+// that reading it fails gracefully, if at all.
+func TestCorrupt(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	root, _ := mkRoot(t)
+	b, err := os.ReadFile(mkExt(t, "ext4", root, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The superblock, group descriptors, and the start of the inode tables
+	// are in the first few hundred KiB.
+	const meta = 512 << 10
+	rng := rand.New(rand.NewSource(1))
+	img := make([]byte, len(b))
+	for i := 0; i < 200; i++ {
+		copy(img, b)
+		for j := 0; j < 32; j++ {
+			img[extSuperOffset+rng.Intn(meta)] = byte(rng.Int())
+		}
+		r := bytes.NewReader(img)
+		f := Probe(r)
+		if f == nil {
+			continue
+		}
+		fsys, err := f.Open(r, r.Size())
+		if err != nil {
+			continue
+		}
+		if err := writeTree(ctx, tar.NewWriter(io.Discard), fsys, ""); err != nil {
+			t.Logf("%d: %v", i, err)
+		}
+	}
+}
+
+func TestImplausibleTables(t *testing.T) {
+	write := func(t *testing.T, b []byte) string {
+		t.Helper()
+		name := filepath.Join(t.TempDir(), "disk")
+		if err := os.WriteFile(name, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	qcow := func(bits uint32, size uint64, l1n uint32) []byte {
+		const cs = 1 << 16
+		b := make([]byte, 2*cs)
+		copy(b, qcowMagic)
+		be := binary.BigEndian
+		be.PutUint32(b[4:], 2)
+		be.PutUint32(b[20:], bits)
+		be.PutUint64(b[24:], size)
+		be.PutUint32(b[36:], l1n)
+		be.PutUint64(b[40:], cs)
+		return b
+	}
+	t.Run("Qcow2", func(t *testing.T) {
+		tt := []struct {
+			Name string
+			Bits uint32
+			Size uint64
+			L1   uint32
+			OK   bool
+		}{
+			{Name: "Valid", Bits: 16, Size: 1 << 20, L1: 1, OK: true},
+			{Name: "LargeL1", Bits: 16, Size: 1 << 20, L1: 1<<32 - 1, OK: true},
+			{Name: "SmallL1", Bits: 16, Size: 1 << 40, L1: 1},
+			{Name: "HugeSize", Bits: 9, Size: 1 << 62, L1: 1<<32 - 1},
+			{Name: "NegativeSize", Bits: 16, Size: 1 << 63, L1: 1<<32 - 1},
+		}
+		for _, tc := range tt {
+			t.Run(tc.Name, func(t *testing.T) {
+				img, err := Open(write(t, qcow(tc.Bits, tc.Size, tc.L1)))
+				if err == nil {
+					img.Close()
+				}
+				t.Log(err)
+				if got, want := err == nil, tc.OK; got != want {
+					t.Errorf("got: %v, want: %v", got, want)
+				}
+			})
+		}
+	})
+	t.Run("GPT", func(t *testing.T) {
+		for _, sz := range []uint32{0, 100, 129, 8192, 1 << 31} {
+			b := make([]byte, 4*sectorSize)
+			e := b[446:]
+			e[4] = 0xee
+			b[510], b[511] = 0x55, 0xaa
+			h := b[sectorSize:]
+			copy(h, gptSignature)
+			le := binary.LittleEndian
+			le.PutUint64(h[72:], 2)
+			le.PutUint32(h[80:], 128)
+			le.PutUint32(h[84:], sz)
+			img, err := Open(write(t, b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Partitions(img)
+			img.Close()
+			t.Logf("%d: %v", sz, err)
+			if err == nil {
+				t.Errorf("%d: unexpected success", sz)
+			}
+		}
+	})
+}
@@ -0,0 +1,418 @@
+package vmimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Xfs reads XFS filesystems (v4 and v5).
+//
+// Realtime devices and external logs are not supported.
+type xfs struct{}
+
+func (xfs) Name() string { return "xfs" }
+
+var xfsMagic = []byte("XFSB")
+
+// Inode data fork formats.
+const (
+	xfsFmtDev     = 0
+	xfsFmtLocal   = 1
+	xfsFmtExtents = 2
+	xfsFmtBtree   = 3
+)
+
+const (
+	xfsFtypeV4 = 0x200 // sb_features2 bit
+	xfsFtypeV5 = 0x1   // sb_features_incompat bit
+	// XfsDirLeafOffset is the byte offset in a directory where the leaf
+	// (hash index) blocks start. Only the data blocks before it are read.
+	xfsDirLeafOffset = 32 << 30
+)
+
+func (xfs) Probe(r io.ReaderAt) bool {
+	var b [4]byte
+	if _, err := r.ReadAt(b[:], 0); err != nil {
+		return false
+	}
+	return bytes.Equal(b[:], xfsMagic)
+}
+
+func (xfs) Open(r io.ReaderAt, size int64) (FS, error) {
+	sb := make([]byte, 512)
+	if _, err := r.ReadAt(sb, 0); err != nil {
+		return nil, fmt.Errorf("vmimage: xfs: reading superblock: %w", err)
+	}
+	if !bytes.Equal(sb[:4], xfsMagic) {
+		return nil, fmt.Errorf("vmimage: xfs: bad magic")
+	}
+	be := binary.BigEndian
+	t := &xfsTree{
+		r:         r,
+		blockSize: int64(be.Uint32(sb[4:])),
+		rootIno:   be.Uint64(sb[56:]),
+		agBlocks:  int64(be.Uint32(sb[84:])),
+		inodeSize: int64(be.Uint16(sb[104:])),
+		inopbLog:  uint(sb[123]),
+		agBlkLog:  uint(sb[124]),
+		dirBlkLog: uint(sb[192]),
+		v5:        be.Uint16(sb[100:])&0xf == 5,
+	}
+	copy(t.id[:], sb[32:48])
+	if t.blockSize > 0 {
+		t.blocks = size / t.blockSize
+	}
+	if t.v5 {
+		t.ftype = be.Uint32(sb[216:])&xfsFtypeV5 != 0
+	} else {
+		t.ftype = be.Uint32(sb[200:])&xfsFtypeV4 != 0
+	}
+	if t.blockSize < 512 || t.blockSize > 65536 || t.inodeSize < 256 && t.v5 || t.inodeSize < 128 {
+		return nil, fmt.Errorf("vmimage: xfs: implausible superblock")
+	}
+	return &treeFS{t: t}, nil
+}
+
+// XfsTree implements tree for XFS.
+type xfsTree struct {
+	r         io.ReaderAt
+	blockSize int64
+	rootIno   uint64
+	agBlocks  int64
+	inodeSize int64
+	inopbLog  uint
+	agBlkLog  uint
+	dirBlkLog uint
+	v5        bool
+	ftype     bool
+	id        [16]byte
+	// Blocks is the number of blocks in the filesystem.
+	blocks int64
+}
+
+func (t *xfsTree) root() uint64   { return t.rootIno }
+func (t *xfsTree) uuid() [16]byte { return t.id }
+
+// FsbToOffset converts a filesystem block number (AG number and AG block
+// packed together) to a byte offset.
+func (t *xfsTree) fsbToOffset(fsb uint64) int64 {
+	ag := int64(fsb >> t.agBlkLog)
+	bno := int64(fsb & (1<<t.agBlkLog - 1))
+	return (ag*t.agBlocks + bno) * t.blockSize
+}
+
+func (t *xfsTree) inode(ino uint64) (*inode, error) {
+	fsb := ino >> t.inopbLog
+	idx := int64(ino & (1<<t.inopbLog - 1))
+	b := make([]byte, t.inodeSize)
+	if _, err := t.r.ReadAt(b, t.fsbToOffset(fsb)+idx*t.inodeSize); err != nil {
+		return nil, fmt.Errorf("reading inode %d: %w", ino, err)
+	}
+	be := binary.BigEndian
+	if b[0] != 'I' || b[1] != 'N' {
+		return nil, fmt.Errorf("inode %d: bad magic", ino)
+	}
+	i := &inode{
+		ino:   ino,
+		mode:  be.Uint16(b[2:]),
+		size:  int64(be.Uint64(b[56:])),
+		mtime: time.Unix(int64(int32(be.Uint32(b[40:]))), int64(be.Uint32(b[44:]))),
+	}
+	switch {
+	case i.size < 0:
+		return nil, fmt.Errorf("inode %d: implausible size %d", ino, i.size)
+	case i.mode&sIFMT == sIFREG && i.size > t.blocks*t.blockSize:
+		// Sparse files can be this large, but copying out all the zeros
+		// isn't useful.
+		return nil, fmt.Errorf("%w: inode %d: larger than filesystem", ErrUnsupported, ino)
+	}
+	core := 100
+	if b[4] == 3 {
+		core = 176
+	}
+	fork := b[core:]
+	if off := int(b[82]) * 8; off != 0 && off < len(fork) {
+		fork = fork[:off]
+	}
+	nextents := int(be.Uint32(b[76:]))
+	switch format := b[5]; {
+	case i.mode&sIFMT != sIFREG && i.mode&sIFMT != sIFDIR && i.mode&sIFMT != sIFLNK:
+		i.size = 0
+		i.data = bytes.NewReader(nil)
+	case format == xfsFmtLocal:
+		if i.size > int64(len(fork)) {
+			return nil, fmt.Errorf("inode %d: local data larger than fork", ino)
+		}
+		i.data = bytes.NewReader(append([]byte(nil), fork[:i.size]...))
+	case format == xfsFmtExtents:
+		if 16*nextents > len(fork) {
+			return nil, fmt.Errorf("inode %d: bad extent count", ino)
+		}
+		exts, err := t.extentList(fork, nextents)
+		if err != nil {
+			return nil, fmt.Errorf("inode %d: %w", ino, err)
+		}
+		i.data = t.reader(i, exts)
+	case format == xfsFmtBtree:
+		exts, err := t.btree(fork)
+		if err != nil {
+			return nil, fmt.Errorf("inode %d: %w", ino, err)
+		}
+		i.data = t.reader(i, exts)
+	default:
+		return nil, fmt.Errorf("%w: xfs inode %d: data fork format %d", ErrUnsupported, ino, format)
+	}
+	return i, nil
+}
+
+// Reader returns an io.ReaderAt over the extents. Remote symlinks on v5
+// filesystems carry a header in every block, which is stripped.
+func (t *xfsTree) reader(i *inode, exts []extent) io.ReaderAt {
+	r := &extentReader{r: t.r, bs: t.blockSize, size: i.size, exts: exts}
+	if i.mode&sIFMT != sIFLNK || !t.v5 {
+		return r
+	}
+	r.size = 0
+	for _, e := range exts {
+		if end := (e.logical + e.length) * t.blockSize; end > r.size {
+			r.size = end
+		}
+	}
+	var out []byte
+	b := make([]byte, t.blockSize)
+	const hdr = 56
+	for off := int64(0); off < r.size && int64(len(out)) < i.size; off += t.blockSize {
+		if _, err := r.ReadAt(b, off); err != nil && err != io.EOF {
+			break
+		}
+		out = append(out, b[hdr:]...)
+	}
+	if int64(len(out)) > i.size {
+		out = out[:i.size]
+	}
+	return bytes.NewReader(out)
+}
+
+// ExtentList decodes "n" packed extent records.
+func (t *xfsTree) extentList(b []byte, n int) ([]extent, error) {
+	be := binary.BigEndian
+	out := make([]extent, 0, n)
+	for i := 0; i < n; i++ {
+		hi := be.Uint64(b[16*i:])
+		lo := be.Uint64(b[16*i+8:])
+		e := xfsExtent(hi, lo)
+		e.physical = t.fsbToOffset(uint64(e.physical)) / t.blockSize
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// XfsExtent unpacks an extent record. The physical block is returned as a
+// filesystem block number.
+func xfsExtent(hi, lo uint64) extent {
+	return extent{
+		zero:     hi>>63 != 0,
+		logical:  int64((hi & (1<<63 - 1)) >> 9),
+		physical: int64((hi&0x1ff)<<43 | lo>>21),
+		length:   int64(lo & (1<<21 - 1)),
+	}
+}
+
+// Btree walks a data fork in btree format, returning all the leaf extents.
+func (t *xfsTree) btree(fork []byte) ([]extent, error) {
+	be := binary.BigEndian
+	if len(fork) < 4 {
+		return nil, fmt.Errorf("short btree root")
+	}
+	level := be.Uint16(fork[0:])
+	n := int(be.Uint16(fork[2:]))
+	max := (len(fork) - 4) / 16
+	if level == 0 || n > max {
+		return nil, fmt.Errorf("bad btree root")
+	}
+	var out []extent
+	for i := 0; i < n; i++ {
+		ptr := be.Uint64(fork[4+8*max+8*i:])
+		exts, err := t.btreeBlock(ptr, int(level)-1)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, exts...)
+	}
+	return out, nil
+}
+
+func (t *xfsTree) btreeBlock(fsb uint64, level int) ([]extent, error) {
+	be := binary.BigEndian
+	b := make([]byte, t.blockSize)
+	if _, err := t.r.ReadAt(b, t.fsbToOffset(fsb)); err != nil {
+		return nil, err
+	}
+	hdr := 24
+	magic := "BMAP"
+	if t.v5 {
+		hdr, magic = 72, "BMA3"
+	}
+	if string(b[:4]) != magic {
+		return nil, fmt.Errorf("bad btree block magic")
+	}
+	if int(be.Uint16(b[4:])) != level {
+		return nil, fmt.Errorf("btree level mismatch")
+	}
+	n := int(be.Uint16(b[6:]))
+	if level == 0 {
+		if hdr+16*n > len(b) {
+			return nil, fmt.Errorf("bad btree record count")
+		}
+		return t.extentList(b[hdr:], n)
+	}
+	max := (len(b) - hdr) / 16
+	if n > max {
+		return nil, fmt.Errorf("bad btree record count")
+	}
+	var out []extent
+	for i := 0; i < n; i++ {
+		ptr := be.Uint64(b[hdr+8*max+8*i:])
+		exts, err := t.btreeBlock(ptr, level-1)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, exts...)
+	}
+	return out, nil
+}
+
+func (t *xfsTree) readDir(i *inode) ([]dirent, error) {
+	if _, ok := i.data.(*bytes.Reader); ok {
+		return t.shortformDir(i)
+	}
+	er, ok := i.data.(*extentReader)
+	if !ok {
+		return nil, fmt.Errorf("inode %d: unexpected directory format", i.ino)
+	}
+	dbs := t.blockSize << t.dirBlkLog
+	// Only the data blocks are needed; they're before the leaf offset.
+	end := i.size
+	if end > xfsDirLeafOffset {
+		end = xfsDirLeafOffset
+	}
+	var out []dirent
+	b := make([]byte, dbs)
+	for _, e := range er.exts {
+		start := e.logical * t.blockSize
+		stop := (e.logical + e.length) * t.blockSize
+		if stop > end {
+			stop = end
+		}
+		for off := start - start%dbs; off < stop; off += dbs {
+			if _, err := er.ReadAt(b, off); err != nil && err != io.EOF {
+				return nil, err
+			}
+			ents, err := t.dataBlock(b)
+			if err != nil {
+				return nil, fmt.Errorf("inode %d: %w", i.ino, err)
+			}
+			out = append(out, ents...)
+		}
+	}
+	return out, nil
+}
+
+// DataBlock parses a directory data block (or single-block directory).
+func (t *xfsTree) dataBlock(b []byte) ([]dirent, error) {
+	be := binary.BigEndian
+	var hdr int
+	var block bool
+	switch string(b[:4]) {
+	case "XD2B":
+		hdr, block = 16, true
+	case "XD2D":
+		hdr = 16
+	case "XDB3":
+		hdr, block = 64, true
+	case "XDD3":
+		hdr = 64
+	default:
+		// Leaf and free-index blocks share the directory's extent list when
+		// they're contiguous with data blocks; skip them.
+		return nil, nil
+	}
+	end := len(b)
+	if block {
+		// The block tail holds the leaf entry count; the leaf entries sit
+		// between the data and the tail.
+		count := int(be.Uint32(b[len(b)-8:]))
+		end = len(b) - 8 - 8*count
+		if end < hdr {
+			return nil, fmt.Errorf("bad directory block tail")
+		}
+	}
+	var out []dirent
+	for off := hdr; off+8 <= end; {
+		if be.Uint16(b[off:]) == 0xffff {
+			l := int(be.Uint16(b[off+2:]))
+			if l < 8 || l%8 != 0 {
+				return nil, fmt.Errorf("bad unused directory entry")
+			}
+			off += l
+			continue
+		}
+		ino := be.Uint64(b[off:])
+		nl := int(b[off+8])
+		if off+9+nl > end {
+			return nil, fmt.Errorf("bad directory entry")
+		}
+		out = append(out, dirent{name: string(b[off+9 : off+9+nl]), ino: ino})
+		sz := 8 + 1 + nl + 2
+		if t.ftype {
+			sz++
+		}
+		off += (sz + 7) &^ 7
+	}
+	return out, nil
+}
+
+// ShortformDir parses a directory stored in the inode.
+func (t *xfsTree) shortformDir(i *inode) ([]dirent, error) {
+	b, err := readAll(i)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 6 {
+		return nil, fmt.Errorf("inode %d: short directory", i.ino)
+	}
+	be := binary.BigEndian
+	count := int(b[0])
+	isz := 4
+	if b[1] != 0 {
+		count = int(b[1])
+		isz = 8
+	}
+	readIno := func(p []byte) uint64 {
+		if isz == 8 {
+			return be.Uint64(p)
+		}
+		return uint64(be.Uint32(p))
+	}
+	off := 2 + isz
+	out := []dirent{{name: "..", ino: readIno(b[2:])}}
+	for n := 0; n < count; n++ {
+		if off+3 > len(b) {
+			return nil, fmt.Errorf("inode %d: truncated directory", i.ino)
+		}
+		nl := int(b[off])
+		p := off + 3 + nl
+		if t.ftype {
+			p++
+		}
+		if p+isz > len(b) {
+			return nil, fmt.Errorf("inode %d: truncated directory", i.ino)
+		}
+		out = append(out, dirent{name: string(b[off+3 : off+3+nl]), ino: readIno(b[p:])})
+		off = p + isz
+	}
+	return out, nil
+}
@@ -0,0 +1,271 @@
+// Package webhook turns registry push notifications into index requests, so
+// images can be indexed as they're pushed rather than on demand.
+//
+// The webhook payloads sent by Harbor (version 2), Quay, and Docker Hub are
+// understood. A Handler receives the notifications and passes the pushed
+// images to a function, such as one returned by Index:
+//
+//	h := &webhook.Handler{
+//		Secret: secret,
+//		Handle: webhook.Index(lib, nil),
+//	}
+//	http.Handle("/webhook", h)
+//
+// Notifications only name the pushed image; its manifest is retrieved from
+// the registry when it's indexed.
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/jsonerr"
+	"github.com/quay/claircore/pkg/registry"
+)
+
+// Source is the kind of registry a notification came from.
+type Source string
+
+// Known Sources.
+const (
+	Harbor    Source = "harbor"
+	Quay      Source = "quay"
+	DockerHub Source = "dockerhub"
+)
+
+// Event is an image push described by a notification.
+type Event struct {
+	Source Source
+	// Reference names the pushed image. Its Digest is set if the
+	// notification reported one, in which case it's used instead of the Tag.
+	Reference registry.Reference
+}
+
+// ErrUnknownPayload is returned by Parse for payloads that aren't in a known
+// format.
+var ErrUnknownPayload = errors.New("webhook: unknown payload")
+
+// MaxPayload is the largest payload a Handler reads.
+const maxPayload = 1 << 20
+
+// Payload has the fields of all the known payloads used to tell them apart.
+type payload struct {
+	// Harbor
+	Type      string `json:"type"`
+	EventData *struct {
+		Resources []struct {
+			Digest      string `json:"digest"`
+			Tag         string `json:"tag"`
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+	} `json:"event_data"`
+	// Quay
+	DockerURL   string   `json:"docker_url"`
+	UpdatedTags []string `json:"updated_tags"`
+	// Docker Hub
+	PushData *struct {
+		Tag string `json:"tag"`
+	} `json:"push_data"`
+	// Quay sends the repository's name as a string; Docker Hub sends an
+	// object.
+	Repository json.RawMessage `json:"repository"`
+}
+
+// Parse returns the pushes described by a notification's payload.
+//
+// Notifications about anything other than pushes, such as Harbor's scan and
+// deletion events, are valid but have no Events. Payloads that aren't in a
+// known format report an error wrapping ErrUnknownPayload.
+func Parse(b []byte) ([]Event, error) {
+	var p payload
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownPayload, err)
+	}
+	switch {
+	case p.EventData != nil && p.Type != "":
+		return parseHarbor(&p)
+	case p.DockerURL != "":
+		return parseQuay(&p)
+	case p.PushData != nil:
+		return parseDockerHub(&p)
+	}
+	return nil, ErrUnknownPayload
+}
+
+// ParseHarbor handles Harbor's payloads. Only "PUSH_ARTIFACT" events are
+// pushes.
+func parseHarbor(p *payload) ([]Event, error) {
+	if p.Type != "PUSH_ARTIFACT" {
+		return nil, nil
+	}
+	var out []Event
+	for _, res := range p.EventData.Resources {
+		r, err := registry.ParseReference(res.ResourceURL)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: harbor: %w", err)
+		}
+		if res.Digest != "" {
+			d, err := claircore.ParseDigest(res.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("webhook: harbor: %w", err)
+			}
+			r.Digest = d.String()
+		}
+		out = append(out, Event{Source: Harbor, Reference: r})
+	}
+	return out, nil
+}
+
+// ParseQuay handles Quay's "repo_push" payloads, which report the tags
+// updated but not the digests.
+func parseQuay(p *payload) ([]Event, error) {
+	out := make([]Event, 0, len(p.UpdatedTags))
+	for _, t := range p.UpdatedTags {
+		r, err := registry.ParseReference(p.DockerURL + ":" + t)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: quay: %w", err)
+		}
+		out = append(out, Event{Source: Quay, Reference: r})
+	}
+	return out, nil
+}
+
+// ParseDockerHub handles Docker Hub's payloads, which report the tag pushed.
+func parseDockerHub(p *payload) ([]Event, error) {
+	var repo struct {
+		RepoName string `json:"repo_name"`
+	}
+	if err := json.Unmarshal(p.Repository, &repo); err != nil || repo.RepoName == "" {
+		return nil, fmt.Errorf("%w: docker hub: missing repository", ErrUnknownPayload)
+	}
+	tag := p.PushData.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	r, err := registry.ParseReference(repo.RepoName + ":" + tag)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: docker hub: %w", err)
+	}
+	return []Event{{Source: DockerHub, Reference: r}}, nil
+}
+
+// Indexer indexes images by reference. *libindex.Libindex implements
+// Indexer.
+type Indexer interface {
+	IndexReference(context.Context, string, *claircore.Platform) (*claircore.IndexReport, error)
+}
+
+// Index returns a function for Handler.Handle that indexes each pushed image
+// for the platform, or registry.DefaultPlatform if nil.
+//
+// Indexing happens before the notification is answered. Registries give up
+// on slow webhooks, so for large images callers may want to queue Events and
+// index them elsewhere instead.
+func Index(idx Indexer, p *claircore.Platform) func(context.Context, Event) error {
+	return func(ctx context.Context, ev Event) error {
+		ir, err := idx.IndexReference(ctx, ev.Reference.String(), p)
+		if err != nil {
+			return err
+		}
+		if !ir.Success {
+			return fmt.Errorf("webhook: indexing %v failed: %s", ev.Reference, ir.Err)
+		}
+		zlog.Info(ctx).
+			Stringer("manifest", ir.Hash).
+			Msg("indexed pushed image")
+		return nil
+	}
+}
+
+// Handler is an http.Handler receiving registry notifications.
+//
+// Notifications with pushes are answered with "202 Accepted" once Handle has
+// returned for each of them, and others with "204 No Content".
+type Handler struct {
+	// Secret, if set, must match the notification's "Authorization" header
+	// exactly. Harbor sends its configured "auth header" there; for Quay and
+	// Docker Hub, which can't set headers, use HTTP basic auth in the
+	// webhook's URL and set Secret to the resulting header value.
+	Secret string
+	// Handle is called with each push in a notification. If it returns an
+	// error, the remaining pushes are skipped and the notification is
+	// answered with an error, so the registry may retry it.
+	Handle func(context.Context, Event) error
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := baggage.ContextWithValues(r.Context(),
+		label.String("component", "pkg/webhook/Handler.ServeHTTP"))
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		resp := &jsonerr.Response{
+			Code:    "method-not-allowed",
+			Message: "endpoint only allows POST",
+		}
+		jsonerr.Error(w, resp, http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Secret != "" &&
+		subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(h.Secret)) != 1 {
+		resp := &jsonerr.Response{
+			Code:    "unauthorized",
+			Message: "missing or incorrect authorization",
+		}
+		jsonerr.Error(w, resp, http.StatusUnauthorized)
+		return
+	}
+	b, err := io.ReadAll(io.LimitReader(r.Body, maxPayload+1))
+	if err == nil && len(b) > maxPayload {
+		err = errors.New("payload too large")
+	}
+	if err != nil {
+		resp := &jsonerr.Response{
+			Code:    "bad-request",
+			Message: err.Error(),
+		}
+		jsonerr.Error(w, resp, http.StatusBadRequest)
+		return
+	}
+	evs, err := Parse(b)
+	if err != nil {
+		resp := &jsonerr.Response{
+			Code:    "bad-request",
+			Message: err.Error(),
+		}
+		jsonerr.Error(w, resp, http.StatusBadRequest)
+		return
+	}
+	if len(evs) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	for _, ev := range evs {
+		ctx := baggage.ContextWithValues(ctx,
+			label.String("source", string(ev.Source)),
+			label.Stringer("reference", ev.Reference))
+		zlog.Debug(ctx).Msg("received push")
+		if err := h.Handle(ctx, ev); err != nil {
+			zlog.Warn(ctx).Err(err).Msg("unable to handle push")
+			resp := &jsonerr.Response{
+				Code:    "internal-server-error",
+				Message: err.Error(),
+			}
+			jsonerr.Error(w, resp, http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/registry"
+)
+
+const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		Name    string
+		Payload string
+		Want    []Event
+	}{
+		{
+			Name: "Harbor",
+			Payload: `{"type":"PUSH_ARTIFACT","occur_at":1586922308,"operator":"admin","event_data":{
+				"resources":[{"digest":"` + digest + `","tag":"v1","resource_url":"harbor.example.com/library/app:v1"}],
+				"repository":{"name":"app","namespace":"library","repo_full_name":"library/app","repo_type":"private"}}}`,
+			Want: []Event{{
+				Source:    Harbor,
+				Reference: registry.Reference{Registry: "harbor.example.com", Repository: "library/app", Tag: "v1", Digest: digest},
+			}},
+		},
+		{
+			Name: "HarborScan",
+			Payload: `{"type":"SCANNING_COMPLETED","event_data":{
+				"resources":[{"digest":"` + digest + `","resource_url":"harbor.example.com/library/app:v1"}]}}`,
+		},
+		{
+			Name: "Quay",
+			Payload: `{"repository":"org/app","namespace":"org","name":"app",
+				"docker_url":"quay.io/org/app","homepage":"https://quay.io/repository/org/app",
+				"updated_tags":["latest","v2"]}`,
+			Want: []Event{
+				{Source: Quay, Reference: registry.Reference{Registry: "quay.io", Repository: "org/app", Tag: "latest"}},
+				{Source: Quay, Reference: registry.Reference{Registry: "quay.io", Repository: "org/app", Tag: "v2"}},
+			},
+		},
+		{
+			Name: "DockerHub",
+			Payload: `{"callback_url":"https://registry.hub.docker.com/u/user/app/hook/1/",
+				"push_data":{"pushed_at":1417566161,"pusher":"user","tag":"v3"},
+				"repository":{"repo_name":"user/app","name":"app","namespace":"user"}}`,
+			Want: []Event{{
+				Source:    DockerHub,
+				Reference: registry.Reference{Registry: "docker.io", Repository: "user/app", Tag: "v3"},
+			}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := Parse([]byte(tc.Payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.Want) {
+				t.Error(cmp.Diff(got, tc.Want))
+			}
+		})
+	}
+
+	for _, p := range []string{`{}`, `{"type":"PUSH_ARTIFACT"}`, `[]`, `{"push_data":{"tag":"x"}}`} {
+		if _, err := Parse([]byte(p)); !errors.Is(err, ErrUnknownPayload) {
+			t.Errorf("%s: got: %v, want: %v", p, err, ErrUnknownPayload)
+		}
+	}
+}
+
+type indexer struct {
+	refs []string
+	err  error
+}
+
+func (i *indexer) IndexReference(_ context.Context, ref string, _ *claircore.Platform) (*claircore.IndexReport, error) {
+	i.refs = append(i.refs, ref)
+	if i.err != nil {
+		return nil, i.err
+	}
+	return &claircore.IndexReport{Success: true}, nil
+}
+
+func TestHandler(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const push = `{"docker_url":"quay.io/org/app","updated_tags":["latest"]}`
+	tt := []struct {
+		Name    string
+		Method  string
+		Auth    string
+		Payload string
+		Err     error
+		Status  int
+		Refs    []string
+	}{
+		{Name: "Push", Auth: "secret", Payload: push, Status: http.StatusAccepted, Refs: []string{"quay.io/org/app:latest"}},
+		{Name: "Ignored", Auth: "secret", Payload: `{"type":"DELETE_ARTIFACT","event_data":{}}`, Status: http.StatusNoContent},
+		{Name: "Unauthorized", Auth: "wrong", Payload: push, Status: http.StatusUnauthorized},
+		{Name: "Method", Method: http.MethodGet, Auth: "secret", Status: http.StatusMethodNotAllowed},
+		{Name: "Unknown", Auth: "secret", Payload: `{"hello":"world"}`, Status: http.StatusBadRequest},
+		{Name: "Failed", Auth: "secret", Payload: push, Err: errors.New("oops"), Status: http.StatusInternalServerError, Refs: []string{"quay.io/org/app:latest"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			idx := &indexer{err: tc.Err}
+			h := &Handler{Secret: "secret", Handle: Index(idx, nil)}
+			m := tc.Method
+			if m == "" {
+				m = http.MethodPost
+			}
+			req := httptest.NewRequest(m, "/", strings.NewReader(tc.Payload)).WithContext(ctx)
+			req.Header.Set("Authorization", tc.Auth)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got, want := rec.Code, tc.Status; got != want {
+				t.Errorf("got: %d, want: %d", got, want)
+			}
+			if !cmp.Equal(idx.refs, tc.Refs) {
+				t.Error(cmp.Diff(idx.refs, tc.Refs))
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package claircore
+
+import "sort"
+
+// PolicyVerdict is the result of evaluating a policy against a
+// VulnerabilityReport.
+type PolicyVerdict struct {
+	// Policy is the name of the policy evaluated.
+	Policy string `json:"policy"`
+	// Pass reports whether the manifest is acceptable under the policy.
+	Pass bool `json:"pass"`
+	// Violations are the reasons the manifest isn't acceptable.
+	Violations []PolicyViolation `json:"violations,omitempty"`
+}
+
+// PolicyViolation is a single reason a manifest fails a policy.
+type PolicyViolation struct {
+	// Rule identifies the rule that was violated.
+	Rule string `json:"rule"`
+	// Message is a human-readable description of the violation.
+	Message string `json:"message,omitempty"`
+	// PackageID and VulnerabilityID are the IDs of the package and
+	// vulnerability in the VulnerabilityReport the violation concerns, if
+	// any.
+	PackageID       string `json:"package_id,omitempty"`
+	VulnerabilityID string `json:"vulnerability_id,omitempty"`
+}
+
+// Sort puts the Violations into a stable order.
+func (v *PolicyVerdict) Sort() {
+	sort.SliceStable(v.Violations, func(i, j int) bool {
+		a, b := &v.Violations[i], &v.Violations[j]
+		switch {
+		case a.Rule != b.Rule:
+			return a.Rule < b.Rule
+		case a.PackageID != b.PackageID:
+			return lessID(a.PackageID, b.PackageID)
+		default:
+			return lessID(a.VulnerabilityID, b.VulnerabilityID)
+		}
+	})
+}
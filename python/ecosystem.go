@@ -9,12 +9,16 @@ import (
 var scanners = []indexer.PackageScanner{&Scanner{}}
 var reposcanners = []indexer.RepositoryScanner{&RepoScanner{}}
 
-// NewEcosystem provides the set of scanners for the python ecosystem.
+// NewEcosystem provides the set of scanners for the python ecosystem, along
+// with the names of the updaters and matcher covering it.
 func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
 	return &indexer.Ecosystem{
+		Name:                 "python",
 		PackageScanners:      func(_ context.Context) ([]indexer.PackageScanner, error) { return scanners, nil },
 		DistributionScanners: func(_ context.Context) ([]indexer.DistributionScanner, error) { return nil, nil },
 		RepositoryScanners:   func(_ context.Context) ([]indexer.RepositoryScanner, error) { return reposcanners, nil },
 		Coalescer:            NewCoalescer,
+		UpdaterSets:          []string{"pyupio"},
+		Matchers:             []string{"python"},
 	}
 }
@@ -5,6 +5,7 @@ package python
 import (
 	"archive/tar"
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"path/filepath"
 	"runtime/trace"
 	"strings"
+	"unicode"
 
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
@@ -23,14 +25,16 @@ import (
 )
 
 var (
-	_ indexer.VersionedScanner = (*Scanner)(nil)
-	_ indexer.PackageScanner   = (*Scanner)(nil)
+	_ indexer.VersionedScanner   = (*Scanner)(nil)
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
 )
 
 // Scanner implements the scanner.PackageScanner interface.
 //
 // It looks for directories that seem like wheels or eggs, and looks at the
-// metadata recorded there.
+// metadata recorded there. Wheel archives themselves are also examined, and
+// their SHA256 recorded, as are zipapps such as PEX and shiv executables.
 //
 // The zero value is ready to use.
 type Scanner struct{}
@@ -39,11 +43,14 @@ type Scanner struct{}
 func (*Scanner) Name() string { return "python" }
 
 // Version implements scanner.VersionedScanner.
-func (*Scanner) Version() string { return "0.1.0" }
+func (*Scanner) Version() string { return "0.4.0" }
 
 // Kind implements scanner.VersionedScanner.
 func (*Scanner) Kind() string { return "package" }
 
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceDeclared }
+
 // Scan attempts to find wheel or egg info directories and record the package
 // information there.
 //
@@ -77,6 +84,7 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 	var ret []*claircore.Package
 	tr := tar.NewReader(rd)
 	var h *tar.Header
+	var buf bytes.Buffer
 	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
 		n, err := filepath.Rel("/", filepath.Join("/", h.Name))
 		if err != nil {
@@ -88,43 +96,116 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 			continue
 		case strings.HasSuffix(n, `.egg-info/PKG-INFO`):
 			zlog.Debug(ctx).Str("file", n).Msg("found egg")
+		case strings.HasSuffix(n, `.egg-info`):
+			// Distutils installs the metadata as a single file, named like
+			// the directory setuptools would create.
+			zlog.Debug(ctx).Str("file", n).Msg("found egg info file")
+			p := parseMetadata(ctx, n, tr)
+			if p == nil {
+				continue
+			}
+			p.PackageDB = "python:" + filepath.Dir(n)
+			ret = append(ret, p)
+			continue
 		case strings.HasSuffix(n, `.dist-info/METADATA`):
 			zlog.Debug(ctx).Str("file", n).Msg("found wheel")
+		case strings.HasSuffix(n, `.whl`):
+			zlog.Debug(ctx).Str("file", n).Msg("found wheel archive")
+			buf.Reset()
+			if _, err := buf.ReadFrom(tr); err != nil {
+				return nil, err
+			}
+			p, err := wheelPackage(ctx, n, buf.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			if p != nil {
+				ret = append(ret, p)
+			}
+			continue
+		case isZipapp(n):
+			zlog.Debug(ctx).Str("file", n).Msg("found zipapp")
+			buf.Reset()
+			if _, err := buf.ReadFrom(tr); err != nil {
+				return nil, err
+			}
+			ps, err := scanZipapp(ctx, n, buf.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, ps...)
+			continue
 		default:
 			continue
 		}
-		// These two files are in RFC8288 (email message) format, and the
-		// keys we care about are shared.
-		rd := textproto.NewReader(bufio.NewReader(tr))
-		hdr, err := rd.ReadMIMEHeader()
-		if err != nil && hdr == nil {
-			zlog.Warn(ctx).
-				Err(err).
-				Str("path", n).
-				Msg("unable to read metadata, skipping")
+		p := parseMetadata(ctx, n, tr)
+		if p == nil {
 			continue
 		}
-		v, err := pep440.Parse(hdr.Get("Version"))
-		if err != nil {
-			zlog.Warn(ctx).
-				Err(err).
-				Str("path", n).
-				Msg("couldn't parse the version, skipping")
-			continue
-		}
-		ret = append(ret, &claircore.Package{
-			Name:              strings.ToLower(hdr.Get("Name")),
-			Version:           v.String(),
-			PackageDB:         "python:" + filepath.Join(n, "..", ".."),
-			Kind:              claircore.BINARY,
-			NormalizedVersion: v.Version(),
-			// TODO Is there some way to pick up on where a wheel or egg was
-			// found?
-			RepositoryHint: "https://pypi.org/simple",
-		})
+		p.PackageDB = "python:" + filepath.Join(n, "..", "..")
+		ret = append(ret, p)
 	}
 	if err != io.EOF {
 		return nil, err
 	}
 	return ret, nil
 }
+
+// ParseMetadata parses a metadata file, returning nil if it's unusable.
+func parseMetadata(ctx context.Context, n string, r io.Reader) *claircore.Package {
+	// These two files are in RFC8288 (email message) format, and the
+	// keys we care about are shared.
+	rd := textproto.NewReader(bufio.NewReader(r))
+	hdr, err := rd.ReadMIMEHeader()
+	if err != nil && hdr == nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Str("path", n).
+			Msg("unable to read metadata, skipping")
+		return nil
+	}
+	v, err := pep440.Parse(hdr.Get("Version"))
+	if err != nil {
+		zlog.Warn(ctx).
+			Err(err).
+			Str("path", n).
+			Msg("couldn't parse the version, skipping")
+		return nil
+	}
+	return &claircore.Package{
+		Name:              NormalizeName(hdr.Get("Name")),
+		Version:           v.String(),
+		Kind:              claircore.BINARY,
+		NormalizedVersion: v.Version(),
+		// TODO Is there some way to pick up on where a wheel or egg was
+		// found?
+		RepositoryHint: "https://pypi.org/simple",
+	}
+}
+
+// NormalizeName returns the PEP 503 normalized form of the project name "n":
+// lower-cased, with runs of "-", "_", and "." replaced by a single "-".
+//
+// Package indexes treat names differing only in these ways as the same
+// project, so names must be normalized before they're compared.
+func NormalizeName(n string) string {
+	var b strings.Builder
+	b.Grow(len(n))
+	sep := false
+	for _, r := range strings.TrimSpace(n) {
+		switch r {
+		case '-', '_', '.':
+			sep = true
+			continue
+		}
+		if sep {
+			b.WriteByte('-')
+			sep = false
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	if sep {
+		b.WriteByte('-')
+	}
+	return b.String()
+}
@@ -1,7 +1,10 @@
 package python_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"path"
 	"sort"
 	"testing"
@@ -10,6 +13,7 @@ import (
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/python"
 	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/fixture"
 	"github.com/quay/zlog"
 )
 
@@ -442,7 +446,7 @@ var scanTable = []test.ScannerTestcase{
 				},
 			},
 			&claircore.Package{
-				Name:           "discord.py",
+				Name:           "discord-py",
 				Version:        "1.2.5",
 				Kind:           claircore.BINARY,
 				PackageDB:      "python:usr/local/lib/python3.7/site-packages",
@@ -612,3 +616,185 @@ func TestScanLocal(t *testing.T) {
 	}
 
 }
+
+// MkZip returns a zip archive containing "files", with "prefix" prepended.
+func mkZip(t *testing.T, prefix string, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString(prefix)
+	zw := zip.NewWriter(&buf)
+	zw.SetOffset(int64(len(prefix)))
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		w, err := zw.Create(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(files[n]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func metadata(name, version string) []byte {
+	return []byte("Metadata-Version: 2.1\nName: " + name + "\nVersion: " + version + "\n")
+}
+
+func sha256Digest(t *testing.T, b []byte) claircore.Digest {
+	t.Helper()
+	sum := sha256.Sum256(b)
+	d, err := claircore.NewDigest(claircore.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func wantPackage(name, version, db string, v [10]int32, ds ...claircore.Digest) *claircore.Package {
+	return &claircore.Package{
+		Name:              name,
+		Version:           version,
+		Kind:              claircore.BINARY,
+		PackageDB:         db,
+		RepositoryHint:    "https://pypi.org/simple",
+		NormalizedVersion: claircore.Version{Kind: "pep440", V: v},
+		Digests:           ds,
+	}
+}
+
+var cmpDigest = cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() })
+
+func TestScanArchive(t *testing.T) {
+	whl := mkZip(t, "", map[string][]byte{
+		"example/__init__.py":                   nil,
+		"example-1.2.3.dist-info/METADATA":      metadata("Example", "1.2.3"),
+		"example-1.2.3.dist-info/WHEEL":         []byte("Wheel-Version: 1.0\n"),
+		"vendored/other-9.9.dist-info/METADATA": metadata("other", "9.9"),
+	})
+	six := mkZip(t, "", map[string][]byte{
+		"six.py":                         nil,
+		"six-1.16.0.dist-info/METADATA":  metadata("six", "1.16.0"),
+		"six-1.16.0.dist-info/top_level": []byte("six\n"),
+	})
+	pyz := mkZip(t, "#!/usr/bin/env python3\n", map[string][]byte{
+		"__main__.py": nil,
+		// shiv
+		"site-packages/requests-2.25.1.dist-info/METADATA": metadata("requests", "2.25.1"),
+		// PEX, loose
+		".deps/idna-3.1-py3-none-any.whl/idna-3.1.dist-info/METADATA": metadata("idna", "3.1"),
+		// PEX, packed
+		".deps/six-1.16.0-py2.py3-none-any.whl": six,
+		// eggs
+		"lib/attrs-21.2.0-py3.9.egg-info/PKG-INFO": metadata("attrs", "21.2.0"),
+	})
+
+	tt := []struct {
+		Name  string
+		Files map[string]string
+		Want  []*claircore.Package
+	}{
+		{
+			Name: "Wheel",
+			Files: map[string]string{
+				"wheels/example-1.2.3-py3-none-any.whl": string(whl),
+				"wheels/broken-1.0-py3-none-any.whl":    "not a zip",
+			},
+			Want: []*claircore.Package{
+				wantPackage("example", "1.2.3", "python:wheels/example-1.2.3-py3-none-any.whl",
+					[...]int32{0, 1, 2, 3, 0, 0, 0, 0, 0, 0}, sha256Digest(t, whl)),
+			},
+		},
+		{
+			Name: "Zipapp",
+			Files: map[string]string{
+				"usr/local/bin/app.pyz": string(pyz),
+				"usr/local/bin/bad.pex": "#!/bin/sh\n",
+			},
+			Want: []*claircore.Package{
+				wantPackage("attrs", "21.2.0", "python:usr/local/bin/app.pyz:lib",
+					[...]int32{0, 21, 2, 0, 0, 0, 0, 0, 0, 0}),
+				wantPackage("idna", "3.1", "python:usr/local/bin/app.pyz:.deps/idna-3.1-py3-none-any.whl",
+					[...]int32{0, 3, 1, 0, 0, 0, 0, 0, 0, 0}),
+				wantPackage("requests", "2.25.1", "python:usr/local/bin/app.pyz:site-packages",
+					[...]int32{0, 2, 25, 1, 0, 0, 0, 0, 0, 0}),
+				wantPackage("six", "1.16.0", "python:usr/local/bin/app.pyz:.deps/six-1.16.0-py2.py3-none-any.whl",
+					[...]int32{0, 1, 16, 0, 0, 0, 0, 0, 0, 0}, sha256Digest(t, six)),
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(context.Background(), t)
+			got, err := (&python.Scanner{}).Scan(ctx, fixture.Files(tc.Files).Local(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+			if !cmp.Equal(got, tc.Want, cmpDigest) {
+				t.Error(cmp.Diff(got, tc.Want, cmpDigest))
+			}
+		})
+	}
+}
+
+func TestScanInstalled(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const sp = "usr/lib/python3.9/site-packages"
+	l := fixture.Files(map[string]string{
+		sp + "/Zope.Interface-5.4.0.dist-info/METADATA": string(metadata("Zope.Interface", "5.4.0")),
+		sp + "/ruamel_yaml-0.17.16.egg-info/PKG-INFO":   string(metadata("ruamel_yaml", "0.17.16")),
+		sp + "/PyYAML-5.1.egg-info":                     string(metadata("PyYAML", "5.1")),
+	}).Local(t)
+	got, err := (&python.Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+	want := []*claircore.Package{
+		wantPackage("pyyaml", "5.1", "python:"+sp, [...]int32{0, 5, 1, 0, 0, 0, 0, 0, 0, 0}),
+		wantPackage("ruamel-yaml", "0.17.16", "python:"+sp, [...]int32{0, 0, 17, 16, 0, 0, 0, 0, 0, 0}),
+		wantPackage("zope-interface", "5.4.0", "python:"+sp, [...]int32{0, 5, 4, 0, 0, 0, 0, 0, 0, 0}),
+	}
+	if !cmp.Equal(got, want, cmpDigest) {
+		t.Error(cmp.Diff(got, want, cmpDigest))
+	}
+}
+
+// TestRepoScanEggInfoFile checks that a layer with only a single-file egg-info
+// is reported as having packages from PyPI.
+func TestRepoScanEggInfoFile(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	l := fixture.Files(map[string]string{
+		"usr/lib/python3.9/site-packages/PyYAML-5.1.egg-info": string(metadata("PyYAML", "5.1")),
+	}).Local(t)
+	got, err := (&python.RepoScanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*claircore.Repository{&python.Repository}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	for in, want := range map[string]string{
+		"Django":         "django",
+		"zope.interface": "zope-interface",
+		"ruamel_yaml":    "ruamel-yaml",
+		"Foo-_.-Bar":     "foo-bar",
+		"six":            "six",
+	} {
+		if got := python.NormalizeName(in); got != want {
+			t.Errorf("%q: got: %q, want: %q", in, got, want)
+		}
+	}
+}
@@ -35,7 +35,7 @@ type RepoScanner struct{}
 func (*RepoScanner) Name() string { return "pip" }
 
 // Version implements scanner.VersionedScanner.
-func (*RepoScanner) Version() string { return "0.0.1" }
+func (*RepoScanner) Version() string { return "0.0.3" }
 
 // Kind implements scanner.VersionedScanner.
 func (*RepoScanner) Kind() string { return "repository" }
@@ -83,8 +83,14 @@ func (rs *RepoScanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*cla
 			continue
 		case strings.HasSuffix(n, `.egg-info/PKG-INFO`):
 			zlog.Debug(ctx).Str("file", n).Msg("found egg")
+		case strings.HasSuffix(n, `.egg-info`):
+			zlog.Debug(ctx).Str("file", n).Msg("found egg info file")
 		case strings.HasSuffix(n, `.dist-info/METADATA`):
 			zlog.Debug(ctx).Str("file", n).Msg("found wheel")
+		case strings.HasSuffix(n, `.whl`):
+			zlog.Debug(ctx).Str("file", n).Msg("found wheel archive")
+		case isZipapp(n):
+			zlog.Debug(ctx).Str("file", n).Msg("found zipapp")
 		default:
 			continue
 		}
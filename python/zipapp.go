@@ -0,0 +1,153 @@
+package python
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+// Zipapps are single-file executables: a zip archive, usually with a "#!"
+// line prepended. Dependencies are bundled in a few different layouts:
+//
+//	- zipapp or shiv: unpacked into the archive, usually under "site-packages/"
+//	- PEX: unpacked under ".deps/{wheel filename}/"
+//	- PEX "packed" layout: as wheel archives under ".deps/"
+//
+// All of these are handled by looking for metadata files and wheels anywhere
+// in the archive.
+
+// IsZipapp reports whether the named file looks like a zipapp.
+func isZipapp(n string) bool {
+	switch filepath.Ext(n) {
+	case ".pyz", ".pyzw", ".pex":
+		return true
+	}
+	return false
+}
+
+// OpenZip opens "b" as a zip archive. A nil Reader is returned if it's not a
+// valid archive.
+func openZip(ctx context.Context, n string, b []byte) (*zip.Reader, error) {
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, zip.ErrFormat):
+		zlog.Info(ctx).
+			Str("file", n).
+			Err(err).
+			Msg("not actually an archive: invalid zip")
+		return nil, nil
+	default:
+		return nil, err
+	}
+	return z, nil
+}
+
+// WheelPackage examines the wheel archive "b", named "n". A nil Package is
+// returned if the file isn't a usable wheel.
+func wheelPackage(ctx context.Context, n string, b []byte) (*claircore.Package, error) {
+	z, err := openZip(ctx, n, b)
+	if err != nil || z == nil {
+		return nil, err
+	}
+	var p *claircore.Package
+	for _, f := range z.File {
+		// The metadata is at "{distribution}-{version}.dist-info/METADATA"
+		// in the root of the archive.
+		dir, base := path.Split(f.Name)
+		if base != "METADATA" || strings.Count(dir, "/") != 1 || !strings.HasSuffix(dir, ".dist-info/") {
+			continue
+		}
+		p, err = zipMetadata(ctx, n, f)
+		if err != nil || p == nil {
+			return nil, err
+		}
+		p.PackageDB = "python:" + n
+		break
+	}
+	if p == nil {
+		zlog.Info(ctx).
+			Str("file", n).
+			Msg("not actually a wheel: no metadata")
+		return nil, nil
+	}
+	sum := sha256.Sum256(b)
+	dg, err := claircore.NewDigest(claircore.SHA256, sum[:])
+	if err != nil {
+		return nil, err
+	}
+	p.Digests = []claircore.Digest{dg}
+	return p, nil
+}
+
+// ScanZipapp examines the zipapp "b", named "n", for bundled packages.
+func scanZipapp(ctx context.Context, n string, b []byte) ([]*claircore.Package, error) {
+	z, err := openZip(ctx, n, b)
+	if err != nil || z == nil {
+		return nil, err
+	}
+	var ret []*claircore.Package
+	for _, f := range z.File {
+		switch {
+		case strings.HasSuffix(f.Name, `.dist-info/METADATA`),
+			strings.HasSuffix(f.Name, `.egg-info/PKG-INFO`):
+			p, err := zipMetadata(ctx, n, f)
+			if err != nil {
+				return nil, err
+			}
+			if p == nil {
+				continue
+			}
+			p.PackageDB = "python:" + n + ":" + path.Dir(path.Dir(f.Name))
+			ret = append(ret, p)
+		case strings.HasSuffix(f.Name, `.whl`) && f.Mode().IsRegular():
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			wb, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				zlog.Info(ctx).
+					Str("file", n).
+					Str("member", f.Name).
+					Err(err).
+					Msg("unable to read bundled wheel")
+				continue
+			}
+			p, err := wheelPackage(ctx, n+":"+f.Name, wb)
+			if err != nil {
+				return nil, err
+			}
+			if p != nil {
+				ret = append(ret, p)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// ZipMetadata parses the metadata file "f" from the archive named "n".
+func zipMetadata(ctx context.Context, n string, f *zip.File) (*claircore.Package, error) {
+	rc, err := f.Open()
+	if err != nil {
+		zlog.Info(ctx).
+			Str("file", n).
+			Str("member", f.Name).
+			Err(err).
+			Msg("unable to open metadata")
+		return nil, nil
+	}
+	defer rc.Close()
+	return parseMetadata(ctx, n+":"+f.Name, rc), nil
+}
@@ -12,7 +12,6 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
-	"strings"
 
 	pep440 "github.com/aquasecurity/go-pep440-version"
 	"github.com/quay/zlog"
@@ -22,6 +21,7 @@ import (
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/pkg/tmp"
+	"github.com/quay/claircore/python"
 )
 
 const defaultURL = `https://github.com/pyupio/safety-db/archive/master.tar.gz`
@@ -310,7 +310,7 @@ func (db db) Vulnerabilites(ctx context.Context, repo *claircore.Repository, upd
 				Updater:     updater,
 				Description: e.Advisory,
 				Package: &claircore.Package{
-					Name: strings.ToLower(k), // pip database lower cases all package names?
+					Name: python.NormalizeName(k),
 					Kind: claircore.BINARY,
 					// pip provides a "specifier" to understand if a particular package
 					// version is affected by a vulnerability.
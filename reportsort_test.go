@@ -0,0 +1,85 @@
+package claircore_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+)
+
+func TestIndexReportSort(t *testing.T) {
+	l1 := claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`)
+	l2 := claircore.MustParseDigest(`sha256:35c102085707f703de2d9eaad8752d6fe1b8f02b5d2149f1d8357c9cc7fb7d0a`)
+	ir := claircore.IndexReport{
+		Environments: map[string][]*claircore.Environment{
+			"1": {
+				{PackageDB: "var/lib/rpm", IntroducedIn: l1, DistributionID: "10", RepositoryIDs: []string{"10", "9"}},
+				{PackageDB: "var/lib/dpkg/status", IntroducedIn: l1, DistributionID: "2"},
+				{PackageDB: "var/lib/dpkg/status", IntroducedIn: l1, DistributionID: "10"},
+			},
+		},
+		Warnings: []claircore.IndexWarning{
+			{Kind: "b", Message: "x"},
+			{Kind: "a", Message: "y", Layer: &l2},
+			{Kind: "a", Message: "z", Layer: &l1},
+		},
+	}
+	ir.Sort()
+
+	env := ir.Environments["1"]
+	var got []string
+	for _, e := range env {
+		got = append(got, e.PackageDB+"@"+e.DistributionID)
+	}
+	want := []string{"var/lib/dpkg/status@2", "var/lib/dpkg/status@10", "var/lib/rpm@10"}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got, want := env[2].RepositoryIDs, []string{"9", "10"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	got = got[:0]
+	for _, w := range ir.Warnings {
+		got = append(got, w.Message)
+	}
+	if want := []string{"y", "z", "x"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestVulnerabilityReportSort(t *testing.T) {
+	vr := claircore.VulnerabilityReport{
+		PackageVulnerabilities: map[string][]string{
+			"1": {"100", "20", "3"},
+		},
+		WithdrawnVulnerabilities: map[string][]string{
+			"1": {"CVE-2021-2", "CVE-2021-1"},
+		},
+	}
+	vr.Sort()
+	if got, want := vr.PackageVulnerabilities["1"], []string{"3", "20", "100"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got, want := vr.WithdrawnVulnerabilities["1"], []string{"CVE-2021-1", "CVE-2021-2"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestIndexRecordsOrder(t *testing.T) {
+	ir := claircore.IndexReport{
+		Packages:     map[string]*claircore.Package{},
+		Environments: map[string][]*claircore.Environment{},
+	}
+	for _, id := range []string{"12", "3", "1", "20"} {
+		ir.Packages[id] = &claircore.Package{ID: id}
+		ir.Environments[id] = []*claircore.Environment{{}}
+	}
+	var got []string
+	for _, r := range ir.IndexRecords() {
+		got = append(got, r.Package.ID)
+	}
+	if want := []string{"1", "3", "12", "20"}; !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"regexp"
 	"runtime/trace"
+	"strings"
 
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
@@ -19,7 +20,7 @@ const rhReleasePath = `etc/redhat-release`
 
 const (
 	scannerName    = "rhel"
-	scannerVersion = "v0.0.1"
+	scannerVersion = "v0.0.3"
 	scannerKind    = "distribution"
 )
 
@@ -58,13 +59,20 @@ var rhelRegexes = []rhelRegex{
 		release: RHEL8,
 		regexp:  regexp.MustCompile(`Red Hat Enterprise Linux (Server)?\s*(release)?\s*8(\.\d)?`),
 	},
+	{
+		release: RHEL9,
+		regexp:  regexp.MustCompile(`Red Hat Enterprise Linux (Server)?\s*(release)?\s*9(\.\d)?`),
+	},
 }
 
 var _ indexer.DistributionScanner = (*DistributionScanner)(nil)
 var _ indexer.VersionedScanner = (*DistributionScanner)(nil)
+var _ indexer.PathDeclarer = (*DistributionScanner)(nil)
 
 // DistributionScanner attempts to discover if a layer
-// displays characteristics of a RHEL distribution
+// displays characteristics of a RHEL distribution, or one of the
+// distributions rebuilt from it: CentOS Linux, CentOS Stream, Rocky Linux,
+// and AlmaLinux.
 type DistributionScanner struct{}
 
 // Name implements scanner.VersionedScanner.
@@ -76,9 +84,20 @@ func (*DistributionScanner) Version() string { return scannerVersion }
 // Kind implements scanner.VersionedScanner.
 func (*DistributionScanner) Kind() string { return scannerKind }
 
+// Paths implements indexer.PathDeclarer.
+func (*DistributionScanner) Paths() []string {
+	return []string{osReleasePath, rhReleasePath, rhcosReleasePath, systemReleaseCPEPath}
+}
+
 // Scan will inspect the layer for an os-release or redhat-release file
 // and perform a regex match for keywords indicating the associated RHEL release
 //
+// Red Hat CoreOS systems are reported as the RHEL release they're composed
+// from.
+//
+// Rebuilds of RHEL are reported as their own distribution, with the CPE from
+// the system-release-cpe file or the os-release file if present.
+//
 // If neither file is found a (nil,nil) is returned.
 // If the files are found but all regexp fail to match an empty slice is returned.
 func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Distribution, error) {
@@ -89,14 +108,56 @@ func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]
 		label.String("layer", l.Hash.String()))
 	zlog.Debug(ctx).Msg("start")
 	defer zlog.Debug(ctx).Msg("done")
-	files, err := l.Files(osReleasePath, rhReleasePath)
+	files, err := l.Files(osReleasePath, rhReleasePath, rhcosReleasePath, systemReleaseCPEPath)
 	if err != nil {
 		zlog.Debug(ctx).Msg("didn't find an os-release or redhat-release file")
 		return nil, nil
 	}
-	for _, buff := range files {
-		dist := ds.parse(buff)
-		if dist != nil {
+	for _, p := range []string{rhcosReleasePath, osReleasePath} {
+		b, ok := files[p]
+		if !ok {
+			continue
+		}
+		if r := parseRHCOS(b.Bytes()); r != nil {
+			zlog.Debug(ctx).
+				Int("rhel", r.Major).
+				Str("ostree", r.OSTree).
+				Msg("found RHCOS")
+			if d := r.Dist(); d != nil {
+				return []*claircore.Distribution{d}, nil
+			}
+			return []*claircore.Distribution{}, nil
+		}
+	}
+
+	var sys string
+	if b, ok := files[systemReleaseCPEPath]; ok {
+		sys = strings.TrimSpace(b.String())
+	}
+	for _, p := range []string{osReleasePath, rhcosReleasePath} {
+		b, ok := files[p]
+		if !ok {
+			continue
+		}
+		if f, major, osCPE := familyFromOSRelease(b.Bytes()); f != nil {
+			if sys == "" {
+				sys = osCPE
+			}
+			return []*claircore.Distribution{f.Dist(major, sys)}, nil
+		}
+	}
+	if b, ok := files[rhReleasePath]; ok {
+		if f, major := familyFromRelease(b.Bytes()); f != nil {
+			return []*claircore.Distribution{f.Dist(major, sys)}, nil
+		}
+	}
+
+	for _, p := range []string{osReleasePath, rhcosReleasePath, rhReleasePath} {
+		buff, ok := files[p]
+		if !ok {
+			continue
+		}
+		if dist := ds.parse(buff); dist != nil {
 			return []*claircore.Distribution{dist}, nil
 		}
 	}
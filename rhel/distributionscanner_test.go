@@ -2,9 +2,15 @@ package rhel
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/cpe"
+	"github.com/quay/claircore/test/fixture"
 )
 
 var rhel3RHRelease []byte = []byte(`Red Hat Enterprise Linux Server release 3.1 (Taroon)`)
@@ -47,6 +53,20 @@ REDHAT_BUGZILLA_PRODUCT_VERSION=8.1
 REDHAT_SUPPORT_PRODUCT="Red Hat Enterprise Linux"
 REDHAT_SUPPORT_PRODUCT_VERSION="8.1"`)
 
+var rhel9RHRelease []byte = []byte(`Red Hat Enterprise Linux release 9.0 (Plow)`)
+var rhel9OSRelease []byte = []byte(`NAME="Red Hat Enterprise Linux"
+VERSION="9.0 (Plow)"
+ID="rhel"
+ID_LIKE="fedora"
+VERSION_ID="9.0"
+PLATFORM_ID="platform:el9"
+PRETTY_NAME="Red Hat Enterprise Linux 9.0 (Plow)"
+ANSI_COLOR="0;31"
+LOGO="fedora-logo-icon"
+CPE_NAME="cpe:/o:redhat:enterprise_linux:9::baseos"
+HOME_URL="https://www.redhat.com/"
+BUG_REPORT_URL="https://bugzilla.redhat.com/"`)
+
 func TestDistributionScanner(t *testing.T) {
 	table := []struct {
 		name    string
@@ -93,6 +113,16 @@ func TestDistributionScanner(t *testing.T) {
 			release: RHEL8,
 			file:    rhel8OSRelease,
 		},
+		{
+			name:    "RHEL9",
+			release: RHEL9,
+			file:    rhel9RHRelease,
+		},
+		{
+			name:    "RHEL9 OSRelease",
+			release: RHEL9,
+			file:    rhel9OSRelease,
+		},
 	}
 	for _, tt := range table {
 		t.Run(tt.name, func(t *testing.T) {
@@ -104,3 +134,132 @@ func TestDistributionScanner(t *testing.T) {
 		})
 	}
 }
+
+func TestDistributionScannerFamilies(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	table := []struct {
+		name  string
+		files map[string]string
+		want  *claircore.Distribution
+	}{
+		{
+			name: "CentOS6",
+			files: map[string]string{
+				"etc/redhat-release":     "CentOS release 6.10 (Final)\n",
+				"etc/system-release-cpe": "cpe:/o:centos:linux:6:GA\n",
+			},
+			want: &claircore.Distribution{
+				Name:       "CentOS Linux",
+				Version:    "6",
+				VersionID:  "6",
+				DID:        "centos",
+				PrettyName: "CentOS Linux 6",
+				CPE:        cpe.MustUnbind("cpe:/o:centos:linux:6"),
+			},
+		},
+		{
+			name: "CentOS7",
+			files: map[string]string{
+				"etc/os-release": `NAME="CentOS Linux"
+VERSION="7 (Core)"
+ID="centos"
+ID_LIKE="rhel fedora"
+VERSION_ID="7"
+PRETTY_NAME="CentOS Linux 7 (Core)"
+CPE_NAME="cpe:/o:centos:centos:7"
+`,
+				"etc/redhat-release": "CentOS Linux release 7.9.2009 (Core)\n",
+			},
+			want: &claircore.Distribution{
+				Name:       "CentOS Linux",
+				Version:    "7",
+				VersionID:  "7",
+				DID:        "centos",
+				PrettyName: "CentOS Linux 7",
+				CPE:        cpe.MustUnbind("cpe:/o:centos:centos:7"),
+			},
+		},
+		{
+			name: "CentOSStream9",
+			files: map[string]string{
+				"etc/os-release": `NAME="CentOS Stream"
+VERSION="9"
+ID="centos"
+ID_LIKE="rhel fedora"
+VERSION_ID="9"
+PLATFORM_ID="platform:el9"
+PRETTY_NAME="CentOS Stream 9"
+CPE_NAME="cpe:/o:centos:centos:9"
+`,
+				"etc/redhat-release":     "CentOS Stream release 9\n",
+				"etc/system-release-cpe": "cpe:/o:centos:centos:9\n",
+			},
+			want: &claircore.Distribution{
+				Name:       "CentOS Stream",
+				Version:    "9",
+				VersionID:  "9",
+				DID:        "centos",
+				PrettyName: "CentOS Stream 9",
+				CPE:        cpe.MustUnbind("cpe:/o:centos:centos:9"),
+			},
+		},
+		{
+			name: "Rocky8",
+			files: map[string]string{
+				"etc/os-release": `NAME="Rocky Linux"
+VERSION="8.5 (Green Obsidian)"
+ID="rocky"
+ID_LIKE="rhel centos fedora"
+VERSION_ID="8.5"
+PLATFORM_ID="platform:el8"
+PRETTY_NAME="Rocky Linux 8.5 (Green Obsidian)"
+CPE_NAME="cpe:/o:rocky:rocky:8:GA"
+`,
+				"etc/system-release-cpe": "cpe:/o:rocky:rocky:8:GA\n",
+			},
+			want: &claircore.Distribution{
+				Name:       "Rocky Linux",
+				Version:    "8",
+				VersionID:  "8",
+				DID:        "rocky",
+				PrettyName: "Rocky Linux 8",
+				CPE:        cpe.MustUnbind("cpe:/o:rocky:rocky:8"),
+			},
+		},
+		{
+			name: "Alma8ReleaseOnly",
+			files: map[string]string{
+				"etc/redhat-release": "AlmaLinux release 8.5 (Arctic Sphynx)\n",
+			},
+			want: &claircore.Distribution{
+				Name:       "AlmaLinux",
+				Version:    "8",
+				VersionID:  "8",
+				DID:        "almalinux",
+				PrettyName: "AlmaLinux 8",
+				CPE:        cpe.MustUnbind("cpe:/o:almalinux:almalinux:8"),
+			},
+		},
+		{
+			name: "RHEL9",
+			files: map[string]string{
+				"etc/os-release":         string(rhel9OSRelease),
+				"etc/redhat-release":     string(rhel9RHRelease),
+				"etc/system-release-cpe": "cpe:/o:redhat:enterprise_linux:9::baseos\n",
+			},
+			want: rhel9Dist,
+		},
+	}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			ds, err := new(DistributionScanner).Scan(ctx, fixture.Files(tc.files).Local(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := ds, []*claircore.Distribution{tc.want}; !cmp.Equal(got, want) {
+				t.Error(cmp.Diff(got, want))
+			}
+		})
+	}
+}
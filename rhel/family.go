@@ -0,0 +1,140 @@
+package rhel
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/cpe"
+)
+
+// SystemReleaseCPEPath is where Red Hat family distributions record the CPE of
+// the installed release.
+const systemReleaseCPEPath = `etc/system-release-cpe`
+
+// Family describes a rebuild of RHEL, such as CentOS or Rocky Linux.
+//
+// These are reported as their own distributions rather than as the RHEL
+// release they track, with their own CPE, so that vulnerability data for RHEL
+// isn't applied to them by accident, nor theirs to RHEL.
+type family struct {
+	// ID is the os-release ID.
+	id string
+	// Name is the distribution name, which must prefix the os-release NAME.
+	name string
+	// CPE is used for the release if the system doesn't record one. The major
+	// version is appended.
+	cpe string
+	// Release matches the contents of the redhat-release file, with the major
+	// version as the first submatch.
+	release *regexp.Regexp
+}
+
+// Families is checked in order. CentOS Stream is before CentOS Linux because
+// they share an ID.
+var families = []family{
+	{
+		id:      "centos",
+		name:    "CentOS Stream",
+		cpe:     "cpe:/o:centos:centos:",
+		release: regexp.MustCompile(`^CentOS Stream release (\d+)`),
+	},
+	{
+		id:      "centos",
+		name:    "CentOS Linux",
+		cpe:     "cpe:/o:centos:centos:",
+		release: regexp.MustCompile(`^CentOS (?:Linux )?release (\d+)`),
+	},
+	{
+		id:      "rocky",
+		name:    "Rocky Linux",
+		cpe:     "cpe:/o:rocky:rocky:",
+		release: regexp.MustCompile(`^Rocky Linux release (\d+)`),
+	},
+	{
+		id:      "almalinux",
+		name:    "AlmaLinux",
+		cpe:     "cpe:/o:almalinux:almalinux:",
+		release: regexp.MustCompile(`^AlmaLinux release (\d+)`),
+	},
+}
+
+// Dist returns the Distribution for the major release of the family.
+//
+// The CPE "sys", if not empty, is the CPE the system recorded for itself. It's
+// truncated to the major version so that every point release of a major
+// release is the same Distribution.
+func (f *family) Dist(major int, sys string) *claircore.Distribution {
+	v := strconv.Itoa(major)
+	d := claircore.Distribution{
+		Name:       f.name,
+		Version:    v,
+		VersionID:  v,
+		DID:        f.id,
+		PrettyName: f.name + " " + v,
+	}
+	// System CPEs are in the URI binding, like "cpe:/o:rocky:rocky:8:GA".
+	if fs := strings.Split(sys, ":"); len(fs) >= 5 && strings.HasPrefix(sys, "cpe:/") {
+		if w, err := cpe.Unbind(strings.Join(append(fs[:4:4], v), ":")); err == nil {
+			d.CPE = w
+			return &d
+		}
+	}
+	d.CPE = cpe.MustUnbind(f.cpe + v)
+	return &d
+}
+
+// FamilyFromOSRelease returns the family and major release described by the
+// os-release file, if any. The CPE_NAME is returned as well, if present.
+func familyFromOSRelease(b []byte) (f *family, major int, sys string) {
+	kv := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i == -1 {
+			continue
+		}
+		kv[line[:i]] = strings.Trim(line[i+1:], `"'`)
+	}
+	v := kv["VERSION_ID"]
+	if i := strings.IndexByte(v, '.'); i != -1 {
+		v = v[:i]
+	}
+	major, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, 0, ""
+	}
+	for i := range families {
+		f := &families[i]
+		if kv["ID"] == f.id && strings.HasPrefix(kv["NAME"], f.name) {
+			return f, major, kv["CPE_NAME"]
+		}
+	}
+	return nil, 0, ""
+}
+
+// FamilyFromRelease returns the family and major release described by the
+// redhat-release file, if any.
+func familyFromRelease(b []byte) (*family, int) {
+	b = bytes.TrimSpace(b)
+	for i := range families {
+		f := &families[i]
+		m := f.release.FindSubmatch(b)
+		if m == nil {
+			continue
+		}
+		major, err := strconv.Atoi(string(m[1]))
+		if err != nil {
+			continue
+		}
+		return f, major
+	}
+	return nil, 0
+}
@@ -49,5 +49,5 @@ func (m *Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord,
 		vulnVer = version.NewVersion("65535:0")
 	}
 	// compare version and architecture
-	return cmp(pkgVer.Compare(vulnVer)) && vuln.ArchOperation.Cmp(record.Package.Arch, vuln.Package.Arch), nil
+	return cmp(pkgVer.Compare(vulnVer)) && vuln.ArchOperation.CmpRecord(record, vuln.Package.Arch), nil
 }
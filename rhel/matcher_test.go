@@ -142,3 +142,42 @@ func TestVulnerable(t *testing.T) {
 		}
 	}
 }
+
+func TestVulnerableArch(t *testing.T) {
+	vuln := &claircore.Vulnerability{
+		Package: &claircore.Package{
+			Arch: "x86_64",
+		},
+		ArchOperation:  claircore.OpPatternMatch,
+		FixedInVersion: "0.33.0-7.el8",
+	}
+	record := func(arch, platform string) *claircore.IndexRecord {
+		r := &claircore.IndexRecord{
+			Package: &claircore.Package{
+				Version: "0.33.0-6.el8",
+				Arch:    arch,
+			},
+		}
+		if platform != "" {
+			r.Platform = &claircore.Platform{OS: "linux", Architecture: platform}
+		}
+		return r
+	}
+	testCases := []vulnerableTestCase{
+		{ir: record("x86_64", "amd64"), v: vuln, want: true, name: "same arch"},
+		{ir: record("aarch64", "arm64"), v: vuln, want: false, name: "other arch"},
+		{ir: record("noarch", "amd64"), v: vuln, want: true, name: "noarch on amd64 image"},
+		{ir: record("noarch", "arm64"), v: vuln, want: false, name: "noarch on arm64 image"},
+	}
+
+	m := &Matcher{}
+	for _, tc := range testCases {
+		got, err := m.Vulnerable(nil, tc.ir, tc.v)
+		if err != nil {
+			t.Error(err)
+		}
+		if tc.want != got {
+			t.Errorf("%q failed: want %t, got %t", tc.name, tc.want, got)
+		}
+	}
+}
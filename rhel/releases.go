@@ -18,6 +18,7 @@ const (
 	RHEL6 Release = 6
 	RHEL7 Release = 7
 	RHEL8 Release = 8
+	RHEL9 Release = 9
 )
 
 var rhel3Dist = &claircore.Distribution{
@@ -69,6 +70,15 @@ var rhel8Dist = &claircore.Distribution{
 	CPE:        cpe.MustUnbind("cpe:/o:redhat:enterprise_linux:8"),
 }
 
+var rhel9Dist = &claircore.Distribution{
+	Name:       "Red Hat Enterprise Linux Server",
+	Version:    "9",
+	VersionID:  "9",
+	DID:        "rhel",
+	PrettyName: "Red Hat Enterprise Linux Server 9",
+	CPE:        cpe.MustUnbind("cpe:/o:redhat:enterprise_linux:9"),
+}
+
 func releaseToDist(r Release) *claircore.Distribution {
 	switch r {
 	case RHEL3:
@@ -83,6 +93,8 @@ func releaseToDist(r Release) *claircore.Distribution {
 		return rhel7Dist
 	case RHEL8:
 		return rhel8Dist
+	case RHEL9:
+		return rhel9Dist
 	default:
 		// return empty dist
 		return &claircore.Distribution{}
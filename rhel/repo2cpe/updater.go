@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -20,17 +21,29 @@ var interval = rate.Every(10 * time.Minute)
 
 // UpdatingMapper provides local repo -> cpe mapping
 // via a continually updated local mapping file
+//
+// The mapping is fetched from URL or, if Path is set, read from a file. It's
+// only replaced when it's changed: remote mapping files are requested
+// conditionally, and local files are reread when their size or modification
+// time changes.
 type UpdatingMapper struct {
 	URL    string
 	Client *http.Client
+	// Path is a local mapping file to use instead of URL.
+	Path string
 	// an atomic value holding the latest
 	// parsed MappingFile
 	mapping atomic.Value
 
 	// Machinery for updating the mapping file.
-	reqRate      *rate.Limiter
-	mu           sync.Mutex // protects lastModified
+	reqRate *rate.Limiter
+	mu      sync.Mutex // protects the following
+	// Change detection for remote files.
 	lastModified string
+	etag         string
+	// Change detection for local files.
+	modTime time.Time
+	size    int64
 }
 
 // NewUpdatingMapper returns an UpdatingMapper.
@@ -51,6 +64,26 @@ func NewUpdatingMapper(client *http.Client, url string, init *MappingFile) *Upda
 	return lu
 }
 
+// NewFileMapper returns an UpdatingMapper that reads the mapping from the
+// local file at "path", rereading it when it changes.
+func NewFileMapper(path string, init *MappingFile) *UpdatingMapper {
+	lu := &UpdatingMapper{
+		Path:    path,
+		reqRate: rate.NewLimiter(interval, 1),
+	}
+	lu.mapping.Store(init)
+	if init != nil {
+		lu.reqRate.Allow()
+	}
+	return lu
+}
+
+// SetInterval changes how often the mapping is checked for changes. The
+// default is every 10 minutes.
+func (u *UpdatingMapper) SetInterval(d time.Duration) {
+	u.reqRate.SetLimit(rate.Every(d))
+}
+
 // Get translates repositories into CPEs using a mapping file.
 //
 // Get is safe for concurrent usage.
@@ -62,7 +95,7 @@ func (u *UpdatingMapper) Get(ctx context.Context, rs []string) ([]string, error)
 	}
 	if u.reqRate.Allow() {
 		zlog.Debug(ctx).Msg("got unlucky, updating mapping file")
-		if err := u.do(ctx); err != nil {
+		if err := u.Fetch(ctx); err != nil {
 			zlog.Error(ctx).
 				Err(err).
 				Msg("error updating mapping file")
@@ -78,7 +111,11 @@ func (u *UpdatingMapper) Get(ctx context.Context, rs []string) ([]string, error)
 	return m.Get(ctx, rs)
 }
 
+// Fetch updates the mapping immediately, if it's changed.
 func (u *UpdatingMapper) Fetch(ctx context.Context) error {
+	if u.Path != "" {
+		return u.load(ctx)
+	}
 	return u.do(ctx)
 }
 
@@ -102,6 +139,9 @@ func (u *UpdatingMapper) do(ctx context.Context) error {
 	if u.lastModified != "" {
 		req.Header.Set("if-modified-since", u.lastModified)
 	}
+	if u.etag != "" {
+		req.Header.Set("if-none-match", u.etag)
+	}
 
 	resp, err := u.Client.Do(req)
 	if err != nil {
@@ -126,8 +166,47 @@ func (u *UpdatingMapper) do(ctx context.Context) error {
 	}
 
 	u.lastModified = resp.Header.Get("last-modified")
+	u.etag = resp.Header.Get("etag")
 	// atomic store of mapping file
 	u.mapping.Store(&mapping)
 	zlog.Debug(ctx).Msg("atomic update of local mapping file complete")
 	return nil
 }
+
+// load is an internal method called to reread a local mapping file, if it's
+// changed since it was last read.
+//
+// this method may be ran concurrently.
+func (u *UpdatingMapper) load(ctx context.Context) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "rhel/repo2cpe/UpdatingMapper.load"),
+		label.String("path", u.Path))
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	f, err := os.Open(u.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.ModTime().Equal(u.modTime) && fi.Size() == u.size {
+		zlog.Debug(ctx).
+			Time("since", u.modTime).
+			Msg("file not modified; no update necessary")
+		return nil
+	}
+
+	var mapping MappingFile
+	if err := json.NewDecoder(f).Decode(&mapping); err != nil {
+		return fmt.Errorf("failed to decode mapping file: %v", err)
+	}
+	u.modTime, u.size = fi.ModTime(), fi.Size()
+	u.mapping.Store(&mapping)
+	zlog.Debug(ctx).Msg("atomic update of local mapping file complete")
+	return nil
+}
@@ -0,0 +1,100 @@
+package repo2cpe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+)
+
+const (
+	mappingV1 = `{"data":{"rhel-8-for-x86_64-baseos-rpms":{"cpes":["cpe:/o:redhat:enterprise_linux:8::baseos"]}}}`
+	mappingV2 = `{"data":{"rhel-8-for-x86_64-baseos-rpms":{"cpes":["cpe:/o:redhat:enterprise_linux:8::baseos"]},` +
+		`"layered-product-1-for-rhel-8-x86_64-rpms":{"cpes":["cpe:/a:redhat:layered_product:1::el8"]}}}`
+)
+
+var repos = []string{"rhel-8-for-x86_64-baseos-rpms", "layered-product-1-for-rhel-8-x86_64-rpms"}
+
+func get(ctx context.Context, t *testing.T, u *UpdatingMapper) []string {
+	t.Helper()
+	got, err := u.Get(ctx, repos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestFileMapper(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	name := filepath.Join(t.TempDir(), "repository-to-cpe.json")
+	if err := os.WriteFile(name, []byte(mappingV1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	u := NewFileMapper(name, nil)
+	u.SetInterval(time.Nanosecond)
+	if err := u.Fetch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"cpe:/o:redhat:enterprise_linux:8::baseos"}
+	if got := get(ctx, t, u); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	// Replace the file, as a deployment would.
+	if err := os.WriteFile(name, []byte(mappingV2), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	want = []string{"cpe:/a:redhat:layered_product:1::el8", "cpe:/o:redhat:enterprise_linux:8::baseos"}
+	if got := get(ctx, t, u); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+
+	// A broken update keeps the previous mapping.
+	if err := os.WriteFile(name, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if got := get(ctx, t, u); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
+
+func TestUpdatingMapper(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	const etag = `"v2"`
+	var fetches, unmodified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if r.Header.Get("if-none-match") == etag {
+			unmodified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("etag", etag)
+		w.Write([]byte(mappingV2))
+	}))
+	defer srv.Close()
+
+	u := NewUpdatingMapper(srv.Client(), srv.URL, nil)
+	for i := 0; i < 2; i++ {
+		if err := u.Fetch(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetches != 2 || unmodified != 1 {
+		t.Errorf("got %d fetches (%d unmodified), want 2 (1 unmodified)", fetches, unmodified)
+	}
+	want := []string{"cpe:/a:redhat:layered_product:1::el8", "cpe:/o:redhat:enterprise_linux:8::baseos"}
+	if got := get(ctx, t, u); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
@@ -46,11 +46,18 @@ type RepositoryScanner struct {
 
 // RepoScannerConfig is the struct that will be passed to
 // (*RepositoryScanner).Configure's ConfigDeserializer argument.
+//
+// The repository-to-CPE mapping is checked for changes every
+// Repo2CPEMappingInterval, so new layered product releases are picked up
+// without a restart. If only Repo2CPEMappingFile is set, the file is reread
+// when it changes; if Repo2CPEMappingURL is set, the file is only used as the
+// initial mapping.
 type RepoScannerConfig struct {
-	Timeout             time.Duration `json:"timeout" yaml:"timeout"`
-	API                 string        `json:"api" yaml:"api"`
-	Repo2CPEMappingURL  string        `json:"repo2cpe_mapping_url" yaml:"repo2cpe_mapping_url"`
-	Repo2CPEMappingFile string        `json:"repo2cpe_mapping_file" yaml:"repo2cpe_mapping_file"`
+	Timeout                 time.Duration `json:"timeout" yaml:"timeout"`
+	API                     string        `json:"api" yaml:"api"`
+	Repo2CPEMappingURL      string        `json:"repo2cpe_mapping_url" yaml:"repo2cpe_mapping_url"`
+	Repo2CPEMappingFile     string        `json:"repo2cpe_mapping_file" yaml:"repo2cpe_mapping_file"`
+	Repo2CPEMappingInterval time.Duration `json:"repo2cpe_mapping_interval" yaml:"repo2cpe_mapping_interval"`
 }
 
 // RedHatRepositoryKey is a key of Red Hat's CPE based repository
@@ -60,7 +67,7 @@ const RedHatRepositoryKey = "rhel-cpe-repository"
 func (*RepositoryScanner) Name() string { return "rhel-repository-scanner" }
 
 // Version implements scanner.VersionedScanner.
-func (*RepositoryScanner) Version() string { return "1.1" }
+func (*RepositoryScanner) Version() string { return "1.2" }
 
 // Kind implements scanner.VersionedScanner.
 func (*RepositoryScanner) Kind() string { return "repository" }
@@ -121,22 +128,19 @@ func (r *RepositoryScanner) Configure(ctx context.Context, f indexer.ConfigDeser
 	case r.cfg.Repo2CPEMappingURL != "" && r.cfg.Repo2CPEMappingFile == "":
 		// remote only
 		u := repo2cpe.NewUpdatingMapper(r.client, r.cfg.Repo2CPEMappingURL, nil)
+		r.setInterval(u)
 		if err := u.Fetch(ctx); err != nil {
 			return err
 		}
 		r.mapper = u
 	case r.cfg.Repo2CPEMappingURL == "" && r.cfg.Repo2CPEMappingFile != "":
-		// local only
-		f, err := os.Open(r.cfg.Repo2CPEMappingFile)
-		if err != nil {
-			return err
-		}
-		defer f.Close()
-		var mf repo2cpe.MappingFile
-		if err := json.NewDecoder(f).Decode(&mf); err != nil {
+		// local only, reloaded on change
+		u := repo2cpe.NewFileMapper(r.cfg.Repo2CPEMappingFile, nil)
+		r.setInterval(u)
+		if err := u.Fetch(ctx); err != nil {
 			return err
 		}
-		r.mapper = &mf
+		r.mapper = u
 	case r.cfg.Repo2CPEMappingURL != "" && r.cfg.Repo2CPEMappingFile != "":
 		// load, then fetch later
 		f, err := os.Open(r.cfg.Repo2CPEMappingFile)
@@ -148,7 +152,9 @@ func (r *RepositoryScanner) Configure(ctx context.Context, f indexer.ConfigDeser
 		if err := json.NewDecoder(f).Decode(&mf); err != nil {
 			return err
 		}
-		r.mapper = repo2cpe.NewUpdatingMapper(r.client, r.cfg.Repo2CPEMappingURL, &mf)
+		u := repo2cpe.NewUpdatingMapper(r.client, r.cfg.Repo2CPEMappingURL, &mf)
+		r.setInterval(u)
+		r.mapper = u
 	}
 
 	// Additional setup
@@ -165,6 +171,13 @@ func (r *RepositoryScanner) Configure(ctx context.Context, f indexer.ConfigDeser
 	return nil
 }
 
+// SetInterval applies the configured mapping update interval, if any.
+func (r *RepositoryScanner) setInterval(u *repo2cpe.UpdatingMapper) {
+	if r.cfg.Repo2CPEMappingInterval > 0 {
+		u.SetInterval(r.cfg.Repo2CPEMappingInterval)
+	}
+}
+
 // Scan gets Red Hat repositories information.
 func (r *RepositoryScanner) Scan(ctx context.Context, l *claircore.Layer) (repositories []*claircore.Repository, err error) {
 	defer trace.StartRegion(ctx, "Scanner.Scan").End()
@@ -179,6 +192,21 @@ func (r *RepositoryScanner) Scan(ctx context.Context, l *claircore.Layer) (repos
 	if err != nil {
 		return []*claircore.Repository{}, err
 	}
+	if CPEs == nil {
+		// RHCOS images don't carry content manifests, but the os-release
+		// file identifies the RHEL and OpenShift releases.
+		rh, err := findRHCOS(l)
+		if err != nil {
+			return []*claircore.Repository{}, err
+		}
+		if rh != nil {
+			zlog.Debug(ctx).
+				Int("rhel", rh.Major).
+				Str("openshift", rh.OpenShift).
+				Msg("found RHCOS")
+			CPEs = rh.CPEs()
+		}
+	}
 	if CPEs == nil && r.apiFetcher != nil {
 		// Embedded content-sets are available only for new images.
 		// For old images, use fallback option and query Red Hat Container API.
@@ -0,0 +1,124 @@
+package rhel
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/quay/claircore"
+)
+
+// Red Hat CoreOS, the OpenShift node operating system, is an ostree-based
+// build of RHEL packages. It ships an os-release file identifying itself as
+// "rhcos" rather than "rhel", but records the RHEL release it was composed
+// from.
+//
+// The os-release file is found at the path mandated for ostree systems, with
+// "etc/os-release" being a symlink to it.
+const rhcosReleasePath = `usr/lib/os-release`
+
+const rhcosID = "rhcos"
+
+// Rhcos is the information from an RHCOS os-release file needed to map the
+// system onto RHEL content.
+type rhcos struct {
+	// Major is the major version of RHEL the system is composed from.
+	Major int
+	// OpenShift is the OpenShift release the system belongs to, if known.
+	OpenShift string
+	// OSTree is the ostree version of the build, if known.
+	OSTree string
+	// CPE is the CPE_NAME from the os-release file, if present.
+	CPE string
+}
+
+// ParseRHCOS reports the RHCOS information in the provided os-release file
+// contents, or nil if the file doesn't describe an RHCOS system.
+func parseRHCOS(b []byte) *rhcos {
+	kv := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i == -1 {
+			continue
+		}
+		kv[line[:i]] = strings.Trim(line[i+1:], `"'`)
+	}
+	if kv["ID"] != rhcosID {
+		return nil
+	}
+	r := rhcos{
+		OpenShift: kv["OPENSHIFT_VERSION"],
+		OSTree:    kv["OSTREE_VERSION"],
+		CPE:       kv["CPE_NAME"],
+	}
+	// Older releases lack RHEL_VERSION, but all of them have a PLATFORM_ID of
+	// the form "platform:el8".
+	v := kv["RHEL_VERSION"]
+	if v == "" {
+		v = strings.TrimPrefix(kv["PLATFORM_ID"], "platform:el")
+	}
+	if i := strings.IndexByte(v, '.'); i != -1 {
+		v = v[:i]
+	}
+	var err error
+	if r.Major, err = strconv.Atoi(v); err != nil {
+		return nil
+	}
+	return &r
+}
+
+// Dist returns the RHEL distribution the system is composed from, or nil if the
+// RHEL release isn't known.
+func (r *rhcos) Dist() *claircore.Distribution {
+	d := releaseToDist(Release(r.Major))
+	if d.DID == "" {
+		return nil
+	}
+	return d
+}
+
+// CPEs returns the CPEs of the RHEL and OpenShift content sets the system's
+// packages are drawn from.
+func (r *rhcos) CPEs() []string {
+	out := []string{
+		fmt.Sprintf("cpe:/o:redhat:enterprise_linux:%d::baseos", r.Major),
+		fmt.Sprintf("cpe:/a:redhat:enterprise_linux:%d::appstream", r.Major),
+	}
+	if r.CPE != "" {
+		out = append(out, r.CPE)
+	}
+	if r.OpenShift != "" {
+		out = append(out, fmt.Sprintf("cpe:/a:redhat:openshift:%s::el%d", r.OpenShift, r.Major))
+	}
+	return out
+}
+
+// FindRHCOS looks for an RHCOS os-release file in the layer.
+//
+// A nil *rhcos is returned if there's no such file.
+func findRHCOS(l *claircore.Layer) (*rhcos, error) {
+	files, err := l.Files(rhcosReleasePath, osReleasePath)
+	switch {
+	case err == nil:
+	case errors.Is(err, claircore.ErrNotFound):
+		return nil, nil
+	default:
+		return nil, err
+	}
+	for _, p := range []string{rhcosReleasePath, osReleasePath} {
+		if b, ok := files[p]; ok {
+			if r := parseRHCOS(b.Bytes()); r != nil {
+				return r, nil
+			}
+		}
+	}
+	return nil, nil
+}
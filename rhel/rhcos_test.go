@@ -0,0 +1,121 @@
+package rhel
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/cpe"
+)
+
+var rhcos48OSRelease = []byte(`NAME="Red Hat Enterprise Linux CoreOS"
+VERSION="48.84.202109241901-0"
+ID="rhcos"
+ID_LIKE="rhel fedora"
+VERSION_ID="4.8"
+PLATFORM_ID="platform:el8"
+PRETTY_NAME="Red Hat Enterprise Linux CoreOS 48.84.202109241901-0 (Ootpa)"
+ANSI_COLOR="0;31"
+CPE_NAME="cpe:/o:redhat:enterprise_linux:8::coreos"
+HOME_URL="https://www.redhat.com/"
+DOCUMENTATION_URL="https://docs.openshift.com/container-platform/4.8/"
+BUG_REPORT_URL="https://bugzilla.redhat.com/"
+REDHAT_BUGZILLA_PRODUCT="OpenShift Container Platform"
+REDHAT_BUGZILLA_PRODUCT_VERSION="4.8"
+REDHAT_SUPPORT_PRODUCT="OpenShift Container Platform"
+REDHAT_SUPPORT_PRODUCT_VERSION="4.8"
+OPENSHIFT_VERSION="4.8"
+RHEL_VERSION="8.4"
+OSTREE_VERSION='48.84.202109241901-0'
+`)
+
+func TestParseRHCOS(t *testing.T) {
+	got := parseRHCOS(rhcos48OSRelease)
+	want := &rhcos{
+		Major:     8,
+		OpenShift: "4.8",
+		OSTree:    "48.84.202109241901-0",
+		CPE:       "cpe:/o:redhat:enterprise_linux:8::coreos",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got := parseRHCOS(rhel8OSRelease); got != nil {
+		t.Errorf("RHEL os-release reported as RHCOS: %+v", got)
+	}
+}
+
+func TestRHCOSLayer(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	name := filepath.Join(t.TempDir(), "layer.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := tar.NewWriter(f)
+	if err := w.WriteHeader(&tar.Header{
+		Name:     "usr/lib/os-release",
+		Typeflag: tar.TypeReg,
+		Size:     int64(len(rhcos48OSRelease)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(rhcos48OSRelease); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteHeader(&tar.Header{
+		Name:     "etc/os-release",
+		Typeflag: tar.TypeSymlink,
+		Linkname: "../usr/lib/os-release",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var l claircore.Layer
+	if err := l.SetLocal(name); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Distribution", func(t *testing.T) {
+		ds, err := new(DistributionScanner).Scan(ctx, &l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := ds, []*claircore.Distribution{rhel8Dist}; !cmp.Equal(got, want) {
+			t.Error(cmp.Diff(got, want))
+		}
+	})
+	t.Run("Repository", func(t *testing.T) {
+		rs, err := new(RepositoryScanner).Scan(ctx, &l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []*claircore.Repository
+		for _, c := range []string{
+			"cpe:/o:redhat:enterprise_linux:8::baseos",
+			"cpe:/a:redhat:enterprise_linux:8::appstream",
+			"cpe:/o:redhat:enterprise_linux:8::coreos",
+			"cpe:/a:redhat:openshift:4.8::el8",
+		} {
+			want = append(want, &claircore.Repository{
+				Name: c,
+				Key:  RedHatRepositoryKey,
+				CPE:  cpe.MustUnbind(c),
+			})
+		}
+		if !cmp.Equal(rs, want) {
+			t.Error(cmp.Diff(rs, want))
+		}
+	})
+}
@@ -19,6 +19,7 @@ var rhelReleases = []Release{
 	RHEL6,
 	RHEL7,
 	RHEL8,
+	RHEL9,
 }
 
 // DefaultManifest is the url for the Red Hat OVAL pulp repository.
@@ -154,6 +155,8 @@ func (f *Factory) UpdaterSet(ctx context.Context) (driver.UpdaterSet, error) {
 		p := uri.Path
 		var r Release
 		switch {
+		case strings.Contains(p, "RHEL9"):
+			r = RHEL9
 		case strings.Contains(p, "RHEL8"):
 			r = RHEL8
 		case strings.Contains(p, "RHEL7"):
@@ -0,0 +1,81 @@
+package rpm
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/internal/indexer/linux"
+	"github.com/quay/claircore/test"
+	"github.com/quay/claircore/test/fetch"
+)
+
+// TestCoalesceWhiteout checks that the Scanner's PackageDB is matched by the
+// whiteouts of a higher layer.
+func TestCoalesceWhiteout(t *testing.T) {
+	for _, exe := range []string{
+		"tar", "rpm",
+	} {
+		if _, err := exec.LookPath(exe); err != nil {
+			t.Skipf("skipping test: missing needed utility %q (%v)", exe, err)
+		}
+	}
+	ctx := zlog.Test(context.Background(), t)
+	hash, err := claircore.ParseDigest("sha256:729ec3a6ada3a6d26faca9b4779a037231f1762f759ef34c08bdd61bf52cd704")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tctx, done := context.WithTimeout(ctx, 2*time.Minute)
+	defer done()
+	n, err := fetch.Layer(tctx, t, http.DefaultClient, "docker.io", "library/centos", hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	l := &claircore.Layer{Hash: hash}
+	l.SetLocal(n.Name())
+
+	pkgs, err := (&Scanner{}).Scan(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) == 0 {
+		t.Fatal("no packages found")
+	}
+	for i := range pkgs {
+		pkgs[i].ID = strconv.Itoa(i)
+	}
+
+	tt := []struct {
+		Name    string
+		Removed []string
+		Want    int
+	}{
+		{Name: "Database", Removed: []string{"var/lib/rpm"}, Want: 0},
+		{Name: "Parent", Removed: []string{"var/lib"}, Want: 0},
+		{Name: "Sibling", Removed: []string{"var/lib/rpm-state"}, Want: len(pkgs)},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			ls := []*indexer.LayerArtifacts{
+				{Hash: hash, Pkgs: pkgs},
+				{Hash: test.RandomSHA256Digest(t), Removed: tc.Removed},
+			}
+			ir, err := linux.NewCoalescer().Coalesce(ctx, ls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(ir.Packages), tc.Want; got != want {
+				t.Errorf("got: %d packages, want: %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package rpm
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+)
+
+func TestFindDatabases(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	bdb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(bdb[12:], 0x00061561)
+	ndb := make([]byte, 16)
+	copy(ndb, "RpmP")
+	sqlite := append([]byte("SQLite format 3\x00"), make([]byte, 16)...)
+
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, f := range []struct {
+		Name string
+		Data []byte
+	}{
+		{"var/lib/rpm/Packages", bdb},
+		{"usr/lib/sysimage/rpm/Packages.db", ndb},
+		// Ostree-based systems keep the database in /usr.
+		{"usr/share/rpm/rpmdb.sqlite", sqlite},
+		// Rebuilt database: the sqlite database should be preferred.
+		{"opt/rpmdb/Packages", bdb},
+		{"opt/rpmdb/rpmdb.sqlite", sqlite},
+		// Wrong magic.
+		{"srv/rpm/rpmdb.sqlite", ndb},
+		{"srv/rpm/Packages", make([]byte, 16)},
+	} {
+		if err := w.WriteHeader(&tar.Header{
+			Name:     f.Name,
+			Typeflag: tar.TypeReg,
+			Size:     int64(len(f.Data)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(f.Data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := findDatabases(ctx, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, db := range found {
+		got[db.Path] = db.Backend.File
+	}
+	want := map[string]string{
+		"/opt/rpmdb":            "rpmdb.sqlite",
+		"/usr/lib/sysimage/rpm": "Packages.db",
+		"/usr/share/rpm":        "rpmdb.sqlite",
+		"/var/lib/rpm":          "Packages",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+}
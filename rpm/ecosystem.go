@@ -7,6 +7,7 @@ import (
 	"github.com/quay/claircore/internal/indexer"
 	"github.com/quay/claircore/internal/indexer/linux"
 	"github.com/quay/claircore/oracle"
+	"github.com/quay/claircore/ostree"
 	"github.com/quay/claircore/photon"
 	"github.com/quay/claircore/suse"
 )
@@ -26,7 +27,9 @@ func NewEcosystem(ctx context.Context) *indexer.Ecosystem {
 			}, nil
 		},
 		RepositoryScanners: func(ctx context.Context) ([]indexer.RepositoryScanner, error) {
-			return []indexer.RepositoryScanner{}, nil
+			return []indexer.RepositoryScanner{
+				&ostree.RepositoryScanner{},
+			}, nil
 		},
 		Coalescer: func(ctx context.Context) (indexer.Coalescer, error) {
 			return linux.NewCoalescer(), nil
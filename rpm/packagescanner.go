@@ -14,6 +14,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime/trace"
+	"sort"
 	"strings"
 
 	"github.com/quay/zlog"
@@ -27,23 +28,50 @@ import (
 const (
 	pkgName    = "rpm"
 	pkgKind    = "package"
-	pkgVersion = "4"
+	pkgVersion = "5"
 )
 
-// DbNames is a set of files that make up an rpm database.
-var dbnames = map[string]struct{}{
-	"Packages": {},
+// Backend describes one of the on-disk formats of an rpm database.
+type backend struct {
+	// File is the name of the file holding the package headers.
+	File string
+	// Name is the value for rpm's "_db_backend" macro. If empty, rpm's
+	// configured default is used.
+	Name string
+	// Check reports whether the contents look like a database of this kind.
+	Check func(context.Context, io.Reader) bool
+}
+
+// Backends are the rpm database formats the scanner knows how to find.
+//
+// They're listed in order of increasing preference, so that a directory
+// holding a database that's been rebuilt in a newer format is examined using
+// the newer one.
+var backends = []backend{
+	{File: "Packages", Check: checkMagic},
+	{File: "Packages.db", Name: "ndb", Check: checkNDB},
+	{File: "rpmdb.sqlite", Name: "sqlite", Check: checkSQLite},
+}
+
+// Database is a found rpm database.
+type database struct {
+	// Path is the absolute path of the database directory within the layer.
+	Path    string
+	Backend *backend
 }
 
 var (
-	_ indexer.VersionedScanner = (*Scanner)(nil)
-	_ indexer.PackageScanner   = (*Scanner)(nil)
+	_ indexer.VersionedScanner   = (*Scanner)(nil)
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
 )
 
 // Scanner implements the scanner.PackageScanner interface.
 //
 // This looks for directories that look like rpm databases and examines the
-// files it finds there.
+// files it finds there. BerkeleyDB, ndb, and sqlite databases are recognized,
+// so both the traditional "/var/lib/rpm" and the newer "/usr/lib/sysimage/rpm"
+// locations are handled.
 //
 // The zero value is ready to use.
 type Scanner struct{}
@@ -57,6 +85,9 @@ func (*Scanner) Version() string { return pkgVersion }
 // Kind implements scanner.VersionedScanner.
 func (*Scanner) Kind() string { return pkgKind }
 
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceAuthoritative }
+
 // Scan attempts to find rpm databases within the layer and enumerate the
 // packages there.
 //
@@ -88,36 +119,16 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 		return nil, errors.New("rpm: cannot seek on returned layer Reader")
 	}
 
-	// Map of directory to confidence score. Confidence of len(dbnames) means
-	// it's almost certainly an rpm database.
-	possible := make(map[string]int)
-	tr := tar.NewReader(rd)
 	// Find possible rpm dbs
 	// If none found, return
-	var h *tar.Header
-	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
-		n := filepath.Base(h.Name)
-		d := filepath.Dir(h.Name)
-		if _, ok := dbnames[n]; ok && checkMagic(ctx, tr) {
-			possible[d]++
-		}
-	}
-	if err != io.EOF {
+	found, err := findDatabases(ctx, rd)
+	if err != nil {
 		return nil, err
 	}
-	if len(possible) == 0 {
-		return nil, nil
-	}
-	found := make([]string, 0)
-	for k, score := range possible {
-		if score == len(dbnames) {
-			found = append(found, filepath.Join("/", k))
-		}
-	}
-	zlog.Debug(ctx).Int("count", len(found)).Msg("found possible databases")
 	if len(found) == 0 {
 		return nil, nil
 	}
+	zlog.Debug(ctx).Int("count", len(found)).Msg("found possible databases")
 
 	root, err := ioutil.TempDir("", "rpmscanner.")
 	if err != nil {
@@ -147,7 +158,7 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 	if _, err := rd.Seek(0, io.SeekStart); err != nil {
 		return nil, fmt.Errorf("rpm: unable to seek: %w", err)
 	}
-	tr = tar.NewReader(rd)
+	tr := tar.NewReader(rd)
 	const (
 		// Any mode bits need to be or'd with these constants so that this
 		// process can always remove and traverse files it writes.
@@ -163,7 +174,8 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 	made := map[string]struct{}{root: {}}
 	// DeferLn is for queuing up out-of-order hard links.
 	var deferLn [][2]string
-	for h, err = tr.Next(); err == nil; h, err = tr.Next() {
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
 		if strings.HasPrefix(filepath.Base(h.Name), ".wh.") {
 			// Whiteout, skip.
 			stats.Whiteout++
@@ -270,12 +282,19 @@ func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*clairco
 
 	var pkgs []*claircore.Package
 	// Using --root and --dbpath, run rpm query on every suspected database
-	for _, db := range found {
-		zlog.Debug(ctx).Str("db", db).Msg("examining database")
+	for _, fdb := range found {
+		db := fdb.Path
+		zlog.Debug(ctx).
+			Str("db", db).
+			Str("backend", fdb.Backend.Name).
+			Msg("examining database")
 
-		cmd := exec.CommandContext(ctx, "rpm",
-			`--root`, root, `--dbpath`, db,
-			`--query`, `--all`, `--queryformat`, queryFmt)
+		args := []string{`--root`, root, `--dbpath`, db}
+		if n := fdb.Backend.Name; n != "" {
+			args = append(args, `--define`, `_db_backend `+n)
+		}
+		args = append(args, `--query`, `--all`, `--queryformat`, queryFmt)
+		cmd := exec.CommandContext(ctx, "rpm", args...)
 		r, err := cmd.StdoutPipe()
 		if err != nil {
 			return nil, err
@@ -470,6 +489,68 @@ func checkMagic(ctx context.Context, r io.Reader) bool {
 	return false
 }
 
+// FindDatabases reports the directories in the tar stream that look like rpm
+// databases, sorted by path.
+func findDatabases(ctx context.Context, r io.Reader) ([]database, error) {
+	// Map of directory to the most preferred backend found there.
+	possible := make(map[string]int)
+	tr := tar.NewReader(r)
+	h, err := tr.Next()
+	for ; err == nil; h, err = tr.Next() {
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		n := filepath.Base(h.Name)
+		d := filepath.Join("/", filepath.Dir(h.Name))
+		for i := range backends {
+			b := &backends[i]
+			if n != b.File || !b.Check(ctx, tr) {
+				continue
+			}
+			if prev, ok := possible[d]; !ok || prev < i {
+				possible[d] = i
+			}
+			break
+		}
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	found := make([]database, 0, len(possible))
+	for d, i := range possible {
+		found = append(found, database{Path: d, Backend: &backends[i]})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+	return found, nil
+}
+
+// CheckSQLite looks at the provided Reader to see if it looks like a SQLite
+// database file.
+func checkSQLite(ctx context.Context, r io.Reader) bool {
+	const magic = "SQLite format 3\x00"
+	b := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, b); err != nil {
+		zlog.Warn(ctx).Err(err).Msg("unexpected error checking magic")
+		return false
+	}
+	return string(b) == magic
+}
+
+// CheckNDB looks at the provided Reader to see if it looks like an rpm "ndb"
+// package database.
+//
+// The file starts with a little-endian magic number that happens to spell
+// "RpmP".
+func checkNDB(ctx context.Context, r io.Reader) bool {
+	const magic = 'R' | 'p'<<8 | 'm'<<16 | 'P'<<24
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		zlog.Warn(ctx).Err(err).Msg("unexpected error checking magic")
+		return false
+	}
+	return binary.LittleEndian.Uint32(b) == magic
+}
+
 // RelPath takes a member and forcibly interprets it as a path underneath root.
 //
 // This should be used anytime a path for a new file on disk is needed when
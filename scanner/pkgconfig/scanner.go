@@ -24,7 +24,10 @@ import (
 	"github.com/quay/claircore/internal/indexer"
 )
 
-var _ indexer.PackageScanner = (*Scanner)(nil)
+var (
+	_ indexer.PackageScanner     = (*Scanner)(nil)
+	_ indexer.ConfidenceReporter = (*Scanner)(nil)
+)
 
 const (
 	pkgName    = `pkgconfig`
@@ -44,6 +47,9 @@ func (*Scanner) Version() string { return pkgVersion }
 // Kind implements scanner.VersionedScanner.
 func (*Scanner) Kind() string { return pkgKind }
 
+// Confidence implements indexer.ConfidenceReporter.
+func (*Scanner) Confidence() claircore.Confidence { return claircore.ConfidenceDeclared }
+
 // Scan attempts to find and enumerate pkg-config files.
 func (ps *Scanner) Scan(ctx context.Context, layer *claircore.Layer) ([]*claircore.Package, error) {
 	if err := ctx.Err(); err != nil {
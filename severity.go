@@ -36,7 +36,7 @@ func (s *Severity) UnmarshalText(b []byte) error {
 			return nil
 		}
 	}
-	panic("unreachable")
+	return fmt.Errorf("unknown severity %q", string(b))
 }
 
 func (s Severity) Value() (driver.Value, error) {
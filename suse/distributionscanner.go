@@ -1,10 +1,12 @@
 package suse
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"regexp"
 	"runtime/trace"
+	"strings"
 
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/indexer"
+	"github.com/quay/claircore/pkg/cpe"
 )
 
 // Suse Enterprise Server has service pack releases however their security database files are bundled together
@@ -19,10 +22,14 @@ import (
 // we choose to normalize detected distributions into major releases and parse vulnerabilities by major release versions.
 //
 // Suse Leap has well defined sub releases and their sec db's match up fine.
+//
+// When there's an os-release file, the service pack level is reported in the
+// VersionID, so SLES 15 SP4 is reported with a Version of "15" and a VersionID
+// of "15.4".
 
 const (
 	scannerName    = "suse"
-	scannerVersion = "v0.0.1"
+	scannerVersion = "v0.0.2"
 	scannerKind    = "distribution"
 )
 
@@ -64,6 +71,7 @@ var suseRegexes = []suseRegex{
 
 var _ indexer.DistributionScanner = (*DistributionScanner)(nil)
 var _ indexer.VersionedScanner = (*DistributionScanner)(nil)
+var _ indexer.PathDeclarer = (*DistributionScanner)(nil)
 
 // DistributionScanner attempts to discover if a layer
 // displays characteristics of a Suse distribution
@@ -78,7 +86,12 @@ func (*DistributionScanner) Version() string { return scannerVersion }
 // Kind implements scanner.VersionedScanner.
 func (*DistributionScanner) Kind() string { return scannerKind }
 
-// Scan will inspect the layer for an os-release or lsb-release file
+// Paths implements indexer.PathDeclarer.
+func (*DistributionScanner) Paths() []string {
+	return []string{osReleasePath, suseReleasePath}
+}
+
+// Scan will inspect the layer for an os-release or SuSE-release file
 // and perform a regex match for keywords indicating the associated Suse release
 //
 // If neither file is found a (nil,nil) is returned.
@@ -96,9 +109,17 @@ func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]
 		zlog.Debug(ctx).Msg("didn't find an os-release or SuSE-release")
 		return nil, nil
 	}
-	for _, buff := range files {
-		dist := ds.parse(buff)
-		if dist != nil {
+	if buff, ok := files[osReleasePath]; ok {
+		if dist := parseOSRelease(buff.Bytes()); dist != nil {
+			return []*claircore.Distribution{dist}, nil
+		}
+	}
+	for _, p := range []string{osReleasePath, suseReleasePath} {
+		buff, ok := files[p]
+		if !ok {
+			continue
+		}
+		if dist := ds.parse(buff); dist != nil {
 			return []*claircore.Distribution{dist}, nil
 		}
 	}
@@ -117,3 +138,75 @@ func (ds *DistributionScanner) parse(buff *bytes.Buffer) *claircore.Distribution
 	}
 	return nil
 }
+
+// ParseOSRelease returns the distribution described by the os-release file, or
+// nil if it's not a SUSE distribution or isn't understood.
+//
+// SLES distributions are reported per service pack, with the Version being the
+// major release so that vulnerabilities for every service pack match. Leap
+// releases are reported as the known Distribution if there is one. Tumbleweed
+// has no releases, so it's reported with the snapshot as the VersionID and no
+// Version.
+func parseOSRelease(b []byte) *claircore.Distribution {
+	kv := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i == -1 {
+			continue
+		}
+		kv[line[:i]] = strings.Trim(line[i+1:], `"'`)
+	}
+	id, v := kv["ID"], kv["VERSION_ID"]
+	if v == "" {
+		return nil
+	}
+	var d *claircore.Distribution
+	switch id {
+	case "sles":
+		major := v
+		if i := strings.IndexByte(v, '.'); i != -1 {
+			major = v[:i]
+		}
+		d = &claircore.Distribution{
+			Name:       "SLES",
+			Version:    major,
+			VersionID:  v,
+			PrettyName: kv["PRETTY_NAME"],
+			DID:        "sles",
+		}
+	case "opensuse-leap", "opensuse":
+		if !strings.HasPrefix(kv["NAME"], "openSUSE Leap") {
+			return nil
+		}
+		if r := Release("opensuse.leap." + v); releaseToDist(r).DID != "" {
+			return releaseToDist(r)
+		}
+		d = &claircore.Distribution{
+			Name:       "openSUSE Leap",
+			Version:    v,
+			VersionID:  v,
+			PrettyName: "openSUSE Leap " + v,
+			DID:        id,
+		}
+	case "opensuse-tumbleweed":
+		d = &claircore.Distribution{
+			Name:       "openSUSE Tumbleweed",
+			VersionID:  v,
+			PrettyName: "openSUSE Tumbleweed",
+			DID:        id,
+		}
+	default:
+		return nil
+	}
+	if c := kv["CPE_NAME"]; c != "" {
+		if w, err := cpe.Unbind(c); err == nil {
+			d.CPE = w
+		}
+	}
+	return d
+}
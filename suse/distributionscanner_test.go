@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/cpe"
 )
 
 var enterpriseServer15OSRelease []byte = []byte(`NAME="SLES"
@@ -112,3 +115,104 @@ func TestDistributionScanner(t *testing.T) {
 		})
 	}
 }
+
+var tumbleweedOSRelease []byte = []byte(`NAME="openSUSE Tumbleweed"
+# VERSION="20220810"
+ID="opensuse-tumbleweed"
+ID_LIKE="opensuse suse"
+VERSION_ID="20220810"
+PRETTY_NAME="openSUSE Tumbleweed"
+ANSI_COLOR="0;32"
+CPE_NAME="cpe:/o:opensuse:tumbleweed:20220810"
+BUG_REPORT_URL="https://bugs.opensuse.org"
+HOME_URL="https://www.opensuse.org/"`)
+
+var leap154OSRelease []byte = []byte(`NAME="openSUSE Leap"
+VERSION="15.4"
+ID="opensuse-leap"
+ID_LIKE="suse opensuse"
+VERSION_ID="15.4"
+PRETTY_NAME="openSUSE Leap 15.4"
+ANSI_COLOR="0;32"
+CPE_NAME="cpe:/o:opensuse:leap:15.4"
+BUG_REPORT_URL="https://bugs.opensuse.org"
+HOME_URL="https://www.opensuse.org/"`)
+
+func TestParseOSRelease(t *testing.T) {
+	table := []struct {
+		name      string
+		osRelease []byte
+		want      *claircore.Distribution
+	}{
+		{
+			name:      "enterprise server 15 sp1",
+			osRelease: enterpriseServer15OSRelease,
+			want: &claircore.Distribution{
+				Name:       "SLES",
+				Version:    "15",
+				VersionID:  "15.1",
+				PrettyName: "SUSE Linux Enterprise Server 15 SP1",
+				DID:        "sles",
+				CPE:        cpe.MustUnbind("cpe:/o:suse:sles:15:sp1"),
+			},
+		},
+		{
+			name:      "enterprise server 12 sp5",
+			osRelease: enterpriseServer12OSRelase,
+			want: &claircore.Distribution{
+				Name:       "SLES",
+				Version:    "12",
+				VersionID:  "12.5",
+				PrettyName: "SUSE Linux Enterprise Server 12 SP5",
+				DID:        "sles",
+				CPE:        cpe.MustUnbind("cpe:/o:suse:sles:12:sp5"),
+			},
+		},
+		{
+			name:      "leap 15.1",
+			osRelease: leap151OSRelease,
+			want:      releaseToDist(Leap151),
+		},
+		{
+			name:      "leap 42.3",
+			osRelease: leap423OSRelease,
+			want:      releaseToDist(Leap423),
+		},
+		{
+			name:      "leap 15.4",
+			osRelease: leap154OSRelease,
+			want: &claircore.Distribution{
+				Name:       "openSUSE Leap",
+				Version:    "15.4",
+				VersionID:  "15.4",
+				PrettyName: "openSUSE Leap 15.4",
+				DID:        "opensuse-leap",
+				CPE:        cpe.MustUnbind("cpe:/o:opensuse:leap:15.4"),
+			},
+		},
+		{
+			name:      "tumbleweed",
+			osRelease: tumbleweedOSRelease,
+			want: &claircore.Distribution{
+				Name:       "openSUSE Tumbleweed",
+				VersionID:  "20220810",
+				PrettyName: "openSUSE Tumbleweed",
+				DID:        "opensuse-tumbleweed",
+				CPE:        cpe.MustUnbind("cpe:/o:opensuse:tumbleweed:20220810"),
+			},
+		},
+		{
+			name:      "not suse",
+			osRelease: []byte("NAME=\"Fedora Linux\"\nID=fedora\nVERSION_ID=36\n"),
+			want:      nil,
+		},
+	}
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseOSRelease(tt.osRelease)
+			if !cmp.Equal(got, tt.want) {
+				t.Error(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
@@ -50,7 +50,16 @@ func (*Matcher) Query() []driver.MatchConstraint {
 }
 
 // Vulnerable implements driver.Matcher
+//
+// Vulnerabilities scoped to a product (see the Distribution's CPE) only apply
+// to records for that product and service pack. If the record's product can't
+// be determined, the scope is ignored.
 func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, vuln *claircore.Vulnerability) (bool, error) {
+	if vuln.Dist != nil && vuln.Dist.CPE.Valid() == nil && record.Distribution != nil {
+		if p, ok := distCPE(record.Distribution); ok && !productMatch(p, vuln.Dist.CPE) {
+			return false, nil
+		}
+	}
 	pkgVer, vulnVer := version.NewVersion(record.Package.Version), version.NewVersion(vuln.Package.Version)
 	// Assume the vulnerability record we have is for the last known vulnerable
 	// version, so greater versions aren't vulnerable.
@@ -61,7 +70,7 @@ func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, v
 		vulnVer = version.NewVersion(vuln.FixedInVersion)
 		cmp = func(i int) bool { return i == version.LESS }
 	}
-	return cmp(pkgVer.Compare(vulnVer)) && vuln.ArchOperation.Cmp(record.Package.Arch, vuln.Package.Arch), nil
+	return cmp(pkgVer.Compare(vulnVer)) && vuln.ArchOperation.CmpRecord(record, vuln.Package.Arch), nil
 }
 
 // contains is a helper function to see if a slice of strings contains a specific string
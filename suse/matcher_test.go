@@ -0,0 +1,67 @@
+package suse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/cpe"
+)
+
+func TestMatcherProductScope(t *testing.T) {
+	ctx := context.Background()
+	sp1 := cpe.MustUnbind("cpe:/o:suse:sles:15:sp1")
+	vuln := func(w cpe.WFN, arch string) *claircore.Vulnerability {
+		d := *enterpriseServer15Dist
+		d.CPE = w
+		return &claircore.Vulnerability{
+			Package:        &claircore.Package{Name: "curl", Arch: arch},
+			ArchOperation:  claircore.OpPatternMatch,
+			FixedInVersion: "0:7.60.0-3.1",
+			Dist:           &d,
+		}
+	}
+	record := func(d *claircore.Distribution, arch string) *claircore.IndexRecord {
+		return &claircore.IndexRecord{
+			Package:      &claircore.Package{Name: "curl", Version: "7.59.0-1.1", Arch: arch},
+			Distribution: d,
+		}
+	}
+	sles := func(v string) *claircore.Distribution {
+		return &claircore.Distribution{DID: "sles", Name: "SLES", Version: "15", VersionID: v}
+	}
+	tt := []struct {
+		Name   string
+		Record *claircore.IndexRecord
+		Vuln   *claircore.Vulnerability
+		Want   bool
+	}{
+		{"Unscoped", record(sles("15.4"), "x86_64"), vuln(cpe.WFN{}, ""), true},
+		{"SameServicePack", record(sles("15.1"), "x86_64"), vuln(sp1, ""), true},
+		{"OtherServicePack", record(sles("15.4"), "x86_64"), vuln(sp1, ""), false},
+		{
+			"RecordCPE",
+			record(&claircore.Distribution{DID: "sles", Version: "15", CPE: cpe.MustUnbind("cpe:/o:suse:sles:15:sp1")}, "x86_64"),
+			vuln(sp1, ""),
+			true,
+		},
+		{"Leap", record(leap151Dist, "x86_64"), vuln(sp1, ""), false},
+		{"LeapScoped", record(leap151Dist, "x86_64"), vuln(cpe.MustUnbind("cpe:/o:opensuse:leap:15.1"), ""), true},
+		{"MajorOnly", record(sles("15.4"), "x86_64"), vuln(cpe.MustUnbind("cpe:/o:suse:sles:15"), ""), true},
+		{"UnknownProduct", record(&claircore.Distribution{DID: "sles", Version: "15"}, "x86_64"), vuln(sp1, ""), true},
+		{"Arch", record(sles("15.1"), "ppc64le"), vuln(sp1, `^(noarch|x86_64)$`), false},
+		{"Noarch", record(sles("15.1"), "noarch"), vuln(sp1, `^(noarch|x86_64)$`), true},
+	}
+	var m Matcher
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := m.Vulnerable(ctx, tc.Record, tc.Vuln)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.Want {
+				t.Errorf("got: %v, want: %v", got, tc.Want)
+			}
+		})
+	}
+}
@@ -3,8 +3,10 @@ package suse
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/quay/goval-parser/oval"
 	"github.com/quay/zlog"
@@ -14,6 +16,7 @@ import (
 	"github.com/quay/claircore"
 	"github.com/quay/claircore/internal/xmlutil"
 	"github.com/quay/claircore/libvuln/driver"
+	"github.com/quay/claircore/pkg/cpe"
 	"github.com/quay/claircore/pkg/ovalutil"
 )
 
@@ -31,25 +34,200 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 		return nil, fmt.Errorf("suse: unable to decode OVAL document: %w", err)
 	}
 	zlog.Debug(ctx).Msg("xml decoded")
-	protoVulns := func(def oval.Definition) ([]*claircore.Vulnerability, error) {
-		return []*claircore.Vulnerability{
-			&claircore.Vulnerability{
-				Updater:            u.Name(),
-				Name:               def.Title,
-				Description:        def.Description,
-				Links:              ovalutil.Links(def),
-				Severity:           def.Advisory.Severity,
-				NormalizedSeverity: NormalizeSeverity(def.Advisory.Severity),
-				// each updater is configured to parse a suse release
-				// specific xml database. we'll use the updater's release
-				// to map the parsed vulnerabilities
-				Dist: releaseToDist(u.release),
-			},
-		}, nil
-	}
-	vulns, err := ovalutil.RPMDefsToVulns(ctx, &root, protoVulns)
-	if err != nil {
-		return nil, err
+	ovalutil.ApplyClassPolicy(ctx, &root, u.classes)
+	var vulns []*claircore.Vulnerability
+	for _, def := range root.Definitions.Definitions {
+		proto := claircore.Vulnerability{
+			Updater:            u.Name(),
+			Name:               def.Title,
+			Description:        def.Description,
+			Links:              ovalutil.Links(def),
+			Severity:           def.Advisory.Severity,
+			NormalizedSeverity: NormalizeSeverity(def.Advisory.Severity),
+		}
+		w := walker{
+			ctx:     ctx,
+			root:    &root,
+			release: u.release,
+			proto:   &proto,
+			arch:    platformArch(def.Affecteds),
+		}
+		w.walk(&def.Criteria, cpe.WFN{})
+		vulns = append(vulns, w.out...)
 	}
 	return vulns, nil
 }
+
+// Walker turns the criteria of one definition into Vulnerabilities, keeping
+// track of the product each criterion is scoped to.
+type walker struct {
+	ctx     context.Context
+	root    *oval.Root
+	release Release
+	proto   *claircore.Vulnerability
+	arch    string
+	out     []*claircore.Vulnerability
+}
+
+// Walk examines the criteria node and its children.
+//
+// A product criterion in an AND node scopes every other criterion in the node
+// and its children to that product. Product criteria in an OR node don't
+// constrain anything, so they're ignored.
+func (w *walker) walk(node *oval.Criteria, scope cpe.WFN) {
+	type pkg struct {
+		obj   *oval.RPMInfoObject
+		state *oval.RPMInfoState
+	}
+	var pkgs []pkg
+	for i := range node.Criterions {
+		c := &node.Criterions[i]
+		obj, state, err := w.lookup(c)
+		if err != nil {
+			zlog.Debug(w.ctx).
+				Err(err).
+				Str("test_ref", c.TestRef).
+				Msg("skipping criterion")
+			continue
+		}
+		if p, ok := releaseCPE(obj.Name, stateVersion(state)); ok {
+			if !strings.EqualFold(node.Operator, "OR") {
+				scope = p
+			}
+			continue
+		}
+		if state == nil || state.EVR == nil {
+			// Not a package version check.
+			continue
+		}
+		pkgs = append(pkgs, pkg{obj, state})
+	}
+	// Emitted after the loop, because a product criterion may come after the
+	// package criteria it scopes.
+	for _, p := range pkgs {
+		w.emit(p.obj, p.state, scope)
+	}
+	for i := range node.Criterias {
+		w.walk(&node.Criterias[i], scope)
+	}
+}
+
+// Emit adds a Vulnerability for the package and fixed version described by
+// the object and state.
+func (w *walker) emit(obj *oval.RPMInfoObject, state *oval.RPMInfoState, scope cpe.WFN) {
+	v := *w.proto
+	// Each vulnerability gets its own copy of the release's Distribution,
+	// because the product scope is recorded in it.
+	d := *releaseToDist(w.release)
+	d.CPE = scope
+	v.Dist = &d
+	v.Package = &claircore.Package{
+		Name: obj.Name,
+		Kind: claircore.BINARY,
+	}
+	v.FixedInVersion = state.EVR.Body
+	switch {
+	case state.Arch != nil:
+		v.ArchOperation = ovalutil.MapArchOp(state.Arch.Operation)
+		v.Package.Arch = state.Arch.Body
+	case w.arch != "":
+		v.ArchOperation = claircore.OpPatternMatch
+		v.Package.Arch = w.arch
+	}
+	w.out = append(w.out, &v)
+}
+
+var errSkip = errors.New("not an rpminfo criterion")
+
+// Lookup returns the rpminfo object and (optional) state for the criterion.
+func (w *walker) lookup(c *oval.Criterion) (*oval.RPMInfoObject, *oval.RPMInfoState, error) {
+	t, err := ovalutil.TestLookup(w.root, c.TestRef, func(k string) bool { return k == "rpminfo_test" })
+	if err != nil {
+		return nil, nil, err
+	}
+	objs := t.ObjectRef()
+	if len(objs) == 0 {
+		return nil, nil, errSkip
+	}
+	kind, i, err := w.root.Objects.Lookup(objs[0].ObjectRef)
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case kind != "rpminfo_object":
+		return nil, nil, errSkip
+	}
+	obj := &w.root.Objects.RPMInfoObjects[i]
+	states := t.StateRef()
+	if len(states) == 0 {
+		return obj, nil, nil
+	}
+	kind, i, err = w.root.States.Lookup(states[0].StateRef)
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case kind != "rpminfo_state":
+		return nil, nil, errSkip
+	}
+	return obj, &w.root.States.RPMInfoStates[i], nil
+}
+
+// StateVersion returns the version a release package state checks for, if it
+// checks for a specific one.
+func stateVersion(s *oval.RPMInfoState) string {
+	if s == nil || s.RPMVersion == nil {
+		return ""
+	}
+	if op := s.RPMVersion.Operation; op != oval.Operation(0) && op != oval.OpEquals {
+		return ""
+	}
+	return strings.TrimSpace(s.RPMVersion.Body)
+}
+
+// PlatformArches maps the architecture suffixes used in SUSE "affected
+// platform" strings to rpm architectures.
+var platformArches = map[string][]string{
+	"AMD64 and Intel EM64T": {"x86_64"},
+	"x86_64":                {"x86_64"},
+	"x86":                   {"i386", "i486", "i586", "i686"},
+	"IBM zSeries 64bit":     {"s390x"},
+	"IBM zSeries 31bit":     {"s390"},
+	"IBM Z":                 {"s390x"},
+	"IBM POWER":             {"ppc64le", "ppc64"},
+	"IPF":                   {"ia64"},
+	"ARM64":                 {"aarch64"},
+	"AArch64":               {"aarch64"},
+}
+
+// PlatformArch returns a pattern matching the architectures named by the
+// definition's affected platforms, or an empty string if any platform doesn't
+// name one this package knows about.
+//
+// Architecture-independent packages always match.
+func platformArch(as []oval.Affected) string {
+	seen := map[string]bool{"noarch": true}
+	arches := []string{"noarch"}
+	var n int
+	for _, a := range as {
+		for _, p := range a.Platforms {
+			n++
+			i := strings.LastIndex(p, " for ")
+			if i == -1 {
+				return ""
+			}
+			pa, ok := platformArches[p[i+len(" for "):]]
+			if !ok {
+				return ""
+			}
+			for _, arch := range pa {
+				if !seen[arch] {
+					seen[arch] = true
+					arches = append(arches, arch)
+				}
+			}
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+	return `^(` + strings.Join(arches, "|") + `)$`
+}
@@ -0,0 +1,168 @@
+package suse
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+)
+
+const scopedOVAL = `<?xml version="1.0" encoding="UTF-8"?>
+<oval_definitions xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5">
+<definitions>
+<definition id="oval:org.opensuse.security:def:1" version="1" class="vulnerability">
+ <metadata>
+  <title>CVE-2021-0001</title>
+  <affected family="unix">
+   <platform>SUSE Linux Enterprise Server 15 SP1</platform>
+   <platform>SUSE Linux Enterprise Server 15 SP2</platform>
+  </affected>
+ </metadata>
+ <criteria operator="OR">
+  <criteria operator="AND">
+   <criterion test_ref="oval:org.opensuse.security:tst:1" comment="SUSE Linux Enterprise Server 15 SP1 is installed"/>
+   <criterion test_ref="oval:org.opensuse.security:tst:3" comment="curl less than 7.60.0-3.1"/>
+  </criteria>
+  <criteria operator="AND">
+   <criteria operator="OR">
+    <criterion test_ref="oval:org.opensuse.security:tst:4" comment="curl less than 7.66.0-4.1"/>
+   </criteria>
+   <criterion test_ref="oval:org.opensuse.security:tst:2" comment="SUSE Linux Enterprise Server 15 SP2 is installed"/>
+  </criteria>
+ </criteria>
+</definition>
+<definition id="oval:org.opensuse.security:def:2" version="1" class="vulnerability">
+ <metadata>
+  <title>CVE-2021-0002</title>
+  <affected family="unix">
+   <platform>SUSE Linux Enterprise Server 15 for AMD64 and Intel EM64T</platform>
+   <platform>SUSE Linux Enterprise Server 15 for IBM POWER</platform>
+  </affected>
+ </metadata>
+ <criteria operator="AND">
+  <criterion test_ref="oval:org.opensuse.security:tst:3" comment="curl less than 7.60.0-3.1"/>
+ </criteria>
+</definition>
+</definitions>
+<tests>
+ <rpminfo_test id="oval:org.opensuse.security:tst:1" version="1" check="at least one" xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <object object_ref="oval:org.opensuse.security:obj:1"/>
+  <state state_ref="oval:org.opensuse.security:ste:1"/>
+ </rpminfo_test>
+ <rpminfo_test id="oval:org.opensuse.security:tst:2" version="1" check="at least one" xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <object object_ref="oval:org.opensuse.security:obj:1"/>
+  <state state_ref="oval:org.opensuse.security:ste:2"/>
+ </rpminfo_test>
+ <rpminfo_test id="oval:org.opensuse.security:tst:3" version="1" check="at least one" xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <object object_ref="oval:org.opensuse.security:obj:2"/>
+  <state state_ref="oval:org.opensuse.security:ste:3"/>
+ </rpminfo_test>
+ <rpminfo_test id="oval:org.opensuse.security:tst:4" version="1" check="at least one" xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <object object_ref="oval:org.opensuse.security:obj:2"/>
+  <state state_ref="oval:org.opensuse.security:ste:4"/>
+ </rpminfo_test>
+</tests>
+<objects>
+ <rpminfo_object id="oval:org.opensuse.security:obj:1" version="1" xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <name>sles-release</name>
+ </rpminfo_object>
+ <rpminfo_object id="oval:org.opensuse.security:obj:2" version="1" xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <name>curl</name>
+ </rpminfo_object>
+</objects>
+<states>
+ <rpminfo_state id="oval:org.opensuse.security:ste:1" version="1" xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <version operation="equals">15.1</version>
+ </rpminfo_state>
+ <rpminfo_state id="oval:org.opensuse.security:ste:2" version="1" xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <version operation="equals">15.2</version>
+ </rpminfo_state>
+ <rpminfo_state id="oval:org.opensuse.security:ste:3" version="1" xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <evr datatype="evr_string" operation="less than">0:7.60.0-3.1</evr>
+ </rpminfo_state>
+ <rpminfo_state id="oval:org.opensuse.security:ste:4" version="1" xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <evr datatype="evr_string" operation="less than">0:7.66.0-4.1</evr>
+ </rpminfo_state>
+</states>
+</oval_definitions>
+`
+
+func TestParseScoped(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	u, err := NewUpdater(EnterpriseServer15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := u.Parse(ctx, io.NopCloser(strings.NewReader(scopedOVAL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		Name, Fixed, CPE, Arch string
+	}
+	want := []result{
+		{"CVE-2021-0001", "0:7.60.0-3.1", "cpe:2.3:o:suse:sles:15:sp1:*:*:*:*:*:*", ""},
+		{"CVE-2021-0001", "0:7.66.0-4.1", "cpe:2.3:o:suse:sles:15:sp2:*:*:*:*:*:*", ""},
+		{"CVE-2021-0002", "0:7.60.0-3.1", "", `^(noarch|x86_64|ppc64le|ppc64)$`},
+	}
+	if got, want := len(vs), len(want); got != want {
+		t.Fatalf("got: %d vulnerabilities, want: %d", got, want)
+	}
+	for i, v := range vs {
+		got := result{Name: v.Name, Fixed: v.FixedInVersion, Arch: v.Package.Arch}
+		if v.Dist.CPE.Valid() == nil {
+			got.CPE = v.Dist.CPE.String()
+		}
+		if got != want[i] {
+			t.Errorf("%d: got: %+v, want: %+v", i, got, want[i])
+		}
+		if v.Dist.DID != "sles" || v.Dist.Version != "15" {
+			t.Errorf("%d: unexpected distribution: %+v", i, v.Dist)
+		}
+	}
+	if vs[0].Dist == vs[1].Dist {
+		t.Error("distributions shared between vulnerabilities")
+	}
+}
+
+func TestParseDesktop(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	f, err := os.Open("testdata/suse.linux.enterprise.desktop.10.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := NewUpdater(EnterpriseServer15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := u.Parse(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) == 0 {
+		t.Fatal("no vulnerabilities")
+	}
+	found := claircore.Vulnerability{}
+	for _, v := range vs {
+		if v.FixedInVersion == "" {
+			t.Fatalf("product criterion reported as vulnerable: %+v", v)
+		}
+		if v.Name == "CVE-2002-2443" && v.Package.Name == "krb5" {
+			found = *v
+		}
+	}
+	if found.Name == "" {
+		t.Fatal("missing CVE-2002-2443")
+	}
+	if got, exp := found.Dist.CPE.String(), "cpe:2.3:o:suse:sled:10:*:*:*:*:*:*:*"; got != exp {
+		t.Errorf("got: %q, found: %q", got, exp)
+	}
+	if !claircore.OpPatternMatch.Cmp("i586", found.Package.Arch) || claircore.OpPatternMatch.Cmp("s390x", found.Package.Arch) {
+		t.Errorf("unexpected arch pattern: %q", found.Package.Arch)
+	}
+}
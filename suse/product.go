@@ -0,0 +1,115 @@
+package suse
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/pkg/cpe"
+)
+
+// SUSE OVAL definitions gate their package criteria on a "product is
+// installed" criterion, which tests the version of the product's release
+// package: "sles-release" at version "15.1" is SLES 15 SP1, for example.
+//
+// The parser turns these criteria into CPEs of the same form as the CPE_NAME
+// in an os-release file and records them in the Vulnerability's Distribution,
+// so that the matcher can check that a record is for the product and service
+// pack the criterion was written for.
+
+// ReleasePackages maps (lowercased) release package names to the CPE vendor
+// and product they indicate.
+var releasePackages = map[string][2]string{
+	"sles-release":          {"suse", "sles"},
+	"sles_sap-release":      {"suse", "sles_sap"},
+	"sled-release":          {"suse", "sled"},
+	"opensuse-release":      {"opensuse", "leap"},
+	"opensuse-leap-release": {"opensuse", "leap"},
+}
+
+// DistIDs maps os-release IDs to the CPE vendor and product they indicate.
+var distIDs = map[string][2]string{
+	"sles":          {"suse", "sles"},
+	"sles_sap":      {"suse", "sles_sap"},
+	"sled":          {"suse", "sled"},
+	"opensuse":      {"opensuse", "leap"},
+	"opensuse-leap": {"opensuse", "leap"},
+}
+
+// ReleaseCPE returns the product CPE for a release package at the provided
+// version, and reports whether the package is a known release package.
+func releaseCPE(name, version string) (cpe.WFN, bool) {
+	vp, ok := releasePackages[strings.ToLower(name)]
+	if !ok || version == "" {
+		return cpe.WFN{}, false
+	}
+	return productCPE(vp, version)
+}
+
+// DistCPE returns the product CPE for a Distribution, using the CPE the
+// distribution scanner found if there is one.
+//
+// A SUSE Distribution without a CPE or a service pack in its VersionID was
+// detected without an os-release file, so its service pack isn't known and
+// false is reported.
+func distCPE(d *claircore.Distribution) (cpe.WFN, bool) {
+	if d.CPE.Valid() == nil {
+		return d.CPE, true
+	}
+	vp, ok := distIDs[d.DID]
+	if !ok {
+		return cpe.WFN{}, false
+	}
+	v := d.VersionID
+	if vp[0] == "suse" && !strings.Contains(v, ".") {
+		return cpe.WFN{}, false
+	}
+	return productCPE(vp, v)
+}
+
+// Versionexp matches versions that can be expressed in a CPE.
+var versionexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// ProductCPE builds a CPE for the vendor and product pair at the provided
+// version.
+//
+// SUSE products put the service pack in the "update" component, so "15.1"
+// becomes "15:sp1" and "15.0" or "15" becomes just "15". openSUSE products use
+// the version as-is.
+func productCPE(vp [2]string, version string) (cpe.WFN, bool) {
+	if !versionexp.MatchString(version) {
+		return cpe.WFN{}, false
+	}
+	v := version
+	if vp[0] == "suse" {
+		if i := strings.IndexByte(version, '.'); i != -1 {
+			v = version[:i]
+			if sp := version[i+1:]; sp != "0" {
+				v += ":sp" + sp
+			}
+		}
+	}
+	w, err := cpe.Unbind("cpe:/o:" + vp[0] + ":" + vp[1] + ":" + v)
+	if err != nil {
+		return cpe.WFN{}, false
+	}
+	return w, true
+}
+
+// ProductMatch reports whether the record's product CPE is within the scope
+// of the vulnerability's product CPE.
+//
+// The vendor, product, and version must be the same. The update (that is, the
+// service pack) only needs to be the same if the vulnerability names one,
+// because some databases only scope criteria to the major release.
+func productMatch(record, vuln cpe.WFN) bool {
+	for _, a := range []cpe.Attribute{cpe.Vendor, cpe.Product, cpe.Version} {
+		if record.Attr[a].V != vuln.Attr[a].V {
+			return false
+		}
+	}
+	if u := vuln.Attr[cpe.Update]; u.Kind == cpe.ValueSet {
+		return record.Attr[cpe.Update].V == u.V
+	}
+	return true
+}
@@ -1,10 +1,15 @@
 package suse
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
 	"github.com/quay/claircore/libvuln/driver"
 	"github.com/quay/claircore/pkg/ovalutil"
 )
@@ -23,6 +28,7 @@ func init() {
 // Updater implements driver.Updater for SUSE.
 type Updater struct {
 	release          Release
+	classes          ovalutil.ClassPolicy
 	ovalutil.Fetcher // promoted Fetch method
 }
 
@@ -36,6 +42,7 @@ var (
 func NewUpdater(r Release, opts ...Option) (*Updater, error) {
 	u := &Updater{
 		release: r,
+		classes: ovalutil.PreferVulnerability,
 	}
 	for _, o := range opts {
 		if err := o(u); err != nil {
@@ -86,6 +93,44 @@ func WithClient(c *http.Client) Option {
 	}
 }
 
+// WithClassPolicy sets how the Updater handles the classes of definitions in
+// the database.
+//
+// If this Option is not supplied, "vulnerability" class definitions are
+// preferred, and "patch" class definitions only used for fixes not in one.
+func WithClassPolicy(p ovalutil.ClassPolicy) Option {
+	return func(u *Updater) error {
+		u.classes = p
+		return nil
+	}
+}
+
+// Config is the configuration for an Updater, in addition to the keys of
+// ovalutil.FetcherConfig.
+type Config struct {
+	Classes *ovalutil.ClassPolicy `json:"classes" yaml:"classes"`
+}
+
+// Configure implements driver.Configurable.
+func (u *Updater) Configure(ctx context.Context, f driver.ConfigUnmarshaler, c *http.Client) error {
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "suse/Updater.Configure"))
+	if err := u.Fetcher.Configure(ctx, f, c); err != nil {
+		return err
+	}
+	var cfg Config
+	if err := f(&cfg); err != nil {
+		return err
+	}
+	if cfg.Classes != nil {
+		u.classes = *cfg.Classes
+		zlog.Info(ctx).
+			Stringer("policy", u.classes).
+			Msg("configured class policy")
+	}
+	return nil
+}
+
 // Name satisfies driver.Updater.
 func (u *Updater) Name() string {
 	return fmt.Sprintf(`suse-updater-%s`, u.release)
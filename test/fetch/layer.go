@@ -1,7 +1,7 @@
 package fetch
 
 import (
-	"compress/gzip"
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/zreader"
 	"github.com/quay/claircore/test/integration"
 )
 
@@ -70,14 +71,21 @@ func Layer(ctx context.Context, t *testing.T, c *http.Client, from, repo string,
 		}
 		defer cf.Close()
 
-		var gr *gzip.Reader
-		gr, err = gzip.NewReader(rc)
+		// Layers may be compressed with gzip or zstd, or not at all.
+		br := bufio.NewReader(rc)
+		var c zreader.Compression
+		c, err = zreader.Sniff(br)
 		if err != nil {
 			return err
 		}
-		defer gr.Close()
+		var zr io.ReadCloser
+		zr, err = zreader.Reader(br, c)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
 
-		if _, err = io.Copy(cf, gr); err != nil {
+		if _, err = io.Copy(cf, zr); err != nil {
 			return err
 		}
 		if err = cf.Sync(); err != nil {
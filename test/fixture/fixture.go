@@ -0,0 +1,252 @@
+// Package fixture provides small but realistic layered images for end-to-end
+// tests.
+//
+// Each Image is described in code (see images.go) and checked in as
+// gzip-compressed layers in the "testdata" directory. The generator in the
+// "gen" directory writes the compressed layers; run "go generate" in this
+// directory after changing an Image. TestUpToDate checks that the checked-in
+// layers match their descriptions.
+package fixture
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quay/claircore"
+)
+
+//go:generate go run ./gen
+
+// Image is a named sequence of layers.
+type Image struct {
+	Name   string
+	Layers []Layer
+}
+
+// Layer is the list of entries in a layer's archive, in order.
+type Layer []Entry
+
+// Entry is a single member of a layer's archive.
+//
+// Names are relative to the root and may not begin with "/". A Name ending in
+// "/" is a directory.
+type Entry struct {
+	Name string
+	Body string
+	// Link makes the entry a symlink pointing at Link.
+	Link string
+	// Exec makes a regular file executable. Files in a "bin" directory
+	// always are.
+	Exec bool
+}
+
+// Whiteout returns the entry that deletes "p" from lower layers.
+func Whiteout(p string) Entry {
+	d, f := path.Split(p)
+	return Entry{Name: d + ".wh." + f}
+}
+
+// Opaque returns the entry that hides the contents of the directory "p" in
+// lower layers.
+func Opaque(p string) Entry {
+	return Entry{Name: strings.TrimSuffix(p, "/") + "/.wh..wh..opq"}
+}
+
+// Epoch is the modification time of every entry, so that the archives are
+// reproducible.
+var epoch = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteTo writes the layer as an uncompressed tar archive.
+func (l Layer) WriteTo(w io.Writer) (int64, error) {
+	cw := countWriter{w: w}
+	tw := tar.NewWriter(&cw)
+	for _, e := range l {
+		h := tar.Header{
+			Name:    e.Name,
+			ModTime: epoch,
+			Mode:    0644,
+			Format:  tar.FormatPAX,
+		}
+		switch {
+		case strings.HasSuffix(e.Name, "/"):
+			h.Typeflag = tar.TypeDir
+			h.Mode = 0755
+		case e.Link != "":
+			h.Typeflag = tar.TypeSymlink
+			h.Linkname = e.Link
+			h.Mode = 0777
+		default:
+			h.Typeflag = tar.TypeReg
+			h.Size = int64(len(e.Body))
+			if e.Exec || strings.Contains(e.Name, "/bin/") {
+				h.Mode = 0755
+			}
+		}
+		if err := tw.WriteHeader(&h); err != nil {
+			return cw.n, err
+		}
+		if _, err := io.WriteString(tw, e.Body); err != nil {
+			return cw.n, err
+		}
+	}
+	err := tw.Close()
+	return cw.n, err
+}
+
+// Files returns a Layer of regular files with the provided contents, in name
+// order.
+func Files(files map[string]string) Layer {
+	ns := make([]string, 0, len(files))
+	for n := range files {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	l := make(Layer, len(ns))
+	for i, n := range ns {
+		l[i] = Entry{Name: n, Body: files[n]}
+	}
+	return l
+}
+
+// Local writes the layer to a temporary file and returns a Layer backed by
+// it, for tests of a single scanner. The Layer's digest is of the archive.
+func (l Layer) Local(t testing.TB) *claircore.Layer {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "layer.tar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := l.WriteTo(io.MultiWriter(f, h)); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	d, err := claircore.NewDigest("sha256", h.Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &claircore.Layer{Hash: d}
+	if err := out.SetLocal(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// Lookup returns the Image with the provided name.
+func Lookup(name string) (*Image, error) {
+	for _, img := range Images {
+		if img.Name == name {
+			return img, nil
+		}
+	}
+	return nil, fmt.Errorf("fixture: no image %q", name)
+}
+
+// Dir returns the directory the compressed layers are stored in.
+func Dir() string {
+	_, f, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(f), "testdata")
+}
+
+// LayerPath returns the path of the compressed layer "n" of the named image.
+func LayerPath(name string, n int) string {
+	return filepath.Join(Dir(), name, fmt.Sprintf("%d.tar.gz", n))
+}
+
+// Manifest decompresses the named image's layers into a temporary directory
+// and returns a Manifest describing them. The layers are already fetched, so
+// the Manifest can be passed straight to an indexer.
+//
+// Layer digests are of the compressed layers, as they would be in a registry.
+// The manifest digest is derived from the layer digests.
+func Manifest(t testing.TB, name string) *claircore.Manifest {
+	t.Helper()
+	img, err := Lookup(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	m := claircore.Manifest{}
+	mh := sha256.New()
+	for i := range img.Layers {
+		l, err := realize(dir, LayerPath(name, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(mh, l.Hash.String())
+		m.Layers = append(m.Layers, l)
+	}
+	m.Hash, err = claircore.NewDigest("sha256", mh.Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &m
+}
+
+// Realize decompresses the layer at "p" into "dir".
+func realize(dir, p string) (*claircore.Layer, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	gz, err := gzip.NewReader(io.TeeReader(f, h))
+	if err != nil {
+		return nil, err
+	}
+	out, err := os.CreateTemp(dir, "layer.")
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, gz); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	// Hash anything the gzip reader didn't need to read.
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	d, err := claircore.NewDigest("sha256", h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(p)}
+	l := claircore.Layer{
+		Hash: d,
+		URI:  u.String(),
+	}
+	if err := l.SetLocal(out.Name()); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
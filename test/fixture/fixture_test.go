@@ -0,0 +1,154 @@
+package fixture_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/debian"
+	"github.com/quay/claircore/libindex"
+	"github.com/quay/claircore/test/fixture"
+)
+
+// TestUpToDate checks that the checked-in layers are what the generator would
+// write.
+func TestUpToDate(t *testing.T) {
+	for _, img := range fixture.Images {
+		for i, l := range img.Layers {
+			p := fixture.LayerPath(img.Name, i)
+			f, err := os.Open(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(gz)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want bytes.Buffer
+			if _, err := l.WriteTo(&want); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want.Bytes()) {
+				t.Errorf("%s: out of date, run \"go generate\"", p)
+			}
+		}
+	}
+}
+
+// Index runs the named image through an ephemeral indexer with the default
+// ecosystems.
+func index(ctx context.Context, t *testing.T, name string) *claircore.IndexReport {
+	t.Helper()
+	lib, err := libindex.New(ctx, &libindex.Opts{Ephemeral: true}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lib.Close(ctx) })
+	ir, err := lib.Index(ctx, fixture.Manifest(t, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ir.Success {
+		t.Fatalf("index failed: %s", ir.Err)
+	}
+	return ir
+}
+
+// Installed returns the names and versions of the packages in the report.
+func installed(ir *claircore.IndexReport) map[string]string {
+	out := make(map[string]string, len(ir.Packages))
+	for _, p := range ir.Packages {
+		out[p.Name] = p.Version
+	}
+	return out
+}
+
+func TestDebianApp(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := index(ctx, t, "debian-app")
+
+	pkgs := installed(ir)
+	for name, want := range map[string]string{
+		"curl":     "7.74.0-1.3+deb11u1",
+		"libcurl4": "7.74.0-1.3+deb11u1",
+		"zlib1g":   "1:1.2.11.dfsg-2",
+	} {
+		if got := pkgs[name]; got != want {
+			t.Errorf("%s: got: %q, want: %q", name, got, want)
+		}
+	}
+	if v, ok := pkgs["tzdata"]; ok {
+		t.Errorf("tzdata %s: removed in the application layer", v)
+	}
+
+	var m debian.Matcher
+	vuln := &claircore.Vulnerability{
+		Name:           "CVE-2021-22945",
+		Package:        &claircore.Package{Name: "curl", Kind: claircore.SOURCE},
+		Dist:           &claircore.Distribution{VersionCodeName: "bullseye"},
+		FixedInVersion: "7.74.0-1.3+deb11u2",
+	}
+	var hits []string
+	for _, r := range ir.IndexRecords() {
+		// The vulnstore matches binary packages by name or by their source
+		// package's name.
+		src := r.Package.Name
+		if r.Package.Source != nil && r.Package.Source.Name != "" {
+			src = r.Package.Source.Name
+		}
+		if !m.Filter(r) || src != vuln.Package.Name {
+			continue
+		}
+		if r.Distribution == nil || r.Distribution.VersionCodeName != "bullseye" {
+			t.Errorf("%s: unexpected distribution: %+v", r.Package.Name, r.Distribution)
+		}
+		ok, err := m.Vulnerable(ctx, r, vuln)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			hits = append(hits, r.Package.Name)
+		}
+	}
+	if got, want := len(hits), 2; got != want {
+		t.Errorf("got: %d vulnerable packages %v, want: %d", got, hits, want)
+	}
+}
+
+func TestAlpineNode(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	ir := index(ctx, t, "alpine-node")
+
+	pkgs := installed(ir)
+	for name, want := range map[string]string{
+		"musl":    "1.2.2-r3",
+		"nodejs":  "14.17.6-r0",
+		"express": "4.17.1",
+		"lodash":  "4.17.15",
+	} {
+		if got := pkgs[name]; got != want {
+			t.Errorf("%s: got: %q, want: %q", name, got, want)
+		}
+	}
+	var found bool
+	for _, d := range ir.Distributions {
+		if d.DID == "alpine" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no alpine distribution: %+v", ir.Distributions)
+	}
+}
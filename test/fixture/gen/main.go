@@ -0,0 +1,45 @@
+// Gen writes the compressed layers of every fixture image.
+package main
+
+import (
+	"compress/gzip"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/quay/claircore/test/fixture"
+)
+
+func main() {
+	for _, img := range fixture.Images {
+		for i, l := range img.Layers {
+			p := fixture.LayerPath(img.Name, i)
+			if err := write(p, l); err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("wrote %s", p)
+		}
+	}
+}
+
+func write(p string, l fixture.Layer) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewWriterLevel(f, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := l.WriteTo(gz); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
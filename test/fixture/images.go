@@ -0,0 +1,234 @@
+package fixture
+
+import "strings"
+
+// Images is every fixture image.
+var Images = []*Image{
+	debianApp,
+	alpineNode,
+}
+
+// DebianApp is a Debian bullseye base layer with an application installed on
+// top. The application layer installs curl and removes tzdata, so its files
+// and database entries are whited out.
+var debianApp = &Image{
+	Name: "debian-app",
+	Layers: []Layer{
+		{
+			{Name: "bin/"},
+			{Name: "bin/bash", Body: "#!bash\n"},
+			{Name: "bin/sh", Link: "bash"},
+			{Name: "etc/"},
+			{Name: "etc/debian_version", Body: "11.0\n"},
+			{Name: "etc/os-release", Body: debianOSRelease},
+			{Name: "usr/"},
+			{Name: "usr/lib/"},
+			{Name: "usr/lib/x86_64-linux-gnu/"},
+			{Name: "usr/lib/x86_64-linux-gnu/libssl.so.1.1", Body: "libssl\n"},
+			{Name: "usr/lib/x86_64-linux-gnu/libz.so.1", Body: "libz\n"},
+			{Name: "usr/share/"},
+			{Name: "usr/share/doc/"},
+			{Name: "usr/share/doc/zlib1g/"},
+			{Name: "usr/share/doc/zlib1g/copyright", Body: zlibCopyright},
+			{Name: "usr/share/zoneinfo/"},
+			{Name: "usr/share/zoneinfo/UTC", Body: "TZif2\n"},
+			{Name: "var/"},
+			{Name: "var/lib/"},
+			{Name: "var/lib/dpkg/"},
+			{Name: "var/lib/dpkg/info/"},
+			{Name: "var/lib/dpkg/info/bash.list", Body: "/bin/bash\n"},
+			{Name: "var/lib/dpkg/info/libssl1.1:amd64.list", Body: "/usr/lib/x86_64-linux-gnu/libssl.so.1.1\n"},
+			{Name: "var/lib/dpkg/info/tzdata.list", Body: "/usr/share/zoneinfo/UTC\n"},
+			{Name: "var/lib/dpkg/info/zlib1g:amd64.list", Body: "/usr/lib/x86_64-linux-gnu/libz.so.1\n"},
+			{Name: "var/lib/dpkg/info/zlib1g:amd64.md5sums", Body: "d41d8cd98f00b204e9800998ecf8427e  usr/lib/x86_64-linux-gnu/libz.so.1\n"},
+			{Name: "var/lib/dpkg/status", Body: dpkgStatus(debianBase...)},
+		},
+		{
+			{Name: "etc/"},
+			{Name: "etc/app/"},
+			{Name: "etc/app/config.yaml", Body: "listen: :8080\n"},
+			{Name: "usr/"},
+			{Name: "usr/bin/"},
+			{Name: "usr/bin/curl", Body: "#!curl\n"},
+			{Name: "usr/lib/"},
+			{Name: "usr/lib/x86_64-linux-gnu/"},
+			{Name: "usr/lib/x86_64-linux-gnu/libcurl.so.4", Body: "libcurl\n"},
+			{Name: "usr/local/"},
+			{Name: "usr/local/bin/"},
+			{Name: "usr/local/bin/app", Body: "#!app\n"},
+			{Name: "usr/share/"},
+			Whiteout("usr/share/zoneinfo"),
+			{Name: "var/"},
+			{Name: "var/lib/"},
+			{Name: "var/lib/dpkg/"},
+			{Name: "var/lib/dpkg/info/"},
+			{Name: "var/lib/dpkg/info/curl.list", Body: "/usr/bin/curl\n"},
+			{Name: "var/lib/dpkg/info/libcurl4:amd64.list", Body: "/usr/lib/x86_64-linux-gnu/libcurl.so.4\n"},
+			Whiteout("var/lib/dpkg/info/tzdata.list"),
+			{Name: "var/lib/dpkg/status", Body: dpkgStatus(debianApplication...)},
+		},
+		{
+			{Name: "tmp/"},
+			Opaque("tmp"),
+			{Name: "tmp/app.pid", Body: "1\n"},
+		},
+	},
+}
+
+const debianOSRelease = `PRETTY_NAME="Debian GNU/Linux 11 (bullseye)"
+NAME="Debian GNU/Linux"
+VERSION_ID="11"
+VERSION="11 (bullseye)"
+VERSION_CODENAME=bullseye
+ID=debian
+HOME_URL="https://www.debian.org/"
+SUPPORT_URL="https://www.debian.org/support"
+BUG_REPORT_URL="https://bugs.debian.org/"
+`
+
+const zlibCopyright = `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: zlib
+
+Files: *
+Copyright: 1995-2013 Jean-loup Gailly and Mark Adler
+License: Zlib
+`
+
+// DpkgPackage is enough of a package to write a "status" file entry.
+type dpkgPackage struct {
+	Name, Source, Version, Arch string
+}
+
+var debianBase = []dpkgPackage{
+	{Name: "base-files", Version: "11.1+deb11u1", Arch: "amd64"},
+	{Name: "bash", Version: "5.1-2+b3", Arch: "amd64"},
+	{Name: "libc6", Source: "glibc", Version: "2.31-13+deb11u2", Arch: "amd64"},
+	{Name: "libssl1.1", Source: "openssl", Version: "1.1.1k-1+deb11u1", Arch: "amd64"},
+	{Name: "tzdata", Version: "2021a-1+deb11u2", Arch: "all"},
+	{Name: "zlib1g", Source: "zlib", Version: "1:1.2.11.dfsg-2", Arch: "amd64"},
+}
+
+var debianApplication = []dpkgPackage{
+	{Name: "base-files", Version: "11.1+deb11u1", Arch: "amd64"},
+	{Name: "bash", Version: "5.1-2+b3", Arch: "amd64"},
+	{Name: "curl", Version: "7.74.0-1.3+deb11u1", Arch: "amd64"},
+	{Name: "libc6", Source: "glibc", Version: "2.31-13+deb11u2", Arch: "amd64"},
+	{Name: "libcurl4", Source: "curl", Version: "7.74.0-1.3+deb11u1", Arch: "amd64"},
+	{Name: "libssl1.1", Source: "openssl", Version: "1.1.1k-1+deb11u1", Arch: "amd64"},
+	{Name: "zlib1g", Source: "zlib", Version: "1:1.2.11.dfsg-2", Arch: "amd64"},
+}
+
+// DpkgStatus returns a "status" file with the provided packages installed.
+func dpkgStatus(ps ...dpkgPackage) string {
+	var b strings.Builder
+	for i, p := range ps {
+		if i != 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString("Package: " + p.Name + "\n")
+		b.WriteString("Status: install ok installed\n")
+		b.WriteString("Priority: optional\n")
+		b.WriteString("Maintainer: Debian Maintainers <debian@example.org>\n")
+		b.WriteString("Architecture: " + p.Arch + "\n")
+		if p.Source != "" {
+			b.WriteString("Source: " + p.Source + "\n")
+		}
+		b.WriteString("Version: " + p.Version + "\n")
+		b.WriteString("Description: " + p.Name + "\n")
+	}
+	return b.String()
+}
+
+// AlpineNode is an Alpine base layer with Node.js and an application's
+// node_modules installed on top. The last layer removes one of the modules.
+var alpineNode = &Image{
+	Name: "alpine-node",
+	Layers: []Layer{
+		{
+			{Name: "bin/"},
+			{Name: "bin/busybox", Body: "#!busybox\n"},
+			{Name: "bin/sh", Link: "/bin/busybox"},
+			{Name: "etc/"},
+			{Name: "etc/alpine-release", Body: "3.14.2\n"},
+			{Name: "etc/os-release", Body: alpineOSRelease},
+			{Name: "lib/"},
+			{Name: "lib/apk/"},
+			{Name: "lib/apk/db/"},
+			{Name: "lib/apk/db/installed", Body: apkInstalled(alpineBase...)},
+			{Name: "lib/ld-musl-x86_64.so.1", Body: "musl\n"},
+		},
+		{
+			{Name: "lib/"},
+			{Name: "lib/apk/"},
+			{Name: "lib/apk/db/"},
+			{Name: "lib/apk/db/installed", Body: apkInstalled(append(alpineBase[:len(alpineBase):len(alpineBase)], alpineNodejs...)...)},
+			{Name: "usr/"},
+			{Name: "usr/bin/"},
+			{Name: "usr/bin/node", Body: "#!node\n"},
+		},
+		{
+			{Name: "usr/"},
+			{Name: "usr/src/"},
+			{Name: "usr/src/app/"},
+			{Name: "usr/src/app/index.js", Body: "require('express')\n"},
+			{Name: "usr/src/app/package.json", Body: `{"name":"app","version":"1.0.0","dependencies":{"express":"^4.17.1","left-pad":"^1.3.0","lodash":"^4.17.15"}}` + "\n"},
+			{Name: "usr/src/app/node_modules/"},
+			{Name: "usr/src/app/node_modules/express/"},
+			{Name: "usr/src/app/node_modules/express/package.json", Body: npmManifest("express", "4.17.1")},
+			{Name: "usr/src/app/node_modules/left-pad/"},
+			{Name: "usr/src/app/node_modules/left-pad/package.json", Body: npmManifest("left-pad", "1.3.0")},
+			{Name: "usr/src/app/node_modules/lodash/"},
+			{Name: "usr/src/app/node_modules/lodash/package.json", Body: npmManifest("lodash", "4.17.15")},
+		},
+		{
+			{Name: "usr/"},
+			{Name: "usr/src/"},
+			{Name: "usr/src/app/"},
+			{Name: "usr/src/app/node_modules/"},
+			Whiteout("usr/src/app/node_modules/left-pad"),
+		},
+	},
+}
+
+const alpineOSRelease = `NAME="Alpine Linux"
+ID=alpine
+VERSION_ID=3.14.2
+PRETTY_NAME="Alpine Linux v3.14"
+HOME_URL="https://alpinelinux.org/"
+BUG_REPORT_URL="https://bugs.alpinelinux.org/"
+`
+
+// ApkPackage is enough of a package to write an "installed" database entry.
+type apkPackage struct {
+	Name, Origin, Version string
+}
+
+var alpineBase = []apkPackage{
+	{Name: "musl", Origin: "musl", Version: "1.2.2-r3"},
+	{Name: "busybox", Origin: "busybox", Version: "1.33.1-r3"},
+	{Name: "zlib", Origin: "zlib", Version: "1.2.11-r3"},
+	{Name: "libcrypto1.1", Origin: "openssl", Version: "1.1.1k-r0"},
+}
+
+var alpineNodejs = []apkPackage{
+	{Name: "nodejs", Origin: "nodejs", Version: "14.17.6-r0"},
+}
+
+// ApkInstalled returns an "installed" database with the provided packages.
+func apkInstalled(ps ...apkPackage) string {
+	var b strings.Builder
+	for _, p := range ps {
+		b.WriteString("P:" + p.Name + "\n")
+		b.WriteString("V:" + p.Version + "\n")
+		b.WriteString("A:x86_64\n")
+		b.WriteString("T:" + p.Name + "\n")
+		b.WriteString("o:" + p.Origin + "\n")
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// NpmManifest returns a minimal package.json.
+func npmManifest(name, version string) string {
+	return `{"name":"` + name + `","version":"` + version + `"}` + "\n"
+}
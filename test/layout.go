@@ -0,0 +1,77 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// WriteLayout writes an OCI image layout into "dir" holding a single image
+// named "ref", with the provided layer blobs. It returns the digest of the
+// image's manifest.
+func WriteLayout(t testing.TB, dir, ref string, layers ...[]byte) string {
+	t.Helper()
+	blob := func(b []byte) string {
+		sum := sha256.Sum256(b)
+		d := filepath.Join(dir, "blobs", "sha256")
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(d, hex.EncodeToString(sum[:])), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+	marshal := func(v interface{}) []byte {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	type desc struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Size        int               `json:"size"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+	}
+
+	cfg := marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"config": map[string]interface{}{
+			"Labels": map[string]string{"org.opencontainers.image.title": ref},
+		},
+	})
+	m := struct {
+		SchemaVersion int    `json:"schemaVersion"`
+		MediaType     string `json:"mediaType"`
+		Config        desc   `json:"config"`
+		Layers        []desc `json:"layers"`
+	}{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.oci.image.manifest.v1+json",
+		Config:        desc{"application/vnd.oci.image.config.v1+json", blob(cfg), len(cfg), nil},
+	}
+	for _, l := range layers {
+		m.Layers = append(m.Layers, desc{"application/vnd.oci.image.layer.v1.tar", blob(l), len(l), nil})
+	}
+	mb := marshal(m)
+	md := blob(mb)
+	idx := marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"manifests": []desc{
+			{m.MediaType, md, len(mb), map[string]string{"org.opencontainers.image.ref.name": ref}},
+		},
+	})
+	if err := os.WriteFile(filepath.Join(dir, "index.json"), idx, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return md
+}
@@ -61,8 +61,11 @@ func (tc ScannerTestcase) Run(ctx context.Context) func(*testing.T) {
 		}
 		sort.Slice(got, pkgSort(got))
 		t.Logf("found %d packages", len(got))
-		if !cmp.Equal(tc.Want, got) {
-			t.Error(cmp.Diff(tc.Want, got))
+		opts := []cmp.Option{
+			cmp.Comparer(func(a, b claircore.Digest) bool { return a.String() == b.String() }),
+		}
+		if !cmp.Equal(tc.Want, got, opts...) {
+			t.Error(cmp.Diff(tc.Want, got, opts...))
 		}
 	}
 }
@@ -1,10 +1,12 @@
 package ubuntu
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"regexp"
 	"runtime/trace"
+	"strings"
 
 	"github.com/quay/zlog"
 	"go.opentelemetry.io/otel/baggage"
@@ -16,7 +18,7 @@ import (
 
 const (
 	scannerName    = "ubuntu"
-	scannerVersion = "v0.0.1"
+	scannerVersion = "v0.0.2"
 	scannerKind    = "distribution"
 )
 
@@ -25,6 +27,8 @@ type ubuntuRegex struct {
 	regexp  *regexp.Regexp
 }
 
+// UbuntuRegexes is the table of known releases, for files that don't record
+// a codename.
 var ubuntuRegexes = []ubuntuRegex{
 	{
 		release: Artful,
@@ -73,6 +77,7 @@ const lsbReleasePath = `etc/lsb-release`
 
 var _ indexer.DistributionScanner = (*DistributionScanner)(nil)
 var _ indexer.VersionedScanner = (*DistributionScanner)(nil)
+var _ indexer.PathDeclarer = (*DistributionScanner)(nil)
 
 // DistributionScanner attempts to discover if a layer
 // displays characteristics of a Ubuntu distribution
@@ -87,11 +92,19 @@ func (*DistributionScanner) Version() string { return scannerVersion }
 // Kind implements scanner.VersionedScanner.
 func (*DistributionScanner) Kind() string { return scannerKind }
 
-// Scan will inspect the layer for an os-release or lsb-release file
-// and perform a regex match for keywords indicating the associated Ubuntu release
+// Paths implements indexer.PathDeclarer.
+func (*DistributionScanner) Paths() []string {
+	return []string{osReleasePath, lsbReleasePath}
+}
+
+// Scan will inspect the layer for an os-release or lsb-release file and
+// construct the Ubuntu release from the version and codename they record.
+//
+// Files that don't record a codename, as in some very old releases, are
+// matched against the table of known releases instead.
 //
 // If neither file is found a (nil,nil) is returned.
-// If the files are found but all regexp fail to match an empty slice is returned.
+// If the files are found but aren't for Ubuntu an empty slice is returned.
 func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Distribution, error) {
 	defer trace.StartRegion(ctx, "Scanner.Scan").End()
 	ctx = baggage.ContextWithValues(ctx,
@@ -105,7 +118,28 @@ func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]
 		zlog.Debug(ctx).Msg("didn't find an os-release or lsb release file")
 		return nil, nil
 	}
-	for _, buff := range files {
+	paths := []string{osReleasePath, lsbReleasePath}
+	for _, p := range paths {
+		buff, ok := files[p]
+		if !ok {
+			continue
+		}
+		var dist *claircore.Distribution
+		switch p {
+		case osReleasePath:
+			dist = parseOSRelease(buff.Bytes())
+		case lsbReleasePath:
+			dist = parseLSBRelease(buff.Bytes())
+		}
+		if dist != nil {
+			return []*claircore.Distribution{dist}, nil
+		}
+	}
+	for _, p := range paths {
+		buff, ok := files[p]
+		if !ok {
+			continue
+		}
 		dist := ds.parse(buff)
 		if dist != nil {
 			return []*claircore.Distribution{dist}, nil
@@ -126,3 +160,65 @@ func (ds *DistributionScanner) parse(buff *bytes.Buffer) *claircore.Distribution
 	}
 	return nil
 }
+
+// ParseOSRelease returns the release described by an os-release file, or nil
+// if it's not Ubuntu or doesn't name a codename.
+func parseOSRelease(b []byte) *claircore.Distribution {
+	kv := parseKV(b)
+	if kv["ID"] != "ubuntu" {
+		return nil
+	}
+	codename := kv["VERSION_CODENAME"]
+	if codename == "" {
+		codename = kv["UBUNTU_CODENAME"]
+	}
+	return mkDist(codename, kv["VERSION_ID"])
+}
+
+// ParseLSBRelease returns the release described by an lsb-release file, or
+// nil if it's not Ubuntu or doesn't name a codename.
+func parseLSBRelease(b []byte) *claircore.Distribution {
+	kv := parseKV(b)
+	if kv["DISTRIB_ID"] != "Ubuntu" {
+		return nil
+	}
+	return mkDist(kv["DISTRIB_CODENAME"], kv["DISTRIB_RELEASE"])
+}
+
+// MkDist returns the Distribution for the codename, filling in the version
+// for releases not known to this package.
+func mkDist(codename, versionID string) *claircore.Distribution {
+	if codename == "" {
+		return nil
+	}
+	r := Release(codename)
+	if _, ok := AllReleases[r]; ok {
+		return releaseToDist(r)
+	}
+	d := *releaseToDist(r)
+	d.VersionID = versionID
+	d.PrettyName = "Ubuntu " + codename
+	if versionID != "" {
+		d.PrettyName = "Ubuntu " + versionID
+	}
+	return &d
+}
+
+// ParseKV parses the shell-style assignments used by os-release and
+// lsb-release.
+func parseKV(b []byte) map[string]string {
+	kv := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i == -1 {
+			continue
+		}
+		kv[line[:i]] = strings.Trim(line[i+1:], `"'`)
+	}
+	return kv
+}
@@ -2,9 +2,14 @@ package ubuntu
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
 )
 
 // impish test data
@@ -257,6 +262,16 @@ func TestDistributionScanner(t *testing.T) {
 	}
 	for _, tt := range table {
 		t.Run(tt.name, func(t *testing.T) {
+			// Files from releases that record a codename resolve the same
+			// way without the regexp table.
+			want := releaseToDist(tt.release)
+			if dist := parseOSRelease(tt.osRelease); dist != nil && !cmp.Equal(dist, want) {
+				t.Error(cmp.Diff(dist, want))
+			}
+			if dist := parseLSBRelease(tt.lsbRelease); !cmp.Equal(dist, want) {
+				t.Error(cmp.Diff(dist, want))
+			}
+
 			scanner := DistributionScanner{}
 			dist := scanner.parse(bytes.NewBuffer(tt.osRelease))
 			if !cmp.Equal(dist, releaseToDist(tt.release)) {
@@ -269,3 +284,103 @@ func TestDistributionScanner(t *testing.T) {
 		})
 	}
 }
+
+var nobleOSRelease = `PRETTY_NAME="Ubuntu 24.04 LTS"
+NAME="Ubuntu"
+VERSION_ID="24.04"
+VERSION="24.04 LTS (Noble Numbat)"
+VERSION_CODENAME=noble
+ID=ubuntu
+ID_LIKE=debian
+HOME_URL="https://www.ubuntu.com/"
+UBUNTU_CODENAME=noble
+LOGO=ubuntu-logo
+`
+
+var oracularOSRelease = `PRETTY_NAME="Ubuntu 24.10"
+NAME="Ubuntu"
+VERSION_ID="24.10"
+VERSION="24.10 (Oracular Oriole)"
+VERSION_CODENAME=oracular
+ID=ubuntu
+ID_LIKE=debian
+UBUNTU_CODENAME=oracular
+`
+
+func TestDistributionScannerFiles(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	table := []struct {
+		name  string
+		files map[string]string
+		want  []*claircore.Distribution
+	}{
+		{
+			name:  "Noble",
+			files: map[string]string{"etc/os-release": nobleOSRelease},
+			want:  []*claircore.Distribution{nobleDist},
+		},
+		{
+			name: "Unreleased",
+			files: map[string]string{
+				"etc/os-release": oracularOSRelease,
+			},
+			want: []*claircore.Distribution{{
+				Name:            "Ubuntu",
+				Version:         "oracular",
+				DID:             "ubuntu",
+				PrettyName:      "Ubuntu 24.10",
+				VersionID:       "24.10",
+				VersionCodeName: "oracular",
+			}},
+		},
+		{
+			name: "LSBOnly",
+			files: map[string]string{
+				"etc/lsb-release": "DISTRIB_ID=Ubuntu\nDISTRIB_RELEASE=22.04\nDISTRIB_CODENAME=jammy\n",
+			},
+			want: []*claircore.Distribution{jammyDist},
+		},
+		{
+			name: "Trusty",
+			files: map[string]string{
+				"etc/os-release":  string(trustyOSRelease),
+				"etc/lsb-release": string(trustyLSBRelease),
+			},
+			want: []*claircore.Distribution{trustyDist},
+		},
+		{
+			name: "Debian",
+			files: map[string]string{
+				"etc/os-release": "ID=debian\nVERSION_ID=\"11\"\nVERSION_CODENAME=bullseye\n",
+			},
+			want: []*claircore.Distribution{},
+		},
+	}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			var s DistributionScanner
+			got, err := s.Scan(ctx, fixture.Files(tc.files).Local(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+}
+
+// TestUnreleasedMatch confirms the updater and scanner agree on the
+// Distribution for a release this package doesn't know about.
+func TestUnreleasedMatch(t *testing.T) {
+	u := NewUpdater(Release("oracular"))
+	if u == nil {
+		t.Fatal("no updater for unknown release")
+	}
+	vd := releaseToDist(u.release)
+	sd := parseOSRelease([]byte(oracularOSRelease))
+	if vd.DID != sd.DID || vd.Name != sd.Name || vd.Version != sd.Version {
+		t.Errorf("updater: %+v, scanner: %+v", vd, sd)
+	}
+}
@@ -0,0 +1,147 @@
+package ubuntu
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	version "github.com/knqyf263/go-deb-version"
+
+	"github.com/quay/claircore"
+)
+
+// Ubuntu kernel binary packages have the kernel ABI in their names, such as
+// "linux-image-5.4.0-1055-aws", so a fixed kernel is always a different
+// package than the vulnerable one. Systems track a flavor through
+// metapackages, such as "linux-image-aws", which depend on the current ABI's
+// packages and are versioned like "5.4.0.1055.58": the upstream version, the
+// ABI, and an upload number that's independent of the kernel's own.
+//
+// To match kernels meaningfully, kernel vulnerabilities are additionally
+// recorded against the flavor's metapackages, with the upload number dropped,
+// and metapackage versions are compared by upstream version and ABI only.
+
+// KernelBinary matches the ABI-versioned kernel binary packages, capturing the
+// kind, the upstream version, the ABI, and the flavor.
+var kernelBinary = regexp.MustCompile(`^linux-(image|image-unsigned|headers|modules)-(\d+\.\d+\.\d+)-(\d+)-([a-z][a-z0-9-]*)$`)
+
+// KernelVersion matches both kernel package versions ("5.4.0-1055.58") and
+// metapackage versions ("5.4.0.1055.58"), capturing the upstream version and
+// the ABI.
+var kernelVersion = regexp.MustCompile(`^(\d+\.\d+\.\d+)[.-](\d+)(\.|$)`)
+
+// KernelMetapackages returns the metapackages tracking the flavor of the
+// kernel binary package "name", or nil if "name" isn't a kernel binary
+// package.
+func kernelMetapackages(name string) []string {
+	m := kernelBinary.FindStringSubmatch(name)
+	if m == nil {
+		return nil
+	}
+	flavor := m[4]
+	switch m[1] {
+	case "image", "image-unsigned":
+		return []string{"linux-" + flavor, "linux-image-" + flavor}
+	case "headers":
+		return []string{"linux-headers-" + flavor}
+	}
+	return nil
+}
+
+// MetaVersion matches metapackage versions, which have the ABI as a fourth
+// dotted component.
+var metaVersion = regexp.MustCompile(`^\d+\.\d+\.\d+\.\d+(\.|$)`)
+
+// IsKernelMetapackage reports whether the package is a kernel metapackage.
+func isKernelMetapackage(p *claircore.Package) bool {
+	return strings.HasPrefix(p.Name, "linux-") &&
+		!kernelBinary.MatchString(p.Name) &&
+		metaVersion.MatchString(p.Version)
+}
+
+// SplitKernelVersion returns the upstream version and ABI of a kernel or
+// kernel metapackage version.
+func splitKernelVersion(v string) (string, int, bool) {
+	m := kernelVersion.FindStringSubmatch(v)
+	if m == nil {
+		return "", 0, false
+	}
+	abi, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return m[1], abi, true
+}
+
+// KernelMetaVulns returns copies of the kernel binary package vulnerabilities
+// in "vs", recorded against the flavor's metapackages.
+func kernelMetaVulns(vs []*claircore.Vulnerability) []*claircore.Vulnerability {
+	type key struct{ vuln, pkg string }
+	seen := make(map[key]struct{})
+	pkgs := make(map[string]*claircore.Package)
+	var out []*claircore.Vulnerability
+	for _, v := range vs {
+		if v.Package == nil {
+			continue
+		}
+		metas := kernelMetapackages(v.Package.Name)
+		if metas == nil {
+			continue
+		}
+		fixed := ""
+		if v.FixedInVersion != "" {
+			up, abi, ok := splitKernelVersion(v.FixedInVersion)
+			if !ok {
+				continue
+			}
+			fixed = up + "." + strconv.Itoa(abi)
+		}
+		for _, n := range metas {
+			k := key{v.Name, n}
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			p, ok := pkgs[n]
+			if !ok {
+				p = &claircore.Package{Name: n, Kind: claircore.BINARY}
+				pkgs[n] = p
+			}
+			mv := *v
+			mv.Package = p
+			mv.FixedInVersion = fixed
+			out = append(out, &mv)
+		}
+	}
+	return out
+}
+
+// KernelVulnerable reports whether the kernel metapackage version "have" is
+// older than the fixed version "fixed", comparing only the upstream version
+// and ABI. The final result is false if either version isn't a kernel
+// version.
+func kernelVulnerable(have, fixed string) (vulnerable bool, ok bool) {
+	hu, ha, ok := splitKernelVersion(have)
+	if !ok {
+		return false, false
+	}
+	fu, fa, ok := splitKernelVersion(fixed)
+	if !ok {
+		return false, false
+	}
+	hv, err := version.NewVersion(hu)
+	if err != nil {
+		return false, false
+	}
+	fv, err := version.NewVersion(fu)
+	if err != nil {
+		return false, false
+	}
+	switch {
+	case hv.LessThan(fv):
+		return true, true
+	case fv.LessThan(hv):
+		return false, true
+	}
+	return ha < fa, true
+}
@@ -0,0 +1,89 @@
+package ubuntu
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/quay/claircore"
+)
+
+func TestKernelMetaVulns(t *testing.T) {
+	dist := releaseToDist(Focal)
+	in := []*claircore.Vulnerability{
+		{
+			Name:           "CVE-2021-1234",
+			Package:        &claircore.Package{Name: "linux-image-5.4.0-1056-aws", Kind: claircore.BINARY},
+			FixedInVersion: "5.4.0-1056.59",
+			Dist:           dist,
+		},
+		{
+			Name:           "CVE-2021-1234",
+			Package:        &claircore.Package{Name: "linux-image-unsigned-5.4.0-1056-aws", Kind: claircore.BINARY},
+			FixedInVersion: "5.4.0-1056.59",
+			Dist:           dist,
+		},
+		{
+			Name:           "CVE-2021-1234",
+			Package:        &claircore.Package{Name: "linux-headers-5.4.0-91-generic", Kind: claircore.BINARY},
+			FixedInVersion: "5.4.0-91.102",
+			Dist:           dist,
+		},
+		{
+			Name:           "CVE-2021-5678",
+			Package:        &claircore.Package{Name: "openssl", Kind: claircore.BINARY},
+			FixedInVersion: "1.1.1f-1ubuntu2.10",
+			Dist:           dist,
+		},
+	}
+	got := kernelMetaVulns(in)
+	type result struct{ Name, Package, Fixed string }
+	var rs []result
+	for _, v := range got {
+		rs = append(rs, result{v.Name, v.Package.Name, v.FixedInVersion})
+	}
+	want := []result{
+		{"CVE-2021-1234", "linux-aws", "5.4.0.1056"},
+		{"CVE-2021-1234", "linux-image-aws", "5.4.0.1056"},
+		{"CVE-2021-1234", "linux-headers-generic", "5.4.0.91"},
+	}
+	if !cmp.Equal(rs, want) {
+		t.Error(cmp.Diff(rs, want))
+	}
+}
+
+func TestKernelMatcher(t *testing.T) {
+	ctx := context.Background()
+	m := &Matcher{}
+	tt := []struct {
+		Name    string
+		Version string
+		Fixed   string
+		Want    bool
+	}{
+		// Metapackages are compared by upstream version and ABI, ignoring
+		// the upload number.
+		{"linux-image-aws", "5.4.0.1055.58", "5.4.0.1056", true},
+		{"linux-image-aws", "5.4.0.1056.58", "5.4.0.1056", false},
+		{"linux-aws", "5.4.0.1057.57", "5.4.0.1056", false},
+		{"linux-image-generic", "5.4.0.90.94", "5.4.0-91.102", true},
+		{"linux-image-generic-hwe-20.04", "5.11.0.41.45~20.04.19", "5.13.0.21", true},
+		// Everything else uses the usual comparison.
+		{"linux-image-5.4.0-1055-aws", "5.4.0-1055.58", "5.4.0-1055.59", true},
+		{"linux-libc-dev", "5.4.0-90.101", "5.4.0-90.102", true},
+		{"openssl", "1.1.1f-1ubuntu2.10", "1.1.1f-1ubuntu2.9", false},
+	}
+	for _, tc := range tt {
+		got, err := m.Vulnerable(ctx,
+			&claircore.IndexRecord{Package: &claircore.Package{Name: tc.Name, Version: tc.Version}},
+			&claircore.Vulnerability{Package: &claircore.Package{Name: tc.Name}, FixedInVersion: tc.Fixed})
+		if err != nil {
+			t.Errorf("%s: %v", tc.Name, err)
+			continue
+		}
+		if got != tc.Want {
+			t.Errorf("%s %s < %s: got: %v, want: %v", tc.Name, tc.Version, tc.Fixed, got, tc.Want)
+		}
+	}
+}
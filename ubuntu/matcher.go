@@ -49,6 +49,11 @@ func (*Matcher) Vulnerable(ctx context.Context, record *claircore.IndexRecord, v
 	if vuln.FixedInVersion == "" {
 		return true, nil
 	}
+	if isKernelMetapackage(record.Package) {
+		if v, ok := kernelVulnerable(record.Package.Version, vuln.FixedInVersion); ok {
+			return v, nil
+		}
+	}
 
 	v1, err := version.NewVersion(record.Package.Version)
 	if err != nil {
@@ -35,6 +35,7 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 			Name:               def.Title,
 			Description:        def.Description,
 			Issued:             def.Advisory.Issued.Date,
+			Modified:           def.Advisory.Updated.Date,
 			Links:              ovalutil.Links(def),
 			NormalizedSeverity: normalizeSeverity(def.Advisory.Severity),
 			Dist:               releaseToDist(u.release),
@@ -46,7 +47,11 @@ func (u *Updater) Parse(ctx context.Context, r io.ReadCloser) ([]*claircore.Vuln
 	if err != nil {
 		return nil, err
 	}
-	return vulns, nil
+	meta := kernelMetaVulns(vulns)
+	zlog.Debug(ctx).
+		Int("count", len(meta)).
+		Msg("added kernel metapackage vulnerabilities")
+	return append(vulns, meta...), nil
 }
 
 func normalizeSeverity(severity string) claircore.Severity {
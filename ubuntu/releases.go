@@ -17,6 +17,11 @@ const (
 	Eoan    Release = "eoan"
 	Focal   Release = "focal"
 	Impish  Release = "impish"
+	Jammy   Release = "jammy"
+	Kinetic Release = "kinetic"
+	Lunar   Release = "lunar"
+	Mantic  Release = "mantic"
+	Noble   Release = "noble"
 )
 
 var AllReleases = map[Release]struct{}{
@@ -30,6 +35,11 @@ var AllReleases = map[Release]struct{}{
 	Eoan:    struct{}{},
 	Focal:   struct{}{},
 	Impish:  struct{}{},
+	Jammy:   struct{}{},
+	Kinetic: struct{}{},
+	Lunar:   struct{}{},
+	Mantic:  struct{}{},
+	Noble:   struct{}{},
 }
 
 var ReleaseToVersionID = map[Release]string{
@@ -43,6 +53,11 @@ var ReleaseToVersionID = map[Release]string{
 	Eoan:    "19.10",
 	Focal:   "20.04",
 	Impish:  "21.10",
+	Jammy:   "22.04",
+	Kinetic: "22.10",
+	Lunar:   "23.04",
+	Mantic:  "23.10",
+	Noble:   "24.04",
 }
 
 var artfulDist = &claircore.Distribution{
@@ -133,6 +148,56 @@ var impishDist = &claircore.Distribution{
 	VersionCodeName: "impish",
 }
 
+var jammyDist = &claircore.Distribution{
+	Name:            "Ubuntu",
+	Version:         "22.04 LTS (Jammy Jellyfish)",
+	DID:             "ubuntu",
+	PrettyName:      "Ubuntu 22.04 LTS",
+	VersionID:       "22.04",
+	VersionCodeName: "jammy",
+}
+
+var kineticDist = &claircore.Distribution{
+	Name:            "Ubuntu",
+	Version:         "22.10 (Kinetic Kudu)",
+	DID:             "ubuntu",
+	PrettyName:      "Ubuntu 22.10",
+	VersionID:       "22.10",
+	VersionCodeName: "kinetic",
+}
+
+var lunarDist = &claircore.Distribution{
+	Name:            "Ubuntu",
+	Version:         "23.04 (Lunar Lobster)",
+	DID:             "ubuntu",
+	PrettyName:      "Ubuntu 23.04",
+	VersionID:       "23.04",
+	VersionCodeName: "lunar",
+}
+
+var manticDist = &claircore.Distribution{
+	Name:            "Ubuntu",
+	Version:         "23.10 (Mantic Minotaur)",
+	DID:             "ubuntu",
+	PrettyName:      "Ubuntu 23.10",
+	VersionID:       "23.10",
+	VersionCodeName: "mantic",
+}
+
+var nobleDist = &claircore.Distribution{
+	Name:            "Ubuntu",
+	Version:         "24.04 LTS (Noble Numbat)",
+	DID:             "ubuntu",
+	PrettyName:      "Ubuntu 24.04 LTS",
+	VersionID:       "24.04",
+	VersionCodeName: "noble",
+}
+
+// ReleaseToDist returns the Distribution for the release.
+//
+// Releases newer than this package are reported with the codename as the
+// Version, as that's all the updater knows about them. The matcher compares
+// Versions, so the scanner must report the same for such releases.
 func releaseToDist(r Release) *claircore.Distribution {
 	switch r {
 	case Artful:
@@ -155,8 +220,39 @@ func releaseToDist(r Release) *claircore.Distribution {
 		return focalDist
 	case Impish:
 		return impishDist
-	default:
+	case Jammy:
+		return jammyDist
+	case Kinetic:
+		return kineticDist
+	case Lunar:
+		return lunarDist
+	case Mantic:
+		return manticDist
+	case Noble:
+		return nobleDist
+	case "":
 		// return empty dist
 		return &claircore.Distribution{}
+	default:
+		return &claircore.Distribution{
+			Name:            "Ubuntu",
+			Version:         string(r),
+			DID:             "ubuntu",
+			VersionCodeName: string(r),
+		}
+	}
+}
+
+// DistributionFor returns the Distribution for the release with the provided
+// version ID, such as "20.04", or nil if it's not a known release.
+//
+// This is the Distribution the DistributionScanner reports for the release,
+// for updaters outside this package importing Ubuntu vulnerabilities.
+func DistributionFor(versionID string) *claircore.Distribution {
+	for r, id := range ReleaseToVersionID {
+		if id == versionID {
+			return releaseToDist(r)
+		}
 	}
+	return nil
 }
@@ -33,6 +33,11 @@ var shouldBzipFetch = map[Release]bool{
 	Focal:   true,
 	Eoan:    true,
 	Impish:  true,
+	Jammy:   true,
+	Kinetic: true,
+	Lunar:   true,
+	Mantic:  true,
+	Noble:   true,
 }
 
 var (
@@ -52,11 +57,18 @@ type Updater struct {
 	curVuln claircore.Vulnerability
 }
 
+// NewUpdater returns an Updater for the named release.
+//
+// Releases unknown to this package are assumed to be published like the
+// recent ones, so a new release can be configured without a code change.
 func NewUpdater(release Release) *Updater {
-	var fetchBzip, ok bool
-	if fetchBzip, ok = shouldBzipFetch[release]; !ok {
+	if release == "" {
 		return nil
 	}
+	fetchBzip, ok := shouldBzipFetch[release]
+	if !ok {
+		fetchBzip = true
+	}
 
 	var url string
 	if fetchBzip {
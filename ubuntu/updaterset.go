@@ -22,6 +22,11 @@ var Releases = []Release{
 	Xenial,
 	Focal,
 	Eoan,
+	Jammy,
+	Kinetic,
+	Lunar,
+	Mantic,
+	Noble,
 }
 
 var (
@@ -17,6 +17,8 @@ type Vulnerability struct {
 	Description string `json:"description"`
 	// the timestamp when vulnerability was issued
 	Issued time.Time `json:"issued"`
+	// the timestamp when the vulnerability was last modified, if known
+	Modified time.Time `json:"modified"`
 	// any links to more details about the vulnerability
 	Links string `json:"links"`
 	// the severity string retrieved from the security database
@@ -37,4 +39,14 @@ type Vulnerability struct {
 	// ArchOperation indicates how the affected Package's "arch" should be
 	// compared.
 	ArchOperation ArchOp `json:"arch_op,omitempty"`
+	// Withdrawn reports whether the vulnerability has been withdrawn or
+	// rejected by its source. Withdrawn vulnerabilities are kept so that
+	// previously reported findings can be explained, but are never reported
+	// as affecting a package.
+	Withdrawn bool `json:"withdrawn,omitempty"`
+	// FixState records why the vulnerability has no fix, if its source says.
+	// For example, the Debian security tracker marks issues that won't get an
+	// advisory as "no-dsa" and ones deferred to a later update as
+	// "postponed".
+	FixState string `json:"fix_state,omitempty"`
 }
@@ -1,6 +1,9 @@
 package claircore
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"sort"
+)
 
 // VulnerabilityReport provides a report of packages and their
 // associated vulnerabilities.
@@ -19,6 +22,30 @@ type VulnerabilityReport struct {
 	Vulnerabilities map[string]*Vulnerability `json:"vulnerabilities"`
 	// a lookup table associating package ids with 1 or more vulnerability ids. keyed by package id
 	PackageVulnerabilities map[string][]string `json:"package_vulnerabilities"`
+	// a lookup table associating package ids with the names of withdrawn vulnerabilities
+	// that would otherwise affect them. keyed by package id
+	WithdrawnVulnerabilities map[string][]string `json:"withdrawn_vulnerabilities,omitempty"`
 	// a map of enrichments keyed by a type.
 	Enrichments map[string][]json.RawMessage `json:"enrichments"`
+	// the verdict of the configured policy, if any
+	Policy *PolicyVerdict `json:"policy,omitempty"`
+}
+
+// Sort puts every collection in the VulnerabilityReport into a stable order,
+// so that serializing the same report twice produces identical output.
+//
+// Maps are already serialized in key order; this sorts the slices: each
+// package's Environments and vulnerability IDs, and the names of withdrawn
+// vulnerabilities, and the Policy's violations.
+func (report *VulnerabilityReport) Sort() {
+	sortEnvironments(report.Environments)
+	for _, ids := range report.PackageVulnerabilities {
+		sort.Slice(ids, func(i, j int) bool { return lessID(ids[i], ids[j]) })
+	}
+	for _, ns := range report.WithdrawnVulnerabilities {
+		sort.Strings(ns)
+	}
+	if report.Policy != nil {
+		report.Policy.Sort()
+	}
 }
@@ -0,0 +1,64 @@
+package claircore
+
+import "time"
+
+// VulnerabilitySummary is the number of vulnerabilities of each severity
+// affecting a manifest.
+//
+// Summaries are much cheaper to store and retrieve than VulnerabilityReports,
+// for use when only the counts are needed.
+type VulnerabilitySummary struct {
+	// Manifest is the manifest the summary describes.
+	Manifest Digest `json:"manifest_hash"`
+	// Updated is when the VulnerabilityReport the summary was made from was
+	// generated.
+	Updated    time.Time `json:"updated"`
+	Unknown    int       `json:"unknown"`
+	Negligible int       `json:"negligible"`
+	Low        int       `json:"low"`
+	Medium     int       `json:"medium"`
+	High       int       `json:"high"`
+	Critical   int       `json:"critical"`
+}
+
+// Total returns the number of vulnerabilities of any severity.
+func (s *VulnerabilitySummary) Total() int {
+	return s.Unknown + s.Negligible + s.Low + s.Medium + s.High + s.Critical
+}
+
+// Summarize counts the vulnerabilities affecting packages in the
+// VulnerabilityReport by their normalized severity.
+func Summarize(vr *VulnerabilityReport) *VulnerabilitySummary {
+	s := VulnerabilitySummary{
+		Manifest: vr.Hash,
+		Updated:  time.Now(),
+	}
+	seen := make(map[string]struct{})
+	for _, ids := range vr.PackageVulnerabilities {
+		for _, id := range ids {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			v, ok := vr.Vulnerabilities[id]
+			if !ok {
+				continue
+			}
+			switch v.NormalizedSeverity {
+			case Negligible:
+				s.Negligible++
+			case Low:
+				s.Low++
+			case Medium:
+				s.Medium++
+			case High:
+				s.High++
+			case Critical:
+				s.Critical++
+			default:
+				s.Unknown++
+			}
+		}
+	}
+	return &s
+}
@@ -0,0 +1,46 @@
+package claircore_test
+
+import (
+	"testing"
+
+	"github.com/quay/claircore"
+)
+
+// TestSummarize confirms vulnerabilities are counted once per report,
+// regardless of how many packages they affect.
+func TestSummarize(t *testing.T) {
+	manifest := claircore.MustParseDigest(`sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef`)
+	vr := claircore.VulnerabilityReport{
+		Hash: manifest,
+		Vulnerabilities: map[string]*claircore.Vulnerability{
+			"1": {ID: "1", NormalizedSeverity: claircore.High},
+			"2": {ID: "2", NormalizedSeverity: claircore.Critical},
+			"3": {ID: "3", NormalizedSeverity: claircore.Unknown},
+			"4": {ID: "4", NormalizedSeverity: claircore.Low},
+		},
+		PackageVulnerabilities: map[string][]string{
+			"a": {"1", "2"},
+			"b": {"1", "3"},
+		},
+	}
+	s := claircore.Summarize(&vr)
+	if got, want := s.Manifest, manifest; got.String() != want.String() {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if got, want := s.Total(), 3; got != want {
+		t.Errorf("total: got: %d, want: %d", got, want)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want int
+	}{
+		{"unknown", s.Unknown, 1},
+		{"low", s.Low, 0},
+		{"high", s.High, 1},
+		{"critical", s.Critical, 1},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got: %d, want: %d", tc.name, tc.got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package wolfi provides distribution detection for Wolfi and Chainguard
+// images.
+//
+// Both are rolling-release distributions built from the Wolfi package
+// repository and managed with apk, so the Alpine package scanner reports their
+// packages. The os-release VERSION_ID is a build date rather than a release,
+// so it's not carried into the reported Distribution.
+package wolfi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"runtime/trace"
+	"strings"
+
+	"github.com/quay/zlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/internal/indexer"
+)
+
+const (
+	scannerName    = "wolfi"
+	scannerVersion = "v0.0.1"
+	scannerKind    = "distribution"
+)
+
+const (
+	osReleasePath    = `etc/os-release`
+	libOSReleasePath = `usr/lib/os-release`
+)
+
+var (
+	// WolfiDist is the Distribution reported for Wolfi images.
+	wolfiDist = &claircore.Distribution{
+		Name:       "Wolfi",
+		DID:        "wolfi",
+		PrettyName: "Wolfi",
+	}
+	// ChainguardDist is the Distribution reported for Chainguard images.
+	chainguardDist = &claircore.Distribution{
+		Name:       "Chainguard",
+		DID:        "chainguard",
+		PrettyName: "Chainguard",
+	}
+)
+
+var _ indexer.DistributionScanner = (*DistributionScanner)(nil)
+var _ indexer.VersionedScanner = (*DistributionScanner)(nil)
+var _ indexer.PathDeclarer = (*DistributionScanner)(nil)
+
+// DistributionScanner reports the Wolfi or Chainguard distribution from a
+// layer's os-release file.
+type DistributionScanner struct{}
+
+// Name implements scanner.VersionedScanner.
+func (*DistributionScanner) Name() string { return scannerName }
+
+// Version implements scanner.VersionedScanner.
+func (*DistributionScanner) Version() string { return scannerVersion }
+
+// Kind implements scanner.VersionedScanner.
+func (*DistributionScanner) Kind() string { return scannerKind }
+
+// Paths implements indexer.PathDeclarer.
+func (*DistributionScanner) Paths() []string {
+	return []string{osReleasePath, libOSReleasePath}
+}
+
+// Scan looks for an os-release file with an ID of "wolfi" or "chainguard".
+//
+// If no os-release file is found a (nil,nil) is returned.
+// If the file is for a different distribution an empty slice is returned.
+func (ds *DistributionScanner) Scan(ctx context.Context, l *claircore.Layer) ([]*claircore.Distribution, error) {
+	defer trace.StartRegion(ctx, "Scanner.Scan").End()
+	ctx = baggage.ContextWithValues(ctx,
+		label.String("component", "wolfi/DistributionScanner.Scan"),
+		label.String("version", ds.Version()),
+		label.String("layer", l.Hash.String()))
+	zlog.Debug(ctx).Msg("start")
+	defer zlog.Debug(ctx).Msg("done")
+	files, err := l.Files(osReleasePath, libOSReleasePath)
+	if err != nil {
+		zlog.Debug(ctx).Msg("didn't find an os-release file")
+		return nil, nil
+	}
+	for _, p := range []string{osReleasePath, libOSReleasePath} {
+		b, ok := files[p]
+		if !ok {
+			continue
+		}
+		if dist := parse(b); dist != nil {
+			return []*claircore.Distribution{dist}, nil
+		}
+		// The first os-release file found is authoritative.
+		break
+	}
+	return []*claircore.Distribution{}, nil
+}
+
+// Parse returns the Distribution for the os-release file contents, or nil if
+// it's not a Wolfi or Chainguard os-release file.
+func parse(b *bytes.Buffer) *claircore.Distribution {
+	s := bufio.NewScanner(bytes.NewReader(b.Bytes()))
+	for s.Scan() {
+		k, v := splitKV(s.Text())
+		if k != "ID" {
+			continue
+		}
+		switch v {
+		case "wolfi":
+			return wolfiDist
+		case "chainguard":
+			return chainguardDist
+		}
+		return nil
+	}
+	return nil
+}
+
+// SplitKV splits an os-release line into its key and unquoted value.
+func splitKV(line string) (string, string) {
+	i := strings.IndexByte(line, '=')
+	if i < 0 {
+		return "", ""
+	}
+	k := strings.TrimSpace(line[:i])
+	v := strings.TrimSpace(line[i+1:])
+	v = strings.Trim(v, `"'`)
+	return k, v
+}
@@ -0,0 +1,74 @@
+package wolfi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/quay/zlog"
+
+	"github.com/quay/claircore"
+	"github.com/quay/claircore/test/fixture"
+)
+
+const wolfiOSRelease = `ID=wolfi
+NAME="Wolfi"
+PRETTY_NAME="Wolfi"
+VERSION_ID="20230201"
+HOME_URL="https://wolfi.dev"
+`
+
+const chainguardOSRelease = `ID="chainguard"
+NAME="Chainguard"
+PRETTY_NAME="Chainguard"
+VERSION_ID="20230214"
+HOME_URL="https://chainguard.dev/"
+`
+
+func TestDistributionScanner(t *testing.T) {
+	ctx := zlog.Test(context.Background(), t)
+	table := []struct {
+		name  string
+		files map[string]string
+		want  []*claircore.Distribution
+	}{
+		{
+			name:  "Wolfi",
+			files: map[string]string{"etc/os-release": wolfiOSRelease},
+			want:  []*claircore.Distribution{wolfiDist},
+		},
+		{
+			name:  "Chainguard",
+			files: map[string]string{"etc/os-release": chainguardOSRelease},
+			want:  []*claircore.Distribution{chainguardDist},
+		},
+		{
+			name:  "UsrLib",
+			files: map[string]string{"usr/lib/os-release": wolfiOSRelease},
+			want:  []*claircore.Distribution{wolfiDist},
+		},
+		{
+			name:  "Alpine",
+			files: map[string]string{"etc/os-release": "NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.18.4\n"},
+			want:  []*claircore.Distribution{},
+		},
+		{
+			name:  "None",
+			files: map[string]string{"etc/alpine-release": "3.18.4\n"},
+			want:  nil,
+		},
+	}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := zlog.Test(ctx, t)
+			var s DistributionScanner
+			got, err := s.Scan(ctx, fixture.Files(tc.files).Local(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error(cmp.Diff(got, tc.want))
+			}
+		})
+	}
+}